@@ -0,0 +1,113 @@
+//go:build js && wasm
+// +build js,wasm
+
+// zwiftpower-wasm exposes the zp parsing and stats to JavaScript, so that a static
+// dashboard can work on JSON it has fetched itself (say from a mirror) without a
+// server. It sets up a global zwiftpower object whose functions take JSON strings
+// and return JSON strings, or an Error if the input can't be parsed:
+//
+//	zwiftpower.rider(profileJSON, riderID[, asOf])  // stats as of an ISO date, or now
+//	zwiftpower.club(clubJSON)                       // the club's riders
+//	zwiftpower.results(eventJSON)                   // results, with dual-category entries merged
+//	zwiftpower.efficiency(profileJSON)              // aerobic efficiency trend
+//
+// Build it with make wasm.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// export wraps a function so JavaScript gets its result as JSON, or an Error
+func export(name string, fn func(args []js.Value) (interface{}, error)) {
+	js.Global().Get("zwiftpower").Set(name, js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		v, err := fn(args)
+		if err == nil {
+			var data []byte
+			data, err = json.Marshal(v)
+			if err == nil {
+				return string(data)
+			}
+		}
+		return js.Global().Get("Error").New(fmt.Sprintf("zwiftpower.%s: %v", name, err))
+	}))
+}
+
+func arg(args []js.Value, i int) (string, error) {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return "", fmt.Errorf("argument %d should be a string", i+1)
+	}
+	return args[i].String(), nil
+}
+
+func main() {
+	js.Global().Set("zwiftpower", js.Global().Get("Object").New())
+
+	export("rider", func(args []js.Value) (interface{}, error) {
+		data, err := arg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 2 || args[1].Type() != js.TypeNumber {
+			return nil, fmt.Errorf("argument 2 should be the rider's ID")
+		}
+		asOf := time.Now()
+		if len(args) > 2 && args[2].Truthy() {
+			s, err := arg(args, 2)
+			if err != nil {
+				return nil, err
+			}
+			asOf, err = time.Parse("2006-01-02", s)
+			if err != nil {
+				return nil, err
+			}
+			asOf = asOf.AddDate(0, 0, 1).Add(-time.Second)
+		}
+
+		events, err := zp.ParseEvents([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		return zp.RiderFromEventsAsOf(args[1].Int(), events, asOf), nil
+	})
+
+	export("club", func(args []js.Value) (interface{}, error) {
+		data, err := arg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return zp.ParseClub([]byte(data))
+	})
+
+	export("results", func(args []js.Value) (interface{}, error) {
+		data, err := arg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		events, err := zp.ParseEvents([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		return zp.CanonicalResults(events), nil
+	})
+
+	export("efficiency", func(args []js.Value) (interface{}, error) {
+		data, err := arg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		events, err := zp.ParseEvents([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		return zp.EfficiencyTrend(events), nil
+	})
+
+	// Keep running so JavaScript can go on calling the functions
+	select {}
+}
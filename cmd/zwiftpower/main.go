@@ -0,0 +1,7 @@
+package main
+
+import "github.com/lizrice/zwiftpower/v2/internal/cli"
+
+func main() {
+	cli.Execute(cli.NewCommand())
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var archiveColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "latestdate", Header: "Latest event date"},
+	{Key: "archived", Header: "Left"},
+}
+
+// archiveCommand archives riders who have left the club, keeping their history in the
+// store for past standings
+func archiveCommand() *cobra.Command {
+	var list bool
+	archiveCmd := &cobra.Command{
+		Use:   "archive [club ID]",
+		Short: "Archive riders in the store who have left the club, and list everyone archived",
+		Long: `Riders in the store (--store) who aren't on the club's roster any more are marked as
+archived. Their events stay in the store, so leagues, awards and recomputed stats for
+when they were in the club still include them, but the dashboard and other reports of
+the club as it is now leave them out. Riders who rejoin are restored. The store should
+only be used for this club. Hosted clubs (--tenants) are archived after every refresh.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := ArchiveDeparted(clubID, list)
+			exitOnError(err, fmt.Sprintf("archiving riders who have left club %d", clubID))
+		},
+	}
+	archiveCmd.Flags().BoolVar(&list, "list", false, "Only list the archived riders, without checking the roster")
+	return archiveCmd
+}
+
+// ArchiveDeparted archives the riders in the store who are no longer on the club's
+// roster, then writes out everyone who is archived
+func ArchiveDeparted(clubID int, listOnly bool) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	if !listOnly {
+		client, err := newClient()
+		if err != nil {
+			return fmt.Errorf("error getting client: %w", err)
+		}
+		roster, err := client.Club(context.Background(), clubID)
+		if err != nil {
+			return fmt.Errorf("getting roster: %w", err)
+		}
+		archived, restored, err := zp.ArchiveDeparted(store, roster, time.Now())
+		if err != nil {
+			return err
+		}
+		logArchived(archived, restored)
+		if err := recordRoster(store, clubID, roster); err != nil {
+			return err
+		}
+	}
+
+	riders, err := store.QueryRiders(zp.RiderQuery{Archived: zp.ArchivedRiders})
+	if err != nil {
+		return fmt.Errorf("reading archived riders: %w", err)
+	}
+	return writeRows(clubID, archiveColumns, len(riders), func(i int) []string {
+		r := riders[i]
+		return []string{strings.TrimSpace(r.Name), strconv.Itoa(r.Zwid), formatDate(r.LatestEventDate), formatDate(*r.Archived)}
+	})
+}
+
+// logArchived reports who ArchiveDeparted archived and restored
+func logArchived(archived, restored []zp.Rider) {
+	for _, r := range archived {
+		log.Printf("Archived %s (%d), who has left the club", r.Name, r.Zwid)
+	}
+	for _, r := range restored {
+		log.Printf("Restored %s (%d), who is back in the club", r.Name, r.Zwid)
+	}
+}
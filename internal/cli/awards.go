@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var awardColumns = []zp.Column{
+	{Key: "award", Header: "Award"},
+	{Key: "place", Header: "Place"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "score", Header: "Score"},
+	{Key: "citation", Header: "Citation"},
+}
+
+func awardsCommand() *cobra.Command {
+	var from string
+	cmd := &cobra.Command{
+		Use:   "awards [club ID]",
+		Short: "Hand out the club's end-of-season awards, from the store",
+		Long: `Works out the season's awards from the races in the store (--store) between --from and
+the end of --as-of (or today): most races, most podiums, most improved FTP and best
+attendance, each with its top three and a citation for each of them. Ties are broken
+by podiums, wins or races depending on the award, and riders still tied share a place.
+The riders are those in the club's latest snapshot from before the end of the season,
+or everyone in the store if there isn't one. The club ID defaults to 2672.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			start, err := time.Parse("2006-01-02", from)
+			exitOnError(err, "reading --from, which should be a date like 2021-01-01")
+			end := asOf
+			if end.IsZero() {
+				end = time.Now()
+			}
+			exitOnError(SeasonAwards(clubID, start, end), fmt.Sprintf("working out awards for %d", clubID))
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", time.Now().Format("2006")+"-01-01", "The first day of the season")
+	return cmd
+}
+
+// SeasonAwards writes out the club's awards for the season between the two times, using
+// only what's in the store
+func SeasonAwards(clubID int, from, to time.Time) error {
+	if !from.Before(to) {
+		return fmt.Errorf("the season has to start before it ends")
+	}
+	if _, err := os.Stat(StoreDir); os.IsNotExist(err) {
+		return fmt.Errorf("no store at %s, so no events to hand out awards for", StoreDir)
+	}
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	snapshot, roster, err := zp.RosterAsOf(store, clubID, to)
+	switch {
+	case zp.IsNotFound(err):
+		log.Printf("%v; using every rider in the store", err)
+		roster, err = store.Riders()
+		if err != nil {
+			return fmt.Errorf("reading riders: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("reading snapshot: %w", err)
+	default:
+		log.Printf("Riders in the club from snapshot %s", snapshot)
+	}
+	disambiguate(roster)
+
+	events := make(map[int][]zp.Event)
+	for _, r := range roster {
+		ee, err := store.Events(r.Zwid)
+		if zp.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading events for %d: %w", r.Zwid, err)
+		}
+		events[r.Zwid] = ee
+	}
+
+	var rows [][]string
+	for _, a := range zp.SeasonAwards(roster, events, from, to, ftpAggregation) {
+		for _, p := range a.Places {
+			rows = append(rows, []string{
+				a.Title,
+				strconv.Itoa(p.Place),
+				p.Name,
+				strconv.Itoa(p.Zwid),
+				strconv.FormatFloat(p.Score, 'f', -1, 64),
+				p.Citation,
+			})
+		}
+	}
+	log.Printf("Awards for %d riders from %s to %s", len(events), from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	return writeRows(clubID, awardColumns, len(rows), func(i int) []string {
+		return rows[i]
+	})
+}
@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// NewBotCommand is the zwiftpower-bot command, which posts the club digest to a chat
+// webhook, either once or on a schedule
+func NewBotCommand() *cobra.Command {
+	var (
+		days     int
+		fun      bool
+		tmpl     string
+		interval time.Duration
+	)
+	notify := &botNotifiers{}
+
+	botCmd := &cobra.Command{
+		Use:   "zwiftpower-bot [club ID]",
+		Short: "Post a round-up of the club's results to a chat webhook",
+		Long: `Posts the digest to each of the webhooks and email addresses that are set, or writes it
+to stdout if none are. With --every it keeps running and posts again after each interval.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			for {
+				err := postDigest(notify, StoreDir, clubID, days, fun, tmpl)
+				if interval == 0 {
+					exitOnError(err, fmt.Sprintf("posting digest for %d", clubID))
+					return
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error posting digest for %d: %v\n", clubID, err)
+				}
+				time.Sleep(interval)
+			}
+		},
+	}
+	addGlobalFlags(botCmd)
+	botCmd.Flags().IntVar(&days, "days", 7, "Include results from this many days ago")
+	botCmd.Flags().BoolVar(&fun, "fun", false, "Add some not-very-serious awards")
+	botCmd.Flags().StringVarP(&tmpl, "template", "t", os.Getenv("DIGEST_TEMPLATE"), "File containing a text/template to use instead of the default digest")
+	notify.addFlags(botCmd)
+	botCmd.Flags().DurationVar(&interval, "every", 0, "Post again after this long, e.g. 168h for weekly. 0 means post once and exit.")
+	botCmd.AddCommand(versionCommand())
+	botCmd.AddCommand(remindCommand(notify))
+	botCmd.AddCommand(milestonesCommand(notify))
+	botCmd.AddCommand(resultsCommand(notify))
+	botCmd.AddCommand(telegramCommand(notify))
+	return botCmd
+}
+
+// botNotifiers holds the flags saying where the bot posts to
+type botNotifiers struct {
+	slack   string
+	discord string
+	webhook string
+	smtp    string
+	from    string
+	to      string
+
+	telegramToken string
+	telegramChat  string
+}
+
+func (b *botNotifiers) addFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&b.slack, "webhook", os.Getenv("ZP_WEBHOOK_URL"), "Slack-compatible incoming webhook URL to post to")
+	flags.StringVar(&b.discord, "discord", os.Getenv("ZP_DISCORD_URL"), "Discord channel webhook URL to post to")
+	flags.StringVar(&b.webhook, "json-webhook", os.Getenv("ZP_JSON_WEBHOOK_URL"), "URL to post each message to as JSON with kind, title and text fields")
+	flags.StringVar(&b.smtp, "smtp", os.Getenv("ZP_SMTP_ADDR"), "host:port of an SMTP server to send email through, logging in with SMTP_USERNAME and SMTP_PASSWORD if set")
+	flags.StringVar(&b.from, "email-from", os.Getenv("ZP_EMAIL_FROM"), "Address to send email from")
+	flags.StringVar(&b.to, "email-to", os.Getenv("ZP_EMAIL_TO"), "Comma-separated addresses to email each message to")
+	flags.StringVar(&b.telegramToken, "telegram-token", os.Getenv("ZP_TELEGRAM_TOKEN"), "Telegram bot token from @BotFather")
+	flags.StringVar(&b.telegramChat, "telegram-chat", os.Getenv("ZP_TELEGRAM_CHAT"), "Telegram chat ID, or @name of a public channel, to post to")
+}
+
+// notifiers are the places the flags say to post to
+func (b *botNotifiers) notifiers() []zp.Notifier {
+	var notifiers []zp.Notifier
+	if b.slack != "" {
+		notifiers = append(notifiers, zp.SlackNotifier{URL: b.slack})
+	}
+	if b.discord != "" {
+		notifiers = append(notifiers, zp.DiscordNotifier{URL: b.discord})
+	}
+	if b.webhook != "" {
+		notifiers = append(notifiers, zp.WebhookNotifier{URL: b.webhook})
+	}
+	if b.smtp != "" && b.to != "" {
+		e := zp.EmailNotifier{Addr: b.smtp, From: b.from}
+		for _, to := range strings.Split(b.to, ",") {
+			e.To = append(e.To, strings.TrimSpace(to))
+		}
+		if user := os.Getenv("SMTP_USERNAME"); user != "" {
+			host, _, _ := net.SplitHostPort(b.smtp)
+			e.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		notifiers = append(notifiers, e)
+	}
+	if b.telegramToken != "" && b.telegramChat != "" {
+		notifiers = append(notifiers, zp.TelegramNotifier{Token: b.telegramToken, ChatID: b.telegramChat})
+	}
+	return notifiers
+}
+
+// notifier sends to all the places the flags say, or to stdout if there aren't any
+func (b *botNotifiers) notifier() zp.Notifier {
+	notifiers := b.notifiers()
+	switch {
+	case len(notifiers) == 0:
+		return zp.WriterNotifier{W: os.Stdout}
+	case DryRun:
+		return zp.NotifierFunc(func(ctx context.Context, n zp.Notification) error {
+			log.Printf("Dry run: would post %s to %d places:\n%s", n.Kind, len(notifiers), strings.TrimRight(n.Text, "\n"))
+			return nil
+		})
+	}
+	return zp.FanOut{Notifiers: notifiers}
+}
+
+// posted is true if notifications really go somewhere
+func (b *botNotifiers) posted() bool {
+	return len(b.notifiers()) > 0 && !DryRun
+}
+
+// postDigest sends the digest to the notifiers, using the store in dir
+func postDigest(notify *botNotifiers, dir string, clubID int, days int, fun bool, templateFile string) error {
+	var buf bytes.Buffer
+	err := writeClubDigest(&buf, dir, clubID, days, fun, templateFile)
+	if err != nil {
+		return err
+	}
+
+	err = notify.notifier().Notify(context.Background(), zp.Notification{
+		Kind:  zp.NotifyDigest,
+		Title: fmt.Sprintf("Club %d digest", clubID),
+		Text:  buf.String(),
+	})
+	if err == nil && notify.posted() {
+		log.Printf("Posted digest for %d", clubID)
+	}
+	return err
+}
+
+// remindCommand posts a reminder shortly before each of the events, naming the club's
+// riders who have signed up
+func remindCommand(notify *botNotifiers) *cobra.Command {
+	var (
+		clubID   int
+		before   time.Duration
+		interval time.Duration
+	)
+
+	remindCmd := &cobra.Command{
+		Use:   "remind [event ID...]",
+		Short: "Remind the club's riders shortly before events they have signed up for",
+		Long: `Checks the signups for each event, and once an event is due to start within --before,
+posts a reminder naming the club's riders in it. It keeps checking every --every until all the
+events have started, and only reminds once for each event. Events with no signups at all
+are checked until it is stopped, since until then we don't know when they start.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventIDs, err := parseIDs(args)
+			exitOnError(err, "reading event IDs")
+			exitOnError(remindEvents(notify.notifier(), clubID, eventIDs, before, interval), "sending reminders")
+		},
+	}
+	remindCmd.Flags().IntVar(&clubID, "club", 2672, "Club whose riders to remind")
+	remindCmd.Flags().DurationVar(&before, "before", 2*time.Hour, "How long before the start to send the reminder")
+	remindCmd.Flags().DurationVar(&interval, "every", 15*time.Minute, "How often to check the signups")
+	return remindCmd
+}
+
+// remindEvents checks each event's signups until every event has started or had its
+// reminder sent
+func remindEvents(notifier zp.Notifier, clubID int, eventIDs []int, before time.Duration, interval time.Duration) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	pending := make(map[int]bool)
+	for _, id := range eventIDs {
+		pending[id] = true
+	}
+
+	for len(pending) > 0 {
+		now := time.Now()
+		for id := range pending {
+			signups, err := client.EventSignups(context.Background(), id)
+			if err != nil {
+				log.Printf("Getting signups for %d: %v", id, err)
+				continue
+			}
+
+			r := zp.NewEventReminder(publicMasks().MaskEvents(signups), strconv.Itoa(clubID))
+			switch {
+			case r.Due(now, before):
+				err = notifier.Notify(context.Background(), zp.Notification{
+					Kind:  zp.NotifyReminder,
+					Title: fmt.Sprintf("Reminder: %s", r.Title),
+					Text:  r.Message(now),
+				})
+				if err != nil {
+					log.Printf("Posting reminder for %d: %v", id, err)
+					continue
+				}
+				log.Printf("Sent reminder for %d to %d riders", id, len(r.Riders))
+				delete(pending, id)
+			case !r.Start.IsZero() && !r.Start.After(now):
+				log.Printf("Event %d has started", id)
+				delete(pending, id)
+			}
+		}
+
+		if len(pending) > 0 {
+			time.Sleep(interval)
+		}
+	}
+	return nil
+}
+
+// milestonesCommand posts the milestones the club's riders have reached recently, on their
+// own rather than as part of the digest
+func milestonesCommand(notify *botNotifiers) *cobra.Command {
+	var days int
+	milestonesCmd := &cobra.Command{
+		Use:   "milestones [club ID]",
+		Short: "Post the milestones the club's riders have reached recently",
+		Long: `Posts riders' 100th races, first wins in a category, anniversaries of joining the club
+(see tenure), and the club's rides together passing 10,000 and so on, from the last
+--days. Nothing is posted if there aren't any, so it can run daily.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			exitOnError(postMilestones(notify.notifier(), clubID, days), "posting milestones")
+		},
+	}
+	milestonesCmd.Flags().IntVar(&days, "days", 1, "Include milestones from this many days ago")
+	return milestonesCmd
+}
+
+func postMilestones(notifier zp.Notifier, clubID int, days int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := clubEvents(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	milestones := zp.Milestones(publicMasks().MaskEvents(events), since)
+	store, err := existingStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	anniversaries, err := clubAnniversaries(store, clubID, since, time.Now())
+	if err != nil {
+		return err
+	}
+	milestones = append(milestones, anniversaries...)
+	if len(milestones) == 0 {
+		log.Printf("No milestones for %d in the last %d days", clubID, days)
+		return nil
+	}
+
+	var b strings.Builder
+	for _, m := range milestones {
+		fmt.Fprintf(&b, "%s %s\n", m.Name, m.Text)
+	}
+	return notifier.Notify(context.Background(), zp.Notification{
+		Kind:  zp.NotifyMilestones,
+		Title: fmt.Sprintf("Club %d milestones", clubID),
+		Text:  b.String(),
+	})
+}
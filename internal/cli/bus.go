@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// BusURL is the message bus that imports are published to, if any: nats:// for NATS, or
+// a Kafka REST Proxy's http:// URL
+var BusURL string
+
+// BusPrefix goes in front of the subject or topic of everything published to the bus
+var BusPrefix string
+
+// bus is set up from BusURL before each command runs, and is nil if there's no bus
+var bus *busPublisher
+
+// busPublisher publishes what imports find to the message bus. Failures are logged
+// rather than stopping the import, as the bus is for other services' benefit.
+type busPublisher struct {
+	publisher zp.Publisher
+	prefix    string
+	dest      string // For logs, without any password
+}
+
+func newBus(busURL string, prefix string) (*busPublisher, error) {
+	if busURL == "" {
+		return nil, nil
+	}
+	p, err := zp.NewPublisher(busURL)
+	if err != nil {
+		return nil, err
+	}
+	dest := busURL
+	if u, err := url.Parse(busURL); err == nil {
+		dest = u.Redacted()
+	}
+	return &busPublisher{publisher: p, prefix: prefix, dest: dest}, nil
+}
+
+// publish sends an event about the rider to the bus, if there is one
+func (b *busPublisher) publish(ctx context.Context, clubID int, kind string, r zp.Rider) {
+	if b == nil {
+		return
+	}
+	m, err := zp.BusEvent{Type: kind, Time: time.Now().UTC(), ClubID: clubID, Rider: r}.Message(b.prefix)
+	if err != nil {
+		log.Printf("Making %s message for %d: %v", kind, r.Zwid, err)
+		return
+	}
+	if DryRun {
+		log.Printf("Dry run: would publish %s for %d to %s", m.Subject, r.Zwid, b.dest)
+		return
+	}
+	if err := b.publisher.Publish(ctx, m); err != nil {
+		log.Printf("Publishing %s for %d to %s: %v", m.Subject, r.Zwid, b.dest, err)
+	}
+}
+
+// publishUpdates sends the live dashboard's updates to the bus as well
+func (b *busPublisher) publishUpdates(ctx context.Context, clubID int, updates []liveUpdate) {
+	kinds := map[string]string{
+		updateJoined: zp.BusRiderJoined,
+		updateLeft:   zp.BusRiderLeft,
+		updateResult: zp.BusRiderResult,
+	}
+	for _, u := range updates {
+		b.publish(ctx, clubID, kinds[u.Type], u.Rider)
+	}
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// cacheCommand looks after the local copy of ZwiftPower's files kept by mirror, so that
+// long-running deployments don't fill the disk
+func cacheCommand() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Look after the local copy of ZwiftPower's files kept by mirror",
+	}
+
+	var dir, maxSize string
+	var maxAge time.Duration
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove mirrored files that are too old, then the oldest until the rest fit in --max-size",
+		Long: `Files are aged by when they were last written, so riders' profiles and recent results,
+which each refresh of the mirror writes again, stay while results of events that have
+dropped out of its --days go once they're older than --max-age. Then the least recently
+written files go until the rest fit in --max-size. mirror can do this after every refresh
+with the same flags.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			limits, err := cacheLimits(maxAge, maxSize)
+			if err != nil {
+				exitWith(err, "", exitUsage)
+			}
+			if limits == (zp.Limits{}) {
+				exitWith(fmt.Errorf("give --max-age, --max-size or both"), "", exitUsage)
+			}
+			r, err := zp.Prune(dir, limits, time.Now(), DryRun)
+			exitOnError(err, "pruning cache")
+			log.Printf("Removed %d files (%d bytes) from %s, leaving %d (%d bytes)", r.Removed, r.RemovedBytes, dir, r.Kept, r.KeptBytes)
+		},
+	}
+	pruneCmd.Flags().StringVar(&dir, "dir", mirrorDir(), "Directory of mirrored files")
+	addLimitFlags(pruneCmd, &maxAge, &maxSize)
+
+	cacheCmd.AddCommand(pruneCmd)
+	return cacheCmd
+}
+
+func addLimitFlags(cmd *cobra.Command, maxAge *time.Duration, maxSize *string) {
+	age, _ := time.ParseDuration(os.Getenv("ZP_CACHE_MAX_AGE"))
+	cmd.Flags().DurationVar(maxAge, "max-age", age, "Remove files not written for this long, e.g. 720h. 0 means no limit")
+	cmd.Flags().StringVar(maxSize, "max-size", os.Getenv("ZP_CACHE_MAX_SIZE"), "Keep the files within this size, removing the oldest first, e.g. 500MB or 2GiB")
+}
+
+func cacheLimits(maxAge time.Duration, maxSize string) (zp.Limits, error) {
+	limits := zp.Limits{MaxAge: maxAge}
+	if maxSize != "" {
+		size, err := zp.ParseSize(maxSize)
+		if err != nil {
+			return limits, fmt.Errorf("--max-size: %w", err)
+		}
+		limits.MaxSize = size
+	}
+	return limits, nil
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var changesColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "change", Header: "Change"},
+}
+
+func changesCommand() *cobra.Command {
+	var field bool
+	cmd := &cobra.Command{
+		Use:   "changes [event ID] [club ID]",
+		Short: "Record an event's results, and list what has changed since they were provisional",
+		Long: `The first results seen for an event are kept in the store (--store) as provisional, and
+the latest as final. This fetches the latest and lists how the club's riders' results
+differ from the provisional ones: riders removed (usually disqualified) or added,
+positions and categories changed, and times adjusted. The club ID defaults to 2672;
+--field lists changes for everyone in the event.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			clubID := getID(args[1:], 2672)
+			if field {
+				clubID = 0
+			}
+			exitOnError(ResultChanges(eventID, clubID), fmt.Sprintf("checking results for %d", eventID))
+		},
+	}
+	cmd.Flags().BoolVar(&field, "field", false, "List changes for the whole field, not just the club")
+	return cmd
+}
+
+// ResultChanges records the event's latest results, and writes out how they differ from
+// the provisional results for the club's riders, or everyone if the club ID is zero
+func ResultChanges(eventID int, clubID int) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	if _, err := updateResults(store, eventID); err != nil {
+		return err
+	}
+
+	provisional, err := store.EventResults(eventID, zp.ProvisionalResults)
+	if err != nil {
+		return fmt.Errorf("reading provisional results: %w", err)
+	}
+	final, err := store.EventResults(eventID, zp.FinalResults)
+	if zp.IsNotFound(err) {
+		final = provisional
+	} else if err != nil {
+		return fmt.Errorf("reading final results: %w", err)
+	}
+
+	d := zp.DiffResults(provisional, final)
+	if clubID != 0 {
+		d = d.ForClub(clubID)
+	}
+	log.Printf("%d changes to the results of %s", len(d.Changes), d.Title)
+	masks := publicMasks()
+	return writeRows(clubID, changesColumns, len(d.Changes), func(i int) []string {
+		c := d.Changes[i]
+		c.Name = masks.Name(c.Zwid, c.Name)
+		return []string{c.Name, strconv.Itoa(c.Zwid), c.String()}
+	})
+}
+
+// updateResults fetches the event's results and stores them, giving what has changed
+// since they were last stored
+func updateResults(store *zp.FileStore, eventID int) (zp.ResultsDiff, error) {
+	client, err := newClient()
+	if err != nil {
+		return zp.ResultsDiff{}, fmt.Errorf("error getting client: %w", err)
+	}
+	results, err := client.EventResults(context.Background(), eventID)
+	if err != nil {
+		return zp.ResultsDiff{}, fmt.Errorf("getting results: %w", err)
+	}
+	d, err := store.UpdateResults(eventID, results)
+	if err != nil {
+		return d, fmt.Errorf("storing results: %w", err)
+	}
+	return d, nil
+}
+
+// resultsCommand posts the changes to events' results since they were last checked, such
+// as DQs applied after the standings went out
+func resultsCommand(notify *botNotifiers) *cobra.Command {
+	var (
+		clubID int
+		league string
+	)
+	resultsCmd := &cobra.Command{
+		Use:   "results [event ID...]",
+		Short: "Post what has changed in events' results since they were last checked",
+		Long: `Fetches each event's results and compares them with the version last stored (--store).
+If any of the club's riders have been disqualified, moved, recategorised or retimed, posts
+a "results updated" message listing the changes. Nothing is posted the first time an
+event is checked, or if nothing has changed, so it can run every few hours for a day or
+two after each race. --league checks all of the league's fixtures.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			eventIDs, err := parseIDs(args)
+			exitOnError(err, "reading event IDs")
+			if league != "" {
+				store, err := openStore(StoreDir)
+				exitOnError(err, "opening store")
+				l, err := store.League(league)
+				exitOnError(err, "reading league")
+				for _, f := range l.Fixtures {
+					eventIDs = append(eventIDs, f.EventID)
+				}
+			}
+			if len(eventIDs) == 0 {
+				exitWith(fmt.Errorf("no events to check: give event IDs or --league"), "", exitUsage)
+			}
+			exitOnError(postResultChanges(notify.notifier(), clubID, eventIDs), "posting result changes")
+		},
+	}
+	resultsCmd.Flags().IntVar(&clubID, "club", 2672, "Club whose riders' changes to post")
+	resultsCmd.Flags().StringVar(&league, "league", "", "Check the fixtures of this league")
+	return resultsCmd
+}
+
+func postResultChanges(notifier zp.Notifier, clubID int, eventIDs []int) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	masks := publicMasks()
+	for _, id := range eventIDs {
+		d, err := updateResults(store, id)
+		if zp.IsNotFound(err) {
+			log.Printf("No results for %d yet", id)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("event %d: %w", id, err)
+		}
+		d = d.ForClub(clubID)
+		if len(d.Changes) == 0 {
+			continue
+		}
+		for i, c := range d.Changes {
+			d.Changes[i].Name = masks.Name(c.Zwid, c.Name)
+		}
+
+		err = notifier.Notify(context.Background(), zp.Notification{
+			Kind:  zp.NotifyResultsUpdated,
+			Title: fmt.Sprintf("Results updated: %s", d.Title),
+			Text:  d.Text(),
+		})
+		if err != nil {
+			return fmt.Errorf("posting changes to %d: %w", id, err)
+		}
+		log.Printf("Posted %d changes to the results of %d", len(d.Changes), id)
+	}
+	return nil
+}
@@ -0,0 +1,1556 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"cloud.google.com/go/storage"
+)
+
+var (
+	Filename         string
+	SpreadsheetID    string
+	SpreadsheetSheet string
+	Limit            int
+	Distance         float64
+	Elevation        float64
+	TemplateFile     string
+	BackendName      string
+	Days             int
+	StoreDir         string
+	Pause            time.Duration
+	Disambiguate     string
+	FtpAggregation   string
+	BucketURL        string
+	Format           string
+	Columns          string
+	SortBy           string
+	Cookies          string
+	ZwiftToken       string
+	ZwiftRacingKey   string
+	ShowProgress     bool
+	Dial             zp.DialOptions
+	Activities       bool
+	MinPace          time.Duration
+	MaxPace          time.Duration
+	MaintenanceWait  time.Duration
+	RoutesFile       string
+	AsOf             string
+	ResultsDir       string
+	LocaleName       string
+	storageClient    *storage.Client // Get it with sharedStorageClient
+	storageErr       error
+	storageOnce      sync.Once
+
+	// asOf is when riders' stats are worked out for, if not now
+	asOf time.Time
+
+	// locale is how numbers and dates are written in output
+	locale = zp.DefaultLocale
+
+	// disambiguation is how riders with the same name are told apart, from --disambiguate
+	disambiguation = zp.ByCountry
+
+	// ftpAggregation is how riders' FTPs are combined, from --ftp-aggregation, for the
+	// clients and for stats worked out from stored events
+	ftpAggregation zp.FtpAggregations
+
+	// pacer is shared by all our clients, so they slow down together when ZwiftPower
+	// pushes back. Get it with sharedPacer.
+	pacer     *zp.Pacer
+	pacerOnce sync.Once
+
+	// requestMetrics is shared by all our clients too, and counts and times their requests
+	// by the part of ZwiftPower they go to, for /metrics
+	requestMetrics = zp.NewRequestMetrics()
+
+	// imports tracks the progress of club imports while serving, for /status
+	imports *zp.ImportTracker
+)
+
+func getID(args []string, defaultID int) (id int) {
+	id = defaultID
+	if len(args) >= 1 {
+		id64, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			exitWith(err, "parsing ID", exitUsage)
+		}
+		id = int(id64)
+	}
+	return id
+}
+
+// reportTime is when reports are for: --as-of if it's set, or else now
+func reportTime() time.Time {
+	if asOf.IsZero() {
+		return time.Now()
+	}
+	return asOf
+}
+
+// sharedPacer gets the pacer, creating it the first time. The server creates clients
+// from several goroutines at once, and they must all get the same one.
+func sharedPacer() *zp.Pacer {
+	pacerOnce.Do(func() {
+		pacer = zp.NewPacer(MinPace, MaxPace)
+	})
+	return pacer
+}
+
+// newClient gets a ZwiftPower client, logged in with the session cookies if we have them
+func newClient() (*zp.Client, error) {
+	return newClientFor(StoreDir)
+}
+
+// newClientFor gets a client that leaves out the riders who have opted out in the store
+// in dir, which for a hosted club is its own
+func newClientFor(dir string) (*zp.Client, error) {
+	middleware := []zp.Middleware{sharedPacer().Middleware, schemaWatch().Middleware, requestMetrics.Middleware}
+	if MaintenanceWait > 0 {
+		middleware = append([]zp.Middleware{zp.NewMaintenance(MaintenanceWait).Middleware}, middleware...)
+	}
+	client, err := zp.New(middleware...)
+	if err != nil {
+		return nil, err
+	}
+
+	if Cookies != "" {
+		err = zp.SetCookies(client.HTTP, Cookies)
+		if err != nil {
+			return nil, fmt.Errorf("setting cookies: %w", err)
+		}
+	}
+	if !asOf.IsZero() {
+		client.Clock = zp.FixedClock(asOf)
+	}
+	client.ResultsDir = ResultsDir
+	client.OptOuts = storeOptOuts(dir)
+	client.FtpAggregation = ftpAggregation
+	if Activities {
+		client.ZwiftToken = ZwiftToken
+	}
+	if ShowProgress {
+		client.Progress = zp.ProgressBar(os.Stderr)
+	}
+
+	return client, nil
+}
+
+// isTerminal is true if the file is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// serve runs the HTTP service
+func serve(cmd *cobra.Command, args []string) {
+	// Unless a filename is specified, assume that this is being written to a bucket
+	if Filename == "" && BucketURL == "" {
+		BucketURL = defaultBucketURL
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	shutdown, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	auth := newTokenAuth(StoreDir)
+	if !auth.enabled() {
+		log.Printf("No API tokens in %s, so the server is open to anyone", auth.path)
+	}
+
+	http.HandleFunc("/healthz", serveHealth)
+	http.HandleFunc("/readyz", serveReady)
+	http.Handle("/", auth.require(roleRead, http.FileServer(http.Dir("/tmp"))))
+	http.Handle("/pacing", auth.require(roleRead, http.HandlerFunc(servePacing)))
+	http.Handle("/schema", auth.require(roleRead, http.HandlerFunc(serveSchema)))
+	http.Handle("/metrics", auth.require(roleRead, http.HandlerFunc(serveMetrics)))
+	imports = zp.NewImportTracker()
+	http.Handle("/status", auth.require(roleRead, http.HandlerFunc(serveStatus)))
+	if TenantsFile != "" {
+		tenants, err := loadTenants(TenantsFile, StoreDir)
+		if err != nil {
+			log.Fatalf("Loading tenants from %s: %v", TenantsFile, err)
+		}
+		serveTenants(http.DefaultServeMux, tenants, auth)
+	} else {
+		http.Handle("/trigger", auth.require(roleAdmin, http.HandlerFunc(HelloZP)))
+		http.Handle("/riders/", auth.require(roleAdmin, &riderRefresher{clubID: ServeClubID, hub: live, dir: StoreDir, limiter: newRefreshLimiter()}))
+		http.Handle("/events", auth.require(roleRead, http.HandlerFunc(live.ServeEvents)))
+		http.Handle("/dashboard/", auth.require(roleRead, newDashboard(ServeClubID, StoreDir, live, "")))
+	}
+
+	// Start HTTP server.
+	log.Printf("Listening on port %s (%s)", port, versionString())
+	if err := listen(port, http.DefaultServeMux); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// servePacing reports how ZwiftPower has been responding to our requests, and how far
+// we have slowed down as a result
+func servePacing(w http.ResponseWriter, r *http.Request) {
+	stats := sharedPacer().Stats()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Requests     int     `json:"requests"`
+		RateLimited  int     `json:"rateLimited"`
+		Forbidden    int     `json:"forbidden"`
+		PushbackRate float64 `json:"pushbackRate"`
+		Slowdowns    int     `json:"slowdowns"`
+		Speedups     int     `json:"speedups"`
+		Interval     string  `json:"interval"`
+		Waited       string  `json:"waited"`
+	}{stats.Requests, stats.RateLimited, stats.Forbidden, stats.PushbackRate(),
+		stats.Slowdowns, stats.Speedups, stats.Interval.String(), stats.Waited.String()})
+}
+
+// serveMetrics reports how each part of ZwiftPower has been responding, for Prometheus
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := requestMetrics.WritePrometheus(w); err != nil {
+		log.Printf("Writing metrics: %v", err)
+	}
+}
+
+// serveStatus reports how far each club import has got, so that a long import can be
+// told apart from one that has hung
+func serveStatus(w http.ResponseWriter, r *http.Request) {
+	type importStatus struct {
+		Name     string    `json:"name"`
+		Done     int       `json:"done"`
+		Total    int       `json:"total"`
+		Current  string    `json:"current"`
+		Started  time.Time `json:"started"`
+		Updated  time.Time `json:"updated"`
+		Finished bool      `json:"finished"`
+	}
+	status := []importStatus{}
+	for _, s := range imports.Status() {
+		status = append(status, importStatus{s.Name, s.Done, s.Total, s.Current, s.Started, s.Updated, s.Finished})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Imports []importStatus `json:"imports"`
+	}{status})
+}
+
+// NewServerCommand is the zwiftpower-server command, which runs the HTTP service
+func NewServerCommand() *cobra.Command {
+	serverCmd := &cobra.Command{
+		Use:   "zwiftpower-server",
+		Short: "Run the ZwiftPower import and dashboard as an HTTP service",
+		Args:  cobra.NoArgs,
+		Run:   serve,
+	}
+	addGlobalFlags(serverCmd)
+	addServerFlags(serverCmd)
+	serverCmd.AddCommand(versionCommand())
+	return serverCmd
+}
+
+// NewCommand is the zwiftpower command line tool
+func NewCommand() *cobra.Command {
+	httpCmd := &cobra.Command{
+		Use:   "http",
+		Short: "Run as a service",
+		Run:   serve,
+	}
+
+	riderCmd := &cobra.Command{
+		Use:   "rider [ID]",
+		Short: "Import data for rider ID",
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 98588)
+			client, err := newClient()
+			exitOnError(err, "getting client")
+
+			rider, err := client.Rider(context.Background(), riderID)
+			exitOnError(err, fmt.Sprintf("getting rider %d", riderID))
+
+			writer, err := NewRowWriter(os.Stdout, Format, zp.RiderColumns)
+			exitOnError(err, "")
+			writer.WriteRow(rider.StringsAsOf(reportTime()))
+			writer.Flush()
+		},
+	}
+
+	var precedence string
+	profileCmd := &cobra.Command{
+		Use:   "profile [ID]",
+		Short: "Write everything ZwiftPower has about rider ID as JSON",
+		Long: `Writes the rider's events, races, victims, rivals and primes from ZwiftPower. Their
+weight, FTP and category are also taken from Zwift with --zwift-token, and from
+zwiftracing.app with --zwiftracing-key, and merged into Details, noting any conflicts
+between the sources.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 98588)
+			p, err := zp.ParsePrecedence(precedence)
+			if err != nil {
+				exitWith(err, "in --precedence", exitUsage)
+			}
+			err = RiderProfile(riderID, p)
+			exitOnError(err, fmt.Sprintf("getting profile for %d", riderID))
+		},
+	}
+	profileCmd.Flags().StringVar(&precedence, "precedence", os.Getenv("ZP_PRECEDENCE"), "Sources to trust for each detail, most trusted first, e.g. weight=zwift,zwiftpower;ftp=zwiftracing")
+
+	var handicapClubID int
+	handicapCmd := &cobra.Command{
+		Use:   "handicap [ID...]",
+		Short: "Calculate handicap start offsets for these riders, or for the whole club if none are given",
+		Run: func(cmd *cobra.Command, args []string) {
+			var riderIDs []int
+			for i := range args {
+				riderIDs = append(riderIDs, getID(args[i:], 0))
+			}
+
+			course := zp.Course{Distance: Distance, Elevation: Elevation}
+			err := HandicapRace(handicapClubID, riderIDs, course)
+			exitOnError(err, "calculating handicaps")
+		},
+	}
+	handicapCmd.Flags().Float64VarP(&Distance, "distance", "d", 20, "Route distance in km")
+	handicapCmd.Flags().Float64VarP(&Elevation, "elevation", "e", 0, "Route elevation gain in metres")
+	handicapCmd.Flags().IntVar(&handicapClubID, "club", 2672, "Club to include if no riders are given")
+
+	var reportClubID int
+	reportCmd := &cobra.Command{
+		Use:   "report [event ID]",
+		Short: "Write a race report for the club's riders in this event",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			err := RaceReport(eventID, reportClubID)
+			exitOnError(err, fmt.Sprintf("writing race report for %d", eventID))
+		},
+	}
+	reportCmd.Flags().StringVarP(&TemplateFile, "template", "t", os.Getenv("REPORT_TEMPLATE"), "File containing a text/template to use instead of the default report")
+	reportCmd.Flags().IntVar(&reportClubID, "club", 2672, "Club whose riders to report on")
+
+	var seriesBy string
+	seriesCmd := &cobra.Command{
+		Use:   "series [club ID]",
+		Short: "Compare how the club has done in each race series, world or time slot",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := SeriesStats(clubID, time.Now().AddDate(0, 0, -Days), seriesBy)
+			exitOnError(err, fmt.Sprintf("getting series stats for %d", clubID))
+		},
+	}
+	seriesCmd.Flags().IntVar(&Days, "days", 365, "Include results from this many days ago")
+	seriesCmd.Flags().StringVar(&seriesBy, "by", "series", "Group results by series, world, timeslot or terrain")
+
+	backfillCmd := &cobra.Command{
+		Use:   "backfill [club ID]",
+		Short: "Load the full event history for every rider in the club into the store",
+		Long:  `Safe to interrupt: running it again carries on from where it left off`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			client, err := newClient()
+			exitOnError(err, "getting client")
+
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+
+			err = client.Backfill(context.Background(), store, clubID, Pause)
+			exitOnError(err, fmt.Sprintf("backfilling club %d", clubID))
+		},
+	}
+	backfillCmd.Flags().DurationVar(&Pause, "pause", 2*time.Second, "Time to wait between riders")
+
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot [club ID]",
+		Short: "Save today's stats for every rider in the club into the store",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := Snapshot(clubID)
+			exitOnError(err, fmt.Sprintf("saving snapshot for %d", clubID))
+		},
+	}
+
+	loadCmd := &cobra.Command{
+		Use:   "load [ID]",
+		Short: "Show training load for each of rider ID's events",
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 98588)
+			err := TrainingLoad(riderID)
+			exitOnError(err, fmt.Sprintf("getting training load for %d", riderID))
+		},
+	}
+
+	ageGroupsCmd := &cobra.Command{
+		Use:   "agegroups [club ID]",
+		Short: "Leaderboards for each age group in the club",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := AgeGroups(clubID)
+			exitOnError(err, fmt.Sprintf("getting age groups for %d", clubID))
+		},
+	}
+
+	gendersCmd := &cobra.Command{
+		Use:   "genders [club ID]",
+		Short: "Compare the club's women and men: numbers, activity and categories",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := Genders(clubID)
+			exitOnError(err, fmt.Sprintf("getting gender stats for %d", clubID))
+		},
+	}
+
+	var margin float64
+	categoriesCmd := &cobra.Command{
+		Use:   "categories [club ID]",
+		Short: "How long each rider has been in their category, and how close they are to moving up",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := Categories(clubID, margin)
+			exitOnError(err, fmt.Sprintf("getting categories for %d", clubID))
+		},
+	}
+	categoriesCmd.Flags().Float64Var(&margin, "margin", 0.2, "Flag riders within this many w/kg of the next category up")
+
+	var recentDays int
+	recentCmd := &cobra.Command{
+		Use:   "recent [club ID]",
+		Short: "Everyone's results from the last few days, grouped by event",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := RecentResults(clubID, time.Now().AddDate(0, 0, -recentDays))
+			exitOnError(err, fmt.Sprintf("getting recent results for %d", clubID))
+		},
+	}
+	recentCmd.Flags().IntVar(&recentDays, "days", 7, "Include results from this many days ago")
+
+	var digestDays int
+	var digestFun bool
+	var digestTemplate string
+	digestCmd := &cobra.Command{
+		Use:   "digest [club ID]",
+		Short: "Write a round-up of the club's results from the last few days",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := ClubDigest(clubID, digestDays, digestFun, digestTemplate)
+			exitOnError(err, fmt.Sprintf("writing digest for %d", clubID))
+		},
+	}
+	digestCmd.Flags().IntVar(&digestDays, "days", 7, "Include results from this many days ago")
+	digestCmd.Flags().BoolVar(&digestFun, "fun", false, "Add some not-very-serious awards")
+	digestCmd.Flags().StringVarP(&digestTemplate, "template", "t", os.Getenv("DIGEST_TEMPLATE"), "File containing a text/template to use instead of the default digest")
+
+	rootCmd := &cobra.Command{
+		Use:   "zwiftpower [club ID]",
+		Short: "Import data for club ID",
+		Long:  `Default club ID is 2672, Revolution Velo`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			err := ZwiftPower(context.Background(), clubID, Limit)
+			exitOnError(err, fmt.Sprintf("getting ZwiftPower data for %d", clubID))
+		},
+	}
+
+	addGlobalFlags(rootCmd)
+	addServerFlags(httpCmd)
+	rootCmd.AddCommand(httpCmd)
+	rootCmd.AddCommand(riderCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(handicapCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(seriesCmd)
+	rootCmd.AddCommand(backfillCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(loadCmd)
+	rootCmd.AddCommand(ageGroupsCmd)
+	rootCmd.AddCommand(gendersCmd)
+	rootCmd.AddCommand(categoriesCmd)
+	rootCmd.AddCommand(recentCmd)
+	rootCmd.AddCommand(digestCmd)
+	rootCmd.AddCommand(leagueCommand())
+	rootCmd.AddCommand(goalCommand())
+	rootCmd.AddCommand(zpLeagueCommand())
+	rootCmd.AddCommand(reviewCommand())
+	rootCmd.AddCommand(mirrorCommand(), cacheCommand())
+	rootCmd.AddCommand(rivalsCommand())
+	rootCmd.AddCommand(policyCommand())
+	rootCmd.AddCommand(pensCommand())
+	rootCmd.AddCommand(percentilesCommand())
+	rootCmd.AddCommand(historyCommand(), tokensCommand(), scoutCommand(), submissionCommand(), efficiencyCommand(), whoisCommand(), duplicatesCommand(), optOutCommand(), podiumCommand(), vetCommand(), recomputeCommand(), upgradesCommand(), heatmapCommand(), entriesCommand(), awardsCommand(), placingsCommand(), archiveCommand(), maskCommand(), changesCommand(), opponentsCommand(), trendsCommand(), schemaCommand(), trainingCommand(), dirsCommand(), tenureCommand(), simulateCommand())
+	rootCmd.AddCommand(versionCommand())
+	return rootCmd
+}
+
+// addGlobalFlags sets up the flags and environment variables shared by every command
+func addGlobalFlags(rootCmd *cobra.Command) {
+	var limit int
+	limitString := os.Getenv("LIMIT")
+	if limitString != "" {
+		limit, _ = strconv.Atoi(limitString)
+	}
+
+	rootCmd.PersistentFlags().StringVarP(&Filename, "filename", "f", os.Getenv("FILENAME"), "Output file name")
+	rootCmd.PersistentFlags().StringVarP(&SpreadsheetID, "spreadsheet", "s", os.Getenv("SPREADSHEET_ID"), "Google sheets ID")
+	rootCmd.PersistentFlags().StringVarP(&SpreadsheetSheet, "sheetname", "n", os.Getenv("SPREADSHEET_SHEET"), "Google sheets sheet name")
+	rootCmd.PersistentFlags().StringVar(&StoreDir, "store", storeDir(), "Directory for storing rider and event data (see dirs)")
+	rootCmd.PersistentFlags().StringVar(&BackendName, "backend", os.Getenv("ZP_BACKEND"), "ZwiftPower endpoints to use: cache3 or api3")
+	rootCmd.PersistentFlags().StringVar(&Cookies, "cookies", os.Getenv("ZP_COOKIES"), "Logged-in ZwiftPower session cookies, needed for the api3 backend")
+	rootCmd.PersistentFlags().StringVar(&ZwiftToken, "zwift-token", os.Getenv("ZWIFT_TOKEN"), "Zwift API access token, used to get riders' profile pictures and Category Enforcement categories")
+	rootCmd.PersistentFlags().StringVar(&ZwiftRacingKey, "zwiftracing-key", os.Getenv("ZWIFTRACING_KEY"), "zwiftracing.app API key, used for riders' weight, FTP and category in their profiles")
+	rootCmd.PersistentFlags().BoolVar(&ShowProgress, "progress", isTerminal(os.Stderr), "Show a progress bar for long imports (defaults to on when writing to a terminal)")
+	rootCmd.PersistentFlags().BoolVar(&Activities, "activities", os.Getenv("ZP_ACTIVITIES") != "", "Count free rides and workouts from the Zwift API in riders' Rides, using --zwift-token")
+	baseURL := os.Getenv("ZP_BASE_URL")
+	if baseURL == "" {
+		baseURL = zp.BaseURL
+	}
+	rootCmd.PersistentFlags().StringVar(&zp.BaseURL, "base-url", baseURL, "Where to find ZwiftPower, or a mirror of it")
+	rootCmd.PersistentFlags().BoolVar(&Dial.IPv4Only, "ipv4", os.Getenv("ZP_IPV4") != "", "Only connect over IPv4")
+	rootCmd.PersistentFlags().StringVar(&Dial.DNSServer, "dns", os.Getenv("ZP_DNS"), "DNS server to look names up with instead of the system's, as host:port")
+	rootCmd.PersistentFlags().StringVar(&Dial.DoHURL, "doh", os.Getenv("ZP_DOH"), "DNS-over-HTTPS server to look names up with instead, e.g. https://1.1.1.1/dns-query")
+	rootCmd.PersistentFlags().StringVar(&ResultsDir, "results-dir", os.Getenv("ZP_RESULTS_DIR"), "Directory of saved event results, as <event ID>.json, .csv or .html, to use instead of ZwiftPower's")
+	rootCmd.PersistentFlags().StringVar(&AsOf, "as-of", os.Getenv("ZP_AS_OF"), "Work out riders' stats as they were at the end of this date, e.g. 2020-12-31, ignoring later events")
+	rootCmd.PersistentFlags().BoolVar(&Mask, "mask", os.Getenv("ZP_MASK") != "", "Show the alias or initials of riders who have asked for their names to be masked (see mask), for output that will be public")
+	rootCmd.PersistentFlags().BoolVar(&DryRun, "dry-run", os.Getenv("ZP_DRY_RUN") != "", "Log what would be written, uploaded, posted or stored, without doing it")
+	rootCmd.PersistentFlags().BoolVar(&JSONErrors, "json-errors", os.Getenv("ZP_JSON_ERRORS") != "", "Write errors to stderr as JSON, with the kind of error and exit code")
+
+	// Execute reports errors, and in JSON the usage would get in the way
+	rootCmd.SilenceErrors = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		cmd.SilenceUsage = JSONErrors
+		return err
+	})
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = JSONErrors
+		zp.BaseURL = strings.TrimSuffix(zp.BaseURL, "/")
+		backend, err := zp.ParseBackend(BackendName)
+		if err != nil {
+			return err
+		}
+		zp.DefaultBackend = backend
+		if Dial != (zp.DialOptions{}) {
+			// Webhooks and the other services we talk to connect the same way as ZwiftPower
+			zp.Transport = zp.NewTransport(Dial)
+			http.DefaultTransport = zp.Transport
+		}
+		if AsOf != "" {
+			day, err := time.Parse("2006-01-02", AsOf)
+			if err != nil {
+				return fmt.Errorf("--as-of should be a date like 2020-12-31: %w", err)
+			}
+			// As of the end of that day, so its events count
+			asOf = day.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		bus, err = newBus(BusURL, BusPrefix)
+		if err != nil {
+			return fmt.Errorf("--bus: %w", err)
+		}
+		disambiguation, err = zp.ParseDisambiguation(Disambiguate)
+		if err != nil {
+			return fmt.Errorf("--disambiguate: %w", err)
+		}
+		ftpAggregation, err = zp.ParseFtpAggregations(FtpAggregation)
+		if err != nil {
+			return fmt.Errorf("--ftp-aggregation: %w", err)
+		}
+		locale, err = zp.ParseLocale(LocaleName)
+		if err != nil {
+			return err
+		}
+		if Activities && ZwiftToken == "" {
+			return fmt.Errorf("--activities needs a Zwift access token (--zwift-token)")
+		}
+		if RoutesFile != "" {
+			data, err := ioutil.ReadFile(RoutesFile)
+			if err != nil {
+				return err
+			}
+			routes, err := zp.ParseRouteCourses(data)
+			if err != nil {
+				return err
+			}
+			for id, c := range routes {
+				zp.RouteCourses[id] = c
+			}
+		}
+		return checkFormat(Format)
+	}
+	format := os.Getenv("FORMAT")
+	if format == "" {
+		format = formatCSV
+	}
+	rootCmd.PersistentFlags().StringVar(&Format, "format", format, "Output format: table, csv, json, ndjson or html")
+	rootCmd.PersistentFlags().StringVar(&LocaleName, "locale", os.Getenv("ZP_LOCALE"), "Write numbers and dates for this locale, e.g. de for 3,4 and 03.02.2021 with ; between CSV fields. JSON is unchanged")
+	rootCmd.PersistentFlags().StringVar(&Columns, "columns", os.Getenv("ZP_COLUMNS"), "Comma-separated list of columns to output, e.g. name,ftp90,races30")
+	rootCmd.PersistentFlags().StringVar(&SortBy, "sort", os.Getenv("ZP_SORT"), "Sort output by this column, prefixed with - for descending order, e.g. -ftp90")
+	rootCmd.PersistentFlags().StringVar(&BucketURL, "bucket", os.Getenv("BUCKET_URL"), "Upload output to a gs:// or s3:// bucket URL. The object name can include {{.ClubID}}, {{.Date}}, {{.Time}} and {{.Format}}")
+	rootCmd.PersistentFlags().StringVar(&FtpAggregation, "ftp-aggregation", os.Getenv("ZP_FTP_AGGREGATION"), "How to combine riders' FTP estimates in each window: max, second, p95 or top3 (the mean of the best three). Give one for all of FTP 30/60/90 days, or one each, e.g. ftp90=p95,ftp30=second")
+	rootCmd.PersistentFlags().StringVar(&BusURL, "bus", os.Getenv("ZP_BUS_URL"), "Publish imported riders and roster changes to this message bus: nats://host:4222 for NATS, or the http:// URL of a Kafka REST Proxy")
+	busPrefix := os.Getenv("ZP_BUS_PREFIX")
+	if busPrefix == "" {
+		busPrefix = "zwiftpower"
+	}
+	rootCmd.PersistentFlags().StringVar(&BusPrefix, "bus-prefix", busPrefix, "Prefix for the NATS subjects or Kafka topics published to, e.g. zwiftpower for zwiftpower.roster.joined")
+	disambiguateBy := os.Getenv("ZP_DISAMBIGUATE")
+	if disambiguateBy == "" {
+		disambiguateBy = string(zp.ByCountry)
+	}
+	rootCmd.PersistentFlags().StringVar(&Disambiguate, "disambiguate", disambiguateBy, "How to tell apart riders with the same name: country or zwid")
+	rootCmd.PersistentFlags().StringVar(&RoutesFile, "routes", configFile("ZP_ROUTES", "routes.json"), "JSON file of route distances and elevations, keyed by ZwiftPower route ID")
+	rootCmd.PersistentFlags().DurationVar(&MinPace, "pace", envDuration("ZP_PACE", 0), "Minimum time between requests to ZwiftPower. Pacing slows down automatically if ZwiftPower pushes back")
+	rootCmd.PersistentFlags().DurationVar(&MaxPace, "max-pace", envDuration("ZP_MAX_PACE", time.Minute), "Longest that automatic pacing waits between requests to ZwiftPower")
+	rootCmd.PersistentFlags().DurationVar(&MaintenanceWait, "maintenance-wait", envDuration("ZP_MAINTENANCE_WAIT", 2*time.Hour), "Longest to wait for ZwiftPower to come back when it's down for maintenance, trying again every so often. 0 means fail straight away")
+	rootCmd.PersistentFlags().IntVarP(&Limit, "limit", "l", limit, "Restrict to retrieving this number of riders' data. 0 means no limit - get them all.")
+}
+
+// addServerFlags adds the flags that only matter when running as a service
+func addServerFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&ServeClubID, "club", envInt("ZP_CLUB_ID", ServeClubID), "The club to import on /trigger and show on the dashboard, unless there are --tenants")
+	cmd.Flags().StringVar(&TenantsFile, "tenants", configFile("ZP_TENANTS", "tenants.json"), "JSON file listing the clubs to host, each with its own schedule, storage and API tokens")
+	cmd.Flags().DurationVar(&RiderRefreshInterval, "rider-refresh-interval", envDuration("ZP_RIDER_REFRESH_INTERVAL", 5*time.Minute), "How often each rider can be refreshed on request with POST /riders/<id>/refresh")
+	cmd.Flags().DurationVar(&ShutdownTimeout, "shutdown-timeout", envDuration("ZP_SHUTDOWN_TIMEOUT", 25*time.Second), "How long to let imports and requests finish when told to stop, before cancelling them")
+}
+
+// envDuration reads a duration such as 90s or 2h from the environment variable, for a
+// flag's default
+func envDuration(name string, defaultValue time.Duration) time.Duration {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Printf("Ignoring %s: %v", name, err)
+		return defaultValue
+	}
+	return d
+}
+
+// envInt reads a number from the environment variable, for a flag's default
+func envInt(name string, defaultValue int) int {
+	s := os.Getenv(name)
+	if s == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		log.Printf("Ignoring %s: %v", name, err)
+		return defaultValue
+	}
+	return n
+}
+
+func setOutput(filename string, clubID int) (io.WriteCloser, error) {
+	ctx := context.Background()
+
+	if DryRun && SpreadsheetID != "" {
+		return &dryRunWriter{dest: "spreadsheet " + SpreadsheetID}, nil
+	}
+	if DryRun && BucketURL != "" {
+		return &dryRunWriter{dest: BucketURL}, nil
+	}
+
+	if SpreadsheetID != "" {
+		log.Printf("Writing to spreadsheet")
+		sw, err := NewSpreadsheetWriter(ctx, SpreadsheetID, SpreadsheetSheet)
+		if err != nil {
+			return nil, fmt.Errorf("error getting spreadsheet client: %w", err)
+		}
+		return sw, nil
+	}
+
+	if BucketURL != "" {
+		return newBucketWriter(ctx, BucketURL, newObjectKeyData(clubID, Format))
+	}
+
+	if filename == "" {
+		log.Printf("Writing to stdout")
+		return os.Stdout, nil
+	}
+
+	log.Printf("Writing to file %s", filename)
+	f, err := createFile(filename)
+	if err != nil {
+		log.Printf("Error creating file %s: %v\n", filename, err)
+	}
+
+	return f, err
+}
+
+func ZwiftPower(ctx context.Context, clubID int, limit int) error {
+	riders, err := importClub(ctx, live, StoreDir, clubID, limit, func() (io.WriteCloser, error) {
+		f, err := setOutput(Filename, clubID)
+		if err != nil {
+			return nil, fmt.Errorf("opening file %s: %w", Filename, err)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// With a limit we haven't seen the whole roster, so can't tell who has joined or left
+	if limit == 0 {
+		store, err := openStore(StoreDir)
+		if err == nil {
+			err = recordRoster(store, clubID, riders)
+		}
+		if err != nil {
+			log.Printf("Not tracking who is in the club: %v", err)
+		}
+	}
+	return nil
+}
+
+// importClub gets the stats for every rider in the club, writing them to the output and
+// telling the hub's listeners about changes. It returns the riders it imported.
+func importClub(ctx context.Context, hub *liveHub, dir string, clubID int, limit int, output func() (io.WriteCloser, error)) ([]zp.Rider, error) {
+	client, err := newClientFor(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	riders, err := client.Club(ctx, clubID)
+	if err != nil {
+		return nil, fmt.Errorf("error in ImportZP: %w", err)
+	}
+	disambiguate(riders)
+	bus.publishUpdates(ctx, clubID, hub.rosterImported(riders))
+	indexNamesIn(dir, riders, nil)
+
+	progress := client.Progress
+	if imports != nil {
+		progress = imports.Progress(fmt.Sprintf("club %d", clubID))
+	}
+	total := len(riders)
+	if limit > 0 && limit < total {
+		total = limit
+	}
+
+	f, err := output()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			log.Printf("closing: %v", err)
+		}
+	}()
+
+	writer, err := NewRowWriter(f, Format, zp.RiderColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		log.Printf("About to flush")
+		writer.Flush()
+	}()
+
+	masks := storeMasks(dir)
+	for i, rider := range riders {
+		var err error
+		riders[i], err = client.ClubRider(ctx, rider)
+		if err != nil {
+			return nil, fmt.Errorf("loading data for %s (%d): %w", rider.Name, rider.Zwid, err)
+		}
+		if ZwiftToken != "" {
+			err = zp.AddZwiftProfile(ctx, client.HTTP, ZwiftToken, &riders[i])
+			if err != nil {
+				log.Printf("No Zwift profile for %s (%d): %v", rider.Name, rider.Zwid, err)
+			}
+		}
+		updates := hub.riderImported(riders[i])
+		bus.publish(ctx, clubID, zp.BusRiderImported, riders[i])
+		bus.publishUpdates(ctx, clubID, updates)
+		// fmt.Printf("%v\n", riders[i])
+		row := riders[i]
+		row.Name = masks.Name(row.Zwid, row.Name)
+		err = writer.WriteRow(row.StringsAsOf(reportTime()))
+		if err != nil {
+			return nil, fmt.Errorf("writing to file: %w", err)
+		}
+		if progress != nil {
+			progress(i+1, total, rider.Name)
+		}
+
+		if limit > 0 && i >= (limit-1) {
+			log.Printf("Limiting output to %d riders", limit)
+			riders = riders[:i+1]
+			break
+		}
+	}
+
+	return riders, nil
+}
+
+var handicapColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "time", Header: "Estimated time"},
+	{Key: "offset", Header: "Start offset"},
+}
+
+// HandicapRace writes out start offsets for a handicap race on this course. If no rider IDs
+// are given, it uses all the riders in the club.
+func HandicapRace(clubID int, riderIDs []int, course zp.Course) error {
+	profiles, err := powerProfiles(clubID, riderIDs)
+	if err != nil {
+		return err
+	}
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, handicapColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, h := range zp.Handicaps(course, profiles) {
+		err = writer.WriteRow([]string{
+			h.Name,
+			strconv.Itoa(h.Zwid),
+			formatDuration(h.EstimatedTime),
+			formatDuration(h.Offset),
+		})
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// powerProfiles gets the riders' power profiles from the last three months of data. If no
+// rider IDs are given, it uses all the riders in the club.
+func powerProfiles(clubID int, riderIDs []int) ([]zp.PowerProfile, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	names := make(map[int]string)
+	if len(riderIDs) == 0 {
+		riders, err := client.Club(context.Background(), clubID)
+		if err != nil {
+			return nil, fmt.Errorf("error in ImportZP: %w", err)
+		}
+		disambiguate(riders)
+		for _, r := range riders {
+			riderIDs = append(riderIDs, r.Zwid)
+			names[r.Zwid] = r.Name
+		}
+	}
+
+	since := time.Now().AddDate(0, 0, -90)
+	var profiles []zp.PowerProfile
+	for i, riderID := range riderIDs {
+		events, err := client.Events(context.Background(), riderID)
+		if err != nil {
+			return nil, fmt.Errorf("loading events for %d: %w", riderID, err)
+		}
+
+		p := zp.NewPowerProfile(riderID, events, since)
+		p.Name = names[riderID]
+		if p.Name == "" && len(events) > 0 {
+			p.Name = events[0].RiderName()
+		}
+		profiles = append(profiles, p)
+
+		if Limit > 0 && i >= (Limit-1) {
+			log.Printf("Limiting to %d riders", Limit)
+			break
+		}
+	}
+	return profiles, nil
+}
+
+// RaceReport writes a report on how the club did in this event
+func RaceReport(eventID int, clubID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	results, err := client.EventResults(context.Background(), eventID)
+	if err != nil {
+		return err
+	}
+	indexNames(nil, results)
+
+	var tmpl string
+	if TemplateFile != "" {
+		data, err := ioutil.ReadFile(TemplateFile)
+		if err != nil {
+			return fmt.Errorf("reading template: %w", err)
+		}
+		tmpl = string(data)
+	}
+
+	var w io.Writer = os.Stdout
+	if Filename != "" {
+		f, err := createFile(Filename)
+		if err != nil {
+			return fmt.Errorf("opening file %s: %w", Filename, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return zp.WriteRaceReport(w, zp.NewRaceReport(eventID, clubID, results), tmpl)
+}
+
+// RiderProfile writes out the rider's full profile as JSON, with their details merged
+// from the sources we have credentials for in this precedence
+func RiderProfile(riderID int, precedence zp.Precedence) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+	client.ZwiftToken = ZwiftToken
+	client.ZwiftRacingKey = ZwiftRacingKey
+	client.Precedence = precedence
+
+	profile, err := client.RiderProfile(context.Background(), riderID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if Filename != "" {
+		f, err := createFile(Filename)
+		if err != nil {
+			return fmt.Errorf("opening file %s: %w", Filename, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(profile)
+}
+
+// clubEvents gets the events for every rider in the club
+func clubEvents(client *zp.Client, clubID int) ([]zp.Event, error) {
+	riders, err := client.Club(context.Background(), clubID)
+	if err != nil {
+		return nil, fmt.Errorf("error in ImportZP: %w", err)
+	}
+
+	return rosterEvents(client, riders)
+}
+
+// rosterEvents gets the events for every rider on the roster
+func rosterEvents(client *zp.Client, riders []zp.Rider) ([]zp.Event, error) {
+	total := len(riders)
+	if Limit > 0 && Limit < total {
+		total = Limit
+	}
+
+	var events []zp.Event
+	for i, rider := range riders {
+		ee, err := client.Events(context.Background(), rider.Zwid)
+		if err != nil {
+			return nil, fmt.Errorf("loading events for %s (%d): %w", rider.Name, rider.Zwid, err)
+		}
+		events = append(events, ee...)
+		if client.Progress != nil {
+			client.Progress(i+1, total, rider.Name)
+		}
+
+		if Limit > 0 && i >= (Limit-1) {
+			log.Printf("Limiting to %d riders", Limit)
+			break
+		}
+	}
+
+	return events, nil
+}
+
+var seriesColumns = []zp.Column{
+	{Key: "series", Header: "Series"},
+	{Key: "riders", Header: "Riders"},
+	{Key: "results", Header: "Results"},
+	{Key: "wins", Header: "Wins"},
+	{Key: "podiums", Header: "Podiums"},
+	{Key: "position", Header: "Average position"},
+	{Key: "wkg", Header: "Average w/kg"},
+	{Key: "speed", Header: "Average km/h"},
+}
+
+// clubRiders gets the stats for every rider in the club
+func clubRiders(client *zp.Client, clubID int) ([]zp.Rider, error) {
+	roster, err := client.Club(context.Background(), clubID)
+	if err != nil {
+		return nil, fmt.Errorf("error in ImportZP: %w", err)
+	}
+	disambiguate(roster)
+
+	var riders []zp.Rider
+	for i, r := range roster {
+		rider, err := client.ClubRider(context.Background(), r)
+		if err != nil {
+			return nil, fmt.Errorf("loading data for %s (%d): %w", r.Name, r.Zwid, err)
+		}
+		riders = append(riders, rider)
+
+		if Limit > 0 && i >= (Limit-1) {
+			log.Printf("Limiting to %d riders", Limit)
+			break
+		}
+	}
+
+	return riders, nil
+}
+
+var ageGroupColumns = []zp.Column{
+	{Key: "agegroup", Header: "Age group"},
+	{Key: "rank", Header: "Rank"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "ftp90", Header: "FTP 90 days"},
+	{Key: "races90", Header: "Races 90 days"},
+}
+
+// AgeGroups writes out a leaderboard for each age group in the club
+func AgeGroups(clubID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	riders, err := clubRiders(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, ageGroupColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, board := range zp.AgeGroupLeaderboards(riders) {
+		for i, r := range board.Riders {
+			err = writer.WriteRow([]string{
+				board.AgeGroup,
+				strconv.Itoa(i + 1),
+				r.Name,
+				strconv.Itoa(r.Zwid),
+				strconv.FormatFloat(r.Ftp90, 'f', 1, 64),
+				strconv.Itoa(r.Races90),
+			})
+			if err != nil {
+				return fmt.Errorf("writing to file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// splitters group results for the series command, by the name of the --by flag
+var splitters = map[string]struct {
+	header string
+	split  func([]zp.Event, time.Time) []zp.SeriesStats
+}{
+	"series":   {"Series", zp.SplitBySeries},
+	"world":    {"World", zp.SplitByWorld},
+	"timeslot": {"Time slot", zp.SplitByTimeSlot},
+	"terrain":  {"Terrain", zp.SplitByTerrain},
+}
+
+// SeriesStats writes out a summary of the club's results in each series, world, time
+// slot or terrain
+func SeriesStats(clubID int, since time.Time, by string) error {
+	splitter, ok := splitters[by]
+	if !ok {
+		return fmt.Errorf("can't group results by %q: use series, world, timeslot or terrain", by)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := clubEvents(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	columns := append([]zp.Column(nil), seriesColumns...)
+	columns[0].Header = splitter.header
+	writer, err := NewRowWriter(f, Format, columns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, s := range splitter.split(events, since) {
+		err = writer.WriteRow([]string{
+			s.Series,
+			strconv.Itoa(s.Riders),
+			strconv.Itoa(s.Results),
+			strconv.Itoa(s.Wins),
+			strconv.Itoa(s.Podiums),
+			strconv.FormatFloat(s.AvgPosition, 'f', 1, 64),
+			strconv.FormatFloat(s.AvgWkg, 'f', 1, 64),
+			strconv.FormatFloat(s.AvgSpeed, 'f', 1, 64),
+		})
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+var loadColumns = []zp.Column{
+	{Key: "date", Header: "Date"},
+	{Key: "event", Header: "Event"},
+	{Key: "duration", Header: "Duration"},
+	{Key: "np", Header: "NP"},
+	{Key: "if", Header: "IF"},
+	{Key: "tss", Header: "TSS"},
+	{Key: "fitness", Header: "Fitness"},
+	{Key: "fatigue", Header: "Fatigue"},
+	{Key: "form", Header: "Form"},
+}
+
+var genderColumns = []zp.Column{
+	{Key: "gender", Header: "Gender"},
+	{Key: "riders", Header: "Riders"},
+	{Key: "active", Header: "Active 90 days"},
+	{Key: "racing", Header: "Racing 90 days"},
+	{Key: "races30", Header: "Races 30 days"},
+	{Key: "races90", Header: "Races 90 days"},
+	{Key: "a", Header: "A"},
+	{Key: "b", Header: "B"},
+	{Key: "c", Header: "C"},
+	{Key: "d", Header: "D"},
+	{Key: "e", Header: "E"},
+}
+
+// Genders writes out the club's stats for each gender, with the number of riders
+// whose latest race was in each category
+func Genders(clubID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	riders, err := clubRiders(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, genderColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, s := range zp.GenderSplit(riders, reportTime()) {
+		row := []string{
+			s.Gender.String(),
+			strconv.Itoa(s.Riders),
+			strconv.Itoa(s.Active),
+			strconv.Itoa(s.Racing),
+			strconv.Itoa(s.Races30),
+			strconv.Itoa(s.Races90),
+		}
+		for _, c := range []string{"A", "B", "C", "D", "E"} {
+			row = append(row, strconv.Itoa(s.Categories[c]))
+		}
+
+		err = writer.WriteRow(row)
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+var categoryColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "category", Header: "Category"},
+	{Key: "since", Header: "Since"},
+	{Key: "days", Header: "Days in category"},
+	{Key: "changes", Header: "Changes"},
+	{Key: "ftp60", Header: "FTP 60 days"},
+	{Key: "toup", Header: "W/kg to move up"},
+	{Key: "abovedown", Header: "W/kg above category"},
+	{Key: "nearupgrade", Header: "Near upgrade"},
+}
+
+// Categories writes out each rider's current category, how long they've held it, and
+// how close they are to the category boundaries
+func Categories(clubID int, margin float64) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := clubEvents(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	byRider := make(map[int][]zp.Event)
+	var ids []int
+	for _, e := range events {
+		if _, ok := byRider[e.Zwid]; !ok {
+			ids = append(ids, e.Zwid)
+		}
+		byRider[e.Zwid] = append(byRider[e.Zwid], e)
+	}
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, categoryColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	now := reportTime()
+	for _, id := range ids {
+		h := zp.CategoryHistoryAsOf(id, byRider[id], now)
+		if h.Current == "" {
+			continue
+		}
+
+		near := ""
+		if h.NearUpgrade(margin) {
+			near = "yes"
+		}
+		err = writer.WriteRow([]string{
+			h.Name,
+			strconv.Itoa(h.Zwid),
+			h.Current,
+			h.Since.Format("2006-01-02"),
+			strconv.Itoa(int(h.Held(now).Hours() / 24)),
+			strconv.Itoa(len(h.Changes)),
+			strconv.FormatFloat(h.Ftp60, 'f', 1, 64),
+			strconv.FormatFloat(h.ToUp, 'f', 2, 64),
+			strconv.FormatFloat(h.AboveDown, 'f', 2, 64),
+			near,
+		})
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+var recentColumns = []zp.Column{
+	{Key: "date", Header: "Date"},
+	{Key: "start", Header: "Start"},
+	{Key: "event", Header: "Event"},
+	{Key: "zid", Header: "Event ID"},
+	{Key: "world", Header: "World"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "category", Header: "Category"},
+	{Key: "position", Header: "Position"},
+	{Key: "positionincat", Header: "Position in category"},
+	{Key: "time", Header: "Time"},
+}
+
+// RecentResults writes out a row for each club result since this time, grouped by event
+func RecentResults(clubID int, since time.Time) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := zp.ClubRecentResults(client.HTTP, clubID, since)
+	if err != nil {
+		return err
+	}
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, recentColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, ev := range events {
+		for _, e := range ev.Results {
+			err = writer.WriteRow([]string{
+				ev.Date.Format("2006-01-02"),
+				e.StartTime(),
+				ev.Title,
+				ev.Zid,
+				e.World(),
+				e.RiderName(),
+				strconv.Itoa(e.Zwid),
+				e.Category,
+				strconv.Itoa(e.Pos),
+				strconv.Itoa(e.PositionInCat),
+				formatDuration(time.Duration(float64(e.Time) * float64(time.Second))),
+			})
+			if err != nil {
+				return fmt.Errorf("writing to file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Snapshot stores the club's riders as they are today, named for the club and date
+func Snapshot(clubID int) error {
+	var store zp.Store
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	riders, err := clubRiders(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	name := zp.SnapshotName(clubID, time.Now())
+	log.Printf("Saving snapshot %s of %d riders", name, len(riders))
+	return store.PutSnapshot(name, riders)
+}
+
+// ClubDigest writes a round-up of the club's results over the last few days
+func ClubDigest(clubID int, days int, fun bool, templateFile string) error {
+	var w io.Writer = os.Stdout
+	if Filename != "" {
+		f, err := createFile(Filename)
+		if err != nil {
+			return fmt.Errorf("opening file %s: %w", Filename, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return writeClubDigest(w, StoreDir, clubID, days, fun, templateFile)
+}
+
+// writeClubDigest renders the digest for the club's last few days of results, with
+// masks, anniversaries and goals from the store in dir
+func writeClubDigest(w io.Writer, dir string, clubID int, days int, fun bool, templateFile string) error {
+	client, err := newClientFor(dir)
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := clubEvents(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	var tmpl string
+	if templateFile != "" {
+		data, err := ioutil.ReadFile(templateFile)
+		if err != nil {
+			return fmt.Errorf("reading template: %w", err)
+		}
+		tmpl = string(data)
+	}
+
+	now := reportTime()
+	masks := storeMasks(dir)
+	d := zp.NewDigest(clubID, masks.MaskEvents(events), now.AddDate(0, 0, -days), now, fun)
+	store, err := existingStore(dir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	anniversaries, err := clubAnniversaries(store, clubID, d.Since, now)
+	if err != nil {
+		return err
+	}
+	d.Milestones = append(d.Milestones, anniversaries...)
+	d.Goals, err = clubGoals(store, events, now)
+	if err != nil {
+		return err
+	}
+	for i, g := range d.Goals {
+		d.Goals[i].Name = masks.Name(g.Zwid, g.Name)
+	}
+	return zp.WriteDigest(w, d, tmpl)
+}
+
+// clubGoals tracks any goals in the store for riders who have events here. With no
+// store there are none.
+func clubGoals(store *zp.FileStore, events []zp.Event, now time.Time) ([]zp.GoalProgress, error) {
+	if store == nil {
+		return nil, nil
+	}
+	goals, err := store.Goals()
+	if err != nil {
+		return nil, fmt.Errorf("reading goals: %w", err)
+	}
+
+	riders := make(map[int]bool)
+	for _, e := range events {
+		riders[e.Zwid] = true
+	}
+	var club []zp.Goal
+	for _, g := range goals {
+		if riders[g.Zwid] {
+			club = append(club, g)
+		}
+	}
+	return zp.TrackGoals(club, events, now), nil
+}
+
+// TrainingLoad writes out the load for each of the rider's events, along with their
+// rolling fitness, fatigue and form
+func TrainingLoad(riderID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := client.Events(context.Background(), riderID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].EventDate.Before(events[j].EventDate)
+	})
+	loads := zp.RollingLoad(events, time.Now())
+
+	f, err := setOutput(Filename, 0)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, loadColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, e := range events {
+		l, ok := e.Load()
+		if !ok {
+			continue
+		}
+
+		tl := zp.LoadOn(loads, e.EventDate)
+		err = writer.WriteRow([]string{
+			e.EventDate.Format("2006-01-02"),
+			e.EventTitle,
+			formatDuration(l.Duration),
+			strconv.FormatFloat(l.NP, 'f', 0, 64),
+			strconv.FormatFloat(l.IF, 'f', 2, 64),
+			strconv.FormatFloat(l.TSS, 'f', 0, 64),
+			strconv.FormatFloat(tl.Fitness, 'f', 0, 64),
+			strconv.FormatFloat(tl.Fatigue, 'f', 0, 64),
+			strconv.FormatFloat(tl.Form, 'f', 0, 64),
+		})
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// disambiguate makes sure riders' names are unique, warning about any that weren't
+func disambiguate(riders []zp.Rider) {
+	for _, c := range zp.DisambiguateNames(riders, disambiguation) {
+		log.Printf("Warning: %v", c)
+	}
+}
+
+// formatDuration gives a duration as h:mm:ss
+func formatDuration(d time.Duration) string {
+	secs := int(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", secs/3600, (secs/60)%60, secs%60)
+}
+
+func HelloZP(w http.ResponseWriter, r *http.Request) {
+	clubID := ServeClubID
+
+	// Carry on the platform's trace, so the import shows up underneath the request
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := otel.Tracer("github.com/lizrice/zwiftpower/v2").Start(ctx, "HelloZP",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.Int("zwiftpower.club_id", clubID)))
+	defer span.End()
+
+	err := ZwiftPower(ctx, clubID, Limit)
+	if err != nil {
+		span.RecordError(err)
+		fmt.Fprintf(os.Stderr, "Error getting ZwiftPower data for %d: %v", clubID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("%v", err)))
+	}
+
+	fmt.Fprintf(w, "Reading data for %d\n", clubID)
+}
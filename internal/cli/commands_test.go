@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+func TestSharedPacer(t *testing.T) {
+	// The server creates clients from several goroutines at once
+	pacers := make([]*zp.Pacer, 10)
+	var wg sync.WaitGroup
+	for i := range pacers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pacers[i] = sharedPacer()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, p := range pacers {
+		if p == nil || p != pacers[0] {
+			t.Fatalf("Got different pacers %v", pacers)
+		}
+	}
+}
+
+func TestNewClientConcurrently(t *testing.T) {
+	oldDir := StoreDir
+	StoreDir = t.TempDir()
+	defer func() { StoreDir = oldDir }()
+
+	watches := make([]*zp.SchemaWatch, 10)
+	var wg sync.WaitGroup
+	for i := range watches {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := newClient(); err != nil {
+				t.Error(err)
+			}
+			watches[i] = schemaWatch()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, w := range watches {
+		if w == nil || w != watches[0] {
+			t.Fatalf("Got different schema watches %v", watches)
+		}
+	}
+}
@@ -0,0 +1,513 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// dashboard serves a few simple pages about the club, for people who would rather click
+// around than read a spreadsheet
+type dashboard struct {
+	clubID    int
+	dir       string
+	store     zp.Store // May be nil if there's no store
+	live      *liveHub
+	base      string
+	templates *template.Template
+}
+
+// newDashboard makes a dashboard for the club. Its links start with base, so that it
+// can be served somewhere other than /dashboard/.
+func newDashboard(clubID int, storeDir string, hub *liveHub, base string) *dashboard {
+	d := &dashboard{
+		clubID: clubID,
+		dir:    storeDir,
+		live:   hub,
+		base:   base,
+		templates: template.Must(dashboardTemplates.Clone()).Funcs(template.FuncMap{
+			"base": func() string { return base },
+		}),
+	}
+	store, err := openStore(storeDir)
+	if err != nil {
+		log.Printf("Dashboard running without a store: %v", err)
+	} else {
+		d.store = store
+	}
+	return d
+}
+
+func (d *dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/dashboard"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "":
+		d.serveRoster(w, r)
+	case path == "heatmap.json":
+		d.serveHeatmap(w, r)
+	case len(parts) == 2 && parts[0] == "riders":
+		d.serveID(w, r, parts[1], d.serveRider)
+	case len(parts) == 2 && parts[0] == "results":
+		d.serveID(w, r, parts[1], d.serveResults)
+	case len(parts) == 2 && parts[0] == "avatars":
+		d.serveID(w, r, parts[1], d.serveAvatar)
+	case len(parts) == 3 && parts[0] == "riders" && parts[2] == "opponents.json":
+		d.serveID(w, r, parts[1], d.serveOpponents)
+	case len(parts) == 3 && parts[0] == "results" && parts[2] == "podium.png":
+		d.serveID(w, r, parts[1], d.servePodium)
+	case len(parts) == 3 && (parts[0] == "riders" || parts[0] == "results") && (parts[2] == "card.json" || parts[2] == "card.png"):
+		d.serveID(w, r, parts[1], func(w http.ResponseWriter, r *http.Request, id int) {
+			d.serveCard(w, r, parts[0], id, parts[2])
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (d *dashboard) serveID(w http.ResponseWriter, r *http.Request, s string, serve func(http.ResponseWriter, *http.Request, int)) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	serve(w, r, id)
+}
+
+func (d *dashboard) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := d.templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		log.Printf("rendering %s: %v", name, err)
+	}
+}
+
+// serveRoster lists the riders from the latest import, or from the store if the
+// server hasn't imported anything yet
+func (d *dashboard) serveRoster(w http.ResponseWriter, r *http.Request) {
+	riders := d.live.clubRiders()
+	if len(riders) == 0 && d.store != nil {
+		var err error
+		riders, err = d.store.QueryRiders(zp.RiderQuery{})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading riders: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	d.render(w, "roster", struct {
+		ClubID int
+		Riders []zp.Rider
+	}{d.clubID, riders})
+}
+
+// riderEvents gets the rider's stats and events, from the store if we have them, or
+// straight from ZwiftPower if not
+func (d *dashboard) riderEvents(r *http.Request, riderID int) (zp.Rider, []zp.Event, error) {
+	var events []zp.Event
+	var err error
+	if d.store != nil {
+		events, err = d.store.Events(riderID)
+	}
+	if d.store == nil || err != nil {
+		var client *zp.Client
+		client, err = newClientFor(d.dir)
+		if err == nil {
+			events, err = client.Events(r.Context(), riderID)
+		}
+	}
+	if err != nil {
+		return zp.Rider{}, nil, err
+	}
+
+	rider := zp.RiderFromEventsAggregated(riderID, events, time.Now(), ftpAggregation)
+	for _, c := range d.live.clubRiders() {
+		if c.Zwid == riderID {
+			rider.Name = c.Name
+		}
+	}
+	if rider.Name == "" && len(events) > 0 {
+		rider.Name = events[0].RiderName()
+	}
+	return rider, events, nil
+}
+
+// serveRider shows the rider's stats and event history
+func (d *dashboard) serveRider(w http.ResponseWriter, r *http.Request, riderID int) {
+	rider, events, err := d.riderEvents(r, riderID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("getting events for %d: %v", riderID, err), http.StatusBadGateway)
+		return
+	}
+
+	// Latest first
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventDate.After(events[j].EventDate)
+	})
+
+	d.render(w, "rider", struct {
+		Rider  zp.Rider
+		Events []zp.Event
+		Card   zp.Card
+	}{rider, events, d.card(r, zp.RiderCard(rider), "riders", riderID)})
+}
+
+// serveAvatar redirects to the rider's profile picture if we know it, and otherwise
+// draws their initials
+func (d *dashboard) serveAvatar(w http.ResponseWriter, r *http.Request, riderID int) {
+	var rider zp.Rider
+	for _, c := range d.live.clubRiders() {
+		if c.Zwid == riderID {
+			rider = c
+		}
+	}
+	if rider.Zwid == 0 && d.store != nil {
+		rider, _ = d.store.Rider(riderID)
+	}
+
+	if rider.Avatar != "" {
+		http.Redirect(w, r, rider.Avatar, http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "max-age=3600")
+	w.Write(zp.InitialsAvatar(rider.Name))
+}
+
+// serveResults shows the results for an event, picking out the club's riders
+func (d *dashboard) serveResults(w http.ResponseWriter, r *http.Request, eventID int) {
+	client, err := newClientFor(d.dir)
+	if err == nil {
+		var results []zp.Event
+		results, err = client.EventResults(r.Context(), eventID)
+		if err == nil {
+			var title string
+			if len(results) > 0 {
+				title = results[0].EventTitle
+			}
+
+			clubID := strconv.Itoa(d.clubID)
+			d.render(w, "results", struct {
+				EventID int
+				Title   string
+				ClubID  string
+				Results []zp.Event
+				Card    zp.Card
+			}{eventID, title, clubID, results, d.card(r, zp.EventCard(eventID, results, clubID), "results", eventID)})
+			return
+		}
+	}
+
+	http.Error(w, fmt.Sprintf("getting results for %d: %v", eventID, err), http.StatusBadGateway)
+}
+
+// card fills in the links for a rider or event card. Link previews need absolute URLs,
+// so these come from the host the request was made to.
+func (d *dashboard) card(r *http.Request, c zp.Card, kind string, id int) zp.Card {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	page := fmt.Sprintf("%s://%s%s/dashboard/%s/%d", scheme, r.Host, d.base, kind, id)
+	c.URL = page
+	c.Image = page + "/card.png"
+	return c
+}
+
+// serveCard serves the card for a rider or event as JSON, or as a PNG for link previews
+func (d *dashboard) serveCard(w http.ResponseWriter, r *http.Request, kind string, id int, file string) {
+	var c zp.Card
+	if kind == "riders" {
+		rider, _, err := d.riderEvents(r, id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("getting events for %d: %v", id, err), http.StatusBadGateway)
+			return
+		}
+		c = zp.RiderCard(rider)
+	} else {
+		client, err := newClientFor(d.dir)
+		var results []zp.Event
+		if err == nil {
+			results, err = client.EventResults(r.Context(), id)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("getting results for %d: %v", id, err), http.StatusBadGateway)
+			return
+		}
+		c = zp.EventCard(id, results, strconv.Itoa(d.clubID))
+	}
+	c = d.card(r, c, kind, id)
+
+	w.Header().Set("Cache-Control", "max-age=3600")
+	if file == "card.png" {
+		w.Header().Set("Content-Type", "image/png")
+		err := writeCardPNG(w, c)
+		if err != nil {
+			log.Printf("drawing card for %s %d: %v", kind, id, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(c)
+	if err != nil {
+		log.Printf("writing card for %s %d: %v", kind, id, err)
+	}
+}
+
+// serveHeatmap gives the hours of the week the club's riders raced in, from the store,
+// as JSON for drawing a heatmap. The days parameter says how far back to go.
+// serveOpponents gives the rider's victims and rivals from ZwiftPower as JSON. With
+// ?club=true, only the club's riders are included.
+func (d *dashboard) serveOpponents(w http.ResponseWriter, r *http.Request, riderID int) {
+	client, err := newClientFor(d.dir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("getting client: %v", err), http.StatusInternalServerError)
+		return
+	}
+	o, err := client.Opponents(r.Context(), riderID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("getting opponents for %d: %v", riderID, err), http.StatusBadGateway)
+		return
+	}
+
+	if club, _ := strconv.ParseBool(r.URL.Query().Get("club")); club {
+		riders := d.live.clubRiders()
+		if len(riders) == 0 && d.store != nil {
+			riders, err = d.store.QueryRiders(zp.RiderQuery{})
+			if err != nil {
+				http.Error(w, fmt.Sprintf("reading riders: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
+		o = o.Among(riders)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "max-age=3600")
+	if err := json.NewEncoder(w).Encode(o); err != nil {
+		log.Printf("writing opponents: %v", err)
+	}
+}
+
+func (d *dashboard) serveHeatmap(w http.ResponseWriter, r *http.Request) {
+	if d.store == nil {
+		http.Error(w, "no store to read events from", http.StatusServiceUnavailable)
+		return
+	}
+
+	days := 90
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("bad number of days %q", s), http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	riders := d.live.clubRiders()
+	if len(riders) == 0 {
+		var err error
+		riders, err = d.store.QueryRiders(zp.RiderQuery{})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading riders: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var events []zp.Event
+	for _, rider := range riders {
+		ee, err := d.store.Events(rider.Zwid)
+		if err != nil && !zp.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("reading events for %d: %v", rider.Zwid, err), http.StatusInternalServerError)
+			return
+		}
+		events = append(events, ee...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "max-age=3600")
+	err := json.NewEncoder(w).Encode(zp.RaceTimes(events, time.Now().AddDate(0, 0, -days)))
+	if err != nil {
+		log.Printf("writing heatmap: %v", err)
+	}
+}
+
+// servePodium draws the club's podium for an event, to download and share
+func (d *dashboard) servePodium(w http.ResponseWriter, r *http.Request, eventID int) {
+	client, err := newClientFor(d.dir)
+	var results []zp.Event
+	if err == nil {
+		results, err = client.EventResults(r.Context(), eventID)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("getting results for %d: %v", eventID, err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "max-age=3600")
+	err = writePodiumPNG(w, zp.NewPodium(eventID, results, strconv.Itoa(d.clubID)))
+	if err != nil {
+		log.Printf("drawing podium for %d: %v", eventID, err)
+	}
+}
+
+var dashboardTemplates = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"date": func(e zp.Event) string {
+		if e.EventDateSecs == 0 {
+			return ""
+		}
+		return e.EventDate.Format("2006-01-02")
+	},
+	"wkg": func(v interface{}) string {
+		if a, ok := v.([]interface{}); ok && len(a) > 0 {
+			return fmt.Sprint(a[0])
+		}
+		return ""
+	},
+	"duration": func(secs zp.Number) string {
+		if secs <= 0 {
+			return ""
+		}
+		return formatDuration(time.Duration(float64(secs) * float64(time.Second)))
+	},
+	"unescape": func(s string) string {
+		return zp.Event{Name: s}.RiderName()
+	},
+	"base": func() string { return "" },
+	"page": func(title string, card ...zp.Card) interface{} {
+		p := struct {
+			Title string
+			Card  *zp.Card
+		}{Title: title}
+		if len(card) > 0 {
+			p.Card = &card[0]
+		}
+		return p
+	},
+}).Parse(dashboardHTML))
+
+const dashboardHTML = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{- with .Card}}
+<meta property="og:type" content="website">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+<meta property="og:image" content="{{.Image}}">
+<meta property="og:image:width" content="600">
+<meta property="og:image:height" content="315">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="description" content="{{.Description}}">
+<link rel="alternate" type="application/json" href="{{.URL}}/card.json">
+<script type="application/ld+json">{{.JSONLD}}</script>
+{{- end}}
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+th { cursor: pointer; background: #f4f4f4; }
+img.avatar { width: 1.6em; height: 1.6em; border-radius: 50%; vertical-align: middle; margin-right: 0.4em; }
+tr.club { font-weight: bold; }
+#news { display: none; background: #ffd; padding: 0.5em; margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<div id="news">There are new results. <a href="">Reload</a></div>
+<p><a href="{{base}}/dashboard/">Club roster</a></p>
+<h1>{{.Title}}</h1>
+{{end}}
+
+{{define "footer"}}
+<script>
+// Click a column heading to sort by it, click again to reverse
+document.querySelectorAll("th").forEach(function (th, col) {
+  th.addEventListener("click", function () {
+    var tbody = th.closest("table").querySelector("tbody");
+    var rows = Array.from(tbody.rows);
+    var dir = th.dataset.dir === "asc" ? -1 : 1;
+    th.dataset.dir = dir === 1 ? "asc" : "desc";
+    rows.sort(function (a, b) {
+      var x = a.cells[col].innerText, y = b.cells[col].innerText;
+      var nx = parseFloat(x), ny = parseFloat(y);
+      if (!isNaN(nx) && !isNaN(ny)) return dir * (nx - ny);
+      return dir * x.localeCompare(y);
+    });
+    rows.forEach(function (r) { tbody.appendChild(r); });
+  });
+});
+
+// Let people know when the server has imported something new
+if (window.EventSource) {
+  var source = new EventSource("{{base}}/events");
+  ["joined", "left", "result"].forEach(function (type) {
+    source.addEventListener(type, function () {
+      document.getElementById("news").style.display = "block";
+    });
+  });
+}
+</script>
+</body>
+</html>
+{{end}}
+
+{{define "roster"}}{{template "header" (page "Club riders")}}
+{{if not .Riders}}<p>No riders yet. They appear here once the server has imported the club, or once they have been backfilled into the store.</p>{{end}}
+<table>
+<thead><tr><th>Name</th><th>Country</th><th>Category</th><th>FTP 90 days</th><th>Races 30 days</th><th>Races 90 days</th><th>Latest event</th><th>When</th></tr></thead>
+<tbody>
+{{- range .Riders}}
+<tr><td><img class="avatar" src="{{base}}/dashboard/avatars/{{.Zwid}}" alt="" loading="lazy"><a href="{{base}}/dashboard/riders/{{.Zwid}}">{{unescape .Name}}</a></td><td>{{.Country}}</td><td>{{.Category}}</td><td>{{printf "%.1f" .Ftp90}}</td><td>{{.Races30}}</td><td>{{.Races90}}</td><td>{{.LatestEvent}}</td><td>{{if not .LatestEventDate.IsZero}}{{.LatestEventDate.Format "2006-01-02"}}{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{template "footer"}}{{end}}
+
+{{define "rider"}}{{template "header" (page (unescape .Rider.Name) .Card)}}
+<p>
+<img class="avatar" src="{{base}}/dashboard/avatars/{{.Rider.Zwid}}" alt="">
+<a href="https://www.zwiftpower.com/profile.php?z={{.Rider.Zwid}}">ZwiftPower profile</a> &middot;
+FTP {{printf "%.1f" .Rider.Ftp30}} W/kg (30 days), {{printf "%.1f" .Rider.Ftp90}} W/kg (90 days) &middot;
+{{.Rider.Races30}} races in 30 days, {{.Rider.Races90}} in 90 days
+{{- with .Rider.Provenance}} &middot; {{.Source}} data as of {{.AsOf.UTC.Format "2006-01-02 15:04"}} UTC{{end}}
+</p>
+<table>
+<thead><tr><th>Date</th><th>Event</th><th>Category</th><th>Position</th><th>In category</th><th>Time</th><th>Avg W/kg</th></tr></thead>
+<tbody>
+{{- range .Events}}
+<tr><td>{{date .}}</td><td><a href="{{base}}/dashboard/results/{{.Zid}}">{{.EventTitle}}</a></td><td>{{.Category}}</td><td>{{.Pos}}</td><td>{{.PositionInCat}}</td><td>{{duration .Time}}</td><td>{{wkg .AvgWkg}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{template "footer"}}{{end}}
+
+{{define "results"}}{{template "header" (page (or .Title (printf "Event %d" .EventID)) .Card)}}
+<p><a href="https://www.zwiftpower.com/events.php?zid={{.EventID}}">Results on ZwiftPower</a> &middot; <a href="{{base}}/dashboard/results/{{.EventID}}/podium.png" download>Club podium</a> &middot; club riders in bold</p>
+<table>
+<thead><tr><th>Position</th><th>Category</th><th>In category</th><th>Name</th><th>Team</th><th>Time</th><th>Avg W/kg</th></tr></thead>
+<tbody>
+{{- $club := .ClubID}}
+{{- range .Results}}
+<tr{{if eq .TeamID $club}} class="club"{{end}}><td>{{.Pos}}</td><td>{{.Category}}</td><td>{{.PositionInCat}}</td><td><a href="{{base}}/dashboard/riders/{{.Zwid}}">{{.RiderName}}</a></td><td>{{unescape .TeamName}}</td><td>{{duration .Time}}</td><td>{{wkg .AvgWkg}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{template "footer"}}{{end}}
+`
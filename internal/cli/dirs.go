@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// appName names the directories we keep under the OS's config, data and cache directories
+const appName = "zwiftpower"
+
+// userDataDir is the OS's place for a user's application data: $XDG_DATA_HOME or
+// ~/.local/share on Linux, ~/Library/Application Support on macOS, and %AppData% on
+// Windows. Go only knows the config and cache directories, and on macOS and Windows the
+// config directory is the data directory too.
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		return os.UserConfigDir()
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// defaultDir picks a directory from the environment variable if it's set, then the old
+// default in the working directory if it's already there, so that existing setups carry
+// on as they were, and otherwise our directory under the OS's one. If the OS's directory
+// isn't known, as when there's no home directory, it's the old default after all.
+func defaultDir(env, old string, osDir func() (string, error), elem ...string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	if fi, err := os.Stat(old); err == nil && fi.IsDir() {
+		return old
+	}
+	dir, err := osDir()
+	if err != nil || dir == "" {
+		return old
+	}
+	return filepath.Join(append([]string{dir, appName}, elem...)...)
+}
+
+// storeDir is where the store is kept by default
+func storeDir() string {
+	return defaultDir("ZP_STORE", "zpdata", userDataDir)
+}
+
+// mirrorDir is where mirror keeps its files by default. They can be fetched again, so
+// they're in the cache directory.
+func mirrorDir() string {
+	return defaultDir("ZP_MIRROR_DIR", "zpmirror", os.UserCacheDir, "mirror")
+}
+
+// configDir is where the policy, routes and tenants files are looked for if they aren't
+// given
+func configDir() string {
+	if dir := os.Getenv("ZP_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, appName)
+}
+
+// configFile is the file from the environment variable if it's set, or otherwise the
+// file with this name in the config directory if there is one
+func configFile(env, name string) string {
+	if file := os.Getenv(env); file != "" {
+		return file
+	}
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	file := filepath.Join(dir, name)
+	if _, err := os.Stat(file); err != nil {
+		return ""
+	}
+	return file
+}
+
+// configFileNames are the files looked for in the config directory
+const configFileNames = "policy.json, routes.json and tenants.json"
+
+// dirsCommand shows where everything is kept, which is different on each OS
+func dirsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dirs",
+		Short: "Show the directories used for the store, the mirror and config files",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			rows := [][]string{
+				{"store", StoreDir, "--store or ZP_STORE"},
+				{"mirror", mirrorDir(), "--dir or ZP_MIRROR_DIR"},
+				{"config", configDir(), "ZP_CONFIG_DIR, for " + configFileNames},
+			}
+			exitOnError(writeRows(0, dirColumns, len(rows), func(i int) []string {
+				return rows[i]
+			}), "writing directories")
+		},
+	}
+}
+
+var dirColumns = []zp.Column{
+	{Key: "dir", Header: "Directory"},
+	{Key: "path", Header: "Path"},
+	{Key: "override", Header: "Override with"},
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// setenv sets the environment variable for the rest of the test, or unsets it if value is
+// empty, putting back what was there before when the test ends
+func setenv(t *testing.T, name, value string) {
+	old, had := os.LookupEnv(name)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+	if value == "" {
+		os.Unsetenv(name)
+	} else {
+		os.Setenv(name, value)
+	}
+}
+
+// chdir changes to the directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(old) })
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("The OS's directories are only the XDG ones on Linux")
+	}
+
+	home := t.TempDir()
+	setenv(t, "HOME", home)
+	for _, env := range []string{"XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_CONFIG_HOME", "ZP_STORE", "ZP_MIRROR_DIR", "ZP_CONFIG_DIR"} {
+		setenv(t, env, "")
+	}
+	work := t.TempDir()
+	chdir(t, work)
+
+	check := func(what, got, expected string) {
+		t.Helper()
+		if got != expected {
+			t.Errorf("%s: got %q, expected %q", what, got, expected)
+		}
+	}
+
+	check("store", storeDir(), filepath.Join(home, ".local", "share", "zwiftpower"))
+	check("mirror", mirrorDir(), filepath.Join(home, ".cache", "zwiftpower", "mirror"))
+	check("config", configDir(), filepath.Join(home, ".config", "zwiftpower"))
+
+	setenv(t, "XDG_DATA_HOME", "/xdg/data")
+	setenv(t, "XDG_CACHE_HOME", "/xdg/cache")
+	setenv(t, "XDG_CONFIG_HOME", "/xdg/config")
+	check("XDG store", storeDir(), "/xdg/data/zwiftpower")
+	check("XDG mirror", mirrorDir(), "/xdg/cache/zwiftpower/mirror")
+	check("XDG config", configDir(), "/xdg/config/zwiftpower")
+
+	// Existing directories in the working directory are used as they were
+	must(t, os.Mkdir(filepath.Join(work, "zpdata"), 0755))
+	must(t, ioutil.WriteFile(filepath.Join(work, "zpmirror"), nil, 0644))
+	check("old store", storeDir(), "zpdata")
+	check("mirror with a file in the way", mirrorDir(), "/xdg/cache/zwiftpower/mirror")
+
+	setenv(t, "ZP_STORE", "/my/store")
+	setenv(t, "ZP_MIRROR_DIR", "/my/mirror")
+	setenv(t, "ZP_CONFIG_DIR", "/my/config")
+	check("ZP_STORE", storeDir(), "/my/store")
+	check("ZP_MIRROR_DIR", mirrorDir(), "/my/mirror")
+	check("ZP_CONFIG_DIR", configDir(), "/my/config")
+
+	// Without a home directory, it's the old defaults after all
+	for _, env := range []string{"HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_CONFIG_HOME", "ZP_STORE", "ZP_MIRROR_DIR", "ZP_CONFIG_DIR"} {
+		setenv(t, env, "")
+	}
+	must(t, os.Remove(filepath.Join(work, "zpdata")))
+	check("store without a home", storeDir(), "zpdata")
+	check("mirror without a home", mirrorDir(), "zpmirror")
+	check("config without a home", configDir(), "")
+}
+
+func TestConfigFile(t *testing.T) {
+	config := t.TempDir()
+	setenv(t, "ZP_CONFIG_DIR", config)
+	setenv(t, "ZP_POLICY", "")
+
+	if f := configFile("ZP_POLICY", "policy.json"); f != "" {
+		t.Errorf("Got %q without a policy file, expected none", f)
+	}
+
+	path := filepath.Join(config, "policy.json")
+	must(t, ioutil.WriteFile(path, []byte("{}"), 0644))
+	if f := configFile("ZP_POLICY", "policy.json"); f != path {
+		t.Errorf("Got %q, expected %q", f, path)
+	}
+
+	setenv(t, "ZP_POLICY", "/elsewhere/policy.json")
+	if f := configFile("ZP_POLICY", "policy.json"); f != "/elsewhere/policy.json" {
+		t.Errorf("Got %q, expected the file from ZP_POLICY", f)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// DryRun means logging what would be written, uploaded, posted or stored, rather than
+// doing it. Data is still read from ZwiftPower and the store as usual.
+var DryRun bool
+
+// dryRunWriter stands in for a file, bucket or spreadsheet in a dry run, counting what
+// would have been written to it
+type dryRunWriter struct {
+	dest  string
+	bytes int
+	lines int
+}
+
+func (w *dryRunWriter) Write(p []byte) (int, error) {
+	w.bytes += len(p)
+	w.lines += bytes.Count(p, []byte("\n"))
+	return len(p), nil
+}
+
+func (w *dryRunWriter) Close() error {
+	log.Printf("Dry run: would write %d lines (%d bytes) to %s", w.lines, w.bytes, w.dest)
+	return nil
+}
+
+// createFile creates the file, or in a dry run something that only pretends to
+func createFile(filename string) (io.WriteCloser, error) {
+	if DryRun {
+		return &dryRunWriter{dest: filename}, nil
+	}
+	return os.Create(filename)
+}
+
+// openStore opens the store in this directory, which only logs its writes in a dry run
+func openStore(dir string) (*zp.FileStore, error) {
+	store, err := zp.NewFileStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	store.DryRun = DryRun
+	return store, nil
+}
+
+// existingStore opens the store in this directory if there is one, or returns nil if
+// not, for commands that can do without
+func existingStore(dir string) (*zp.FileStore, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return openStore(dir)
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var efficiencyColumns = []zp.Column{
+	{Key: "date", Header: "Date"},
+	{Key: "event", Header: "Event"},
+	{Key: "zid", Header: "Event ID"},
+	{Key: "power", Header: "Power"},
+	{Key: "hr", Header: "Avg HR"},
+	{Key: "ef", Header: "Efficiency"},
+	{Key: "trend", Header: "Trend"},
+}
+
+func efficiencyCommand() *cobra.Command {
+	var days int
+
+	efficiencyCmd := &cobra.Command{
+		Use:   "efficiency [rider ID]",
+		Short: "Aerobic efficiency (power per heartbeat) in each of a rider's events with heart rate, and its trend",
+		Long: `Efficiency is NP (or average power) divided by average heart rate, for events of at
+least 20 minutes. The trend averages it over the previous 42 days. A rising trend means
+more power for the same effort, which is a sign of fitness that doesn't need an FTP test.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 0)
+			exitOnError(AerobicEfficiency(riderID, days), fmt.Sprintf("getting efficiency for %d", riderID))
+		},
+	}
+	efficiencyCmd.Flags().IntVar(&days, "days", 90, "Report the change in the trend over this many days")
+	return efficiencyCmd
+}
+
+// AerobicEfficiency writes out the rider's efficiency in each event with heart rate,
+// oldest first, and logs how much the trend has changed over the last so many days
+func AerobicEfficiency(riderID int, days int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := client.Events(context.Background(), riderID)
+	if err != nil {
+		return err
+	}
+
+	trend := zp.EfficiencyTrend(events)
+	if change, ok := zp.EfficiencyChange(trend, days); ok {
+		log.Printf("Efficiency trend for %d changed by %+.1f%% over %d days", riderID, change, days)
+	}
+
+	return writeRows(riderID, efficiencyColumns, len(trend), func(i int) []string {
+		p := trend[i]
+		return []string{
+			p.Date.Format("2006-01-02"),
+			p.Title,
+			p.Zid,
+			strconv.FormatFloat(p.Power, 'f', 0, 64),
+			strconv.FormatFloat(p.HR, 'f', 0, 64),
+			strconv.FormatFloat(p.Efficiency, 'f', 2, 64),
+			strconv.FormatFloat(p.Trend, 'f', 2, 64),
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var entriesColumns = []zp.Column{
+	{Key: "status", Header: "Status"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "team", Header: "Team"},
+	{Key: "category", Header: "Category"},
+}
+
+func entriesCommand() *cobra.Command {
+	var field, all bool
+	cmd := &cobra.Command{
+		Use:   "entries [event ID] [club ID]",
+		Short: "Compare who signed up for an event with who finished it",
+		Long: `Lists the club's no shows, who signed up but have no result, and unregistered
+finishers, who have a result but didn't sign up. With --all, riders who signed up and
+finished are listed too. The club ID defaults to 2672; --field checks everyone in the
+event instead.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			clubID := getID(args[1:], 2672)
+			teamID := strconv.Itoa(clubID)
+			if field {
+				teamID = ""
+			}
+			exitOnError(CheckEntries(eventID, clubID, teamID, all), fmt.Sprintf("checking entries for %d", eventID))
+		},
+	}
+	cmd.Flags().BoolVar(&field, "field", false, "Check the whole field, not just the club")
+	cmd.Flags().BoolVar(&all, "all", false, "List riders who signed up and finished as well")
+	return cmd
+}
+
+// CheckEntries writes out the differences between an event's signups and its results,
+// for riders in the team, or everyone if the team ID is empty
+func CheckEntries(eventID int, clubID int, teamID string, all bool) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+	ctx := context.Background()
+
+	signups, err := client.EventSignups(ctx, eventID)
+	if err != nil && !errors.Is(err, zp.ErrNotFound) {
+		return fmt.Errorf("getting signups: %w", err)
+	}
+	results, err := client.EventResults(ctx, eventID)
+	if err != nil && !errors.Is(err, zp.ErrNotFound) {
+		return fmt.Errorf("getting results: %w", err)
+	}
+
+	c := zp.CheckEntries(eventID, signups, results, teamID)
+	if !c.HasResults {
+		log.Printf("No results for %d yet, so there are no no shows", eventID)
+	}
+	log.Printf("%d finished, %d no shows, %d unregistered", len(c.Finished), len(c.NoShows), len(c.Unregistered))
+
+	var rows [][]string
+	add := func(status string, events []zp.Event) {
+		for _, e := range events {
+			rows = append(rows, []string{status, e.RiderName(), strconv.Itoa(e.Zwid), html.UnescapeString(e.TeamName), e.Category})
+		}
+	}
+	add(string(zp.NoShow), c.NoShows)
+	add("unregistered", c.Unregistered)
+	if all {
+		add(string(zp.Finished), c.Finished)
+	}
+
+	return writeRows(clubID, entriesColumns, len(rows), func(i int) []string {
+		return rows[i]
+	})
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// JSONErrors means errors are written to stderr as a line of JSON rather than as text
+var JSONErrors bool
+
+// Exit codes, so that scripts can tell what went wrong without reading the message. These
+// are part of the CLI's interface and shouldn't change.
+const (
+	exitError       = 1 // Anything not covered below
+	exitUsage       = 2 // Bad arguments or flags
+	exitNotFound    = 3 // No such rider, club or event, or nothing in the store
+	exitAuth        = 4 // Cookies or tokens missing or rejected
+	exitRateLimited = 5 // Rate limited or stopped by bot protection, so try again later
+	exitPartial     = 6 // Some of a batch failed, and the rest was done
+	exitMaintenance = 7 // ZwiftPower was down for maintenance for longer than --maintenance-wait
+)
+
+// errorKinds names the exit codes in JSON errors
+var errorKinds = map[int]string{
+	exitError:       "error",
+	exitUsage:       "usage",
+	exitNotFound:    "not-found",
+	exitAuth:        "unauthorized",
+	exitRateLimited: "rate-limited",
+	exitPartial:     "partial",
+	exitMaintenance: "maintenance",
+}
+
+// jsonError is what --json-errors writes
+type jsonError struct {
+	Kind       string `json:"kind"`
+	ExitCode   int    `json:"exitCode"`
+	Doing      string `json:"doing,omitempty"`
+	Error      string `json:"error"`
+	Status     int    `json:"status,omitempty"`     // HTTP status, if it was a bad response
+	RetryAfter int    `json:"retryAfter,omitempty"` // Seconds the server asked us to wait
+	Failed     int    `json:"failed,omitempty"`     // For partial failures
+	Total      int    `json:"total,omitempty"`
+}
+
+// exitCode picks the exit code for an error
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, zp.ErrRateLimited), errors.Is(err, zp.ErrChallenged):
+		return exitRateLimited
+	case errors.Is(err, zp.ErrUnauthorized):
+		return exitAuth
+	case errors.Is(err, zp.ErrPartial):
+		return exitPartial
+	case errors.Is(err, zp.ErrMaintenance):
+		return exitMaintenance
+	case errors.Is(err, zp.ErrNotFound):
+		return exitNotFound
+	}
+	return exitError
+}
+
+func exitOnError(err error, doing string) {
+	if err != nil {
+		exitWith(err, doing, exitCode(err))
+	}
+}
+
+// exitWith reports the error, as text or JSON, and exits with the code
+func exitWith(err error, doing string, code int) {
+	if JSONErrors {
+		e := jsonError{Kind: errorKinds[code], ExitCode: code, Doing: doing, Error: err.Error()}
+		var status *zp.StatusError
+		if errors.As(err, &status) {
+			e.Status = status.StatusCode
+			e.RetryAfter = int(status.RetryAfter.Seconds())
+		}
+		var partial *zp.PartialError
+		if errors.As(err, &partial) {
+			e.Failed, e.Total = partial.Failed, partial.Total
+		}
+		json.NewEncoder(os.Stderr).Encode(e)
+	} else if doing != "" {
+		fmt.Fprintf(os.Stderr, "Error %s: %v\n", doing, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// Execute runs the command, exiting with one of the codes above if it fails
+func Execute(cmd *cobra.Command) {
+	// Commands report their own errors, so any that cobra returns are about how the
+	// command was called
+	if err := cmd.Execute(); err != nil {
+		if !JSONErrors {
+			// Unknown commands are found before the flags are parsed
+			cmd.ParseFlags(os.Args[1:])
+		}
+		exitWith(err, "", exitUsage)
+	}
+}
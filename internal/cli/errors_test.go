@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+func TestExitCodeNotFound(t *testing.T) {
+	store, err := zp.NewFileStore(t.TempDir())
+	must(t, err)
+	_, err = store.Rider(1)
+	if code := exitCode(fmt.Errorf("getting rider: %w", err)); code != exitNotFound {
+		t.Errorf("Missing rider should exit with %d, got %d", exitNotFound, code)
+	}
+
+	_, err = ioutil.ReadFile(filepath.Join(t.TempDir(), "digest.tmpl"))
+	if code := exitCode(fmt.Errorf("reading template: %w", err)); code != exitError {
+		t.Errorf("Missing template should exit with %d, got %d", exitError, code)
+	}
+}
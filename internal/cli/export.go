@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// defaultBucketURL is where the service writes results if not told otherwise
+const defaultBucketURL = "gs://revo-rider-aardvark/results.csv"
+
+// defaultObjectKey is used if the bucket URL doesn't include an object name
+const defaultObjectKey = "{{.ClubID}}/{{.Date}}/results.{{.Format}}"
+
+// objectKeyData is available to templates in bucket URLs, so for example
+// gs://bucket/{{.ClubID}}/{{.Date}}.csv writes a new object for each club and day
+type objectKeyData struct {
+	ClubID int
+	Date   string // 2006-01-02
+	Time   string // 150405
+	Format string
+}
+
+func newObjectKeyData(clubID int, format string) objectKeyData {
+	now := time.Now().UTC()
+	return objectKeyData{
+		ClubID: clubID,
+		Date:   now.Format("2006-01-02"),
+		Time:   now.Format("150405"),
+		Format: format,
+	}
+}
+
+// parseBucketURL splits a gs:// or s3:// URL into scheme, bucket and object key,
+// expanding any template in the key
+func parseBucketURL(bucketURL string, data objectKeyData) (scheme, bucket, key string, err error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("parsing bucket URL: %w", err)
+	}
+
+	if u.Scheme != "gs" && u.Scheme != "s3" {
+		return "", "", "", fmt.Errorf("unsupported bucket URL %s, expected gs:// or s3://", bucketURL)
+	}
+
+	// Templates can contain characters that URL parsing mangles, so take the key
+	// straight from the original string
+	keyTemplate := strings.TrimPrefix(bucketURL, u.Scheme+"://"+u.Host)
+	keyTemplate = strings.TrimPrefix(keyTemplate, "/")
+	if keyTemplate == "" {
+		keyTemplate = defaultObjectKey
+	}
+
+	t, err := template.New("key").Parse(keyTemplate)
+	if err != nil {
+		return "", "", "", fmt.Errorf("parsing object key template: %w", err)
+	}
+
+	var b bytes.Buffer
+	err = t.Execute(&b, data)
+	if err != nil {
+		return "", "", "", fmt.Errorf("expanding object key template: %w", err)
+	}
+
+	return u.Scheme, u.Host, b.String(), nil
+}
+
+// sharedStorageClient opens the Google Cloud Storage client the first time it's needed.
+// The server writes reports from several goroutines, and they all share it. It isn't tied
+// to any one request's context, as it outlives them.
+func sharedStorageClient() (*storage.Client, error) {
+	storageOnce.Do(func() {
+		storageClient, storageErr = storage.NewClient(context.Background())
+		if storageErr != nil {
+			storageErr = fmt.Errorf("storage.NewClient: %w", storageErr)
+			return
+		}
+		log.Printf("Opened storageClient")
+	})
+	return storageClient, storageErr
+}
+
+// newBucketWriter gets a writer that uploads to an object in a Google Cloud Storage
+// or S3 bucket
+func newBucketWriter(ctx context.Context, bucketURL string, data objectKeyData) (io.WriteCloser, error) {
+	scheme, bucket, key, err := parseBucketURL(bucketURL, data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scheme {
+	case "s3":
+		log.Printf("Writing to S3 bucket %s, object %s", bucket, key)
+		return newS3Writer(ctx, bucket, key)
+
+	default:
+		client, err := sharedStorageClient()
+		if err != nil {
+			return nil, err
+		}
+
+		bkt := client.Bucket(bucket)
+		attrs, err := bkt.Attrs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error getting bucket attributes: %w", err)
+		}
+
+		log.Printf("bucket %s, created at %s, is located in %s with storage class %s\n",
+			attrs.Name, attrs.Created, attrs.Location, attrs.StorageClass)
+		log.Printf("Writing to storage bucket %s, object %s", bucket, key)
+		w := bkt.Object(key).NewWriter(ctx)
+		w.ContentType = contentTypes[data.Format]
+		return w, nil
+	}
+}
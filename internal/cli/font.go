@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// glyphs is a 5x7 pixel font, enough for names, numbers and simple punctuation in the
+// images we draw without needing a font package. Each row is 5 bits, most significant on
+// the left. Accented letters are drawn without their accents, and anything else as a box.
+var glyphs = map[rune][7]uint8{
+	'A':  {0x0e, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'B':  {0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e},
+	'C':  {0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e},
+	'D':  {0x1e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1e},
+	'E':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f},
+	'F':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10},
+	'G':  {0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f},
+	'H':  {0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'I':  {0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c},
+	'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f},
+	'M':  {0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O':  {0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'P':  {0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10},
+	'Q':  {0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d},
+	'R':  {0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11},
+	'S':  {0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e},
+	'T':  {0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04},
+	'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a},
+	'X':  {0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11},
+	'Y':  {0x11, 0x11, 0x11, 0x0a, 0x04, 0x04, 0x04},
+	'Z':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f},
+	'a':  {0x00, 0x00, 0x0e, 0x01, 0x0f, 0x11, 0x0f},
+	'b':  {0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x1e},
+	'c':  {0x00, 0x00, 0x0e, 0x10, 0x10, 0x11, 0x0e},
+	'd':  {0x01, 0x01, 0x0d, 0x13, 0x11, 0x11, 0x0f},
+	'e':  {0x00, 0x00, 0x0e, 0x11, 0x1f, 0x10, 0x0e},
+	'f':  {0x06, 0x09, 0x08, 0x1c, 0x08, 0x08, 0x08},
+	'g':  {0x00, 0x0f, 0x11, 0x11, 0x0f, 0x01, 0x0e},
+	'h':  {0x10, 0x10, 0x16, 0x19, 0x11, 0x11, 0x11},
+	'i':  {0x04, 0x00, 0x0c, 0x04, 0x04, 0x04, 0x0e},
+	'j':  {0x02, 0x00, 0x06, 0x02, 0x02, 0x12, 0x0c},
+	'k':  {0x10, 0x10, 0x12, 0x14, 0x18, 0x14, 0x12},
+	'l':  {0x0c, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'm':  {0x00, 0x00, 0x1a, 0x15, 0x15, 0x11, 0x11},
+	'n':  {0x00, 0x00, 0x16, 0x19, 0x11, 0x11, 0x11},
+	'o':  {0x00, 0x00, 0x0e, 0x11, 0x11, 0x11, 0x0e},
+	'p':  {0x00, 0x00, 0x1e, 0x11, 0x1e, 0x10, 0x10},
+	'q':  {0x00, 0x00, 0x0d, 0x13, 0x0f, 0x01, 0x01},
+	'r':  {0x00, 0x00, 0x16, 0x19, 0x10, 0x10, 0x10},
+	's':  {0x00, 0x00, 0x0e, 0x10, 0x0e, 0x01, 0x1e},
+	't':  {0x08, 0x08, 0x1c, 0x08, 0x08, 0x09, 0x06},
+	'u':  {0x00, 0x00, 0x11, 0x11, 0x11, 0x13, 0x0d},
+	'v':  {0x00, 0x00, 0x11, 0x11, 0x11, 0x0a, 0x04},
+	'w':  {0x00, 0x00, 0x11, 0x11, 0x15, 0x15, 0x0a},
+	'x':  {0x00, 0x00, 0x11, 0x0a, 0x04, 0x0a, 0x11},
+	'y':  {0x00, 0x00, 0x11, 0x11, 0x0f, 0x01, 0x0e},
+	'z':  {0x00, 0x00, 0x1f, 0x02, 0x04, 0x08, 0x1f},
+	'0':  {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1':  {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3':  {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4':  {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5':  {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6':  {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8':  {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9':  {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	' ':  {},
+	'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+	',':  {0x00, 0x00, 0x00, 0x00, 0x0c, 0x04, 0x08},
+	':':  {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},
+	';':  {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x04, 0x08},
+	'-':  {0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00},
+	'+':  {0x00, 0x04, 0x04, 0x1f, 0x04, 0x04, 0x00},
+	'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'%':  {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'&':  {0x0c, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0d},
+	'\'': {0x0c, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+	'"':  {0x0a, 0x0a, 0x0a, 0x00, 0x00, 0x00, 0x00},
+	'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'[':  {0x0e, 0x08, 0x08, 0x08, 0x08, 0x08, 0x0e},
+	']':  {0x0e, 0x02, 0x02, 0x02, 0x02, 0x02, 0x0e},
+	'!':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'?':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'#':  {0x0a, 0x0a, 0x1f, 0x0a, 0x1f, 0x0a, 0x0a},
+	'|':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'_':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f},
+	'·':  {0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00},
+}
+
+var unknownGlyph = [7]uint8{0x1f, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1f}
+
+const (
+	glyphWidth   = 5
+	glyphHeight  = 7
+	glyphAdvance = glyphWidth + 1
+)
+
+// glyph finds the pixels for a character, dropping any accent so that Özge is drawn
+// as Ozge rather than with a box
+func glyph(r rune) ([7]uint8, bool) {
+	if g, ok := glyphs[r]; ok {
+		return g, true
+	}
+	base := []rune(norm.NFD.String(string(r)))
+	if len(base) > 1 {
+		g, ok := glyphs[base[0]]
+		return g, ok
+	}
+	return unknownGlyph, false
+}
+
+// textWidth is how wide the text is in pixels when drawn at this scale
+func textWidth(text string, scale int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n*glyphAdvance - 1) * scale
+}
+
+// drawText draws the text with its top left corner at x, y, each font pixel being a
+// scale x scale square
+func drawText(img draw.Image, x int, y int, scale int, text string, c color.Color) {
+	src := image.NewUniform(c)
+	for _, r := range text {
+		g, ok := glyph(r)
+		if !ok {
+			g = unknownGlyph
+		}
+		for row, bits := range g {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<uint(glyphWidth-1-col)) != 0 {
+					px := image.Rect(x+col*scale, y+row*scale, x+(col+1)*scale, y+(row+1)*scale)
+					draw.Draw(img, px, src, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += glyphAdvance * scale
+	}
+}
+
+// wrapText splits the text into lines of at most width characters, breaking between
+// words where it can. A line that still doesn't fit ends with "..." instead.
+func wrapText(text string, width int, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, w := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = w
+		case len([]rune(line))+1+len([]rune(w)) <= width:
+			line += " " + w
+		default:
+			lines = append(lines, line)
+			line = w
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	for i, l := range lines {
+		if r := []rune(l); len(r) > width {
+			lines[i] = string(r[:width-3]) + "..."
+		}
+	}
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := []rune(lines[maxLines-1])
+		if len(last) > width-3 {
+			last = last[:width-3]
+		}
+		lines[maxLines-1] = string(last) + "..."
+	}
+	return lines
+}
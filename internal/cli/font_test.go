@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	cases := []struct {
+		text     string
+		width    int
+		maxLines int
+		expected []string
+	}{
+		{"Zwift Racing League", 20, 2, []string{"Zwift Racing League"}},
+		{"Zwift Racing League", 12, 2, []string{"Zwift Racing", "League"}},
+		{"Zwift Racing League Womens Division", 12, 2, []string{"Zwift Racing", "League..."}},
+		{"Supercalifragilistic", 10, 2, []string{"Superca..."}},
+		{"", 10, 2, nil},
+	}
+	for _, c := range cases {
+		got := wrapText(c.text, c.width, c.maxLines)
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%q: got %q, expected %q", c.text, got, c.expected)
+		}
+	}
+}
+
+func TestDrawText(t *testing.T) {
+	if w := textWidth("AB", 2); w != 22 {
+		t.Errorf("Got width %d, expected 22", w)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, 20, 10))
+	drawText(img, 1, 1, 1, "I!", color.White)
+
+	// The I has a bar across the top from x=2 to 4, and the ! a dot at the bottom
+	for _, p := range []image.Point{{2, 1}, {3, 1}, {4, 1}, {3, 4}, {9, 7}} {
+		if img.GrayAt(p.X, p.Y).Y != 0xff {
+			t.Errorf("Expected %v to be drawn", p)
+		}
+	}
+	for _, p := range []image.Point{{1, 1}, {5, 1}, {9, 6}, {0, 0}} {
+		if img.GrayAt(p.X, p.Y).Y != 0 {
+			t.Errorf("Expected %v to be blank", p)
+		}
+	}
+}
+
+func TestGlyph(t *testing.T) {
+	o, _ := glyph('O')
+	if g, ok := glyph('Ö'); !ok || g != o {
+		t.Errorf("Expected Ö to be drawn as O")
+	}
+	lower, _ := glyph('o')
+	if g, ok := glyph('ö'); !ok || g != lower || g == o {
+		t.Errorf("Expected ö to be drawn as a lower case o")
+	}
+	for r := 'a'; r <= 'z'; r++ {
+		if _, ok := glyphs[r]; !ok {
+			t.Errorf("No glyph for %c", r)
+		}
+	}
+	if _, ok := glyph('€'); ok {
+		t.Errorf("Expected no glyph for €")
+	}
+}
@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// Output formats, which are also used as file extensions
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatHTML   = "html"
+	formatTable  = "table"
+)
+
+var contentTypes = map[string]string{
+	formatCSV:    "text/csv",
+	formatJSON:   "application/json",
+	formatNDJSON: "application/x-ndjson",
+	formatHTML:   "text/html",
+	formatTable:  "text/plain",
+}
+
+// checkFormat returns an error if we don't know how to write this format
+func checkFormat(format string) error {
+	if _, ok := contentTypes[format]; !ok {
+		return fmt.Errorf("unsupported format %q, expected table, csv, json, ndjson or html", format)
+	}
+	return nil
+}
+
+// jsonRows writes rows as a JSON array of objects, using the headers as keys
+type jsonRows struct {
+	w       io.Writer
+	columns []zp.Column
+	rows    []map[string]string
+}
+
+// rowObject turns a row into an object keyed by the column headers, as the JSON
+// output always has been, rather than the shorter keys used to pick columns
+func rowObject(columns []zp.Column, record []string) map[string]string {
+	row := make(map[string]string, len(record))
+	for i, v := range record {
+		key := fmt.Sprintf("column%d", i+1)
+		if i < len(columns) {
+			key = columns[i].Header
+		}
+		row[key] = v
+	}
+	return row
+}
+
+func (j *jsonRows) WriteRow(record []string) error {
+	j.rows = append(j.rows, rowObject(j.columns, record))
+	return nil
+}
+
+func (j *jsonRows) Flush() {
+	if j.rows == nil {
+		j.rows = []map[string]string{}
+	}
+
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(j.rows)
+	if err != nil {
+		log.Printf("writing JSON: %v", err)
+	}
+	j.rows = nil
+}
+
+// ndjsonRows writes each row as a JSON object on a line of its own as soon as it
+// arrives, so that whatever is reading can get started before the import finishes
+type ndjsonRows struct {
+	enc     *json.Encoder
+	columns []zp.Column
+}
+
+func newNDJSONRows(w io.Writer, columns []zp.Column) *ndjsonRows {
+	return &ndjsonRows{enc: json.NewEncoder(w), columns: columns}
+}
+
+func (n *ndjsonRows) WriteRow(record []string) error {
+	return n.enc.Encode(rowObject(n.columns, record))
+}
+
+func (n *ndjsonRows) Flush() {}
+
+var htmlTable = template.Must(template.New("table").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"></head>
+<body>
+<table>
+<thead><tr>{{range .Columns}}<th>{{.Header}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+</body>
+</html>
+`))
+
+// htmlRows writes rows as a simple HTML table
+type htmlRows struct {
+	w       io.Writer
+	columns []zp.Column
+	rows    [][]string
+}
+
+func (h *htmlRows) WriteRow(record []string) error {
+	h.rows = append(h.rows, record)
+	return nil
+}
+
+func (h *htmlRows) Flush() {
+	err := htmlTable.Execute(h.w, struct {
+		Columns []zp.Column
+		Rows    [][]string
+	}{h.columns, h.rows})
+	if err != nil {
+		log.Printf("writing HTML: %v", err)
+	}
+	h.rows = nil
+}
+
+// tableRows writes rows as a plain text table, with columns wide enough for their contents
+type tableRows struct {
+	tw            *tabwriter.Writer
+	columns       []zp.Column
+	headerWritten bool
+}
+
+func newTableRows(w io.Writer, columns []zp.Column) *tableRows {
+	return &tableRows{
+		tw:      tabwriter.NewWriter(w, 0, 0, 2, ' ', 0),
+		columns: columns,
+	}
+}
+
+func (t *tableRows) WriteRow(record []string) error {
+	if !t.headerWritten && len(t.columns) > 0 {
+		headers := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			headers[i] = strings.ToUpper(c.Header)
+		}
+		_, err := fmt.Fprintln(t.tw, strings.Join(headers, "\t"))
+		if err != nil {
+			return err
+		}
+		t.headerWritten = true
+	}
+
+	// Tabs inside a field would throw the columns out
+	fields := make([]string, len(record))
+	for i, f := range record {
+		fields[i] = strings.Replace(f, "\t", " ", -1)
+	}
+	_, err := fmt.Fprintln(t.tw, strings.Join(fields, "\t"))
+	return err
+}
+
+func (t *tableRows) Flush() {
+	err := t.tw.Flush()
+	if err != nil {
+		log.Printf("writing table: %v", err)
+	}
+}
+
+// localeRows writes numbers and dates for the locale, and passes the rows on
+type localeRows struct {
+	next   rowWriter
+	locale zp.Locale
+}
+
+func (l *localeRows) WriteRow(record []string) error {
+	return l.next.WriteRow(l.locale.LocalizeRow(record))
+}
+
+func (l *localeRows) Flush() {
+	l.next.Flush()
+}
+
+// selectRows holds on to rows so that they can be sorted, and passes on only the
+// chosen columns
+type selectRows struct {
+	next    rowWriter
+	indexes []int
+	sortBy  int // Index of the column to sort by, or -1 to leave the order alone
+	desc    bool
+	rows    [][]string
+}
+
+// columnIndex finds the column with this key or header
+func columnIndex(columns []zp.Column, name string) (int, error) {
+	for i, c := range columns {
+		if strings.EqualFold(c.Key, name) || strings.EqualFold(c.Header, name) {
+			return i, nil
+		}
+	}
+
+	keys := make([]string, len(columns))
+	for i, c := range columns {
+		keys[i] = c.Key
+	}
+	return 0, fmt.Errorf("unknown column %q, expected one of %s", name, strings.Join(keys, ","))
+}
+
+// selectColumns works out which of the columns to output, from a comma-separated list of
+// keys. An empty list means all of them.
+func selectColumns(columns []zp.Column, list string) ([]int, []zp.Column, error) {
+	if list == "" {
+		indexes := make([]int, len(columns))
+		for i := range columns {
+			indexes[i] = i
+		}
+		return indexes, columns, nil
+	}
+
+	var indexes []int
+	var selected []zp.Column
+	for _, name := range strings.Split(list, ",") {
+		i, err := columnIndex(columns, strings.TrimSpace(name))
+		if err != nil {
+			return nil, nil, err
+		}
+		indexes = append(indexes, i)
+		selected = append(selected, columns[i])
+	}
+	return indexes, selected, nil
+}
+
+func (s *selectRows) WriteRow(record []string) error {
+	if s.sortBy < 0 {
+		// Nothing to sort, so there's no need to wait for the rest
+		return s.next.WriteRow(s.selected(record))
+	}
+	s.rows = append(s.rows, record)
+	return nil
+}
+
+// selected picks out the chosen columns from the row
+func (s *selectRows) selected(record []string) []string {
+	out := make([]string, len(s.indexes))
+	for i, index := range s.indexes {
+		out[i] = field(record, index)
+	}
+	return out
+}
+
+// lessField compares numerically if both fields are numbers, and as strings otherwise
+func lessField(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return fa < fb
+	}
+	return a < b
+}
+
+func (s *selectRows) Flush() {
+	if s.sortBy >= 0 {
+		sort.SliceStable(s.rows, func(i, j int) bool {
+			a, b := field(s.rows[i], s.sortBy), field(s.rows[j], s.sortBy)
+			if s.desc {
+				return lessField(b, a)
+			}
+			return lessField(a, b)
+		})
+	}
+
+	for _, row := range s.rows {
+		err := s.next.WriteRow(s.selected(row))
+		if err != nil {
+			log.Printf("writing row: %v", err)
+		}
+	}
+
+	s.rows = nil
+	s.next.Flush()
+}
+
+func field(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
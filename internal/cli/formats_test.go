@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+func TestJSONRowsUseHeaders(t *testing.T) {
+	columns := []zp.Column{{Key: "name", Header: "Name"}, {Key: "ftp90", Header: "FTP 90 days"}}
+	var buf bytes.Buffer
+	j := &jsonRows{w: &buf, columns: columns}
+	must(t, j.WriteRow([]string{"Ann", "3.5", "extra"}))
+	j.Flush()
+
+	var rows []map[string]string
+	must(t, json.Unmarshal(buf.Bytes(), &rows))
+	if len(rows) != 1 || rows[0]["Name"] != "Ann" || rows[0]["FTP 90 days"] != "3.5" || rows[0]["column3"] != "extra" {
+		t.Errorf("Unexpected JSON rows %v", rows)
+	}
+
+	buf.Reset()
+	n := newNDJSONRows(&buf, columns)
+	must(t, n.WriteRow([]string{"Bob", "4.0"}))
+	var row map[string]string
+	must(t, json.Unmarshal(buf.Bytes(), &row))
+	if row["Name"] != "Bob" || row["FTP 90 days"] != "4.0" {
+		t.Errorf("Unexpected NDJSON row %v", row)
+	}
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var goalColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "goal", Header: "Goal"},
+	{Key: "target", Header: "Target"},
+	{Key: "current", Header: "Current"},
+	{Key: "percent", Header: "Percent"},
+	{Key: "projected", Header: "Projected"},
+	{Key: "by", Header: "By"},
+	{Key: "ontrack", Header: "On track"},
+}
+
+// goalCommand has subcommands for setting riders' goals in the store and seeing how
+// they are getting on
+func goalCommand() *cobra.Command {
+	goalCmd := &cobra.Command{
+		Use:   "goal",
+		Short: "Track riders' goals",
+	}
+
+	var by, name string
+	setCmd := &cobra.Command{
+		Use:   "set [rider ID] [ftp|wkg|races] [target]",
+		Short: "Set a goal: FTP in watts or w/kg, or races a month",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(setGoal(args, name, by), "setting goal")
+		},
+	}
+	setCmd.Flags().StringVar(&by, "by", "", "Deadline for the goal, as YYYY-MM-DD")
+	setCmd.Flags().StringVar(&name, "name", "", "Rider's name, if you don't want to look it up")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [rider ID] [ftp|wkg|races]",
+		Short: "Remove a goal",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(removeGoal(args), "removing goal")
+		},
+	}
+
+	progressCmd := &cobra.Command{
+		Use:   "progress",
+		Short: "Write out everyone's progress towards their goals",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(GoalProgress(), "tracking goals")
+		},
+	}
+
+	goalCmd.AddCommand(setCmd, removeCmd, progressCmd)
+	return goalCmd
+}
+
+func setGoal(args []string, name string, by string) error {
+	riderID := getID(args, 0)
+	kind, err := zp.ParseGoalKind(args[1])
+	if err != nil {
+		return err
+	}
+	target, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || target <= 0 {
+		return fmt.Errorf("target must be a positive number, not %q", args[2])
+	}
+
+	g := zp.Goal{Zwid: riderID, Name: name, Kind: kind, Target: target, Set: time.Now()}
+	if by != "" {
+		g.By, err = time.ParseInLocation("2006-01-02", by, time.Local)
+		if err != nil {
+			return fmt.Errorf("parsing deadline: %w", err)
+		}
+	}
+
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	if g.Name == "" {
+		g.Name, err = riderName(store, riderID)
+		if err != nil {
+			return err
+		}
+	}
+	return store.SetGoal(g)
+}
+
+// riderName finds the rider's name in the store, or failing that from their events
+func riderName(store *zp.FileStore, riderID int) (string, error) {
+	if r, err := store.Rider(riderID); err == nil && r.Name != "" {
+		return r.Name, nil
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return "", fmt.Errorf("error getting client: %w", err)
+	}
+	events, err := client.Events(context.Background(), riderID)
+	if err != nil {
+		return "", fmt.Errorf("looking up rider %d: %w", riderID, err)
+	}
+	if len(events) == 0 {
+		return "", fmt.Errorf("no events for rider %d, use --name", riderID)
+	}
+	return events[0].RiderName(), nil
+}
+
+func removeGoal(args []string) error {
+	riderID := getID(args, 0)
+	kind, err := zp.ParseGoalKind(args[1])
+	if err != nil {
+		return err
+	}
+
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	return store.RemoveGoal(riderID, kind)
+}
+
+// storedGoals gets everyone's goals, and the events we need to track them
+func storedGoals() ([]zp.Goal, []zp.Event, error) {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening store: %w", err)
+	}
+
+	goals, err := store.Goals()
+	if err != nil || len(goals) == 0 {
+		return nil, nil, err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	var events []zp.Event
+	imported := make(map[int]bool)
+	for _, g := range goals {
+		if imported[g.Zwid] {
+			continue
+		}
+		imported[g.Zwid] = true
+
+		ee, err := client.Events(context.Background(), g.Zwid)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading events for %d: %w", g.Zwid, err)
+		}
+		events = append(events, ee...)
+	}
+	return goals, events, nil
+}
+
+// GoalProgress writes out a row for each rider's goal, showing how they are getting on
+func GoalProgress() error {
+	goals, events, err := storedGoals()
+	if err != nil {
+		return err
+	}
+
+	f, err := setOutput(Filename, 0)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, goalColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, p := range zp.TrackGoals(goals, events, time.Now()) {
+		err = writer.WriteRow([]string{
+			p.Name,
+			strconv.Itoa(p.Zwid),
+			p.Kind,
+			strconv.FormatFloat(p.Target, 'f', -1, 64),
+			strconv.FormatFloat(p.Current, 'f', -1, 64),
+			strconv.FormatFloat(p.Percent, 'f', 0, 64),
+			formatDate(p.Projected),
+			formatDate(p.By),
+			strconv.FormatBool(p.OnTrack),
+		})
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
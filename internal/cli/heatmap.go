@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// heatmapColumns are the day, then a column for each hour
+var heatmapColumns = func() []zp.Column {
+	columns := []zp.Column{{Key: "day", Header: "Day (UTC)"}}
+	for hour := 0; hour < 24; hour++ {
+		h := fmt.Sprintf("%02d", hour)
+		columns = append(columns, zp.Column{Key: h, Header: h + ":00"})
+	}
+	return columns
+}()
+
+func heatmapCommand() *cobra.Command {
+	var days int
+	var riders bool
+	cmd := &cobra.Command{
+		Use:   "heatmap [club ID]",
+		Short: "Show which hours of the week the club's riders race in, for scheduling club events",
+		Long: `Counts the club's races by the day of the week and the hour they started, in UTC,
+with a row for each day from Monday and a column for each hour. With --riders each
+cell is how many different riders raced then, rather than how many races there were.
+The club ID defaults to 2672.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			exitOnError(Heatmap(clubID, time.Now().AddDate(0, 0, -days), riders), fmt.Sprintf("getting race times for %d", clubID))
+		},
+	}
+	cmd.Flags().IntVar(&days, "days", 90, "Include races from this many days ago")
+	cmd.Flags().BoolVar(&riders, "riders", false, "Count riders rather than races")
+	return cmd
+}
+
+// Heatmap writes out when the club's riders have raced since the given date
+func Heatmap(clubID int, since time.Time, riders bool) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := clubEvents(client, clubID)
+	if err != nil {
+		return err
+	}
+	h := zp.RaceTimes(events, since)
+
+	counts := h.Races
+	if riders {
+		counts = h.Riders
+	}
+	return writeRows(clubID, heatmapColumns, 7, func(i int) []string {
+		day := time.Weekday((i + 1) % 7) // Monday first
+		row := []string{day.String()}
+		for _, n := range counts[day] {
+			row = append(row, strconv.Itoa(n))
+		}
+		return row
+	})
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var historyColumns = []zp.Column{
+	{Key: "date", Header: "Date"},
+	{Key: "type", Header: "Type"},
+	{Key: "event", Header: "Event"},
+	{Key: "zid", Header: "Event ID"},
+	{Key: "category", Header: "Category"},
+	{Key: "position", Header: "Position"},
+	{Key: "incategory", Header: "In category"},
+	{Key: "time", Header: "Time"},
+	{Key: "distance", Header: "Distance km"},
+	{Key: "elevation", Header: "Elevation m"},
+	{Key: "terrain", Header: "Terrain"},
+	{Key: "speed", Header: "Avg km/h"},
+	{Key: "avgpower", Header: "Avg power"},
+	{Key: "avgwkg", Header: "Avg w/kg"},
+	{Key: "ftpwkg", Header: "FTP w/kg"},
+}
+
+func historyCommand() *cobra.Command {
+	var races bool
+
+	historyCmd := &cobra.Command{
+		Use:   "rider-history [rider ID]",
+		Short: "Every event in a rider's ZwiftPower history, oldest first",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 0)
+			exitOnError(RiderHistory(riderID, races), fmt.Sprintf("getting history for %d", riderID))
+		},
+	}
+	historyCmd.Flags().BoolVar(&races, "races", false, "Only include races")
+	return historyCmd
+}
+
+// RiderHistory writes out every event in the rider's history, or just the races
+func RiderHistory(riderID int, races bool) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	all, err := client.Events(context.Background(), riderID)
+	if err != nil {
+		return err
+	}
+
+	var events []zp.Event
+	for _, e := range all {
+		if !races || e.IsRace() {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventDateSecs < events[j].EventDateSecs
+	})
+
+	return writeRows(riderID, historyColumns, len(events), func(i int) []string {
+		e := events[i]
+		date := ""
+		if e.EventDateSecs != 0 {
+			date = e.EventDate.Format("2006-01-02")
+		}
+		return []string{
+			date,
+			eventType(e),
+			e.EventTitle,
+			e.Zid,
+			e.Category,
+			positiveInt(e.Pos),
+			positiveInt(e.PositionInCat),
+			formatSeconds(e.Time),
+			positiveFloat(e.Distance, 1),
+			positiveFloat(zp.Number(e.Elevation()), 0),
+			e.Terrain(),
+			positiveFloat(zp.Number(e.AvgSpeed()), 1),
+			positiveFloat(e.AvgPower, 0),
+			positiveFloat(zp.Number(e.Wkg()), 1),
+			positiveFloat(zp.Number(e.FtpWkg()), 1),
+		}
+	})
+}
+
+// eventType turns ZwiftPower's "TYPE_RACE" (which is sometimes repeated) into "race"
+func eventType(e zp.Event) string {
+	types := strings.Fields(e.EventType)
+	if len(types) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(types[0], "TYPE_"))
+}
+
+func positiveInt(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func positiveFloat(n zp.Number, decimals int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(n), 'f', decimals, 64)
+}
+
+func formatSeconds(secs zp.Number) string {
+	if secs <= 0 {
+		return ""
+	}
+	return formatDuration(time.Duration(float64(secs) * float64(time.Second)))
+}
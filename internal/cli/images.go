@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// Size of card images, in pixels. This is the shape that link previews expect.
+const (
+	cardWidth  = 600
+	cardHeight = 315
+)
+
+// Size of podium images, in pixels. Square images suit most social media.
+const podiumSize = 1080
+
+// writeCardPNG draws the card as an image: the title, with up to three of the stats below
+func writeCardPNG(w io.Writer, c zp.Card) error {
+	const (
+		margin     = 30
+		titleScale = 4
+		valueScale = 5
+		labelScale = 2
+	)
+
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c.Color()), image.Point{}, draw.Src)
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	pale := color.RGBA{0xff, 0xff, 0xff, 0xb0}
+
+	y := margin
+	perLine := (cardWidth - 2*margin + titleScale) / (glyphAdvance * titleScale)
+	for _, line := range wrapText(c.Title, perLine, 2) {
+		drawText(img, margin, y, titleScale, line, white)
+		y += (glyphHeight + 3) * titleScale
+	}
+
+	stats := c.Stats
+	if len(stats) > 3 {
+		stats = stats[:3]
+	}
+	colWidth := (cardWidth - 2*margin) / 3
+	valueY := cardHeight - margin - glyphHeight*labelScale - 12 - glyphHeight*valueScale
+	for i, s := range stats {
+		x := margin + i*colWidth
+		drawText(img, x, valueY, valueScale, fitText(s.Value, colWidth, valueScale), white)
+		drawText(img, x, cardHeight-margin-glyphHeight*labelScale, labelScale, fitText(s.Label, colWidth, labelScale), pale)
+	}
+
+	return png.Encode(w, img)
+}
+
+// writePodiumPNG draws the podium: the event title and date above three steps in the
+// club's colour, with first place in the middle, second on the left and third on the right
+func writePodiumPNG(w io.Writer, p zp.Podium) error {
+	const (
+		margin     = 60
+		titleScale = 7
+		dateScale  = 4
+		nameScale  = 4
+		timeScale  = 3
+		placeScale = 14
+		gap        = 20
+	)
+
+	img := image.NewRGBA(image.Rect(0, 0, podiumSize, podiumSize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(p.Background), image.Point{}, draw.Src)
+	text := contrastColor(p.Background)
+	onStep := contrastColor(p.Color)
+
+	y := margin
+	perLine := (podiumSize - 2*margin + titleScale) / (glyphAdvance * titleScale)
+	for _, line := range wrapText(p.Title, perLine, 3) {
+		drawText(img, margin, y, titleScale, line, text)
+		y += (glyphHeight + 4) * titleScale
+	}
+	subtitle := strings.TrimSpace(p.Date + "  " + p.Team)
+	if subtitle != "" {
+		drawText(img, margin, y, dateScale, fitText(subtitle, podiumSize-2*margin, dateScale), text)
+	}
+
+	if len(p.Places) == 0 {
+		drawText(img, margin, podiumSize/2, nameScale, "NO CLUB FINISHERS", text)
+		return png.Encode(w, img)
+	}
+
+	// Steps from left to right are second, first and third
+	stepWidth := (podiumSize - 2*margin - 2*gap) / 3
+	heights := []int{300, 380, 220}
+	for slot, i := range []int{1, 0, 2} {
+		if i >= len(p.Places) {
+			continue
+		}
+		place := p.Places[i]
+		x := margin + slot*(stepWidth+gap)
+		top := podiumSize - margin - heights[i]
+		draw.Draw(img, image.Rect(x, top, x+stepWidth, podiumSize-margin), image.NewUniform(p.Color), image.Point{}, draw.Src)
+
+		label := strconv.Itoa(i + 1)
+		drawText(img, x+(stepWidth-textWidth(label, placeScale))/2, top+30, placeScale, label, onStep)
+		detail := place.Detail()
+		drawText(img, x+(stepWidth-textWidth(detail, timeScale))/2, top+50+glyphHeight*placeScale, timeScale, fitText(detail, stepWidth, timeScale), onStep)
+
+		// Name and time above the step
+		lineHeight := (glyphHeight + 3) * nameScale
+		names := wrapText(place.Name, (stepWidth+nameScale)/(glyphAdvance*nameScale), 2)
+		ny := top - 20 - len(names)*lineHeight
+		if place.Time != "" {
+			ny -= (glyphHeight + 4) * timeScale
+		}
+		for _, line := range names {
+			drawText(img, x+(stepWidth-textWidth(line, nameScale))/2, ny, nameScale, line, text)
+			ny += lineHeight
+		}
+		if place.Time != "" {
+			drawText(img, x+(stepWidth-textWidth(place.Time, timeScale))/2, ny, timeScale, place.Time, text)
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// fitText cuts the text short if it's wider than width when drawn at this scale
+func fitText(text string, width int, scale int) string {
+	r := []rune(text)
+	for len(r) > 0 && textWidth(string(r), scale) > width-glyphAdvance*scale {
+		r = r[:len(r)-1]
+	}
+	return string(r)
+}
+
+// contrastColor is black or white, whichever is easier to read on the background
+func contrastColor(bg color.RGBA) color.RGBA {
+	luma := 0.299*float64(bg.R) + 0.587*float64(bg.G) + 0.114*float64(bg.B)
+	if luma > 150 {
+		return color.RGBA{0, 0, 0, 0xff}
+	}
+	return color.RGBA{0xff, 0xff, 0xff, 0xff}
+}
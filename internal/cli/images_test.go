@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+func TestCardPNG(t *testing.T) {
+	var b bytes.Buffer
+	c := zp.RiderCard(zp.Rider{Name: "A rider with a very long name indeed, far too long for one line", Category: "A", Ftp90: 4.5})
+	err := writeCardPNG(&b, c)
+	if err != nil {
+		t.Fatalf("Failed drawing card: %v", err)
+	}
+
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatalf("Failed decoding card: %v", err)
+	}
+	if img.Bounds().Dx() != cardWidth || img.Bounds().Dy() != cardHeight {
+		t.Errorf("Got size %v", img.Bounds())
+	}
+}
+
+func TestPodiumPNG(t *testing.T) {
+	for _, places := range [][]zp.PodiumPlace{
+		nil,
+		{{Name: "Only one rider with a very long name indeed", Category: "A", PositionInCat: 1, Time: "59:59"}},
+		{{Name: "A", PositionInCat: 1}, {Name: "B", PositionInCat: 2}, {Name: "C", PositionInCat: 3}},
+	} {
+		var b bytes.Buffer
+		p := zp.Podium{Title: "Crit City", Date: "2020-10-21", Places: places, Color: color.RGBA{0xfc, 0, 0xe3, 0xff}}
+		err := writePodiumPNG(&b, p)
+		if err != nil {
+			t.Fatalf("Failed drawing podium: %v", err)
+		}
+
+		img, err := png.Decode(&b)
+		if err != nil {
+			t.Fatalf("Failed decoding podium: %v", err)
+		}
+		if img.Bounds().Dx() != podiumSize || img.Bounds().Dy() != podiumSize {
+			t.Errorf("Got size %v", img.Bounds())
+		}
+	}
+}
+
+func TestContrastColor(t *testing.T) {
+	if contrastColor(color.RGBA{0xff, 0xff, 0xff, 0xff}).R != 0 || contrastColor(color.RGBA{0, 0, 0, 0xff}).R != 0xff {
+		t.Errorf("Unexpected contrast colours")
+	}
+}
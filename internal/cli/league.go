@@ -0,0 +1,389 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var leagueColumns = []zp.Column{
+	{Key: "division", Header: "Division"},
+	{Key: "rank", Header: "Rank"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "points", Header: "Points"},
+	{Key: "fixtures", Header: "Fixtures"},
+	{Key: "wins", Header: "Wins"},
+	{Key: "best", Header: "Best"},
+}
+
+// leagueCommand has subcommands for setting up a mini-league in the store and
+// writing out its tables
+func leagueCommand() *cobra.Command {
+	leagueCmd := &cobra.Command{
+		Use:   "league",
+		Short: "Run a league within the club",
+	}
+
+	var remove, missing, matrix bool
+	subcommands := []*cobra.Command{
+		{
+			Use:   "create [name] [club ID]",
+			Short: "Start a new league",
+			Args:  cobra.RangeArgs(1, 2),
+			Run: func(cmd *cobra.Command, args []string) {
+				exitOnError(createLeague(args[0], getID(args[1:], 2672)), "creating league")
+			},
+		},
+		{
+			Use:   "division [name] [division] [category...]",
+			Short: "Add a division for riders in these categories, or change its categories",
+			Args:  cobra.MinimumNArgs(2),
+			Run: func(cmd *cobra.Command, args []string) {
+				err := updateLeague(args[0], func(l *zp.League) error {
+					l.AddDivision(args[1], args[2:]...)
+					return nil
+				})
+				exitOnError(err, "updating division")
+			},
+		},
+		{
+			Use:   "assign [name] [division] [rider ID...]",
+			Short: "Put riders in a division, whatever category they race",
+			Args:  cobra.MinimumNArgs(3),
+			Run: func(cmd *cobra.Command, args []string) {
+				err := updateLeague(args[0], func(l *zp.League) error {
+					ids, err := parseIDs(args[2:])
+					if err != nil {
+						return err
+					}
+					return l.Assign(args[1], ids...)
+				})
+				exitOnError(err, "assigning riders")
+			},
+		},
+		{
+			Use:   "fixture [name] [event ID...]",
+			Short: "Register events as league fixtures",
+			Args:  cobra.MinimumNArgs(2),
+			Run: func(cmd *cobra.Command, args []string) {
+				exitOnError(leagueFixtures(args[0], args[1:], remove), "updating fixtures")
+			},
+		},
+		{
+			Use:   "table [name]",
+			Short: "Write out the table for each division",
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				exitOnError(LeagueTables(args[0]), "writing league tables")
+			},
+		},
+		{
+			Use:   "attendance [name]",
+			Short: "Write out who signed up for and finished each fixture",
+			Long: `Matches the signups and results for each fixture to the league's riders: those
+assigned to a division, and club riders whose category puts them in one. Each rider is
+finished, signed up, a no show (signed up, but not in the results), or missing. Use
+--missing to list just the riders who haven't signed up for fixtures still to come, or
+--matrix for a grid of riders and fixtures, with totals for each rider, to keep
+through the season.`,
+			Args: cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				if matrix && missing {
+					exitWith(fmt.Errorf("--matrix and --missing can't be used together"), "", exitUsage)
+				}
+				if matrix {
+					exitOnError(AttendanceMatrix(args[0]), "writing attendance matrix")
+				}
+				exitOnError(LeagueAttendance(args[0], missing), "writing attendance")
+			},
+		},
+	}
+	subcommands[3].Flags().BoolVar(&remove, "remove", false, "Remove the events from the fixture list instead")
+	subcommands[5].Flags().BoolVar(&missing, "missing", false, "Only riders who haven't signed up for fixtures without results yet")
+	subcommands[5].Flags().BoolVar(&matrix, "matrix", false, "Write a row for each rider with a column for each fixture")
+
+	leagueCmd.AddCommand(subcommands...)
+	return leagueCmd
+}
+
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, len(args))
+	for i, a := range args {
+		id, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse ID %q", a)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func createLeague(name string, clubID int) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	if _, err := store.League(name); err == nil {
+		return fmt.Errorf("league %s already exists", name)
+	}
+	return store.PutLeague(zp.NewLeague(name, clubID))
+}
+
+// updateLeague loads the named league from the store, changes it and saves it again
+func updateLeague(name string, change func(l *zp.League) error) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	return store.UpdateLeague(name, change)
+}
+
+// leagueFixtures adds or removes fixtures. When adding, the title and date come from
+// the event results if there are any yet.
+func leagueFixtures(name string, args []string, remove bool) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	var fixtures []zp.Fixture
+	if !remove {
+		client, err := newClient()
+		if err != nil {
+			return fmt.Errorf("error getting client: %w", err)
+		}
+
+		for _, id := range ids {
+			f := zp.Fixture{EventID: id}
+			results, err := client.EventResults(context.Background(), id)
+			if err != nil && !errors.Is(err, zp.ErrNotFound) {
+				return fmt.Errorf("getting results for %d: %w", id, err)
+			}
+			if len(results) == 0 {
+				// Events still to come have signups instead
+				results, err = client.EventSignups(context.Background(), id)
+				if err != nil && !errors.Is(err, zp.ErrNotFound) {
+					return fmt.Errorf("getting signups for %d: %w", id, err)
+				}
+			}
+			if len(results) > 0 {
+				f.Title = results[0].EventTitle
+				f.Date = results[0].EventDate
+			}
+			fixtures = append(fixtures, f)
+		}
+	}
+
+	return updateLeague(name, func(l *zp.League) error {
+		if remove {
+			for _, id := range ids {
+				l.RemoveFixture(id)
+			}
+		}
+		for _, f := range fixtures {
+			l.AddFixture(f)
+		}
+		return nil
+	})
+}
+
+// LeagueTables imports the results of each fixture and writes out the division tables
+func LeagueTables(name string) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	l, err := store.League(name)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	results := make(map[int][]zp.Event, len(l.Fixtures))
+	for _, f := range l.Fixtures {
+		results[f.EventID], err = client.EventResults(context.Background(), f.EventID)
+		if err != nil {
+			return fmt.Errorf("getting results for %d: %w", f.EventID, err)
+		}
+		indexNames(nil, results[f.EventID])
+	}
+
+	f, err := setOutput(Filename, l.ClubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, leagueColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	masks := publicMasks()
+	for _, t := range l.Tables(results) {
+		for i, s := range t.Standings {
+			err = writer.WriteRow([]string{
+				t.Division,
+				strconv.Itoa(i + 1),
+				strings.TrimSpace(masks.Name(s.Zwid, s.Name)),
+				strconv.Itoa(s.Zwid),
+				strconv.Itoa(s.Points),
+				strconv.Itoa(s.Fixtures),
+				strconv.Itoa(s.Wins),
+				strconv.Itoa(s.Best),
+			})
+			if err != nil {
+				return fmt.Errorf("writing to file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+var attendanceColumns = []zp.Column{
+	{Key: "date", Header: "Date"},
+	{Key: "event", Header: "Event ID"},
+	{Key: "title", Header: "Fixture"},
+	{Key: "division", Header: "Division"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "status", Header: "Status"},
+	{Key: "unregistered", Header: "Unregistered"},
+}
+
+// LeagueAttendance imports the signups and results of each fixture and writes out who
+// turned up. If missing is set, it only lists riders who haven't signed up for
+// fixtures without results yet, for captains to chase.
+func LeagueAttendance(name string, missing bool) error {
+	l, attendance, results, err := leagueAttendance(name)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, fa := range attendance {
+		f := fa.Fixture
+		for _, r := range fa.Riders {
+			if missing && (r.Status != zp.Missing || len(results[f.EventID]) > 0) {
+				continue
+			}
+			unregistered := ""
+			if r.Unregistered {
+				unregistered = "yes"
+			}
+			rows = append(rows, []string{
+				f.Date.Format("2006-01-02"),
+				strconv.Itoa(f.EventID),
+				f.Title,
+				r.Division,
+				strings.TrimSpace(r.Name),
+				strconv.Itoa(r.Zwid),
+				string(r.Status),
+				unregistered,
+			})
+		}
+	}
+
+	return writeRows(l.ClubID, attendanceColumns, len(rows), func(i int) []string {
+		return rows[i]
+	})
+}
+
+// leagueAttendance imports the signups and results of each of the league's fixtures, and
+// works out who turned up to each one
+func leagueAttendance(name string) (*zp.League, []zp.FixtureAttendance, map[int][]zp.Event, error) {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("opening store: %w", err)
+	}
+
+	l, err := store.League(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error getting client: %w", err)
+	}
+	ctx := context.Background()
+
+	roster, err := client.Club(ctx, l.ClubID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("getting club riders: %w", err)
+	}
+	// The roster doesn't have categories, but riders in the store do
+	for i, r := range roster {
+		if stored, err := store.Rider(r.Zwid); err == nil {
+			roster[i].Category = stored.Category
+		}
+	}
+
+	var signupEvents, resultEvents []zp.Event
+	for _, f := range l.Fixtures {
+		events, err := client.EventSignups(ctx, f.EventID)
+		if err != nil && !errors.Is(err, zp.ErrNotFound) {
+			return nil, nil, nil, fmt.Errorf("getting signups for %d: %w", f.EventID, err)
+		}
+		signupEvents = append(signupEvents, events...)
+
+		events, err = client.EventResults(ctx, f.EventID)
+		if err != nil && !errors.Is(err, zp.ErrNotFound) {
+			return nil, nil, nil, fmt.Errorf("getting results for %d: %w", f.EventID, err)
+		}
+		resultEvents = append(resultEvents, events...)
+	}
+	signups, results := l.FixtureEvents(signupEvents), l.FixtureEvents(resultEvents)
+	return l, l.Attendance(roster, signups, results), results, nil
+}
+
+// AttendanceMatrix writes out the league's attendance as a grid, with a row for each
+// rider and a column for each fixture, followed by how many they finished and didn't
+// show up for
+func AttendanceMatrix(name string) error {
+	l, attendance, _, err := leagueAttendance(name)
+	if err != nil {
+		return err
+	}
+	m := zp.NewAttendanceMatrix(attendance)
+
+	columns := []zp.Column{
+		{Key: "division", Header: "Division"},
+		{Key: "name", Header: "Name"},
+		{Key: "zwid", Header: "Zwid"},
+	}
+	for _, f := range m.Fixtures {
+		header := f.Title
+		if header == "" {
+			header = strconv.Itoa(f.EventID)
+		}
+		if !f.Date.IsZero() {
+			header = f.Date.Format("2006-01-02") + " " + header
+		}
+		columns = append(columns, zp.Column{Key: fmt.Sprintf("event%d", f.EventID), Header: header})
+	}
+	columns = append(columns, zp.Column{Key: "finished", Header: "Finished"}, zp.Column{Key: "noshows", Header: "No shows"})
+
+	return writeRows(l.ClubID, columns, len(m.Riders), func(i int) []string {
+		r := m.Riders[i]
+		row := []string{r.Division, strings.TrimSpace(r.Name), strconv.Itoa(r.Zwid)}
+		for _, a := range m.Cells[i] {
+			row = append(row, string(a))
+		}
+		return append(row, strconv.Itoa(m.Count(i, zp.Finished)), strconv.Itoa(m.Count(i, zp.NoShow)))
+	})
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// liveUpdate is sent to dashboard clients listening on the /events stream
+type liveUpdate struct {
+	Type  string   `json:"type"`
+	Rider zp.Rider `json:"rider"`
+}
+
+const (
+	updateJoined = "joined" // Rider has joined the club
+	updateLeft   = "left"   // Rider has left the club
+	updateResult = "result" // Rider has a new event result
+)
+
+// liveHub keeps track of the riders we've seen, and sends updates about changes to
+// everyone who is listening
+type liveHub struct {
+	mu      sync.Mutex
+	clients map[chan liveUpdate]bool
+	riders  map[int]zp.Rider
+	roster  map[int]zp.Rider // As listed on the club roster
+}
+
+var live = newLiveHub()
+
+func newLiveHub() *liveHub {
+	return &liveHub{
+		clients: make(map[chan liveUpdate]bool),
+		riders:  make(map[int]zp.Rider),
+	}
+}
+
+func (h *liveHub) subscribe() chan liveUpdate {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := make(chan liveUpdate, 16)
+	h.clients[c] = true
+	return c
+}
+
+func (h *liveHub) unsubscribe(c chan liveUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.clients, c)
+	close(c)
+}
+
+// publish must be called with the lock held
+func (h *liveHub) publish(u liveUpdate) {
+	for c := range h.clients {
+		select {
+		case c <- u:
+		default:
+			// Don't let one slow client hold everything up
+			log.Printf("Dropping %s update for a slow client", u.Type)
+		}
+	}
+}
+
+// rosterImported compares the club roster with the last one we saw, and sends updates
+// for riders who have joined or left. The first roster we see is just recorded. It
+// returns the updates it sent.
+func (h *liveHub) rosterImported(riders []zp.Rider) []liveUpdate {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var updates []liveUpdate
+	roster := make(map[int]zp.Rider, len(riders))
+	for _, r := range riders {
+		roster[r.Zwid] = r
+		if _, ok := h.roster[r.Zwid]; h.roster != nil && !ok {
+			updates = append(updates, liveUpdate{Type: updateJoined, Rider: r})
+		}
+	}
+
+	for id := range h.roster {
+		if _, ok := roster[id]; !ok {
+			r, ok := h.riders[id]
+			if !ok {
+				r = zp.Rider{Zwid: id}
+			}
+			updates = append(updates, liveUpdate{Type: updateLeft, Rider: r})
+			delete(h.riders, id)
+		}
+	}
+
+	h.roster = roster
+	for _, u := range updates {
+		h.publish(u)
+	}
+	return updates
+}
+
+// riderImported sends an update if the rider has a newer event than last time we looked,
+// returning the updates it sent
+func (h *liveHub) riderImported(r zp.Rider) []liveUpdate {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous, ok := h.riders[r.Zwid]
+	h.riders[r.Zwid] = r
+	if ok && r.LatestEventDate.After(previous.LatestEventDate) {
+		u := liveUpdate{Type: updateResult, Rider: r}
+		h.publish(u)
+		return []liveUpdate{u}
+	}
+	return nil
+}
+
+// ServeEvents streams live updates to the client as server-sent events
+func (h *liveHub) ServeEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	c := h.subscribe()
+	defer h.unsubscribe(c)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-stopping:
+			return
+		case u := <-c:
+			data, err := json.Marshal(u)
+			if err != nil {
+				log.Printf("marshalling update: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", u.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// rosterRider finds the rider on the club roster as of the latest import
+func (h *liveHub) rosterRider(riderID int) (zp.Rider, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.roster[riderID]
+	return r, ok
+}
+
+// clubRiders gets the riders in the club as of the latest import, in name order
+func (h *liveHub) clubRiders() []zp.Rider {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	riders := make([]zp.Rider, 0, len(h.roster))
+	for id, r := range h.roster {
+		if imported, ok := h.riders[id]; ok {
+			r = imported
+		}
+		riders = append(riders, r)
+	}
+
+	sort.Slice(riders, func(i, j int) bool {
+		return riders[i].Name < riders[j].Name
+	})
+	return riders
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// Mask means riders who have asked for their names not to be shown publicly get their
+// alias or initials instead, in exports and posts
+var Mask bool
+
+var maskColumns = []zp.Column{
+	{Key: "zwid", Header: "Zwift ID"},
+	{Key: "alias", Header: "Shown as"},
+	{Key: "date", Header: "Masked"},
+	{Key: "note", Header: "Note"},
+}
+
+// publicMasks gets the riders whose names to mask, from the store, if --mask says to
+func publicMasks() zp.Masks {
+	return storeMasks(StoreDir)
+}
+
+// storeMasks gets the riders whose names to mask from the store in dir, if --mask says
+// to and there is a store there
+func storeMasks(dir string) zp.Masks {
+	if !Mask {
+		return nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	store, err := openStore(dir)
+	if err != nil {
+		log.Printf("Opening store for masks: %v", err)
+		return nil
+	}
+	masks, err := store.Masks()
+	if err != nil {
+		log.Printf("Reading masks: %v", err)
+	}
+	return masks
+}
+
+// maskCommand manages the riders whose names are masked in public output, which are kept
+// in the store
+func maskCommand() *cobra.Command {
+	maskCmd := &cobra.Command{
+		Use:   "mask",
+		Short: "Manage riders who don't want their names on public pages",
+		Long: `Masked riders are kept in masks.json in the store (--store). With --mask (or ZP_MASK=1),
+exports, league tables, digests, milestones and reminders show their alias, or their
+initials if they haven't got one, instead of their name. Everything else, including the
+store, keeps their details in full.`,
+	}
+
+	var alias, note string
+	addCmd := &cobra.Command{
+		Use:   "add [rider ID...]",
+		Short: "Mask riders' names, or change their alias",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ids, err := parseIDs(args)
+			exitOnError(err, "reading rider IDs")
+			if alias != "" && len(ids) > 1 {
+				exitWith(fmt.Errorf("an alias is for one rider, got %d", len(ids)), "masking riders", exitUsage)
+			}
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			for _, id := range ids {
+				exitOnError(store.Mask(zp.Mask{Zwid: id, Alias: alias, Note: note}), fmt.Sprintf("masking %d", id))
+				log.Printf("Masked %d", id)
+			}
+		},
+	}
+	addCmd.Flags().StringVar(&alias, "alias", "", "Show this instead of their name, rather than their initials")
+	addCmd.Flags().StringVar(&note, "note", "", "How they asked, or anything else to remember")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [rider ID...]",
+		Short: "Show riders' names again",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ids, err := parseIDs(args)
+			exitOnError(err, "reading rider IDs")
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			for _, id := range ids {
+				exitOnError(store.Unmask(id), fmt.Sprintf("unmasking %d", id))
+			}
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the riders whose names are masked",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			masks, err := store.Masks()
+			exitOnError(err, "reading masks")
+			list := masks.List()
+			exitOnError(writeRows(0, maskColumns, len(list), func(i int) []string {
+				m := list[i]
+				shown := m.Alias
+				if shown == "" {
+					shown = "initials"
+				}
+				return []string{strconv.Itoa(m.Zwid), shown, m.Date.Format(time.RFC3339), m.Note}
+			}), "writing masks")
+		},
+	}
+
+	maskCmd.AddCommand(addCmd, removeCmd, listCmd)
+	return maskCmd
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var duplicateColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "clubs", Header: "Clubs"},
+	{Key: "count", Header: "Number of clubs"},
+}
+
+func duplicatesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "duplicates [club ID] [club ID...]",
+		Short: "Riders who are members of more than one of the clubs",
+		Long: `Compares the clubs' rosters and lists anyone on more than one of them, for leagues where
+riders may only race for one club.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubIDs, err := parseIDs(args)
+			exitOnError(err, "reading club IDs")
+			exitOnError(DuplicateMembers(clubIDs), "checking club memberships")
+		},
+	}
+}
+
+// DuplicateMembers writes out the riders who are on more than one of the clubs' rosters
+func DuplicateMembers(clubIDs []int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	rosters := make(map[int][]zp.Rider, len(clubIDs))
+	for _, id := range clubIDs {
+		riders, err := client.Club(context.Background(), id)
+		if err != nil {
+			return fmt.Errorf("getting riders for club %d: %w", id, err)
+		}
+		rosters[id] = riders
+		indexNames(riders, nil)
+	}
+
+	duplicates := zp.DuplicateMembers(rosters)
+	return writeRows(0, duplicateColumns, len(duplicates), func(i int) []string {
+		m := duplicates[i]
+		clubs := make([]string, len(m.Clubs))
+		for j, id := range m.Clubs {
+			clubs[j] = strconv.Itoa(id)
+		}
+		return []string{m.Name, strconv.Itoa(m.Zwid), strings.Join(clubs, " "), strconv.Itoa(len(m.Clubs))}
+	})
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// mirrorCommand keeps a local copy of the club's ZwiftPower data up to date, and
+// serves it to other tools
+func mirrorCommand() *cobra.Command {
+	m := &zp.Mirror{}
+	var every, maxAge time.Duration
+	var listen, maxSize string
+
+	mirrorCmd := &cobra.Command{
+		Use:   "mirror [club ID...]",
+		Short: "Keep a local copy of the clubs' ZwiftPower data, and serve it to other tools",
+		Long: `Copies the roster, rider profiles and recent event results for each club, and
+serves them with the same paths as ZwiftPower's cache3. Point other tools at the mirror
+with --base-url (or ZP_BASE_URL) so that only the mirror talks to ZwiftPower.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clubIDs, err := parseIDs(args)
+			exitOnError(err, "reading club IDs")
+			if len(clubIDs) == 0 {
+				clubIDs = []int{2672}
+			}
+
+			client, err := newClient()
+			exitOnError(err, "getting client")
+			m.Client = client.HTTP
+			m.DryRun = DryRun
+			m.OptOuts = client.OptOuts
+			m.Progress = client.Progress
+			m.Limits, err = cacheLimits(maxAge, maxSize)
+			if err != nil {
+				exitWith(err, "", exitUsage)
+			}
+
+			if listen == "" {
+				exitOnError(refreshMirror(context.Background(), m, clubIDs), "refreshing mirror")
+				return
+			}
+
+			go func() {
+				for {
+					if err := refreshMirror(context.Background(), m, clubIDs); err != nil {
+						log.Printf("Refreshing mirror: %v", err)
+					}
+					time.Sleep(every)
+				}
+			}()
+
+			log.Printf("Serving mirror of %s on %s", m.Dir, listen)
+			exitOnError(http.ListenAndServe(listen, m.Handler()), "serving mirror")
+		},
+	}
+
+	mirrorCmd.Flags().StringVar(&m.Dir, "dir", mirrorDir(), "Directory for the mirrored files")
+	mirrorCmd.Flags().IntVar(&m.Days, "days", 30, "Copy results for events from this many days ago")
+	mirrorCmd.Flags().DurationVar(&m.Pause, "pause", 2*time.Second, "Time to wait between requests to ZwiftPower")
+	mirrorCmd.Flags().DurationVar(&every, "every", 6*time.Hour, "Time between refreshes when serving")
+	mirrorCmd.Flags().StringVar(&listen, "listen", "", "Address to serve the mirror on, e.g. :8081. If not set, refresh once and exit.")
+	addLimitFlags(mirrorCmd, &maxAge, &maxSize)
+	return mirrorCmd
+}
+
+func refreshMirror(ctx context.Context, m *zp.Mirror, clubIDs []int) error {
+	var failed []int
+	for _, clubID := range clubIDs {
+		_, err := m.Refresh(ctx, clubID)
+		if err != nil {
+			log.Printf("Mirroring club %d: %v", clubID, err)
+			failed = append(failed, clubID)
+		}
+	}
+
+	if len(failed) > 0 {
+		err := fmt.Errorf("mirror failed for clubs %v", failed)
+		if len(failed) < len(clubIDs) {
+			return &zp.PartialError{Failed: len(failed), Total: len(clubIDs), Err: err}
+		}
+		return err
+	}
+	return nil
+}
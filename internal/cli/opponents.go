@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var opponentsColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "kind", Header: "Victim or rival"},
+	{Key: "opponent", Header: "Opponent"},
+	{Key: "opponentzwid", Header: "Opponent zwid"},
+	{Key: "team", Header: "Team"},
+	{Key: "races", Header: "Races"},
+	{Key: "record", Header: "Record"},
+}
+
+var matchupsColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "rival", Header: "Rival"},
+	{Key: "rivalzwid", Header: "Rival zwid"},
+	{Key: "races", Header: "Races"},
+	{Key: "record", Header: "Record"},
+}
+
+func opponentsCommand() *cobra.Command {
+	var clubID int
+	var clubOnly, matchups bool
+
+	opponentsCmd := &cobra.Command{
+		Use:   "opponents [rider ID...]",
+		Short: "The riders each rider beats (victims) and loses to (rivals) most often, from ZwiftPower",
+		Long: `Lists the victims and rivals on each rider's ZwiftPower profile, with how many races
+they've met in and the rider's record against them. --club-only keeps opponents who are
+in the club. --matchups pairs up clubmates who often race each other instead, for seeding
+races within the club; with no rider IDs, it looks at everyone in the club (up to --limit).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			riderIDs, err := parseIDs(args)
+			exitOnError(err, "reading rider IDs")
+			if len(riderIDs) == 0 && !matchups {
+				exitWith(fmt.Errorf("give rider IDs, or --matchups for the whole club"), "", exitUsage)
+			}
+			exitOnError(RiderOpponents(clubID, riderIDs, clubOnly, matchups), "getting opponents")
+		},
+	}
+	opponentsCmd.Flags().IntVar(&clubID, "club", 2672, "The club, for riders' names, --club-only and --matchups")
+	opponentsCmd.Flags().BoolVar(&clubOnly, "club-only", false, "Only list opponents who are in the club")
+	opponentsCmd.Flags().BoolVar(&matchups, "matchups", false, "List pairs of clubmates who often race each other")
+	return opponentsCmd
+}
+
+// RiderOpponents writes out the riders' victims and rivals, or the matchups between
+// clubmates among them
+func RiderOpponents(clubID int, riderIDs []int, clubOnly bool, matchups bool) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	// The roster also gives us the riders' names, which aren't in their opponents
+	club, err := client.Club(context.Background(), clubID)
+	if err != nil {
+		return fmt.Errorf("getting club %d: %w", clubID, err)
+	}
+	disambiguate(club)
+	if len(riderIDs) == 0 {
+		for i, r := range club {
+			if Limit > 0 && i >= Limit {
+				log.Printf("Limiting to %d riders", Limit)
+				break
+			}
+			riderIDs = append(riderIDs, r.Zwid)
+		}
+	}
+
+	var all []zp.Opponents
+	for i, id := range riderIDs {
+		o, err := client.Opponents(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		if clubOnly {
+			o = o.Among(club)
+		}
+		all = append(all, o)
+		if client.Progress != nil {
+			client.Progress(i+1, len(riderIDs), strconv.Itoa(id))
+		}
+	}
+
+	masks := publicMasks()
+	if matchups {
+		m := zp.Matchups(club, all)
+		return writeRows(clubID, matchupsColumns, len(m), func(i int) []string {
+			h := m[i]
+			return []string{
+				masks.Name(h.Zwid, h.Name),
+				strconv.Itoa(h.Zwid),
+				masks.Name(h.RivalZwid, h.RivalName),
+				strconv.Itoa(h.RivalZwid),
+				strconv.Itoa(h.Races),
+				h.Record(),
+			}
+		})
+	}
+
+	names := make(map[int]string)
+	for _, r := range club {
+		names[r.Zwid] = r.Name
+	}
+	var rows [][]string
+	for _, o := range all {
+		name := names[o.Zwid]
+		for _, kind := range []struct {
+			name      string
+			opponents []zp.Opponent
+		}{{"victim", o.Victims}, {"rival", o.Rivals}} {
+			for _, opp := range kind.opponents {
+				rows = append(rows, []string{
+					masks.Name(o.Zwid, name),
+					strconv.Itoa(o.Zwid),
+					kind.name,
+					masks.Name(opp.Zwid, opp.Name),
+					strconv.Itoa(opp.Zwid),
+					opp.TeamName,
+					strconv.Itoa(int(opp.Races)),
+					fmt.Sprintf("%d-%d", int(opp.Wins), int(opp.Losses)),
+				})
+			}
+		}
+	}
+	return writeRows(clubID, opponentsColumns, len(rows), func(i int) []string {
+		return rows[i]
+	})
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var optOutColumns = []zp.Column{
+	{Key: "zwid", Header: "Zwift ID"},
+	{Key: "date", Header: "Opted out"},
+	{Key: "note", Header: "Note"},
+}
+
+// storeOptOuts gets the riders who have opted out, from the store in dir if there is one
+func storeOptOuts(dir string) zp.OptOuts {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	store, err := openStore(dir)
+	if err != nil {
+		log.Printf("Opening store for opt-outs: %v", err)
+		return nil
+	}
+	optOuts, err := store.OptOuts()
+	if err != nil {
+		log.Printf("Reading opt-outs: %v", err)
+	}
+	return optOuts
+}
+
+// optOutCommand manages the riders who have asked to be left out, which are kept in the
+// store
+func optOutCommand() *cobra.Command {
+	optOutCmd := &cobra.Command{
+		Use:   "optout",
+		Short: "Manage riders who have asked for their data not to be kept",
+		Long: `Opted-out riders are kept in optouts.json in the store (--store). Every command leaves
+them out of what it imports, so they don't appear in exports, digests, notifications or
+the dashboard, and the store won't keep anything about them.`,
+	}
+
+	var note string
+	addCmd := &cobra.Command{
+		Use:   "add [rider ID...]",
+		Short: "Opt riders out, deleting everything the store has about them",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ids, err := parseIDs(args)
+			exitOnError(err, "reading rider IDs")
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			for _, id := range ids {
+				exitOnError(store.OptOut(zp.OptOut{Zwid: id, Note: note}), fmt.Sprintf("opting out %d", id))
+				log.Printf("Opted out %d", id)
+			}
+		},
+	}
+	addCmd.Flags().StringVar(&note, "note", "", "How they asked, or anything else to remember")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [rider ID...]",
+		Short: "Opt riders back in, so their data is kept again from the next import",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ids, err := parseIDs(args)
+			exitOnError(err, "reading rider IDs")
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			for _, id := range ids {
+				exitOnError(store.OptIn(id), fmt.Sprintf("opting in %d", id))
+			}
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the riders who have opted out",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			list := storeOptOuts(StoreDir).List()
+			exitOnError(writeRows(0, optOutColumns, len(list), func(i int) []string {
+				o := list[i]
+				return []string{strconv.Itoa(o.Zwid), o.Date.Format(time.RFC3339), o.Note}
+			}), "writing opt-outs")
+		},
+	}
+
+	optOutCmd.AddCommand(addCmd, removeCmd, listCmd)
+	return optOutCmd
+}
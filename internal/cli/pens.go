@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var penColumns = []zp.Column{
+	{Key: "category", Header: "Category"},
+	{Key: "riders", Header: "Riders"},
+	{Key: "club", Header: "Club riders"},
+	{Key: "share", Header: "Club share"},
+	{Key: "need", Header: "Need"},
+	{Key: "names", Header: "Signed up"},
+	{Key: "candidates", Header: "Could ask"},
+}
+
+func pensCommand() *cobra.Command {
+	var clubID, target int
+	var suggest bool
+
+	pensCmd := &cobra.Command{
+		Use:   "pens [event ID]",
+		Short: "For captains: how many club riders have signed up in each pen of an upcoming event",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			exitOnError(PenBalance(eventID, clubID, target, suggest), fmt.Sprintf("checking pens for event %d", eventID))
+		},
+	}
+	pensCmd.Flags().IntVar(&clubID, "club", 2672, "Club whose riders to count")
+	pensCmd.Flags().IntVar(&target, "target", 3, "Club riders wanted in each pen for team tactics")
+	pensCmd.Flags().BoolVar(&suggest, "suggest", false, "Suggest club riders for pens that are short, which means importing the whole club")
+	return pensCmd
+}
+
+// PenBalance writes out the club's representation in each pen of the event, and how many
+// more riders each pen needs
+func PenBalance(eventID int, clubID int, target int, suggest bool) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	signups, err := client.EventSignups(context.Background(), eventID)
+	if err != nil {
+		return err
+	}
+
+	var roster []zp.Rider
+	if suggest {
+		roster, err = clubRiders(client, clubID)
+		if err != nil {
+			return err
+		}
+	}
+
+	pens := zp.PenBalance(signups, strconv.Itoa(clubID), target, roster)
+	return writeRows(eventID, penColumns, len(pens), func(i int) []string {
+		p := pens[i]
+		var names, candidates []string
+		for _, e := range p.Club {
+			names = append(names, e.RiderName())
+		}
+		for _, r := range p.Candidates {
+			candidates = append(candidates, r.Name)
+		}
+		return []string{
+			p.Category,
+			strconv.Itoa(p.Riders),
+			strconv.Itoa(len(p.Club)),
+			fmt.Sprintf("%.0f%%", 100*p.Share),
+			strconv.Itoa(p.Need),
+			strings.Join(names, ", "),
+			strings.Join(candidates, ", "),
+		}
+	})
+}
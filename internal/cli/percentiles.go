@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var percentileColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "category", Header: "Category"},
+	{Key: "ftp90", Header: "FTP 90 days"},
+	{Key: "percentile", Header: "Club percentile"},
+	{Key: "catpercentile", Header: "Category percentile"},
+	{Key: "previous", Header: "FTP 90 days ago"},
+	{Key: "change", Header: "Change"},
+}
+
+func percentilesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "percentiles [club ID]",
+		Short: "Where each rider's FTP sits in the club, overall and in their category, and how that has changed",
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			exitOnError(FtpPercentiles(clubID), fmt.Sprintf("getting FTP percentiles for %d", clubID))
+		},
+	}
+}
+
+// FtpPercentiles writes out each rider's FTP percentile within the club and their
+// category, and the change over the last 90 days
+func FtpPercentiles(clubID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := clubEvents(client, clubID)
+	if err != nil {
+		return err
+	}
+
+	ranks := zp.FtpPercentiles(events, time.Now())
+	return writeRows(clubID, percentileColumns, len(ranks), func(i int) []string {
+		r := ranks[i]
+		previous, change := "", ""
+		if r.PreviousFtp > 0 {
+			previous = strconv.FormatFloat(r.PreviousFtp, 'f', 1, 64)
+			change = fmt.Sprintf("%+.0f", r.Change)
+		}
+		return []string{
+			r.Name,
+			strconv.Itoa(r.Zwid),
+			r.Category,
+			strconv.FormatFloat(r.Ftp, 'f', 1, 64),
+			strconv.FormatFloat(r.Percentile, 'f', 0, 64),
+			strconv.FormatFloat(r.CategoryPercentile, 'f', 0, 64),
+			previous,
+			change,
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var placingsColumns = []zp.Column{
+	{Key: "date", Header: "Date"},
+	{Key: "event", Header: "Event"},
+	{Key: "zid", Header: "Event ID"},
+	{Key: "category", Header: "Category"},
+	{Key: "position", Header: "Position"},
+	{Key: "field", Header: "Field"},
+	{Key: "winnerwkg", Header: "Winner w/kg"},
+	{Key: "percentile", Header: "Percentile"},
+	{Key: "score", Header: "Score"},
+}
+
+func placingsCommand() *cobra.Command {
+	var days int
+
+	placingsCmd := &cobra.Command{
+		Use:   "placings [rider ID]",
+		Short: "A rider's placings in each race, adjusted for how big and fast the field was",
+		Long: `Positions in a 20-rider pen and a 150-rider pen aren't comparable, so each placing is
+turned into the share of the category the rider beat, pulled towards the middle for small
+fields, and scaled by how fast the winner was compared with the rider's other races. The
+middle of an average field scores 50, and winning a big one close to 100. This fetches the
+results of every race, so it takes a while.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 0)
+			exitOnError(Placings(riderID, days), fmt.Sprintf("getting placings for %d", riderID))
+		},
+	}
+	placingsCmd.Flags().IntVar(&days, "days", 90, "Include races from this many days ago")
+	return placingsCmd
+}
+
+// Placings writes out the rider's normalized placings in their races over the last so
+// many days, oldest first
+func Placings(riderID int, days int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	ctx := context.Background()
+	events, err := client.Events(ctx, riderID)
+	if err != nil {
+		return err
+	}
+
+	now := reportTime()
+	var recent []zp.Event
+	for _, e := range events {
+		if !e.EventDate.After(now) {
+			recent = append(recent, e)
+		}
+	}
+	results, err := client.RaceResults(ctx, recent, now.AddDate(0, 0, -days))
+	if err != nil {
+		return err
+	}
+
+	placings := zp.NormalizePlacings(riderID, recent, results)
+	log.Printf("Average score for %d over %d races: %.1f", riderID, len(placings), zp.AverageScore(placings))
+
+	return writeRows(riderID, placingsColumns, len(placings), func(i int) []string {
+		p := placings[i]
+		return []string{
+			p.Date.Format("2006-01-02"),
+			p.Title,
+			p.Zid,
+			p.Field.Category,
+			strconv.Itoa(p.Position),
+			strconv.Itoa(p.Field.Size),
+			strconv.FormatFloat(p.Field.WinnerWkg, 'f', 1, 64),
+			strconv.FormatFloat(p.Percentile, 'f', 0, 64),
+			strconv.FormatFloat(p.Score, 'f', 1, 64),
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+func podiumCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "podium [event ID] [club ID]",
+		Short: "Draw the club's top three finishers in an event as a PNG to share",
+		Long: `Draws the club's best three results in the event, by position in category, on a
+podium in the club's ZwiftPower team colours. The image is written to --filename, or
+podium-<event ID>.png if that isn't set.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			clubID := getID(args[1:], 2672)
+			exitOnError(WritePodium(eventID, clubID), fmt.Sprintf("drawing podium for %d", eventID))
+		},
+	}
+}
+
+// WritePodium draws the club's podium for the event
+func WritePodium(eventID int, clubID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	results, err := client.EventResults(context.Background(), eventID)
+	if err != nil {
+		return err
+	}
+	p := zp.NewPodium(eventID, results, strconv.Itoa(clubID))
+	if len(p.Places) == 0 {
+		log.Printf("No finishers from club %d in event %d", clubID, eventID)
+	}
+
+	filename := Filename
+	if filename == "" {
+		filename = fmt.Sprintf("podium-%d.png", eventID)
+	}
+	f, err := createFile(filename)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", filename, err)
+	}
+	err = writePodiumPNG(f, p)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		log.Printf("Podium for event %d written to %s", eventID, filename)
+	}
+	return err
+}
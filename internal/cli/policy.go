@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var policyColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "status", Header: "Status"},
+	{Key: "rule", Header: "Rule"},
+	{Key: "count", Header: "Count"},
+	{Key: "deadline", Header: "Grace until"},
+}
+
+func policyCommand() *cobra.Command {
+	var policyFile string
+	var all bool
+
+	policyCmd := &cobra.Command{
+		Use:   "policy [club ID]",
+		Short: "Check every rider in the club against the club's membership policy",
+		Long: `Reads the policy's rules from a JSON file, for example
+
+  {"Name": "Active members", "Exempt": [98588],
+   "Rules": [{"Min": 1, "Days": 60, "Grace": 14}, {"Min": 2, "Days": 180, "Races": true}]}
+
+and lists riders in breach of it first, then riders in their grace period.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			exitOnError(PolicyCompliance(clubID, policyFile, all), fmt.Sprintf("checking policy for %d", clubID))
+		},
+	}
+	policyCmd.Flags().StringVar(&policyFile, "file", configFile("ZP_POLICY", "policy.json"), "JSON file with the membership policy")
+	policyCmd.Flags().BoolVar(&all, "all", false, "Include riders who meet the policy, or are exempt")
+	return policyCmd
+}
+
+// PolicyCompliance writes out how each rider in the club stands against the policy
+func PolicyCompliance(clubID int, policyFile string, all bool) error {
+	if policyFile == "" {
+		return fmt.Errorf("no policy file: use --file or ZP_POLICY, or put policy.json in %s", configDir())
+	}
+	data, err := ioutil.ReadFile(policyFile)
+	if err != nil {
+		return err
+	}
+	policy, err := zp.ParsePolicy(data)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	riders, err := client.Club(context.Background(), clubID)
+	if err != nil {
+		return fmt.Errorf("error in ImportZP: %w", err)
+	}
+	if Limit > 0 && len(riders) > Limit {
+		riders = riders[:Limit]
+	}
+	events, err := rosterEvents(client, riders)
+	if err != nil {
+		return err
+	}
+
+	var results []zp.Compliance
+	for _, c := range policy.Evaluate(riders, events, joinDates(clubID), time.Now()) {
+		if all || (c.Status != zp.PolicyOK && c.Status != zp.PolicyExempt) {
+			results = append(results, c)
+		}
+	}
+
+	return writeRows(clubID, policyColumns, len(results), func(i int) []string {
+		c := results[i]
+		var count string
+		if c.Rule != "" {
+			count = strconv.Itoa(c.Count)
+		}
+		return []string{c.Name, strconv.Itoa(c.Zwid), c.Status, c.Rule, count, formatDate(c.Deadline)}
+	})
+}
+
+// joinDates gets when riders joined the club from its stints in the store, if there is
+// one, so that the policy gives new members time to ride
+func joinDates(clubID int) map[int]time.Time {
+	if _, err := os.Stat(StoreDir); os.IsNotExist(err) {
+		return nil
+	}
+	store, err := openStore(StoreDir)
+	if err != nil {
+		log.Printf("Opening store for join dates: %v", err)
+		return nil
+	}
+	stints, err := store.Stints(clubID)
+	if err != nil {
+		log.Printf("Reading stints: %v", err)
+	}
+	return zp.JoinDates(stints)
+}
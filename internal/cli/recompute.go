@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+func recomputeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "recompute [club ID]",
+		Short: "Work out the club's stats as they were on --as-of, from the store alone",
+		Long: `Recomputes every rider's stats as of the end of the --as-of date from the events in the
+store (--store), without asking ZwiftPower for anything, and writes them out like the
+zwiftpower command does. The riders are those in the club's latest snapshot from on or
+before that date, or everyone in the store if there isn't one. Run backfill first so the
+store has riders' full histories.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			exitOnError(Recompute(clubID), fmt.Sprintf("recomputing stats for %d", clubID))
+		},
+	}
+}
+
+// Recompute writes out the club's stats as they were at the --as-of date, using only
+// what's in the store
+func Recompute(clubID int) error {
+	if asOf.IsZero() {
+		return fmt.Errorf("needs a date to recompute the stats for, with --as-of")
+	}
+	if _, err := os.Stat(StoreDir); os.IsNotExist(err) {
+		return fmt.Errorf("no store at %s, so no events to recompute from", StoreDir)
+	}
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	snapshot, roster, err := zp.RosterAsOf(store, clubID, asOf)
+	switch {
+	case zp.IsNotFound(err):
+		log.Printf("%v; using every rider in the store", err)
+		roster, err = store.Riders()
+		if err != nil {
+			return fmt.Errorf("reading riders: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("reading snapshot: %w", err)
+	default:
+		log.Printf("Riders in the club from snapshot %s", snapshot)
+	}
+	if Limit > 0 && len(roster) > Limit {
+		log.Printf("Limiting to %d riders", Limit)
+		roster = roster[:Limit]
+	}
+
+	riders, missing, err := zp.RecomputeAsOf(store, roster, asOf, ftpAggregation)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		log.Printf("Left out riders with no stored events from before then: %v", missing)
+	}
+	disambiguate(riders)
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, zp.RiderColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for _, r := range riders {
+		err = writer.WriteRow(r.StringsAsOf(asOf))
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+	log.Printf("Recomputed %d riders as of %s", len(riders), asOf.Format("2006-01-02"))
+	return nil
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// RiderRefreshInterval is how long after re-importing a rider on request the server
+// waits before it will do that rider again
+var RiderRefreshInterval time.Duration
+
+// refreshLimiter allows each rider to be refreshed once per RiderRefreshInterval, so
+// that a rider who keeps asking can't send a stream of requests to ZwiftPower
+type refreshLimiter struct {
+	mu   sync.Mutex
+	last map[int]time.Time
+}
+
+func newRefreshLimiter() *refreshLimiter {
+	return &refreshLimiter{last: make(map[int]time.Time)}
+}
+
+// reserve takes the rider's refresh for now if they can have one, so that requests
+// arriving together can't all go to ZwiftPower. Otherwise it says how long until they
+// can. Only successful refreshes count, so call release if the refresh fails, and the
+// rider can try again straight away.
+func (l *refreshLimiter) reserve(riderID int, now time.Time) (wait time.Duration, release func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, ok := l.last[riderID]
+	if wait := previous.Add(RiderRefreshInterval).Sub(now); wait > 0 {
+		return wait, nil
+	}
+	l.last[riderID] = now
+	return 0, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !ok {
+			delete(l.last, riderID)
+			return
+		}
+		l.last[riderID] = previous
+	}
+}
+
+// riderRefresher re-imports one of the club's riders at a time on request, for when a
+// rider's new result isn't showing yet and they don't want to wait for the whole club
+type riderRefresher struct {
+	clubID  int
+	hub     *liveHub
+	dir     string // Store the refreshed rider is kept in, if the club's riders are stored
+	limiter *refreshLimiter
+}
+
+// ServeHTTP handles POST /riders/<id>/refresh, replying with the refreshed rider
+func (f *riderRefresher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "riders" || parts[2] != "refresh" {
+		http.NotFound(w, r)
+		return
+	}
+	riderID, err := strconv.Atoi(parts[1])
+	if err != nil || riderID <= 0 {
+		http.Error(w, fmt.Sprintf("bad rider ID %q", parts[1]), http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST to refresh a rider", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clubRider, ok := f.clubRider(riderID)
+	if !ok {
+		http.Error(w, fmt.Sprintf("rider %d isn't in club %d as of the last import", riderID, f.clubID), http.StatusNotFound)
+		return
+	}
+	wait, release := f.limiter.reserve(riderID, time.Now())
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds()+0.5)))
+		http.Error(w, fmt.Sprintf("rider %d was refreshed recently, try again in %s", riderID, wait.Round(time.Second)), http.StatusTooManyRequests)
+		return
+	}
+
+	rider, err := f.refresh(r.Context(), clubRider)
+	if err != nil {
+		release()
+		log.Printf("Refreshing rider %d: %v", riderID, err)
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rider)
+}
+
+// clubRider finds the rider on the club roster as of the last import, or failing that
+// in the store, so that only the club's own riders can be refreshed
+func (f *riderRefresher) clubRider(riderID int) (zp.Rider, bool) {
+	if r, ok := f.hub.rosterRider(riderID); ok {
+		return r, true
+	}
+	if f.dir == "" {
+		return zp.Rider{}, false
+	}
+	store, err := openStore(f.dir)
+	if err != nil {
+		return zp.Rider{}, false
+	}
+	r, err := store.Rider(riderID)
+	if err != nil || r.Archived != nil {
+		return zp.Rider{}, false
+	}
+	return r, true
+}
+
+// refresh re-imports the rider as a club import would, telling the hub's listeners and
+// the bus about a new result, and storing the rider if the club's riders are stored
+func (f *riderRefresher) refresh(ctx context.Context, clubRider zp.Rider) (zp.Rider, error) {
+	client, err := newClientFor(f.dir)
+	if err != nil {
+		return zp.Rider{}, fmt.Errorf("error getting client: %w", err)
+	}
+	rider, err := client.ClubRider(ctx, clubRider)
+	if err != nil {
+		return zp.Rider{}, fmt.Errorf("loading data for %s (%d): %w", clubRider.Name, clubRider.Zwid, err)
+	}
+	if ZwiftToken != "" {
+		if err := zp.AddZwiftProfile(ctx, client.HTTP, ZwiftToken, &rider); err != nil {
+			log.Printf("No Zwift profile for %s (%d): %v", rider.Name, rider.Zwid, err)
+		}
+	}
+
+	updates := f.hub.riderImported(rider)
+	bus.publish(ctx, f.clubID, zp.BusRiderImported, rider)
+	bus.publishUpdates(ctx, f.clubID, updates)
+
+	if f.dir != "" {
+		store, err := openStore(f.dir)
+		if err != nil {
+			return zp.Rider{}, err
+		}
+		if err := store.PutRider(rider); err != nil {
+			return zp.Rider{}, fmt.Errorf("storing %s: %w", rider.Name, err)
+		}
+	}
+	log.Printf("Refreshed rider %s (%d) in club %d on request", rider.Name, rider.Zwid, f.clubID)
+	return rider, nil
+}
+
+// statusFor picks the HTTP status for a failed refresh: ZwiftPower not having the rider,
+// or being unavailable for now, or otherwise failing
+func statusFor(err error) int {
+	switch {
+	case zp.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, zp.ErrRateLimited), errors.Is(err, zp.ErrChallenged), errors.Is(err, zp.ErrMaintenance):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
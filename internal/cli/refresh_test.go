@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+func TestRefreshLimiter(t *testing.T) {
+	old := RiderRefreshInterval
+	RiderRefreshInterval = 5 * time.Minute
+	defer func() { RiderRefreshInterval = old }()
+
+	l := newRefreshLimiter()
+	now := time.Now()
+	wait, release := l.reserve(1, now)
+	if wait != 0 {
+		t.Errorf("Expected no wait before the first refresh, got %s", wait)
+	}
+	// A refresh that failed doesn't count
+	release()
+	if wait, _ := l.reserve(1, now); wait != 0 {
+		t.Errorf("Expected no wait after a released refresh, got %s", wait)
+	}
+
+	if wait, _ := l.reserve(1, now.Add(time.Minute)); wait != 4*time.Minute {
+		t.Errorf("Expected to wait 4m, got %s", wait)
+	}
+	if wait, _ := l.reserve(2, now); wait != 0 {
+		t.Errorf("Expected other riders not to wait, got %s", wait)
+	}
+	if wait, _ := l.reserve(1, now.Add(5*time.Minute)); wait != 0 {
+		t.Errorf("Expected no wait after the interval, got %s", wait)
+	}
+}
+
+func TestRiderRefresherConcurrently(t *testing.T) {
+	oldInterval, oldBaseURL := RiderRefreshInterval, zp.BaseURL
+	RiderRefreshInterval = 5 * time.Minute
+	defer func() { RiderRefreshInterval, zp.BaseURL = oldInterval, oldBaseURL }()
+
+	var mu sync.Mutex
+	fetched := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cache3/profile/1_all.json" {
+			mu.Lock()
+			fetched++
+			mu.Unlock()
+			// Slow enough that the other requests arrive while this one is going
+			time.Sleep(50 * time.Millisecond)
+		}
+		fmt.Fprintf(w, `{"data":[{"zid":"10","zwid":1,"event_date":%d}]}`, time.Now().Unix())
+	}))
+	defer ts.Close()
+	zp.BaseURL = ts.URL
+
+	hub := newLiveHub()
+	hub.rosterImported([]zp.Rider{{Zwid: 1, Name: "One"}})
+	f := &riderRefresher{clubID: 123, hub: hub, limiter: newRefreshLimiter()}
+
+	codes := make([]int, 10)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			f.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/riders/1/refresh", nil))
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			ok++
+		case http.StatusTooManyRequests:
+		default:
+			t.Errorf("Unexpected status %d", code)
+		}
+	}
+	if ok != 1 || fetched != 1 {
+		t.Errorf("Expected one refresh to go to ZwiftPower, got %d OK and %d fetches", ok, fetched)
+	}
+}
+
+func TestRiderRefresher(t *testing.T) {
+	oldInterval, oldBaseURL, oldStoreDir := RiderRefreshInterval, zp.BaseURL, StoreDir
+	RiderRefreshInterval = 5 * time.Minute
+	StoreDir = t.TempDir()
+	defer func() { RiderRefreshInterval, zp.BaseURL, StoreDir = oldInterval, oldBaseURL, oldStoreDir }()
+
+	missing := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/profile/1_all.json":
+			fmt.Fprintf(w, `{"data":[{"zid":"10","zwid":1,"event_date":%d}]}`, time.Now().Unix())
+		case "/cache3/profile/2_all.json":
+			// ZwiftPower doesn't have rider 2 the first time
+			if missing {
+				missing = false
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"data":[{"zid":"20","zwid":2,"event_date":%d}]}`, time.Now().Unix())
+		}
+	}))
+	defer ts.Close()
+	zp.BaseURL = ts.URL
+
+	hub := newLiveHub()
+	hub.rosterImported([]zp.Rider{{Zwid: 1, Name: "One"}, {Zwid: 2, Name: "Two"}})
+	dir := t.TempDir()
+	f := &riderRefresher{clubID: 123, hub: hub, dir: dir, limiter: newRefreshLimiter()}
+	post := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		f.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w
+	}
+
+	w := post("/riders/1/refresh")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got %d refreshing rider 1: %s", w.Code, w.Body)
+	}
+	var rider zp.Rider
+	if err := json.NewDecoder(w.Body).Decode(&rider); err != nil || rider.Zwid != 1 || rider.Name != "One" {
+		t.Errorf("Got %+v, %v", rider, err)
+	}
+	store, err := openStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := store.Rider(1); err != nil || stored.Name != "One" {
+		t.Errorf("Expected the refreshed rider in the store, got %+v, %v", stored, err)
+	}
+
+	if w := post("/riders/1/refresh"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "300" {
+		t.Errorf("Got %d with Retry-After %q refreshing again straight away", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// A failed refresh doesn't count towards the limit
+	if w := post("/riders/2/refresh"); w.Code != http.StatusNotFound {
+		t.Errorf("Got %d for a rider ZwiftPower doesn't have", w.Code)
+	}
+	if w := post("/riders/2/refresh"); w.Code != http.StatusOK {
+		t.Errorf("Got %d retrying after a failed refresh: %s", w.Code, w.Body)
+	}
+
+	if w := post("/riders/3/refresh"); w.Code != http.StatusNotFound {
+		t.Errorf("Got %d for a rider who isn't in the club", w.Code)
+	}
+	if w := post("/riders/x/refresh"); w.Code != http.StatusBadRequest {
+		t.Errorf("Got %d for a bad rider ID", w.Code)
+	}
+	if w := post("/riders/1"); w.Code != http.StatusNotFound {
+		t.Errorf("Got %d for an unknown path", w.Code)
+	}
+	w = httptest.NewRecorder()
+	f.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/riders/1/refresh", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Got %d for GET", w.Code)
+	}
+}
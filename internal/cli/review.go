@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var reviewColumns = []zp.Column{
+	{Key: "position", Header: "Position"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "category", Header: "Category"},
+	{Key: "check", Header: "Check"},
+	{Key: "detail", Header: "Detail"},
+}
+
+func reviewCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "review [event ID]",
+		Short: "For organizers: list results in an event that look odd enough to check",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			exitOnError(ReviewEvent(eventID), fmt.Sprintf("reviewing event %d", eventID))
+		},
+	}
+}
+
+// ReviewEvent writes out anomalies in the event's results for an organizer to look at
+func ReviewEvent(eventID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	results, err := client.EventResults(context.Background(), eventID)
+	if err != nil {
+		return err
+	}
+	indexNames(nil, results)
+
+	anomalies := zp.ReviewResults(results)
+	return writeRows(eventID, reviewColumns, len(anomalies), func(i int) []string {
+		a := anomalies[i]
+		return []string{strconv.Itoa(a.Pos), a.Name, strconv.Itoa(a.Zwid), a.Category, a.Check, a.Detail}
+	})
+}
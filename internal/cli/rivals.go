@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var rivalsColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "rival", Header: "Rival"},
+	{Key: "rivalzwid", Header: "Rival zwid"},
+	{Key: "races", Header: "Races"},
+	{Key: "record", Header: "Record"},
+	{Key: "margin", Header: "Average margin"},
+}
+
+func rivalsCommand() *cobra.Command {
+	var clubID, days int
+	var matrix bool
+
+	rivalsCmd := &cobra.Command{
+		Use:   "rivals [rider ID...]",
+		Short: "Head-to-head records between club members in the races they have shared",
+		Long: `Compares riders who finished the same race in the same category: who beat whom, and by
+how much on average. With no rider IDs, compares everyone in the club.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			riderIDs, err := parseIDs(args)
+			exitOnError(err, "reading rider IDs")
+			exitOnError(Rivals(clubID, riderIDs, time.Now().AddDate(0, 0, -days), matrix), "getting head-to-head records")
+		},
+	}
+	rivalsCmd.Flags().IntVar(&clubID, "club", 2672, "Club to compare if no riders are given")
+	rivalsCmd.Flags().IntVar(&days, "days", 365, "Include races from this many days ago")
+	rivalsCmd.Flags().BoolVar(&matrix, "matrix", false, "Write a grid of each rider's record against every other")
+	return rivalsCmd
+}
+
+// Rivals writes out head-to-head records between the riders, or everyone in the club if
+// there are none, either as a list of rivalries or as a matrix
+func Rivals(clubID int, riderIDs []int, since time.Time, matrix bool) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	var events []zp.Event
+	if len(riderIDs) == 0 {
+		events, err = clubEvents(client, clubID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range riderIDs {
+		ee, err := client.Events(context.Background(), id)
+		if err != nil {
+			return fmt.Errorf("loading events for %d: %w", id, err)
+		}
+		events = append(events, ee...)
+	}
+
+	m := zp.NewRivalryMatrix(events, riderIDs, since)
+	if matrix {
+		return writeRivalryMatrix(clubID, m)
+	}
+
+	rivalries := m.Rivalries()
+	return writeRows(clubID, rivalsColumns, len(rivalries), func(i int) []string {
+		h := rivalries[i]
+		return []string{
+			h.Name,
+			strconv.Itoa(h.Zwid),
+			h.RivalName,
+			strconv.Itoa(h.RivalZwid),
+			strconv.Itoa(h.Races),
+			h.Record(),
+			formatMargin(h.Margin),
+		}
+	})
+}
+
+// writeRivalryMatrix has a row for each rider, with their record against each of the
+// others in the columns
+func writeRivalryMatrix(clubID int, m zp.RivalryMatrix) error {
+	columns := []zp.Column{{Key: "name", Header: "Name"}}
+	for i, id := range m.Riders {
+		columns = append(columns, zp.Column{Key: strconv.Itoa(id), Header: m.Names[i]})
+	}
+
+	return writeRows(clubID, columns, len(m.Riders), func(i int) []string {
+		row := []string{m.Names[i]}
+		for j, h := range m.Records[i] {
+			switch {
+			case i == j:
+				row = append(row, "-")
+			case h.Races == 0:
+				row = append(row, "")
+			default:
+				row = append(row, h.Record())
+			}
+		}
+		return row
+	})
+}
+
+// formatMargin gives a margin in seconds as e.g. "+0:12" or "-1:05"
+func formatMargin(secs float64) string {
+	sign := "+"
+	if secs < 0 {
+		sign = "-"
+		secs = -secs
+	}
+	d := time.Duration(secs * float64(time.Second)).Round(time.Second)
+	return fmt.Sprintf("%s%d:%02d", sign, int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsCredentials come from the standard AWS environment variables
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+}
+
+func awsCredentialsFromEnv() (awsCredentials, error) {
+	c := awsCredentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		region:          os.Getenv("AWS_REGION"),
+	}
+
+	if c.region == "" {
+		c.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if c.region == "" {
+		c.region = "us-east-1"
+	}
+
+	if c.accessKeyID == "" || c.secretAccessKey == "" {
+		return c, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to write to S3")
+	}
+	return c, nil
+}
+
+// s3Writer collects everything written to it, and uploads it to S3 on Close. This keeps
+// us free of the whole AWS SDK just to put one object.
+type s3Writer struct {
+	ctx    context.Context
+	bucket string
+	key    string
+	creds  awsCredentials
+	buf    bytes.Buffer
+}
+
+func newS3Writer(ctx context.Context, bucket string, key string) (*s3Writer, error) {
+	creds, err := awsCredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Writer{
+		ctx:    ctx,
+		bucket: bucket,
+		key:    key,
+		creds:  creds,
+	}, nil
+}
+
+func (s *s3Writer) Write(p []byte) (n int, err error) {
+	return s.buf.Write(p)
+}
+
+// objectURL is virtual-hosted style on AWS, or path style if AWS_ENDPOINT_URL points
+// somewhere else, such as a MinIO server
+func (s *s3Writer) objectURL() string {
+	path := "/" + s3EscapePath(s.key)
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/" + s.bucket + path
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", s.bucket, s.creds.region, path)
+}
+
+func (s *s3Writer) Close() error {
+	body := s.buf.Bytes()
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPut, s.objectURL(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating S3 request: %w", err)
+	}
+
+	if ext := strings.LastIndex(s.key, "."); ext >= 0 {
+		if ct, ok := contentTypes[s.key[ext+1:]]; ok {
+			req.Header.Set("Content-Type", ct)
+		}
+	}
+
+	signS3Request(req, sha256Hex(body), s.creds, time.Now().UTC())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading to S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d uploading to S3: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath escapes each segment of an object key the way SigV4 expects
+func s3EscapePath(key string) string {
+	return awsURIEncode(key, false)
+}
+
+// awsURIEncode escapes everything but the unreserved characters, as SigV4 requires. That's
+// more than url.PathEscape does: it leaves $ and others as they are.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// signS3Request adds AWS Signature Version 4 headers to the request, signing the host
+// and all the headers already set on it
+func signS3Request(req *http.Request, payloadHash string, creds awsCredentials, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	canonical, signedHeaders := s3CanonicalRequest(req, payloadHash)
+	scope := strings.Join([]string{date, creds.region, "s3", "aws4_request"}, "/")
+	signature := s3Signature(creds, date, s3StringToSign(amzDate, scope, canonical))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+// s3CanonicalRequest is the request as SigV4 signs it, along with the headers it signs
+func s3CanonicalRequest(req *http.Request, payloadHash string) (canonical string, signedHeaders string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		// Values are trimmed, with runs of spaces inside them collapsed to one
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonical = strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	return canonical, signedHeaders
+}
+
+// s3CanonicalQuery sorts the parameters, escaping them as awsURIEncode does. A parameter
+// without a value, such as ?lifecycle, has an empty one.
+func s3CanonicalQuery(query url.Values) string {
+	var params [][2]string
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, [2]string{awsURIEncode(name, true), awsURIEncode(value, true)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+
+	encoded := make([]string, len(params))
+	for i, p := range params {
+		encoded[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+func s3StringToSign(amzDate string, scope string, canonical string) string {
+	return strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonical)),
+	}, "\n")
+}
+
+func s3Signature(creds awsCredentials, date string, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+creds.secretAccessKey), date)
+	key = hmacSHA256(key, creds.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
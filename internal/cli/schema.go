@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// schemas is shared by all our clients, and remembers the shape of ZwiftPower's JSON in
+// the store between runs, so that we warn when it changes. Get it with schemaWatch.
+var (
+	schemas     *zp.SchemaWatch
+	schemasOnce sync.Once
+)
+
+var schemaColumns = []zp.Column{
+	{Key: "endpoint", Header: "Endpoint"},
+	{Key: "fingerprint", Header: "Fingerprint"},
+	{Key: "fields", Header: "Fields"},
+}
+
+// schemaWatch gets the shared schema watch, loading the known shapes from the store the
+// first time. Without a store, shapes are only known for this run.
+func schemaWatch() *zp.SchemaWatch {
+	schemasOnce.Do(func() {
+		schemas = loadSchemaWatch()
+	})
+	return schemas
+}
+
+func loadSchemaWatch() *zp.SchemaWatch {
+	if _, err := os.Stat(StoreDir); os.IsNotExist(err) {
+		return zp.NewSchemaWatch(nil)
+	}
+	store, err := openStore(StoreDir)
+	if err != nil {
+		log.Printf("Opening store for schemas: %v", err)
+		return zp.NewSchemaWatch(nil)
+	}
+	known, err := store.Schemas()
+	if err != nil {
+		log.Printf("Reading schemas: %v", err)
+	}
+	w := zp.NewSchemaWatch(known)
+	w.OnLearn = func() {
+		if err := store.PutSchemas(w.Known()); err != nil {
+			log.Printf("Saving schemas: %v", err)
+		}
+	}
+	return w
+}
+
+func schemaCommand() *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "List the shape of ZwiftPower's JSON that we know, for each endpoint",
+		Long: `Every response from ZwiftPower is checked against the fields and types seen the first
+time we used that endpoint, kept in schemas.json in the store (--store). If fields go
+missing or change type, which would otherwise just leave zero values, a warning is
+logged, and counted at /schema when serving. Once you've dealt with a change, reset the
+endpoint so that its next response becomes the known shape.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			known, err := store.Schemas()
+			exitOnError(err, "reading schemas")
+			endpoints := sortedEndpoints(known)
+			exitOnError(writeRows(0, schemaColumns, len(endpoints), func(i int) []string {
+				s := known[endpoints[i]]
+				return []string{endpoints[i], s.Fingerprint(), strconv.Itoa(len(s))}
+			}), "writing schemas")
+		},
+	}
+
+	resetCmd := &cobra.Command{
+		Use:   "reset [endpoint...]",
+		Short: "Forget the shape of these endpoints, or all of them",
+		Run: func(cmd *cobra.Command, args []string) {
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			known, err := store.Schemas()
+			exitOnError(err, "reading schemas")
+			if len(args) == 0 {
+				args = sortedEndpoints(known)
+			}
+			for _, endpoint := range args {
+				if _, ok := known[endpoint]; !ok {
+					exitWith(fmt.Errorf("no schema for %s; see zwiftpower schema", endpoint), "", exitUsage)
+				}
+				delete(known, endpoint)
+			}
+			exitOnError(store.PutSchemas(known), "saving schemas")
+			log.Printf("Reset %s", strings.Join(args, ", "))
+		},
+	}
+
+	schemaCmd.AddCommand(resetCmd)
+	return schemaCmd
+}
+
+func sortedEndpoints(known map[string]zp.Schema) []string {
+	var endpoints []string
+	for endpoint := range known {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// serveSchema reports how many responses from ZwiftPower didn't have the shape we knew,
+// and how they'd changed
+func serveSchema(w http.ResponseWriter, r *http.Request) {
+	stats := schemaWatch().Stats()
+
+	type drift struct {
+		Endpoint string   `json:"endpoint"`
+		Missing  []string `json:"missing,omitempty"`
+		Changed  []string `json:"changed,omitempty"`
+		Added    []string `json:"added,omitempty"`
+	}
+	drifts := []drift{}
+	for _, d := range stats.Latest {
+		drifts = append(drifts, drift{d.Endpoint, d.Missing, d.Changed, d.Added})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Checked   int            `json:"checked"`
+		Drifted   int            `json:"drifted"`
+		Endpoints map[string]int `json:"endpoints"`
+		Drifts    []drift        `json:"drifts"`
+	}{stats.Checked, stats.Drifted, stats.Drifts, drifts})
+}
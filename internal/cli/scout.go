@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var scoutColumns = []zp.Column{
+	{Key: "category", Header: "Category"},
+	{Key: "rank", Header: "Rank"},
+	{Key: "team", Header: "Team"},
+	{Key: "teamid", Header: "Team ID"},
+	{Key: "riders", Header: "Riders"},
+	{Key: "scouted", Header: "With data"},
+	{Key: "wkg1", Header: "1 min w/kg"},
+	{Key: "wkg5", Header: "5 min w/kg"},
+	{Key: "wkg20", Header: "20 min w/kg"},
+	{Key: "ftpwkg", Header: "FTP w/kg"},
+	{Key: "strength", Header: "Strength"},
+}
+
+func scoutCommand() *cobra.Command {
+	var clubID, days int
+	var rivals []int
+
+	scoutCmd := &cobra.Command{
+		Use:   "scout [event ID]",
+		Short: "For captains: how strong each team looks in each pen of a fixture",
+		Long: `Looks up who has signed up for the fixture, imports each rider's recent events, and
+compares the teams in each pen by their riders' best 1, 5 and 20 minute w/kg. Our club
+is marked with a *. Without --rivals, every team in the fixture is included.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			since := time.Now().AddDate(0, 0, -days)
+			exitOnError(ScoutFixture(eventID, clubID, rivals, since), fmt.Sprintf("scouting event %d", eventID))
+		},
+	}
+	scoutCmd.Flags().IntVar(&clubID, "club", 2672, "Our club")
+	scoutCmd.Flags().IntSliceVar(&rivals, "rivals", nil, "Comma-separated IDs of the clubs we're up against")
+	scoutCmd.Flags().IntVar(&days, "days", 60, "Use power from events in this many days")
+	return scoutCmd
+}
+
+// ScoutFixture writes out a scouting report for each team in each pen of the event
+func ScoutFixture(eventID int, clubID int, rivals []int, since time.Time) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	signups, err := client.EventSignups(context.Background(), eventID)
+	if err != nil {
+		return err
+	}
+
+	club := strconv.Itoa(clubID)
+	teamIDs := make([]string, len(rivals))
+	wanted := map[string]bool{club: true}
+	for i, id := range rivals {
+		teamIDs[i] = strconv.Itoa(id)
+		wanted[teamIDs[i]] = true
+	}
+
+	histories := make(map[int][]zp.Event)
+	for _, e := range signups {
+		if len(rivals) > 0 && !wanted[e.TeamID] {
+			continue
+		}
+		if _, ok := histories[e.Zwid]; ok {
+			continue
+		}
+		events, err := client.Events(context.Background(), e.Zwid)
+		if err != nil {
+			log.Printf("No history for %s (%d): %v", e.RiderName(), e.Zwid, err)
+		}
+		histories[e.Zwid] = events
+	}
+
+	scouts := zp.ScoutFixture(signups, club, teamIDs, histories, since)
+	return writeRows(eventID, scoutColumns, len(scouts), func(i int) []string {
+		s := scouts[i]
+		team := s.Team
+		if s.Club {
+			team += " *"
+		}
+		return []string{
+			s.Category,
+			strconv.Itoa(s.Rank),
+			team,
+			s.TeamID,
+			strconv.Itoa(s.Riders),
+			strconv.Itoa(s.Scouted),
+			strconv.FormatFloat(s.Wkg1, 'f', 2, 64),
+			strconv.FormatFloat(s.Wkg5, 'f', 2, 64),
+			strconv.FormatFloat(s.Wkg20, 'f', 2, 64),
+			strconv.FormatFloat(s.FtpWkg, 'f', 2, 64),
+			strconv.FormatFloat(s.Strength, 'f', 2, 64),
+		}
+	})
+}
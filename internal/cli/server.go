@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout is how long the server waits, once it's told to stop, for imports and
+// other requests to finish before cancelling them
+var ShutdownTimeout time.Duration
+
+// ServeClubID is the club the server looks after, unless it has --tenants
+var ServeClubID = 2672
+
+var (
+	// stopping is closed when the server starts shutting down. From then on it isn't ready,
+	// scheduled jobs don't start, and live update streams end.
+	stopping = make(chan struct{})
+
+	// work is the context imports run in when they aren't part of a request. It's
+	// cancelled if they haven't finished by the end of ShutdownTimeout.
+	work, cancelWork = context.WithCancel(context.Background())
+
+	// jobs is held for reading by each scheduled job while it runs, so that taking it for
+	// writing waits for them all, and stops any more starting
+	jobs sync.RWMutex
+)
+
+// runJob runs a scheduled job, unless the server is shutting down
+func runJob(job func(ctx context.Context)) {
+	jobs.RLock()
+	defer jobs.RUnlock()
+	select {
+	case <-stopping:
+		return
+	default:
+	}
+	job(work)
+}
+
+// serveHealth is the liveness check: if the server can answer, it's alive
+func serveHealth(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// serveReady is the readiness check. The server isn't ready once it's shutting down, so
+// that it's taken out of the load balancer while it finishes, or if the store can't be
+// read.
+func serveReady(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-stopping:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+	if _, err := os.Stat(StoreDir); err != nil && !os.IsNotExist(err) {
+		http.Error(w, fmt.Sprintf("store: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// listen serves HTTP on the port until the process gets SIGTERM or SIGINT, then stops
+// taking new requests and gives those in progress, and any scheduled jobs, up to
+// ShutdownTimeout to finish
+func listen(port string, handler http.Handler) error {
+	srv := &http.Server{
+		Addr:        ":" + port,
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return work },
+	}
+
+	failed := make(chan error, 1)
+	go func() {
+		failed <- srv.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	select {
+	case err := <-failed:
+		return err
+	case s := <-signals:
+		log.Printf("Got %s, shutting down within %s", s, ShutdownTimeout)
+	}
+	close(stopping)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+
+	finished := make(chan struct{})
+	go func() {
+		jobs.Lock()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+	if ctx.Err() != nil {
+		log.Printf("Cancelling what's still running after %s", ShutdownTimeout)
+		cancelWork()
+	}
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	log.Printf("Shut down")
+	return nil
+}
@@ -1,4 +1,4 @@
-package main
+package cli
 
 import (
 	"context"
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/lizrice/zwiftpower/v2/zp"
 	"google.golang.org/api/sheets/v4"
 )
 
@@ -26,7 +28,7 @@ func NewSpreadsheetWriter(ctx context.Context, spreadsheetID string, spreadsheet
 	log.Printf("Getting new spreadsheetWriter")
 	srv, err := sheets.NewService(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("getting NewSpreadsheetWriter: %v", err)
+		return nil, fmt.Errorf("getting NewSpreadsheetWriter: %w", err)
 	}
 
 	// Start at row 2 to leave the header row in place
@@ -157,17 +159,69 @@ type rowWriter interface {
 	Flush()
 }
 
-func NewRowWriter(w io.Writer) rowWriter {
+// NewRowWriter gets a rowWriter for the output format. Columns are used by formats that
+// label their columns, and for choosing which columns to output and sorting.
+func NewRowWriter(w io.Writer, format string, columns []zp.Column) (rowWriter, error) {
+	indexes, selected, err := selectColumns(columns, Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	next := newFormatWriter(w, format, selected)
+	if Columns == "" && SortBy == "" {
+		return next, nil
+	}
+
+	s := &selectRows{
+		next:    next,
+		indexes: indexes,
+		sortBy:  -1,
+	}
+
+	if SortBy != "" {
+		s.desc = strings.HasPrefix(SortBy, "-")
+		s.sortBy, err = columnIndex(columns, strings.TrimPrefix(SortBy, "-"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// newFormatWriter gets a rowWriter for the output format, writing numbers and dates for
+// the locale in every format except JSON and NDJSON
+func newFormatWriter(w io.Writer, format string, columns []zp.Column) rowWriter {
+	next := formatWriter(w, format, columns)
+	if locale.Name == "" || format == formatJSON || format == formatNDJSON {
+		return next
+	}
+	return &localeRows{next: next, locale: locale}
+}
+
+func formatWriter(w io.Writer, format string, columns []zp.Column) rowWriter {
 	sw, ok := w.(*spreadsheetWriter)
 	if ok {
 		log.Printf("This is a spreadsheetWriter")
 		return sw
 	}
 
+	switch format {
+	case formatJSON:
+		return &jsonRows{w: w, columns: columns}
+	case formatNDJSON:
+		return newNDJSONRows(w, columns)
+	case formatHTML:
+		return &htmlRows{w: w, columns: columns}
+	case formatTable:
+		return newTableRows(w, columns)
+	}
+
 	log.Printf("This is a csv.Writer")
 	m := &myCSV{
 		csv.NewWriter(w),
 	}
+	m.Comma = locale.Comma
 
 	return m
 }
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// simulateCommand predicts the finishing order of riders on a route, for planning team
+// time trials and race tactics
+func simulateCommand() *cobra.Command {
+	var profile string
+	var distance, elevation float64
+	simulateCmd := &cobra.Command{
+		Use:   "simulate [ID...]",
+		Short: "Predict the finishing order and gaps for these riders on a route, or for the whole club if none are given",
+		Long: `Each rider rides solo at the steady power their last three months of results say they
+could hold for the whole route, with each segment of --profile at the speed that power gives
+on its gradient. The split at the end of each segment shows where riders gain and lose time,
+such as who drops back on the climbs, which helps with pull orders for a team time trial.
+It's a crude model: no drafting, no surges, and perfect pacing.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			riderIDs, err := parseIDs(args)
+			if err != nil {
+				exitWith(err, "", exitUsage)
+			}
+			route := zp.Course{Distance: distance, Elevation: elevation}.Profile()
+			if profile != "" {
+				route, err = zp.ParseRouteProfile(profile)
+				if err != nil {
+					exitWith(fmt.Errorf("--profile: %w", err), "", exitUsage)
+				}
+			}
+			if len(route) == 0 {
+				exitWith(fmt.Errorf("give a --profile, or a --distance"), "", exitUsage)
+			}
+			exitOnError(SimulateRace(2672, riderIDs, route), "simulating race")
+		},
+	}
+	simulateCmd.Flags().StringVarP(&profile, "profile", "p", "", "Route as km:gradient% segments in order, e.g. 10:0,1.2:7.5,3:-4")
+	simulateCmd.Flags().Float64VarP(&distance, "distance", "d", 20, "Route distance in km, without a --profile")
+	simulateCmd.Flags().Float64VarP(&elevation, "elevation", "e", 0, "Route elevation gain in metres, without a --profile")
+	return simulateCmd
+}
+
+// SimulateRace writes out the riders' predicted times on the route, fastest first, with
+// their gaps and a split at the end of each segment
+func SimulateRace(clubID int, riderIDs []int, route zp.RouteProfile) error {
+	profiles, err := powerProfiles(clubID, riderIDs)
+	if err != nil {
+		return err
+	}
+
+	columns := []zp.Column{
+		{Key: "name", Header: "Name"},
+		{Key: "zwid", Header: "Zwid"},
+		{Key: "time", Header: "Predicted time"},
+		{Key: "gap", Header: "Gap"},
+		{Key: "watts", Header: "Watts"},
+		{Key: "wkg", Header: "W/kg"},
+	}
+	var km float64
+	for i, seg := range route {
+		km += seg.Distance
+		columns = append(columns, zp.Column{
+			Key:    fmt.Sprintf("split%d", i+1),
+			Header: fmt.Sprintf("%gkm (%g%%)", km, seg.Gradient),
+		})
+	}
+	if len(route) == 1 {
+		// The only split is the finish
+		columns = columns[:len(columns)-1]
+	}
+
+	predictions := zp.Simulate(route, profiles)
+	return writeRows(clubID, columns, len(predictions), func(i int) []string {
+		p := predictions[i]
+		row := []string{p.Name, strconv.Itoa(p.Zwid), formatDuration(p.Time), formatDuration(p.Gap),
+			strconv.FormatFloat(p.Power, 'f', 0, 64), strconv.FormatFloat(p.WKg, 'f', 2, 64)}
+		if len(route) > 1 {
+			for _, split := range p.Splits {
+				row = append(row, formatDuration(split))
+			}
+		}
+		return row
+	})
+}
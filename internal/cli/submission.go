@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+func submissionCommand() *cobra.Command {
+	var clubID int
+
+	submissionCmd := &cobra.Command{
+		Use:   "submission [event ID]",
+		Short: "For results secretaries: the club's results in an event, laid out for submitting to a league",
+		Long: `Writes the club's finishers with their Zwift ID, name, category, time, average power and
+heart rate, in the columns community league organizers usually ask for. Use --club 0 to
+include everyone in the event.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			eventID := getID(args, 0)
+			exitOnError(SubmitResults(eventID, clubID), fmt.Sprintf("writing submission for event %d", eventID))
+		},
+	}
+	submissionCmd.Flags().IntVar(&clubID, "club", 2672, "Club whose results to submit, or 0 for everyone")
+	return submissionCmd
+}
+
+// SubmitResults writes out the club's results in the event, ready to submit to a league
+func SubmitResults(eventID int, clubID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	results, err := client.EventResults(context.Background(), eventID)
+	if err != nil {
+		return err
+	}
+	indexNames(nil, results)
+
+	var club string
+	if clubID != 0 {
+		club = strconv.Itoa(clubID)
+	}
+	s := zp.NewSubmission(results, club)
+	return writeRows(eventID, zp.SubmissionColumns, len(s), func(i int) []string {
+		return s[i].Strings()
+	})
+}
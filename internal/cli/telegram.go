@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// telegramCommand runs the Telegram bot, answering questions about the club's riders
+func telegramCommand(notify *botNotifiers) *cobra.Command {
+	return &cobra.Command{
+		Use:   "telegram [club ID]",
+		Short: "Answer /rider <name> in Telegram with a summary of the club's riders",
+		Long: `Runs a Telegram bot with the token from --telegram-token, answering /rider with a
+summary of the club's riders whose names match, or the rider with that Zwift ID. With inline
+mode turned on in @BotFather, "@yourbot name" works in any chat too. It keeps running until
+stopped.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			if notify.telegramToken == "" {
+				exitOnError(fmt.Errorf("needs --telegram-token or ZP_TELEGRAM_TOKEN"), "starting Telegram bot")
+			}
+			client, err := newClient()
+			exitOnError(err, "getting client")
+
+			lookup := newRosterLookup(client, clubID)
+			bot := zp.TelegramBot{Token: notify.telegramToken, Lookup: lookup.riders}
+			log.Printf("Telegram bot answering for club %d", clubID)
+			exitOnError(bot.Run(context.Background()), "running Telegram bot")
+		},
+	}
+}
+
+// rosterRefresh is how long the roster and riders' stats are kept before fetching them
+// again
+const rosterRefresh = time.Hour
+
+// rosterLookup finds the club's riders by name, keeping the roster and the stats it has
+// fetched for a while so that a chat full of questions doesn't keep asking ZwiftPower
+type rosterLookup struct {
+	client *zp.Client
+	clubID int
+
+	mu      sync.Mutex
+	fetched time.Time
+	roster  map[int]zp.Rider
+	index   *zp.NameIndex
+	stats   map[int]zp.Rider
+}
+
+func newRosterLookup(client *zp.Client, clubID int) *rosterLookup {
+	return &rosterLookup{client: client, clubID: clubID}
+}
+
+// riders are the club's riders the query could mean, by name or Zwift ID
+func (l *rosterLookup) riders(ctx context.Context, query string) ([]zp.Rider, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Since(l.fetched) > rosterRefresh {
+		riders, err := l.client.Club(ctx, l.clubID)
+		if err != nil {
+			return nil, err
+		}
+		l.roster = make(map[int]zp.Rider)
+		l.index = zp.NewNameIndex()
+		l.index.AddRiders(riders)
+		for _, r := range riders {
+			l.roster[r.Zwid] = r
+		}
+		l.stats = make(map[int]zp.Rider)
+		l.fetched = time.Now()
+	}
+
+	var matches []zp.NameMatch
+	if zwid, err := strconv.Atoi(query); err == nil {
+		if _, ok := l.roster[zwid]; ok {
+			matches = append(matches, zp.NameMatch{Zwid: zwid})
+		}
+	} else {
+		matches = l.index.Lookup(query)
+	}
+	if len(matches) > 3 {
+		matches = matches[:3]
+	}
+
+	var riders []zp.Rider
+	for _, m := range matches {
+		r, ok := l.stats[m.Zwid]
+		if !ok {
+			var err error
+			r, err = l.client.ClubRider(ctx, l.roster[m.Zwid])
+			if err != nil {
+				return riders, err
+			}
+			l.stats[m.Zwid] = r
+		}
+		riders = append(riders, r)
+	}
+	return riders, nil
+}
@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+// TenantsFile configures the server to look after several clubs
+var TenantsFile string
+
+// tenant is a club that the server looks after on behalf of its admins. Tenants are
+// configured in a JSON file, e.g.
+//
+//	{"Tenants": [
+//	  {"Name": "revo", "ClubID": 2672, "Every": "6h", "Tokens": ["s3cret"]},
+//	  {"Name": "hills", "ClubID": 12345, "Tokens": ["0ther"],
+//	   "Bucket": "gs://hills-club/{{.Date}}.csv",
+//	   "Jobs": [{"Kind": "refresh", "Cron": "0 2 * * *"},
+//	            {"Kind": "digest", "Cron": "0 18 * * sun", "Discord": "https://discord.com/api/webhooks/..."}]}
+//	]}
+//
+// Each tenant's pages and API are under /clubs/<name>/. They need one of its own
+// tokens, or a token for the tenant from the tokens file, which needs the admin role to
+// trigger a refresh. Its data is kept in its own directory under the store, and its
+// results are written there too unless it has a bucket.
+type tenant struct {
+	Name   string
+	ClubID int
+	Every  string   // Time between refreshes, e.g. "6h", as a shorthand for a refresh job
+	Tokens []string // Admin tokens for this tenant
+	Bucket string   // Optional gs:// or s3:// URL for the results
+	Jobs   []*tenantJob
+
+	dir  string
+	live *liveHub
+	mu   sync.Mutex // Held while running a job, so they take turns
+}
+
+// Kinds of tenant job
+const (
+	jobRefresh  = "refresh"  // Import the club, writing the results and storing the riders
+	jobSnapshot = "snapshot" // Save today's stats for the club's riders in the tenant's store
+	jobDigest   = "digest"   // Post the club digest to the job's webhooks
+	jobTraining = "training" // Export races to the training logs connected in the tenant's store
+)
+
+// tenantJob is something the server does for a tenant on its own schedule. Every is
+// the same as a refresh job with a Cron of "@every <Every>".
+type tenantJob struct {
+	Kind string
+	Cron string // When to run, e.g. "0 2 * * *", "@weekly" or "@every 15m" (see zp.ParseSchedule)
+
+	// For digests and training exports
+	Days        int // Days of results to cover, defaulting to 7
+	Fun         bool
+	Webhook     string // Slack-compatible webhook URL
+	Discord     string
+	JSONWebhook string
+
+	schedule *zp.Schedule
+}
+
+var tenantName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// loadTenants reads the tenants from the file, giving each a directory under storeDir
+func loadTenants(filename string, storeDir string) ([]*tenant, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Tenants []*tenant
+	}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling tenants: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for _, t := range config.Tenants {
+		if !tenantName.MatchString(t.Name) {
+			return nil, fmt.Errorf("tenant name %q should be lower case letters, digits and dashes", t.Name)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("tenant %s is configured twice", t.Name)
+		}
+		names[t.Name] = true
+
+		if t.ClubID == 0 {
+			return nil, fmt.Errorf("tenant %s has no ClubID", t.Name)
+		}
+		if len(t.Tokens) == 0 {
+			return nil, fmt.Errorf("tenant %s has no Tokens", t.Name)
+		}
+		for _, token := range t.Tokens {
+			if len(token) < 6 {
+				return nil, fmt.Errorf("tenant %s has a token shorter than 6 characters", t.Name)
+			}
+		}
+		if t.Every != "" {
+			every, err := time.ParseDuration(t.Every)
+			if err != nil || every < time.Minute {
+				return nil, fmt.Errorf("tenant %s: Every should be a duration of at least 1m, not %q", t.Name, t.Every)
+			}
+			t.Jobs = append(t.Jobs, &tenantJob{Kind: jobRefresh, Cron: "@every " + t.Every})
+		}
+		for i, j := range t.Jobs {
+			switch j.Kind {
+			case jobRefresh, jobSnapshot, jobTraining:
+			case jobDigest:
+				if j.Webhook == "" && j.Discord == "" && j.JSONWebhook == "" {
+					return nil, fmt.Errorf("tenant %s: digest job %d has nowhere to post to", t.Name, i+1)
+				}
+			default:
+				return nil, fmt.Errorf("tenant %s: job %d has unknown kind %q, expected %s, %s, %s or %s", t.Name, i+1, j.Kind, jobRefresh, jobSnapshot, jobDigest, jobTraining)
+			}
+			j.schedule, err = zp.ParseSchedule(j.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %s: job %d: %w", t.Name, i+1, err)
+			}
+		}
+
+		t.dir = filepath.Join(storeDir, "tenants", t.Name)
+		t.live = newLiveHub()
+	}
+	return config.Tenants, nil
+}
+
+// authorized is true if the request has one of the tenant's own tokens, which can do
+// anything, or a token from the tokens file with this role for the tenant
+func (t *tenant) authorized(r *http.Request, auth *tokenAuth, role string) bool {
+	token := requestToken(r)
+	ok := false
+	for _, valid := range t.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(valid)) == 1 {
+			ok = true
+		}
+	}
+	return ok || auth.allows(token, role, t.Name)
+}
+
+// handler serves the tenant's dashboard at /clubs/<name>/dashboard/, live updates at
+// /clubs/<name>/events, triggers a refresh on /clubs/<name>/trigger, and refreshes one
+// rider on /clubs/<name>/riders/<id>/refresh
+func (t *tenant) handler(auth *tokenAuth) http.Handler {
+	base := "/clubs/" + t.Name
+	mux := http.NewServeMux()
+	mux.Handle("/dashboard/", newDashboard(t.ClubID, t.dir, t.live, base))
+	mux.HandleFunc("/events", t.live.ServeEvents)
+	mux.HandleFunc("/trigger", func(w http.ResponseWriter, r *http.Request) {
+		err := t.run(r.Context(), &tenantJob{Kind: jobRefresh})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "Reading data for %d\n", t.ClubID)
+	})
+	mux.Handle("/riders/", &riderRefresher{clubID: t.ClubID, hub: t.live, dir: t.dir, limiter: newRefreshLimiter()})
+
+	return http.StripPrefix(base, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := roleRead
+		if r.URL.Path == "/trigger" || strings.HasPrefix(r.URL.Path, "/riders/") {
+			role = roleAdmin
+		}
+		if !t.authorized(r, auth, role) {
+			http.Error(w, fmt.Sprintf("needs a token with the %s role", role), http.StatusUnauthorized)
+			return
+		}
+		rememberToken(w, r, base+"/")
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+// refresh imports the club, writing the results to the tenant's bucket or directory and
+// keeping the riders in the tenant's store for the dashboard. Riders who have left the
+// club are archived.
+func (t *tenant) refresh(ctx context.Context) error {
+	err := os.MkdirAll(t.dir, 0755)
+	if err != nil {
+		return err
+	}
+	store, err := openStore(t.dir)
+	if err != nil {
+		return err
+	}
+
+	riders, err := importClub(ctx, t.live, t.dir, t.ClubID, Limit, func() (io.WriteCloser, error) {
+		if t.Bucket != "" && DryRun {
+			return &dryRunWriter{dest: t.Bucket}, nil
+		}
+		if t.Bucket != "" {
+			return newBucketWriter(ctx, t.Bucket, newObjectKeyData(t.ClubID, Format))
+		}
+		return createFile(filepath.Join(t.dir, "results."+Format))
+	})
+	if err != nil {
+		return fmt.Errorf("refreshing %s: %w", t.Name, err)
+	}
+
+	for _, r := range riders {
+		if err := store.PutRider(r); err != nil {
+			return fmt.Errorf("storing %s for %s: %w", r.Name, t.Name, err)
+		}
+	}
+	// With a limit we haven't seen the whole roster, so can't tell who has left
+	if Limit == 0 {
+		archived, restored, err := zp.ArchiveDeparted(store, riders, time.Now())
+		if err != nil {
+			return fmt.Errorf("archiving riders who have left %s: %w", t.Name, err)
+		}
+		logArchived(archived, restored)
+		if err := recordRoster(store, t.ClubID, riders); err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+	}
+	log.Printf("Refreshed %s: %d riders in club %d", t.Name, len(riders), t.ClubID)
+	return nil
+}
+
+// snapshot saves today's stats for the club's riders in the tenant's store
+func (t *tenant) snapshot(ctx context.Context) error {
+	store, err := openStore(t.dir)
+	if err != nil {
+		return err
+	}
+	client, err := newClientFor(t.dir)
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+	riders, err := clubRiders(client, t.ClubID)
+	if err != nil {
+		return fmt.Errorf("snapshot for %s: %w", t.Name, err)
+	}
+
+	name := zp.SnapshotName(t.ClubID, time.Now())
+	log.Printf("Saving snapshot %s of %d riders for %s", name, len(riders), t.Name)
+	return store.PutSnapshot(name, riders)
+}
+
+// run does the job, waiting for any other job for the tenant to finish first
+func (t *tenant) run(ctx context.Context, j *tenantJob) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch j.Kind {
+	case jobSnapshot:
+		return t.snapshot(ctx)
+	case jobDigest:
+		days := j.Days
+		if days == 0 {
+			days = 7
+		}
+		notify := &botNotifiers{slack: j.Webhook, discord: j.Discord, webhook: j.JSONWebhook}
+		err := postDigest(notify, t.dir, t.ClubID, days, j.Fun, "")
+		if err != nil {
+			return fmt.Errorf("digest for %s: %w", t.Name, err)
+		}
+		return nil
+	case jobTraining:
+		days := j.Days
+		if days == 0 {
+			days = 7
+		}
+		if err := exportTraining(ctx, t.dir, days, nil); err != nil {
+			return fmt.Errorf("training export for %s: %w", t.Name, err)
+		}
+		return nil
+	}
+	return t.refresh(ctx)
+}
+
+// schedule runs each of the tenant's jobs on its own schedule
+func (t *tenant) schedule(ctx context.Context) {
+	for _, j := range t.Jobs {
+		j := j
+		log.Printf("Running %s for %s on schedule %q", j.Kind, t.Name, j.Cron)
+		go zp.RunSchedule(ctx, j.schedule, func(context.Context) {
+			runJob(func(ctx context.Context) {
+				if err := t.run(ctx, j); err != nil {
+					log.Print(err)
+				}
+			})
+		})
+	}
+}
+
+// serveTenants sets up the handlers and refresh schedules for each tenant
+func serveTenants(mux *http.ServeMux, tenants []*tenant, auth *tokenAuth) {
+	// Jobs stop being scheduled when the server starts shutting down
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopping
+		cancel()
+	}()
+	for _, t := range tenants {
+		mux.Handle("/clubs/"+t.Name+"/", t.handler(auth))
+		go t.schedule(ctx)
+		log.Printf("Serving club %d as %s under /clubs/%s/", t.ClubID, t.Name, t.Name)
+	}
+}
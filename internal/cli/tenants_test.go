@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+)
+
+func TestTenantsKeepApart(t *testing.T) {
+	oldBaseURL, oldStoreDir, oldFormat, oldMask, oldLimit := zp.BaseURL, StoreDir, Format, Mask, Limit
+	defer func() {
+		zp.BaseURL, StoreDir, Format, Mask, Limit = oldBaseURL, oldStoreDir, oldFormat, oldMask, oldLimit
+	}()
+	Format, Mask, Limit = formatCSV, true, 0
+
+	rosters := map[int][]int{10: {1, 2}, 20: {3, 4}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id int
+		if _, err := fmt.Sscanf(r.URL.Path, "/cache3/teams/%d_riders.json", &id); err == nil {
+			var riders []string
+			for _, zwid := range rosters[id] {
+				riders = append(riders, fmt.Sprintf(`{"name":"Rider %d","zwid":%d}`, zwid, zwid))
+			}
+			fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(riders, ","))
+			return
+		}
+		if _, err := fmt.Sscanf(r.URL.Path, "/cache3/profile/%d_all.json", &id); err == nil {
+			fmt.Fprintf(w, `{"data":[{"zid":"%d","zwid":%d,"event_date":%d}]}`, id*10, id, time.Now().Unix())
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+	zp.BaseURL = ts.URL
+
+	// The shared store has its own opt-outs and masks, which are nothing to do with the tenants
+	StoreDir = t.TempDir()
+	shared, err := openStore(StoreDir)
+	must(t, err)
+	must(t, shared.OptOut(zp.OptOut{Zwid: 3}))
+	must(t, shared.Mask(zp.Mask{Zwid: 4, Alias: "Shared alias"}))
+
+	root := t.TempDir()
+	a := &tenant{Name: "a", ClubID: 10, dir: filepath.Join(root, "a"), live: newLiveHub()}
+	b := &tenant{Name: "b", ClubID: 20, dir: filepath.Join(root, "b"), live: newLiveHub()}
+	storeA, err := openStore(a.dir)
+	must(t, err)
+	must(t, storeA.OptOut(zp.OptOut{Zwid: 2}))
+	must(t, storeA.Mask(zp.Mask{Zwid: 1, Alias: "Masked in a"}))
+
+	for _, tn := range []*tenant{a, b} {
+		must(t, tn.refresh(context.Background()))
+	}
+
+	results := func(tn *tenant) string {
+		data, err := ioutil.ReadFile(filepath.Join(tn.dir, "results.csv"))
+		must(t, err)
+		return string(data)
+	}
+	if r := results(a); !strings.Contains(r, "Masked in a") || strings.Contains(r, "Rider 2") {
+		t.Errorf("Expected a's own mask and opt-out in its results:\n%s", r)
+	}
+	if r := results(b); !strings.Contains(r, "Rider 3") || !strings.Contains(r, "Rider 4") {
+		t.Errorf("Expected the shared store's opt-outs and masks not to apply to b:\n%s", r)
+	}
+
+	names := func(dir string) map[int]string {
+		store, err := openStore(dir)
+		must(t, err)
+		index, err := store.NameIndex()
+		must(t, err)
+		return index.Names
+	}
+	if n := names(a.dir); n[1] == "" || n[3] != "" {
+		t.Errorf("Unexpected names indexed for a: %v", n)
+	}
+	if n := names(b.dir); n[3] == "" || n[1] != "" {
+		t.Errorf("Unexpected names indexed for b: %v", n)
+	}
+	if n := names(StoreDir); len(n) != 0 {
+		t.Errorf("Expected nothing indexed in the shared store, got %v", n)
+	}
+
+	// Each tenant's anniversaries and goals come from its own store
+	b1, err := openStore(b.dir)
+	must(t, err)
+	if stints, err := b1.Stints(b.ClubID); err != nil || len(stints) != 2 {
+		t.Errorf("Expected b's stints in its own store, got %v, %v", stints, err)
+	}
+	if stints, err := storeA.Stints(b.ClubID); err != nil || len(stints) != 0 {
+		t.Errorf("Expected none of b's stints in a's store, got %v, %v", stints, err)
+	}
+	must(t, storeA.SetGoal(zp.Goal{Zwid: 1, Kind: zp.GoalRaces, Target: 5}))
+	if goals, err := clubGoals(b1, []zp.Event{{Zwid: 1}}, time.Now()); err != nil || len(goals) != 0 {
+		t.Errorf("Expected no goals from a's store for b, got %v, %v", goals, err)
+	}
+	if goals, err := clubGoals(storeA, []zp.Event{{Zwid: 1}}, time.Now()); err != nil || len(goals) != 1 {
+		t.Errorf("Expected a's goal for a, got %v, %v", goals, err)
+	}
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var tenureColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "joined", Header: "Joined"},
+	{Key: "months", Header: "Months"},
+	{Key: "before", Header: "Joined before tracking"},
+}
+
+var retentionColumns = []zp.Column{
+	{Key: "days", Header: "Days"},
+	{Key: "joined", Header: "Joined"},
+	{Key: "left", Header: "Left"},
+	{Key: "cohort", Header: "Joined at least this long ago"},
+	{Key: "stayed", Header: "Stayed this long"},
+	{Key: "rate", Header: "Retention"},
+}
+
+// tenureCommand records when riders joined the club, and reports how long they've been
+// in it and how well the club keeps new riders
+func tenureCommand() *cobra.Command {
+	var list bool
+	tenureCmd := &cobra.Command{
+		Use:   "tenure [club ID]",
+		Short: "Record when riders join and leave the club, and list how long everyone has been in it",
+		Long: `Compares the club's roster with the riders' stints in the store (--store), starting a
+stint for anyone new and ending it for anyone who has left. Riders who were already in the
+club the first time are marked as having joined before tracking started, and don't get
+anniversaries. Full imports, and the hosted clubs' refreshes, record the roster too.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			exitOnError(ClubTenure(clubID, list), fmt.Sprintf("tracking tenure for club %d", clubID))
+		},
+	}
+	tenureCmd.Flags().BoolVar(&list, "list", false, "Only list the riders' tenure, without checking the roster")
+
+	periods := "30,90,365"
+	retentionCmd := &cobra.Command{
+		Use:   "retention [club ID]",
+		Short: "Write out how many riders joined and left, and how many new riders stayed, over each period",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			days, err := parseIDs(strings.Split(periods, ","))
+			if err != nil {
+				exitWith(fmt.Errorf("--days: %w", err), "", exitUsage)
+			}
+			exitOnError(ClubRetention(clubID, days), fmt.Sprintf("working out retention for club %d", clubID))
+		},
+	}
+	retentionCmd.Flags().StringVar(&periods, "days", periods, "Comma-separated periods, in days")
+
+	tenureCmd.AddCommand(retentionCmd)
+	return tenureCmd
+}
+
+// ClubTenure records the club's roster, unless listOnly is set, then writes out the
+// current riders and when they joined, longest-serving first
+func ClubTenure(clubID int, listOnly bool) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+
+	if !listOnly {
+		client, err := newClient()
+		if err != nil {
+			return fmt.Errorf("error getting client: %w", err)
+		}
+		roster, err := client.Club(context.Background(), clubID)
+		if err != nil {
+			return fmt.Errorf("getting roster: %w", err)
+		}
+		if err := recordRoster(store, clubID, roster); err != nil {
+			return err
+		}
+	}
+
+	stints, err := store.Stints(clubID)
+	if err != nil {
+		return fmt.Errorf("reading stints: %w", err)
+	}
+	var current []zp.Stint
+	for _, s := range stints {
+		if s.Current() {
+			current = append(current, s)
+		}
+	}
+	sort.SliceStable(current, func(i, j int) bool {
+		return current[i].Joined.Before(current[j].Joined)
+	})
+
+	masks := publicMasks()
+	now := time.Now()
+	return writeRows(clubID, tenureColumns, len(current), func(i int) []string {
+		s := current[i]
+		return []string{masks.Name(s.Zwid, s.Name), strconv.Itoa(s.Zwid), formatDate(s.Joined), strconv.Itoa(s.Months(now)), strconv.FormatBool(s.Before)}
+	})
+}
+
+// ClubRetention writes out the club's comings and goings over each period
+func ClubRetention(clubID int, periods []int) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	stints, err := store.Stints(clubID)
+	if err != nil {
+		return fmt.Errorf("reading stints: %w", err)
+	}
+	if len(stints) == 0 {
+		return fmt.Errorf("no stints for club %d in %s: run tenure or import the club first: %w", clubID, StoreDir, zp.ErrNotFound)
+	}
+
+	stats := zp.Retention(stints, time.Now(), periods)
+	return writeRows(clubID, retentionColumns, len(stats), func(i int) []string {
+		s := stats[i]
+		return []string{strconv.Itoa(s.Days), strconv.Itoa(s.Joined), strconv.Itoa(s.Left), strconv.Itoa(s.Cohort), strconv.Itoa(s.Stayed), fmt.Sprintf("%.0f%%", s.Rate()*100)}
+	})
+}
+
+// recordRoster tracks who's in the club in the store, logging who joined and left
+func recordRoster(store *zp.FileStore, clubID int, roster []zp.Rider) error {
+	joined, left, err := store.RecordRoster(clubID, roster, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("recording roster: %w", err)
+	}
+	for _, s := range joined {
+		log.Printf("%s (%d) joined the club", s.Name, s.Zwid)
+	}
+	for _, s := range left {
+		log.Printf("%s (%d) left the club after %d months", s.Name, s.Zwid, s.Months(time.Now()))
+	}
+	return nil
+}
+
+// clubAnniversaries finds the club's riders whose anniversaries of joining it fall in the
+// period, from their stints in the store, with their names masked for posting. With no
+// store there are none.
+func clubAnniversaries(store *zp.FileStore, clubID int, since, until time.Time) ([]zp.Milestone, error) {
+	if store == nil {
+		return nil, nil
+	}
+	stints, err := store.Stints(clubID)
+	if err != nil {
+		return nil, fmt.Errorf("reading stints: %w", err)
+	}
+
+	masks := storeMasks(store.Dir)
+	anniversaries := zp.Anniversaries(stints, since, until)
+	for i, m := range anniversaries {
+		anniversaries[i].Name = masks.Name(m.Zwid, m.Name)
+	}
+	return anniversaries, nil
+}
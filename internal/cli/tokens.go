@@ -0,0 +1,348 @@
+package cli
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+// Roles for API tokens. Admins can do everything readers can.
+const (
+	roleRead  = "read"  // View the dashboard and live updates
+	roleAdmin = "admin" // Trigger refreshes as well
+)
+
+var roleLevel = map[string]int{roleRead: 1, roleAdmin: 2}
+
+// apiToken is a token for the server, as kept in tokens.json in the store. We only keep
+// a hash of the secret, which is shown once when the token is made.
+type apiToken struct {
+	ID      string
+	Hash    string
+	Role    string
+	Tenant  string // Only valid for this tenant; empty for all of them
+	Note    string
+	Created time.Time
+}
+
+// allows is true if the token has at least this role for the tenant
+func (t apiToken) allows(role string, tenant string) bool {
+	return roleLevel[t.Role] >= roleLevel[role] && (t.Tenant == "" || t.Tenant == tenant)
+}
+
+func tokensPath(storeDir string) string {
+	return filepath.Join(storeDir, "tokens.json")
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// loadTokens reads the tokens file, which doesn't exist until a token is added
+func loadTokens(path string) ([]apiToken, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []apiToken
+	err = json.Unmarshal(data, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling %s: %w", path, err)
+	}
+	return tokens, nil
+}
+
+func saveTokens(path string, tokens []apiToken) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	if DryRun {
+		log.Printf("Dry run: would save %d tokens to %s", len(tokens), path)
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// addToken makes a new token, returning the secret to hand to whoever will use it
+func addToken(path string, role string, tenant string, note string) (string, apiToken, error) {
+	var t apiToken
+	if _, ok := roleLevel[role]; !ok {
+		return "", t, fmt.Errorf("unknown role %q, expected %s or %s", role, roleRead, roleAdmin)
+	}
+
+	unlock, err := zp.LockFile(path)
+	if err != nil {
+		return "", t, err
+	}
+	defer unlock()
+	tokens, err := loadTokens(path)
+	if err != nil {
+		return "", t, err
+	}
+
+	id, err := randomHex(4)
+	if err != nil {
+		return "", t, err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", t, err
+	}
+	secret = "zp_" + secret
+
+	t = apiToken{ID: id, Hash: hashToken(secret), Role: role, Tenant: tenant, Note: note, Created: time.Now().UTC()}
+	return secret, t, saveTokens(path, append(tokens, t))
+}
+
+func revokeToken(path string, id string) error {
+	unlock, err := zp.LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	tokens, err := loadTokens(path)
+	if err != nil {
+		return err
+	}
+
+	for i, t := range tokens {
+		if t.ID == id {
+			return saveTokens(path, append(tokens[:i], tokens[i+1:]...))
+		}
+	}
+	return fmt.Errorf("no token with ID %s", id)
+}
+
+// checkTenant makes sure a token isn't made for a tenant that the tenants file doesn't
+// have, where it would never work. Without a tenants file there's nothing to check.
+func checkTenant(tenantsFile string, name string) error {
+	if name == "" || tenantsFile == "" {
+		return nil
+	}
+	tenants, err := loadTenants(tenantsFile, StoreDir)
+	if err != nil {
+		return fmt.Errorf("loading tenants from %s: %w", tenantsFile, err)
+	}
+	names := make([]string, len(tenants))
+	for i, t := range tenants {
+		if t.Name == name {
+			return nil
+		}
+		names[i] = t.Name
+	}
+	return fmt.Errorf("no tenant called %s in %s, expected one of %s", name, tenantsFile, strings.Join(names, ", "))
+}
+
+// requestToken finds the token in the request. Cloud Run uses the Authorization header
+// for its own identity tokens, so X-ZP-Token comes first. Browsers can pass ?token= once,
+// and then it's kept in a cookie.
+func requestToken(r *http.Request) string {
+	if token := r.Header.Get("X-ZP-Token"); token != "" {
+		return token
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	if c, err := r.Cookie(tokenCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// tokenCookie holds the token, so that once someone has opened the dashboard with
+// ?token=, its links and live updates work too
+const tokenCookie = "zp_token"
+
+// tokenAuth checks requests against the tokens file, rereading it when it changes so
+// that tokens added or revoked from the command line take effect straight away. Until
+// the file exists the server is open. Once it has, the server stays locked: if the file
+// can't be read, has gone, or has no tokens left, nothing is allowed.
+type tokenAuth struct {
+	path string
+
+	mu         sync.Mutex
+	configured bool
+	modified   time.Time
+	tokens     []apiToken
+}
+
+func newTokenAuth(storeDir string) *tokenAuth {
+	return &tokenAuth{path: tokensPath(storeDir)}
+}
+
+// current gets the valid tokens, and whether auth is configured at all
+func (a *tokenAuth) current() ([]apiToken, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	info, err := os.Stat(a.path)
+	if os.IsNotExist(err) && !a.configured {
+		return nil, false
+	}
+	a.configured = true
+	if err != nil {
+		log.Printf("Refusing all tokens: %v", err)
+		a.tokens, a.modified = nil, time.Time{}
+		return nil, true
+	}
+	if !info.ModTime().Equal(a.modified) {
+		tokens, err := loadTokens(a.path)
+		if err != nil {
+			log.Printf("Refusing all tokens: reading them: %v", err)
+			a.tokens, a.modified = nil, time.Time{}
+			return nil, true
+		}
+		a.tokens, a.modified = tokens, info.ModTime()
+	}
+	return a.tokens, true
+}
+
+// enabled is true once the tokens file exists, even if it has no tokens left in it
+func (a *tokenAuth) enabled() bool {
+	_, configured := a.current()
+	return configured
+}
+
+// allows is true if the secret is a token with at least this role for the tenant
+func (a *tokenAuth) allows(secret string, role string, tenant string) bool {
+	if secret == "" {
+		return false
+	}
+	hash := []byte(hashToken(secret))
+	ok := false
+	tokens, _ := a.current()
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 && t.allows(role, tenant) {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// require wraps the handler so that it needs a token with this role. If no tokens have
+// ever been added, everything is open as before.
+func (a *tokenAuth) require(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.enabled() && !a.allows(requestToken(r), role, "") {
+			http.Error(w, fmt.Sprintf("needs a token with the %s role", role), http.StatusUnauthorized)
+			return
+		}
+		rememberToken(w, r, "/")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rememberToken sets the cookie if the token came as a parameter
+func rememberToken(w http.ResponseWriter, r *http.Request, path string) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		http.SetCookie(w, &http.Cookie{Name: tokenCookie, Value: token, Path: path, HttpOnly: true, Secure: true, SameSite: http.SameSiteStrictMode})
+	}
+}
+
+var tokenColumns = []zp.Column{
+	{Key: "id", Header: "ID"},
+	{Key: "role", Header: "Role"},
+	{Key: "tenant", Header: "Tenant"},
+	{Key: "note", Header: "Note"},
+	{Key: "created", Header: "Created"},
+}
+
+// tokensCommand manages the tokens that the server accepts, which are kept in the store
+func tokensCommand() *cobra.Command {
+	tokensCmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Manage API tokens for the server",
+		Long: `Tokens are kept in tokens.json in the store (--store), which the server rereads when
+it changes. Read tokens can view the dashboard and live updates; admin tokens can also
+trigger refreshes. Once any token exists, the server needs one for every request, and
+revoking the last one locks everyone out rather than opening the server up again.`,
+	}
+
+	var role, tenant, note string
+	addCmd := &cobra.Command{
+		Use:   "add",
+		Short: "Make a new token, and print its secret",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkTenant(TenantsFile, tenant); err != nil {
+				exitWith(err, "in --tenant", exitUsage)
+			}
+			secret, t, err := addToken(tokensPath(StoreDir), role, tenant, note)
+			exitOnError(err, "adding token")
+			fmt.Printf("Token %s (%s): %s\n", t.ID, t.Role, secret)
+			fmt.Println("Keep the secret safe: it can't be shown again.")
+		},
+	}
+	addCmd.Flags().StringVar(&role, "role", roleRead, "What the token can do: read or admin")
+	addCmd.Flags().StringVar(&tenant, "tenant", "", "Only allow the token for this tenant (see the server's --tenants)")
+	addCmd.Flags().StringVar(&TenantsFile, "tenants", configFile("ZP_TENANTS", "tenants.json"), "JSON file listing the tenants, to check --tenant against")
+	addCmd.Flags().StringVar(&note, "note", "", "Who or what the token is for")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the tokens, without their secrets",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			tokens, err := loadTokens(tokensPath(StoreDir))
+			exitOnError(err, "reading tokens")
+			exitOnError(writeRows(0, tokenColumns, len(tokens), func(i int) []string {
+				t := tokens[i]
+				return []string{t.ID, t.Role, t.Tenant, t.Note, t.Created.Format(time.RFC3339)}
+			}), "writing tokens")
+		},
+	}
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke [token ID]",
+		Short: "Revoke a token",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(revokeToken(tokensPath(StoreDir), args[0]), "revoking token")
+		},
+	}
+
+	tokensCmd.AddCommand(addCmd, listCmd, revokeCmd)
+	return tokensCmd
+}
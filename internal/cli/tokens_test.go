@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenAuth(t *testing.T) {
+	dir := t.TempDir()
+	path := tokensPath(dir)
+	auth := newTokenAuth(dir)
+	handler := auth.require(roleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	status := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/trigger", nil)
+		if token != "" {
+			req.Header.Set("X-ZP-Token", token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	// Changes to the file are noticed by its modification time
+	touch := func() {
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if status("") != http.StatusOK {
+		t.Errorf("Expected the server to be open before any tokens are added")
+	}
+
+	reader, _, err := addToken(path, roleRead, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, adminToken, err := addToken(path, roleAdmin, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for token, expected := range map[string]int{"": 401, "wrong": 401, reader: 401, admin: 200} {
+		if got := status(token); got != expected {
+			t.Errorf("Expected %d for token %q, got %d", expected, token, got)
+		}
+	}
+	if _, _, err := addToken(path, "superuser", "", ""); err == nil {
+		t.Errorf("Expected an error for an unknown role")
+	}
+
+	// Revoking the last admin token shuts admins out, and revoking everything doesn't
+	// open the server up again
+	if err := revokeToken(path, adminToken.ID); err != nil {
+		t.Fatal(err)
+	}
+	touch()
+	if status(admin) != 401 {
+		t.Errorf("Expected a revoked token to be refused")
+	}
+	tokens, err := loadTokens(path)
+	if err != nil || len(tokens) != 1 {
+		t.Fatalf("Expected one token left, got %v: %v", tokens, err)
+	}
+	if err := revokeToken(path, tokens[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	touch()
+	if status("") != 401 {
+		t.Errorf("Expected everything to be refused with no tokens left")
+	}
+	if err := revokeToken(path, "nope"); err == nil {
+		t.Errorf("Expected an error revoking an unknown token")
+	}
+
+	// A broken or missing file refuses everything too
+	if err := ioutil.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	touch()
+	if status("") != 401 {
+		t.Errorf("Expected everything to be refused with a corrupt tokens file")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if status("") != 401 {
+		t.Errorf("Expected everything to be refused once the tokens file has gone")
+	}
+}
+
+func TestTokenCookie(t *testing.T) {
+	w := httptest.NewRecorder()
+	rememberToken(w, httptest.NewRequest(http.MethodGet, "/dashboard/?token=s3cret", nil), "/")
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != "s3cret" || !cookies[0].Secure || !cookies[0].HttpOnly {
+		t.Errorf("Unexpected cookies %+v", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard/", nil)
+	req.AddCookie(cookies[0])
+	if got := requestToken(req); got != "s3cret" {
+		t.Errorf("Expected the token from the cookie, got %q", got)
+	}
+}
+
+func TestAddTokensConcurrently(t *testing.T) {
+	path := tokensPath(t.TempDir())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := addToken(path, roleRead, "", ""); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	tokens, err := loadTokens(path)
+	if err != nil || len(tokens) != 10 {
+		t.Errorf("Expected all 10 tokens to be kept, got %d: %v", len(tokens), err)
+	}
+}
+
+func TestCheckTenant(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tenants.json")
+	must(t, ioutil.WriteFile(file, []byte(`{"Tenants": [{"Name": "revo", "ClubID": 2672, "Tokens": ["secret"]}]}`), 0600))
+
+	if err := checkTenant(file, "revo"); err != nil {
+		t.Errorf("Expected revo to be a tenant: %v", err)
+	}
+	if err := checkTenant(file, "rveo"); err == nil {
+		t.Errorf("Expected an error for a tenant that isn't configured")
+	}
+	if err := checkTenant("", "rveo"); err != nil {
+		t.Errorf("Without a tenants file there's nothing to check: %v", err)
+	}
+}
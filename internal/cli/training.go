@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var trainingColumns = []zp.Column{
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "service", Header: "Service"},
+	{Key: "athlete", Header: "Athlete"},
+	{Key: "connected", Header: "Connected"},
+	{Key: "exported", Header: "Exported"},
+}
+
+// trainingCommand has subcommands for connecting riders' training logs, and exporting
+// their races to them
+func trainingCommand() *cobra.Command {
+	trainingCmd := &cobra.Command{
+		Use:   "training",
+		Short: "Export riders' races to intervals.icu or TrainingPeaks",
+	}
+
+	var service, athlete, key string
+	connectCmd := &cobra.Command{
+		Use:   "connect [rider ID]",
+		Short: "Connect a rider's training log, so their races are exported to it",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(connectTraining(getID(args, 0), service, athlete, key), "connecting training log")
+		},
+	}
+	connectCmd.Flags().StringVar(&service, "service", zp.TrainingIntervals, "Training log: "+zp.TrainingIntervals+" or "+zp.TrainingTrainingPeaks)
+	connectCmd.Flags().StringVar(&athlete, "athlete", "", "The rider's intervals.icu athlete ID, such as i12345 (defaults to the owner of the key)")
+	connectCmd.Flags().StringVar(&key, "key", "", "The rider's intervals.icu API key, or TrainingPeaks access token")
+	_ = connectCmd.MarkFlagRequired("key")
+
+	var only string
+	disconnectCmd := &cobra.Command{
+		Use:   "disconnect [rider ID]",
+		Short: "Stop exporting a rider's races, and forget their key",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			exitOnError(store.DisconnectTraining(getID(args, 0), only), "disconnecting training log")
+		},
+	}
+	disconnectCmd.Flags().StringVar(&only, "service", "", "Only disconnect this training log (defaults to all of the rider's)")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the connected training logs, without their keys",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			store, err := openStore(StoreDir)
+			exitOnError(err, "opening store")
+			list, err := store.TrainingConnections()
+			exitOnError(err, "reading training logs")
+			exitOnError(writeRows(0, trainingColumns, len(list), func(i int) []string {
+				c := list[i]
+				return []string{strconv.Itoa(c.Zwid), c.Service, c.Athlete, c.Date.Format(time.RFC3339), strconv.Itoa(len(c.Exported))}
+			}), "writing training logs")
+		},
+	}
+
+	var days int
+	exportCmd := &cobra.Command{
+		Use:   "export [rider ID...]",
+		Short: "Export recent races to the riders' training logs (all connected riders by default)",
+		Run: func(cmd *cobra.Command, args []string) {
+			riderIDs, err := parseIDs(args)
+			if err != nil {
+				exitWith(err, "", exitUsage)
+			}
+			exitOnError(exportTraining(context.Background(), StoreDir, days, riderIDs), "exporting races")
+		},
+	}
+	exportCmd.Flags().IntVar(&days, "days", 14, "Export races from this many days back")
+
+	trainingCmd.AddCommand(connectCmd, disconnectCmd, listCmd, exportCmd)
+	return trainingCmd
+}
+
+func connectTraining(riderID int, service, athlete, key string) error {
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	return store.ConnectTraining(zp.TrainingConnection{Zwid: riderID, Service: service, Athlete: athlete, Key: key})
+}
+
+// dryRunTrainingLog stands in for a training log in a dry run
+type dryRunTrainingLog struct {
+	dest string
+}
+
+func (d dryRunTrainingLog) Log(ctx context.Context, a zp.TrainingActivity) error {
+	log.Printf("Dry run: would export %q on %s to %s", a.Name, formatDate(a.Start), d.dest)
+	return nil
+}
+
+// exportTraining exports the last few days' races to the training logs connected in
+// the store in dir, for these riders or everyone connected. It carries on past a rider
+// whose export fails, so one bad key doesn't hold up everyone else.
+func exportTraining(ctx context.Context, dir string, days int, riderIDs []int) error {
+	store, err := openStore(dir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	list, err := store.TrainingConnections()
+	if err != nil {
+		return err
+	}
+	only := make(map[int]bool, len(riderIDs))
+	for _, id := range riderIDs {
+		only[id] = true
+	}
+
+	client, err := newClientFor(dir)
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	var failed int
+	for _, c := range list {
+		if len(only) > 0 && !only[c.Zwid] {
+			continue
+		}
+		trainingLog, err := c.Log(http.DefaultClient)
+		if err != nil {
+			return err
+		}
+		if DryRun {
+			trainingLog = dryRunTrainingLog{dest: c.Service + " for " + strconv.Itoa(c.Zwid)}
+		}
+
+		events, err := client.Events(ctx, c.Zwid)
+		if err != nil {
+			log.Printf("Getting races for %d: %v", c.Zwid, err)
+			failed++
+			continue
+		}
+		done, err := zp.ExportRaces(ctx, trainingLog, events, since, c.ExportedSet())
+		if len(done) > 0 {
+			log.Printf("Exported %d races for %d to %s", len(done), c.Zwid, c.Service)
+		}
+		if markErr := store.MarkExported(c.Zwid, c.Service, done); markErr != nil {
+			return markErr
+		}
+		if err != nil {
+			log.Printf("Exporting races for %d to %s: %v", c.Zwid, c.Service, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of the exports failed", failed)
+	}
+	return nil
+}
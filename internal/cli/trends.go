@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var trendsColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "direction", Header: "Direction"},
+	{Key: "change", Header: "Places gained per window"},
+	{Key: "races", Header: "Races lately"},
+	{Key: "average", Header: "Average position lately"},
+	{Key: "best", Header: "Best position lately"},
+	{Key: "before", Header: "Average position before"},
+}
+
+var trendWindowColumns = []zp.Column{
+	{Key: "from", Header: "From"},
+	{Key: "to", Header: "To"},
+	{Key: "races", Header: "Races"},
+	{Key: "average", Header: "Average position"},
+	{Key: "best", Header: "Best position"},
+}
+
+func trendsCommand() *cobra.Command {
+	var days, windows, riderID int
+
+	trendsCmd := &cobra.Command{
+		Use:   "trends [club ID]",
+		Short: "Whether each rider is finishing further up the field lately, from their positions in category",
+		Long: `Splits the last --windows lots of --days days into windows, and works out each rider's
+average and best position in their category in each. The direction comes from a straight
+line through the averages: improving or declining if it moves by more than a tenth of the
+rider's average position, and steady otherwise. Riders without races in at least two
+windows have no direction. --rider lists one rider's windows instead.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			exitOnError(PositionTrends(clubID, riderID, days, windows), "getting position trends")
+		},
+	}
+	trendsCmd.Flags().IntVar(&days, "days", 30, "Days in each window")
+	trendsCmd.Flags().IntVar(&windows, "windows", 3, "Number of windows, the last ending today")
+	trendsCmd.Flags().IntVar(&riderID, "rider", 0, "List the windows for this rider")
+	return trendsCmd
+}
+
+// PositionTrends writes out which way each of the club's riders' finishing positions is
+// heading, or one rider's positions in each window
+func PositionTrends(clubID int, riderID int, days int, windows int) error {
+	if days <= 0 || windows <= 0 {
+		return fmt.Errorf("--days and --windows must be more than 0")
+	}
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	var events []zp.Event
+	if riderID != 0 {
+		events, err = client.Events(context.Background(), riderID)
+	} else {
+		events, err = clubEvents(client, clubID)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := reportTime()
+	trends := zp.PositionTrends(events, now, days, windows)
+	masks := publicMasks()
+
+	if riderID != 0 {
+		var t zp.PositionTrend
+		for _, trend := range trends {
+			if trend.Zwid == riderID {
+				t = trend
+			}
+		}
+		return writeRows(riderID, trendWindowColumns, len(t.Windows), func(i int) []string {
+			w := t.Windows[i]
+			return []string{
+				formatDate(w.Start),
+				formatDate(w.End),
+				strconv.Itoa(w.Races),
+				formatPosition(w.Average),
+				formatPosition(float64(w.Best)),
+			}
+		})
+	}
+
+	return writeRows(clubID, trendsColumns, len(trends), func(i int) []string {
+		t := trends[i]
+		latest := t.Latest()
+		var before zp.PositionWindow
+		if len(t.Windows) > 1 {
+			before = t.Windows[len(t.Windows)-2]
+		}
+		return []string{
+			masks.Name(t.Zwid, t.Name),
+			strconv.Itoa(t.Zwid),
+			string(t.Direction),
+			strconv.FormatFloat(t.Change, 'f', 1, 64),
+			strconv.Itoa(latest.Races),
+			formatPosition(latest.Average),
+			formatPosition(float64(latest.Best)),
+			formatPosition(before.Average),
+		}
+	})
+}
+
+// formatPosition leaves out positions we haven't got, and only shows tenths for averages
+func formatPosition(p float64) string {
+	if p == 0 {
+		return ""
+	}
+	if p == float64(int(p)) {
+		return strconv.Itoa(int(p))
+	}
+	return strconv.FormatFloat(p, 'f', 1, 64)
+}
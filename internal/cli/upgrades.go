@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var upgradeColumns = []zp.Column{
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "category", Header: "Category"},
+	{Key: "next", Header: "Next"},
+	{Key: "wkg", Header: "FTP w/kg"},
+	{Key: "watts", Header: "FTP W"},
+	{Key: "wkg_to_go", Header: "W/kg to go"},
+	{Key: "watts_to_go", Header: "W to go"},
+	{Key: "events", Header: "Events"},
+}
+
+func upgradesCommand() *cobra.Command {
+	var limitsFile string
+	margin := zp.DefaultUpgradeMargin
+	cmd := &cobra.Command{
+		Use:   "upgrades [club ID]",
+		Short: "Warn about riders whose recent races put them close to moving up a category",
+		Long: fmt.Sprintf(`Lists riders whose FTP estimates from races over the last %d days are within the
+margin of both the w/kg and the watts needed for the category above, along with the
+races that put them there, so that an upgrade in the middle of a season isn't a
+surprise. Negative numbers to go mean the rider is already over. The club ID
+defaults to 2672.
+
+Category limits default to ZwiftPower's. If they change, --limits reads them from
+a JSON file, from the top category down:
+
+  [{"Category": "A", "MinWkg": 4.0, "MinWatts": 250}, {"Category": "B", "MinWkg": 3.2, "MinWatts": 200},
+   {"Category": "C", "MinWkg": 2.5, "MinWatts": 150}, {"Category": "D"}]`, zp.UpgradeDays),
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			clubID := getID(args, 2672)
+			var limits []zp.CategoryBoundary
+			if limitsFile != "" {
+				data, err := ioutil.ReadFile(limitsFile)
+				exitOnError(err, "reading category limits")
+				limits, err = zp.ParseCategoryBoundaries(data)
+				exitOnError(err, fmt.Sprintf("reading category limits from %s", limitsFile))
+			}
+			exitOnError(Upgrades(clubID, limits, margin), fmt.Sprintf("checking upgrades for %d", clubID))
+		},
+	}
+	cmd.Flags().Float64Var(&margin.Wkg, "margin", margin.Wkg, "Warn about riders within this many w/kg of the next category up")
+	cmd.Flags().Float64Var(&margin.Watts, "watts-margin", margin.Watts, "Warn about riders within this many watts of the next category up")
+	cmd.Flags().StringVar(&limitsFile, "limits", "", "JSON file of category limits to use instead of ZwiftPower's")
+	return cmd
+}
+
+// Upgrades writes out the club's riders who are close to moving up a category. The
+// limits are ZwiftPower's if nil.
+func Upgrades(clubID int, limits []zp.CategoryBoundary, margin zp.UpgradeMargin) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	riders, err := client.Club(context.Background(), clubID)
+	if err != nil {
+		return fmt.Errorf("getting club %d: %w", clubID, err)
+	}
+
+	events, err := rosterEvents(client, riders)
+	if err != nil {
+		return err
+	}
+
+	byRider := make(map[int][]zp.Event)
+	for _, e := range events {
+		byRider[e.Zwid] = append(byRider[e.Zwid], e)
+	}
+
+	f, err := setOutput(Filename, clubID)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, upgradeColumns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	now := reportTime()
+	warned := 0
+	for _, r := range riders {
+		w, ok := zp.CheckUpgrade(r.Zwid, byRider[r.Zwid], limits, margin, now)
+		if !ok {
+			continue
+		}
+		warned++
+
+		name := r.Name
+		if name == "" {
+			name = w.Name
+		}
+		var races []string
+		for _, e := range w.Events {
+			races = append(races, fmt.Sprintf("%s %s (%.2f w/kg, %.0fW)", e.EventDate.Format("2006-01-02"), e.EventTitle, e.FtpWkg(), float64(e.Wftp)))
+		}
+		err = writer.WriteRow([]string{
+			name,
+			strconv.Itoa(r.Zwid),
+			w.Category,
+			w.Next,
+			strconv.FormatFloat(w.Wkg, 'f', 2, 64),
+			strconv.FormatFloat(w.Watts, 'f', 0, 64),
+			strconv.FormatFloat(w.WkgToGo, 'f', 2, 64),
+			strconv.FormatFloat(w.WattsToGo, 'f', 0, 64),
+			strings.Join(races, "; "),
+		})
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+
+	log.Printf("%d of %d riders close to moving up", warned, len(riders))
+	return nil
+}
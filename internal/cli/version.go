@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Version details are set when building a release, with for example
+//
+//	go build -ldflags "-X github.com/lizrice/zwiftpower/v2/internal/cli.Version=v1.2.3" ./cmd/zwiftpower
+//
+// Binaries built with go install get the module version instead.
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// versionString describes the build, falling back to what the Go toolchain recorded
+// if the version wasn't set with ldflags
+func versionString() string {
+	version := Version
+	if version == "" {
+		version = "dev"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+
+	s := version
+	if Commit != "" {
+		s += " commit " + Commit
+	}
+	if Date != "" {
+		s += " built " + Date
+	}
+	return fmt.Sprintf("%s (%s)", s, runtime.Version())
+}
+
+func versionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", cmd.Root().Name(), versionString())
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var vettingColumns = []zp.Column{
+	{Key: "section", Header: "Section"},
+	{Key: "item", Header: "Item"},
+	{Key: "detail", Header: "Detail"},
+}
+
+func vetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "vet [rider ID] [club ID]",
+		Short: "For club admins: summarise a rider who has asked to join the club",
+		Long: `Lists the rider's activity, the categories they have raced in over the last year,
+anything in their results that looks odd, and the other teams they have ridden for,
+all from their ZwiftPower events. The club ID defaults to 2672.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			riderID := getID(args, 0)
+			clubID := getID(args[1:], 2672)
+			exitOnError(VetRider(riderID, clubID), fmt.Sprintf("vetting rider %d", riderID))
+		},
+	}
+}
+
+// VetRider writes out a summary of the rider for a join request to the club
+func VetRider(riderID int, clubID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	events, err := client.Events(context.Background(), riderID)
+	if err != nil {
+		return err
+	}
+	indexNames(nil, events)
+
+	now := reportTime()
+	v := zp.VetRider(riderID, events, strconv.Itoa(clubID), now, client.FtpAggregation)
+	r := v.Rider
+
+	var rows [][]string
+	add := func(section, item, detail string) {
+		rows = append(rows, []string{section, item, detail})
+	}
+	add("rider", r.Name, fmt.Sprintf("https://www.zwiftpower.com/profile.php?z=%d", riderID))
+	add("activity", "Events in the last year", fmt.Sprintf("%d, %d of them races", r.Rides, r.Races))
+	add("activity", "Races", fmt.Sprintf("%d in 30 days, %d in 90 days", r.Races30, r.Races90))
+	if !r.LatestEventDate.IsZero() {
+		add("activity", "Latest event", fmt.Sprintf("%s on %s", r.LatestEvent, r.LatestEventDate.Format("2006-01-02")))
+	}
+
+	h := v.Category
+	if h.Current != "" {
+		add("category", "Current", fmt.Sprintf("%s since %s", h.Current, h.Since.Format("2006-01-02")))
+	}
+	if r.Ftp90 > 0 {
+		add("category", "FTP 90 days", fmt.Sprintf("%.1f w/kg", r.Ftp90))
+	}
+	var counts []string
+	for _, c := range v.Categories {
+		counts = append(counts, fmt.Sprintf("%s: %d", c.Category, c.Races))
+	}
+	if len(counts) > 0 {
+		add("category", "Races in the last year", strings.Join(counts, ", "))
+	}
+
+	for _, f := range v.Flags {
+		detail := f.Detail
+		if f.Zid != "" {
+			detail += " in event " + f.Zid
+		}
+		add("flag", f.Check, detail)
+	}
+
+	for _, t := range v.Teams {
+		add("team", t.Team, fmt.Sprintf("%d events from %s to %s", t.Events, t.First.Format("2006-01-02"), t.Last.Format("2006-01-02")))
+	}
+
+	return writeRows(riderID, vettingColumns, len(rows), func(i int) []string {
+		return rows[i]
+	})
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var whoisColumns = []zp.Column{
+	{Key: "zwid", Header: "Zwift ID"},
+	{Key: "name", Header: "Name"},
+	{Key: "profile", Header: "Profile"},
+}
+
+// whoisCommand looks riders up in the store's name index, without asking ZwiftPower
+func whoisCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "whois [name or Zwift ID]",
+		Short: "Find riders by name, or a rider's name by ID, from what has been imported",
+		Long: `Looks in the store (--store) for riders in the club and anyone who has turned up in
+event results imported since. Case, accents and team tags don't matter, and initials match,
+so "j. smith" finds John Smith. Nothing is fetched from ZwiftPower.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(Whois(strings.Join(args, " ")), "looking up riders")
+		},
+	}
+}
+
+// Whois writes out the riders the query could mean
+func Whois(query string) error {
+	if _, err := os.Stat(StoreDir); os.IsNotExist(err) {
+		return fmt.Errorf("no store at %s, so no names to look up", StoreDir)
+	}
+	store, err := openStore(StoreDir)
+	if err != nil {
+		return fmt.Errorf("opening store: %w", err)
+	}
+	index, err := store.NameIndex()
+	if err != nil {
+		return fmt.Errorf("reading name index: %w", err)
+	}
+
+	var matches []zp.NameMatch
+	if zwid, err := strconv.Atoi(query); err == nil {
+		if name, ok := index.Name(zwid); ok {
+			matches = append(matches, zp.NameMatch{Zwid: zwid, Name: name})
+		}
+	} else {
+		matches = index.Lookup(query)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("nobody called %q in the %d names we know", query, len(index.Names))
+	}
+
+	return writeRows(0, whoisColumns, len(matches), func(i int) []string {
+		m := matches[i]
+		return []string{strconv.Itoa(m.Zwid), m.Name, zp.ColumnFormats["profile"](m.Zwid)}
+	})
+}
+
+// indexNames adds the riders, and everyone in the events, to the store's name index so
+// that whois can find them later. It does nothing if there's no store.
+func indexNames(riders []zp.Rider, events []zp.Event) {
+	indexNamesIn(StoreDir, riders, events)
+}
+
+// indexNamesIn adds the riders and events to the name index of the store in dir
+func indexNamesIn(dir string, riders []zp.Rider, events []zp.Event) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return
+	}
+	store, err := openStore(dir)
+	if err == nil {
+		err = store.IndexNames(riders, events)
+	}
+	if err != nil {
+		log.Printf("Indexing names: %v", err)
+	}
+}
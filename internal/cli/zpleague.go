@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lizrice/zwiftpower/v2/zp"
+	"github.com/spf13/cobra"
+)
+
+var leagueStandingColumns = []zp.Column{
+	{Key: "position", Header: "Position"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "team", Header: "Team"},
+	{Key: "category", Header: "Category"},
+	{Key: "points", Header: "Points"},
+	{Key: "rounds", Header: "Rounds"},
+}
+
+var leagueRoundColumns = []zp.Column{
+	{Key: "round", Header: "Round"},
+	{Key: "date", Header: "Date"},
+	{Key: "event", Header: "Event"},
+	{Key: "zid", Header: "Event ID"},
+}
+
+var leagueResultColumns = []zp.Column{
+	{Key: "round", Header: "Round"},
+	{Key: "zid", Header: "Event ID"},
+	{Key: "position", Header: "Position"},
+	{Key: "name", Header: "Name"},
+	{Key: "zwid", Header: "Zwid"},
+	{Key: "team", Header: "Team"},
+	{Key: "category", Header: "Category"},
+	{Key: "points", Header: "Points"},
+	{Key: "time", Header: "Time"},
+}
+
+// zpLeagueCommand has subcommands for importing leagues hosted on ZwiftPower
+func zpLeagueCommand() *cobra.Command {
+	zpLeagueCmd := &cobra.Command{
+		Use:   "zpleague",
+		Short: "Import a league hosted on ZwiftPower",
+	}
+
+	zpLeagueCmd.AddCommand(
+		&cobra.Command{
+			Use:   "standings [league ID]",
+			Short: "Write out the league's overall standings",
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				exitOnError(HostedLeagueStandings(getID(args, 0)), "getting league standings")
+			},
+		},
+		&cobra.Command{
+			Use:   "rounds [league ID]",
+			Short: "List the league's rounds",
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				exitOnError(HostedLeagueRounds(getID(args, 0)), "getting league rounds")
+			},
+		},
+		&cobra.Command{
+			Use:   "results [league ID] [event ID...]",
+			Short: "Write out results for these rounds, or every round if none are given",
+			Args:  cobra.MinimumNArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				exitOnError(HostedLeagueResults(getID(args, 0), args[1:]), "getting league results")
+			},
+		},
+	)
+	return zpLeagueCmd
+}
+
+// HostedLeagueStandings writes out the standings of a league hosted on ZwiftPower
+func HostedLeagueStandings(leagueID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	standings, err := zp.ImportLeagueStandings(client.HTTP, leagueID)
+	if err != nil {
+		return err
+	}
+
+	return writeRows(leagueID, leagueStandingColumns, len(standings), func(i int) []string {
+		s := standings[i]
+		return []string{
+			strconv.Itoa(s.Position),
+			s.RiderName(),
+			strconv.Itoa(s.Zwid),
+			s.TeamName,
+			s.Category,
+			strconv.FormatFloat(float64(s.Points), 'f', -1, 64),
+			strconv.FormatFloat(float64(s.Rounds), 'f', -1, 64),
+		}
+	})
+}
+
+// HostedLeagueRounds lists the rounds of a league hosted on ZwiftPower
+func HostedLeagueRounds(leagueID int) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	rounds, err := zp.ImportLeagueRounds(client.HTTP, leagueID)
+	if err != nil {
+		return err
+	}
+
+	return writeRows(leagueID, leagueRoundColumns, len(rounds), func(i int) []string {
+		r := rounds[i]
+		return []string{strconv.Itoa(r.Round), formatDate(r.Date), r.Title, r.Zid}
+	})
+}
+
+// HostedLeagueResults writes out the results of these rounds of a league hosted on
+// ZwiftPower, or of every round if no event IDs are given
+func HostedLeagueResults(leagueID int, zids []string) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("error getting client: %w", err)
+	}
+
+	rounds, err := zp.ImportLeagueRounds(client.HTTP, leagueID)
+	if err != nil {
+		return err
+	}
+	roundNumbers := make(map[string]int, len(rounds))
+	for _, r := range rounds {
+		roundNumbers[r.Zid] = r.Round
+	}
+	if len(zids) == 0 {
+		for _, r := range rounds {
+			zids = append(zids, r.Zid)
+		}
+	}
+
+	var results []zp.LeagueResult
+	for _, zid := range zids {
+		rr, err := zp.ImportLeagueResults(client.HTTP, leagueID, zid)
+		if err != nil {
+			return err
+		}
+		for i := range rr {
+			rr[i].Zid = zid
+		}
+		results = append(results, rr...)
+	}
+
+	return writeRows(leagueID, leagueResultColumns, len(results), func(i int) []string {
+		r := results[i]
+		return []string{
+			strconv.Itoa(roundNumbers[r.Zid]),
+			r.Zid,
+			strconv.Itoa(r.Pos),
+			r.RiderName(),
+			strconv.Itoa(r.Zwid),
+			r.TeamName,
+			r.Category,
+			strconv.FormatFloat(float64(r.Points), 'f', -1, 64),
+			formatDuration(time.Duration(float64(r.Time) * float64(time.Second))),
+		}
+	})
+}
+
+// writeRows sets up the output and writes n rows to it
+func writeRows(id int, columns []zp.Column, n int, row func(i int) []string) error {
+	f, err := setOutput(Filename, id)
+	if err != nil {
+		return fmt.Errorf("opening file %s: %w", Filename, err)
+	}
+	defer f.Close()
+
+	writer, err := NewRowWriter(f, Format, columns)
+	if err != nil {
+		return err
+	}
+	defer writer.Flush()
+
+	for i := 0; i < n; i++ {
+		err = writer.WriteRow(row(i))
+		if err != nil {
+			return fmt.Errorf("writing to file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ZwiftActivitiesURL is where we find a rider's activities: every ride, including free
+// rides and workouts that ZwiftPower doesn't list. Like the profile it needs an access
+// token, and only has activities the token's owner is allowed to see.
+var ZwiftActivitiesURL = "https://us-or-rly101.zwift.com/api/profiles/%d/activities?start=%d&limit=%d"
+
+// activityPageSize is how many activities we ask Zwift for at a time, and maxActivityPages
+// stops us paging forever through a history that doesn't go back as far as we want
+var (
+	activityPageSize = 50
+	maxActivityPages = 20
+)
+
+// eventActivityWindow is how close an activity has to start to one of the rider's
+// ZwiftPower events for us to take it to be the same ride
+var eventActivityWindow = time.Hour
+
+// Activity is a ride from the Zwift API
+type Activity struct {
+	ID               int64     `json:"id"`
+	Name             string    `json:"name"`
+	Sport            string    `json:"sport"`
+	StartDate        zwiftTime `json:"startDate"`
+	DistanceInMeters float64   `json:"distanceInMeters"`
+	MovingTimeInMs   int64     `json:"movingTimeInMs"`
+	AvgWatts         float64   `json:"avgWatts"`
+}
+
+// IsRide is true for cycling, as opposed to running
+func (a Activity) IsRide() bool {
+	return a.Sport == "" || strings.EqualFold(a.Sport, "CYCLING")
+}
+
+// zwiftTime copes with the Zwift API's times, such as 2021-02-03T18:00:00.000+0000
+type zwiftTime struct {
+	time.Time
+}
+
+func (t *zwiftTime) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil || s == "" {
+		return err
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.000-0700", time.RFC3339} {
+		if t.Time, err = time.Parse(layout, s); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// ImportActivities gets the rider's activities from the Zwift API, newest first, going
+// back to this time
+func ImportActivities(ctx context.Context, client *http.Client, token string, riderID int, since time.Time) (activities []Activity, err error) {
+	ctx, span := startSpan(ctx, "ImportActivities", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	for page := 0; page < maxActivityPages; page++ {
+		var aa []Activity
+		url := fmt.Sprintf(ZwiftActivitiesURL, riderID, page*activityPageSize, activityPageSize)
+		err = getZwiftJSON(ctx, client, token, url, "Zwift activities", &aa)
+		if err != nil {
+			return activities, err
+		}
+
+		for _, a := range aa {
+			if a.StartDate.Before(since) {
+				return activities, nil
+			}
+			activities = append(activities, a)
+		}
+		if len(aa) < activityPageSize {
+			break
+		}
+	}
+	span.SetAttributes(attribute.Int("zwiftpower.activities", len(activities)))
+	return activities, nil
+}
+
+// addActivities counts rides from the last year that aren't ZwiftPower events, such as
+// free rides and workouts, into the rider's Rides. An activity that starts close to one
+// of their events is that event, so isn't counted twice.
+func (s *riderStats) addActivities(activities []Activity) {
+	for _, a := range activities {
+		start := a.StartDate.Time
+		if !a.IsRide() || start.After(s.now) || s.now.Sub(start) > 365*24*time.Hour {
+			continue
+		}
+
+		event := false
+		for _, t := range s.eventTimes {
+			if d := start.Sub(t); d < eventActivityWindow && d > -eventActivityWindow {
+				event = true
+				break
+			}
+		}
+		if !event {
+			s.rider.Rides++
+			s.rider.FreeRides++
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestImportActivities(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+	last := events[12].EventDate.UTC()
+	asOf := last.AddDate(0, 0, 1)
+
+	activity := func(id int64, start time.Time, sport string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "sport": sport, "startDate": start.Format("2006-01-02T15:04:05.000-0700")}
+	}
+	// Newest first, as Zwift has them
+	activities := []map[string]interface{}{
+		activity(5, last.AddDate(0, 0, 2), "CYCLING"),   // After the clock
+		activity(1, last.Add(12*time.Hour), "CYCLING"),  // Free ride
+		activity(2, last.Add(5*time.Minute), "CYCLING"), // The race itself
+		activity(3, last.Add(-3*time.Hour), "RUNNING"),  // Not a ride
+		activity(4, last.AddDate(0, -1, 0), "CYCLING"),  // Workout
+		activity(6, asOf.AddDate(-1, 0, 1), "CYCLING"),  // Just within the year
+		activity(7, asOf.AddDate(-2, 0, 0), "CYCLING"),  // Never fetched
+	}
+
+	oldPageSize := activityPageSize
+	activityPageSize = 2
+	requests := 0
+	zwift := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := start + limit
+		if end > len(activities) {
+			end = len(activities)
+		}
+		json.NewEncoder(w).Encode(activities[start:end])
+	}))
+	oldURL := ZwiftActivitiesURL
+	ZwiftActivitiesURL = zwift.URL + "/api/profiles/%d/activities?start=%d&limit=%d"
+	t.Cleanup(func() {
+		zwift.Close()
+		ZwiftActivitiesURL = oldURL
+		activityPageSize = oldPageSize
+	})
+
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testdata)
+	})
+
+	// Paging stops at the first activity before since
+	aa, err := ImportActivities(context.Background(), http.DefaultClient, "good", 1261784, asOf.AddDate(-1, 0, 0))
+	if err != nil || len(aa) != 6 || requests != 4 {
+		t.Errorf("Got %d activities in %d requests, %v", len(aa), requests, err)
+	}
+	if aa[1].StartDate.Unix() != last.Add(12*time.Hour).Unix() {
+		t.Errorf("Unexpected start date %v", aa[1].StartDate)
+	}
+
+	client, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Clock = FixedClock(asOf)
+	without, err := client.Rider(context.Background(), 1261784)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.ZwiftToken = "good"
+	with, err := client.Rider(context.Background(), 1261784)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if with.FreeRides != 3 || with.Rides != without.Rides+3 {
+		t.Errorf("Expected three free rides on top of %d, got %d of %d", without.Rides, with.FreeRides, with.Rides)
+	}
+
+	client.ZwiftToken = "bad"
+	_, err = client.Rider(context.Background(), 1261784)
+	if !errors.Is(err, ErrZwiftAuth) {
+		t.Errorf("Expected auth error, got %v", err)
+	}
+}
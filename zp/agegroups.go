@@ -0,0 +1,111 @@
+package zp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Age groups, in the order we report them
+const (
+	Junior      = "Junior"
+	Senior      = "Senior"
+	Veteran     = "Veteran"
+	Masters     = "Masters"
+	Masters50   = "50+"
+	Masters60   = "60+"
+	Masters70   = "70+"
+	UnknownAges = "Unknown"
+)
+
+var ageGroupOrder = []string{Junior, Senior, Veteran, Masters, Masters50, Masters60, Masters70, UnknownAges}
+
+// ZwiftPower's own abbreviations for age groups
+var ageGroupNames = map[string]string{
+	"jnr": Junior,
+	"snr": Senior,
+	"vet": Veteran,
+	"mas": Masters,
+	"50+": Masters50,
+	"60+": Masters60,
+	"70+": Masters70,
+}
+
+// AgeGroup turns the age from ZwiftPower into an age group. Depending on where it comes
+// from, ZwiftPower gives either an abbreviated group name or an age in years.
+func AgeGroup(age string) string {
+	age = strings.ToLower(strings.TrimSpace(age))
+	if g, ok := ageGroupNames[age]; ok {
+		return g
+	}
+
+	years, err := strconv.Atoi(age)
+	if err != nil || years <= 0 {
+		return UnknownAges
+	}
+
+	switch {
+	case years < 19:
+		return Junior
+	case years < 30:
+		return Senior
+	case years < 40:
+		return Veteran
+	case years < 50:
+		return Masters
+	case years < 60:
+		return Masters50
+	case years < 70:
+		return Masters60
+	default:
+		return Masters70
+	}
+}
+
+// AgeGroup is the age group this rider belongs to
+func (r Rider) AgeGroup() string {
+	return AgeGroup(r.Age)
+}
+
+// IsMasters is true for riders aged 40 and over
+func (r Rider) IsMasters() bool {
+	switch r.AgeGroup() {
+	case Masters, Masters50, Masters60, Masters70:
+		return true
+	}
+	return false
+}
+
+// Leaderboard ranks the riders in one age group
+type Leaderboard struct {
+	AgeGroup string
+	Riders   []Rider
+}
+
+// AgeGroupLeaderboards splits riders into age groups, ranking each group by 90-day FTP
+// and then by number of races. Empty groups are left out.
+func AgeGroupLeaderboards(riders []Rider) []Leaderboard {
+	groups := make(map[string][]Rider)
+	for _, r := range riders {
+		g := r.AgeGroup()
+		groups[g] = append(groups[g], r)
+	}
+
+	var boards []Leaderboard
+	for _, g := range ageGroupOrder {
+		rr := groups[g]
+		if len(rr) == 0 {
+			continue
+		}
+
+		sort.SliceStable(rr, func(i, j int) bool {
+			if rr[i].Ftp90 != rr[j].Ftp90 {
+				return rr[i].Ftp90 > rr[j].Ftp90
+			}
+			return rr[i].Races90 > rr[j].Races90
+		})
+		boards = append(boards, Leaderboard{AgeGroup: g, Riders: rr})
+	}
+
+	return boards
+}
@@ -0,0 +1,85 @@
+package zp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAgeGroup(t *testing.T) {
+	cases := map[string]string{
+		"Jnr": Junior,
+		"17":  Junior,
+		"26":  Senior,
+		"Vet": Veteran,
+		"39":  Veteran,
+		"MAS": Masters,
+		"45":  Masters,
+		"50+": Masters50,
+		"64":  Masters60,
+		"81":  Masters70,
+		"":    UnknownAges,
+		"0":   UnknownAges,
+	}
+
+	for age, expected := range cases {
+		if result := AgeGroup(age); result != expected {
+			t.Errorf("Got %s for %q, expected %s", result, age, expected)
+		}
+	}
+}
+
+func TestAgeFromData(t *testing.T) {
+	var c club
+	err := json.Unmarshal([]byte(`{"data":[{"name":"A","zwid":1,"age":"Mas"}]}`), &c)
+	if err != nil || c.Data[0].AgeGroup() != Masters || !c.Data[0].IsMasters() {
+		t.Errorf("Unexpected riders %v, %v", c.Data, err)
+	}
+
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	r := RiderFromEvents(1261784, events)
+	if r.Age != "27" || r.AgeGroup() != Senior || r.IsMasters() {
+		t.Errorf("Got age %s, expected 27 from latest event", r.Age)
+	}
+
+	r = withRosterData(r, Rider{Age: "Vet"})
+	if r.AgeGroup() != Veteran {
+		t.Errorf("Expected age group from roster, got %s", r.AgeGroup())
+	}
+}
+
+func TestAgeGroupLeaderboards(t *testing.T) {
+	riders := []Rider{
+		{Name: "A", Age: "Vet", Ftp90: 3.1},
+		{Name: "B", Age: "Mas", Ftp90: 3.5},
+		{Name: "C", Age: "Vet", Ftp90: 3.4},
+		{Name: "D", Age: "Vet", Ftp90: 3.4, Races90: 5},
+		{Name: "E"},
+	}
+
+	boards := AgeGroupLeaderboards(riders)
+	if len(boards) != 3 {
+		t.Fatalf("Got %d leaderboards, expected 3", len(boards))
+	}
+
+	expected := []struct {
+		group string
+		names string
+	}{
+		{Veteran, "DCA"},
+		{Masters, "B"},
+		{UnknownAges, "E"},
+	}
+	for i, e := range expected {
+		var names string
+		for _, r := range boards[i].Riders {
+			names += r.Name
+		}
+		if boards[i].AgeGroup != e.group || names != e.names {
+			t.Errorf("Got %s %s, expected %s %s", boards[i].AgeGroup, names, e.group, e.names)
+		}
+	}
+}
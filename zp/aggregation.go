@@ -0,0 +1,121 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Aggregation is how the FTP estimates from a rider's events in a window, such as the last
+// 90 days, are combined into one figure
+type Aggregation string
+
+const (
+	AggregateMax    Aggregation = "max"    // The best, as ZwiftPower does
+	AggregateSecond Aggregation = "second" // The second best, so that one spiky result doesn't count
+	AggregateP95    Aggregation = "p95"    // The 95th percentile
+	AggregateTop3   Aggregation = "top3"   // The mean of the best three
+)
+
+// Aggregations lists the ways of combining FTP estimates
+var Aggregations = []Aggregation{AggregateMax, AggregateSecond, AggregateP95, AggregateTop3}
+
+// ParseAggregation checks that s names an aggregation we know about. Empty means
+// AggregateMax.
+func ParseAggregation(s string) (Aggregation, error) {
+	a := Aggregation(strings.ToLower(strings.TrimSpace(s)))
+	if a == "" {
+		return AggregateMax, nil
+	}
+	for _, known := range Aggregations {
+		if a == known {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unknown aggregation %q, expected one of %s", s, joinAggregations())
+}
+
+func joinAggregations() string {
+	names := make([]string, len(Aggregations))
+	for i, a := range Aggregations {
+		names[i] = string(a)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Apply combines the values. With fewer values than the aggregation looks at, such as a
+// single race for AggregateSecond, it makes do with what there is. No values give zero.
+func (a Aggregation) Apply(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	switch a {
+	case AggregateSecond:
+		if len(sorted) > 1 {
+			return sorted[1]
+		}
+	case AggregateP95:
+		// Interpolating between the nearest ranks, counting down from the best
+		rank := 0.05 * float64(len(sorted)-1)
+		i := int(rank)
+		if i+1 >= len(sorted) {
+			return sorted[i]
+		}
+		return sorted[i] - (rank-float64(i))*(sorted[i]-sorted[i+1])
+	case AggregateTop3:
+		n := len(sorted)
+		if n > 3 {
+			n = 3
+		}
+		total := 0.0
+		for _, v := range sorted[:n] {
+			total += v
+		}
+		return total / float64(n)
+	}
+	return sorted[0]
+}
+
+// FtpAggregations say how each of a rider's FTP figures is worked out. Empty means
+// AggregateMax.
+type FtpAggregations struct {
+	Ftp30 Aggregation
+	Ftp60 Aggregation
+	Ftp90 Aggregation
+}
+
+// ParseFtpAggregations reads an aggregation for all three FTP figures, such as "p95", or
+// for each one separately, such as "ftp90=p95,ftp30=second". Any that aren't given are
+// AggregateMax.
+func ParseFtpAggregations(s string) (FtpAggregations, error) {
+	var f FtpAggregations
+	if !strings.Contains(s, "=") {
+		a, err := ParseAggregation(s)
+		return FtpAggregations{Ftp30: a, Ftp60: a, Ftp90: a}, err
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return f, fmt.Errorf("expected metric=aggregation, got %q", part)
+		}
+		a, err := ParseAggregation(kv[1])
+		if err != nil {
+			return f, err
+		}
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "ftp30":
+			f.Ftp30 = a
+		case "ftp60":
+			f.Ftp60 = a
+		case "ftp90":
+			f.Ftp90 = a
+		default:
+			return f, fmt.Errorf("unknown metric %q, expected ftp30, ftp60 or ftp90", kv[0])
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,103 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAggregations(t *testing.T) {
+	values := []float64{3.0, 4.5, 3.2, 3.4, 3.1}
+	cases := map[Aggregation]float64{
+		AggregateMax:    4.5,
+		AggregateSecond: 3.4,
+		AggregateP95:    4.5 - 0.2*(4.5-3.4),
+		AggregateTop3:   (4.5 + 3.4 + 3.2) / 3,
+	}
+	for a, expected := range cases {
+		if got := a.Apply(values); math.Abs(got-expected) > 1e-9 {
+			t.Errorf("%s gave %v, expected %v", a, got, expected)
+		}
+	}
+	if values[0] != 3.0 {
+		t.Errorf("Apply changed the values")
+	}
+
+	// Making do with fewer values
+	for _, a := range Aggregations {
+		if got := a.Apply([]float64{3.3}); got != 3.3 {
+			t.Errorf("%s of one value gave %v", a, got)
+		}
+		if got := a.Apply(nil); got != 0 {
+			t.Errorf("%s of nothing gave %v", a, got)
+		}
+	}
+}
+
+func TestParseFtpAggregations(t *testing.T) {
+	f, err := ParseFtpAggregations("P95")
+	if err != nil || f != (FtpAggregations{Ftp30: AggregateP95, Ftp60: AggregateP95, Ftp90: AggregateP95}) {
+		t.Errorf("Got %+v: %v", f, err)
+	}
+	f, err = ParseFtpAggregations("ftp90=top3, ftp30=second")
+	if err != nil || f != (FtpAggregations{Ftp30: AggregateSecond, Ftp60: "", Ftp90: AggregateTop3}) {
+		t.Errorf("Got %+v: %v", f, err)
+	}
+	for _, bad := range []string{"median", "ftp20=max", "ftp90=mean", "ftp90=max,top3"} {
+		if _, err := ParseFtpAggregations(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRiderFtpAggregation(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	var events []Event
+	for i, wkg := range []float64{3.0, 4.5, 3.2, 0} {
+		date := now.AddDate(0, 0, -10*(i+1))
+		events = append(events, Event{Zid: string(rune('a' + i)), EventDate: date, EventDateSecs: EventDateType(date.Unix()), WkgFtp: []interface{}{wkg, 0.0}})
+	}
+
+	r := RiderFromEventsAsOf(1, events, now)
+	if r.Ftp30 != 4.5 || r.Ftp90 != 4.5 {
+		t.Errorf("Expected the best FTPs by default, got %v and %v", r.Ftp30, r.Ftp90)
+	}
+
+	r = RiderFromEventsAggregated(1, events, now, FtpAggregations{Ftp90: AggregateSecond})
+	if r.Ftp30 != 4.5 || r.Ftp90 != 3.2 {
+		t.Errorf("Expected the second best FTP for 90 days only, got %v and %v", r.Ftp30, r.Ftp90)
+	}
+}
+
+func TestClientFtpAggregation(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testdata)
+	})
+	events, err := parseEvents([]byte(testdata))
+	must(t, err)
+	now := events[0].EventDate
+	for _, e := range events {
+		if e.EventDate.After(now) {
+			now = e.EventDate
+		}
+	}
+
+	// Two clients in one process can combine FTPs differently
+	best, second := Wrap(http.DefaultClient), Wrap(http.DefaultClient)
+	best.Clock, second.Clock = FixedClock(now), FixedClock(now)
+	second.FtpAggregation = FtpAggregations{Ftp90: AggregateSecond}
+
+	a, err := best.Rider(context.Background(), 1261784)
+	must(t, err)
+	b, err := second.Rider(context.Background(), 1261784)
+	must(t, err)
+	if expected := RiderFromEventsAsOf(1261784, events, now).Ftp90; a.Ftp90 != expected {
+		t.Errorf("Expected the best FTP %v by default, got %v", expected, a.Ftp90)
+	}
+	if expected := RiderFromEventsAggregated(1261784, events, now, second.FtpAggregation).Ftp90; b.Ftp90 != expected || b.Ftp90 == a.Ftp90 {
+		t.Errorf("Expected the second best FTP %v, got %v (best %v)", expected, b.Ftp90, a.Ftp90)
+	}
+}
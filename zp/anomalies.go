@@ -0,0 +1,244 @@
+package zp
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Anomaly is something odd about a result that an organizer might want to look at. It's
+// only a heuristic: plenty of anomalies have innocent explanations.
+type Anomaly struct {
+	Zid      string
+	Zwid     int
+	Name     string
+	Pos      int
+	Category string
+	Check    string
+	Detail   string
+}
+
+// Names of the anomaly checks
+const (
+	CheckPower   = "power"    // Power beyond what's humanly plausible
+	CheckWeight  = "weight"   // Improbable weight for height
+	CheckNoHR    = "no-hr"    // Podium finisher with no heart rate data
+	CheckHR      = "hr"       // Power far out of line with heart rate, compared with the field
+	CheckOutlier = "outlier"  // Power far above the rest of the category
+	CheckNoDraft = "no-draft" // Faster than the power allows riding solo, in a no-draft event
+)
+
+const (
+	minOutlierField = 5    // Riders needed before we look for statistical outliers
+	outlierScore    = 3.5  // Robust z-score above which a value is an outlier
+	noDraftMargin   = 0.85 // Finishing in less than this fraction of the solo time is suspicious
+	minBMI          = 16.0 // Body mass index below which a weight is improbable
+	minWeight       = 40.0 // kg
+	podium          = 3    // Positions in category that need heart rate data
+	hrCheckMinWkg   = 2.0  // Ignore power/HR ratios for very easy efforts
+)
+
+// PowerLimits are the w/kg we treat as beyond plausible for each duration in seconds.
+// They are around world-class levels, so anything above them deserves a second look.
+var PowerLimits = map[int]float64{5: 25, 15: 20, 30: 16, 60: 12, 120: 9.5, 300: 7.5, 1200: 6.5}
+
+var noDraftTitle = regexp.MustCompile(`(?i)\b(i?tt|time trial|no[- ]?draft)\b`)
+
+// IsNoDraft is true for time trials and other events where riders can't draft
+func (e Event) IsNoDraft() bool {
+	return strings.Contains(e.EventType, "TYPE_TT") || noDraftTitle.MatchString(e.EventTitle)
+}
+
+// ReviewResults scans an event's results for anomalies, returning them in finishing order
+func ReviewResults(results []Event) []Anomaly {
+	var anomalies []Anomaly
+	for _, e := range results {
+		anomalies = append(anomalies, checkPower(e)...)
+		anomalies = append(anomalies, checkWeight(e)...)
+		anomalies = append(anomalies, checkNoHR(e)...)
+		anomalies = append(anomalies, checkNoDraft(e)...)
+	}
+	anomalies = append(anomalies, checkHR(results)...)
+	anomalies = append(anomalies, checkOutliers(results)...)
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].Pos < anomalies[j].Pos
+	})
+	return anomalies
+}
+
+func newAnomaly(e Event, check string, format string, args ...interface{}) Anomaly {
+	return Anomaly{
+		Zid:      e.Zid,
+		Zwid:     e.Zwid,
+		Name:     e.RiderName(),
+		Pos:      e.Pos,
+		Category: e.Category,
+		Check:    check,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
+
+// powerCurve is the event's best power for each duration in seconds
+func powerCurve(e Event) map[int]Number {
+	return map[int]Number{5: e.W5, 15: e.W15, 30: e.W30, 60: e.W60, 120: e.W120, 300: e.W300, 1200: e.W1200}
+}
+
+func checkPower(e Event) []Anomaly {
+	if e.Weight <= 0 {
+		return nil
+	}
+
+	// Report the longest duration over the limit, as the most telling
+	longest := 0
+	for secs, w := range powerCurve(e) {
+		if float64(w)/float64(e.Weight) > PowerLimits[secs] && secs > longest {
+			longest = secs
+		}
+	}
+	if longest == 0 {
+		return nil
+	}
+
+	wkg := float64(powerCurve(e)[longest]) / float64(e.Weight)
+	return []Anomaly{newAnomaly(e, CheckPower, "%.1f w/kg for %s, above the %.1f limit",
+		wkg, formatSeconds(float64(longest)), PowerLimits[longest])}
+}
+
+func checkWeight(e Event) []Anomaly {
+	weight := float64(e.Weight)
+	if weight <= 0 {
+		return nil
+	}
+	if weight < minWeight {
+		return []Anomaly{newAnomaly(e, CheckWeight, "weighs %.1fkg", weight)}
+	}
+
+	height := float64(e.Height) / 100
+	if height <= 0 {
+		return nil
+	}
+	if bmi := weight / (height * height); bmi < minBMI {
+		return []Anomaly{newAnomaly(e, CheckWeight, "%.1fkg at %.0fcm is a BMI of %.1f", weight, float64(e.Height), bmi)}
+	}
+	return nil
+}
+
+func checkNoHR(e Event) []Anomaly {
+	if e.PositionInCat < 1 || e.PositionInCat > podium || e.AvgHR > 0 {
+		return nil
+	}
+	return []Anomaly{newAnomaly(e, CheckNoHR, "%s in %s with no heart rate data", ordinal(e.PositionInCat), e.Category)}
+}
+
+func checkNoDraft(e Event) []Anomaly {
+	if !e.IsNoDraft() || e.Distance <= 0 || e.Time <= 0 || e.AvgPower <= 0 || e.Weight <= 0 {
+		return nil
+	}
+
+	// Riding solo on the flat is as fast as this power can go without drafting
+	v := speed(float64(e.AvgPower), float64(e.Weight)+bikeWeight, 0)
+	solo := float64(e.Distance) * 1000 / v
+	if float64(e.Time) >= solo*noDraftMargin {
+		return nil
+	}
+	return []Anomaly{newAnomaly(e, CheckNoDraft, "finished in %s, but %.0fW should take at least %s solo",
+		formatSeconds(float64(e.Time)), float64(e.AvgPower), formatSeconds(solo))}
+}
+
+// hrRatio is w/kg per 100 beats per minute, or zero if we don't have both
+func hrRatio(e Event) float64 {
+	wkg := toFloat(e.AvgWkg)
+	if e.AvgHR <= 0 || wkg < hrCheckMinWkg {
+		return 0
+	}
+	return 100 * wkg / float64(e.AvgHR)
+}
+
+// checkHR compares each rider's power for their heart rate with the rest of the field
+func checkHR(results []Event) []Anomaly {
+	var field []Event
+	var ratios []float64
+	for _, e := range results {
+		if r := hrRatio(e); r > 0 {
+			field = append(field, e)
+			ratios = append(ratios, r)
+		}
+	}
+
+	var anomalies []Anomaly
+	for i, z := range robustScores(ratios) {
+		if z > outlierScore {
+			e := field[i]
+			anomalies = append(anomalies, newAnomaly(e, CheckHR, "%.1f w/kg per 100bpm at %.0fbpm, far above the field's %.1f",
+				ratios[i], float64(e.AvgHR), median(ratios)))
+		}
+	}
+	return anomalies
+}
+
+// checkOutliers compares each rider's average w/kg with the rest of their category
+func checkOutliers(results []Event) []Anomaly {
+	byCategory := make(map[string][]Event)
+	for _, e := range results {
+		if toFloat(e.AvgWkg) > 0 {
+			byCategory[e.Category] = append(byCategory[e.Category], e)
+		}
+	}
+
+	var anomalies []Anomaly
+	for category, field := range byCategory {
+		wkg := make([]float64, len(field))
+		for i, e := range field {
+			wkg[i] = toFloat(e.AvgWkg)
+		}
+		for i, z := range robustScores(wkg) {
+			if z > outlierScore {
+				anomalies = append(anomalies, newAnomaly(field[i], CheckOutlier, "averaged %.1f w/kg, against %.1f for %s",
+					wkg[i], median(wkg), category))
+			}
+		}
+	}
+	return anomalies
+}
+
+// robustScores are like z-scores, but based on the median and median absolute deviation
+// so that the outliers we are looking for don't skew them. They are all zero if there
+// aren't enough values, or they don't vary.
+func robustScores(values []float64) []float64 {
+	scores := make([]float64, len(values))
+	if len(values) < minOutlierField {
+		return scores
+	}
+
+	m := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - m)
+	}
+	mad := median(deviations)
+	if mad == 0 {
+		return scores
+	}
+
+	for i, v := range values {
+		scores[i] = 0.6745 * (v - m) / mad
+	}
+	return scores
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
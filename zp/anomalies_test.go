@@ -0,0 +1,112 @@
+package zp
+
+import (
+	"fmt"
+	"testing"
+)
+
+// field makes a plausible set of results for a category, with everyone at around
+// 3 w/kg and 150bpm
+func field(n int) []Event {
+	results := make([]Event, n)
+	for i := range results {
+		results[i] = Event{
+			Zid:           "1",
+			Zwid:          i + 1,
+			Name:          "Rider",
+			Pos:           i + 1,
+			PositionInCat: i + 1,
+			Category:      "C",
+			EventTitle:    "Club Race",
+			Weight:        70,
+			Height:        175,
+			AvgWkg:        []interface{}{fmt.Sprintf("%.2f", 3+float64(i%5)/100)},
+			AvgHR:         Number(145 + i%10),
+			AvgPower:      210,
+			W5:            700,
+			W1200:         230,
+		}
+	}
+	return results
+}
+
+func checksFor(anomalies []Anomaly, zwid int) []string {
+	var checks []string
+	for _, a := range anomalies {
+		if a.Zwid == zwid {
+			checks = append(checks, a.Check)
+		}
+	}
+	return checks
+}
+
+func TestReviewResults(t *testing.T) {
+	if a := ReviewResults(field(10)); len(a) != 0 {
+		t.Fatalf("Expected no anomalies for a normal field, got %+v", a)
+	}
+
+	results := field(10)
+	results[0].W1200 = 490    // 7 w/kg for 20 minutes
+	results[1].Weight = 38    // Very light
+	results[2].AvgHR = 0      // On the podium, but no HR
+	results[3].AvgHR = 90     // Cruising along at 3 w/kg
+	results[4].AvgWkg = "4.5" // Much stronger than the rest of C
+	results[4].AvgHR = 150
+
+	anomalies := ReviewResults(results)
+	expected := map[int]string{1: CheckPower, 2: CheckWeight, 3: CheckNoHR, 4: CheckHR, 5: CheckOutlier}
+	for zwid, check := range expected {
+		checks := checksFor(anomalies, zwid)
+		found := false
+		for _, c := range checks {
+			found = found || c == check
+		}
+		if !found {
+			t.Errorf("Expected %s anomaly for rider %d, got %v", check, zwid, checks)
+		}
+	}
+	for i := 1; i < len(anomalies); i++ {
+		if anomalies[i].Pos < anomalies[i-1].Pos {
+			t.Errorf("Anomalies not in finishing order")
+		}
+	}
+	if a := anomalies[0]; a.Detail != "7.0 w/kg for 20:00, above the 6.5 limit" {
+		t.Errorf("Unexpected detail %q", a.Detail)
+	}
+}
+
+func TestNoDraft(t *testing.T) {
+	e := Event{EventTitle: "Club ITT", Distance: 20, Time: 1500, AvgPower: 200, Weight: 70}
+	if !e.IsNoDraft() || (Event{EventTitle: "Team TTT"}).IsNoDraft() {
+		t.Errorf("No-draft events not recognised")
+	}
+
+	// 48km/h on 200W is only possible in a bunch
+	if a := checkNoDraft(e); len(a) != 1 {
+		t.Errorf("Expected no-draft anomaly, got %v", a)
+	}
+
+	e.Time = 2400
+	if a := checkNoDraft(e); len(a) != 0 {
+		t.Errorf("Expected no anomaly at 30km/h, got %v", a)
+	}
+
+	e.Time = 1500
+	e.EventTitle = "Club Race"
+	if a := checkNoDraft(e); len(a) != 0 {
+		t.Errorf("Drafting is fine in a normal race, got %v", a)
+	}
+}
+
+func TestRobustScores(t *testing.T) {
+	scores := robustScores([]float64{1, 2, 3, 4, 100})
+	if scores[4] < outlierScore || scores[2] != 0 {
+		t.Errorf("Unexpected scores %v", scores)
+	}
+	if scores := robustScores([]float64{1, 100}); scores[1] != 0 {
+		t.Errorf("Expected no scores for a small field, got %v", scores)
+	}
+	if m := median([]float64{4, 1, 3, 2}); m != 2.5 {
+		t.Errorf("Got median %v", m)
+	}
+}
@@ -0,0 +1,67 @@
+package zp
+
+import (
+	"fmt"
+	"time"
+)
+
+// Archival says which riders a RiderQuery matches, according to whether they've left
+// the club
+type Archival int
+
+const (
+	ActiveRiders   Archival = iota // Riders still in the club, which is the default
+	ArchivedRiders                 // Only riders who have left (see ArchiveDeparted)
+	AllRiders                      // Everyone in the store
+)
+
+// matches is true if the rider is one that the archival is for
+func (a Archival) matches(r Rider) bool {
+	switch a {
+	case ActiveRiders:
+		return r.Archived == nil
+	case ArchivedRiders:
+		return r.Archived != nil
+	}
+	return true
+}
+
+// ArchiveDeparted marks the stored riders who aren't on the club's roster any more as
+// archived, as of now. Their events and everything else stay in the store, so that
+// historical standings, awards and recomputed stats still have their results, but
+// queries leave them out unless they ask for them. Archived riders who are back on the
+// roster are restored. It returns the riders it archived and restored.
+//
+// The store should only hold riders from this club, or riders from other clubs will be
+// archived too. An empty roster is an error rather than a reason to archive everyone.
+func ArchiveDeparted(store Store, roster []Rider, now time.Time) (archived, restored []Rider, err error) {
+	if len(roster) == 0 {
+		return nil, nil, fmt.Errorf("not archiving anyone: the roster is empty")
+	}
+	onRoster := make(map[int]bool, len(roster))
+	for _, r := range roster {
+		onRoster[r.Zwid] = true
+	}
+
+	stored, err := store.QueryRiders(RiderQuery{Archived: AllRiders})
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading riders: %w", err)
+	}
+	for _, r := range stored {
+		switch {
+		case !onRoster[r.Zwid] && r.Archived == nil:
+			when := now
+			r.Archived = &when
+			archived = append(archived, r)
+		case onRoster[r.Zwid] && r.Archived != nil:
+			r.Archived = nil
+			restored = append(restored, r)
+		default:
+			continue
+		}
+		if err := store.PutRider(r); err != nil {
+			return archived, restored, fmt.Errorf("storing %s (%d): %w", r.Name, r.Zwid, err)
+		}
+	}
+	return archived, restored, nil
+}
@@ -0,0 +1,71 @@
+package zp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestArchiveDeparted(t *testing.T) {
+	s := NewMemoryStore()
+	for _, id := range []int{1, 2, 3} {
+		must(t, s.PutRider(Rider{Zwid: id, Name: fmt.Sprintf("Rider %d", id)}))
+		must(t, s.PutEvents(id, []Event{{Zwid: id, Zid: "100"}}))
+	}
+
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	archived, restored, err := ArchiveDeparted(s, []Rider{{Zwid: 1}, {Zwid: 3}}, now)
+	if err != nil || len(archived) != 1 || archived[0].Zwid != 2 || len(restored) != 0 {
+		t.Fatalf("Expected to archive rider 2, got %v %v: %v", archived, restored, err)
+	}
+
+	ids := func(q RiderQuery) string {
+		riders, err := s.QueryRiders(q)
+		must(t, err)
+		var ids []int
+		for _, r := range riders {
+			ids = append(ids, r.Zwid)
+		}
+		return fmt.Sprint(ids)
+	}
+	if got := ids(RiderQuery{}); got != "[1 3]" {
+		t.Errorf("Active riders are %s", got)
+	}
+	if got := ids(RiderQuery{Archived: ArchivedRiders}); got != "[2]" {
+		t.Errorf("Archived riders are %s", got)
+	}
+	if got := ids(RiderQuery{Archived: AllRiders}); got != "[1 2 3]" {
+		t.Errorf("All riders are %s", got)
+	}
+
+	// Their history is still there
+	r, err := s.Rider(2)
+	if err != nil || r.Name != "Rider 2" || r.Archived == nil || !r.Archived.Equal(now) {
+		t.Errorf("Archived rider is %+v: %v", r, err)
+	}
+	if events, err := s.Events(2); err != nil || len(events) != 1 {
+		t.Errorf("Archived rider's events are %v: %v", events, err)
+	}
+
+	// Archiving again changes nothing, and the date they left stays the same
+	archived, _, err = ArchiveDeparted(s, []Rider{{Zwid: 1}, {Zwid: 3}}, now.AddDate(0, 0, 1))
+	if err != nil || len(archived) != 0 {
+		t.Errorf("Archived %v again: %v", archived, err)
+	}
+
+	// Riders who come back are restored
+	_, restored, err = ArchiveDeparted(s, []Rider{{Zwid: 1}, {Zwid: 2}, {Zwid: 3}}, now)
+	if err != nil || len(restored) != 1 || restored[0].Zwid != 2 {
+		t.Errorf("Expected to restore rider 2, got %v: %v", restored, err)
+	}
+	if got := ids(RiderQuery{}); got != "[1 2 3]" {
+		t.Errorf("Active riders after restoring are %s", got)
+	}
+
+	if _, _, err := ArchiveDeparted(s, nil, now); err == nil {
+		t.Errorf("Expected an error for an empty roster")
+	}
+	if got := ids(RiderQuery{}); got != "[1 2 3]" {
+		t.Errorf("Empty roster archived riders: %s", got)
+	}
+}
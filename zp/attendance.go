@@ -0,0 +1,202 @@
+package zp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attendance says whether a league rider turned up for a fixture
+type Attendance string
+
+const (
+	// Finished riders have a result in the fixture
+	Finished Attendance = "finished"
+
+	// SignedUp riders are on the signups for a fixture without results yet
+	SignedUp Attendance = "signed up"
+
+	// NoShow riders signed up but have no result, once the results are in
+	NoShow Attendance = "no show"
+
+	// Missing riders neither signed up nor have a result
+	Missing Attendance = "missing"
+)
+
+// RiderAttendance is one league rider's attendance at a fixture
+type RiderAttendance struct {
+	Zwid         int
+	Name         string
+	Division     string
+	Status       Attendance
+	Unregistered bool // Finished without having signed up
+}
+
+// FixtureAttendance is who turned up to a fixture, in order of division then name
+type FixtureAttendance struct {
+	Fixture Fixture
+	Riders  []RiderAttendance
+}
+
+// FixtureEvents picks out the events that belong to the league's fixtures, from signups
+// or results imported for any number of events, keyed by event ID. Events for anything
+// other than a fixture are left out.
+func (l *League) FixtureEvents(events []Event) map[int][]Event {
+	fixtures := make(map[string]int, len(l.Fixtures))
+	for _, f := range l.Fixtures {
+		fixtures[strconv.Itoa(f.EventID)] = f.EventID
+	}
+
+	matched := make(map[int][]Event)
+	for _, e := range events {
+		if id, ok := fixtures[e.Zid]; ok {
+			matched[id] = append(matched[id], e)
+		}
+	}
+	return matched
+}
+
+// Attendance tracks the league's riders across its fixtures, from each fixture's
+// signups and results, keyed by event ID. The league's riders are those assigned to a
+// division, and riders from the club roster or the fixtures whose category puts them
+// in one. Roster riders without a category take it from their latest fixture.
+func (l *League) Attendance(roster []Rider, signups map[int][]Event, results map[int][]Event) []FixtureAttendance {
+	club := strconv.Itoa(l.ClubID)
+	latest := make(map[int]Event)
+	for _, f := range l.Fixtures {
+		for _, ee := range [][]Event{signups[f.EventID], results[f.EventID]} {
+			for _, e := range ee {
+				latest[e.Zwid] = e
+			}
+		}
+	}
+
+	// Work out who's in the league, and in which division
+	riders := make(map[int]RiderAttendance)
+	add := func(e Event) {
+		if _, ok := riders[e.Zwid]; ok || e.Zwid == 0 {
+			return
+		}
+		if d := l.DivisionFor(e); d != "" {
+			riders[e.Zwid] = RiderAttendance{Zwid: e.Zwid, Name: e.RiderName(), Division: d}
+		}
+	}
+	for _, r := range roster {
+		e := Event{Zwid: r.Zwid, Name: r.Name, Category: r.Category, TeamID: club}
+		if e.Category == "" {
+			e.Category = latest[r.Zwid].Category
+		}
+		add(e)
+	}
+	for _, d := range l.Divisions {
+		for _, id := range d.Riders {
+			e, ok := latest[id]
+			if !ok {
+				e = Event{Zwid: id, Name: strconv.Itoa(id)}
+			}
+			add(e)
+		}
+	}
+	for _, id := range sortedKeys(latest) {
+		add(latest[id])
+	}
+
+	var attendance []FixtureAttendance
+	for _, f := range l.Fixtures {
+		signedUp := make(map[int]bool)
+		for _, e := range signups[f.EventID] {
+			signedUp[e.Zwid] = true
+		}
+		finished := make(map[int]bool)
+		for _, e := range results[f.EventID] {
+			finished[e.Zwid] = true
+		}
+		hasResults := len(results[f.EventID]) > 0
+
+		fa := FixtureAttendance{Fixture: f}
+		for _, r := range riders {
+			switch {
+			case finished[r.Zwid]:
+				r.Status = Finished
+				r.Unregistered = !signedUp[r.Zwid]
+			case signedUp[r.Zwid] && hasResults:
+				r.Status = NoShow
+			case signedUp[r.Zwid]:
+				r.Status = SignedUp
+			default:
+				r.Status = Missing
+			}
+			fa.Riders = append(fa.Riders, r)
+		}
+		sort.Slice(fa.Riders, func(i, j int) bool {
+			a, b := fa.Riders[i], fa.Riders[j]
+			if a.Division != b.Division {
+				return l.divisionIndex(a.Division) < l.divisionIndex(b.Division)
+			}
+			if !strings.EqualFold(a.Name, b.Name) {
+				return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+			}
+			return a.Zwid < b.Zwid
+		})
+		attendance = append(attendance, fa)
+	}
+	return attendance
+}
+
+// AttendanceMatrix is the league's riders down the side and its fixtures across the top,
+// the way league coordinators keep track of who turned up over a season
+type AttendanceMatrix struct {
+	Fixtures []Fixture
+	Riders   []RiderAttendance // In order of division then name, without a Status
+	Cells    [][]Attendance    // Each rider's attendance at each fixture, Cells[rider][fixture]
+}
+
+// NewAttendanceMatrix lays out the attendance at each fixture (see League.Attendance) as a
+// grid
+func NewAttendanceMatrix(attendance []FixtureAttendance) AttendanceMatrix {
+	var m AttendanceMatrix
+	rows := make(map[int]int)
+	for i, fa := range attendance {
+		m.Fixtures = append(m.Fixtures, fa.Fixture)
+		for _, r := range fa.Riders {
+			row, ok := rows[r.Zwid]
+			if !ok {
+				row = len(m.Riders)
+				rows[r.Zwid] = row
+				m.Riders = append(m.Riders, RiderAttendance{Zwid: r.Zwid, Name: r.Name, Division: r.Division})
+				m.Cells = append(m.Cells, make([]Attendance, len(attendance)))
+			}
+			m.Cells[row][i] = r.Status
+		}
+	}
+	return m
+}
+
+// Count is how many fixtures the rider in this row of the matrix had this attendance at
+func (m AttendanceMatrix) Count(row int, status Attendance) int {
+	n := 0
+	for _, a := range m.Cells[row] {
+		if a == status {
+			n++
+		}
+	}
+	return n
+}
+
+func (l *League) divisionIndex(name string) int {
+	for i, d := range l.Divisions {
+		if d.Name == name {
+			return i
+		}
+	}
+	return len(l.Divisions)
+}
+
+func sortedKeys(m map[int]Event) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeagueAttendance(t *testing.T) {
+	l := testLeague()
+	l.Assign("Premier", 9)
+	l.AddFixture(Fixture{EventID: 3, Date: time.Date(2020, 12, 15, 0, 0, 0, 0, time.UTC)})
+
+	imported := []Event{
+		// Signups for all three fixtures and an event that isn't one
+		{Zid: "1", Zwid: 1, Name: "Ann", Category: "A", TeamID: "2672"},
+		{Zid: "1", Zwid: 2, Name: "Bob", Category: "C", TeamID: "2672"},
+		{Zid: "2", Zwid: 1, Name: "Ann", Category: "A", TeamID: "2672"},
+		{Zid: "2", Zwid: 3, Name: "Cat", Category: "B", TeamID: "2672"},
+		{Zid: "3", Zwid: 2, Name: "Bob", Category: "C", TeamID: "2672"},
+		{Zid: "3", Zwid: 5, Name: "Eve", Category: "B", TeamID: "1234"},
+		{Zid: "99", Zwid: 4, Name: "Dan", Category: "D", TeamID: "2672"},
+	}
+	signups := l.FixtureEvents(imported)
+	if len(signups) != 3 || len(signups[2]) != 2 || signups[99] != nil {
+		t.Fatalf("Unexpected fixture events %v", signups)
+	}
+
+	results := map[int][]Event{
+		1: {{Zid: "1", Zwid: 1, Name: "Ann", Category: "A", TeamID: "2672", Pos: 1}},
+		2: {{Zid: "2", Zwid: 1, Name: "Ann", Category: "A", TeamID: "2672", Pos: 2}, {Zid: "2", Zwid: 9, Name: "Ian", Category: "D", TeamID: "2672", Pos: 5}},
+	}
+
+	// Dan is on the roster with a category, Fay without one and no fixtures
+	roster := []Rider{{Zwid: 4, Name: "Dan", Category: "D"}, {Zwid: 6, Name: "Fay"}, {Zwid: 1, Name: "Ann"}}
+
+	attendance := l.Attendance(roster, signups, results)
+	if len(attendance) != 3 {
+		t.Fatalf("Expected 3 fixtures, got %d", len(attendance))
+	}
+
+	expected := []map[int]Attendance{
+		{1: Finished, 3: Missing, 9: Missing, 2: NoShow, 4: Missing},
+		{1: Finished, 3: NoShow, 9: Finished, 2: Missing, 4: Missing},
+		{1: Missing, 3: Missing, 9: Missing, 2: SignedUp, 4: Missing},
+	}
+	for i, fa := range attendance {
+		if len(fa.Riders) != len(expected[i]) {
+			t.Errorf("Fixture %d: expected %d riders, got %v", fa.Fixture.EventID, len(expected[i]), fa.Riders)
+		}
+		for _, r := range fa.Riders {
+			if r.Status != expected[i][r.Zwid] {
+				t.Errorf("Fixture %d: %s is %q, expected %q", fa.Fixture.EventID, r.Name, r.Status, expected[i][r.Zwid])
+			}
+			// Ian finished the second fixture without signing up
+			if r.Unregistered != (i == 1 && r.Zwid == 9) {
+				t.Errorf("Fixture %d: %s has unregistered %v", fa.Fixture.EventID, r.Name, r.Unregistered)
+			}
+		}
+	}
+
+	// Premier riders first, by name
+	var order []string
+	for _, r := range attendance[0].Riders {
+		order = append(order, r.Division+" "+r.Name)
+	}
+	if order[0] != "Premier Ann" || order[1] != "Premier Cat" || order[2] != "Premier Ian" || order[3] != "Championship Bob" || order[4] != "Championship Dan" {
+		t.Errorf("Unexpected order %v", order)
+	}
+}
+
+func TestAttendanceMatrix(t *testing.T) {
+	f1, f2 := Fixture{EventID: 1, Title: "Round 1"}, Fixture{EventID: 2, Title: "Round 2"}
+	attendance := []FixtureAttendance{
+		{Fixture: f1, Riders: []RiderAttendance{
+			{Zwid: 1, Name: "Ann", Division: "Premier", Status: Finished},
+			{Zwid: 2, Name: "Bob", Division: "Championship", Status: NoShow},
+		}},
+		{Fixture: f2, Riders: []RiderAttendance{
+			{Zwid: 1, Name: "Ann", Division: "Premier", Status: Finished, Unregistered: true},
+			{Zwid: 2, Name: "Bob", Division: "Championship", Status: Missing},
+		}},
+	}
+
+	m := NewAttendanceMatrix(attendance)
+	if len(m.Fixtures) != 2 || m.Fixtures[1] != f2 {
+		t.Errorf("Unexpected fixtures %v", m.Fixtures)
+	}
+	if len(m.Riders) != 2 || m.Riders[0] != (RiderAttendance{Zwid: 1, Name: "Ann", Division: "Premier"}) || m.Riders[1].Name != "Bob" {
+		t.Errorf("Unexpected riders %v", m.Riders)
+	}
+	if m.Cells[0][0] != Finished || m.Cells[0][1] != Finished || m.Cells[1][0] != NoShow || m.Cells[1][1] != Missing {
+		t.Errorf("Unexpected cells %v", m.Cells)
+	}
+	if m.Count(0, Finished) != 2 || m.Count(1, NoShow) != 1 || m.Count(1, Finished) != 0 {
+		t.Errorf("Unexpected counts")
+	}
+
+	if m := NewAttendanceMatrix(nil); len(m.Riders) != 0 || len(m.Fixtures) != 0 {
+		t.Errorf("Expected an empty matrix, got %v", m)
+	}
+}
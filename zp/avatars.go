@@ -0,0 +1,149 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ZwiftProfileURL is where we find a rider's Zwift profile, which has their picture.
+// Unlike ZwiftPower, the Zwift API needs an access token.
+var ZwiftProfileURL = "https://us-or-rly101.zwift.com/api/profiles/%d"
+
+// ErrZwiftAuth means Zwift didn't accept the access token. It wraps ErrUnauthorized.
+var ErrZwiftAuth = fmt.Errorf("Zwift access token rejected: %w", ErrUnauthorized)
+
+// zwiftProfile is the part of a Zwift profile we're interested in
+type zwiftProfile struct {
+	ImageSrc      string  `json:"imageSrc"`
+	ImageSrcLarge string  `json:"imageSrcLarge"`
+	Weight        float64 `json:"weight"` // grams
+	FTP           float64 `json:"ftp"`
+
+	CompetitionMetrics *zwiftCompetition `json:"competitionMetrics"`
+}
+
+// ImportAvatar gets the URL of the rider's profile picture from Zwift, using this
+// access token. It returns an empty string if they haven't got one.
+func ImportAvatar(client *http.Client, token string, riderID int) (string, error) {
+	return ImportAvatarContext(context.Background(), client, token, riderID)
+}
+
+// ImportAvatarContext gets the URL of the rider's profile picture from Zwift, using
+// this access token. It returns an empty string if they haven't got one.
+func ImportAvatarContext(ctx context.Context, client *http.Client, token string, riderID int) (avatar string, err error) {
+	ctx, span := startSpan(ctx, "ImportAvatar", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	var p zwiftProfile
+	err = getZwiftJSON(ctx, client, token, fmt.Sprintf(ZwiftProfileURL, riderID), "Zwift profile", &p)
+	if err != nil {
+		return "", err
+	}
+
+	if p.ImageSrcLarge != "" {
+		return p.ImageSrcLarge, nil
+	}
+	return p.ImageSrc, nil
+}
+
+// getZwiftJSON gets JSON from the Zwift API with the access token, and decodes it into v
+func getZwiftJSON(ctx context.Context, client *http.Client, token string, url string, what string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: status %d", ErrZwiftAuth, resp.StatusCode)
+	default:
+		return checkResponse(resp, url)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return &ParseError{What: what, Err: err}
+	}
+	return nil
+}
+
+// AddAvatars fills in the Avatar for any riders that don't have one. A rider whose
+// profile can't be found is skipped, but if the token is rejected we give up.
+func AddAvatars(ctx context.Context, client *http.Client, token string, riders []Rider) error {
+	for i := range riders {
+		if riders[i].Avatar != "" {
+			continue
+		}
+
+		avatar, err := ImportAvatarContext(ctx, client, token, riders[i].Zwid)
+		if err != nil {
+			if errors.Is(err, ErrZwiftAuth) {
+				return err
+			}
+			log.Printf("No avatar for %d: %v", riders[i].Zwid, err)
+			continue
+		}
+		riders[i].Avatar = avatar
+	}
+	return nil
+}
+
+// Initials are the first letters of the first and last words of the name, leaving out
+// any team tag in brackets
+func Initials(name string) string {
+	var words []string
+	for _, w := range strings.Fields(html.UnescapeString(name)) {
+		if strings.ContainsAny(w[:1], "[(") {
+			continue
+		}
+		words = append(words, w)
+	}
+
+	var initials []rune
+	for i, w := range words {
+		if i == 0 || i == len(words)-1 {
+			r := []rune(w)[0]
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+			}
+		}
+	}
+	return string(initials)
+}
+
+// InitialsAvatar is an SVG picture of the rider's initials, for riders with no profile
+// picture. The colour is picked from the name, so it stays the same from one page to
+// the next.
+func InitialsAvatar(name string) []byte {
+	hue := nameHue(name)
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="64" height="64" viewBox="0 0 64 64">`+
+		`<circle cx="32" cy="32" r="32" fill="hsl(%d,55%%,45%%)"/>`+
+		`<text x="32" y="32" dy="0.35em" text-anchor="middle" font-family="sans-serif" font-size="26" fill="#fff">%s</text>`+
+		`</svg>`, hue, html.EscapeString(Initials(name))))
+}
+
+// nameHue picks a colour for the name, as an angle on the colour wheel
+func nameHue(name string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return h.Sum32() % 360
+}
@@ -0,0 +1,74 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAddAvatars(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/profiles/1":
+			fmt.Fprint(w, `{"id":1,"imageSrc":"https://example.com/1-small","imageSrcLarge":"https://example.com/1-large"}`)
+		case "/api/profiles/2":
+			fmt.Fprint(w, `{"id":2,"imageSrc":"https://example.com/2-small"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	oldURL := ZwiftProfileURL
+	ZwiftProfileURL = ts.URL + "/api/profiles/%d"
+	defer func() { ZwiftProfileURL = oldURL }()
+
+	riders := []Rider{{Zwid: 1}, {Zwid: 2}, {Zwid: 3}, {Zwid: 4, Avatar: "https://example.com/mine"}}
+	err := AddAvatars(context.Background(), http.DefaultClient, "good", riders)
+	if err != nil {
+		t.Fatalf("Failed adding avatars: %v", err)
+	}
+
+	expected := []string{"https://example.com/1-large", "https://example.com/2-small", "", "https://example.com/mine"}
+	for i, r := range riders {
+		if r.Avatar != expected[i] {
+			t.Errorf("Got avatar %q for %d, expected %q", r.Avatar, r.Zwid, expected[i])
+		}
+	}
+
+	err = AddAvatars(context.Background(), http.DefaultClient, "bad", []Rider{{Zwid: 1}})
+	if !errors.Is(err, ErrZwiftAuth) {
+		t.Errorf("Expected auth error, got %v", err)
+	}
+}
+
+func TestInitialsAvatar(t *testing.T) {
+	cases := map[string]string{
+		"&Ouml;zge Yazar [REVO]":  "ÖY",
+		"Liz Rice":                "LR",
+		"Madonna":                 "M",
+		"[TEAM] Ann van der Berg": "AB",
+		"":                        "",
+	}
+	for name, expected := range cases {
+		if i := Initials(name); i != expected {
+			t.Errorf("Got initials %q for %q, expected %q", i, name, expected)
+		}
+	}
+
+	svg := string(InitialsAvatar("Liz Rice"))
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, ">LR</text>") {
+		t.Errorf("Unexpected SVG %s", svg)
+	}
+	if svg != string(InitialsAvatar("Liz Rice")) {
+		t.Errorf("Avatar should be the same each time")
+	}
+}
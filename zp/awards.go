@@ -0,0 +1,195 @@
+package zp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AwardPlaces is how many places SeasonAwards gives out for each award, not counting
+// anyone tied with the last of them
+const AwardPlaces = 3
+
+// Award is an end-of-season award, with the riders placed in it
+type Award struct {
+	Title  string
+	Places []AwardPlace
+}
+
+// AwardPlace is a rider's place in an award. Riders who can't be separated, even by
+// the award's tie-breaks, share a place.
+type AwardPlace struct {
+	Place    int
+	Zwid     int
+	Name     string
+	Score    float64
+	Citation string // What they did to earn it, to read out or print
+}
+
+// contender is a rider's claim to an award. Higher scores win, then higher tie-breaks,
+// in order.
+type contender struct {
+	zwid     int
+	score    float64
+	ties     []float64
+	citation string
+}
+
+// SeasonAwards hands out the club's end-of-season awards for races between the two
+// dates, from each rider's events, keyed by rider ID. Names come from the roster, or
+// from the events for riders who aren't on it. The awards and their tie-breaks are:
+//
+//	Most races: then most podiums, then whoever reached their total first
+//	Most podiums (top three in category): then most wins, then fewest races
+//	Most improved FTP: the 90-day FTP estimate in w/kg at the end of the season over
+//	  that at the start, for riders with both; then most races
+//	Best attendance: the share of the season's weeks with a race; then most races
+//
+// Riders still tied after that share the place. Awards nobody qualifies for are left out.
+// FTPs are combined as the aggregation says.
+func SeasonAwards(roster []Rider, events map[int][]Event, from, to time.Time, aggregation FtpAggregations) []Award {
+	names := make(map[int]string)
+	for _, r := range roster {
+		names[r.Zwid] = r.Name
+	}
+
+	weeks := int(math.Ceil(to.Sub(from).Hours() / (24 * 7)))
+	if weeks < 1 {
+		weeks = 1
+	}
+
+	var races, podiums, improved, attendance []contender
+	for id, ee := range events {
+		var season []Event
+		for _, e := range ee {
+			if e.EventDateSecs != 0 && e.IsRace() && !e.EventDate.Before(from) && !e.EventDate.After(to) {
+				season = append(season, e)
+			}
+			if names[id] == "" && e.Name != "" {
+				names[id] = e.RiderName()
+			}
+		}
+		if len(season) == 0 {
+			continue
+		}
+		sort.Slice(season, func(i, j int) bool {
+			return season[i].EventDate.Before(season[j].EventDate)
+		})
+
+		wins, podium := 0, 0
+		raced := make(map[int]bool)
+		for _, e := range season {
+			if e.PositionInCat >= 1 && e.PositionInCat <= 3 {
+				podium++
+			}
+			if e.PositionInCat == 1 {
+				wins++
+			}
+			raced[int(e.EventDate.Sub(from).Hours()/(24*7))] = true
+		}
+		n := float64(len(season))
+		last := season[len(season)-1].EventDate
+
+		races = append(races, contender{id, n, []float64{float64(podium), -float64(last.Unix())},
+			fmt.Sprintf("%s between %s and %s", plural(len(season), "race"), from.Format("2 January"), to.Format("2 January 2006"))})
+
+		if podium > 0 {
+			podiums = append(podiums, contender{id, float64(podium), []float64{float64(wins), -n},
+				fmt.Sprintf("%s and %s from %s", plural(podium, "podium"), plural(wins, "win"), plural(len(season), "race"))})
+		}
+
+		start := RiderFromEventsAggregated(id, ee, from, aggregation).Ftp90
+		end := RiderFromEventsAggregated(id, ee, to, aggregation).Ftp90
+		if start > 0 && end > start {
+			// To the nearest 0.01 w/kg, so that differences too small to show count as ties
+			gain := math.Round((end-start)*100) / 100
+			improved = append(improved, contender{id, gain, []float64{n},
+				fmt.Sprintf("FTP up from %.1f to %.1f w/kg", start, end)})
+		}
+
+		attendance = append(attendance, contender{id, float64(len(raced)) / float64(weeks), []float64{n},
+			fmt.Sprintf("raced in %d of the season's %d weeks", len(raced), weeks)})
+	}
+
+	var awards []Award
+	for _, a := range []struct {
+		title      string
+		contenders []contender
+	}{
+		{"Most races", races},
+		{"Most podiums", podiums},
+		{"Most improved FTP", improved},
+		{"Best attendance", attendance},
+	} {
+		if award := rankAward(a.title, a.contenders, names); len(award.Places) > 0 {
+			awards = append(awards, award)
+		}
+	}
+	return awards
+}
+
+// rankAward places the contenders, keeping the top AwardPlaces and anyone tied with them
+func rankAward(title string, contenders []contender, names map[int]string) Award {
+	better := func(a, b contender) int {
+		if a.score != b.score {
+			return sign(a.score - b.score)
+		}
+		for i := range a.ties {
+			if a.ties[i] != b.ties[i] {
+				return sign(a.ties[i] - b.ties[i])
+			}
+		}
+		return 0
+	}
+	sort.Slice(contenders, func(i, j int) bool {
+		if c := better(contenders[i], contenders[j]); c != 0 {
+			return c > 0
+		}
+		return strings.ToLower(names[contenders[i].zwid]) < strings.ToLower(names[contenders[j].zwid])
+	})
+
+	award := Award{Title: title}
+	for i, c := range contenders {
+		place := i + 1
+		if i > 0 && better(c, contenders[i-1]) == 0 {
+			place = award.Places[i-1].Place
+		}
+		if place > AwardPlaces {
+			break
+		}
+		award.Places = append(award.Places, AwardPlace{
+			Place:    place,
+			Zwid:     c.zwid,
+			Name:     names[c.zwid],
+			Score:    c.score,
+			Citation: c.citation,
+		})
+	}
+	return award
+}
+
+// plural is the count and the noun, with an s if there's more or less than one
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func sign(f float64) int {
+	switch {
+	case f > 0:
+		return 1
+	case f < 0:
+		return -1
+	}
+	return 0
+}
+
+// String is the place as a line of text for a citation list, e.g. "1st: Jane Smith, 42
+// races between 1 January and 31 March 2021"
+func (p AwardPlace) String() string {
+	return fmt.Sprintf("%s: %s, %s", ordinal(p.Place), strings.TrimSpace(p.Name), p.Citation)
+}
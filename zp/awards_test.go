@@ -0,0 +1,57 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeasonAwards(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 1, 28, 23, 59, 59, 0, time.UTC)
+	race := func(zwid int, day int, pos int, ftp float64) Event {
+		date := from.AddDate(0, 0, day)
+		return Event{Zwid: zwid, Name: "Rider", EventType: "TYPE_RACE", Category: "B", PositionInCat: pos,
+			EventDateSecs: EventDateType(date.Unix()), EventDate: date, WkgFtp: []interface{}{ftp, 0.0}}
+	}
+
+	events := map[int][]Event{
+		// Ann: three races in three different weeks, one of them a win, and much fitter
+		1: {race(1, -20, 5, 3.0), race(1, 1, 1, 3.2), race(1, 8, 4, 3.4), race(1, 15, 5, 3.6)},
+		// Bob: three races in three different weeks, with two podiums
+		2: {race(2, 2, 2, 3.0), race(2, 9, 3, 3.0), race(2, 20, 6, 3.0)},
+		// Cat: three races in two weeks, two podiums like Bob, and the same last race day
+		3: {race(3, -30, 8, 2.5), race(3, 1, 2, 2.6), race(3, 2, 3, 2.6), race(3, 20, 9, 2.6)},
+		// Dan: one race after the season, which doesn't count
+		4: {race(4, 40, 1, 4.0)},
+	}
+	roster := []Rider{{Zwid: 1, Name: "Ann"}, {Zwid: 2, Name: "Bob"}, {Zwid: 3, Name: "Cat"}, {Zwid: 4, Name: "Dan"}}
+
+	awards := SeasonAwards(roster, events, from, to, FtpAggregations{})
+	if len(awards) != 4 {
+		t.Fatalf("Got %d awards: %v", len(awards), awards)
+	}
+
+	places := func(a Award) string {
+		s := ""
+		for _, p := range a.Places {
+			s += ordinal(p.Place) + " " + p.Name + " "
+		}
+		return s
+	}
+	expected := map[string]string{
+		// Bob and Cat have the same races, podiums and last race day, so they share first
+		"Most races":        "1st Bob 1st Cat 3rd Ann ",
+		"Most podiums":      "1st Bob 1st Cat 3rd Ann ",
+		"Most improved FTP": "1st Ann 2nd Cat ",
+		"Best attendance":   "1st Ann 1st Bob 3rd Cat ",
+	}
+	for _, a := range awards {
+		if got := places(a); got != expected[a.Title] {
+			t.Errorf("%s: got %s, expected %s", a.Title, got, expected[a.Title])
+		}
+	}
+
+	if s := awards[0].Places[2].String(); s != "3rd: Ann, 3 races between 1 January and 28 January 2021" {
+		t.Errorf("Got citation %q", s)
+	}
+}
@@ -0,0 +1,184 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BaseURL is where we find ZwiftPower
+var BaseURL = zwiftPowerURL
+
+// Backend selects which set of ZwiftPower endpoints we import data from
+type Backend string
+
+const (
+	// Cache3 reads the JSON files that ZwiftPower caches under /cache3. These don't need
+	// a login, but they are sometimes stale or missing.
+	Cache3 Backend = "cache3"
+
+	// API3 uses the api3.php endpoints, which are fresher but need an authenticated
+	// session (see SetCookies)
+	API3 Backend = "api3"
+)
+
+// DefaultBackend is the backend used by the Import functions and new Clients
+var DefaultBackend = Cache3
+
+// ParseBackend checks that s names a backend we know about
+func ParseBackend(s string) (Backend, error) {
+	switch b := Backend(strings.ToLower(s)); b {
+	case Cache3, API3:
+		return b, nil
+	case "":
+		return Cache3, nil
+	default:
+		return "", fmt.Errorf("unknown backend %q, expected %s or %s", s, Cache3, API3)
+	}
+}
+
+func (b Backend) clubURL(clubID int) string {
+	if b == API3 {
+		return fmt.Sprintf("%s/api3.php?do=team_riders&id=%d", BaseURL, clubID)
+	}
+	return fmt.Sprintf("%s/cache3/teams/%d_riders.json", BaseURL, clubID)
+}
+
+func (b Backend) profileURL(riderID int) string {
+	return b.profilePartURL(riderID, profileAll)
+}
+
+// profilePartURL finds one of the files that make up a rider's profile. In cache3 they
+// sit side by side, while api3 has the results under one call and the rest under others.
+func (b Backend) profilePartURL(riderID int, part string) string {
+	if b == API3 {
+		switch part {
+		case profileAll:
+			return fmt.Sprintf("%s/api3.php?do=profile_results&z=%d&type=all", BaseURL, riderID)
+		case profileRaces:
+			return fmt.Sprintf("%s/api3.php?do=profile_results&z=%d&type=race", BaseURL, riderID)
+		default:
+			return fmt.Sprintf("%s/api3.php?do=profile_%s&z=%d", BaseURL, part, riderID)
+		}
+	}
+	return fmt.Sprintf("%s/cache3/profile/%d_%s.json", BaseURL, riderID, part)
+}
+
+func (b Backend) eventURL(eventID int) string {
+	if b == API3 {
+		return fmt.Sprintf("%s/api3.php?do=event_results&zid=%d", BaseURL, eventID)
+	}
+	return fmt.Sprintf("%s/cache3/results/%d_view.json", BaseURL, eventID)
+}
+
+func (b Backend) signupsURL(eventID int) string {
+	if b == API3 {
+		return fmt.Sprintf("%s/api3.php?do=event_sign_ups&zid=%d", BaseURL, eventID)
+	}
+	return fmt.Sprintf("%s/cache3/results/%d_signups.json", BaseURL, eventID)
+}
+
+// The parts of a league hosted on ZwiftPower
+const (
+	leagueStandings = "standings"
+	leagueRounds    = "events"
+)
+
+func (b Backend) leagueURL(leagueID int, part string) string {
+	if b == API3 {
+		return fmt.Sprintf("%s/api3.php?do=league_%s&id=%d", BaseURL, part, leagueID)
+	}
+	return fmt.Sprintf("%s/cache3/lg/%d_%s.json", BaseURL, leagueID, part)
+}
+
+func (b Backend) leagueResultsURL(leagueID int, zid string) string {
+	if b == API3 {
+		return fmt.Sprintf("%s/api3.php?do=league_event_results&id=%d&zid=%s", BaseURL, leagueID, url.QueryEscape(zid))
+	}
+	return fmt.Sprintf("%s/cache3/lg/%d_%s_results.json", BaseURL, leagueID, url.PathEscape(zid))
+}
+
+// getJSON for a backend adds a check that we really got JSON, because when the session
+// isn't logged in api3.php sends back a login page rather than an error status
+func (b Backend) getJSON(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	data, _, err := b.fetchJSON(ctx, client, url)
+	return data, err
+}
+
+// fetchJSON is getJSON that also says when the data was last modified, if the
+// response had a Last-Modified header
+func (b Backend) fetchJSON(ctx context.Context, client *http.Client, url string) ([]byte, time.Time, error) {
+	data, modified, err := fetchJSON(ctx, client, url)
+	if err != nil {
+		return data, modified, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if b == API3 && (len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[')) {
+		if isChallenge(http.Header{}, data) {
+			return nil, modified, fmt.Errorf("%w: no JSON from %s", ErrChallenged, url)
+		}
+		return nil, modified, fmt.Errorf("%w: no JSON from %s, is the session logged in?", ErrUnauthorized, url)
+	}
+
+	return data, modified, nil
+}
+
+// openJSON for a backend makes the same check as getJSON, peeking at the start of the
+// body before handing it over to be streamed
+func (b Backend) openJSON(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	body, err := openJSON(ctx, client, url)
+	if err != nil || b != API3 {
+		return body, err
+	}
+
+	r := body.(*jsonBody).Reader
+	for {
+		c, err := r.ReadByte()
+		if err != nil || (c != ' ' && c != '\t' && c != '\r' && c != '\n') {
+			if err == nil && (c == '{' || c == '[') {
+				r.UnreadByte()
+				return body, nil
+			}
+			if err == nil {
+				r.UnreadByte()
+			}
+			start, _ := r.Peek(4096)
+			maintenance := isMaintenancePage(start)
+			body.Close()
+			if maintenance {
+				return nil, fmt.Errorf("%w: got a maintenance page from %s", ErrMaintenance, url)
+			}
+			return nil, fmt.Errorf("%w: no JSON from %s, is the session logged in?", ErrUnauthorized, url)
+		}
+	}
+}
+
+// SetCookies adds session cookies for ZwiftPower to the client's cookie jar, so that it
+// can use the authenticated API3 backend. The cookies are given in the same form as
+// a Cookie header, e.g. copied from a logged-in browser: "name1=value1; name2=value2".
+func SetCookies(client *http.Client, cookies string) error {
+	if client.Jar == nil {
+		return fmt.Errorf("client has no cookie jar")
+	}
+
+	u, err := url.Parse(BaseURL)
+	if err != nil {
+		return fmt.Errorf("parsing base URL: %v", err)
+	}
+
+	// Borrow the http package's cookie parsing
+	req := http.Request{Header: http.Header{"Cookie": []string{cookies}}}
+	cc := req.Cookies()
+	if len(cc) == 0 {
+		return fmt.Errorf("no cookies found in %q", cookies)
+	}
+
+	client.Jar.SetCookies(u, cc)
+	return nil
+}
@@ -0,0 +1,106 @@
+package zp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// withTestServer points BaseURL at a local server for the duration of a test
+func withTestServer(t testing.TB, handler http.HandlerFunc) *httptest.Server {
+	ts := httptest.NewServer(handler)
+	oldBaseURL := BaseURL
+	BaseURL = ts.URL
+	t.Cleanup(func() {
+		BaseURL = oldBaseURL
+		ts.Close()
+	})
+	return ts
+}
+
+func TestParseBackend(t *testing.T) {
+	cases := map[string]Backend{"": Cache3, "cache3": Cache3, "API3": API3}
+	for s, expected := range cases {
+		b, err := ParseBackend(s)
+		if err != nil || b != expected {
+			t.Errorf("Got %v, %v for %q, expected %s", b, err, s, expected)
+		}
+	}
+
+	if _, err := ParseBackend("api4"); err == nil {
+		t.Errorf("Expected error for unknown backend")
+	}
+}
+
+func TestAPI3Backend(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events.php" {
+			// Falling back to the missing event's results page
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path != "/api3.php" {
+			t.Errorf("Unexpected request for %s", r.URL)
+		}
+
+		if c, err := r.Cookie("phpbb3_sid"); err != nil || c.Value != "abc" {
+			fmt.Fprint(w, "<html>Please log in</html>")
+			return
+		}
+
+		switch r.URL.Query().Get("do") {
+		case "team_riders":
+			fmt.Fprint(w, `{"data":[{"name":"Liz Rice","zwid":98588}]}`)
+		case "profile_results":
+			fmt.Fprint(w, testdata)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	DefaultBackend = API3
+	defer func() { DefaultBackend = Cache3 }()
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+
+	_, err = ImportZP(client, 2672)
+	if err == nil {
+		t.Errorf("Expected error without a logged-in session")
+	}
+
+	err = SetCookies(client, "phpbb3_sid=abc; phpbb3_u=98588")
+	if err != nil {
+		t.Fatalf("Failed setting cookies: %v", err)
+	}
+
+	u, _ := url.Parse(BaseURL)
+	if len(client.Jar.Cookies(u)) != 2 {
+		t.Errorf("Expected 2 cookies in the jar, got %v", client.Jar.Cookies(u))
+	}
+
+	riders, err := ImportZP(client, 2672)
+	if err != nil {
+		t.Fatalf("Failed importing club: %v", err)
+	}
+	if len(riders) != 1 || riders[0].Zwid != 98588 {
+		t.Errorf("Unexpected riders %v", riders)
+	}
+
+	events, err := ImportEvents(client, 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing events: %v", err)
+	}
+	if len(events) != 14 {
+		t.Errorf("Got %d events, expected 14", len(events))
+	}
+
+	_, err = ImportEventResults(client, 1234)
+	if err == nil {
+		t.Errorf("Expected error for missing event")
+	}
+}
@@ -0,0 +1,104 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const backfillRetries = 3
+
+// Backfill loads the full event history for every rider in the club into the store.
+//
+// Deprecated: use Client.Backfill
+func Backfill(client *http.Client, store Store, clubID int, pause time.Duration) error {
+	return Wrap(client).Backfill(context.Background(), store, clubID, pause)
+}
+
+// Backfill loads the full event history for every rider in the club into the store.
+// It pauses between riders to go easy on ZwiftPower, and records a checkpoint after
+// each one so that an interrupted run picks up where it left off. Riders that still
+// fail after a few retries are skipped, and listed in the error returned at the end.
+// Progress is reported to the client's Progress hook after each rider.
+func (c *Client) Backfill(ctx context.Context, store Store, clubID int, pause time.Duration) error {
+	riders, err := c.Club(ctx, clubID)
+	if err != nil {
+		return fmt.Errorf("getting club data: %w", err)
+	}
+
+	checkpoint := fmt.Sprintf("backfill_%d", clubID)
+	done, err := store.Checkpoint(checkpoint)
+	if err != nil {
+		return fmt.Errorf("reading checkpoint: %v", err)
+	}
+	if len(done) > 0 {
+		log.Printf("Resuming backfill for club %d, %d of %d riders already done", clubID, len(done), len(riders))
+	}
+
+	var failed []int
+	for i, r := range riders {
+		if done[r.Zwid] {
+			c.Progress.report(i+1, len(riders), r.Name)
+			continue
+		}
+
+		var events []Event
+		for attempt := 0; attempt < backfillRetries; attempt++ {
+			if attempt > 0 {
+				wait := pause * time.Duration(1<<attempt)
+				var status *StatusError
+				if errors.As(err, &status) && status.RetryAfter > wait {
+					wait = status.RetryAfter
+				}
+				log.Printf("Retrying %s (%d) in %v: %v", r.Name, r.Zwid, wait, err)
+				time.Sleep(wait)
+			}
+
+			events, err = c.Events(ctx, r.Zwid)
+			if err == nil || !retryable(err) {
+				break
+			}
+		}
+
+		if err == nil {
+			err = backfillRider(store, r, events, c.FtpAggregation)
+		}
+
+		if err != nil {
+			log.Printf("Backfill %d/%d: giving up on %s (%d): %v", i+1, len(riders), r.Name, r.Zwid, err)
+			failed = append(failed, r.Zwid)
+		} else {
+			log.Printf("Backfill %d/%d: %s (%d), %d events", i+1, len(riders), r.Name, r.Zwid, len(events))
+			done[r.Zwid] = true
+			err = store.SaveCheckpoint(checkpoint, done)
+			if err != nil {
+				return fmt.Errorf("saving checkpoint: %v", err)
+			}
+		}
+
+		c.Progress.report(i+1, len(riders), r.Name)
+		time.Sleep(pause)
+	}
+
+	if len(failed) > 0 {
+		return partial(len(failed), len(riders), fmt.Errorf("backfill failed for %d riders: %v", len(failed), failed))
+	}
+
+	log.Printf("Backfill complete for %d riders", len(riders))
+	return store.ClearCheckpoint(checkpoint)
+}
+
+// retryable is false for errors that won't go away if we try again
+func retryable(err error) bool {
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrUnauthorized) && !errors.Is(err, ErrParse)
+}
+
+func backfillRider(store Store, r Rider, events []Event, aggregation FtpAggregations) error {
+	return store.Update(r.Zwid, func(tx *RiderTx) error {
+		tx.PutEvents(events)
+		return tx.PutRider(withRosterData(RiderFromEventsAggregated(r.Zwid, events, SystemClock.Now(), aggregation), r))
+	})
+}
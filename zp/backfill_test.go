@@ -0,0 +1,76 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBackfill(t *testing.T) {
+	broken := true
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/teams/2672_riders.json":
+			fmt.Fprint(w, `{"data":[{"name":"Liz Rice","zwid":98588},{"name":"Özge Yazar","zwid":1261784}]}`)
+		case "/cache3/profile/98588_all.json":
+			fmt.Fprint(w, `{"data":[]}`)
+		case "/cache3/profile/1261784_all.json":
+			if broken {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprint(w, testdata)
+		}
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	s := testStore(t)
+
+	err = Backfill(client, s, 2672, 0)
+	if err == nil {
+		t.Fatalf("Expected backfill to report a failed rider")
+	}
+	var partial *PartialError
+	if !errors.As(err, &partial) || partial.Failed != 1 || partial.Total != 2 {
+		t.Errorf("Expected a partial failure, got %#v", err)
+	}
+
+	done, _ := s.Checkpoint("backfill_2672")
+	if len(done) != 1 || !done[98588] {
+		t.Errorf("Unexpected checkpoint %v", done)
+	}
+
+	broken = false
+	c := Wrap(client)
+	var progress []string
+	c.Progress = func(done, total int, current string) {
+		progress = append(progress, fmt.Sprintf("%d/%d %s", done, total, current))
+	}
+	err = c.Backfill(context.Background(), s, 2672, 0)
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	if len(progress) != 2 || progress[0] != "1/2 Liz Rice" || progress[1] != "2/2 Özge Yazar" {
+		t.Errorf("Got progress %v", progress)
+	}
+
+	r, err := s.Rider(1261784)
+	if err != nil || r.Name != "Özge Yazar" || r.LatestRace == "" {
+		t.Errorf("Unexpected rider %v, %v", r, err)
+	}
+
+	events, err := s.Events(1261784)
+	if err != nil || len(events) != 14 {
+		t.Errorf("Got %d events, %v", len(events), err)
+	}
+
+	done, _ = s.Checkpoint("backfill_2672")
+	if len(done) != 0 {
+		t.Errorf("Expected checkpoint to be cleared, got %v", done)
+	}
+}
@@ -0,0 +1,150 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// largeEvents makes n events from the ones in testdata, each with its own event ID and
+// a day apart going back from the last race, by rider. It returns them as a ZwiftPower
+// {"data": [...]} document.
+func largeEvents(tb testing.TB, n int, riders int, dualCategory bool) []byte {
+	var doc struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	err := json.Unmarshal([]byte(testdata), &doc)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	last := int64(1612320300)
+	rows := make([]map[string]interface{}, 0, n)
+	for i := 0; len(rows) < n; i++ {
+		row := make(map[string]interface{}, len(doc.Data[0]))
+		for k, v := range doc.Data[i%len(doc.Data)] {
+			row[k] = v
+		}
+		row["zid"] = strconv.Itoa(2000000 + i/riders)
+		row["zwid"] = 1000 + i%riders
+		row["event_date"] = last - int64(i/riders)*24*3600
+		rows = append(rows, row)
+
+		if dualCategory && i%2 == 0 && len(rows) < n {
+			overall := make(map[string]interface{}, len(row))
+			for k, v := range row {
+				overall[k] = v
+			}
+			overall["category"] = "E"
+			rows = append(rows, overall)
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"data": rows})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func parsedEvents(tb testing.TB, n int, riders int, dualCategory bool) []Event {
+	events, err := parseEvents(largeEvents(tb, n, riders, dualCategory))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return events
+}
+
+// A profile at the cache3 limit
+func BenchmarkParseEvents(b *testing.B) {
+	data := largeEvents(b, cacheEventLimit, 1, false)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseEvents(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeEvents(b *testing.B) {
+	data := largeEvents(b, cacheEventLimit, 1, false)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := decodeEvents(bytes.NewReader(data), func(e *Event) error { return nil })
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Importing a rider end to end, from the HTTP response to their stats
+func BenchmarkImportRider(b *testing.B) {
+	data := largeEvents(b, cacheEventLimit-1, 1, false)
+	withTestServer(b, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+	c, err := New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	c.Clock = FixedClock(time.Unix(1612320300, 0))
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Rider(context.Background(), 1000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRiderFromEvents(b *testing.B) {
+	events := parsedEvents(b, cacheEventLimit, 1, false)
+	asOf := time.Unix(1612320300, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RiderFromEventsAsOf(1000, events, asOf)
+	}
+}
+
+// A big event's results, with half the riders in a dual category
+func BenchmarkCanonicalResults(b *testing.B) {
+	results := parsedEvents(b, 3000, 2000, true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CanonicalResults(results)
+	}
+}
+
+// A year of events for a club of 200
+func BenchmarkNewDigest(b *testing.B) {
+	events := parsedEvents(b, 200*365, 200, false)
+	until := time.Unix(1612320300, 0)
+	since := until.AddDate(0, 0, -7)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewDigest(2672, events, since, until, true)
+	}
+}
+
+func BenchmarkFtpPercentiles(b *testing.B) {
+	events := parsedEvents(b, 200*365, 200, false)
+	now := time.Unix(1612320300, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FtpPercentiles(events, now)
+	}
+}
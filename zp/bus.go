@@ -0,0 +1,316 @@
+package zp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BusEvent is something that has happened to the club's data, published on a message bus
+// so that other services (a bot, the club site, analytics) can subscribe rather than poll
+// the store
+type BusEvent struct {
+	Type   string    `json:"type"` // One of the Bus... types
+	Time   time.Time `json:"time"`
+	ClubID int       `json:"club_id,omitempty"`
+	Rider  Rider     `json:"rider"`
+}
+
+// Types of BusEvent
+const (
+	BusRiderImported = "rider.imported" // The rider's stats have been imported
+	BusRiderResult   = "rider.result"   // The rider has a new event result since the last import
+	BusRiderJoined   = "roster.joined"  // The rider has joined the club
+	BusRiderLeft     = "roster.left"    // The rider has left the club
+)
+
+// BusMessage is what goes on the bus: the data is sent to the subject (a Kafka topic, or a
+// NATS subject), with the key deciding the Kafka partition so that each rider's messages
+// stay in order
+type BusMessage struct {
+	Subject string
+	Key     string
+	Data    []byte
+}
+
+// Message makes the event into a message, as JSON, on a subject made of the prefix and its
+// type, e.g. "zwiftpower.roster.joined". It's keyed by the rider's Zwift ID.
+func (e BusEvent) Message(prefix string) (BusMessage, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return BusMessage{}, err
+	}
+	subject := e.Type
+	if prefix != "" {
+		subject = prefix + "." + e.Type
+	}
+	return BusMessage{Subject: subject, Key: strconv.Itoa(e.Rider.Zwid), Data: data}, nil
+}
+
+// Publisher puts messages onto a message bus. NATSPublisher and KafkaPublisher are
+// provided; NewPublisher picks one from a URL.
+type Publisher interface {
+	Publish(ctx context.Context, m BusMessage) error
+}
+
+// PublisherFunc lets an ordinary function be a Publisher
+type PublisherFunc func(ctx context.Context, m BusMessage) error
+
+// Publish calls the function
+func (f PublisherFunc) Publish(ctx context.Context, m BusMessage) error {
+	return f(ctx, m)
+}
+
+// NewPublisher makes a publisher for the bus at this URL: nats:// (or tls:// for NATS over
+// TLS) for a NATS server, with any user and password or token in the URL, or http:// or
+// https:// for a Kafka REST Proxy
+func NewPublisher(busURL string) (Publisher, error) {
+	u, err := url.Parse(busURL)
+	if err != nil {
+		return nil, fmt.Errorf("bus URL: %v", err)
+	}
+	switch u.Scheme {
+	case "nats", "tls":
+		return &NATSPublisher{URL: busURL}, nil
+	case "http", "https":
+		return &KafkaPublisher{URL: busURL}, nil
+	}
+	return nil, fmt.Errorf("bus URL %q: expected nats://, tls://, http:// or https://", u.Redacted())
+}
+
+// NATSPublisher publishes to a NATS server, keeping a connection open between messages.
+// Each Publish waits for the server to acknowledge it, so errors aren't lost.
+type NATSPublisher struct {
+	URL     string        // nats://[user:password@]host:port, or tls:// to use TLS
+	Timeout time.Duration // For connecting and each publish, defaulting to 10s
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// natsInfo is the part of the NATS server's INFO message we need
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// Publish sends the message, connecting first if need be. The connection is dropped
+// after an error, and made again next time.
+func (n *NATSPublisher) Publish(ctx context.Context, m BusMessage) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if strings.ContainsAny(m.Subject, " \t\r\n") || m.Subject == "" {
+		return fmt.Errorf("invalid NATS subject %q", m.Subject)
+	}
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+	n.setDeadline(ctx)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "PUB %s %d\r\n", m.Subject, len(m.Data))
+	b.Write(m.Data)
+	b.WriteString("\r\nPING\r\n")
+	_, err := n.conn.Write(b.Bytes())
+	if err == nil {
+		err = n.awaitPong()
+	}
+	if err != nil {
+		n.close()
+		return fmt.Errorf("publishing %s to NATS: %w", m.Subject, err)
+	}
+	return nil
+}
+
+// Close closes the connection, if there is one
+func (n *NATSPublisher) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.close()
+}
+
+func (n *NATSPublisher) close() error {
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn, n.r = nil, nil
+	return err
+}
+
+func (n *NATSPublisher) setDeadline(ctx context.Context) {
+	timeout := n.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	n.conn.SetDeadline(deadline)
+}
+
+// connect dials the server, reads its INFO, upgrades to TLS if either side wants it, and
+// logs in
+func (n *NATSPublisher) connect(ctx context.Context) error {
+	u, err := url.Parse(n.URL)
+	if err != nil {
+		return fmt.Errorf("NATS URL: %v", err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS: %w", err)
+	}
+	n.conn, n.r = conn, bufio.NewReader(conn)
+	n.setDeadline(ctx)
+
+	line, err := n.r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		n.close()
+		return fmt.Errorf("connecting to NATS on %s: expected INFO, got %q (%v)", u.Host, line, err)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		n.close()
+		return fmt.Errorf("connecting to NATS on %s: reading INFO: %v", u.Host, err)
+	}
+
+	if u.Scheme == "tls" || info.TLSRequired {
+		t := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := t.Handshake(); err != nil {
+			n.close()
+			return fmt.Errorf("connecting to NATS on %s: %w", u.Host, err)
+		}
+		n.conn, n.r = t, bufio.NewReader(t)
+	}
+
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "name": "zwiftpower", "lang": "go"}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"], opts["pass"] = u.User.Username(), pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		n.close()
+		return err
+	}
+	_, err = fmt.Fprintf(n.conn, "CONNECT %s\r\nPING\r\n", connect)
+	if err == nil {
+		err = n.awaitPong()
+	}
+	if err != nil {
+		n.close()
+		return fmt.Errorf("connecting to NATS on %s: %w", u.Host, err)
+	}
+	return nil
+}
+
+// awaitPong reads from the server until it answers our PING, which means it has dealt with
+// everything we sent before it
+func (n *NATSPublisher) awaitPong() error {
+	for {
+		line, err := n.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+		// +OK and INFO updates need nothing from us
+	}
+}
+
+// KafkaPublisher publishes to Kafka through a Confluent-compatible REST Proxy (API v2),
+// so that no Kafka client library is needed. The subject is the topic.
+type KafkaPublisher struct {
+	URL    string       // The REST Proxy, e.g. http://kafka-rest:8082
+	Client *http.Client // Defaults to http.DefaultClient
+}
+
+// Publish produces the message as a JSON record on the topic
+func (k *KafkaPublisher) Publish(ctx context.Context, m BusMessage) error {
+	client := k.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	type record struct {
+		Key   string          `json:"key,omitempty"`
+		Value json.RawMessage `json:"value"`
+	}
+	body, err := json.Marshal(struct {
+		Records []record `json:"records"`
+	}{[]record{{Key: m.Key, Value: m.Data}}})
+	if err != nil {
+		return err
+	}
+
+	topic := strings.TrimRight(k.URL, "/") + "/topics/" + url.PathEscape(m.Subject)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, topic, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("publishing %s to Kafka: %w", m.Subject, err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "Kafka topic "+m.Subject); err != nil {
+		return err
+	}
+
+	// Each record can fail on its own, even though the request as a whole succeeded
+	var produced struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("publishing %s to Kafka: %w", m.Subject, err)
+	}
+	if err := json.Unmarshal(data, &produced); err != nil {
+		return fmt.Errorf("publishing %s to Kafka: %w: %v", m.Subject, ErrParse, err)
+	}
+	for _, o := range produced.Offsets {
+		if o.ErrorCode != nil || o.Error != "" {
+			return fmt.Errorf("publishing %s to Kafka: %s", m.Subject, o.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package zp
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Card summarises a rider or an event for link previews, so that a dashboard link shared
+// in Discord or similar shows something useful. It can be rendered as Open Graph tags,
+// JSON-LD or an image.
+type Card struct {
+	Type        string     `json:"type"` // CardRider or CardEvent
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Date        string     `json:"date,omitempty"` // 2006-01-02, for events
+	URL         string     `json:"url,omitempty"`
+	Image       string     `json:"image,omitempty"`
+	Stats       []CardStat `json:"stats"`
+}
+
+// CardStat is one of the headline numbers on a card
+type CardStat struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Types of card
+const (
+	CardRider = "rider"
+	CardEvent = "event"
+)
+
+// RiderCard summarises the rider's stats
+func RiderCard(r Rider) Card {
+	name := Event{Name: r.Name}.RiderName()
+	c := Card{Type: CardRider, Title: name}
+
+	var parts []string
+	if r.Category != "" {
+		c.Stats = append(c.Stats, CardStat{"Category", r.Category})
+		parts = append(parts, "Category "+r.Category)
+	}
+	if r.CECategory != "" {
+		c.Stats = append(c.Stats, CardStat{"CE category", r.CECategory})
+		parts = append(parts, "CE "+r.CECategory)
+	}
+	if r.Ftp90 > 0 {
+		wkg := strconv.FormatFloat(r.Ftp90, 'f', 1, 64)
+		c.Stats = append(c.Stats, CardStat{"FTP w/kg", wkg})
+		parts = append(parts, fmt.Sprintf("FTP %s w/kg over 90 days", wkg))
+	}
+	c.Stats = append(c.Stats, CardStat{"Races 30 days", strconv.Itoa(r.Races30)})
+	parts = append(parts, fmt.Sprintf("%d races in the last 30 days", r.Races30))
+	if r.LatestEvent != "" {
+		parts = append(parts, "latest: "+r.LatestEvent)
+	}
+
+	c.Description = strings.Join(parts, " · ")
+	return c
+}
+
+// EventCard summarises the results of an event, picking out the club's best finisher
+func EventCard(eventID int, results []Event, clubID string) Card {
+	c := Card{Type: CardEvent, Title: fmt.Sprintf("Event %d", eventID)}
+	if len(results) > 0 {
+		if results[0].EventTitle != "" {
+			c.Title = Event{Name: results[0].EventTitle}.RiderName()
+		}
+		if results[0].EventDateSecs != 0 {
+			c.Date = results[0].EventDate.Format("2006-01-02")
+		}
+	}
+
+	var club int
+	var best *Event
+	for i, e := range results {
+		if e.TeamID != clubID {
+			continue
+		}
+		club++
+		if e.PositionInCat > 0 && (best == nil || e.PositionInCat < best.PositionInCat) {
+			best = &results[i]
+		}
+	}
+
+	c.Stats = []CardStat{
+		{"Riders", strconv.Itoa(len(results))},
+		{"Club riders", strconv.Itoa(club)},
+	}
+	c.Description = fmt.Sprintf("%d riders, %d from the club", len(results), club)
+	if best != nil {
+		c.Stats = append(c.Stats, CardStat{"Best club", fmt.Sprintf("%s %s", ordinal(best.PositionInCat), strings.ToUpper(best.Category))})
+		c.Description += fmt.Sprintf(". Best club result: %s, %s in %s", best.RiderName(), ordinal(best.PositionInCat), strings.ToUpper(best.Category))
+	}
+	return c
+}
+
+// JSONLD describes the card with schema.org terms, for search engines and embeds that
+// read structured data
+func (c Card) JSONLD() map[string]interface{} {
+	ld := map[string]interface{}{
+		"@context":    "https://schema.org",
+		"@type":       "Person",
+		"name":        c.Title,
+		"description": c.Description,
+	}
+	if c.Type == CardEvent {
+		ld["@type"] = "SportsEvent"
+		ld["sport"] = "Cycling"
+		if c.Date != "" {
+			ld["startDate"] = c.Date
+		}
+	}
+	if c.URL != "" {
+		ld["url"] = c.URL
+	}
+	if c.Image != "" {
+		ld["image"] = c.Image
+	}
+	return ld
+}
+
+// Color is a background colour for the card's image, made up from its title so that each
+// rider or event keeps the same one
+func (c Card) Color() color.RGBA {
+	return hslColor(float64(nameHue(c.Title)), 0.45, 0.32)
+}
+
+// hslColor converts hue (degrees), saturation and lightness to RGB
+func hslColor(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	to8 := func(v float64) uint8 { return uint8(math.Round((v + m) * 255)) }
+	return color.RGBA{to8(r), to8(g), to8(b), 0xff}
+}
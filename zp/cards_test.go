@@ -0,0 +1,79 @@
+package zp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRiderCard(t *testing.T) {
+	c := RiderCard(Rider{Name: "Liz &amp; Co", Category: "B", Ftp90: 3.14, Races30: 4, LatestEvent: "Crit City"})
+	if c.Type != CardRider || c.Title != "Liz & Co" {
+		t.Errorf("Unexpected card %v", c)
+	}
+	expected := "Category B · FTP 3.1 w/kg over 90 days · 4 races in the last 30 days · latest: Crit City"
+	if c.Description != expected {
+		t.Errorf("Got description %q, expected %q", c.Description, expected)
+	}
+	if len(c.Stats) != 3 || c.Stats[1].Value != "3.1" {
+		t.Errorf("Unexpected stats %v", c.Stats)
+	}
+
+	ld := c.JSONLD()
+	if ld["@type"] != "Person" || ld["name"] != "Liz & Co" {
+		t.Errorf("Unexpected JSON-LD %v", ld)
+	}
+
+	c = RiderCard(Rider{Name: "Liz", Category: "C", CECategory: "B"})
+	if !strings.HasPrefix(c.Description, "Category C · CE B · ") || c.Stats[1] != (CardStat{"CE category", "B"}) {
+		t.Errorf("Unexpected card for CE category %v", c)
+	}
+}
+
+func TestEventCard(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := events[12]
+	results := []Event{
+		{Zwid: 1, Name: "Other", TeamID: "1", PositionInCat: 1, Category: "A", EventTitle: "Crit &amp; City"},
+		e,
+		{Zwid: 2, Name: "Club mate", TeamID: e.TeamID, PositionInCat: 0, Category: "C"},
+	}
+	results[1].PositionInCat = 3
+
+	c := EventCard(123, results, e.TeamID)
+	if c.Type != CardEvent || c.Title != "Crit & City" {
+		t.Errorf("Unexpected card %v", c)
+	}
+	if c.Stats[0].Value != "3" || c.Stats[1].Value != "2" {
+		t.Errorf("Unexpected stats %v", c.Stats)
+	}
+	if len(c.Stats) != 3 || !strings.HasPrefix(c.Stats[2].Value, "3rd ") {
+		t.Errorf("Expected the best club result, got %v", c.Stats)
+	}
+
+	c = EventCard(123, nil, "2672")
+	if c.Title != "Event 123" || len(c.Stats) != 2 {
+		t.Errorf("Unexpected card without results %v", c)
+	}
+	if c.JSONLD()["@type"] != "SportsEvent" {
+		t.Errorf("Unexpected JSON-LD %v", c.JSONLD())
+	}
+}
+
+func TestHSLColor(t *testing.T) {
+	if c := hslColor(0, 1, 0.5); c.R != 0xff || c.G != 0 || c.B != 0 {
+		t.Errorf("Expected red, got %v", c)
+	}
+	if c := hslColor(240, 1, 0.5); c.R != 0 || c.G != 0 || c.B != 0xff {
+		t.Errorf("Expected blue, got %v", c)
+	}
+
+	// Each card keeps its colour
+	a, b := Card{Title: "Someone"}, Card{Title: "Someone else"}
+	if a.Color() != a.Color() || a.Color() == b.Color() {
+		t.Errorf("Got colours %v and %v", a.Color(), b.Color())
+	}
+}
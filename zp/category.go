@@ -0,0 +1,123 @@
+package zp
+
+import (
+	"sort"
+	"time"
+)
+
+// CategoryBoundary is the lowest 60-day w/kg FTP for a ZwiftPower category, and the
+// lowest FTP in watts (zFTP) that goes with it
+type CategoryBoundary struct {
+	Category string
+	MinWkg   float64
+	MinWatts float64 `json:",omitempty"`
+}
+
+// CategoryBoundaries are in order from the top category down
+var CategoryBoundaries = []CategoryBoundary{
+	{Category: "A", MinWkg: 4.0, MinWatts: 250},
+	{Category: "B", MinWkg: 3.2, MinWatts: 200},
+	{Category: "C", MinWkg: 2.5, MinWatts: 150},
+	{Category: "D", MinWkg: 0},
+}
+
+func categoryIndex(category string) int {
+	for i, b := range CategoryBoundaries {
+		if b.Category == category {
+			return i
+		}
+	}
+	return -1
+}
+
+// CategoryChange is the first race a rider rode in a different category
+type CategoryChange struct {
+	Date       time.Time
+	From       string
+	To         string
+	EventTitle string
+}
+
+// CategoryHistory shows how a rider's category has changed, and how close they are
+// to changing again
+type CategoryHistory struct {
+	Zwid      int
+	Name      string
+	Current   string    // Category of the latest race
+	Since     time.Time // First race in the current category, since the last change
+	Changes   []CategoryChange
+	Ftp60     float64 // Best w/kg FTP over the last 60 days, which is what categories go on
+	ToUp      float64 // w/kg more needed to reach the category above. Zero for A.
+	AboveDown float64 // w/kg above the bottom of the current category. Zero for D.
+}
+
+// CategoryHistoryFromEvents works out the rider's category history from their races.
+// Only races in categories A to D count, so for example TTTs in category V are ignored.
+func CategoryHistoryFromEvents(riderID int, events []Event) CategoryHistory {
+	return CategoryHistoryAsOf(riderID, events, SystemClock.Now())
+}
+
+// CategoryHistoryAsOf works out the rider's category history as it stood at this time,
+// ignoring any races after it
+func CategoryHistoryAsOf(riderID int, events []Event, asOf time.Time) CategoryHistory {
+	h := CategoryHistory{Zwid: riderID}
+
+	var races []Event
+	for _, e := range events {
+		if h.Name == "" && e.Name != "" {
+			h.Name = e.RiderName()
+		}
+		if e.EventDateSecs != 0 && !e.EventDate.After(asOf) && categoryIndex(e.Category) >= 0 && e.IsRace() {
+			races = append(races, e)
+		}
+	}
+
+	sort.SliceStable(races, func(i, j int) bool {
+		return races[i].EventDate.Before(races[j].EventDate)
+	})
+
+	for _, e := range races {
+		if e.Category == h.Current {
+			continue
+		}
+		if h.Current != "" {
+			h.Changes = append(h.Changes, CategoryChange{
+				Date:       e.EventDate,
+				From:       h.Current,
+				To:         e.Category,
+				EventTitle: e.EventTitle,
+			})
+		}
+		h.Current = e.Category
+		h.Since = e.EventDate
+	}
+
+	if len(events) > 0 {
+		h.Ftp60 = RiderFromEventsAsOf(riderID, events, asOf).Ftp60
+	}
+
+	if i := categoryIndex(h.Current); i >= 0 {
+		if i > 0 {
+			h.ToUp = CategoryBoundaries[i-1].MinWkg - h.Ftp60
+		}
+		if i < len(CategoryBoundaries)-1 {
+			h.AboveDown = h.Ftp60 - CategoryBoundaries[i].MinWkg
+		}
+	}
+
+	return h
+}
+
+// Held is how long the rider has been in their current category
+func (h CategoryHistory) Held(now time.Time) time.Duration {
+	if h.Since.IsZero() {
+		return 0
+	}
+	return now.Sub(h.Since)
+}
+
+// NearUpgrade is true if the rider is within margin w/kg of the category above, or
+// already over it
+func (h CategoryHistory) NearUpgrade(margin float64) bool {
+	return categoryIndex(h.Current) > 0 && h.ToUp <= margin
+}
@@ -0,0 +1,78 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCategoryHistory(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	h := CategoryHistoryFromEvents(1261784, events)
+	if h.Current != "C" || !h.Since.Equal(time.Unix(1610505900, 0)) {
+		t.Errorf("Got current category %s since %v", h.Current, h.Since)
+	}
+
+	// D -> C -> A -> C, ignoring the TTTs in V
+	expected := []struct{ from, to string }{{"D", "C"}, {"C", "A"}, {"A", "C"}}
+	if len(h.Changes) != len(expected) {
+		t.Fatalf("Got changes %v", h.Changes)
+	}
+	for i, c := range expected {
+		if h.Changes[i].From != c.from || h.Changes[i].To != c.to {
+			t.Errorf("Change %d: got %s to %s, expected %s to %s", i, h.Changes[i].From, h.Changes[i].To, c.from, c.to)
+		}
+	}
+
+	if held := h.Held(h.Since.Add(48 * time.Hour)); held != 48*time.Hour {
+		t.Errorf("Got held %v", held)
+	}
+
+	// Before the move back down to C, the rider was still in A
+	before := CategoryHistoryAsOf(1261784, events, h.Changes[2].Date.Add(-time.Second))
+	if before.Current != "A" || len(before.Changes) != 2 {
+		t.Errorf("Got category %s with changes %v before the last change", before.Current, before.Changes)
+	}
+}
+
+func TestCategoryBoundaryProximity(t *testing.T) {
+	now := time.Now()
+	race := func(category string, daysAgo int, wkgFtp float64) Event {
+		date := now.AddDate(0, 0, -daysAgo)
+		return Event{
+			Zwid:          1,
+			Name:          "Rider",
+			Category:      category,
+			EventType:     "TYPE_RACE",
+			EventDateSecs: EventDateType(date.Unix()),
+			EventDate:     date,
+			WkgFtp:        []interface{}{wkgFtp, 0.0},
+			AvgWkg:        []interface{}{"3.0", 0.0},
+		}
+	}
+
+	h := CategoryHistoryFromEvents(1, []Event{race("C", 30, 3.1), race("C", 5, 3.0)})
+	if h.Current != "C" || len(h.Changes) != 0 || h.Ftp60 != 3.1 {
+		t.Errorf("Unexpected history %+v", h)
+	}
+	if math.Abs(h.ToUp-0.1) > 0.001 || math.Abs(h.AboveDown-0.6) > 0.001 {
+		t.Errorf("Got %.2f to go up, %.2f above down", h.ToUp, h.AboveDown)
+	}
+	if !h.NearUpgrade(0.2) || h.NearUpgrade(0.05) {
+		t.Errorf("Wrong NearUpgrade for %.2f to go", h.ToUp)
+	}
+
+	h = CategoryHistoryFromEvents(1, []Event{race("A", 5, 4.5)})
+	if h.ToUp != 0 || h.NearUpgrade(1) {
+		t.Errorf("A rider can't go up: %+v", h)
+	}
+
+	h = CategoryHistoryFromEvents(1, nil)
+	if h.Current != "" || h.Held(now) != 0 {
+		t.Errorf("Unexpected history with no events %+v", h)
+	}
+}
@@ -0,0 +1,57 @@
+package zp
+
+import (
+	"net/http"
+)
+
+// Client imports data from ZwiftPower. Unlike the Import functions, which predate it and
+// always use DefaultBackend, each Client has its own backend, so one program can talk to
+// more than one of them.
+type Client struct {
+	HTTP    *http.Client
+	Backend Backend
+	Clock   Clock // Riders' stats are worked out as of this time; nil means SystemClock
+
+	// ZwiftToken, if set, is a Zwift API access token used to count riders' free rides
+	// and workouts in their Rides, not just their ZwiftPower events (see ImportActivities),
+	// and for their weight and FTP in their profiles
+	ZwiftToken string
+
+	// ZwiftRacingKey, if set, is a zwiftracing.app API key, used along with Zwift and
+	// ZwiftPower for riders' weight, FTP and category in their profiles
+	ZwiftRacingKey string
+
+	// Precedence says which source to believe for each of a rider's details when they
+	// disagree. Nil means DefaultPrecedence.
+	Precedence Precedence
+
+	// ResultsDir, if set, is checked for event results before ZwiftPower, as files named
+	// after the event ID: 123.json, 123.csv or 123.html (see ReadResultsFile)
+	ResultsDir string
+
+	// Progress, if set, is told how far long imports such as Backfill have got
+	Progress Progress
+
+	// OptOuts are riders who are left out of everything the client imports: rosters,
+	// results and signups, and their own events
+	OptOuts OptOuts
+
+	// FtpAggregation is how riders' Ftp30, Ftp60 and Ftp90 are worked out from their
+	// events. The zero value takes the best, as ZwiftPower does.
+	FtpAggregation FtpAggregations
+}
+
+// New gets a Client for talking to ZwiftPower with DefaultBackend, with any middleware
+// wrapped around its transport (see NewClient)
+func New(middleware ...Middleware) (*Client, error) {
+	client, err := NewClient(middleware...)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(client), nil
+}
+
+// Wrap makes a Client from an existing HTTP client, using DefaultBackend
+func Wrap(client *http.Client) *Client {
+	return &Client{HTTP: client, Backend: DefaultBackend}
+}
@@ -0,0 +1,91 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestClientBackends(t *testing.T) {
+	var paths []string
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case r.URL.Path == "/cache3/teams/2672_riders.json",
+			r.URL.Path == "/api3.php" && r.URL.Query().Get("do") == "team_riders":
+			fmt.Fprint(w, `{"data":[{"name":"Liz Rice","zwid":98588}]}`)
+		case r.URL.Path == "/cache3/profile/1261784_all.json",
+			r.URL.Path == "/api3.php" && r.URL.Query().Get("do") == "profile_results":
+			fmt.Fprint(w, testdata)
+		case r.URL.Path == "/profile.php":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	cache3, err := New()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	api3, err := New()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	api3.Backend = API3
+
+	ctx := context.Background()
+	for _, c := range []*Client{cache3, api3} {
+		paths = nil
+		riders, err := c.Club(ctx, 2672)
+		if err != nil {
+			t.Fatalf("%s: failed importing club: %v", c.Backend, err)
+		}
+		if len(riders) != 1 || riders[0].Zwid != 98588 {
+			t.Errorf("%s: unexpected riders %v", c.Backend, riders)
+		}
+
+		rider, err := c.ClubRider(ctx, Rider{Zwid: 1261784, Name: "From the roster"})
+		if err != nil {
+			t.Fatalf("%s: failed importing rider: %v", c.Backend, err)
+		}
+		if rider.Name != "From the roster" || rider.Zwid != 1261784 {
+			t.Errorf("%s: unexpected rider %v", c.Backend, rider)
+		}
+
+		for _, p := range paths {
+			if (c.Backend == API3) != (p == "/api3.php") {
+				t.Errorf("%s: unexpected request for %s", c.Backend, p)
+			}
+		}
+	}
+
+	if DefaultBackend != Cache3 {
+		t.Errorf("Client changed DefaultBackend to %s", DefaultBackend)
+	}
+}
+
+func TestDeprecatedImports(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cache3/profile/1261784_all.json" {
+			fmt.Fprint(w, testdata)
+		}
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+
+	old, err := ImportRider(client, 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing rider: %v", err)
+	}
+	rider, err := Wrap(client).Rider(context.Background(), 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing rider: %v", err)
+	}
+	if fmt.Sprint(old.Strings()) != fmt.Sprint(rider.Strings()) {
+		t.Errorf("ImportRider got %v, Client.Rider got %v", old.Strings(), rider.Strings())
+	}
+}
@@ -0,0 +1,35 @@
+package zp
+
+import "time"
+
+// Clock tells the time. A rider's stats cover windows counted back from now, so taking
+// now from a Clock lets them be worked out as of any date, to test them or to recompute
+// a report from the past.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the real time, used when no other Clock is given
+var SystemClock Clock = systemClock{}
+
+// FixedClock is always the same time
+type FixedClock time.Time
+
+// Now is the fixed time
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// now is the time according to the client's Clock, if it has one
+func (c *Client) now() time.Time {
+	if c.Clock == nil {
+		return SystemClock.Now()
+	}
+	return c.Clock.Now()
+}
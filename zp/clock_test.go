@@ -0,0 +1,74 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRiderAsOf(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	// Just after the last race, everything is recent
+	last := events[12].EventDate
+	asOf := last.Add(time.Hour)
+	r := RiderFromEventsAsOf(1261784, events, asOf)
+	if r.Races30 == 0 || r.Ftp30 == 0 {
+		t.Errorf("Expected recent races and FTP as of %v, got %+v", asOf, r)
+	}
+	if again := RiderFromEventsAsOf(1261784, events, asOf); !reflect.DeepEqual(r, again) {
+		t.Errorf("Same time gave different stats: %+v, %+v", r, again)
+	}
+
+	// Just before it, the last race hasn't happened
+	before := RiderFromEventsAsOf(1261784, events, last.Add(-time.Hour))
+	if !before.LatestEventDate.Before(last) || before.Races > r.Races-1 {
+		t.Errorf("Expected stats without the last race, got %+v", before)
+	}
+
+	// Long after, nothing is recent
+	later := RiderFromEventsAsOf(1261784, events, last.AddDate(2, 0, 0))
+	if later.Rides != 0 || later.Ftp90 != 0 || !later.LatestEventDate.Equal(r.LatestEventDate) {
+		t.Errorf("Expected no recent stats two years on, got %+v", later)
+	}
+}
+
+func TestClientClock(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testdata)
+	})
+
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+	asOf := events[12].EventDate.Add(24 * time.Hour)
+
+	client, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Clock = FixedClock(asOf)
+	rider, err := client.Rider(context.Background(), 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing rider: %v", err)
+	}
+	if rider.Provenance == nil || !rider.Provenance.Fetched.Equal(asOf) {
+		t.Errorf("Expected the rider to be fetched as of the clock, got %+v", rider.Provenance)
+	}
+	rider.Provenance = nil
+	if expected := RiderFromEventsAsOf(1261784, events, asOf); !reflect.DeepEqual(rider, expected) {
+		t.Errorf("Got %+v, expected %+v", rider, expected)
+	}
+
+	client.Clock = nil
+	if now := client.now(); time.Since(now) > time.Minute {
+		t.Errorf("Expected the system clock without a Clock, got %v", now)
+	}
+}
@@ -0,0 +1,162 @@
+package zp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ColumnFormats are named ways of turning a field's value into a column
+var ColumnFormats = map[string]func(v interface{}) string{
+	"date": func(v interface{}) string {
+		return v.(time.Time).Format("2006-01-02")
+	},
+	"profile": func(v interface{}) string {
+		return fmt.Sprintf("https://www.zwiftpower.com/profile.php?z=%v", v)
+	},
+}
+
+// relativeFormats are like ColumnFormats, but depend on when the report is for
+var relativeFormats = map[string]func(v interface{}, now time.Time) string{
+	"monthsago": func(v interface{}, now time.Time) string {
+		return monthsAgo(v.(time.Time), now)
+	},
+}
+
+type tagColumn struct {
+	Column
+	order  int
+	field  int
+	format string
+}
+
+// parseColumnTags reads the columns from the struct's col tags, in order
+func parseColumnTags(t reflect.Type) ([]tagColumn, error) {
+	var columns []tagColumn
+	orders := make(map[int]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("col")
+		if !ok {
+			continue
+		}
+
+		for _, spec := range strings.Split(tag, ";") {
+			parts := strings.Split(spec, ",")
+			c := tagColumn{Column: Column{Key: strings.ToLower(f.Name), Header: parts[0]}, field: i}
+			for _, opt := range parts[1:] {
+				kv := strings.SplitN(opt, "=", 2)
+				if len(kv) != 2 {
+					return nil, fmt.Errorf("%s.%s: bad column option %q", t.Name(), f.Name, opt)
+				}
+				switch kv[0] {
+				case "key":
+					c.Key = kv[1]
+				case "order":
+					n, err := strconv.Atoi(kv[1])
+					if err != nil || n < 1 {
+						return nil, fmt.Errorf("%s.%s: bad column order %q", t.Name(), f.Name, kv[1])
+					}
+					c.order = n
+				case "format":
+					if !knownFormat(kv[1]) {
+						return nil, fmt.Errorf("%s.%s: unknown column format %q", t.Name(), f.Name, kv[1])
+					}
+					c.format = kv[1]
+				default:
+					return nil, fmt.Errorf("%s.%s: unknown column option %q", t.Name(), f.Name, kv[0])
+				}
+			}
+
+			if c.order == 0 {
+				return nil, fmt.Errorf("%s.%s: column %q has no order", t.Name(), f.Name, c.Header)
+			}
+			if other, ok := orders[c.order]; ok {
+				return nil, fmt.Errorf("%s: columns %q and %q are both order %d", t.Name(), other, c.Header, c.order)
+			}
+			orders[c.order] = c.Header
+			columns = append(columns, c)
+		}
+	}
+
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].order < columns[j].order
+	})
+	return columns, nil
+}
+
+func knownFormat(format string) bool {
+	_, named := ColumnFormats[format]
+	_, relative := relativeFormats[format]
+	return named || relative || strings.HasPrefix(format, "%")
+}
+
+var tagColumnCache sync.Map // reflect.Type -> []tagColumn
+
+// tagColumns gets the columns for a struct type, panicking if its tags are wrong as
+// that's a mistake in the code rather than the data
+func tagColumns(t reflect.Type) []tagColumn {
+	if columns, ok := tagColumnCache.Load(t); ok {
+		return columns.([]tagColumn)
+	}
+
+	columns, err := parseColumnTags(t)
+	if err != nil {
+		panic(err)
+	}
+	tagColumnCache.Store(t, columns)
+	return columns
+}
+
+// TagColumns describes the columns of tabular output for a struct. Its fields say which
+// columns they appear in with a col tag, e.g.
+//
+//	Ftp90 float64 `col:"FTP 90 days,order=9,format=%.1f"`
+//
+// The tag starts with the column header, followed by options:
+//
+//	key=    short name for the column, defaulting to the field name in lower case
+//	order=  where the column goes, counting from 1
+//	format= a fmt verb such as %.1f, one of the names in ColumnFormats, or monthsago
+//
+// A field can appear in several columns, separated by semicolons. Fields without a col
+// tag aren't output.
+func TagColumns(v interface{}) []Column {
+	tc := tagColumns(reflect.TypeOf(v))
+	columns := make([]Column, len(tc))
+	for i, c := range tc {
+		columns[i] = c.Column
+	}
+	return columns
+}
+
+// TagStrings turns a struct into the columns described by TagColumns, as of now
+func TagStrings(v interface{}) []string {
+	return TagStringsAsOf(v, SystemClock.Now())
+}
+
+// TagStringsAsOf is TagStrings for a report as of this time, which columns such as
+// monthsago count back from
+func TagStringsAsOf(v interface{}, now time.Time) []string {
+	rv := reflect.ValueOf(v)
+	tc := tagColumns(rv.Type())
+	output := make([]string, len(tc))
+	for i, c := range tc {
+		value := rv.Field(c.field).Interface()
+		switch {
+		case c.format == "":
+			output[i] = fmt.Sprint(value)
+		case ColumnFormats[c.format] != nil:
+			output[i] = ColumnFormats[c.format](value)
+		case relativeFormats[c.format] != nil:
+			output[i] = relativeFormats[c.format](value, now)
+		default:
+			output[i] = fmt.Sprintf(c.format, value)
+		}
+	}
+	return output
+}
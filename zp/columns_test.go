@@ -0,0 +1,86 @@
+package zp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRiderColumns(t *testing.T) {
+	var keys []string
+	for _, c := range RiderColumns {
+		keys = append(keys, c.Key)
+	}
+	expected := "name,zwid,latestdate,monthsago,latestevent,rides,profile,ftp30,ftp90,races30,races90,races,latestrace,latestracedate"
+	if strings.Join(keys, ",") != expected {
+		t.Errorf("Got columns %v, expected %s", keys, expected)
+	}
+	if RiderColumns[8].Header != "FTP 90 days" {
+		t.Errorf("Unexpected header %q", RiderColumns[8].Header)
+	}
+
+	r := Rider{
+		Name:            "Liz Rice",
+		Zwid:            98588,
+		LatestEventDate: time.Date(2020, 4, 15, 18, 0, 0, 0, time.UTC),
+		LatestEvent:     "ZZRC SUB 2.0 Ride",
+		Rides:           160,
+		Ftp30:           2.5,
+		Ftp90:           2.72,
+		Races90:         3,
+		Races:           47,
+		LatestRace:      "Stage 4 Race - Tour of Watopia 2020",
+		LatestRaceDate:  time.Date(2020, 3, 21, 18, 0, 0, 0, time.UTC),
+	}
+	expected = "Liz Rice,98588,2020-04-15,Over a year ago,ZZRC SUB 2.0 Ride,160,https://www.zwiftpower.com/profile.php?z=98588,2.5,2.7,0,3,47,Stage 4 Race - Tour of Watopia 2020,2020-03-21"
+	if s := strings.Join(r.Strings(), ","); s != expected {
+		t.Errorf("Got %s\nexpected %s", s, expected)
+	}
+}
+
+func TestParseColumnTags(t *testing.T) {
+	type good struct {
+		Second  float64 `col:"Second,order=2,format=%.2f"`
+		Hidden  string
+		First   string    `col:"First,key=one,order=1"`
+		Both    time.Time `col:"Date,order=3,format=date;When,key=when,order=4,format=monthsago"`
+		private int
+	}
+	columns := TagColumns(good{})
+	if len(columns) != 4 || columns[0].Key != "one" || columns[1].Key != "second" || columns[3].Header != "When" {
+		t.Errorf("Unexpected columns %v", columns)
+	}
+	s := TagStrings(good{Second: 1, First: "a", Both: time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC)})
+	if strings.Join(s, ",") != "a,1.00,2021-02-03,Over a year ago" {
+		t.Errorf("Unexpected strings %v", s)
+	}
+	s = TagStringsAsOf(good{Both: time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC)}, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC))
+	if s[3] != "Last month" {
+		t.Errorf("Got %v as of March 2021, expected last month", s)
+	}
+
+	bad := []interface{}{
+		struct {
+			A int `col:"A"`
+		}{},
+		struct {
+			A int `col:"A,order=1"`
+			B int `col:"B,order=1"`
+		}{},
+		struct {
+			A int `col:"A,order=1,format=nonsense"`
+		}{},
+		struct {
+			A int `col:"A,order=1,colour=red"`
+		}{},
+		struct {
+			A int `col:"A,order=x"`
+		}{},
+	}
+	for i, v := range bad {
+		if _, err := parseColumnTags(reflect.TypeOf(v)); err == nil {
+			t.Errorf("Case %d: expected error for bad tags", i)
+		}
+	}
+}
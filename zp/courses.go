@@ -0,0 +1,125 @@
+package zp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RouteCourses maps ZwiftPower route IDs to the distance and elevation of one lap of the
+// route, so that we can tell how hilly an event was. ZwiftPower gives each result's
+// distance but not its elevation. It starts empty: add routes as you come across them,
+// or load them with ParseRouteCourses.
+var RouteCourses = map[string]Course{}
+
+// ParseRouteCourses reads routes from JSON keyed by ZwiftPower route ID, e.g.
+//
+//	{"2007026433": {"Distance": 14.1, "Elevation": 78}}
+func ParseRouteCourses(data []byte) (map[string]Course, error) {
+	var routes map[string]Course
+	err := json.Unmarshal(data, &routes)
+	if err != nil {
+		return nil, &ParseError{What: "routes", Err: err}
+	}
+
+	for id, c := range routes {
+		if c.Distance <= 0 || c.Elevation < 0 {
+			return nil, fmt.Errorf("route %s needs a positive Distance and an Elevation of at least 0", id)
+		}
+	}
+	return routes, nil
+}
+
+// Terrain describes how hilly an event was
+const (
+	TerrainFlat    = "Flat"    // Less than 5m of climbing per km
+	TerrainRolling = "Rolling" // 5m to 12m per km
+	TerrainHilly   = "Hilly"   // More than 12m per km
+)
+
+// Course works out the distance and elevation of the event from its route, scaling the
+// route's elevation to the distance of the event so that laps and lead-ins are allowed
+// for. It's false if we don't know the route or the distance.
+func (e Event) Course() (Course, bool) {
+	route, ok := RouteCourses[e.RouteID]
+	if !ok || e.Distance <= 0 {
+		return Course{}, false
+	}
+
+	distance := float64(e.Distance)
+	return Course{Distance: distance, Elevation: route.Elevation * distance / route.Distance}, true
+}
+
+// Elevation is metres climbed in the event, or zero if we don't know
+func (e Event) Elevation() float64 {
+	c, _ := e.Course()
+	return c.Elevation
+}
+
+// Gradient is the average climbing in metres per km, or zero if we don't know
+func (e Event) Gradient() float64 {
+	c, ok := e.Course()
+	if !ok {
+		return 0
+	}
+	return c.Elevation / c.Distance
+}
+
+// Terrain says whether the event was flat, rolling or hilly, or is empty if we don't know
+func (e Event) Terrain() string {
+	if _, ok := e.Course(); !ok {
+		return ""
+	}
+
+	switch g := e.Gradient(); {
+	case g < 5:
+		return TerrainFlat
+	case g < 12:
+		return TerrainRolling
+	default:
+		return TerrainHilly
+	}
+}
+
+// AvgSpeed is the average speed in km/h, or zero if we don't have the distance and time
+func (e Event) AvgSpeed() float64 {
+	if e.Distance <= 0 || e.Time <= 0 {
+		return 0
+	}
+	return float64(e.Distance) / (float64(e.Time) / 3600)
+}
+
+// ClimbRate is metres climbed per hour, sometimes called VAM, or zero if we don't know
+func (e Event) ClimbRate() float64 {
+	if e.Time <= 0 {
+		return 0
+	}
+	return e.Elevation() / (float64(e.Time) / 3600)
+}
+
+// SpeedRatio compares the average speed with how fast the rider's average power would
+// take them riding solo on a steady gradient like the event's. It's usually above 1 in
+// races thanks to drafting; comparing it across terrain shows where a rider goes better
+// than their power suggests. It's zero if we don't have enough data.
+func (e Event) SpeedRatio() float64 {
+	c, ok := e.Course()
+	if !ok || e.Time <= 0 || e.AvgPower <= 0 || e.Weight <= 0 {
+		return 0
+	}
+
+	solo := speed(float64(e.AvgPower), float64(e.Weight)+bikeWeight, c.Elevation/(c.Distance*1000))
+	return e.AvgSpeed() / 3.6 / solo
+}
+
+func raceTerrain(e Event) string {
+	if !e.IsRace() {
+		return ""
+	}
+	return e.Terrain()
+}
+
+// SplitByTerrain summarises races since the given date by how hilly they were, with the
+// terrain in the Series field. Races on routes missing from RouteCourses are left out.
+func SplitByTerrain(events []Event, since time.Time) []SeriesStats {
+	return splitBy(events, since, raceTerrain)
+}
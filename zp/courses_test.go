@@ -0,0 +1,65 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEventCourse(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	RouteCourses["2007026433"] = Course{Distance: 14, Elevation: 140}
+	RouteCourses["1776635757"] = Course{Distance: 25, Elevation: 50}
+	defer delete(RouteCourses, "2007026433")
+	defer delete(RouteCourses, "1776635757")
+
+	// Two laps of the route
+	e := events[12]
+	if e.Elevation() != 280 || e.Gradient() != 10 || e.Terrain() != TerrainRolling {
+		t.Errorf("Got elevation %v, gradient %v, terrain %s", e.Elevation(), e.Gradient(), e.Terrain())
+	}
+	if speed := e.AvgSpeed(); math.Abs(speed-34.1) > 0.05 {
+		t.Errorf("Got average speed %.2f, expected 34.1", speed)
+	}
+	if vam := e.ClimbRate(); math.Abs(vam-341) > 1 {
+		t.Errorf("Got climb rate %.0f, expected 341", vam)
+	}
+	if r := e.SpeedRatio(); r < 1 || r > 1.5 {
+		t.Errorf("Got speed ratio %.2f, expected a little over 1 for a race", r)
+	}
+
+	// We don't know the route
+	if e := events[0]; e.Elevation() != 0 || e.Terrain() != "" || e.SpeedRatio() != 0 || e.AvgSpeed() == 0 {
+		t.Errorf("Expected no course for unknown route, got %v, %q", e.Elevation(), e.Terrain())
+	}
+
+	// A known route but no distance
+	if e := events[13]; e.Terrain() != "" || e.AvgSpeed() != 0 {
+		t.Errorf("Expected no course without a distance, got %q, %v", e.Terrain(), e.AvgSpeed())
+	}
+
+	stats := SplitByTerrain(events, time.Time{})
+	if len(stats) != 2 || stats[0].Series != TerrainFlat || stats[1].Series != TerrainRolling || stats[1].Results != 1 {
+		t.Fatalf("Unexpected terrain stats %+v", stats)
+	}
+	if math.Abs(stats[1].AvgSpeed-34.1) > 0.05 {
+		t.Errorf("Got average speed %.2f for rolling races", stats[1].AvgSpeed)
+	}
+}
+
+func TestParseRouteCourses(t *testing.T) {
+	routes, err := ParseRouteCourses([]byte(`{"2007026433": {"Distance": 14.1, "Elevation": 78}}`))
+	if err != nil || routes["2007026433"].Elevation != 78 {
+		t.Errorf("Got %v, %v", routes, err)
+	}
+
+	for _, bad := range []string{`[]`, `{"1": {"Distance": 0, "Elevation": 10}}`, `{"1": {"Distance": 10, "Elevation": -1}}`} {
+		if _, err := ParseRouteCourses([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is when a recurring job runs, such as an export or a digest. It's parsed
+// from a cron expression (see ParseSchedule).
+type Schedule struct {
+	spec  string
+	every time.Duration // For @every schedules, instead of the fields below
+	loc   *time.Location
+
+	minutes, hours, days, months, weekdays uint64 // Bit n set if n matches
+	anyDay, anyWeekday                     bool
+}
+
+// cronField is the range and names for one field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // Names for min, min+1, ... if the field has them
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule reads a cron expression: minute, hour, day of month, month and day of
+// week, each of which can be *, a number, a range such as 1-5, a list such as 1,15, or
+// any of those with a step such as */15. Months and days of the week can be names, e.g.
+// "0 18 * * sun" for six o'clock every Sunday; Sunday is 0 or 7. If both days are given,
+// either can match, as in cron. There are also @hourly, @daily, @weekly, @monthly and
+// @yearly, and "@every 15m" for a fixed interval.
+//
+// Times are in the local time zone, unless the expression starts with one such as
+// "CRON_TZ=Europe/London ".
+func ParseSchedule(spec string) (*Schedule, error) {
+	s := &Schedule{spec: spec, loc: time.Local}
+	expr := strings.TrimSpace(spec)
+
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		parts := strings.SplitN(expr, " ", 2)
+		loc, err := time.LoadLocation(parts[0][strings.Index(parts[0], "=")+1:])
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %v", spec, err)
+		}
+		s.loc = loc
+		expr = ""
+		if len(parts) > 1 {
+			expr = strings.TrimSpace(parts[1])
+		}
+	}
+
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("schedule %q: @every needs a duration of at least 1m", spec)
+		}
+		s.every = d
+		return s, nil
+	}
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("schedule %q: expected 5 fields (minute hour day month weekday), got %d", spec, len(fields))
+	}
+	sets := make([]uint64, len(fields))
+	for i, f := range fields {
+		set, err := cronFields[i].parse(f)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %v", spec, err)
+		}
+		sets[i] = set
+	}
+	s.minutes, s.hours, s.days, s.months, s.weekdays = sets[0], sets[1], sets[2], sets[3], sets[4]
+	s.anyDay, s.anyWeekday = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+
+	// Sunday is 0 or 7
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	return s, nil
+}
+
+// parse reads one field of a cron expression into a bit set
+func (f cronField) parse(field string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step in %s %q", f.name, part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := f.min, f.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			lo, err = f.value(bounds[0])
+			if err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = f.value(bounds[1])
+				if err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("%s range %q goes backwards", f.name, part)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %q should be from %d to %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// String is the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Every is the interval for an @every schedule, or zero for others
+func (s *Schedule) Every() time.Duration {
+	return s.every
+}
+
+// Next is the first time after t that the schedule runs. It's the zero time if the
+// schedule can never run, such as on 31st February.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.loc)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: if both the day of the month and the day of the week are
+// restricted, either will do
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// RunSchedule calls the job each time the schedule comes round, until the context is
+// done. @every schedules run the job straight away as well.
+func RunSchedule(ctx context.Context, s *Schedule, job func(ctx context.Context)) {
+	if s.every > 0 {
+		job(ctx)
+	}
+	for {
+		next := s.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		select {
+		case <-time.After(time.Until(next)):
+			job(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package zp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// Monday 1 March 2021, 10:17
+	start := time.Date(2021, 3, 1, 10, 17, 30, 0, time.UTC)
+	cases := []struct {
+		spec     string
+		expected []string
+	}{
+		{"CRON_TZ=UTC 0 18 * * sun", []string{"2021-03-07 18:00", "2021-03-14 18:00"}},
+		{"TZ=UTC 30 2 * * *", []string{"2021-03-02 02:30", "2021-03-03 02:30"}},
+		{"TZ=UTC */20 * * * *", []string{"2021-03-01 10:20", "2021-03-01 10:40", "2021-03-01 11:00"}},
+		{"TZ=UTC 0 9-17/4 * * mon-fri", []string{"2021-03-01 13:00", "2021-03-01 17:00", "2021-03-02 09:00"}},
+		{"TZ=UTC 0 0 1,15 * *", []string{"2021-03-15 00:00", "2021-04-01 00:00"}},
+		{"TZ=UTC 0 0 13 * 5", []string{"2021-03-05 00:00", "2021-03-12 00:00", "2021-03-13 00:00"}}, // Either day
+		{"TZ=UTC 0 0 29 feb *", []string{"2024-02-29 00:00"}},
+		{"TZ=UTC 0 12 * * 7", []string{"2021-03-07 12:00"}},
+		{"TZ=UTC @weekly", []string{"2021-03-07 00:00", "2021-03-14 00:00"}},
+		{"TZ=UTC @every 90m", []string{"2021-03-01 11:47", "2021-03-01 13:17"}},
+		{"TZ=UTC 0 0 31 2 *", []string{"0001-01-01 00:00"}},
+	}
+	for _, c := range cases {
+		s, err := ParseSchedule(c.spec)
+		if err != nil {
+			t.Errorf("Parsing %q: %v", c.spec, err)
+			continue
+		}
+		next := start
+		for _, expected := range c.expected {
+			next = s.Next(next)
+			if got := next.UTC().Format("2006-01-02 15:04"); got != expected {
+				t.Errorf("%q: got %s, expected %s", c.spec, got, expected)
+				break
+			}
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "0 0 * * funday", "5-1 * * * *", "*/0 * * * *", "@every 10s", "@every soon", "CRON_TZ=Nowhere/Special 0 0 * * *"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestScheduleTimeZone(t *testing.T) {
+	s, err := ParseSchedule("CRON_TZ=America/New_York 0 9 * * *")
+	if err != nil {
+		t.Skipf("No time zone data: %v", err)
+	}
+	next := s.Next(time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC))
+	if !next.Equal(time.Date(2021, 3, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("Got %v", next)
+	}
+}
+
+func TestRunSchedule(t *testing.T) {
+	s, err := ParseSchedule("@every 1h")
+	must(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	done := make(chan bool)
+	go func() {
+		RunSchedule(ctx, s, func(ctx context.Context) { runs++ })
+		done <- true
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+	if runs != 1 {
+		t.Errorf("Expected @every to run straight away, ran %d times", runs)
+	}
+}
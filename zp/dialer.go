@@ -0,0 +1,174 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Transport, if set, is what clients from NewClient (and Chain, given no transport)
+// make their requests with, under any middleware. Nil means http.DefaultTransport. Set
+// it to a NewTransport to change how connections are made.
+var Transport http.RoundTripper
+
+// DialOptions change how connections are made, for networks where the defaults don't
+// work well, such as those with unreliable DNS or broken IPv6
+type DialOptions struct {
+	IPv4Only  bool          // Only connect over IPv4
+	DNSServer string        // host:port of a DNS server to use instead of the system's
+	DoHURL    string        // A DNS-over-HTTPS server to look names up with instead, e.g. https://1.1.1.1/dns-query
+	Timeout   time.Duration // How long to wait to connect, defaulting to 30s
+}
+
+// NewTransport makes a transport like http.DefaultTransport, that connects the way the
+// options say
+func NewTransport(opts DialOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = opts.DialContext
+	return t
+}
+
+// DialContext connects to the address, for use as an http.Transport's DialContext
+func (o DialOptions) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if o.IPv4Only {
+		network = strings.TrimSuffix(strings.TrimSuffix(network, "4"), "6") + "4"
+	}
+	d := o.dialer()
+	if o.DoHURL == "" {
+		return d.DialContext(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return d.DialContext(ctx, network, addr)
+	}
+
+	ips, err := o.lookupDoH(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// dialer is a net.Dialer with the options' timeout and DNS server
+func (o DialOptions) dialer() *net.Dialer {
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	d := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if o.DNSServer != "" {
+		d.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: timeout}).DialContext(ctx, network, o.DNSServer)
+			},
+		}
+	}
+	return d
+}
+
+// lookupDoH looks up the host's addresses with the DNS-over-HTTPS server (RFC 8484),
+// IPv4 first. The server itself is reached with the other options.
+func (o DialOptions) lookupDoH(ctx context.Context, host string) ([]net.IP, error) {
+	direct := o
+	direct.DoHURL = ""
+	client := &http.Client{Transport: NewTransport(direct), Timeout: direct.dialer().Timeout}
+
+	types := []dnsmessage.Type{dnsmessage.TypeA}
+	if !o.IPv4Only {
+		types = append(types, dnsmessage.TypeAAAA)
+	}
+
+	var ips []net.IP
+	var lastErr error
+	for _, t := range types {
+		found, err := queryDoH(ctx, client, o.DoHURL, host, t)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ips = append(ips, found...)
+	}
+	if len(ips) == 0 {
+		if lastErr == nil {
+			lastErr = errors.New("no addresses")
+		}
+		return nil, fmt.Errorf("looking up %s with DNS over HTTPS: %v", host, lastErr)
+	}
+	return ips, nil
+}
+
+// queryDoH asks the DNS-over-HTTPS server for one type of record
+func queryDoH(ctx context.Context, client *http.Client, url string, host string, t dnsmessage.Type) ([]net.IP, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	// RFC 8484 asks for an ID of zero, so that responses can be cached
+	query, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: t, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, checkResponse(resp, url)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply dnsmessage.Message
+	err = reply.Unpack(data)
+	if err != nil {
+		return nil, &ParseError{What: "DNS over HTTPS response", Err: err}
+	}
+	if reply.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("%s: %v", host, reply.RCode)
+	}
+
+	var ips []net.IP
+	for _, a := range reply.Answers {
+		switch r := a.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(r.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(r.AAAA[:]))
+		}
+	}
+	return ips, nil
+}
@@ -0,0 +1,78 @@
+package zp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDoHTransport(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello from "+r.Host)
+	}))
+	defer target.Close()
+
+	var queries []string
+	doh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		var q dnsmessage.Message
+		must(t, q.Unpack(data))
+		question := q.Questions[0]
+		queries = append(queries, question.Type.String())
+
+		reply := dnsmessage.Message{Header: dnsmessage.Header{Response: true}, Questions: q.Questions}
+		if question.Name.String() != "zwiftpower.test." {
+			reply.RCode = dnsmessage.RCodeNameError
+		} else if question.Type == dnsmessage.TypeA {
+			reply.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+			}}
+		}
+		out, err := reply.Pack()
+		must(t, err)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(out)
+	}))
+	defer doh.Close()
+
+	port := target.URL[strings.LastIndex(target.URL, ":")+1:]
+	client := &http.Client{Transport: NewTransport(DialOptions{DoHURL: doh.URL, IPv4Only: true})}
+	resp, err := client.Get("http://zwiftpower.test:" + port + "/")
+	must(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello from zwiftpower.test:"+port {
+		t.Errorf("Got %q", body)
+	}
+	if len(queries) != 1 || queries[0] != "TypeA" {
+		t.Errorf("Expected one query for IPv4 addresses, got %v", queries)
+	}
+
+	_, err = client.Get("http://nowhere.test:" + port + "/")
+	if err == nil || !strings.Contains(err.Error(), "nowhere.test") {
+		t.Errorf("Expected an error looking up a missing name, got %v", err)
+	}
+}
+
+func TestTransportVar(t *testing.T) {
+	used := false
+	Transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		used = true
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+	defer func() { Transport = nil }()
+
+	client, err := NewClient()
+	must(t, err)
+	_, err = client.Get("http://zwiftpower.test/")
+	must(t, err)
+	if !used {
+		t.Errorf("Client didn't use Transport")
+	}
+}
@@ -0,0 +1,171 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FieldPrior is how many riders' worth of doubt there is about a placing. Placings in
+// small fields are pulled towards the middle by this much, since finishing ahead of three
+// riders says less than finishing ahead of thirty.
+const FieldPrior = 10
+
+// FieldStrength is how hard it was to place well in one category of an event
+type FieldStrength struct {
+	Category  string
+	Size      int     // Riders who finished in the category
+	WinnerWkg float64 // The category winner's average w/kg, or zero if ZwiftPower hasn't got it
+}
+
+// EventFields works out the strength of the field in each category from an event's results
+func EventFields(results []Event) map[string]FieldStrength {
+	fields := make(map[string]FieldStrength)
+	winners := make(map[string]int)
+	for _, e := range results {
+		pos := e.categoryPosition()
+		if pos <= 0 {
+			continue
+		}
+		f := fields[e.Category]
+		f.Category = e.Category
+		f.Size++
+		if best, ok := winners[e.Category]; !ok || pos < best {
+			winners[e.Category] = pos
+			f.WinnerWkg = e.Wkg()
+		}
+		fields[e.Category] = f
+	}
+	return fields
+}
+
+// categoryPosition is where the rider finished in their category, or overall if
+// ZwiftPower hasn't said
+func (e Event) categoryPosition() int {
+	if e.PositionInCat > 0 {
+		return e.PositionInCat
+	}
+	return e.Pos
+}
+
+// NormalizedPlacing is a rider's placing in a race, adjusted for how strong the field was
+// so that it can be compared with their placings in other races
+type NormalizedPlacing struct {
+	Date       time.Time
+	Zid        string
+	Title      string
+	Position   int // In the category
+	Field      FieldStrength
+	Percentile float64 // Share of the rest of the category they finished ahead of, from 0 to 100
+	Score      float64 // See NormalizePlacings
+}
+
+// NormalizePlacings makes a rider's placings in different races comparable. Each one
+// starts from the share of the rest of the category the rider beat, which is pulled
+// towards the middle for small fields (see FieldPrior), then scaled by how fast the
+// winner was compared with the median winner across these races. The middle of an
+// average field scores 50, and winning a big one scores close to 100, or more if it was
+// faster than usual. Results are keyed by event ID (Zid); races without results, or
+// without the rider in them, are left out. The placings come oldest first.
+func NormalizePlacings(riderID int, events []Event, results map[string][]Event) []NormalizedPlacing {
+	var placings []NormalizedPlacing
+	var winners []float64
+	for _, e := range events {
+		if !e.IsRace() {
+			continue
+		}
+		var mine *Event
+		for i, r := range results[e.Zid] {
+			if r.Zwid == riderID && r.categoryPosition() > 0 {
+				mine = &results[e.Zid][i]
+				break
+			}
+		}
+		if mine == nil {
+			continue
+		}
+
+		field := EventFields(results[e.Zid])[mine.Category]
+		p := NormalizedPlacing{
+			Date:     e.EventDate,
+			Zid:      e.Zid,
+			Title:    e.EventTitle,
+			Position: mine.categoryPosition(),
+			Field:    field,
+		}
+		beaten := field.Size - p.Position
+		if beaten < 0 {
+			beaten = 0
+		}
+		if field.Size > 1 {
+			p.Percentile = 100 * float64(beaten) / float64(field.Size-1)
+		} else {
+			p.Percentile = 100
+		}
+		others := float64(field.Size - 1)
+		p.Score = 50 + (p.Percentile-50)*others/(others+FieldPrior)
+
+		placings = append(placings, p)
+		if field.WinnerWkg > 0 {
+			winners = append(winners, field.WinnerWkg)
+		}
+	}
+
+	if median := median(winners); median > 0 {
+		for i, p := range placings {
+			if p.Field.WinnerWkg > 0 {
+				placings[i].Score *= p.Field.WinnerWkg / median
+			}
+		}
+	}
+
+	sort.SliceStable(placings, func(i, j int) bool {
+		return placings[i].Date.Before(placings[j].Date)
+	})
+	return placings
+}
+
+// AverageScore is the mean Score of the placings, or zero if there aren't any
+func AverageScore(placings []NormalizedPlacing) float64 {
+	if len(placings) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, p := range placings {
+		total += p.Score
+	}
+	return total / float64(len(placings))
+}
+
+// RaceResults gets the results of each of the rider's races since the time, keyed by
+// event ID, for NormalizePlacings. Races ZwiftPower hasn't got results for are left out.
+// Progress is reported to the client's Progress hook after each race.
+func (c *Client) RaceResults(ctx context.Context, events []Event, since time.Time) (map[string][]Event, error) {
+	var races []Event
+	for _, e := range events {
+		if e.IsRace() && e.EventDateSecs != 0 && !e.EventDate.Before(since) {
+			races = append(races, e)
+		}
+	}
+
+	results := make(map[string][]Event)
+	for i, e := range races {
+		id, err := strconv.Atoi(e.Zid)
+		if err != nil {
+			continue
+		}
+		r, err := c.EventResults(ctx, id)
+		if IsNotFound(err) {
+			log.Printf("No results for event %d: %v", id, err)
+		} else if err != nil {
+			return results, fmt.Errorf("getting results for event %d: %w", id, err)
+		} else {
+			results[e.Zid] = r
+		}
+		c.Progress.report(i+1, len(races), e.EventTitle)
+	}
+	return results, nil
+}
@@ -0,0 +1,114 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// categoryResults makes the results for one category of an event, with the rider in the position
+func categoryResults(zid string, category string, size int, riderID int, pos int, winnerWkg float64) []Event {
+	var results []Event
+	for i := 1; i <= size; i++ {
+		e := Event{Zid: zid, Zwid: 1000 + i, Category: category, PositionInCat: i, AvgWkg: fmt.Sprint(winnerWkg - 0.1*float64(i-1))}
+		if i == pos {
+			e.Zwid = riderID
+		}
+		results = append(results, e)
+	}
+	return results
+}
+
+func TestEventFields(t *testing.T) {
+	results := append(categoryResults("1", "A", 5, 1, 2, 4.5), categoryResults("1", "B", 3, 2, 1, 3.8)...)
+	results = append(results, Event{Zwid: 9, Category: "B"}) // Didn't finish
+	fields := EventFields(results)
+	if len(fields) != 2 || fields["A"] != (FieldStrength{"A", 5, 4.5}) || fields["B"] != (FieldStrength{"B", 3, 3.8}) {
+		t.Errorf("Got fields %v", fields)
+	}
+}
+
+func TestNormalizePlacings(t *testing.T) {
+	day := time.Date(2021, 3, 1, 18, 0, 0, 0, time.UTC)
+	event := func(zid string, days int, eventType string) Event {
+		d := day.AddDate(0, 0, days)
+		return Event{Zid: zid, EventType: eventType, EventTitle: "Race " + zid, EventDateSecs: EventDateType(d.Unix()), EventDate: d}
+	}
+	events := []Event{
+		event("3", 2, "TYPE_RACE"),
+		event("1", 0, "TYPE_RACE"),
+		event("2", 1, "TYPE_RACE"),
+		event("4", 3, "TYPE_RIDE"),
+		event("5", 4, "TYPE_RACE"), // No results
+	}
+	results := map[string][]Event{
+		"1": categoryResults("1", "B", 21, 7, 1, 4.0),
+		"2": categoryResults("2", "B", 3, 7, 1, 4.0),
+		"3": append(categoryResults("3", "B", 11, 7, 6, 4.4), categoryResults("3", "A", 30, 8, 1, 5.0)...),
+		"4": categoryResults("4", "B", 5, 7, 1, 3.0),
+	}
+
+	placings := NormalizePlacings(7, events, results)
+	expected := []struct {
+		zid        string
+		position   int
+		size       int
+		percentile float64
+		score      float64
+	}{
+		{"1", 1, 21, 100, 50 + 50*20.0/30},
+		{"2", 1, 3, 100, 50 + 50*2.0/12},
+		{"3", 6, 11, 50, 50 * 4.4 / 4.0},
+	}
+	if len(placings) != len(expected) {
+		t.Fatalf("Got %d placings, expected %d: %+v", len(placings), len(expected), placings)
+	}
+	for i, e := range expected {
+		p := placings[i]
+		if p.Zid != e.zid || p.Position != e.position || p.Field.Size != e.size || p.Percentile != e.percentile || math.Abs(p.Score-e.score) > 0.001 {
+			t.Errorf("Placing %d: got %+v, expected %+v", i, p, e)
+		}
+	}
+
+	// Winning a big field beats winning a small one
+	if placings[0].Score <= placings[1].Score {
+		t.Errorf("Expected the bigger field to score more")
+	}
+
+	avg := AverageScore(placings)
+	if math.Abs(avg-(placings[0].Score+placings[1].Score+placings[2].Score)/3) > 0.001 || AverageScore(nil) != 0 {
+		t.Errorf("Got average score %.2f", avg)
+	}
+}
+
+func TestRaceResults(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/results/1_view.json":
+			fmt.Fprint(w, `{"data": [{"zid": "1", "zwid": 7, "position_in_cat": 1, "category": "B"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	day := time.Date(2021, 3, 1, 18, 0, 0, 0, time.UTC)
+	var events []Event
+	for i, days := range []int{0, 1, -30} {
+		d := day.AddDate(0, 0, days)
+		events = append(events, Event{Zid: strconv.Itoa(i + 1), EventType: "TYPE_RACE", EventDateSecs: EventDateType(d.Unix()), EventDate: d})
+	}
+
+	client, err := New()
+	must(t, err)
+	var progress int
+	client.Progress = func(done, total int, current string) { progress = done }
+	results, err := client.RaceResults(context.Background(), events, day.AddDate(0, 0, -7))
+	must(t, err)
+	if len(results) != 1 || len(results["1"]) != 1 || progress != 2 {
+		t.Errorf("Got results %v after %d races", results, progress)
+	}
+}
@@ -0,0 +1,75 @@
+package zp
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+)
+
+// DefaultDigestTemplate is used to write digests unless the caller supplies their own
+const DefaultDigestTemplate = `Club results from {{.Since.Format "Monday 2 January"}} to {{.Until.Format "Monday 2 January"}}
+{{range .Events}}
+{{.Title}}{{if not .Date.IsZero}} ({{.Date.Format "Mon 2 Jan"}}){{end}}: {{placings .Results}}
+{{- end}}
+{{with .Milestones}}
+Milestones
+{{range .}}
+{{.Name}} {{.Text}}
+{{- end}}
+{{end}}
+{{- with .Goals}}
+Goals
+{{range .}}
+{{.Name}}, {{.Describe}}: {{.Summary}}
+{{- end}}
+{{end}}
+{{- with .Fun}}
+Fun stats
+{{range .}}
+{{.Title}}: {{.Name}} {{.Text}}
+{{- end}}
+{{end}}`
+
+// Digest is a round-up of how everyone in the club did over a period
+type Digest struct {
+	ClubID     int
+	Since      time.Time
+	Until      time.Time
+	Events     []EventResults
+	Milestones []Milestone
+	Fun        []FunStat      // Only filled in if asked for
+	Goals      []GoalProgress // Progress with any goals riders have set
+}
+
+// NewDigest collects the club's results and milestones from this time onwards. If fun
+// is set it also hands out fun stats. For both, events should include earlier history
+// too.
+func NewDigest(clubID int, events []Event, since time.Time, until time.Time, fun bool) Digest {
+	d := Digest{
+		ClubID:     clubID,
+		Since:      since,
+		Until:      until,
+		Events:     GroupByEvent(events, since),
+		Milestones: Milestones(events, since),
+	}
+	if fun {
+		d.Fun = FunStats(events, since)
+	}
+	return d
+}
+
+// WriteDigest renders the digest using the template text. If tmpl is empty the
+// DefaultDigestTemplate is used.
+func WriteDigest(w io.Writer, d Digest, tmpl string) error {
+	if tmpl == "" {
+		tmpl = DefaultDigestTemplate
+	}
+
+	t, err := template.New("digest").Funcs(reportFuncs).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parsing digest template: %v", err)
+	}
+
+	return t.Execute(w, d)
+}
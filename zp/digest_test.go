@@ -0,0 +1,51 @@
+package zp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteDigest(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	// Dates in the output shouldn't depend on where the test runs
+	for i := range events {
+		events[i].EventDate = events[i].EventDate.UTC()
+	}
+
+	since := time.Unix(1610505900, 0).UTC()
+	until := time.Unix(1612320300, 0).UTC().AddDate(0, 0, 1)
+	for _, fun := range []bool{false, true} {
+		d := NewDigest(2672, events, since, until, fun)
+		if len(d.Events) != 2 {
+			t.Errorf("Got %d events", len(d.Events))
+		}
+
+		var b bytes.Buffer
+		err = WriteDigest(&b, d, "")
+		if err != nil {
+			t.Fatalf("Failed writing digest: %v", err)
+		}
+
+		out := b.String()
+		if !strings.HasPrefix(out, "Club results from Wednesday 13 January to Thursday 4 February") {
+			t.Errorf("Unexpected heading in %q", out)
+		}
+		if !strings.Contains(out, "WTRL - Womens AMERICAS W DIVISION 1 (Wed 3 Feb): Özge Yazar [REVO] (9th)") {
+			t.Errorf("Missing result in %q", out)
+		}
+		if strings.Contains(out, "Fun stats") != fun {
+			t.Errorf("Fun stats should be there only if asked for: %q", out)
+		}
+	}
+
+	err = WriteDigest(&bytes.Buffer{}, Digest{}, "{{.Nope")
+	if err == nil {
+		t.Errorf("Expected error for bad template")
+	}
+}
@@ -0,0 +1,78 @@
+package zp
+
+import "strings"
+
+// Placing is one of a rider's result rows in an event, such as their pen result or the
+// overall result
+type Placing struct {
+	Category      string `json:"category"`
+	Pos           int    `json:"pos"`
+	PositionInCat int    `json:"position_in_cat"`
+}
+
+// penCategories are the categories riders are placed in by pen. Anything else, such as
+// E for everyone together, is treated as an overall result.
+var penCategories = map[string]bool{"A+": true, "A": true, "B": true, "C": true, "D": true}
+
+// CanonicalResults reconciles results where a rider has more than one row for the same
+// event, such as a pen result and an overall result in a dual category event, so that
+// each rider has one result per event and isn't counted twice. The pen result is kept,
+// or failing that the first row with a finishing position, and Placings lists where the
+// rider came in every row. Everything else passes through unchanged, in order.
+func CanonicalResults(results []Event) []Event {
+	index := make(map[eventKey]int, len(results))
+	var merged []Event
+	for _, e := range results {
+		if e.Zwid == 0 {
+			// Without an ID we can't tell riders apart
+			merged = append(merged, e)
+			continue
+		}
+		k := eventKey{e.Zwid, e.Zid}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(merged)
+			merged = append(merged, e)
+			continue
+		}
+
+		c := &merged[i]
+		if len(c.Placings) == 0 {
+			c.Placings = []Placing{c.placing()}
+		}
+		placings := append(c.Placings, e.placing())
+		if betterResult(e, *c) {
+			*c = e
+		}
+		c.Placings = placings
+	}
+	return merged
+}
+
+func (e Event) placing() Placing {
+	return Placing{Category: strings.ToUpper(e.Category), Pos: e.Pos, PositionInCat: e.PositionInCat}
+}
+
+// betterResult is true if a should be the canonical result rather than b
+func betterResult(a Event, b Event) bool {
+	aPen, bPen := penCategories[strings.ToUpper(a.Category)], penCategories[strings.ToUpper(b.Category)]
+	if aPen != bPen {
+		return aPen
+	}
+	return a.Pos > 0 && b.Pos <= 0
+}
+
+// PlacingIn finds where the rider came in this category, from the canonical result or
+// any of the other rows merged into it
+func (e Event) PlacingIn(category string) (Placing, bool) {
+	category = strings.ToUpper(category)
+	for _, p := range e.Placings {
+		if p.Category == category {
+			return p, true
+		}
+	}
+	if p := e.placing(); p.Category == category {
+		return p, true
+	}
+	return Placing{}, false
+}
@@ -0,0 +1,71 @@
+package zp
+
+import "testing"
+
+func TestCanonicalResults(t *testing.T) {
+	results := []Event{
+		{Zwid: 1, Zid: "10", Name: "Ann", Category: "E", Pos: 3, PositionInCat: 3},
+		{Zwid: 2, Zid: "10", Name: "Bob", Category: "A", Pos: 1, PositionInCat: 1},
+		{Zwid: 1, Zid: "10", Name: "Ann", Category: "b", Pos: 5, PositionInCat: 1},
+		{Zwid: 3, Zid: "10", Name: "Cat", Category: "C", Pos: 0},
+		{Zwid: 3, Zid: "10", Name: "Cat", Category: "C", Pos: 7, PositionInCat: 2},
+		{Zwid: 1, Zid: "11", Name: "Ann", Category: "B", Pos: 2, PositionInCat: 2},
+	}
+
+	merged := CanonicalResults(results)
+	if len(merged) != 4 {
+		t.Fatalf("Expected 4 results, got %d: %v", len(merged), merged)
+	}
+
+	ann := merged[0]
+	if ann.Zwid != 1 || ann.Category != "b" || ann.Pos != 5 || len(ann.Placings) != 2 {
+		t.Errorf("Expected Ann's pen result with both placings, got %+v", ann)
+	}
+	if p, ok := ann.PlacingIn("E"); !ok || p.Pos != 3 {
+		t.Errorf("Expected Ann 3rd overall, got %+v %v", p, ok)
+	}
+	if p, ok := ann.PlacingIn("B"); !ok || p.PositionInCat != 1 {
+		t.Errorf("Expected Ann 1st in B, got %+v %v", p, ok)
+	}
+	if _, ok := ann.PlacingIn("A"); ok {
+		t.Errorf("Didn't expect Ann to be placed in A")
+	}
+
+	if merged[1].Zwid != 2 || merged[1].Placings != nil {
+		t.Errorf("Expected Bob's single result unchanged, got %+v", merged[1])
+	}
+	if merged[2].Zwid != 3 || merged[2].Pos != 7 {
+		t.Errorf("Expected Cat's finishing result, got %+v", merged[2])
+	}
+	if merged[3].Zid != "11" || merged[3].Placings != nil {
+		t.Errorf("Expected Ann's other event to stay separate, got %+v", merged[3])
+	}
+	if p, ok := merged[3].PlacingIn("b"); !ok || p.Pos != 2 {
+		t.Errorf("Expected placing from a single result, got %+v %v", p, ok)
+	}
+
+	again := CanonicalResults(merged)
+	if len(again) != 4 || len(again[0].Placings) != 2 {
+		t.Errorf("Merging again changed the results: %v", again)
+	}
+}
+
+func TestTablesDualCategory(t *testing.T) {
+	l := testLeague()
+	results := map[int][]Event{
+		1: {
+			{Zwid: 1, Zid: "1", Name: "Ann", Category: "E", TeamID: "2672", Pos: 1},
+			{Zwid: 1, Zid: "1", Name: "Ann", Category: "A", TeamID: "2672", Pos: 1},
+			{Zwid: 2, Zid: "1", Name: "Bob", Category: "B", TeamID: "2672", Pos: 2},
+		},
+	}
+
+	tables := l.Tables(results)
+	if len(tables) == 0 || tables[0].Division != "Premier" {
+		t.Fatalf("Expected a Premier table, got %v", tables)
+	}
+	standings := tables[0].Standings
+	if len(standings) != 2 || standings[0].Name != "Ann" || standings[0].Fixtures != 1 {
+		t.Errorf("Expected Ann and Bob once each, got %v", standings)
+	}
+}
@@ -0,0 +1,100 @@
+package zp
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	efficiencyDays        = 42               // Days of events in the rolling efficiency trend
+	minEfficiencyDuration = 20 * time.Minute // Shorter efforts are too dominated by HR lag
+	minPlausibleHR        = 60
+	maxPlausibleHR        = 230
+)
+
+// Efficiency is the rider's aerobic efficiency in one event, and how it's trending. As
+// fitness improves, the same heart rate drives more power, so a rising trend is a sign
+// of fitness that doesn't need an FTP test.
+type Efficiency struct {
+	Date       time.Time
+	Zid        string
+	Title      string
+	Power      float64 // NP, or average power if there's no NP
+	HR         float64 // Average heart rate
+	Efficiency float64 // Watts per heartbeat per minute
+	Trend      float64 // Average efficiency over the events in the last efficiencyDays
+	Events     int     // Events in the trend
+}
+
+// AerobicEfficiency is the event's power divided by heart rate. It's false if the event
+// has no heart rate, or is too short to say much.
+func (e Event) AerobicEfficiency() (power float64, hr float64, ef float64, ok bool) {
+	power = float64(e.NP)
+	if power <= 0 {
+		power = float64(e.AvgPower)
+	}
+	hr = float64(e.AvgHR)
+
+	if power <= 0 || hr < minPlausibleHR || hr > maxPlausibleHR || e.EventDuration() < minEfficiencyDuration {
+		return power, hr, 0, false
+	}
+	return power, hr, power / hr, true
+}
+
+// EfficiencyTrend works out the aerobic efficiency for each of the rider's events that
+// has heart rate data, oldest first, with the rolling average up to that event
+func EfficiencyTrend(events []Event) []Efficiency {
+	var trend []Efficiency
+	for _, e := range events {
+		power, hr, ef, ok := e.AerobicEfficiency()
+		if !ok || e.EventDateSecs == 0 {
+			continue
+		}
+		trend = append(trend, Efficiency{
+			Date:       e.EventDate,
+			Zid:        e.Zid,
+			Title:      e.EventTitle,
+			Power:      power,
+			HR:         hr,
+			Efficiency: ef,
+		})
+	}
+
+	sort.SliceStable(trend, func(i, j int) bool {
+		return trend[i].Date.Before(trend[j].Date)
+	})
+
+	start := 0
+	total := 0.0
+	for i := range trend {
+		total += trend[i].Efficiency
+		for trend[start].Date.Before(trend[i].Date.AddDate(0, 0, -efficiencyDays)) {
+			total -= trend[start].Efficiency
+			start++
+		}
+		trend[i].Events = i - start + 1
+		trend[i].Trend = total / float64(trend[i].Events)
+	}
+	return trend
+}
+
+// EfficiencyChange is how much the rider's efficiency trend has changed over these
+// days up to the latest event, as a percentage. It's false if there isn't an event with
+// heart rate from that long ago to compare with.
+func EfficiencyChange(trend []Efficiency, days int) (float64, bool) {
+	if len(trend) == 0 {
+		return 0, false
+	}
+	latest := trend[len(trend)-1]
+	since := latest.Date.AddDate(0, 0, -days)
+
+	// The last point at or before the start of the period
+	i := sort.Search(len(trend), func(i int) bool {
+		return trend[i].Date.After(since)
+	})
+	if i == 0 {
+		return 0, false
+	}
+	before := trend[i-1]
+	return 100 * (latest.Trend - before.Trend) / before.Trend, true
+}
@@ -0,0 +1,85 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAerobicEfficiency(t *testing.T) {
+	e := Event{NP: 200, AvgPower: 180, AvgHR: 160, Time: 3600}
+	power, hr, ef, ok := e.AerobicEfficiency()
+	if !ok || power != 200 || hr != 160 || ef != 1.25 {
+		t.Errorf("Got %v, %v, %v, %v", power, hr, ef, ok)
+	}
+
+	e.NP = 0
+	if _, _, ef, _ := e.AerobicEfficiency(); ef != 180.0/160 {
+		t.Errorf("Expected average power without NP, got %v", ef)
+	}
+
+	for name, e := range map[string]Event{
+		"no HR":     {AvgPower: 200, Time: 3600},
+		"silly HR":  {AvgPower: 200, AvgHR: 20, Time: 3600},
+		"no power":  {AvgHR: 150, Time: 3600},
+		"too short": {AvgPower: 200, AvgHR: 150, Time: 600},
+	} {
+		if _, _, _, ok := e.AerobicEfficiency(); ok {
+			t.Errorf("%s: expected no efficiency", name)
+		}
+	}
+}
+
+func TestEfficiencyTrend(t *testing.T) {
+	day := func(d int) (EventDateType, time.Time) {
+		date := time.Date(2021, 1, 1, 18, 0, 0, 0, time.UTC).AddDate(0, 0, d)
+		return EventDateType(date.Unix()), date
+	}
+	event := func(d int, power Number, hr Number) Event {
+		secs, date := day(d)
+		return Event{EventDateSecs: secs, EventDate: date, AvgPower: power, AvgHR: hr, Time: 3600}
+	}
+
+	events := []Event{
+		event(50, 240, 150), // 1.6, out of order
+		event(0, 150, 150),  // 1.0
+		event(10, 180, 150), // 1.2
+		event(20, 200, 150), // no HR below
+		event(30, 210, 150), // 1.4
+	}
+	events[3].AvgHR = 0
+
+	trend := EfficiencyTrend(events)
+	if len(trend) != 4 {
+		t.Fatalf("Got %d points, expected 4", len(trend))
+	}
+
+	expected := []struct {
+		ef, trend float64
+		n         int
+	}{
+		{1.0, 1.0, 1},
+		{1.2, 1.1, 2},
+		{1.4, 1.2, 3},
+		{1.6, 1.4, 3}, // Day 0 is more than 42 days before day 50
+	}
+	for i, x := range expected {
+		p := trend[i]
+		if math.Abs(p.Efficiency-x.ef) > 1e-9 || math.Abs(p.Trend-x.trend) > 1e-9 || p.Events != x.n {
+			t.Errorf("Point %d: got %v, %v from %d events, expected %v", i, p.Efficiency, p.Trend, p.Events, x)
+		}
+	}
+
+	change, ok := EfficiencyChange(trend, 30)
+	// Day 50 trend 1.4 against day 10 trend 1.1
+	if !ok || math.Abs(change-100*0.3/1.1) > 1e-9 {
+		t.Errorf("Got change %v, %v", change, ok)
+	}
+
+	if _, ok := EfficiencyChange(trend, 60); ok {
+		t.Errorf("Expected no change without an event 60 days before")
+	}
+	if _, ok := EfficiencyChange(nil, 30); ok {
+		t.Errorf("Expected no change without events")
+	}
+}
@@ -0,0 +1,115 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CEPaceGroups are the categories of Zwift's Category Enforcement, fastest first. Zwift
+// works them out itself, and events that use them only let riders into the pen for their
+// own category or a faster one. They don't always agree with ZwiftPower's categories,
+// which go by results in ZwiftPower events.
+var CEPaceGroups = []string{"A+", "A", "B", "C", "D", "E"}
+
+// CategoryEnforcement is the category Zwift puts a rider in for Category Enforcement
+// events
+type CategoryEnforcement struct {
+	Category      string  // One of CEPaceGroups
+	CategoryWomen string  // For women-only events, if Zwift gives one
+	RacingScore   float64 // Zwift Racing Score, which the categories go by
+}
+
+// zwiftCompetition is the part of a Zwift profile with Category Enforcement in it
+type zwiftCompetition struct {
+	RacingScore   float64 `json:"racingScore"`
+	Category      string  `json:"category"`
+	CategoryWomen string  `json:"categoryWomen"`
+}
+
+// enforcement reads the Category Enforcement from a Zwift profile. It's false if the
+// profile hasn't got a category we recognise.
+func (p zwiftProfile) enforcement() (CategoryEnforcement, bool) {
+	if p.CompetitionMetrics == nil {
+		return CategoryEnforcement{}, false
+	}
+	category, err := ParseCECategory(p.CompetitionMetrics.Category)
+	if err != nil || category == "" {
+		return CategoryEnforcement{}, false
+	}
+	women, _ := ParseCECategory(p.CompetitionMetrics.CategoryWomen)
+	return CategoryEnforcement{Category: category, CategoryWomen: women, RacingScore: p.CompetitionMetrics.RacingScore}, true
+}
+
+// ParseCECategory reads a Category Enforcement category such as "a+" or " B ", giving it
+// as it is in CEPaceGroups. It's empty if s is.
+func ParseCECategory(s string) (string, error) {
+	s = strings.ToUpper(strings.Replace(s, " ", "", -1))
+	if s == "" {
+		return "", nil
+	}
+	if ceIndex(s) < 0 {
+		return "", fmt.Errorf("unknown Category Enforcement category %q: expected one of %s", s, strings.Join(CEPaceGroups, ", "))
+	}
+	return s, nil
+}
+
+// CECanEnter is true if a rider in this Category Enforcement category can race in the pen,
+// which is for their own category or a faster one. Riders without a category can't enter
+// any pen.
+func CECanEnter(category string, pen string) bool {
+	c, p := ceIndex(category), ceIndex(pen)
+	return c >= 0 && p >= 0 && p <= c
+}
+
+func ceIndex(category string) int {
+	for i, c := range CEPaceGroups {
+		if c == category {
+			return i
+		}
+	}
+	return -1
+}
+
+// ImportCategoryEnforcement gets the rider's Category Enforcement category from their
+// Zwift profile, using this access token. It's ErrNotFound if Zwift hasn't given them one,
+// which happens until they've done enough racing.
+func ImportCategoryEnforcement(ctx context.Context, client *http.Client, token string, riderID int) (ce CategoryEnforcement, err error) {
+	ctx, span := startSpan(ctx, "ImportCategoryEnforcement", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	var p zwiftProfile
+	err = getZwiftJSON(ctx, client, token, fmt.Sprintf(ZwiftProfileURL, riderID), "Zwift profile", &p)
+	if err != nil {
+		return ce, err
+	}
+	ce, ok := p.enforcement()
+	if !ok {
+		return ce, fmt.Errorf("no Category Enforcement category for rider %d: %w", riderID, ErrNotFound)
+	}
+	return ce, nil
+}
+
+// AddZwiftProfile fills in the rider's Avatar and CECategory from their Zwift profile,
+// using this access token, in one request
+func AddZwiftProfile(ctx context.Context, client *http.Client, token string, r *Rider) (err error) {
+	ctx, span := startSpan(ctx, "AddZwiftProfile", attribute.Int("zwiftpower.rider_id", r.Zwid))
+	defer func() { endSpan(span, err) }()
+
+	var p zwiftProfile
+	err = getZwiftJSON(ctx, client, token, fmt.Sprintf(ZwiftProfileURL, r.Zwid), "Zwift profile", &p)
+	if err != nil {
+		return err
+	}
+	r.Avatar = p.ImageSrcLarge
+	if r.Avatar == "" {
+		r.Avatar = p.ImageSrc
+	}
+	if ce, ok := p.enforcement(); ok {
+		r.CECategory = ce.Category
+	}
+	return nil
+}
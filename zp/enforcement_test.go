@@ -0,0 +1,94 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCECategory(t *testing.T) {
+	cases := map[string]string{
+		"a+":  "A+",
+		" B ": "B",
+		"A +": "A+",
+		"e":   "E",
+		"":    "",
+	}
+	for s, expected := range cases {
+		c, err := ParseCECategory(s)
+		if err != nil || c != expected {
+			t.Errorf("Got %q, %v for %q, expected %q", c, err, s, expected)
+		}
+	}
+	for _, s := range []string{"F", "B+", "3"} {
+		if _, err := ParseCECategory(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestCECanEnter(t *testing.T) {
+	cases := []struct {
+		category, pen string
+		expected      bool
+	}{
+		{"B", "B", true},
+		{"B", "A", true},
+		{"B", "A+", true},
+		{"B", "C", false},
+		{"E", "D", true},
+		{"A+", "A", false},
+		{"", "E", false},
+		{"C", "", false},
+	}
+	for _, c := range cases {
+		if CECanEnter(c.category, c.pen) != c.expected {
+			t.Errorf("CECanEnter(%q, %q) should be %v", c.category, c.pen, c.expected)
+		}
+	}
+}
+
+func TestImportCategoryEnforcement(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/profiles/1":
+			fmt.Fprint(w, `{"id":1,"imageSrc":"https://example.com/1","competitionMetrics":{"racingScore":523.4,"category":"a+","categoryWomen":"A"}}`)
+		case "/api/profiles/2":
+			fmt.Fprint(w, `{"id":2,"competitionMetrics":{"racingScore":0,"category":""}}`)
+		case "/api/profiles/3":
+			fmt.Fprint(w, `{"id":3}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	oldURL := ZwiftProfileURL
+	ZwiftProfileURL = ts.URL + "/api/profiles/%d"
+	defer func() { ZwiftProfileURL = oldURL }()
+
+	ctx := context.Background()
+	ce, err := ImportCategoryEnforcement(ctx, http.DefaultClient, "token", 1)
+	must(t, err)
+	if ce != (CategoryEnforcement{Category: "A+", CategoryWomen: "A", RacingScore: 523.4}) {
+		t.Errorf("Got %+v", ce)
+	}
+	for _, id := range []int{2, 3, 4} {
+		if _, err := ImportCategoryEnforcement(ctx, http.DefaultClient, "token", id); !IsNotFound(err) {
+			t.Errorf("Expected not found for %d, got %v", id, err)
+		}
+	}
+
+	r := Rider{Zwid: 1}
+	must(t, AddZwiftProfile(ctx, http.DefaultClient, "token", &r))
+	if r.Avatar != "https://example.com/1" || r.CECategory != "A+" {
+		t.Errorf("Got avatar %q and CE category %q", r.Avatar, r.CECategory)
+	}
+	r = Rider{Zwid: 3}
+	must(t, AddZwiftProfile(ctx, http.DefaultClient, "token", &r))
+	if r.CECategory != "" {
+		t.Errorf("Expected no CE category, got %q", r.CECategory)
+	}
+}
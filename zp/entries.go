@@ -0,0 +1,74 @@
+package zp
+
+import (
+	"sort"
+	"strings"
+)
+
+// EntryCheck compares who signed up for an event with who has a result in it
+type EntryCheck struct {
+	EventID      int
+	HasResults   bool    // Without results, there's nothing to check the signups against yet
+	Finished     []Event // Signed up and have a result, from the results
+	NoShows      []Event // Signed up but have no result, from the signups
+	Unregistered []Event // Have a result but didn't sign up, from the results
+}
+
+// CheckEntries matches an event's signups to its results. With a team ID, only riders
+// from that team in either list are checked; an empty team ID checks the whole field.
+// Each list is in order of name.
+func CheckEntries(eventID int, signups []Event, results []Event, teamID string) EntryCheck {
+	c := EntryCheck{EventID: eventID, HasResults: len(results) > 0}
+
+	signedUp := make(map[int]bool)
+	for _, e := range signups {
+		signedUp[e.Zwid] = true
+	}
+	finished := make(map[int]bool)
+	for _, e := range results {
+		finished[e.Zwid] = true
+	}
+
+	// A rider who changed team between signing up and racing counts if either says so
+	inTeam := make(map[int]bool)
+	for _, ee := range [][]Event{signups, results} {
+		for _, e := range ee {
+			if teamID == "" || e.TeamID == teamID {
+				inTeam[e.Zwid] = true
+			}
+		}
+	}
+
+	seen := make(map[int]bool)
+	for _, e := range results {
+		if !inTeam[e.Zwid] || seen[e.Zwid] {
+			continue
+		}
+		seen[e.Zwid] = true
+		if signedUp[e.Zwid] {
+			c.Finished = append(c.Finished, e)
+		} else {
+			c.Unregistered = append(c.Unregistered, e)
+		}
+	}
+	if c.HasResults {
+		for _, e := range signups {
+			if !inTeam[e.Zwid] || seen[e.Zwid] || finished[e.Zwid] {
+				continue
+			}
+			seen[e.Zwid] = true
+			c.NoShows = append(c.NoShows, e)
+		}
+	}
+
+	for _, ee := range [][]Event{c.Finished, c.NoShows, c.Unregistered} {
+		sortByName(ee)
+	}
+	return c
+}
+
+func sortByName(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return strings.ToLower(events[i].RiderName()) < strings.ToLower(events[j].RiderName())
+	})
+}
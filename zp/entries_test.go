@@ -0,0 +1,43 @@
+package zp
+
+import "testing"
+
+func TestCheckEntries(t *testing.T) {
+	signups := []Event{
+		{Zwid: 1, Name: "Ann", TeamID: "2672"},
+		{Zwid: 2, Name: "bob", TeamID: "2672"},
+		{Zwid: 3, Name: "Cat", TeamID: "2672"},
+		{Zwid: 4, Name: "Dan", TeamID: "1234"},
+		{Zwid: 5, Name: "Eve", TeamID: "1234"},
+	}
+	results := []Event{
+		{Zwid: 1, Name: "Ann", TeamID: "2672", Pos: 2},
+		{Zwid: 6, Name: "Fay", TeamID: "2672", Pos: 1},
+		{Zwid: 4, Name: "Dan", TeamID: "2672", Pos: 3}, // Joined the club since signing up
+		{Zwid: 7, Name: "Gus", TeamID: "1234", Pos: 4},
+	}
+
+	names := func(events []Event) string {
+		var s string
+		for _, e := range events {
+			s += e.Name + " "
+		}
+		return s
+	}
+
+	c := CheckEntries(123, signups, results, "2672")
+	if !c.HasResults || names(c.Finished) != "Ann Dan " || names(c.NoShows) != "bob Cat " || names(c.Unregistered) != "Fay " {
+		t.Errorf("Got finished %s, no shows %s, unregistered %s", names(c.Finished), names(c.NoShows), names(c.Unregistered))
+	}
+
+	c = CheckEntries(123, signups, results, "")
+	if len(c.Finished) != 2 || names(c.NoShows) != "bob Cat Eve " || names(c.Unregistered) != "Fay Gus " {
+		t.Errorf("Got finished %s, no shows %s, unregistered %s for the whole field", names(c.Finished), names(c.NoShows), names(c.Unregistered))
+	}
+
+	// Before the results are in, nobody is a no show
+	c = CheckEntries(123, signups, nil, "2672")
+	if c.HasResults || len(c.NoShows) != 0 || len(c.Finished) != 0 {
+		t.Errorf("Got %+v without results", c)
+	}
+}
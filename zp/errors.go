@@ -0,0 +1,188 @@
+package zp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Errors for the main ways that importing can fail. Errors from this package wrap
+// them, so callers can check with errors.Is rather than matching on the message.
+// ErrNotFound (see Store) is also used for things ZwiftPower hasn't got.
+var (
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrParse        = errors.New("can't parse response")
+	ErrChallenged   = errors.New("challenged by bot protection")
+	ErrPartial      = errors.New("partial failure")
+	ErrMaintenance  = errors.New("down for maintenance")
+)
+
+// StatusError is an HTTP response other than 200 OK. errors.Is matches it against
+// ErrNotFound, ErrRateLimited, ErrUnauthorized, ErrChallenged or ErrMaintenance depending
+// on the status.
+type StatusError struct {
+	URL         string
+	StatusCode  int
+	RetryAfter  time.Duration // How long the server asked us to wait, if it said
+	Challenged  bool          // The response was a bot check, such as Cloudflare's
+	Maintenance bool          // ZwiftPower is down for maintenance, or unreachable behind its proxy
+}
+
+func (e *StatusError) Error() string {
+	if e.Challenged {
+		return fmt.Sprintf("bot check (status %d) for %s", e.StatusCode, e.URL)
+	}
+	if e.Maintenance {
+		return fmt.Sprintf("down for maintenance (status %d) for %s", e.StatusCode, e.URL)
+	}
+	return fmt.Sprintf("unexpected status %d for %s", e.StatusCode, e.URL)
+}
+
+// Is lets errors.Is match the status against our sentinel errors
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrChallenged:
+		return e.Challenged
+	case ErrMaintenance:
+		return e.Maintenance
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnauthorized:
+		return !e.Challenged && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+	}
+	return false
+}
+
+// ParseError is a response we couldn't make sense of. errors.Is(err, ErrParse) is true
+// for it, and errors.As can get at the underlying error from encoding/json.
+type ParseError struct {
+	What string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unmarshalling %s: %v", e.What, e.Err)
+}
+
+// Unwrap gives the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrParse) true
+func (e *ParseError) Is(target error) bool {
+	return target == ErrParse
+}
+
+// notStoredError is a file missing from the store. errors.Is matches it against
+// ErrNotFound as well as os.ErrNotExist.
+type notStoredError struct {
+	err error
+}
+
+func (e *notStoredError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap gives the error from opening the file
+func (e *notStoredError) Unwrap() error {
+	return e.err
+}
+
+// Is makes errors.Is(err, ErrNotFound) true
+func (e *notStoredError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// PartialError is a batch of work, such as a backfill, where some of it failed but the rest
+// was done. errors.Is(err, ErrPartial) is true for it.
+type PartialError struct {
+	Failed int
+	Total  int
+	Err    error // Says what failed
+}
+
+func (e *PartialError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap gives the error saying what failed
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrPartial) true
+func (e *PartialError) Is(target error) bool {
+	return target == ErrPartial
+}
+
+// partial is a *PartialError if only some of the batch failed, or just err if all of it did
+func partial(failed, total int, err error) error {
+	if failed < total {
+		return &PartialError{Failed: failed, Total: total, Err: err}
+	}
+	return err
+}
+
+// checkResponse turns a response other than 200 OK into a *StatusError
+func checkResponse(resp *http.Response, url string) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	e := &StatusError{URL: url, StatusCode: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	start, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	e.Challenged = isChallenge(resp.Header, start)
+	e.Maintenance = !e.Challenged && (isOutage(resp.StatusCode) || isMaintenancePage(start))
+	return e
+}
+
+// isOutage is true for the statuses we get when ZwiftPower is down and its proxy answers
+// instead
+func isOutage(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// maintenancePhrases are how ZwiftPower's maintenance pages say so
+var maintenancePhrases = [][]byte{
+	[]byte("down for maintenance"),
+	[]byte("undergoing maintenance"),
+	[]byte("scheduled maintenance"),
+	[]byte("maintenance mode"),
+	[]byte("<title>maintenance"),
+}
+
+// isMaintenancePage spots the HTML page ZwiftPower sends, sometimes with 200 OK, while
+// it's down for maintenance
+func isMaintenancePage(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '<' {
+		return false
+	}
+	lower := bytes.ToLower(body)
+	for _, phrase := range maintenancePhrases {
+		if bytes.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// isChallenge spots the pages bot protection sends instead of what we asked for
+func isChallenge(header http.Header, body []byte) bool {
+	if header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	return bytes.Contains(body, []byte("challenge-platform")) || bytes.Contains(body, []byte("<title>Just a moment"))
+}
@@ -0,0 +1,99 @@
+package zp
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestErrors(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/results/1_view.json":
+			w.WriteHeader(http.StatusNotFound)
+		case "/cache3/results/2_view.json":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/cache3/results/3_view.json":
+			w.WriteHeader(http.StatusForbidden)
+		case "/cache3/results/4_view.json":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<html><head><title>Just a moment...</title></head></html>`)
+		case "/cache3/results/5_view.json":
+			fmt.Fprint(w, `{"data": [{"zid": 5`)
+		case "/cache3/results/6_view.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/cache3/results/7_view.json":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/cache3/results/8_view.json":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><head><title>Maintenance</title></head><body>ZwiftPower is undergoing maintenance</body></html>`)
+		}
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := []error{ErrNotFound, ErrRateLimited, ErrUnauthorized, ErrChallenged, ErrParse, ErrMaintenance}
+	cases := map[int]error{
+		1: ErrNotFound,
+		2: ErrRateLimited,
+		3: ErrUnauthorized,
+		4: ErrChallenged,
+		5: ErrParse,
+		6: nil,
+		7: ErrMaintenance,
+		8: ErrMaintenance,
+	}
+	for id, expected := range cases {
+		_, err := ImportEventResults(client, id)
+		if err == nil {
+			t.Errorf("Expected error for %d", id)
+			continue
+		}
+		for _, target := range all {
+			if errors.Is(err, target) != (target == expected) {
+				t.Errorf("Event %d: errors.Is(%v, %v) is %v", id, err, target, !(target == expected))
+			}
+		}
+	}
+
+	_, err = ImportEventResults(client, 2)
+	var status *StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusTooManyRequests || status.RetryAfter != 30*time.Second {
+		t.Errorf("Expected status error with Retry-After, got %#v", err)
+	}
+
+	if !errors.Is(ErrZwiftAuth, ErrUnauthorized) {
+		t.Errorf("ErrZwiftAuth should wrap ErrUnauthorized")
+	}
+	if !retryable(err) || !retryable(fmt.Errorf("getting: %w", &StatusError{StatusCode: 503})) {
+		t.Errorf("Rate limits and server errors should be retried")
+	}
+	if _, err := ImportEventResults(client, 1); retryable(err) {
+		t.Errorf("Missing events shouldn't be retried")
+	}
+}
+
+func TestNotFoundIsForLookups(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	must(t, err)
+
+	_, err = s.Rider(1)
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("A rider who isn't stored should be not found, got %v", err)
+	}
+
+	_, err = ioutil.ReadFile(filepath.Join(t.TempDir(), "missing.tmpl"))
+	if errors.Is(err, ErrNotFound) || IsNotFound(err) {
+		t.Errorf("Other missing files shouldn't be not found, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FunStat is a not-very-serious award for the digest
+type FunStat struct {
+	Title string
+	Name  string
+	Text  string
+}
+
+// FunStats hands out awards for the club's races from this time onwards. Events should
+// include earlier history too, because some awards compare with what went before.
+// Awards that nobody qualifies for are left out.
+func FunStats(events []Event, since time.Time) []FunStat {
+	var recent, earlier []Event
+	for _, e := range events {
+		if e.EventDateSecs == 0 || !e.IsRace() {
+			continue
+		}
+		if e.EventDate.Before(since) {
+			earlier = append(earlier, e)
+		} else {
+			recent = append(recent, e)
+		}
+	}
+
+	var stats []FunStat
+	for _, award := range []func([]Event, []Event) (FunStat, bool){
+		mostRacesInAWeek,
+		biggestSandbag,
+		earliestRace,
+		latestRace,
+		mostImprovedSprinter,
+	} {
+		if s, ok := award(recent, earlier); ok {
+			stats = append(stats, s)
+		}
+	}
+	return stats
+}
+
+// mostRacesInAWeek finds the rider with the most races in any seven days
+func mostRacesInAWeek(recent, earlier []Event) (FunStat, bool) {
+	byRider := make(map[int][]Event)
+	for _, e := range recent {
+		byRider[e.Zwid] = append(byRider[e.Zwid], e)
+	}
+
+	var best Event
+	most := 0
+	for _, ee := range byRider {
+		sort.Slice(ee, func(i, j int) bool {
+			return ee[i].EventDate.Before(ee[j].EventDate)
+		})
+
+		start := 0
+		for end := range ee {
+			for ee[end].EventDate.Sub(ee[start].EventDate) >= 7*24*time.Hour {
+				start++
+			}
+			if n := end - start + 1; n > most || (n == most && ee[end].Zwid < best.Zwid) {
+				most = n
+				best = ee[end]
+			}
+		}
+	}
+
+	if most < 2 {
+		return FunStat{}, false
+	}
+	return FunStat{
+		Title: "Most races in a week",
+		Name:  best.RiderName(),
+		Text:  fmt.Sprintf("raced %d times in seven days. Their turbo trainer has asked for a transfer.", most),
+	}, true
+}
+
+// categoryCeiling is the w/kg at which a rider should move up from this category
+func categoryCeiling(category string) (float64, bool) {
+	i := categoryIndex(category)
+	if i <= 0 {
+		return 0, false
+	}
+	return CategoryBoundaries[i-1].MinWkg, true
+}
+
+// biggestSandbag finds the race where someone averaged furthest over the top of
+// their category
+func biggestSandbag(recent, earlier []Event) (FunStat, bool) {
+	var best Event
+	margin := 0.0
+	for _, e := range recent {
+		ceiling, ok := categoryCeiling(e.Category)
+		if !ok {
+			continue
+		}
+		if m := toFloat(e.AvgWkg) - ceiling; m > margin {
+			margin = m
+			best = e
+		}
+	}
+
+	if margin <= 0 {
+		return FunStat{}, false
+	}
+	return FunStat{
+		Title: "Biggest sandbag",
+		Name:  best.RiderName(),
+		Text: fmt.Sprintf("averaged %.1f w/kg in %s, %.1f over the top of %s. That's not a sandbag, that's a sand dune.",
+			toFloat(best.AvgWkg), best.EventTitle, margin, best.Category),
+	}, true
+}
+
+// minutesIntoDay is used to compare times of day, whatever the date
+func minutesIntoDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+func earliestRace(recent, earlier []Event) (FunStat, bool) {
+	if len(recent) == 0 {
+		return FunStat{}, false
+	}
+
+	best := recent[0]
+	for _, e := range recent[1:] {
+		if minutesIntoDay(e.EventDate) < minutesIntoDay(best.EventDate) {
+			best = e
+		}
+	}
+	return FunStat{
+		Title: "Early bird",
+		Name:  best.RiderName(),
+		Text:  fmt.Sprintf("was racing at %s. The worm didn't stand a chance.", best.EventDate.Format("15:04")),
+	}, true
+}
+
+func latestRace(recent, earlier []Event) (FunStat, bool) {
+	if len(recent) == 0 {
+		return FunStat{}, false
+	}
+
+	best := recent[0]
+	for _, e := range recent[1:] {
+		if minutesIntoDay(e.EventDate) > minutesIntoDay(best.EventDate) {
+			best = e
+		}
+	}
+	return FunStat{
+		Title: "Night owl",
+		Name:  best.RiderName(),
+		Text:  fmt.Sprintf("was still racing at %s. Past their bedtime, and everyone else's.", best.EventDate.Format("15:04")),
+	}, true
+}
+
+// mostImprovedSprinter compares each rider's best 15 second power with their best
+// from before
+func mostImprovedSprinter(recent, earlier []Event) (FunStat, bool) {
+	before := make(map[int]float64)
+	for _, e := range earlier {
+		if float64(e.W15) > before[e.Zwid] {
+			before[e.Zwid] = float64(e.W15)
+		}
+	}
+
+	var best Event
+	gain := 0.0
+	for _, e := range recent {
+		previous, ok := before[e.Zwid]
+		if !ok || previous == 0 {
+			continue
+		}
+		if g := float64(e.W15) - previous; g > gain {
+			gain = g
+			best = e
+		}
+	}
+
+	if gain <= 0 {
+		return FunStat{}, false
+	}
+	return FunStat{
+		Title: "Most improved sprinter",
+		Name:  best.RiderName(),
+		Text:  fmt.Sprintf("found an extra %.0fW for 15 seconds. We're checking their spinach supply.", gain),
+	}, true
+}
@@ -0,0 +1,77 @@
+package zp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func funEvent(zwid int, name string, date time.Time, category string, avgWkg string, w15 float64) Event {
+	return Event{
+		Zid:           date.Format("150405") + name,
+		Zwid:          zwid,
+		Name:          name,
+		EventTitle:    "Race " + date.Format("Mon 15:04"),
+		EventType:     "TYPE_RACE",
+		Category:      category,
+		EventDateSecs: EventDateType(date.Unix()),
+		EventDate:     date,
+		AvgWkg:        []interface{}{avgWkg, 0.0},
+		W15:           Number(w15),
+	}
+}
+
+func TestFunStats(t *testing.T) {
+	since := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int, hour int) time.Time {
+		return since.AddDate(0, 0, d).Add(time.Duration(hour) * time.Hour)
+	}
+
+	events := []Event{
+		// Before the period
+		funEvent(1, "Ann", day(-10, 18), "B", "3.0", 600),
+		funEvent(2, "Bob", day(-10, 18), "C", "2.8", 700),
+		funEvent(3, "Cat", day(-3, 3), "D", "2.0", 500),
+
+		funEvent(1, "Ann", day(0, 18), "B", "3.1", 800),
+		funEvent(1, "Ann", day(2, 19), "B", "3.0", 650),
+		funEvent(1, "Ann", day(4, 20), "B", "3.2", 600),
+		funEvent(2, "Bob", day(1, 6), "C", "3.5", 750),
+		funEvent(2, "Bob", day(3, 23), "C", "2.9", 700),
+		funEvent(3, "Cat", day(5, 12), "D", "2.7", 400),
+	}
+
+	stats := FunStats(events, since)
+	expected := map[string]string{
+		"Most races in a week":   "Ann",
+		"Biggest sandbag":        "Bob",
+		"Early bird":             "Bob",
+		"Night owl":              "Bob",
+		"Most improved sprinter": "Ann",
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("Got %d stats: %v", len(stats), stats)
+	}
+	for _, s := range stats {
+		if expected[s.Title] != s.Name {
+			t.Errorf("%s went to %s, expected %s", s.Title, s.Name, expected[s.Title])
+		}
+	}
+
+	// Bob averaged 3.5 in C, where the ceiling is 3.2
+	if !strings.Contains(stats[1].Text, "0.3 over the top of C") {
+		t.Errorf("Unexpected sandbag text %q", stats[1].Text)
+	}
+	// Ann's 800W beats her earlier best of 600W
+	if !strings.Contains(stats[4].Text, "200W") {
+		t.Errorf("Unexpected sprint text %q", stats[4].Text)
+	}
+}
+
+func TestFunStatsQuietWeek(t *testing.T) {
+	since := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	stats := FunStats([]Event{funEvent(1, "Ann", since.AddDate(0, 0, -1), "A", "5.0", 900)}, since)
+	if len(stats) != 0 {
+		t.Errorf("Expected no stats, got %v", stats)
+	}
+}
@@ -0,0 +1,101 @@
+package zp
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Gender is what ZwiftPower records about whether a rider is male or female. It is
+// blank if we don't know.
+type Gender string
+
+// Genders, in the order we report them
+const (
+	Female        Gender = "Female"
+	Male          Gender = "Male"
+	UnknownGender Gender = ""
+)
+
+// UnmarshalJSON reads ZwiftPower's "male" field, which is 1 or 0 as a number or a string.
+// It also reads back the names that older versions wrote. Anything else is unknown.
+func (g *Gender) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v {
+	case 1.0, "1", string(Male):
+		*g = Male
+	case 0.0, "0", string(Female):
+		*g = Female
+	default:
+		*g = UnknownGender
+	}
+	return nil
+}
+
+// MarshalJSON writes the gender the way ZwiftPower does, so that it makes sense as the
+// "male" field: 1, 0, or null if we don't know
+func (g Gender) MarshalJSON() ([]byte, error) {
+	switch g {
+	case Male:
+		return []byte("1"), nil
+	case Female:
+		return []byte("0"), nil
+	default:
+		return []byte("null"), nil
+	}
+}
+
+func (g Gender) String() string {
+	if g == UnknownGender {
+		return "Unknown"
+	}
+	return string(g)
+}
+
+// GenderStats summarises the club's riders of one gender
+type GenderStats struct {
+	Gender     Gender
+	Riders     int
+	Active     int // Riders with an event in the last 90 days
+	Racing     int // Riders with a race in the last 90 days
+	Races30    int
+	Races90    int
+	Categories map[string]int // Number of riders by the category of their latest race
+}
+
+// GenderSplit gives stats for the riders of each gender as of now, women first. Genders
+// with no riders are left out.
+func GenderSplit(riders []Rider, now time.Time) []GenderStats {
+	stats := make(map[Gender]*GenderStats)
+	for _, r := range riders {
+		s, ok := stats[r.Gender]
+		if !ok {
+			s = &GenderStats{Gender: r.Gender, Categories: make(map[string]int)}
+			stats[r.Gender] = s
+		}
+
+		s.Riders++
+		if now.Sub(r.LatestEventDate) <= 90*24*time.Hour {
+			s.Active++
+		}
+		if r.Races90 > 0 {
+			s.Racing++
+		}
+		s.Races30 += r.Races30
+		s.Races90 += r.Races90
+		if r.Category != "" {
+			s.Categories[r.Category]++
+		}
+	}
+
+	var split []GenderStats
+	for _, g := range []Gender{Female, Male, UnknownGender} {
+		if s, ok := stats[g]; ok {
+			split = append(split, *s)
+		}
+	}
+	return split
+}
@@ -0,0 +1,106 @@
+package zp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenderUnmarshal(t *testing.T) {
+	cases := map[string]Gender{
+		`{"male":1}`:        Male,
+		`{"male":"1"}`:      Male,
+		`{"male":0}`:        Female,
+		`{"male":"0"}`:      Female,
+		`{"male":null}`:     UnknownGender,
+		`{}`:                UnknownGender,
+		`{"male":"Female"}`: Female,
+		`{"male":""}`:       UnknownGender,
+		`{"male":"Male"}`:   Male,
+		`{"male":2}`:        UnknownGender,
+		`{"male":"yes"}`:    UnknownGender,
+		`{"male":true}`:     UnknownGender,
+	}
+	for data, expected := range cases {
+		var r Rider
+		err := json.Unmarshal([]byte(data), &r)
+		if err != nil {
+			t.Errorf("Failed unmarshalling %s: %v", data, err)
+		}
+		if r.Gender != expected {
+			t.Errorf("Got %q from %s, expected %q", r.Gender, data, expected)
+		}
+	}
+
+	// Round trip through the store's encoding, which writes it as ZwiftPower does
+	for g, expected := range map[Gender]string{Male: `"male":1`, Female: `"male":0`, UnknownGender: `"male":null`} {
+		data, err := json.Marshal(Rider{Gender: g})
+		if err != nil || !strings.Contains(string(data), expected) {
+			t.Errorf("Got %s, %v for %q, expected %s", data, err, g, expected)
+		}
+		var r Rider
+		json.Unmarshal(data, &r)
+		if r.Gender != g {
+			t.Errorf("Got %q after round trip, expected %q", r.Gender, g)
+		}
+	}
+
+	var g Gender
+	if err := g.UnmarshalJSON([]byte("nonsense")); err == nil {
+		t.Errorf("Expected an error for invalid JSON")
+	}
+}
+
+func TestRiderGender(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	r := RiderFromEvents(1261784, events)
+	if r.Gender != Female {
+		t.Errorf("Got gender %q", r.Gender)
+	}
+	if r.Category == "" {
+		t.Errorf("No category from latest race")
+	}
+
+	// The roster wins if it says something
+	r = withRosterData(r, Rider{Name: "Someone", Gender: Male})
+	if r.Gender != Male {
+		t.Errorf("Got gender %q after roster data", r.Gender)
+	}
+}
+
+func TestGenderSplit(t *testing.T) {
+	now := time.Now()
+	riders := []Rider{
+		{Zwid: 1, Gender: Male, LatestEventDate: now, Races90: 3, Races30: 1, Category: "B"},
+		{Zwid: 2, Gender: Female, LatestEventDate: now, Races90: 2, Category: "C"},
+		{Zwid: 3, Gender: Female, LatestEventDate: now.AddDate(-1, 0, 0)},
+		{Zwid: 4, Gender: Female, LatestEventDate: now, Races90: 5, Races30: 2, Category: "C"},
+		{Zwid: 5},
+	}
+
+	split := GenderSplit(riders, now)
+	if len(split) != 3 {
+		t.Fatalf("Got %d genders", len(split))
+	}
+
+	f := split[0]
+	if f.Gender != Female || f.Riders != 3 || f.Active != 2 || f.Racing != 2 || f.Races30 != 2 || f.Races90 != 7 || f.Categories["C"] != 2 {
+		t.Errorf("Unexpected stats for women: %+v", f)
+	}
+	if split[1].Gender != Male || split[1].Categories["B"] != 1 {
+		t.Errorf("Unexpected stats for men: %+v", split[1])
+	}
+	if split[2].Gender.String() != "Unknown" || split[2].Riders != 1 {
+		t.Errorf("Unexpected stats for unknown: %+v", split[2])
+	}
+	// Nobody has ridden in the 90 days before a date 100 days from now
+	split = GenderSplit(riders, now.AddDate(0, 0, 100))
+	if split[0].Active != 0 || split[1].Active != 0 {
+		t.Errorf("Got %d women and %d men active 100 days from now, expected none", split[0].Active, split[1].Active)
+	}
+}
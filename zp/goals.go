@@ -0,0 +1,216 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of goal
+const (
+	GoalFtp   = "ftp"   // FTP in watts
+	GoalWkg   = "wkg"   // FTP in w/kg
+	GoalRaces = "races" // Races in 30 days
+)
+
+// GoalKinds lists the kinds of goal we can track
+var GoalKinds = []string{GoalFtp, GoalWkg, GoalRaces}
+
+// Goal is something a rider is aiming for, such as an FTP of 4 w/kg or eight races a month
+type Goal struct {
+	Zwid   int
+	Name   string
+	Kind   string
+	Target float64
+	Set    time.Time
+	By     time.Time // Zero if there's no deadline
+}
+
+// GoalProgress says how a rider is getting on with a goal
+type GoalProgress struct {
+	Goal
+	Current   float64
+	Percent   float64
+	Met       bool
+	Projected time.Time // When the trend reaches the target; zero if it isn't heading there
+	OnTrack   bool      // Met, or projected to be met by the deadline if there is one
+}
+
+// furthestProjection is as far ahead as we are prepared to guess
+const furthestProjection = 2 * 365 * 24 * time.Hour
+
+// ParseGoalKind checks that s is a kind of goal we can track
+func ParseGoalKind(s string) (string, error) {
+	kind := strings.ToLower(s)
+	for _, k := range GoalKinds {
+		if kind == k {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown goal %q, expected one of %s", s, strings.Join(GoalKinds, ", "))
+}
+
+// Describe says what the goal is in words, e.g. "4.0 w/kg FTP by 31 Dec 2021"
+func (g Goal) Describe() string {
+	var s string
+	switch g.Kind {
+	case GoalFtp:
+		s = fmt.Sprintf("%.0fW FTP", g.Target)
+	case GoalWkg:
+		s = fmt.Sprintf("%.1f w/kg FTP", g.Target)
+	case GoalRaces:
+		s = fmt.Sprintf("%.0f races a month", g.Target)
+	default:
+		s = fmt.Sprintf("%v %s", g.Target, g.Kind)
+	}
+	if !g.By.IsZero() {
+		s += " by " + g.By.Format("2 Jan 2006")
+	}
+	return s
+}
+
+// Summary says how the rider is doing, e.g. "3.6 (90%), on track for 12 Mar 2021"
+func (p GoalProgress) Summary() string {
+	var current string
+	switch p.Kind {
+	case GoalWkg:
+		current = fmt.Sprintf("%.1f", p.Current)
+	default:
+		current = fmt.Sprintf("%.0f", p.Current)
+	}
+
+	switch {
+	case p.Met:
+		return fmt.Sprintf("%s, goal reached", current)
+	case p.Projected.IsZero():
+		return fmt.Sprintf("%s (%.0f%%), not heading there yet", current, p.Percent)
+	case !p.OnTrack:
+		return fmt.Sprintf("%s (%.0f%%), behind: on course for %s", current, p.Percent, p.Projected.Format("2 Jan 2006"))
+	default:
+		return fmt.Sprintf("%s (%.0f%%), on track for %s", current, p.Percent, p.Projected.Format("2 Jan 2006"))
+	}
+}
+
+// goalValue is the event's contribution to an FTP goal
+func goalValue(kind string, e Event) float64 {
+	if kind == GoalWkg {
+		return toFloat(e.WkgFtp)
+	}
+	return float64(e.Ftp)
+}
+
+// Progress works out how far the rider has got with the goal from their events. FTP goals
+// use the best FTP from the last 90 days, and race goals count the last 30 days. The
+// projection follows the trend over the last six months.
+func (g Goal) Progress(events []Event, now time.Time) GoalProgress {
+	p := GoalProgress{Goal: g}
+
+	var xs, ys []float64
+	switch g.Kind {
+	case GoalFtp, GoalWkg:
+		for _, e := range events {
+			if e.EventDateSecs == 0 || e.EventDate.After(now) {
+				continue
+			}
+			age := now.Sub(e.EventDate)
+			v := goalValue(g.Kind, e)
+			if v <= 0 || age > 180*24*time.Hour {
+				continue
+			}
+			if age <= 90*24*time.Hour && v > p.Current {
+				p.Current = v
+			}
+			xs = append(xs, -age.Hours()/24)
+			ys = append(ys, v)
+		}
+
+	case GoalRaces:
+		// Races in each of the last six 30-day windows
+		counts := make([]float64, 6)
+		for _, e := range events {
+			if e.EventDateSecs == 0 || !e.IsRace() || e.EventDate.After(now) {
+				continue
+			}
+			if w := int(now.Sub(e.EventDate).Hours() / 24 / 30); w < len(counts) {
+				counts[w]++
+			}
+		}
+		p.Current = counts[0]
+		for w, c := range counts {
+			xs = append(xs, -float64(w*30))
+			ys = append(ys, c)
+		}
+	}
+
+	if g.Target > 0 {
+		p.Percent = 100 * p.Current / g.Target
+	}
+	p.Met = g.Target > 0 && p.Current >= g.Target
+
+	if p.Met {
+		p.Projected = now
+	} else if slope := trend(xs, ys); slope > 0 {
+		days := (g.Target - p.Current) / slope
+		if ahead := time.Duration(days * 24 * float64(time.Hour)); ahead <= furthestProjection {
+			p.Projected = now.Add(ahead)
+		}
+	}
+
+	p.OnTrack = p.Met || (!p.Projected.IsZero() && (g.By.IsZero() || !p.Projected.After(g.By)))
+	return p
+}
+
+// trend is the slope of the least-squares line through the points, per day
+func trend(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	if n < 2 {
+		return 0
+	}
+
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / d
+}
+
+// TrackGoals works out progress on each goal from the events, which can be for any
+// number of riders. Results are in order of rider name, then kind of goal.
+func TrackGoals(goals []Goal, events []Event, now time.Time) []GoalProgress {
+	byRider := make(map[int][]Event)
+	for _, e := range events {
+		byRider[e.Zwid] = append(byRider[e.Zwid], e)
+	}
+
+	progress := make([]GoalProgress, len(goals))
+	for i, g := range goals {
+		progress[i] = g.Progress(byRider[g.Zwid], now)
+	}
+	sort.SliceStable(progress, func(i, j int) bool {
+		if progress[i].Name != progress[j].Name {
+			return progress[i].Name < progress[j].Name
+		}
+		return progress[i].Kind < progress[j].Kind
+	})
+	return progress
+}
+
+// setGoal adds the goal to the list, replacing any goal of the same kind
+func setGoal(goals []Goal, g Goal) []Goal {
+	for i := range goals {
+		if goals[i].Kind == g.Kind {
+			goals[i] = g
+			return goals
+		}
+	}
+	return append(goals, g)
+}
@@ -0,0 +1,103 @@
+package zp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoalProgress(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+	now := time.Date(2021, 2, 10, 12, 0, 0, 0, time.UTC)
+
+	reached := Goal{Zwid: 1261784, Kind: GoalWkg, Target: 2.8}.Progress(events, now)
+	if !reached.Met || !reached.OnTrack || reached.Current != 2.9 {
+		t.Errorf("Expected goal to be met: %+v", reached)
+	}
+	if s := reached.Summary(); s != "2.9, goal reached" {
+		t.Errorf("Unexpected summary %q", s)
+	}
+
+	// Her w/kg is creeping up, so there's a projected date for a higher target
+	climbing := Goal{Zwid: 1261784, Kind: GoalWkg, Target: 3.0}.Progress(events, now)
+	if climbing.Met || !climbing.Projected.After(now) || !climbing.OnTrack {
+		t.Errorf("Expected projection for rising w/kg: %+v", climbing)
+	}
+
+	// ...but not by tomorrow
+	soon := Goal{Zwid: 1261784, Kind: GoalWkg, Target: 3.0, By: now.AddDate(0, 0, 1)}.Progress(events, now)
+	if soon.OnTrack || soon.Projected != climbing.Projected {
+		t.Errorf("Expected to be behind for a tight deadline: %+v", soon)
+	}
+	if !strings.Contains(soon.Summary(), "behind") {
+		t.Errorf("Unexpected summary %q", soon.Summary())
+	}
+
+	// FTP in watts hasn't moved, so there's no telling when she'll get there
+	flat := Goal{Zwid: 1261784, Kind: GoalFtp, Target: 200}.Progress(events, now)
+	if flat.Current != 170 || !flat.Projected.IsZero() || flat.OnTrack {
+		t.Errorf("Unexpected progress for flat FTP: %+v", flat)
+	}
+	if s := flat.Summary(); s != "170 (85%), not heading there yet" {
+		t.Errorf("Unexpected summary %q", s)
+	}
+
+	races := Goal{Zwid: 1261784, Kind: GoalRaces, Target: 4}.Progress(events, now)
+	if races.Current != 2 || races.Percent != 50 || races.Met {
+		t.Errorf("Unexpected race progress: %+v", races)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	if s := trend([]float64{0, 1, 2}, []float64{1, 3, 5}); s != 2 {
+		t.Errorf("Got slope %v, expected 2", s)
+	}
+	if s := trend([]float64{1}, []float64{1}); s != 0 {
+		t.Errorf("Got slope %v for a single point", s)
+	}
+}
+
+func TestParseGoalKind(t *testing.T) {
+	if k, err := ParseGoalKind("WKG"); err != nil || k != GoalWkg {
+		t.Errorf("Got %q, %v", k, err)
+	}
+	if _, err := ParseGoalKind("kom"); err == nil {
+		t.Errorf("Expected error for unknown goal")
+	}
+
+	by := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	if d := (Goal{Kind: GoalWkg, Target: 4, By: by}).Describe(); d != "4.0 w/kg FTP by 31 Dec 2021" {
+		t.Errorf("Unexpected description %q", d)
+	}
+}
+
+func TestDigestGoals(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+	now := time.Date(2021, 2, 10, 12, 0, 0, 0, time.UTC)
+
+	goals := []Goal{
+		{Zwid: 1261784, Name: "Özge Yazar", Kind: GoalWkg, Target: 2.8},
+		{Zwid: 2, Name: "Ann Other", Kind: GoalRaces, Target: 8},
+	}
+	d := NewDigest(2672, events, now.AddDate(0, 0, -7), now, false)
+	d.Goals = TrackGoals(goals, events, now)
+	if len(d.Goals) != 2 || d.Goals[0].Name != "Ann Other" || d.Goals[1].Current != 2.9 {
+		t.Fatalf("Unexpected goals %+v", d.Goals)
+	}
+
+	var b bytes.Buffer
+	err = WriteDigest(&b, d, "")
+	if err != nil {
+		t.Fatalf("Failed writing digest: %v", err)
+	}
+	if !strings.Contains(b.String(), "Özge Yazar, 2.8 w/kg FTP: 2.9, goal reached") {
+		t.Errorf("Missing goal in %q", b.String())
+	}
+}
@@ -0,0 +1,183 @@
+package zp
+
+import (
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// Physical constants for the (very) rough speed model. These are in the right ballpark
+// for Zwift on a road bike, which is good enough when all we care about is the
+// difference between riders.
+const (
+	gravity      = 9.8067
+	airDensity   = 1.225
+	rollingCoeff = 0.004
+	dragArea     = 0.32 // CdA in m^2
+	bikeWeight   = 8.0  // kg
+)
+
+// Course describes the route for a handicap race
+type Course struct {
+	Distance  float64 // km
+	Elevation float64 // metres climbed
+}
+
+// PowerProfile is a summary of a rider's recent power data
+type PowerProfile struct {
+	Zwid   int
+	Name   string
+	Weight float64 // kg
+	FTP    float64 // watts
+
+	// Curve holds best power in watts, keyed by duration in seconds
+	Curve map[int]float64
+}
+
+// Handicap is a rider's start offset for a handicap race
+type Handicap struct {
+	Zwid          int
+	Name          string
+	EstimatedTime time.Duration
+	Offset        time.Duration
+}
+
+// NewPowerProfile builds a power profile from a rider's events since the given date,
+// taking the best value for each duration and the most recent weight
+func NewPowerProfile(riderID int, events []Event, since time.Time) PowerProfile {
+	p := PowerProfile{
+		Zwid:  riderID,
+		Curve: make(map[int]float64),
+	}
+
+	var latest time.Time
+	for _, e := range events {
+		if e.EventDate.Before(since) {
+			continue
+		}
+
+		if e.Weight > 0 && e.EventDate.After(latest) {
+			latest = e.EventDate
+			p.Weight = float64(e.Weight)
+		}
+
+		if float64(e.Wftp) > p.FTP {
+			p.FTP = float64(e.Wftp)
+		}
+
+		for secs, w := range map[int]Number{5: e.W5, 15: e.W15, 30: e.W30, 60: e.W60, 120: e.W120, 300: e.W300, 1200: e.W1200} {
+			if float64(w) > p.Curve[secs] {
+				p.Curve[secs] = float64(w)
+			}
+		}
+	}
+
+	return p
+}
+
+// Power estimates the power in watts this rider could sustain for the given duration,
+// interpolating between the points we know about. Beyond an hour we assume a gentle
+// decline from FTP.
+func (p PowerProfile) Power(d time.Duration) float64 {
+	points := make(map[int]float64, len(p.Curve)+1)
+	for secs, w := range p.Curve {
+		if w > 0 {
+			points[secs] = w
+		}
+	}
+	if p.FTP > 0 {
+		points[3600] = p.FTP
+	}
+	if len(points) == 0 {
+		return 0
+	}
+
+	durations := make([]int, 0, len(points))
+	for secs := range points {
+		durations = append(durations, secs)
+	}
+	sort.Ints(durations)
+
+	t := d.Seconds()
+	first, last := durations[0], durations[len(durations)-1]
+	if t <= float64(first) {
+		return points[first]
+	}
+	if t >= float64(last) {
+		return points[last] * math.Pow(t/float64(last), -0.07)
+	}
+
+	for i := 1; i < len(durations); i++ {
+		lo, hi := durations[i-1], durations[i]
+		if t <= float64(hi) {
+			// Power falls off roughly linearly with log(time)
+			frac := math.Log(t/float64(lo)) / math.Log(float64(hi)/float64(lo))
+			return points[lo] + frac*(points[hi]-points[lo])
+		}
+	}
+
+	return points[last]
+}
+
+// speed returns the steady-state speed in m/s for this power on a constant gradient
+func speed(power float64, mass float64, gradient float64) float64 {
+	theta := math.Atan(gradient)
+	resistance := mass * gravity * (rollingCoeff*math.Cos(theta) + math.Sin(theta))
+
+	// Bisection, since the power equation is monotonic in speed
+	lo, hi := 0.0, 40.0
+	for i := 0; i < 60; i++ {
+		v := (lo + hi) / 2
+		if v*resistance+0.5*airDensity*dragArea*v*v*v > power {
+			hi = v
+		} else {
+			lo = v
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// EstimateTime estimates how long this rider would take to complete the course riding
+// solo at a steady effort. It returns zero if we don't have enough data.
+func (p PowerProfile) EstimateTime(c Course) time.Duration {
+	pred, ok := p.Ride(c.Profile())
+	if !ok {
+		return 0
+	}
+	return pred.Time
+}
+
+// Handicaps calculates start offsets so that everyone should finish together. The
+// slowest rider starts first with a zero offset. Riders without enough data to make
+// an estimate are left out.
+func Handicaps(c Course, riders []PowerProfile) []Handicap {
+	var hh []Handicap
+	var slowest time.Duration
+	for _, r := range riders {
+		t := r.EstimateTime(c)
+		if t == 0 {
+			log.Printf("Not enough data to estimate a handicap for %s (%d)", r.Name, r.Zwid)
+			continue
+		}
+
+		hh = append(hh, Handicap{
+			Zwid:          r.Zwid,
+			Name:          r.Name,
+			EstimatedTime: t,
+		})
+		if t > slowest {
+			slowest = t
+		}
+	}
+
+	for i := range hh {
+		hh[i].Offset = slowest - hh[i].EstimatedTime
+	}
+
+	sort.SliceStable(hh, func(i, j int) bool {
+		return hh[i].Offset < hh[j].Offset
+	})
+
+	return hh
+}
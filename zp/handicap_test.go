@@ -0,0 +1,94 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewPowerProfile(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	p := NewPowerProfile(1261784, events, time.Time{})
+	if p.Weight != 56.3 {
+		t.Errorf("Got weight %v expected 56.3", p.Weight)
+	}
+	if p.FTP != 163 {
+		t.Errorf("Got FTP %v expected 163", p.FTP)
+	}
+	if p.Curve[5] != 392 {
+		t.Errorf("Got 5s power %v expected 392", p.Curve[5])
+	}
+
+	// Only the last event is after this date
+	p = NewPowerProfile(1261784, events, time.Unix(1612000000, 0))
+	if p.FTP != 163 || p.Curve[1200] != 172 {
+		t.Errorf("Got FTP %v, 20 min power %v, expected 163 and 172", p.FTP, p.Curve[1200])
+	}
+}
+
+func TestPowerProfilePower(t *testing.T) {
+	p := PowerProfile{
+		FTP:   200,
+		Curve: map[int]float64{60: 400, 1200: 220},
+	}
+
+	cases := []struct {
+		d        time.Duration
+		expected float64
+	}{
+		{d: 10 * time.Second, expected: 400},
+		{d: time.Minute, expected: 400},
+		{d: 20 * time.Minute, expected: 220},
+		{d: time.Hour, expected: 200},
+	}
+
+	for i, c := range cases {
+		result := p.Power(c.d)
+		if result != c.expected {
+			t.Errorf("Case %d: got %v expected %v", i, result, c.expected)
+		}
+	}
+
+	if w := p.Power(5 * time.Minute); w >= 400 || w <= 220 {
+		t.Errorf("Got %v for 5 minute power, expected between 220 and 400", w)
+	}
+
+	if w := p.Power(2 * time.Hour); w >= 200 {
+		t.Errorf("Got %v for 2 hour power, expected less than FTP", w)
+	}
+}
+
+func TestHandicaps(t *testing.T) {
+	course := Course{Distance: 20, Elevation: 150}
+	riders := []PowerProfile{
+		{Zwid: 1, Name: "Strong", Weight: 70, FTP: 300},
+		{Zwid: 2, Name: "Steady", Weight: 70, FTP: 200},
+		{Zwid: 3, Name: "No data"},
+	}
+
+	hh := Handicaps(course, riders)
+	if len(hh) != 2 {
+		t.Fatalf("Got %d handicaps, expected 2", len(hh))
+	}
+
+	if hh[0].Zwid != 2 || hh[0].Offset != 0 {
+		t.Errorf("Expected slowest rider to start first with no offset, got %v", hh[0])
+	}
+
+	if hh[1].Zwid != 1 || hh[1].Offset <= 0 {
+		t.Errorf("Expected strongest rider to start last, got %v", hh[1])
+	}
+
+	if hh[1].EstimatedTime+hh[1].Offset != hh[0].EstimatedTime {
+		t.Errorf("Riders should finish together: %v, %v", hh[0], hh[1])
+	}
+
+	// Sanity check that the speed model is plausible: 200W for 70kg on a flat 40km
+	tt := riders[1].EstimateTime(Course{Distance: 40})
+	if tt < 50*time.Minute || tt > 90*time.Minute {
+		t.Errorf("Implausible 40km time %v", tt)
+	}
+}
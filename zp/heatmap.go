@@ -0,0 +1,76 @@
+package zp
+
+import (
+	"sort"
+	"time"
+)
+
+// RaceHeatmap counts when the club's riders race, by the day of the week and the hour
+// that each race started, in UTC as riders are spread across time zones. The matrices
+// are indexed by time.Weekday, so Sunday first, and then by hour, ready to draw as a
+// heatmap.
+type RaceHeatmap struct {
+	Since  time.Time  `json:"since"`
+	Total  int        `json:"total"`  // Results counted
+	Races  [7][24]int `json:"races"`  // Results in each hour
+	Riders [7][24]int `json:"riders"` // Different riders racing in each hour
+}
+
+// HeatmapSlot is one hour of the week in a RaceHeatmap
+type HeatmapSlot struct {
+	Weekday time.Weekday
+	Hour    int
+	Races   int
+	Riders  int
+}
+
+// RaceTimes builds a heatmap of the races in the events since the given date
+func RaceTimes(events []Event, since time.Time) RaceHeatmap {
+	h := RaceHeatmap{Since: since}
+	var riders [7][24]map[int]bool
+	for _, e := range events {
+		if !e.IsRace() || e.EventDateSecs == 0 || e.EventDate.Before(since) {
+			continue
+		}
+
+		start := e.EventDate.UTC()
+		day, hour := start.Weekday(), start.Hour()
+		h.Total++
+		h.Races[day][hour]++
+		if riders[day][hour] == nil {
+			riders[day][hour] = make(map[int]bool)
+		}
+		riders[day][hour][e.Zwid] = true
+	}
+
+	for day := range riders {
+		for hour := range riders[day] {
+			h.Riders[day][hour] = len(riders[day][hour])
+		}
+	}
+	return h
+}
+
+// Busiest gives the n hours of the week with the most riders racing, then the most
+// races, as a start for picking when to hold club events
+func (h RaceHeatmap) Busiest(n int) []HeatmapSlot {
+	var slots []HeatmapSlot
+	for day := range h.Races {
+		for hour, races := range h.Races[day] {
+			if races > 0 {
+				slots = append(slots, HeatmapSlot{Weekday: time.Weekday(day), Hour: hour, Races: races, Riders: h.Riders[day][hour]})
+			}
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Riders != slots[j].Riders {
+			return slots[i].Riders > slots[j].Riders
+		}
+		return slots[i].Races > slots[j].Races
+	})
+	if len(slots) > n {
+		slots = slots[:n]
+	}
+	return slots
+}
@@ -0,0 +1,38 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRaceTimes(t *testing.T) {
+	race := func(zwid int, date time.Time) Event {
+		return Event{Zwid: zwid, EventType: "TYPE_RACE", EventDateSecs: EventDateType(date.Unix()), EventDate: date}
+	}
+	tuesday := time.Date(2021, 2, 2, 18, 10, 0, 0, time.UTC)
+	nyc := time.FixedZone("EST", -5*60*60)
+	events := []Event{
+		race(1, tuesday),
+		race(2, tuesday),
+		race(1, tuesday.AddDate(0, 0, 7)),
+		race(3, time.Date(2021, 2, 6, 21, 0, 0, 0, nyc)), // Sunday 02:00 UTC
+		race(1, tuesday.AddDate(0, 0, -60)),              // Too long ago
+		{Zwid: 1, EventType: "TYPE_RIDE", EventDateSecs: EventDateType(tuesday.Unix()), EventDate: tuesday},
+	}
+
+	h := RaceTimes(events, tuesday.AddDate(0, 0, -30))
+	if h.Total != 4 {
+		t.Errorf("Got %d races in total", h.Total)
+	}
+	if h.Races[time.Tuesday][18] != 3 || h.Riders[time.Tuesday][18] != 2 {
+		t.Errorf("Got %d races by %d riders on Tuesday at 18:00", h.Races[time.Tuesday][18], h.Riders[time.Tuesday][18])
+	}
+	if h.Races[time.Sunday][2] != 1 || h.Races[time.Saturday][21] != 0 {
+		t.Errorf("Expected the race in New York to be counted in UTC")
+	}
+
+	busiest := h.Busiest(5)
+	if len(busiest) != 2 || busiest[0].Weekday != time.Tuesday || busiest[0].Hour != 18 || busiest[1].Riders != 1 {
+		t.Errorf("Got busiest %+v", busiest)
+	}
+}
@@ -0,0 +1,366 @@
+package zp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// resultCell is what we can read from one cell of a results table
+type resultCell struct {
+	text  string
+	links []string
+}
+
+// resultColumns fill in an Event from a column of ZwiftPower's results table, keyed by
+// header in lower case. Values can carry units, e.g. "285w" or "72kg".
+var resultColumns = map[string]func(e *Event, c resultCell){
+	"pos":         func(e *Event, c resultCell) { setPosition(e, c.text) },
+	"position":    func(e *Event, c resultCell) { setPosition(e, c.text) },
+	"#":           func(e *Event, c resultCell) { setPosition(e, c.text) },
+	"pos in cat":  func(e *Event, c resultCell) { e.PositionInCat = int(leadingNumber(c.text)) },
+	"cat pos":     func(e *Event, c resultCell) { e.PositionInCat = int(leadingNumber(c.text)) },
+	"name":        setRider,
+	"rider":       setRider,
+	"zwid":        func(e *Event, c resultCell) { e.Zwid = int(leadingNumber(c.text)) },
+	"zwift id":    func(e *Event, c resultCell) { e.Zwid = int(leadingNumber(c.text)) },
+	"cat":         func(e *Event, c resultCell) { e.Category = strings.ToUpper(c.text) },
+	"category":    func(e *Event, c resultCell) { e.Category = strings.ToUpper(c.text) },
+	"team":        setTeam,
+	"tid":         func(e *Event, c resultCell) { e.TeamID = c.text },
+	"time":        func(e *Event, c resultCell) { e.Time = Number(clockSeconds(c.text)) },
+	"avg":         func(e *Event, c resultCell) { e.AvgPower = Number(leadingNumber(c.text)) },
+	"avg power":   func(e *Event, c resultCell) { e.AvgPower = Number(leadingNumber(c.text)) },
+	"power":       func(e *Event, c resultCell) { e.AvgPower = Number(leadingNumber(c.text)) },
+	"w/kg":        func(e *Event, c resultCell) { e.AvgWkg = leadingNumber(c.text) },
+	"avg w/kg":    func(e *Event, c resultCell) { e.AvgWkg = leadingNumber(c.text) },
+	"np":          func(e *Event, c resultCell) { e.NP = Number(leadingNumber(c.text)) },
+	"hr":          func(e *Event, c resultCell) { e.AvgHR = Number(leadingNumber(c.text)) },
+	"avg hr":      func(e *Event, c resultCell) { e.AvgHR = Number(leadingNumber(c.text)) },
+	"max hr":      func(e *Event, c resultCell) { e.MaxHR = Number(leadingNumber(c.text)) },
+	"weight":      func(e *Event, c resultCell) { e.Weight = Number(leadingNumber(c.text)) },
+	"height":      func(e *Event, c resultCell) { e.Height = Number(leadingNumber(c.text)) },
+	"distance":    func(e *Event, c resultCell) { e.Distance = Number(leadingNumber(c.text)) },
+	"20m":         func(e *Event, c resultCell) { e.W1200 = Number(leadingNumber(c.text)) },
+	"5m":          func(e *Event, c resultCell) { e.W300 = Number(leadingNumber(c.text)) },
+	"1m":          func(e *Event, c resultCell) { e.W60 = Number(leadingNumber(c.text)) },
+	"30s":         func(e *Event, c resultCell) { e.W30 = Number(leadingNumber(c.text)) },
+	"15s":         func(e *Event, c resultCell) { e.W15 = Number(leadingNumber(c.text)) },
+	"5s":          func(e *Event, c resultCell) { e.W5 = Number(leadingNumber(c.text)) },
+	"event":       func(e *Event, c resultCell) { e.EventTitle = c.text },
+	"event title": func(e *Event, c resultCell) { e.EventTitle = c.text },
+}
+
+var (
+	numberPattern   = regexp.MustCompile(`-?[0-9][0-9,]*(\.[0-9]+)?`)
+	categoryPattern = regexp.MustCompile(`\b(A\+|[A-E])\b`)
+)
+
+// leadingNumber is the first number in the text, ignoring any units and thousands
+// separators, or 0 if there isn't one
+func leadingNumber(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.Replace(numberPattern.FindString(s), ",", "", -1), 64)
+	return f
+}
+
+// clockSeconds reads a time like 1:02:03.456 or 59:30 as seconds. Anything else, such
+// as a gap like +1.2s, is 0.
+func clockSeconds(s string) float64 {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0
+	}
+	secs := 0.0
+	for _, p := range parts {
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0
+		}
+		secs = secs*60 + f
+	}
+	return secs
+}
+
+// setPosition reads the position, which on the results page can share its cell with a
+// category badge
+func setPosition(e *Event, s string) {
+	e.Pos = int(leadingNumber(s))
+	if e.Category == "" {
+		e.Category = categoryPattern.FindString(strings.ToUpper(s))
+	}
+}
+
+// setRider takes the name, and the Zwift ID from the link to their profile if there is one
+func setRider(e *Event, c resultCell) {
+	e.Name = c.text
+	for _, link := range c.links {
+		if id := linkParam(link, "profile.php", "z"); id != 0 {
+			e.Zwid = id
+		}
+	}
+}
+
+func setTeam(e *Event, c resultCell) {
+	e.TeamName = c.text
+	for _, link := range c.links {
+		if id := linkParam(link, "team.php", "id"); id != 0 {
+			e.TeamID = strconv.Itoa(id)
+		}
+	}
+}
+
+// linkParam finds a numeric parameter in a link to a ZwiftPower page
+func linkParam(link string, page string, param string) int {
+	u, err := url.Parse(link)
+	if err != nil || !strings.HasSuffix(u.Path, page) {
+		return 0
+	}
+	id, _ := strconv.Atoi(u.Query().Get(param))
+	return id
+}
+
+// resultsFromTable makes events from the rows of a results table. It needs a name
+// column, and skips rows without a name.
+func resultsFromTable(headers []string, rows [][]resultCell) ([]Event, error) {
+	setters := make([]func(*Event, resultCell), len(headers))
+	named, posInCat := false, false
+	for i, h := range headers {
+		key := strings.ToLower(strings.Join(strings.Fields(h), " "))
+		setters[i] = resultColumns[key]
+		named = named || key == "name" || key == "rider"
+		posInCat = posInCat || key == "pos in cat" || key == "cat pos"
+	}
+	if !named {
+		return nil, fmt.Errorf("no name or rider column in %q", headers)
+	}
+
+	var results []Event
+	for _, row := range rows {
+		var e Event
+		for i, c := range row {
+			if i < len(setters) && setters[i] != nil {
+				setters[i](&e, c)
+			}
+		}
+		if e.Name == "" {
+			continue
+		}
+		if !posInCat {
+			// The results page numbers riders within their category
+			e.PositionInCat = e.Pos
+		}
+		results = append(results, e)
+	}
+	return CanonicalResults(results), nil
+}
+
+// ParseResultsCSV reads event results exported from ZwiftPower as CSV, with a header row.
+// Columns are matched by header, such as Pos, Name, Cat, Team, Time, Avg, W/kg and HR,
+// and any others are ignored.
+func ParseResultsCSV(r io.Reader) ([]Event, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, &ParseError{What: "results CSV", Err: err}
+	}
+	if len(records) == 0 {
+		return nil, &ParseError{What: "results CSV", Err: fmt.Errorf("no header row")}
+	}
+
+	rows := make([][]resultCell, len(records)-1)
+	for i, record := range records[1:] {
+		rows[i] = make([]resultCell, len(record))
+		for j, text := range record {
+			rows[i][j] = resultCell{text: strings.TrimSpace(text)}
+		}
+	}
+	results, err := resultsFromTable(records[0], rows)
+	if err != nil {
+		return nil, &ParseError{What: "results CSV", Err: err}
+	}
+	return results, nil
+}
+
+// ParseResultsHTML reads event results from a ZwiftPower results page saved from the
+// browser. It uses the first table with a Name or Rider column, matching columns by
+// header as ParseResultsCSV does, and takes riders' IDs from the links to their
+// profiles. The page title becomes the event title.
+func ParseResultsHTML(r io.Reader) ([]Event, error) {
+	tables, title, err := htmlTables(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error = fmt.Errorf("no results table")
+	for _, t := range tables {
+		results, err := resultsFromTable(t.headers, t.rows)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for i := range results {
+			if results[i].EventTitle == "" {
+				results[i].EventTitle = title
+			}
+		}
+		return results, nil
+	}
+	return nil, &ParseError{What: "results page", Err: lastErr}
+}
+
+// htmlTable is the header and the rows of a table on a page
+type htmlTable struct {
+	headers []string
+	rows    [][]resultCell
+}
+
+// htmlTables reads every table on the page, and the page's title
+func htmlTables(r io.Reader) ([]htmlTable, string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, "", &ParseError{What: "results page", Err: err}
+	}
+
+	title := ""
+	if t := findElement(doc, "title"); t != nil {
+		title = strings.TrimPrefix(readCell(t).text, "ZwiftPower - ")
+	}
+
+	var tables []htmlTable
+	for _, table := range findElements(doc, "table") {
+		var t htmlTable
+		for _, tr := range findElements(table, "tr") {
+			var cells []resultCell
+			header := false
+			for c := tr.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+					cells = append(cells, readCell(c))
+					header = header || c.Data == "th"
+				}
+			}
+			switch {
+			case header && t.headers == nil:
+				for _, c := range cells {
+					t.headers = append(t.headers, c.text)
+				}
+			case !header && len(cells) > 0:
+				t.rows = append(t.rows, cells)
+			}
+		}
+		tables = append(tables, t)
+	}
+	return tables, title, nil
+}
+
+func findElements(n *html.Node, tag string) []*html.Node {
+	var found []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, findElements(c, tag)...)
+	}
+	return found
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if found := findElements(n, tag); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
+
+// readCell gets the text of an element, with spaces tidied up, and any links in it
+func readCell(n *html.Node) resultCell {
+	var text []string
+	var links []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			text = append(text, strings.Fields(n.Data)...)
+		case html.ElementNode:
+			if n.Data == "a" {
+				for _, a := range n.Attr {
+					if a.Key == "href" {
+						links = append(links, a.Val)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return resultCell{text: strings.Join(text, " "), links: links}
+}
+
+// resultsExtensions are the kinds of file we can read event results from
+var resultsExtensions = []string{".json", ".csv", ".html", ".htm"}
+
+// ReadResultsFile reads event results from a file: ZwiftPower's JSON, a CSV export, or a
+// saved results page, depending on the extension
+func ReadResultsFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		results, err := parseEvents(data)
+		if err != nil {
+			return nil, &ParseError{What: "event results", Err: err}
+		}
+		return CanonicalResults(results), nil
+	case ".csv":
+		return ParseResultsCSV(f)
+	case ".html", ".htm":
+		return ParseResultsHTML(f)
+	default:
+		return nil, fmt.Errorf("don't know how to read results from %s, expected one of %s", path, strings.Join(resultsExtensions, ", "))
+	}
+}
+
+// localResults reads the event's results from the client's ResultsDir, if there's a file
+// for it there
+func (c *Client) localResults(eventID int) ([]Event, bool, error) {
+	for _, ext := range resultsExtensions {
+		path := filepath.Join(c.ResultsDir, strconv.Itoa(eventID)+ext)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		results, err := ReadResultsFile(path)
+		if err != nil {
+			return nil, true, fmt.Errorf("reading results for %d: %w", eventID, err)
+		}
+		p := &Provenance{Source: SourceFile, Fetched: c.now(), Modified: info.ModTime()}
+		for i := range results {
+			if results[i].Zid == "" {
+				results[i].Zid = strconv.Itoa(eventID)
+			}
+			results[i].Provenance = p
+		}
+		return results, true, nil
+	}
+	return nil, false, nil
+}
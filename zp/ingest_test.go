@@ -0,0 +1,137 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testResultsCSV = `Pos,Name,Cat,Team,Time,Avg,W/kg,HR,Weight,Zwift ID
+1,Ann Example,B,REVO,1:02:03.456,250w,3.5w/kg,160bpm,71.4kg,123
+2,Bob Example,B,,"1:02:05",240w,3.2w/kg,,75kg,456
+,,,,,,,,,
+1,Cat Example,A,Other,59:30,300w,4.1w/kg,170bpm,73kg,789
+`
+
+func TestParseResultsCSV(t *testing.T) {
+	results, err := ParseResultsCSV(strings.NewReader(testResultsCSV))
+	if err != nil {
+		t.Fatalf("Failed parsing CSV: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d: %v", len(results), results)
+	}
+
+	ann := results[0]
+	if ann.Zwid != 123 || ann.Name != "Ann Example" || ann.Category != "B" || ann.TeamName != "REVO" ||
+		ann.Pos != 1 || ann.PositionInCat != 1 || ann.AvgPower != 250 || ann.Wkg() != 3.5 ||
+		ann.AvgHR != 160 || ann.Weight != 71.4 {
+		t.Errorf("Unexpected result %+v", ann)
+	}
+	if ann.Time < 3723.45 || ann.Time > 3723.46 {
+		t.Errorf("Expected time 3723.456, got %v", ann.Time)
+	}
+	if results[2].Time != 3570 {
+		t.Errorf("Expected time 3570, got %v", results[2].Time)
+	}
+
+	for _, doc := range []string{"", "Pos,Team\n1,REVO\n", "a,\"b\n"} {
+		if _, err := ParseResultsCSV(strings.NewReader(doc)); err == nil {
+			t.Errorf("Expected error for %q", doc)
+		}
+	}
+}
+
+const testResultsHTML = `<html><head><title>ZwiftPower - Saturday Crit</title></head><body>
+<table><tr><th>Menu</th></tr><tr><td>Home</td></tr></table>
+<table id="table_event_results_final">
+<thead><tr><th>Pos</th><th>Name</th><th>Team</th><th>Time</th><th>Avg</th><th>W/kg</th><th>20m</th></tr></thead>
+<tbody>
+<tr><td><span class="label">A</span> 1</td><td><a href="profile.php?z=789">Cat
+  Example</a></td><td><a href="team.php?id=99">Other</a></td><td>59:30</td><td>300w</td><td>4.1w/kg</td><td>320w</td></tr>
+<tr><td>B 1</td><td><a href="https://www.zwiftpower.com/profile.php?z=123">Ann Example</a></td>
+  <td><a href="/team.php?id=2672">REVO</a></td><td>1:02:03.456</td><td>250w</td><td>3.5w/kg</td><td>270w</td></tr>
+<tr><td>B 1</td><td><a href="profile.php?z=123">Ann Example</a></td><td></td><td>1:02:03.456</td><td></td><td></td><td></td></tr>
+</tbody></table></body></html>`
+
+func TestParseResultsHTML(t *testing.T) {
+	results, err := ParseResultsHTML(strings.NewReader(testResultsHTML))
+	if err != nil {
+		t.Fatalf("Failed parsing HTML: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d: %v", len(results), results)
+	}
+
+	c := results[0]
+	if c.Zwid != 789 || c.Name != "Cat Example" || c.Category != "A" || c.PositionInCat != 1 ||
+		c.TeamID != "99" || c.TeamName != "Other" || c.Time != 3570 || c.W1200 != 320 || c.EventTitle != "Saturday Crit" {
+		t.Errorf("Unexpected result %+v", c)
+	}
+	if a := results[1]; a.Zwid != 123 || a.TeamID != "2672" || a.Category != "B" || a.AvgPower != 250 {
+		t.Errorf("Unexpected result %+v", a)
+	}
+
+	if _, err := ParseResultsHTML(strings.NewReader("<html><p>Nothing here</p></html>")); err == nil {
+		t.Errorf("Expected error for page without results")
+	}
+}
+
+func TestClientResultsDir(t *testing.T) {
+	fetched := 0
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		w.Write([]byte(`{"data":[{"zwid":1,"name":"Remote","pos":1}]}`))
+	})
+
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "100.csv"), []byte(testResultsCSV), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "200.html"), []byte(testResultsHTML), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "300.csv"), []byte("Pos\n1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ResultsDir = dir
+	ctx := context.Background()
+
+	results, err := client.EventResults(ctx, 100)
+	if err != nil || len(results) != 3 || results[0].Zid != "100" {
+		t.Errorf("Expected results from CSV, got %v, %v", results, err)
+	}
+	results, err = client.EventResults(ctx, 200)
+	if err != nil || len(results) != 2 || results[0].Zid != "200" {
+		t.Errorf("Expected results from HTML, got %v, %v", results, err)
+	}
+	if fetched != 0 {
+		t.Errorf("Didn't expect to fetch results we have locally")
+	}
+
+	_, err = client.EventResults(ctx, 300)
+	if !errors.Is(err, ErrParse) {
+		t.Errorf("Expected parse error for bad file, got %v", err)
+	}
+
+	results, err = client.EventResults(ctx, 400)
+	if err != nil || len(results) != 1 || results[0].Name != "Remote" || fetched != 1 {
+		t.Errorf("Expected results from ZwiftPower, got %v, %v", results, err)
+	}
+
+	if _, err := ReadResultsFile(filepath.Join(dir, "100.txt")); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
@@ -0,0 +1,235 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLeaguePoints are awarded for 1st, 2nd, 3rd... within a division, if the league
+// doesn't set its own
+var DefaultLeaguePoints = []int{25, 20, 16, 13, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+// League is a competition within the club. Riders are split into divisions, and score
+// points for where they finish among their division in each of the fixtures.
+type League struct {
+	Name      string
+	ClubID    int
+	Divisions []Division
+	Fixtures  []Fixture
+	Points    []int // Points for 1st, 2nd, 3rd... in a division. Nil means DefaultLeaguePoints.
+}
+
+// Division holds the riders who are assigned to it by hand, and any other club riders
+// who race in one of its categories
+type Division struct {
+	Name       string
+	Categories []string
+	Riders     []int
+}
+
+// Fixture is an event that counts towards the league
+type Fixture struct {
+	EventID int
+	Title   string
+	Date    time.Time
+}
+
+// Standing is a rider's position in a division table
+type Standing struct {
+	Zwid     int
+	Name     string
+	Points   int
+	Fixtures int
+	Wins     int
+	Best     int // Best placing within the division
+}
+
+// DivisionTable is the league table for one division, leader first
+type DivisionTable struct {
+	Division  string
+	Standings []Standing
+}
+
+// NewLeague starts a league for the club, with no divisions or fixtures yet
+func NewLeague(name string, clubID int) *League {
+	return &League{Name: name, ClubID: clubID}
+}
+
+func (l *League) division(name string) *Division {
+	for i := range l.Divisions {
+		if strings.EqualFold(l.Divisions[i].Name, name) {
+			return &l.Divisions[i]
+		}
+	}
+	return nil
+}
+
+// AddDivision adds a division for riders in these categories, or changes the
+// categories if the division already exists
+func (l *League) AddDivision(name string, categories ...string) {
+	if d := l.division(name); d != nil {
+		d.Categories = categories
+		return
+	}
+	l.Divisions = append(l.Divisions, Division{Name: name, Categories: categories})
+}
+
+// Assign puts riders into the named division by hand, taking them out of any other
+// division they were assigned to
+func (l *League) Assign(division string, riderIDs ...int) error {
+	d := l.division(division)
+	if d == nil {
+		return fmt.Errorf("no division %q in league %s", division, l.Name)
+	}
+
+	moving := make(map[int]bool, len(riderIDs))
+	for _, id := range riderIDs {
+		moving[id] = true
+	}
+	for i := range l.Divisions {
+		var keep []int
+		for _, id := range l.Divisions[i].Riders {
+			if !moving[id] {
+				keep = append(keep, id)
+			}
+		}
+		l.Divisions[i].Riders = keep
+	}
+
+	d.Riders = append(d.Riders, riderIDs...)
+	sort.Ints(d.Riders)
+	return nil
+}
+
+// AddFixture registers an event with the league, replacing it if it's already there.
+// Fixtures are kept in date order.
+func (l *League) AddFixture(f Fixture) {
+	l.RemoveFixture(f.EventID)
+	l.Fixtures = append(l.Fixtures, f)
+	sort.SliceStable(l.Fixtures, func(i, j int) bool {
+		return l.Fixtures[i].Date.Before(l.Fixtures[j].Date)
+	})
+}
+
+// RemoveFixture takes an event out of the league
+func (l *League) RemoveFixture(eventID int) {
+	var keep []Fixture
+	for _, f := range l.Fixtures {
+		if f.EventID != eventID {
+			keep = append(keep, f)
+		}
+	}
+	l.Fixtures = keep
+}
+
+// DivisionFor works out which division this result counts for. Assignment by hand
+// wins over category. It returns "" if the result doesn't count for the league,
+// either because the rider isn't in the club or no division takes their category.
+func (l *League) DivisionFor(e Event) string {
+	for _, d := range l.Divisions {
+		for _, id := range d.Riders {
+			if id == e.Zwid {
+				return d.Name
+			}
+		}
+	}
+
+	if l.ClubID != 0 && e.TeamID != strconv.Itoa(l.ClubID) {
+		return ""
+	}
+
+	for _, d := range l.Divisions {
+		for _, c := range d.Categories {
+			if strings.EqualFold(c, e.Category) {
+				return d.Name
+			}
+		}
+	}
+	return ""
+}
+
+func (l *League) points(place int) int {
+	points := l.Points
+	if points == nil {
+		points = DefaultLeaguePoints
+	}
+	if place < 1 || place > len(points) {
+		return 0
+	}
+	return points[place-1]
+}
+
+// Tables calculates the table for each division from the results of the fixtures,
+// keyed by event ID. Fixtures without results yet are skipped. Within a division,
+// riders are placed in the order they finished the event, counting each rider once
+// even if they have several result rows.
+func (l *League) Tables(results map[int][]Event) []DivisionTable {
+	standings := make(map[string]map[int]*Standing)
+	for _, d := range l.Divisions {
+		standings[d.Name] = make(map[int]*Standing)
+	}
+
+	for _, f := range l.Fixtures {
+		finishers := make(map[string][]Event)
+		for _, e := range CanonicalResults(results[f.EventID]) {
+			if e.Pos <= 0 {
+				continue
+			}
+			if d := l.DivisionFor(e); d != "" {
+				finishers[d] = append(finishers[d], e)
+			}
+		}
+
+		for d, ee := range finishers {
+			sort.SliceStable(ee, func(i, j int) bool {
+				return ee[i].Pos < ee[j].Pos
+			})
+
+			for i, e := range ee {
+				place := i + 1
+				s, ok := standings[d][e.Zwid]
+				if !ok {
+					s = &Standing{Zwid: e.Zwid, Name: e.RiderName()}
+					standings[d][e.Zwid] = s
+				}
+
+				s.Points += l.points(place)
+				s.Fixtures++
+				if place == 1 {
+					s.Wins++
+				}
+				if s.Best == 0 || place < s.Best {
+					s.Best = place
+				}
+			}
+		}
+	}
+
+	tables := make([]DivisionTable, 0, len(l.Divisions))
+	for _, d := range l.Divisions {
+		t := DivisionTable{Division: d.Name}
+		for _, s := range standings[d.Name] {
+			t.Standings = append(t.Standings, *s)
+		}
+
+		sort.Slice(t.Standings, func(i, j int) bool {
+			a, b := t.Standings[i], t.Standings[j]
+			if a.Points != b.Points {
+				return a.Points > b.Points
+			}
+			if a.Wins != b.Wins {
+				return a.Wins > b.Wins
+			}
+			if a.Best != b.Best {
+				return a.Best < b.Best
+			}
+			return a.Name < b.Name
+		})
+		tables = append(tables, t)
+	}
+
+	return tables
+}
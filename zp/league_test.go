@@ -0,0 +1,131 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func testLeague() *League {
+	l := NewLeague("Winter", 2672)
+	l.AddDivision("Premier", "A", "B")
+	l.AddDivision("Championship", "C", "D")
+	l.AddFixture(Fixture{EventID: 2, Date: time.Date(2020, 12, 8, 0, 0, 0, 0, time.UTC)})
+	l.AddFixture(Fixture{EventID: 1, Date: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)})
+	return l
+}
+
+func TestLeagueDivisions(t *testing.T) {
+	l := testLeague()
+	if l.Fixtures[0].EventID != 1 {
+		t.Errorf("Fixtures not in date order: %v", l.Fixtures)
+	}
+
+	cases := []struct {
+		e        Event
+		division string
+	}{
+		{Event{Zwid: 1, Category: "A", TeamID: "2672"}, "Premier"},
+		{Event{Zwid: 2, Category: "d", TeamID: "2672"}, "Championship"},
+		{Event{Zwid: 3, Category: "A", TeamID: "1234"}, ""},
+		{Event{Zwid: 4, Category: "E", TeamID: "2672"}, ""},
+	}
+	for _, c := range cases {
+		if d := l.DivisionFor(c.e); d != c.division {
+			t.Errorf("Got division %q for %v, expected %q", d, c.e, c.division)
+		}
+	}
+
+	// Manual assignment wins over category and club
+	l.Assign("Championship", 1, 3)
+	if d := l.DivisionFor(Event{Zwid: 1, Category: "A", TeamID: "2672"}); d != "Championship" {
+		t.Errorf("Got division %q for assigned rider", d)
+	}
+	l.Assign("Premier", 3)
+	if d := l.DivisionFor(Event{Zwid: 3, Category: "D", TeamID: "1234"}); d != "Premier" {
+		t.Errorf("Got division %q for reassigned rider", d)
+	}
+	if len(l.Divisions[1].Riders) != 1 {
+		t.Errorf("Reassigned rider still in old division: %v", l.Divisions[1].Riders)
+	}
+
+	if err := l.Assign("Conference", 5); err == nil {
+		t.Errorf("Expected error assigning to unknown division")
+	}
+}
+
+func TestLeagueTables(t *testing.T) {
+	l := testLeague()
+	l.Points = []int{10, 5, 1}
+
+	results := map[int][]Event{
+		1: {
+			{Zwid: 10, Name: "Alice", Pos: 3, Category: "A", TeamID: "2672"},
+			{Zwid: 11, Name: "Bob", Pos: 7, Category: "B", TeamID: "2672"},
+			{Zwid: 12, Name: "Carol", Pos: 20, Category: "C", TeamID: "2672"},
+			{Zwid: 13, Name: "Outsider", Pos: 1, Category: "A", TeamID: "999"},
+			{Zwid: 14, Name: "DNF", Pos: 0, Category: "A", TeamID: "2672"},
+		},
+		2: {
+			{Zwid: 11, Name: "Bob", Pos: 1, Category: "B", TeamID: "2672"},
+			{Zwid: 10, Name: "Alice", Pos: 2, Category: "A", TeamID: "2672"},
+			{Zwid: 15, Name: "Dave", Pos: 9, Category: "A", TeamID: "2672"},
+		},
+		// Not a fixture
+		3: {
+			{Zwid: 15, Name: "Dave", Pos: 1, Category: "A", TeamID: "2672"},
+		},
+	}
+
+	tables := l.Tables(results)
+	if len(tables) != 2 {
+		t.Fatalf("Got %d tables", len(tables))
+	}
+
+	premier := tables[0]
+	if premier.Division != "Premier" || len(premier.Standings) != 3 {
+		t.Fatalf("Unexpected Premier table %v", premier)
+	}
+
+	// Alice and Bob both have 15 points and a win, Alice is ahead on name
+	expected := []Standing{
+		{Zwid: 10, Name: "Alice", Points: 15, Fixtures: 2, Wins: 1, Best: 1},
+		{Zwid: 11, Name: "Bob", Points: 15, Fixtures: 2, Wins: 1, Best: 1},
+		{Zwid: 15, Name: "Dave", Points: 1, Fixtures: 1, Wins: 0, Best: 3},
+	}
+	for i, s := range expected {
+		if premier.Standings[i] != s {
+			t.Errorf("Got standing %v, expected %v", premier.Standings[i], s)
+		}
+	}
+
+	championship := tables[1]
+	if len(championship.Standings) != 1 || championship.Standings[0].Points != 10 {
+		t.Errorf("Unexpected Championship table %v", championship)
+	}
+}
+
+func TestLeagueStore(t *testing.T) {
+	s := testStore(t)
+	l := testLeague()
+	l.Assign("Premier", 98588)
+
+	err := s.PutLeague(l)
+	if err != nil {
+		t.Fatalf("Failed storing league: %v", err)
+	}
+
+	stored, err := s.League("Winter")
+	if err != nil {
+		t.Fatalf("Failed reading league: %v", err)
+	}
+	if len(stored.Divisions) != 2 || len(stored.Fixtures) != 2 || stored.Divisions[0].Riders[0] != 98588 {
+		t.Errorf("Unexpected stored league %v", stored)
+	}
+
+	if _, err := s.League("Summer"); err == nil {
+		t.Errorf("Expected error for missing league")
+	}
+	if err := s.PutLeague(NewLeague("../escape", 0)); err == nil {
+		t.Errorf("Expected error for bad league name")
+	}
+}
@@ -0,0 +1,117 @@
+package zp
+
+import (
+	"sort"
+	"time"
+)
+
+// Time constants for the rolling training load, in days
+const (
+	fitnessDays = 42
+	fatigueDays = 7
+)
+
+// Load holds training load metrics for one event
+type Load struct {
+	Duration time.Duration
+	NP       float64 // Normalized power in watts
+	IF       float64 // Intensity factor: NP as a fraction of FTP
+	TSS      float64 // Training stress score: an hour at FTP scores 100
+}
+
+// TrainingLoad is a rider's rolling training load on a given date
+type TrainingLoad struct {
+	Date    time.Time
+	Fitness float64 // Chronic training load: exponentially weighted daily TSS over 42 days
+	Fatigue float64 // Acute training load: exponentially weighted daily TSS over 7 days
+	Form    float64 // Training stress balance: Fitness - Fatigue
+}
+
+// EventDuration is how long the rider was riding in this event. For races it's the
+// finishing time, otherwise the duration of the ride.
+func (e Event) EventDuration() time.Duration {
+	secs := float64(e.Time)
+	if secs <= 0 {
+		secs = float64(e.Duration)
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// Load calculates the training load for this event. If there's no NP we fall back to
+// average power, and ok is false if there isn't enough data to say anything.
+func (e Event) Load() (l Load, ok bool) {
+	l.Duration = e.EventDuration()
+	l.NP = float64(e.NP)
+	if l.NP <= 0 {
+		l.NP = float64(e.AvgPower)
+	}
+
+	ftp := float64(e.Ftp)
+	if ftp <= 0 || l.NP <= 0 || l.Duration <= 0 {
+		return l, false
+	}
+
+	l.IF = l.NP / ftp
+	l.TSS = l.Duration.Hours() * l.IF * l.IF * 100
+	return l, true
+}
+
+// RollingLoad works out the rider's training load for each day from their first event up
+// to the given date, and returns the daily values.
+func RollingLoad(events []Event, until time.Time) []TrainingLoad {
+	daily := make(map[time.Time]float64)
+	var first time.Time
+	for _, e := range events {
+		l, ok := e.Load()
+		if !ok || e.EventDateSecs == 0 || e.EventDate.After(until) {
+			continue
+		}
+
+		day := truncateDay(e.EventDate)
+		daily[day] += l.TSS
+		if first.IsZero() || day.Before(first) {
+			first = day
+		}
+	}
+
+	if first.IsZero() {
+		return nil
+	}
+
+	var loads []TrainingLoad
+	var fitness, fatigue float64
+	for day := first; !day.After(truncateDay(until)); day = day.AddDate(0, 0, 1) {
+		tss := daily[day]
+
+		// Form is based on the load coming into the day, before today's ride
+		form := fitness - fatigue
+		fitness += (tss - fitness) / fitnessDays
+		fatigue += (tss - fatigue) / fatigueDays
+		loads = append(loads, TrainingLoad{
+			Date:    day,
+			Fitness: fitness,
+			Fatigue: fatigue,
+			Form:    form,
+		})
+	}
+
+	return loads
+}
+
+// LoadOn finds the training load on the given date from a list of daily loads
+func LoadOn(loads []TrainingLoad, date time.Time) TrainingLoad {
+	day := truncateDay(date)
+	i := sort.Search(len(loads), func(i int) bool {
+		return !loads[i].Date.Before(day)
+	})
+
+	if i < len(loads) && loads[i].Date.Equal(day) {
+		return loads[i]
+	}
+	return TrainingLoad{Date: day}
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,73 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEventLoad(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	l, ok := events[0].Load()
+	if !ok {
+		t.Fatalf("Expected load for first event")
+	}
+
+	// 156W NP for 1557s with FTP 170
+	if math.Abs(l.IF-0.918) > 0.001 || math.Abs(l.TSS-36.4) > 0.1 {
+		t.Errorf("Got IF %.3f, TSS %.1f, expected 0.918, 36.4", l.IF, l.TSS)
+	}
+
+	// The social ride has a duration rather than a race time
+	l, ok = events[13].Load()
+	if !ok || l.Duration != time.Hour {
+		t.Errorf("Got %v, %v for ride, expected an hour", l, ok)
+	}
+
+	if _, ok := (Event{NP: 200, Time: 3600}).Load(); ok {
+		t.Errorf("Shouldn't get load without FTP")
+	}
+
+	l, ok = (Event{AvgPower: 200, Ftp: 200, Time: 3600}).Load()
+	if !ok || l.TSS != 100 {
+		t.Errorf("Expected average power to be used without NP, got %v", l)
+	}
+}
+
+func TestRollingLoad(t *testing.T) {
+	start := time.Date(2021, 1, 1, 18, 0, 0, 0, time.UTC)
+	var events []Event
+	for i := 0; i < 100; i++ {
+		d := start.AddDate(0, 0, i)
+		events = append(events, Event{EventDate: d, EventDateSecs: EventDateType(d.Unix()), NP: 200, Ftp: 200, Time: 3600})
+	}
+
+	loads := RollingLoad(events, start.AddDate(0, 0, 99))
+	if len(loads) != 100 {
+		t.Fatalf("Got %d days of load, expected 100", len(loads))
+	}
+
+	// 100 TSS every day, so fatigue should settle at 100 quickly and fitness should
+	// be on its way there
+	last := loads[99]
+	if math.Abs(last.Fatigue-100) > 1 || last.Fitness < 85 || last.Fitness > 100 {
+		t.Errorf("Unexpected load %v", last)
+	}
+	if last.Form >= 0 {
+		t.Errorf("Expected negative form when training every day, got %v", last.Form)
+	}
+
+	l := LoadOn(loads, start.AddDate(0, 0, 10))
+	if l.Fitness != loads[10].Fitness {
+		t.Errorf("Got %v expected %v", l, loads[10])
+	}
+
+	l = LoadOn(loads, start.AddDate(1, 0, 0))
+	if l.Fitness != 0 {
+		t.Errorf("Expected no load outside range, got %v", l)
+	}
+}
@@ -0,0 +1,87 @@
+package zp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Locale says how numbers and dates are written for people whose spreadsheets expect
+// something other than 3.4 and 2021-02-03
+type Locale struct {
+	Name       string
+	Decimal    string // Decimal separator
+	DateLayout string // Layout for time.Format
+	Comma      rune   // CSV field separator, which can't also be the decimal separator
+}
+
+// Locales we know how to write, by name
+var Locales = map[string]Locale{
+	"en-gb": {Decimal: ".", DateLayout: "02/01/2006", Comma: ','},
+	"en-us": {Decimal: ".", DateLayout: "01/02/2006", Comma: ','},
+	"de":    {Decimal: ",", DateLayout: "02.01.2006", Comma: ';'},
+	"es":    {Decimal: ",", DateLayout: "02/01/2006", Comma: ';'},
+	"fr":    {Decimal: ",", DateLayout: "02/01/2006", Comma: ';'},
+	"it":    {Decimal: ",", DateLayout: "02/01/2006", Comma: ';'},
+	"nl":    {Decimal: ",", DateLayout: "02-01-2006", Comma: ';'},
+	"pt":    {Decimal: ",", DateLayout: "02/01/2006", Comma: ';'},
+}
+
+// DefaultLocale leaves output as it is, with a decimal point and ISO dates
+var DefaultLocale = Locale{Decimal: ".", DateLayout: "2006-01-02", Comma: ','}
+
+// ParseLocale finds the locale with this name, such as de or en-gb. Regions we don't
+// have, such as de-at, fall back to the language. An empty name is the DefaultLocale.
+func ParseLocale(name string) (Locale, error) {
+	name = strings.ToLower(strings.Replace(name, "_", "-", -1))
+	if name == "" {
+		return DefaultLocale, nil
+	}
+
+	l, ok := Locales[name]
+	if !ok {
+		l, ok = Locales[strings.SplitN(name, "-", 2)[0]]
+	}
+	if !ok {
+		names := make([]string, 0, len(Locales))
+		for n := range Locales {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Locale{}, fmt.Errorf("unknown locale %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	l.Name = name
+	return l, nil
+}
+
+var (
+	decimalPattern  = regexp.MustCompile(`^-?[0-9]+\.[0-9]+$`)
+	raceTimePattern = regexp.MustCompile(`^[0-9]+(:[0-9]{2}){1,2}\.[0-9]+$`)
+	isoDatePattern  = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+)
+
+// Localize rewrites a column value for the locale. Decimal numbers and race times such
+// as 1:02:03.456 get its decimal separator, and dates its layout. Anything else, such as
+// a name that happens to contain a number, is left alone.
+func (l Locale) Localize(s string) string {
+	switch {
+	case decimalPattern.MatchString(s) || raceTimePattern.MatchString(s):
+		return strings.Replace(s, ".", l.Decimal, 1)
+	case isoDatePattern.MatchString(s):
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t.Format(l.DateLayout)
+		}
+	}
+	return s
+}
+
+// LocalizeRow rewrites each value in a row for the locale
+func (l Locale) LocalizeRow(row []string) []string {
+	out := make([]string, len(row))
+	for i, s := range row {
+		out[i] = l.Localize(s)
+	}
+	return out
+}
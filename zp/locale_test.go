@@ -0,0 +1,48 @@
+package zp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	cases := map[string]string{"": "", "de": "de", "DE_at": "de-at", "en-GB": "en-gb", "fr-CA": "fr-ca"}
+	for name, expected := range cases {
+		l, err := ParseLocale(name)
+		if err != nil || l.Name != expected {
+			t.Errorf("Got %+v, %v for %q, expected %q", l, err, name, expected)
+		}
+	}
+
+	if l, _ := ParseLocale("de-AT"); l.Decimal != "," || l.Comma != ';' {
+		t.Errorf("Expected de-at to fall back to de, got %+v", l)
+	}
+	if _, err := ParseLocale("xx"); err == nil {
+		t.Errorf("Expected error for unknown locale")
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	row := []string{"Ann 3.4", "3.4", "-0.25", "1261784", "2021-02-03", "1:02:03.456", "59:30.1", "2021-13-45", "0001-01-01", "1.2.3", ""}
+
+	de, err := ParseLocale("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Ann 3.4", "3,4", "-0,25", "1261784", "03.02.2021", "1:02:03,456", "59:30,1", "2021-13-45", "01.01.0001", "1.2.3", ""}
+	if got := de.LocalizeRow(row); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+
+	us, _ := ParseLocale("en-us")
+	if got := us.Localize("2021-02-03"); got != "02/03/2021" {
+		t.Errorf("Got %s for en-us", got)
+	}
+	if got := us.Localize("3.4"); got != "3.4" {
+		t.Errorf("Got %s for en-us", got)
+	}
+
+	if got := DefaultLocale.LocalizeRow(row); !reflect.DeepEqual(got, row) {
+		t.Errorf("Default locale changed %q to %q", row, got)
+	}
+}
@@ -0,0 +1,73 @@
+package zp
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockTimeout is how long to wait for another process to finish with a file
+var lockTimeout = 30 * time.Second
+
+// staleLock is how old a lock has to be before we decide whoever took it has died.
+// Locks are only held while a file is read, merged and written back, which takes well
+// under a second.
+const staleLock = time.Minute
+
+// lockFile stops other processes (and goroutines) reading and rewriting the file at the
+// same time, so that cron jobs and the server can share a directory without losing each
+// other's changes. It works by creating path.lock exclusively, which works the same on
+// every platform and filesystem. Call the returned function to release the lock.
+func lockFile(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	wait := 5 * time.Millisecond
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("locking %s: %v", path, err)
+		}
+
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLock {
+			breakLock(lock, info)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", lock)
+		}
+
+		time.Sleep(wait)
+		if wait < 200*time.Millisecond {
+			wait *= 2
+		}
+	}
+}
+
+// LockFile takes the same lock as the store, for files kept alongside it, such as the
+// server's tokens
+func LockFile(path string) (unlock func(), err error) {
+	return lockFile(path)
+}
+
+// breakLock gets rid of a lock whose holder seems to have died. Another process may have
+// broken it already and taken a fresh lock since we looked, so rather than remove whatever
+// is there now, it moves the lock aside and only throws it away if it's the stale one.
+func breakLock(lock string, stale os.FileInfo) {
+	aside := fmt.Sprintf("%s.%d-%d", lock, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lock, aside); err != nil {
+		// Someone else got there first
+		return
+	}
+	info, err := os.Stat(aside)
+	if err == nil && (!os.SameFile(info, stale) || !info.ModTime().Equal(stale.ModTime())) {
+		// That was someone's fresh lock, which may even have reused the stale one's
+		// inode, so put it back. Link won't replace a lock taken in the meantime.
+		os.Link(aside, lock)
+	}
+	os.Remove(aside)
+}
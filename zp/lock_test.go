@@ -0,0 +1,98 @@
+package zp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentPutEvents(t *testing.T) {
+	dir := testStore(t).Dir
+
+	// Separate stores on the same directory, as if they were separate processes
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := &FileStore{Dir: dir}
+			err := s.PutEvents(1, []Event{{Zwid: 1, Zid: strconv.Itoa(i)}})
+			if err != nil {
+				t.Errorf("Failed storing events: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s := &FileStore{Dir: dir}
+	events, err := s.Events(1)
+	if err != nil || len(events) != 20 {
+		t.Errorf("Got %d events, expected 20: %v", len(events), err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "events", "*.lock"))
+	if len(files) != 0 {
+		t.Errorf("Lock files left behind: %v", files)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zplock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	old := lockTimeout
+	lockTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { lockTimeout = old })
+
+	path := filepath.Join(dir, "file.json")
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Failed taking lock: %v", err)
+	}
+	if _, err := lockFile(path); err == nil {
+		t.Errorf("Expected timeout while the file is locked")
+	}
+	unlock()
+
+	// A lock left behind by a process that died is eventually broken
+	err = ioutil.WriteFile(path+".lock", []byte("123\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockFile(path); err == nil {
+		t.Errorf("Expected timeout for a recent lock")
+	}
+	stale := time.Now().Add(-2 * staleLock)
+	os.Chtimes(path+".lock", stale, stale)
+	unlock, err = lockFile(path)
+	if err != nil {
+		t.Fatalf("Failed to break stale lock: %v", err)
+	}
+	unlock()
+
+	// Someone else broke the stale lock and took a fresh one since we looked at it
+	lock := path + ".lock"
+	must(t, ioutil.WriteFile(lock, []byte("123\n"), 0644))
+	os.Chtimes(lock, stale, stale)
+	info, err := os.Stat(lock)
+	must(t, err)
+	must(t, os.Remove(lock))
+	unlock, err = lockFile(path)
+	must(t, err)
+	breakLock(lock, info)
+	if _, err := os.Stat(lock); err != nil {
+		t.Errorf("Broke someone else's fresh lock: %v", err)
+	}
+	unlock()
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Left files behind: %v", files)
+	}
+}
@@ -0,0 +1,111 @@
+package zp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maintenance waits out ZwiftPower's maintenance windows and outages, so that a long
+// import such as a nightly backfill pauses rather than fails. When a request gets a
+// maintenance page, or a 502, 503 or 504 from the proxy in front of ZwiftPower, its
+// Middleware tries the request again after First, then doubles the wait each time
+// ZwiftPower is still down, up to Max between tries, and honours any Retry-After. Once
+// the request gets through, it carries on as if nothing happened. It gives up and hands
+// back the last response, which is then an ErrMaintenance, once it has waited for Limit
+// in all, or when the request's context is done. Only GET and HEAD requests are tried
+// again.
+type Maintenance struct {
+	First time.Duration // Wait before trying again the first time
+	Max   time.Duration // Longest wait between tries
+	Limit time.Duration // Longest to wait in all; zero means until the context is done
+}
+
+// NewMaintenance makes a Maintenance that waits up to limit for ZwiftPower to come back,
+// trying again after a minute, then two, four and so on up to a quarter of an hour
+func NewMaintenance(limit time.Duration) *Maintenance {
+	return &Maintenance{First: time.Minute, Max: 15 * time.Minute, Limit: limit}
+}
+
+// Middleware holds on to requests while ZwiftPower is down, probing until it's back
+func (m *Maintenance) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err != nil || (req.Method != http.MethodGet && req.Method != http.MethodHead) || !isDown(resp) {
+			return resp, err
+		}
+
+		var waited time.Duration
+		wait := m.First
+		for {
+			if err == nil {
+				if secs, e := strconv.Atoi(resp.Header.Get("Retry-After")); e == nil && time.Duration(secs)*time.Second > wait {
+					wait = time.Duration(secs) * time.Second
+				}
+			}
+			if m.Limit > 0 && waited+wait > m.Limit {
+				wait = m.Limit - waited
+			}
+			if wait <= 0 {
+				log.Printf("ZwiftPower is still down after %v, giving up on %s", waited, req.URL)
+				return resp, err
+			}
+
+			if err == nil {
+				resp.Body.Close()
+				log.Printf("ZwiftPower is down for maintenance (status %d), trying %s again in %v", resp.StatusCode, req.URL, wait)
+			} else {
+				log.Printf("ZwiftPower is down for maintenance (%v), trying %s again in %v", err, req.URL, wait)
+			}
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-req.Context().Done():
+				t.Stop()
+				return nil, req.Context().Err()
+			}
+			waited += wait
+
+			// Connection errors are part of the outage while we're waiting for it to end
+			resp, err = next.RoundTrip(req)
+			if err == nil && !isDown(resp) {
+				log.Printf("ZwiftPower is back after %v, carrying on", waited)
+				return resp, nil
+			}
+
+			wait *= 2
+			if m.Max > 0 && wait > m.Max {
+				wait = m.Max
+			}
+		}
+	})
+}
+
+// isDown is true if the response says ZwiftPower is down for maintenance. It peeks at the
+// start of HTML bodies, leaving them to be read as before.
+func isDown(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK && !isOutage(resp.StatusCode) {
+		return false
+	}
+	if resp.StatusCode == http.StatusOK && !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return false
+	}
+
+	start, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(start), resp.Body), Closer: resp.Body}
+	if isChallenge(resp.Header, start) {
+		return false
+	}
+	return isOutage(resp.StatusCode) || isMaintenancePage(start)
+}
+
+// peekedBody is a response body with its start read and put back
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,106 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// downResponses is a transport that answers with each of the responses in turn, given as
+// status, content type and body
+func downResponses(t *testing.T, responses ...string) (http.RoundTripper, *[]time.Time) {
+	var sent []time.Time
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, time.Now())
+		if len(responses) == 0 {
+			t.Fatalf("Unexpected request %d", len(sent))
+		}
+		parts := strings.SplitN(responses[0], " ", 3)
+		responses = responses[1:]
+		status := map[string]int{"200": 200, "503": 503, "502": 502}[parts[0]]
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{parts[1]}},
+			Body:       ioutil.NopCloser(strings.NewReader(parts[2])),
+		}, nil
+	}), &sent
+}
+
+func TestMaintenance(t *testing.T) {
+	transport, sent := downResponses(t,
+		"503 text/html <html>Bad gateway</html>",
+		"200 text/html <html><body><h1>ZwiftPower is currently down for maintenance</h1></body></html>",
+		"502 text/plain -",
+		`200 application/json {"data": []}`,
+	)
+	m := &Maintenance{First: 10 * time.Millisecond, Max: 25 * time.Millisecond}
+	client := &http.Client{Transport: Chain(transport, m.Middleware)}
+
+	data, err := getJSON(context.Background(), client, "http://zp.test/cache3/results/1_view.json")
+	if err != nil {
+		t.Fatalf("Expected to wait until ZwiftPower was back, got %v", err)
+	}
+	if string(data) != `{"data": []}` {
+		t.Errorf("Got %q", data)
+	}
+	if len(*sent) != 4 {
+		t.Fatalf("Expected 4 requests, got %d", len(*sent))
+	}
+	for i, min := range []time.Duration{10, 20, 25} {
+		if gap := (*sent)[i+1].Sub((*sent)[i]); gap < min*time.Millisecond {
+			t.Errorf("Try %d only waited %v", i+1, gap)
+		}
+	}
+}
+
+func TestMaintenanceLimit(t *testing.T) {
+	transport, sent := downResponses(t,
+		"503 text/html <html>Down for maintenance</html>",
+		"503 text/html <html>Down for maintenance</html>",
+		"503 text/html <html>Down for maintenance</html>",
+	)
+	m := &Maintenance{First: 10 * time.Millisecond, Max: time.Second, Limit: 25 * time.Millisecond}
+	client := &http.Client{Transport: Chain(transport, m.Middleware)}
+
+	_, err := getJSON(context.Background(), client, "http://zp.test/cache3/results/1_view.json")
+	if !errors.Is(err, ErrMaintenance) || errors.Is(err, ErrChallenged) {
+		t.Errorf("Expected maintenance error, got %v", err)
+	}
+	if !retryable(err) {
+		t.Errorf("Maintenance should be retried")
+	}
+	if len(*sent) != 3 {
+		t.Errorf("Expected to try three times within the limit, got %d", len(*sent))
+	}
+}
+
+func TestMaintenanceNotDown(t *testing.T) {
+	transport, sent := downResponses(t,
+		"503 text/html <html><head><title>Just a moment...</title></head></html>",
+		"200 text/html <html><body>Results</body></html>",
+	)
+	m := &Maintenance{First: time.Hour}
+	client := &http.Client{Transport: Chain(transport, m.Middleware)}
+
+	_, err := getJSON(context.Background(), client, "http://zp.test/cache3/results/1_view.json")
+	if !errors.Is(err, ErrChallenged) || errors.Is(err, ErrMaintenance) {
+		t.Errorf("Bot checks aren't maintenance, got %v", err)
+	}
+
+	resp, err := client.Get("http://zp.test/events.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "<html><body>Results</body></html>" {
+		t.Errorf("Peeking spoilt the body: %q", body)
+	}
+	if len(*sent) != 2 {
+		t.Errorf("Expected no retries, got %d requests", len(*sent))
+	}
+}
@@ -0,0 +1,148 @@
+package zp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Mask records a rider who doesn't want their name on public pages, such as exported
+// results or posts to chat channels. Their data is still kept in full, and is shown as
+// usual wherever it's private.
+type Mask struct {
+	Zwid  int
+	Alias string `json:",omitempty"` // Shown instead of their name; their initials if empty
+	Date  time.Time
+	Note  string `json:",omitempty"` // Such as how they asked
+}
+
+// Masks are the riders whose names are masked, by Zwid
+type Masks map[int]Mask
+
+// Has is true if the rider's name is masked
+func (m Masks) Has(riderID int) bool {
+	_, ok := m[riderID]
+	return ok
+}
+
+// Name is what to show for the rider: their alias or initials, such as "J. S.", if
+// they're masked, or the name as it is if not
+func (m Masks) Name(riderID int, name string) string {
+	mask, ok := m[riderID]
+	switch {
+	case !ok:
+		return name
+	case mask.Alias != "":
+		return mask.Alias
+	}
+	var initials []string
+	for _, r := range Initials(name) {
+		initials = append(initials, string(r)+".")
+	}
+	return strings.Join(initials, " ")
+}
+
+// MaskRiders gets copies of the riders with masked names
+func (m Masks) MaskRiders(riders []Rider) []Rider {
+	if len(m) == 0 {
+		return riders
+	}
+	masked := make([]Rider, len(riders))
+	for i, r := range riders {
+		r.Name = m.Name(r.Zwid, r.Name)
+		masked[i] = r
+	}
+	return masked
+}
+
+// MaskEvents gets copies of the events with masked riders' names, such as their rows in
+// an event's results
+func (m Masks) MaskEvents(events []Event) []Event {
+	if len(m) == 0 {
+		return events
+	}
+	masked := make([]Event, len(events))
+	for i, e := range events {
+		e.Name = m.Name(e.Zwid, e.Name)
+		masked[i] = e
+	}
+	return masked
+}
+
+// List gets the masks in order of Zwid
+func (m Masks) List() []Mask {
+	list := make([]Mask, 0, len(m))
+	for _, mask := range m {
+		list = append(list, mask)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Zwid < list[j].Zwid
+	})
+	return list
+}
+
+func (s *FileStore) masksPath() string {
+	return filepath.Join(s.Dir, "masks.json")
+}
+
+// Masks gets the riders whose names are masked
+func (s *FileStore) Masks() (Masks, error) {
+	var list []Mask
+	err := s.read(s.masksPath(), &list)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	m := make(Masks, len(list))
+	for _, mask := range list {
+		m[mask.Zwid] = mask
+	}
+	return m, nil
+}
+
+// Mask adds the rider to the masks, or changes their alias if they're already there.
+// Nothing else in the store changes.
+func (s *FileStore) Mask(mask Mask) error {
+	if mask.Zwid == 0 {
+		return fmt.Errorf("masking needs a rider ID")
+	}
+
+	path := s.masksPath()
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	masks, err := s.Masks()
+	if err != nil {
+		return err
+	}
+	if mask.Date.IsZero() {
+		mask.Date = time.Now().UTC()
+	}
+	masks[mask.Zwid] = mask
+	return s.write(path, masks.List())
+}
+
+// Unmask removes the rider from the masks, so their name is shown everywhere again
+func (s *FileStore) Unmask(riderID int) error {
+	path := s.masksPath()
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	masks, err := s.Masks()
+	if err != nil {
+		return err
+	}
+	if !masks.Has(riderID) {
+		return fmt.Errorf("rider %d isn't masked", riderID)
+	}
+	delete(masks, riderID)
+	return s.write(path, masks.List())
+}
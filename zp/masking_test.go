@@ -0,0 +1,69 @@
+package zp
+
+import (
+	"testing"
+)
+
+func TestMaskedName(t *testing.T) {
+	masks := Masks{1: {Zwid: 1}}
+	cases := map[string]string{
+		"Jane Smith":              "J. S.",
+		"jane van der Berg":       "J. B.",
+		"Élodie Martin [TEAM]":    "É. M.",
+		"Bob (REVO) O&#39;Connor": "B. O.",
+		"":                        "",
+	}
+	for name, expected := range cases {
+		if got := masks.Name(1, name); got != expected {
+			t.Errorf("Masked %q is %q, expected %q", name, got, expected)
+		}
+	}
+	if got := masks.Name(2, "Jane Smith"); got != "Jane Smith" {
+		t.Errorf("Unmasked rider is %q", got)
+	}
+}
+
+func TestMasks(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	riders := []Rider{{Zwid: 1, Name: "Liz Rice"}, {Zwid: 2, Name: "Bob Smith"}, {Zwid: 3, Name: "Ann Other"}}
+	for _, r := range riders {
+		must(t, s.PutRider(r))
+	}
+
+	must(t, s.Mask(Mask{Zwid: 1}))
+	must(t, s.Mask(Mask{Zwid: 2, Alias: "Rider 2", Note: "asked in chat"}))
+	masks, err := s.Masks()
+	if err != nil || len(masks) != 2 || masks[2].Date.IsZero() {
+		t.Fatalf("Got masks %v: %v", masks, err)
+	}
+
+	masked := masks.MaskRiders(riders)
+	for i, expected := range []string{"L. R.", "Rider 2", "Ann Other"} {
+		if masked[i].Name != expected {
+			t.Errorf("Rider %d is %q, expected %q", masked[i].Zwid, masked[i].Name, expected)
+		}
+	}
+	if riders[0].Name != "Liz Rice" {
+		t.Errorf("Masking changed the original riders")
+	}
+	events := masks.MaskEvents([]Event{{Zwid: 1, Name: "Liz Rice"}, {Zwid: 3, Name: "Ann Other"}})
+	if events[0].Name != "L. R." || events[1].Name != "Ann Other" {
+		t.Errorf("Got masked events %v", events)
+	}
+
+	// The store keeps their details in full
+	if r, err := s.Rider(1); err != nil || r.Name != "Liz Rice" {
+		t.Errorf("Stored rider is %+v: %v", r, err)
+	}
+
+	must(t, s.Unmask(1))
+	if err := s.Unmask(1); err == nil {
+		t.Errorf("Expected an error unmasking someone who isn't masked")
+	}
+	if masks, _ := s.Masks(); masks.Has(1) || !masks.Has(2) {
+		t.Errorf("Got masks %v after unmasking", masks)
+	}
+}
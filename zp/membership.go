@@ -0,0 +1,58 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Membership is a rider who is on the roster of more than one club
+type Membership struct {
+	Zwid  int
+	Name  string
+	Clubs []int // In order of club ID
+}
+
+func (m Membership) String() string {
+	return fmt.Sprintf("%s (%d) is in clubs %v", m.Name, m.Zwid, m.Clubs)
+}
+
+// DuplicateMembers finds riders who are on more than one of the clubs' rosters, which
+// leagues that only let riders race for one club need to know about. The rosters are
+// keyed by club ID, as from Client.Club. Riders are listed in name order.
+func DuplicateMembers(rosters map[int][]Rider) []Membership {
+	clubIDs := make([]int, 0, len(rosters))
+	for id := range rosters {
+		clubIDs = append(clubIDs, id)
+	}
+	sort.Ints(clubIDs)
+
+	byZwid := make(map[int]*Membership)
+	for _, id := range clubIDs {
+		for _, r := range rosters[id] {
+			m, ok := byZwid[r.Zwid]
+			if !ok {
+				m = &Membership{Zwid: r.Zwid, Name: r.Name}
+				byZwid[r.Zwid] = m
+			}
+			// A roster can list someone twice; that doesn't make them a member twice
+			if len(m.Clubs) == 0 || m.Clubs[len(m.Clubs)-1] != id {
+				m.Clubs = append(m.Clubs, id)
+			}
+		}
+	}
+
+	var duplicates []Membership
+	for _, m := range byZwid {
+		if len(m.Clubs) > 1 {
+			duplicates = append(duplicates, *m)
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		a, b := normalizeName(duplicates[i].Name), normalizeName(duplicates[j].Name)
+		if a != b {
+			return a < b
+		}
+		return duplicates[i].Zwid < duplicates[j].Zwid
+	})
+	return duplicates
+}
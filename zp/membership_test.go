@@ -0,0 +1,27 @@
+package zp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDuplicateMembers(t *testing.T) {
+	rosters := map[int][]Rider{
+		2672:  {{Zwid: 1, Name: "Liz Rice"}, {Zwid: 2, Name: "Bob Smith"}, {Zwid: 3, Name: "alice Jones"}},
+		12345: {{Zwid: 2, Name: "Bob Smith"}, {Zwid: 4, Name: "Dan Brown"}, {Zwid: 4, Name: "Dan Brown"}},
+		99:    {{Zwid: 3, Name: "Alice Jones"}, {Zwid: 2, Name: "Bob Smith"}},
+	}
+
+	got := DuplicateMembers(rosters)
+	expected := []Membership{
+		{Zwid: 3, Name: "Alice Jones", Clubs: []int{99, 2672}},
+		{Zwid: 2, Name: "Bob Smith", Clubs: []int{99, 2672, 12345}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+
+	if got := DuplicateMembers(map[int][]Rider{2672: rosters[2672]}); len(got) != 0 {
+		t.Errorf("Expected no duplicates in one club, got %v", got)
+	}
+}
@@ -0,0 +1,183 @@
+package zp
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	_ Store = (*FileStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// MemoryStore keeps everything in memory, which is handy for tests and for one-off runs
+// that don't need to remember anything
+type MemoryStore struct {
+	mu          sync.Mutex
+	riders      map[int]Rider
+	events      map[int][]Event
+	snapshots   map[string][]Rider
+	checkpoints map[string]map[int]bool
+}
+
+// NewMemoryStore makes an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		riders:      make(map[int]Rider),
+		events:      make(map[int][]Event),
+		snapshots:   make(map[string][]Rider),
+		checkpoints: make(map[string]map[int]bool),
+	}
+}
+
+// PutRider saves the rider's data, replacing anything we had before
+func (s *MemoryStore) PutRider(r Rider) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.riders[r.Zwid] = r
+	return nil
+}
+
+// Rider gets the stored data for this rider
+func (s *MemoryStore) Rider(riderID int) (Rider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.riders[riderID]
+	if !ok {
+		return r, ErrNotFound
+	}
+	return r, nil
+}
+
+// QueryRiders gets the stored riders that match the query, in order of Zwid
+func (s *MemoryStore) QueryRiders(q RiderQuery) ([]Rider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	riders := make([]Rider, 0, len(s.riders))
+	for _, r := range s.riders {
+		riders = append(riders, r)
+	}
+	sort.Slice(riders, func(i, j int) bool {
+		return riders[i].Zwid < riders[j].Zwid
+	})
+	return q.filter(riders), nil
+}
+
+// PutEvents saves the events for this rider, updating any we already had
+func (s *MemoryStore) PutEvents(riderID int, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events[riderID] = MergeEvents(s.events[riderID], events)
+	return nil
+}
+
+// Events gets the stored events for this rider
+func (s *MemoryStore) Events(riderID int) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, ok := s.events[riderID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]Event(nil), events...), nil
+}
+
+// Update changes a rider's data as a single transaction
+func (s *MemoryStore) Update(riderID int, fn func(tx *RiderTx) error) error {
+	tx := NewRiderTx(riderID)
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rider, events := tx.Staged()
+	if events != nil {
+		s.events[riderID] = MergeEvents(s.events[riderID], events)
+	}
+	if rider != nil {
+		s.riders[riderID] = *rider
+	}
+	return nil
+}
+
+// PutSnapshot saves the riders as they are now under this name
+func (s *MemoryStore) PutSnapshot(name string, riders []Rider) error {
+	if err := checkName("snapshot", name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[name] = append([]Rider(nil), riders...)
+	return nil
+}
+
+// Snapshot gets the riders saved under this name
+func (s *MemoryStore) Snapshot(name string) ([]Rider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	riders, ok := s.snapshots[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]Rider(nil), riders...), nil
+}
+
+// Snapshots lists the names of the saved snapshots, in order
+func (s *MemoryStore) Snapshots() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.snapshots))
+	for name := range s.snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Checkpoint gets the set of rider IDs recorded as done for the named job
+func (s *MemoryStore) Checkpoint(name string) (map[int]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	done := make(map[int]bool, len(s.checkpoints[name]))
+	for id := range s.checkpoints[name] {
+		done[id] = true
+	}
+	return done, nil
+}
+
+// SaveCheckpoint records which rider IDs are done for the named job
+func (s *MemoryStore) SaveCheckpoint(name string, done map[int]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make(map[int]bool, len(done))
+	for id, ok := range done {
+		if ok {
+			saved[id] = true
+		}
+	}
+	s.checkpoints[name] = saved
+	return nil
+}
+
+// ClearCheckpoint removes the checkpoint for the named job
+func (s *MemoryStore) ClearCheckpoint(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.checkpoints, name)
+	return nil
+}
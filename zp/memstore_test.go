@@ -0,0 +1,20 @@
+package zp
+
+import "testing"
+
+func TestMemoryStore(t *testing.T) {
+	checkStore(t, NewMemoryStore())
+}
+
+func TestMemoryStoreCopies(t *testing.T) {
+	s := NewMemoryStore()
+	s.PutEvents(1, []Event{{Zwid: 1, Zid: "1", Pos: 5}})
+
+	events, _ := s.Events(1)
+	events[0].Pos = 1
+
+	stored, _ := s.Events(1)
+	if stored[0].Pos != 5 {
+		t.Errorf("Changing returned events changed the store")
+	}
+}
@@ -0,0 +1,298 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Sources of a rider's details, for merging (see MergeDetails)
+const (
+	SourceZwiftPower  = "zwiftpower"  // The rider's latest ZwiftPower events
+	SourceZwift       = "zwift"       // The rider's Zwift profile, which is what they entered in the game
+	SourceZwiftRacing = "zwiftracing" // zwiftracing.app
+)
+
+// Details that more than one source has for a rider
+const (
+	FieldWeight   = "weight"
+	FieldFTP      = "ftp"
+	FieldCategory = "category"
+)
+
+// SourceDetails is what one source says about a rider. Zero values mean the source
+// doesn't say.
+type SourceDetails struct {
+	Source   string
+	Weight   float64 // kg
+	FTP      float64 // watts
+	Category string
+}
+
+// Precedence lists the sources to take each detail from, most trusted first. Sources
+// that aren't listed for a detail are used after those that are, in the order they
+// were given.
+type Precedence map[string][]string
+
+// DefaultPrecedence trusts Zwift for weight, as riders keep it up to date to race, and
+// ZwiftPower for FTP and category, as those are what its race categories go by
+var DefaultPrecedence = Precedence{
+	FieldWeight:   {SourceZwift, SourceZwiftPower, SourceZwiftRacing},
+	FieldFTP:      {SourceZwiftPower, SourceZwiftRacing, SourceZwift},
+	FieldCategory: {SourceZwiftPower, SourceZwiftRacing},
+}
+
+// ParsePrecedence reads a precedence such as "weight=zwift,zwiftpower;ftp=zwiftracing",
+// starting from DefaultPrecedence for the details it doesn't mention
+func ParsePrecedence(s string) (Precedence, error) {
+	p := make(Precedence)
+	for field, sources := range DefaultPrecedence {
+		p[field] = sources
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		field := strings.TrimSpace(kv[0])
+		if _, ok := DefaultPrecedence[field]; !ok || len(kv) != 2 {
+			return nil, fmt.Errorf("can't parse %q: expected %s, %s or %s=source,source...", part, FieldWeight, FieldFTP, FieldCategory)
+		}
+
+		var sources []string
+		for _, source := range strings.Split(kv[1], ",") {
+			switch source = strings.TrimSpace(source); source {
+			case SourceZwiftPower, SourceZwift, SourceZwiftRacing:
+				sources = append(sources, source)
+			default:
+				return nil, fmt.Errorf("unknown source %q for %s: use %s, %s or %s", source, field, SourceZwiftPower, SourceZwift, SourceZwiftRacing)
+			}
+		}
+		p[field] = sources
+	}
+	return p, nil
+}
+
+// MergeTolerance is how far apart sources' numbers can be without counting as a
+// conflict: a kilo of weight, or five watts of FTP
+var MergeTolerance = map[string]float64{
+	FieldWeight: 1,
+	FieldFTP:    5,
+}
+
+// MergedDetails are a rider's details taken from whichever source is trusted most for
+// each, with the conflicts between sources that were found along the way
+type MergedDetails struct {
+	Weight    float64
+	FTP       float64
+	Category  string
+	From      map[string]string // The source each detail came from
+	Conflicts []Conflict
+}
+
+// Conflict is a detail that sources disagree about
+type Conflict struct {
+	Field  string
+	Chosen string            // The source whose value was used
+	Values map[string]string // Each source's value
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: using %s %s, %v", c.Field, c.Chosen, c.Values[c.Chosen], c.Values)
+}
+
+// MergeDetails combines what the sources say about a rider. Each detail comes from the
+// first source in the precedence for it that has a value, and a conflict is recorded if
+// other sources have values that differ by more than MergeTolerance. The precedence
+// is DefaultPrecedence if nil.
+func MergeDetails(sources []SourceDetails, precedence Precedence) MergedDetails {
+	if precedence == nil {
+		precedence = DefaultPrecedence
+	}
+
+	m := MergedDetails{From: make(map[string]string)}
+	number := func(field string, value func(SourceDetails) float64) float64 {
+		values := make(map[string]float64)
+		for _, s := range sources {
+			if v := value(s); v > 0 {
+				values[s.Source] = v
+			}
+		}
+		chosen, ok := pickSource(field, sources, precedence, func(s string) bool { _, ok := values[s]; return ok })
+		if !ok {
+			return 0
+		}
+		m.From[field] = chosen
+
+		for _, v := range values {
+			if math.Abs(v-values[chosen]) > MergeTolerance[field] {
+				c := Conflict{Field: field, Chosen: chosen, Values: make(map[string]string)}
+				for s, v := range values {
+					c.Values[s] = strconv.FormatFloat(v, 'f', -1, 64)
+				}
+				m.Conflicts = append(m.Conflicts, c)
+				break
+			}
+		}
+		return values[chosen]
+	}
+
+	m.Weight = number(FieldWeight, func(s SourceDetails) float64 { return s.Weight })
+	m.FTP = number(FieldFTP, func(s SourceDetails) float64 { return s.FTP })
+
+	categories := make(map[string]string)
+	for _, s := range sources {
+		if s.Category != "" {
+			categories[s.Source] = s.Category
+		}
+	}
+	if chosen, ok := pickSource(FieldCategory, sources, precedence, func(s string) bool { _, ok := categories[s]; return ok }); ok {
+		m.Category = categories[chosen]
+		m.From[FieldCategory] = chosen
+		for _, c := range categories {
+			if c != m.Category {
+				m.Conflicts = append(m.Conflicts, Conflict{Field: FieldCategory, Chosen: chosen, Values: categories})
+				break
+			}
+		}
+	}
+	return m
+}
+
+// pickSource finds the most trusted source that has a value for the field
+func pickSource(field string, sources []SourceDetails, precedence Precedence, has func(string) bool) (string, bool) {
+	for _, s := range precedence[field] {
+		if has(s) {
+			return s, true
+		}
+	}
+	for _, s := range sources {
+		if has(s.Source) {
+			return s.Source, true
+		}
+	}
+	return "", false
+}
+
+// ZwiftPowerDetails are the rider's weight, FTP and category from their latest
+// ZwiftPower events that have them
+func ZwiftPowerDetails(events []Event) SourceDetails {
+	d := SourceDetails{Source: SourceZwiftPower}
+	var weightDate, ftpDate, categoryDate int64
+	for _, e := range events {
+		if e.EventDateSecs == 0 {
+			continue
+		}
+		date := int64(e.EventDateSecs)
+		if e.Weight > 0 && date >= weightDate {
+			d.Weight, weightDate = float64(e.Weight), date
+		}
+		if e.Wftp > 0 && date >= ftpDate {
+			d.FTP, ftpDate = float64(e.Wftp), date
+		}
+		if e.IsRace() && e.Category != "" && date >= categoryDate {
+			d.Category, categoryDate = e.Category, date
+		}
+	}
+	return d
+}
+
+// ImportZwiftDetails gets the weight and FTP from the rider's Zwift profile, using
+// this access token
+func ImportZwiftDetails(ctx context.Context, client *http.Client, token string, riderID int) (SourceDetails, error) {
+	var p zwiftProfile
+	err := getZwiftJSON(ctx, client, token, fmt.Sprintf(ZwiftProfileURL, riderID), "Zwift profile", &p)
+	if err != nil {
+		return SourceDetails{}, err
+	}
+	return p.details(), nil
+}
+
+func (p zwiftProfile) details() SourceDetails {
+	return SourceDetails{Source: SourceZwift, Weight: p.Weight / 1000, FTP: p.FTP}
+}
+
+// ZwiftRacingURL is where zwiftracing.app has a rider's details
+var ZwiftRacingURL = "https://zwift-ranking.herokuapp.com/public/riders/%d"
+
+// zwiftRacingRider is the part of a zwiftracing.app rider we're interested in
+type zwiftRacingRider struct {
+	Weight     float64 `json:"weight"`
+	ZPCategory string  `json:"zpCategory"`
+	ZPFTP      float64 `json:"zpFTP"`
+}
+
+// ImportZwiftRacingDetails gets the rider's weight, FTP and category from
+// zwiftracing.app, using this API key
+func ImportZwiftRacingDetails(ctx context.Context, client *http.Client, key string, riderID int) (d SourceDetails, err error) {
+	ctx, span := startSpan(ctx, "ImportZwiftRacingDetails", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	url := fmt.Sprintf(ZwiftRacingURL, riderID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return d, err
+	}
+	req.Header.Set("Authorization", key)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return d, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return d, checkResponse(resp, url)
+	}
+
+	var r zwiftRacingRider
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return d, &ParseError{What: "zwiftracing.app rider", Err: err}
+	}
+	return SourceDetails{Source: SourceZwiftRacing, Weight: r.Weight, FTP: r.ZPFTP, Category: r.ZPCategory}, nil
+}
+
+// mergeDetails fills in the profile's Details from ZwiftPower, and from Zwift and
+// zwiftracing.app if the client has the credentials for them. Those that can't be
+// reached are noted in Missing.
+func (p *RiderProfile) mergeDetails(ctx context.Context, c *Client, riderID int) {
+	sources := []SourceDetails{ZwiftPowerDetails(p.Events)}
+
+	if c.ZwiftToken != "" {
+		var zwift zwiftProfile
+		err := getZwiftJSON(ctx, c.HTTP, c.ZwiftToken, fmt.Sprintf(ZwiftProfileURL, riderID), "Zwift profile", &zwift)
+		if err != nil {
+			log.Printf("No Zwift profile for rider %d: %v", riderID, err)
+			p.Missing = append(p.Missing, SourceZwift)
+		} else {
+			sources = append(sources, zwift.details())
+			if ce, ok := zwift.enforcement(); ok {
+				p.Enforcement = &ce
+				p.CECategory = ce.Category
+			}
+		}
+	}
+	if c.ZwiftRacingKey != "" {
+		d, err := ImportZwiftRacingDetails(ctx, c.HTTP, c.ZwiftRacingKey, riderID)
+		if err != nil {
+			log.Printf("No zwiftracing.app details for rider %d: %v", riderID, err)
+			p.Missing = append(p.Missing, SourceZwiftRacing)
+		} else {
+			sources = append(sources, d)
+		}
+	}
+
+	p.Details = MergeDetails(sources, c.Precedence)
+	for _, conflict := range p.Details.Conflicts {
+		log.Printf("Sources disagree for rider %d: %v", riderID, conflict)
+	}
+}
@@ -0,0 +1,104 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMergeDetails(t *testing.T) {
+	sources := []SourceDetails{
+		{Source: SourceZwiftRacing, Weight: 70, FTP: 250, Category: "B"},
+		{Source: SourceZwiftPower, Weight: 72.5, FTP: 252, Category: "B"},
+		{Source: SourceZwift, FTP: 240},
+	}
+
+	m := MergeDetails(sources, nil)
+	if m.Weight != 72.5 || m.FTP != 252 || m.Category != "B" {
+		t.Errorf("Got %+v", m)
+	}
+	if m.From[FieldWeight] != SourceZwiftPower || m.From[FieldFTP] != SourceZwiftPower {
+		t.Errorf("Got sources %v", m.From)
+	}
+	if len(m.Conflicts) != 2 || m.Conflicts[0].Field != FieldWeight || m.Conflicts[1].Field != FieldFTP {
+		t.Fatalf("Got conflicts %v", m.Conflicts)
+	}
+	if c := m.Conflicts[1]; c.Chosen != SourceZwiftPower || c.Values[SourceZwift] != "240" || len(c.Values) != 3 {
+		t.Errorf("Got conflict %v", c)
+	}
+
+	p, err := ParsePrecedence("weight=zwiftracing ; category=zwift,zwiftpower")
+	must(t, err)
+	m = MergeDetails(sources, p)
+	if m.Weight != 70 || m.From[FieldCategory] != SourceZwiftPower || m.From[FieldFTP] != SourceZwiftPower {
+		t.Errorf("Got %+v with precedence %v", m, p)
+	}
+
+	// Sources that aren't in the precedence come after, in order
+	m = MergeDetails(sources, Precedence{})
+	if m.From[FieldWeight] != SourceZwiftRacing || m.From[FieldFTP] != SourceZwiftRacing {
+		t.Errorf("Got sources %v", m.From)
+	}
+
+	for _, s := range []string{"weight", "height=zwift", "ftp=strava"} {
+		if _, err := ParsePrecedence(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestRiderProfileDetails(t *testing.T) {
+	ts := withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/profile/1261784_all.json":
+			fmt.Fprint(w, testdata)
+		case "/api/profiles/1261784":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+			fmt.Fprint(w, `{"weight": 56500, "ftp": 175, "competitionMetrics": {"racingScore": 412.5, "category": "B", "categoryWomen": "C"}}`)
+		case "/public/riders/1261784":
+			if r.Header.Get("Authorization") != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+			fmt.Fprint(w, `{"riderId": 1261784, "weight": 56.3, "zpCategory": "B", "zpFTP": 163}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	oldZwift, oldRacing := ZwiftProfileURL, ZwiftRacingURL
+	ZwiftProfileURL = ts.URL + "/api/profiles/%d"
+	ZwiftRacingURL = ts.URL + "/public/riders/%d"
+	defer func() { ZwiftProfileURL, ZwiftRacingURL = oldZwift, oldRacing }()
+
+	client := Wrap(ts.Client())
+	p, err := client.RiderProfile(context.Background(), 1261784)
+	must(t, err)
+	if len(p.Details.From) != 3 || p.Details.From[FieldWeight] != SourceZwiftPower || len(p.Details.Conflicts) != 0 {
+		t.Errorf("Expected details from ZwiftPower alone, got %+v", p.Details)
+	}
+
+	client.ZwiftToken = "token"
+	client.ZwiftRacingKey = "key"
+	p, err = client.RiderProfile(context.Background(), 1261784)
+	must(t, err)
+	d := p.Details
+	if d.Weight != 56.5 || d.FTP != 163 || d.Category != "C" || d.From[FieldWeight] != SourceZwift {
+		t.Errorf("Got details %+v", d)
+	}
+	if len(d.Conflicts) != 2 || d.Conflicts[0].Field != FieldFTP || d.Conflicts[1].Field != FieldCategory {
+		t.Errorf("Got conflicts %v", d.Conflicts)
+	}
+	if p.Enforcement == nil || *p.Enforcement != (CategoryEnforcement{Category: "B", CategoryWomen: "C", RacingScore: 412.5}) || p.CECategory != "B" {
+		t.Errorf("Got Category Enforcement %+v, CE category %q", p.Enforcement, p.CECategory)
+	}
+
+	client.ZwiftRacingKey = "wrong"
+	p, err = client.RiderProfile(context.Background(), 1261784)
+	must(t, err)
+	if p.Missing[len(p.Missing)-1] != SourceZwiftRacing {
+		t.Errorf("Expected zwiftracing.app to be missing, got %v", p.Missing)
+	}
+}
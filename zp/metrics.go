@@ -0,0 +1,220 @@
+package zp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointClass groups requests by the part of ZwiftPower they go to, so that when imports
+// slow down or fail it's clear which part is to blame
+type EndpointClass struct {
+	Surface string // cache3, api3, site for ZwiftPower's pages, or the host for anything else, such as Zwift's API
+	Kind    string // club, profile, event, league, or other
+}
+
+func (c EndpointClass) String() string {
+	return c.Surface + " " + c.Kind
+}
+
+// ClassifyURL finds the class of endpoint a request is for
+func ClassifyURL(u *url.URL) EndpointClass {
+	switch {
+	case strings.HasPrefix(u.Path, "/cache3/"):
+		parts := strings.Split(u.Path, "/")
+		kinds := map[string]string{"teams": "club", "profile": "profile", "results": "event", "lg": "league"}
+		return EndpointClass{Surface: "cache3", Kind: kindOr(kinds[parts[2]])}
+	case path.Base(u.Path) == "api3.php":
+		do := u.Query().Get("do")
+		var kind string
+		switch {
+		case do == "team_riders":
+			kind = "club"
+		case strings.HasPrefix(do, "profile_"):
+			kind = "profile"
+		case strings.HasPrefix(do, "event_"):
+			kind = "event"
+		case strings.HasPrefix(do, "league_"):
+			kind = "league"
+		}
+		return EndpointClass{Surface: "api3", Kind: kindOr(kind)}
+	case path.Ext(u.Path) == ".php":
+		kinds := map[string]string{"team.php": "club", "profile.php": "profile", "events.php": "event", "league.php": "league"}
+		return EndpointClass{Surface: "site", Kind: kindOr(kinds[path.Base(u.Path)])}
+	}
+	return EndpointClass{Surface: u.Hostname(), Kind: "other"}
+}
+
+func kindOr(kind string) string {
+	if kind == "" {
+		return "other"
+	}
+	return kind
+}
+
+// durationBuckets are the upper bounds of the response time histogram
+var durationBuckets = []time.Duration{
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+// EndpointStats is how one class of endpoint has been responding
+type EndpointStats struct {
+	Class    EndpointClass
+	Requests int
+	Failed   int           // Requests with no response at all, such as timeouts
+	Statuses map[int]int   // Responses by status code
+	Duration time.Duration // Total time waiting for responses, up to their headers
+	Slowest  time.Duration
+
+	buckets []int // Responses no slower than each of durationBuckets
+}
+
+// Mean is the average time to a response
+func (s EndpointStats) Mean() time.Duration {
+	answered := s.Requests - s.Failed
+	if answered == 0 {
+		return 0
+	}
+	return s.Duration / time.Duration(answered)
+}
+
+// ErrorRate is the fraction of requests that failed, or got a 4xx or 5xx response
+func (s EndpointStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	errors := s.Failed
+	for status, n := range s.Statuses {
+		if status >= 400 {
+			errors += n
+		}
+	}
+	return float64(errors) / float64(s.Requests)
+}
+
+// RequestMetrics counts requests and times responses for each class of endpoint. Add its
+// Middleware to a client after any pacing, so that time spent waiting for a turn doesn't
+// count, and share one between clients. It's safe for concurrent use.
+type RequestMetrics struct {
+	mu    sync.Mutex
+	stats map[EndpointClass]*EndpointStats
+}
+
+// NewRequestMetrics makes an empty set of request metrics
+func NewRequestMetrics() *RequestMetrics {
+	return &RequestMetrics{stats: make(map[EndpointClass]*EndpointStats)}
+}
+
+// Middleware records each request's class, outcome and how long the response took
+func (m *RequestMetrics) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		m.observe(ClassifyURL(req.URL), status, time.Since(start))
+		return resp, err
+	})
+}
+
+// observe records a request, with status 0 if there was no response
+func (m *RequestMetrics) observe(class EndpointClass, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.stats[class]
+	if !ok {
+		s = &EndpointStats{Class: class, Statuses: make(map[int]int), buckets: make([]int, len(durationBuckets))}
+		m.stats[class] = s
+	}
+	s.Requests++
+	if status == 0 {
+		s.Failed++
+		return
+	}
+	s.Statuses[status]++
+	s.Duration += d
+	if d > s.Slowest {
+		s.Slowest = d
+	}
+	for i, b := range durationBuckets {
+		if d <= b {
+			s.buckets[i]++
+		}
+	}
+}
+
+// Stats reports each class of endpoint we've made requests to, in order
+func (m *RequestMetrics) Stats() []EndpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]EndpointStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		copied := *s
+		copied.Statuses = make(map[int]int, len(s.Statuses))
+		for status, n := range s.Statuses {
+			copied.Statuses[status] = n
+		}
+		copied.buckets = append([]int(nil), s.buckets...)
+		list = append(list, copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Class.String() < list[j].Class.String()
+	})
+	return list
+}
+
+// WritePrometheus writes the metrics in Prometheus' text format, as
+// zwiftpower_requests_total by status code ("failed" when there was no response) and the
+// zwiftpower_request_duration_seconds histogram, each labelled with the surface and kind
+func (m *RequestMetrics) WritePrometheus(w io.Writer) error {
+	stats := m.Stats()
+	var b strings.Builder
+
+	b.WriteString("# HELP zwiftpower_requests_total Requests to ZwiftPower and the services alongside it.\n")
+	b.WriteString("# TYPE zwiftpower_requests_total counter\n")
+	for _, s := range stats {
+		var statuses []int
+		for status := range s.Statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "zwiftpower_requests_total{%s,code=%q} %d\n", s.Class.labels(), strconv.Itoa(status), s.Statuses[status])
+		}
+		if s.Failed > 0 {
+			fmt.Fprintf(&b, "zwiftpower_requests_total{%s,code=\"failed\"} %d\n", s.Class.labels(), s.Failed)
+		}
+	}
+
+	b.WriteString("# HELP zwiftpower_request_duration_seconds Time to the response's headers.\n")
+	b.WriteString("# TYPE zwiftpower_request_duration_seconds histogram\n")
+	for _, s := range stats {
+		labels := s.Class.labels()
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(&b, "zwiftpower_request_duration_seconds_bucket{%s,le=%q} %d\n", labels, strconv.FormatFloat(bound.Seconds(), 'g', -1, 64), s.buckets[i])
+		}
+		answered := s.Requests - s.Failed
+		fmt.Fprintf(&b, "zwiftpower_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, answered)
+		fmt.Fprintf(&b, "zwiftpower_request_duration_seconds_sum{%s} %g\n", labels, s.Duration.Seconds())
+		fmt.Fprintf(&b, "zwiftpower_request_duration_seconds_count{%s} %d\n", labels, answered)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c EndpointClass) labels() string {
+	return fmt.Sprintf("surface=%q,kind=%q", c.Surface, c.Kind)
+}
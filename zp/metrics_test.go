@@ -0,0 +1,84 @@
+package zp
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyURL(t *testing.T) {
+	cases := map[string]EndpointClass{
+		"https://zwiftpower.com/cache3/teams/2672_riders.json":                 {"cache3", "club"},
+		"https://zwiftpower.com/cache3/profile/123_all.json":                   {"cache3", "profile"},
+		"https://zwiftpower.com/cache3/results/456_signups.json":               {"cache3", "event"},
+		"https://zwiftpower.com/cache3/lg/7_standings.json":                    {"cache3", "league"},
+		"https://zwiftpower.com/cache3/global/site_ann.json":                   {"cache3", "other"},
+		"https://zwiftpower.com/api3.php?do=team_riders&id=2672":               {"api3", "club"},
+		"https://zwiftpower.com/api3.php?do=profile_victims&z=123":             {"api3", "profile"},
+		"https://zwiftpower.com/api3.php?do=event_results&zid=456":             {"api3", "event"},
+		"https://zwiftpower.com/api3.php?do=league_event_results&id=7&zid=456": {"api3", "league"},
+		"https://zwiftpower.com/profile.php?z=123":                             {"site", "profile"},
+		"https://us-or-rly101.zwift.com/api/profiles/123":                      {"us-or-rly101.zwift.com", "other"},
+	}
+	for raw, expected := range cases {
+		u, _ := url.Parse(raw)
+		if got := ClassifyURL(u); got != expected {
+			t.Errorf("%s: expected %s, got %s", raw, expected, got)
+		}
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.Contains(req.URL.Path, "profile"):
+			time.Sleep(120 * time.Millisecond)
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		case strings.Contains(req.URL.Path, "results"):
+			return nil, errors.New("timed out")
+		}
+		return &http.Response{StatusCode: 503, Body: http.NoBody}, nil
+	})
+	m := NewRequestMetrics()
+	client := &http.Client{Transport: Chain(transport, m.Middleware)}
+	for _, u := range []string{"/cache3/profile/1_all.json", "/cache3/profile/2_all.json", "/cache3/results/3_view.json", "/api3.php?do=team_riders&id=1"} {
+		resp, err := client.Get("http://zp.test" + u)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	stats := m.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("Expected three classes, got %+v", stats)
+	}
+	club, event, profile := stats[0], stats[1], stats[2]
+	if club.Class != (EndpointClass{"api3", "club"}) || club.Statuses[503] != 1 || club.ErrorRate() != 1 {
+		t.Errorf("Unexpected club stats %+v", club)
+	}
+	if event.Class != (EndpointClass{"cache3", "event"}) || event.Failed != 1 || event.Mean() != 0 || event.ErrorRate() != 1 {
+		t.Errorf("Unexpected event stats %+v", event)
+	}
+	if profile.Requests != 2 || profile.Statuses[200] != 2 || profile.ErrorRate() != 0 || profile.Mean() < 120*time.Millisecond || profile.Slowest < profile.Mean() {
+		t.Errorf("Unexpected profile stats %+v", profile)
+	}
+
+	var b strings.Builder
+	must(t, m.WritePrometheus(&b))
+	for _, line := range []string{
+		`zwiftpower_requests_total{surface="api3",kind="club",code="503"} 1`,
+		`zwiftpower_requests_total{surface="cache3",kind="event",code="failed"} 1`,
+		`zwiftpower_requests_total{surface="cache3",kind="profile",code="200"} 2`,
+		`zwiftpower_request_duration_seconds_bucket{surface="cache3",kind="profile",le="0.1"} 0`,
+		`zwiftpower_request_duration_seconds_bucket{surface="cache3",kind="profile",le="30"} 2`,
+		`zwiftpower_request_duration_seconds_bucket{surface="cache3",kind="profile",le="+Inf"} 2`,
+		`zwiftpower_request_duration_seconds_count{surface="cache3",kind="event"} 0`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Expected %s in\n%s", line, b.String())
+		}
+	}
+}
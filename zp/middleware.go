@@ -0,0 +1,41 @@
+package zp
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the transport that a client uses to make requests, so that
+// callers can add things like auth, tracing, caching or recording without needing
+// changes to this package
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc lets an ordinary function act as an http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps the transport in the middleware. The first middleware is outermost,
+// so it sees each request first and each response last. A nil transport means
+// Transport, or http.DefaultTransport if that isn't set either.
+func Chain(transport http.RoundTripper, middleware ...Middleware) http.RoundTripper {
+	if transport == nil {
+		transport = Transport
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		transport = middleware[i](transport)
+	}
+	return transport
+}
+
+// Use adds middleware to an existing client. It wraps whatever the client already does,
+// so it sees requests before any middleware added earlier.
+func Use(client *http.Client, middleware ...Middleware) {
+	client.Transport = Chain(client.Transport, middleware...)
+}
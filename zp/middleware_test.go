@@ -0,0 +1,48 @@
+package zp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// tagger is middleware that records the order it sees requests and responses
+func tagger(name string, log *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*log = append(*log, name+" request")
+			req.Header.Add("X-Tag", name)
+			resp, err := next.RoundTrip(req)
+			*log = append(*log, name+" response")
+			return resp, err
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":[{"name":"%s","zwid":1}]}`, strings.Join(r.Header["X-Tag"], ","))
+	})
+
+	var log []string
+	client, err := NewClient(tagger("first", &log), tagger("second", &log))
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	Use(client, tagger("outer", &log))
+
+	riders, err := ImportZP(client, 2672)
+	if err != nil {
+		t.Fatalf("Failed importing club: %v", err)
+	}
+
+	if riders[0].Name != "outer,first,second" {
+		t.Errorf("Unexpected tags %s", riders[0].Name)
+	}
+
+	expected := "outer request,first request,second request,second response,first response,outer response"
+	if strings.Join(log, ",") != expected {
+		t.Errorf("Got %v expected %s", log, expected)
+	}
+}
@@ -0,0 +1,110 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RaceMilestones are the numbers of races a rider has ridden that are worth celebrating
+var RaceMilestones = []int{50, 100, 250, 500, 1000}
+
+// ClubRideMilestones are the numbers of rides by everyone in the club together that are
+// worth celebrating
+var ClubRideMilestones = []int{1000, 5000, 10000, 25000, 50000, 100000}
+
+// Milestone is something a rider or the club achieved, for the digest or a post
+type Milestone struct {
+	Date time.Time
+	Zwid int // The rider, or 0 for the club as a whole
+	Name string
+	Text string
+}
+
+// Milestones finds what the club's riders reached from this time onwards: a rider's
+// 100th race, their first win in a category, or the club's 10,000th ride together.
+// Events should be the riders' whole histories, so there is something to count from.
+func Milestones(events []Event, since time.Time) []Milestone {
+	var sorted []Event
+	seen := make(map[eventKey]bool)
+	for _, e := range events {
+		k := eventKey{e.Zwid, e.Zid}
+		if e.EventDateSecs == 0 || seen[k] {
+			continue
+		}
+		seen[k] = true
+		sorted = append(sorted, e)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].EventDate.Before(sorted[j].EventDate)
+	})
+
+	var milestones []Milestone
+	races := make(map[int]int)
+	wins := make(map[int]map[string]bool)
+	rides := 0
+	for _, e := range sorted {
+		recent := !e.EventDate.Before(since)
+
+		rides++
+		if recent && isMilestone(rides, ClubRideMilestones) {
+			milestones = append(milestones, Milestone{
+				Date: e.EventDate,
+				Name: "The club",
+				Text: fmt.Sprintf("clocked up its %s ride, with %s in %s", countOrdinal(rides), e.RiderName(), e.EventTitle),
+			})
+		}
+
+		if !e.IsRace() {
+			continue
+		}
+		races[e.Zwid]++
+		if recent && isMilestone(races[e.Zwid], RaceMilestones) {
+			milestones = append(milestones, Milestone{
+				Date: e.EventDate,
+				Zwid: e.Zwid,
+				Name: e.RiderName(),
+				Text: fmt.Sprintf("raced for the %s time, in %s", countOrdinal(races[e.Zwid]), e.EventTitle),
+			})
+		}
+
+		category := strings.ToUpper(e.Category)
+		if e.PositionInCat != 1 || !penCategories[category] {
+			continue
+		}
+		if wins[e.Zwid] == nil {
+			wins[e.Zwid] = make(map[string]bool)
+		}
+		if !wins[e.Zwid][category] && recent {
+			milestones = append(milestones, Milestone{
+				Date: e.EventDate,
+				Zwid: e.Zwid,
+				Name: e.RiderName(),
+				Text: fmt.Sprintf("won in category %s for the first time, in %s", category, e.EventTitle),
+			})
+		}
+		wins[e.Zwid][category] = true
+	}
+	return milestones
+}
+
+func isMilestone(n int, milestones []int) bool {
+	for _, m := range milestones {
+		if n == m {
+			return true
+		}
+	}
+	return false
+}
+
+// countOrdinal is like ordinal, but with commas for thousands, e.g. "10,000th"
+func countOrdinal(n int) string {
+	digits := strconv.Itoa(n)
+	suffix := strings.TrimPrefix(ordinal(n), digits)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits + suffix
+}
@@ -0,0 +1,86 @@
+package zp
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMilestones(t *testing.T) {
+	oldRides := ClubRideMilestones
+	ClubRideMilestones = []int{10}
+	t.Cleanup(func() { ClubRideMilestones = oldRides })
+
+	start := time.Date(2021, 1, 1, 18, 0, 0, 0, time.UTC)
+	since := start.AddDate(0, 0, 100)
+	var events []Event
+	add := func(zwid int, name string, day int, eventType string, category string, pos int) {
+		date := start.AddDate(0, 0, day)
+		events = append(events, Event{
+			Zwid: zwid, Name: name, Zid: strconv.Itoa(day), EventTitle: "Race " + strconv.Itoa(day),
+			EventType: eventType, Category: category, PositionInCat: pos,
+			EventDate: date, EventDateSecs: EventDateType(date.Unix()),
+		})
+	}
+
+	// Ann's 100th race is after since, and she won in B before but A only after
+	for day := 1; day <= 100; day++ {
+		pos := 2
+		if day == 50 {
+			pos = 1
+		}
+		add(1, "Ann", day, "TYPE_RACE", "B", pos)
+	}
+	add(1, "Ann", 101, "TYPE_RACE", "A", 1)
+	add(1, "Ann", 102, "TYPE_RACE", "B", 1)
+	add(1, "Ann", 103, "TYPE_RACE", "A", 1)
+
+	// Bob's rides aren't races, and E isn't a category to win
+	add(2, "Bob", 101, "TYPE_RIDE", "", 1)
+	add(2, "Bob", 102, "TYPE_RACE", "E", 1)
+
+	// Duplicates and undated events don't count
+	events = append(events, events[len(events)-1], Event{Zwid: 3, EventType: "TYPE_RACE", PositionInCat: 1, Category: "C"})
+
+	milestones := Milestones(events, since)
+	var texts []string
+	for _, m := range milestones {
+		texts = append(texts, m.Name+" "+m.Text)
+	}
+	expected := []string{
+		"Ann raced for the 100th time, in Race 100",
+		"Ann won in category A for the first time, in Race 101",
+	}
+	if strings.Join(texts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Got %q, expected %q", texts, expected)
+	}
+	if milestones[0].Zwid != 1 || !milestones[0].Date.Equal(start.AddDate(0, 0, 100)) {
+		t.Errorf("Unexpected milestone %+v", milestones[0])
+	}
+
+	// The club's 10th ride is early on
+	milestones = Milestones(events, start)
+	if milestones[0].Name != "The club" || milestones[0].Zwid != 0 || !strings.Contains(milestones[0].Text, "10th ride, with Ann in Race 10") {
+		t.Errorf("Expected the club's 10th ride, got %+v", milestones[0])
+	}
+
+	d := NewDigest(2672, events, since, since.AddDate(0, 0, 7), false)
+	var b bytes.Buffer
+	if err := WriteDigest(&b, d, ""); err != nil {
+		t.Fatalf("Failed writing digest: %v", err)
+	}
+	if !strings.Contains(b.String(), "\nMilestones\n\nAnn raced for the 100th time, in Race 100\n") {
+		t.Errorf("Missing milestones in %q", b.String())
+	}
+}
+
+func TestCountOrdinal(t *testing.T) {
+	cases := map[int]string{1: "1st", 100: "100th", 1000: "1,000th", 10000: "10,000th", 1234567: "1,234,567th", 25002: "25,002nd"}
+	for n, expected := range cases {
+		if s := countOrdinal(n); s != expected {
+			t.Errorf("Got %s for %d, expected %s", s, n, expected)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mirror keeps a local copy of the ZwiftPower JSON that matters to a club: the roster,
+// every rider's profile, and the results of events they have ridden recently. Its
+// Handler serves the copy with the same paths as the Cache3 backend, so other tools can
+// set BaseURL to point at the mirror and never need to talk to ZwiftPower themselves.
+type Mirror struct {
+	Client *http.Client
+	Dir    string
+	Pause  time.Duration // Time to wait between requests to ZwiftPower
+	Days   int           // Mirror results for events from this many days ago
+	DryRun bool          // Fetch everything, but only log what would be written
+	Limits Limits        // How big and stale the copy can get, pruned after each refresh
+
+	OptOuts  OptOuts  // Riders whose profiles aren't copied
+	Progress Progress // Told how far each refresh has got, through the riders and then the events
+}
+
+// MirrorStatus records how the latest refresh went
+type MirrorStatus struct {
+	ClubID    int
+	Refreshed time.Time
+	Riders    int
+	Events    int
+	Failed    []string // URLs that couldn't be fetched
+}
+
+// localPath is where the mirror keeps the file for a Cache3 URL
+func (m *Mirror) localPath(cache3URL string) string {
+	return filepath.Join(m.Dir, filepath.FromSlash(strings.TrimPrefix(cache3URL, BaseURL)))
+}
+
+// copy fetches one URL from ZwiftPower, storing it where the Cache3 URL would find it
+func (m *Mirror) copy(ctx context.Context, url string, cache3URL string) ([]byte, error) {
+	data, err := DefaultBackend.getJSON(ctx, m.Client, url)
+	if err != nil {
+		return nil, err
+	}
+
+	path := m.localPath(cache3URL)
+	if m.DryRun {
+		log.Printf("Dry run: would write %s (%d bytes)", path, len(data))
+		return data, nil
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = writeFile(path, data)
+	}
+	return data, err
+}
+
+// Refresh updates the mirror for this club. It goes through the club's riders one at a
+// time, pausing between requests, and carries on past any that fail. Those are listed
+// in the status, which is also saved as status/<club ID>.json in the mirror.
+func (m *Mirror) Refresh(ctx context.Context, clubID int) (status MirrorStatus, err error) {
+	status = MirrorStatus{ClubID: clubID}
+
+	data, err := m.copy(ctx, DefaultBackend.clubURL(clubID), Cache3.clubURL(clubID))
+	if err != nil {
+		return status, fmt.Errorf("getting club data: %w", err)
+	}
+
+	var c club
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return status, &ParseError{What: "club data", Err: err}
+	}
+
+	since := time.Now().AddDate(0, 0, -m.Days)
+	zids := make(map[string]bool)
+	var order []string
+	for i, r := range c.Data {
+		if m.OptOuts.Has(r.Zwid) {
+			m.Progress.report(i+1, len(c.Data), "")
+			continue
+		}
+		if err := m.wait(ctx); err != nil {
+			return status, err
+		}
+
+		Wrap(m.Client).primeProfile(ctx, r.Zwid)
+		url := DefaultBackend.profileURL(r.Zwid)
+		data, err := m.copy(ctx, url, Cache3.profileURL(r.Zwid))
+		if err == nil {
+			var events []Event
+			events, err = parseEvents(data)
+			for _, e := range events {
+				if !zids[e.Zid] && e.Zid != "" && e.EventDateSecs != 0 && !e.EventDate.Before(since) {
+					zids[e.Zid] = true
+					order = append(order, e.Zid)
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("Mirror %d/%d: failed to copy %s (%d): %v", i+1, len(c.Data), r.Name, r.Zwid, err)
+			status.Failed = append(status.Failed, url)
+			m.Progress.report(i+1, len(c.Data), r.Name)
+			continue
+		}
+		status.Riders++
+		m.Progress.report(i+1, len(c.Data), r.Name)
+	}
+
+	for i, zid := range order {
+		if err := m.wait(ctx); err != nil {
+			return status, err
+		}
+
+		id, err := strconv.Atoi(zid)
+		if err != nil {
+			log.Printf("Mirror: skipping event with ID %q", zid)
+			m.Progress.report(i+1, len(order), "")
+			continue
+		}
+
+		url := DefaultBackend.eventURL(id)
+		_, err = m.copy(ctx, url, Cache3.eventURL(id))
+		m.Progress.report(i+1, len(order), "results for event "+zid)
+		if err != nil {
+			log.Printf("Mirror: failed to copy results for %s: %v", zid, err)
+			status.Failed = append(status.Failed, url)
+			continue
+		}
+		status.Events++
+	}
+
+	status.Refreshed = time.Now()
+	log.Printf("Mirrored club %d: %d riders, %d events, %d failures", clubID, status.Riders, status.Events, len(status.Failed))
+	if err := m.saveStatus(status); err != nil {
+		return status, err
+	}
+	return status, m.prune()
+}
+
+// prune keeps the mirror within its limits, if it has any
+func (m *Mirror) prune() error {
+	if m.Limits == (Limits{}) {
+		return nil
+	}
+	r, err := Prune(m.Dir, m.Limits, time.Now(), m.DryRun)
+	if r.Removed > 0 {
+		log.Printf("Pruned %d files (%d bytes) from the mirror, leaving %d (%d bytes)", r.Removed, r.RemovedBytes, r.Kept, r.KeptBytes)
+	}
+	return err
+}
+
+func (m *Mirror) wait(ctx context.Context) error {
+	select {
+	case <-time.After(m.Pause):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Mirror) saveStatus(status MirrorStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(m.Dir, "status", fmt.Sprintf("%d.json", status.ClubID))
+	if m.DryRun {
+		log.Printf("Dry run: would write %s", path)
+		return nil
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// Handler serves the mirrored files under the same paths as ZwiftPower's cache3, and the
+// status of each club's latest refresh under /status/<club ID>.json
+func (m *Mirror) Handler() http.Handler {
+	files := http.FileServer(http.Dir(m.Dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			http.Error(w, "read only", http.StatusMethodNotAllowed)
+		case r.URL.Path == "/profile.php":
+			// Importers request this first to get ZwiftPower to fill its cache. We have
+			// nothing to do.
+			w.WriteHeader(http.StatusOK)
+		case strings.HasSuffix(r.URL.Path, ".json") &&
+			(strings.HasPrefix(r.URL.Path, "/cache3/") || strings.HasPrefix(r.URL.Path, "/status/")):
+			w.Header().Set("Content-Type", "application/json")
+			files.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
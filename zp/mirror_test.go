@@ -0,0 +1,107 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	requests := 0
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/profile.php":
+		case "/cache3/teams/2672_riders.json":
+			fmt.Fprint(w, `{"data":[{"name":"Özge Yazar","zwid":1261784},{"name":"Gone Missing","zwid":2}]}`)
+		case "/cache3/profile/1261784_all.json":
+			fmt.Fprint(w, testdata)
+		case "/cache3/results/1644250_view.json":
+			fmt.Fprint(w, `{"data":[{"zid":"1644250","zwid":1261784,"pos":9}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "zpmirror")
+	if err != nil {
+		t.Fatalf("Failed making temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Only Özge's latest race is recent enough to mirror its results
+	days := int(time.Since(time.Unix(1612320300, 0)).Hours()/24) + 1
+	var progress []string
+	m := &Mirror{Client: client, Dir: dir, Days: days, DryRun: true, Progress: func(done, total int, current string) {
+		progress = append(progress, fmt.Sprintf("%d/%d %s", done, total, current))
+	}}
+	status, err := m.Refresh(context.Background(), 2672)
+	if err != nil || status.Riders != 1 || status.Events != 1 {
+		t.Errorf("Unexpected dry run status %+v, %v", status, err)
+	}
+	if len(progress) != 3 || progress[1] != "2/2 Gone Missing" || progress[2] != "1/1 results for event 1644250" {
+		t.Errorf("Got progress %v", progress)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Dry run wrote %d files", len(files))
+	}
+
+	m.DryRun = false
+	status, err = m.Refresh(context.Background(), 2672)
+	if err != nil {
+		t.Fatalf("Failed refreshing mirror: %v", err)
+	}
+	if status.Riders != 1 || status.Events != 1 || len(status.Failed) != 1 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "status", "2672.json")); err != nil {
+		t.Errorf("Status not saved: %v", err)
+	}
+
+	// Now import from the mirror instead of ZwiftPower
+	before := requests
+	mirror := httptest.NewServer(m.Handler())
+	defer mirror.Close()
+	BaseURL = mirror.URL
+
+	riders, err := ImportZP(client, 2672)
+	if err != nil || len(riders) != 2 {
+		t.Errorf("Got %d riders from mirror: %v", len(riders), err)
+	}
+	events, err := ImportEvents(client, 1261784)
+	if err != nil || len(events) != 14 {
+		t.Errorf("Got %d events from mirror: %v", len(events), err)
+	}
+	results, err := ImportEventResults(client, 1644250)
+	if err != nil || len(results) != 1 {
+		t.Errorf("Got %d results from mirror: %v", len(results), err)
+	}
+	if _, err := ImportEvents(client, 2); err == nil {
+		t.Errorf("Expected error for rider missing from the mirror")
+	}
+	if requests != before {
+		t.Errorf("Importing from the mirror made %d requests to ZwiftPower", requests-before)
+	}
+
+	for _, path := range []string{"/cache3/", "/cache3/teams/", "/other.json"} {
+		resp, err := http.Get(mirror.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Got status %d for %s", resp.StatusCode, path)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package zp
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NameIndex maps riders' names to their Zwift IDs and back, built up from what has been
+// imported, so tools can work out who "J. Smith" is without asking ZwiftPower
+type NameIndex struct {
+	Names map[int]string // The latest name seen for each rider
+}
+
+// NameMatch is a rider whose name matches a lookup
+type NameMatch struct {
+	Zwid int
+	Name string
+}
+
+// NewNameIndex starts an empty index
+func NewNameIndex() *NameIndex {
+	return &NameIndex{Names: make(map[int]string)}
+}
+
+// Add records the rider's name, replacing any earlier one
+func (x *NameIndex) Add(zwid int, name string) {
+	name = strings.Join(strings.Fields(html.UnescapeString(name)), " ")
+	if zwid == 0 || name == "" {
+		return
+	}
+	if x.Names == nil {
+		x.Names = make(map[int]string)
+	}
+	x.Names[zwid] = name
+}
+
+// AddRiders records the riders' names
+func (x *NameIndex) AddRiders(riders []Rider) {
+	for _, r := range riders {
+		x.Add(r.Zwid, r.Name)
+	}
+}
+
+// AddEvents records the names of everyone in the events, such as an event's results,
+// which include riders from other clubs too
+func (x *NameIndex) AddEvents(events []Event) {
+	for _, e := range events {
+		x.Add(e.Zwid, e.Name)
+	}
+}
+
+// Name gets the rider's name, if we've seen it
+func (x *NameIndex) Name(zwid int) (string, bool) {
+	name, ok := x.Names[zwid]
+	return name, ok
+}
+
+// Lookup finds the riders a name could mean. Case, accents and team tags such as
+// [REVO] don't matter. The best matches are returned, in order of name: riders with
+// exactly this name, or failing that riders whose names match word for word allowing
+// initials ("J. Smith" or "j smith" for "John Smith"), or failing that riders whose
+// names contain it.
+func (x *NameIndex) Lookup(query string) []NameMatch {
+	q := nameWords(query)
+	if len(q) == 0 {
+		return nil
+	}
+
+	var exact, initials, partial []NameMatch
+	joined := strings.Join(q, " ")
+	for zwid, name := range x.Names {
+		words := nameWords(name)
+		m := NameMatch{Zwid: zwid, Name: name}
+		switch {
+		case strings.Join(words, " ") == joined:
+			exact = append(exact, m)
+		case matchesWords(q, words):
+			initials = append(initials, m)
+		case strings.Contains(strings.Join(words, " "), joined):
+			partial = append(partial, m)
+		}
+	}
+
+	for _, matches := range [][]NameMatch{exact, initials, partial} {
+		if len(matches) > 0 {
+			sort.Slice(matches, func(i, j int) bool {
+				if matches[i].Name != matches[j].Name {
+					return matches[i].Name < matches[j].Name
+				}
+				return matches[i].Zwid < matches[j].Zwid
+			})
+			return matches
+		}
+	}
+	return nil
+}
+
+var nameTag = regexp.MustCompile(`[\[(][^\])]*[\])]`)
+
+// nameWords folds a name to lower case words without accents, team tags or full stops
+func nameWords(name string) []string {
+	name = nameTag.ReplaceAllString(html.UnescapeString(name), " ")
+	var b strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Accents, once separated from their letters
+		case r == '.':
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// matchesWords is true if each of the query words matches the name's words in order,
+// either in full or as an initial, with the last query word matching the last name
+func matchesWords(query []string, words []string) bool {
+	if len(words) == 0 || query[len(query)-1] != words[len(words)-1] {
+		return false
+	}
+	i := 0
+	for _, w := range words {
+		if i < len(query) && (query[i] == w || (len([]rune(query[i])) == 1 && strings.HasPrefix(w, query[i]))) {
+			i++
+		}
+	}
+	return i == len(query)
+}
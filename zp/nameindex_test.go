@@ -0,0 +1,81 @@
+package zp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNameIndexLookup(t *testing.T) {
+	x := NewNameIndex()
+	x.AddRiders([]Rider{
+		{Zwid: 1, Name: "John Smith [REVO]"},
+		{Zwid: 2, Name: "Jane  Smith"},
+		{Zwid: 3, Name: "&Ouml;zge Yazar"},
+		{Zwid: 4, Name: "Joe Smithson"},
+		{Zwid: 0, Name: "Nobody"},
+		{Zwid: 5, Name: ""},
+	})
+	x.AddEvents([]Event{{Zwid: 6, Name: "Peter John Smith (ZSUN)"}, {Zwid: 2, Name: "Jane Smith-Jones"}})
+
+	if name, ok := x.Name(3); !ok || name != "Özge Yazar" {
+		t.Errorf("Got %q, %v for rider 3", name, ok)
+	}
+	if _, ok := x.Name(5); ok {
+		t.Errorf("Didn't expect a rider without a name")
+	}
+
+	cases := map[string][]int{
+		"john smith":  {1},
+		"OZGE YAZAR":  {3},
+		"J. Smith":    {1, 6},
+		"j smith":     {1, 6},
+		"P J Smith":   {6},
+		"jane smith":  {2},
+		"smith":       {1, 6},
+		"smit":        {2, 4, 1, 6},
+		"yaz":         {3},
+		"Bob":         nil,
+		"  ":          nil,
+		"smith-jones": {2},
+	}
+	for query, expected := range cases {
+		var zwids []int
+		for _, m := range x.Lookup(query) {
+			zwids = append(zwids, m.Zwid)
+		}
+		if !reflect.DeepEqual(zwids, expected) {
+			t.Errorf("Got %v for %q, expected %v", zwids, query, expected)
+		}
+	}
+}
+
+func TestFileStoreNameIndex(t *testing.T) {
+	s := testStore(t)
+
+	x, err := s.NameIndex()
+	if err != nil || len(x.Names) != 0 {
+		t.Fatalf("Expected empty index, got %v, %v", x, err)
+	}
+
+	err = s.PutRider(Rider{Zwid: 1, Name: "Ann Example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.IndexNames([]Rider{{Zwid: 2, Name: "Bob Example"}}, []Event{{Zwid: 1, Name: "Ann Old Name"}, {Zwid: 3, Name: "Cat Other"}})
+	if err != nil {
+		t.Fatalf("Failed indexing names: %v", err)
+	}
+	err = s.IndexNames(nil, []Event{{Zwid: 3, Name: "Cat Renamed"}})
+	if err != nil {
+		t.Fatalf("Failed indexing names: %v", err)
+	}
+
+	x, err = s.NameIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[int]string{1: "Ann Example", 2: "Bob Example", 3: "Cat Renamed"}
+	if !reflect.DeepEqual(x.Names, expected) {
+		t.Errorf("Got %v, expected %v", x.Names, expected)
+	}
+}
@@ -0,0 +1,86 @@
+package zp
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Disambiguation says what to add to a name to tell riders apart
+type Disambiguation string
+
+const (
+	// ByCountry adds the rider's country, falling back to the Zwid if that's not enough
+	ByCountry Disambiguation = "country"
+
+	// ByZwid adds the rider's Zwift ID
+	ByZwid Disambiguation = "zwid"
+)
+
+// ParseDisambiguation reads country or zwid, as given on the command line
+func ParseDisambiguation(s string) (Disambiguation, error) {
+	switch d := Disambiguation(strings.ToLower(s)); d {
+	case ByCountry, ByZwid:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unknown disambiguation %q, expected %s or %s", s, ByCountry, ByZwid)
+	}
+}
+
+// NameCollision lists riders who share a display name
+type NameCollision struct {
+	Name  string
+	Zwids []int
+}
+
+func (n NameCollision) String() string {
+	return fmt.Sprintf("%d riders called %s: %v", len(n.Zwids), n.Name, n.Zwids)
+}
+
+// normalizeName so that names that look the same to a human compare as equal
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(html.UnescapeString(name)), " "))
+}
+
+// DisambiguateNames makes sure riders' names are unique by adding their country or
+// Zwid where two or more riders share a name. It returns the collisions it found,
+// so that they can be reported.
+func DisambiguateNames(riders []Rider, by Disambiguation) []NameCollision {
+	byName := make(map[string][]int)
+	for i, r := range riders {
+		n := normalizeName(r.Name)
+		byName[n] = append(byName[n], i)
+	}
+
+	var collisions []NameCollision
+	for _, indexes := range byName {
+		if len(indexes) < 2 {
+			continue
+		}
+
+		c := NameCollision{Name: riders[indexes[0]].Name}
+		countries := make(map[string]int)
+		for _, i := range indexes {
+			c.Zwids = append(c.Zwids, riders[i].Zwid)
+			countries[strings.ToUpper(riders[i].Country)]++
+		}
+		sort.Ints(c.Zwids)
+		collisions = append(collisions, c)
+
+		for _, i := range indexes {
+			suffix := strconv.Itoa(riders[i].Zwid)
+			country := strings.ToUpper(riders[i].Country)
+			if by == ByCountry && country != "" && countries[country] == 1 {
+				suffix = country
+			}
+			riders[i].Name = fmt.Sprintf("%s (%s)", riders[i].Name, suffix)
+		}
+	}
+
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Name < collisions[j].Name
+	})
+	return collisions
+}
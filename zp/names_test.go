@@ -0,0 +1,54 @@
+package zp
+
+import (
+	"testing"
+)
+
+func TestDisambiguateNames(t *testing.T) {
+	riders := []Rider{
+		{Name: "John Smith", Zwid: 1, Country: "gb"},
+		{Name: "Liz Rice", Zwid: 2, Country: "gb"},
+		{Name: "john  smith", Zwid: 3, Country: "us"},
+		{Name: "J Bloggs", Zwid: 4, Country: "gb"},
+		{Name: "J Bloggs", Zwid: 5, Country: "gb"},
+		{Name: "J Bloggs", Zwid: 6, Country: "fr"},
+	}
+
+	collisions := DisambiguateNames(riders, ByCountry)
+	if len(collisions) != 2 {
+		t.Fatalf("Got %d collisions, expected 2: %v", len(collisions), collisions)
+	}
+
+	if collisions[0].Name != "J Bloggs" || len(collisions[0].Zwids) != 3 {
+		t.Errorf("Unexpected collision %v", collisions[0])
+	}
+
+	expected := []string{"John Smith (GB)", "Liz Rice", "john  smith (US)", "J Bloggs (4)", "J Bloggs (5)", "J Bloggs (FR)"}
+	for i, r := range riders {
+		if r.Name != expected[i] {
+			t.Errorf("Got %s expected %s", r.Name, expected[i])
+		}
+	}
+
+	riders = []Rider{{Name: "A", Zwid: 1, Country: "gb"}, {Name: "A", Zwid: 2, Country: "us"}}
+	DisambiguateNames(riders, ByZwid)
+	if riders[0].Name != "A (1)" || riders[1].Name != "A (2)" {
+		t.Errorf("Unexpected names %v", riders)
+	}
+}
+
+func TestParseDisambiguation(t *testing.T) {
+	cases := map[string]Disambiguation{"country": ByCountry, "ZWID": ByZwid}
+	for s, expected := range cases {
+		d, err := ParseDisambiguation(s)
+		if err != nil || d != expected {
+			t.Errorf("Got %v, %v for %q, expected %s", d, err, s, expected)
+		}
+	}
+
+	for _, s := range []string{"", "name", "countries"} {
+		if _, err := ParseDisambiguation(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Notification is a message for the club, such as the digest or a reminder about an
+// event. (ZwiftPower already has Events, so here they are Notifications.)
+type Notification struct {
+	Kind  string // What sort of message it is, e.g. NotifyDigest
+	Title string // A subject line, for channels that have one
+	Text  string // The message itself, as plain text
+}
+
+// Kinds of notification
+const (
+	NotifyDigest     = "digest"
+	NotifyReminder   = "reminder"
+	NotifyMilestones = "milestones"
+)
+
+// Notifier sends notifications to somewhere people will read them. Each chat service or
+// other channel is a Notifier; FanOut sends to several at once.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc lets an ordinary function be a Notifier
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+// Notify calls the function
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// SlackNotifier posts to a Slack-compatible incoming webhook, as a message with a text
+// field. Mattermost, Rocket.Chat and Discord's /slack endpoints understand it too.
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client // Defaults to http.DefaultClient
+}
+
+// Notify posts the text
+func (s SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.Client, s.URL, struct {
+		Text string `json:"text"`
+	}{n.Text})
+}
+
+// discordLimit is the most characters Discord accepts in one message
+const discordLimit = 2000
+
+// DiscordNotifier posts to a Discord channel webhook. Long notifications are split into
+// several messages at line breaks, as Discord limits how long each can be.
+type DiscordNotifier struct {
+	URL    string
+	Client *http.Client // Defaults to http.DefaultClient
+}
+
+// Notify posts the text, in as many messages as it needs
+func (d DiscordNotifier) Notify(ctx context.Context, n Notification) error {
+	for _, chunk := range splitText(n.Text, discordLimit) {
+		err := postJSON(ctx, d.Client, d.URL, struct {
+			Content string `json:"content"`
+		}{chunk})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookNotifier posts the whole notification as JSON, with kind, title and text
+// fields, for services of your own
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // Defaults to http.DefaultClient
+}
+
+// Notify posts the notification
+func (h WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, h.Client, h.URL, struct {
+		Kind  string `json:"kind"`
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}{n.Kind, n.Title, n.Text})
+}
+
+// EmailNotifier sends notifications by email through an SMTP server
+type EmailNotifier struct {
+	Addr string // host:port of the SMTP server
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// Notify sends the notification as a plain text email, with the title as its subject
+func (e EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	subject := n.Title
+	if subject == "" {
+		subject = "ZwiftPower " + n.Kind
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(n.Text, "\n", "\r\n", -1))
+
+	err := smtp.SendMail(e.Addr, e.Auth, e.From, e.To, msg.Bytes())
+	if err != nil {
+		return fmt.Errorf("sending email: %v", err)
+	}
+	return nil
+}
+
+// WriterNotifier writes the text of each notification, for when there's nowhere to post
+// it, or to log what would have been posted
+type WriterNotifier struct {
+	W io.Writer
+}
+
+// Notify writes the text, with a newline at the end
+func (w WriterNotifier) Notify(ctx context.Context, n Notification) error {
+	_, err := fmt.Fprintln(w.W, strings.TrimRight(n.Text, "\n"))
+	return err
+}
+
+// Defaults for FanOut
+const (
+	DefaultNotifyAttempts = 3
+	DefaultNotifyBackoff  = 2 * time.Second
+)
+
+// FanOut sends each notification to all of its notifiers. Each one is retried on its own
+// if it fails, and one that keeps failing doesn't stop the others getting the message.
+type FanOut struct {
+	Notifiers []Notifier
+	Attempts  int           // Tries for each notifier, defaulting to DefaultNotifyAttempts
+	Backoff   time.Duration // Wait before the first retry, doubling for each one after
+}
+
+// Notify sends to every notifier, returning an error listing those that still failed
+// after retrying
+func (f FanOut) Notify(ctx context.Context, n Notification) error {
+	attempts := f.Attempts
+	if attempts < 1 {
+		attempts = DefaultNotifyAttempts
+	}
+	backoff := f.Backoff
+	if backoff == 0 {
+		backoff = DefaultNotifyBackoff
+	}
+
+	var failed []string
+	for i, notifier := range f.Notifiers {
+		var err error
+		for attempt := 0; attempt < attempts; attempt++ {
+			if attempt > 0 {
+				wait := backoff * time.Duration(1<<(attempt-1))
+				var status *StatusError
+				if errors.As(err, &status) && status.RetryAfter > wait {
+					wait = status.RetryAfter
+				}
+				log.Printf("Retrying notifier %d in %v: %v", i+1, wait, err)
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			err = notifier.Notify(ctx, n)
+			if err == nil || !retryableNotify(err) {
+				break
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("notifier %d: %v", i+1, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return partial(len(failed), len(f.Notifiers), fmt.Errorf("%d of %d notifiers failed: %s", len(failed), len(f.Notifiers), strings.Join(failed, "; ")))
+	}
+	return nil
+}
+
+// retryableNotify is false for responses that mean the request itself is wrong, such as
+// a webhook that has been deleted, except for being rate limited
+func retryableNotify(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == http.StatusTooManyRequests || status.StatusCode >= 500
+	}
+	return true
+}
+
+// postJSON posts the value to a webhook. Webhook URLs are secrets, so errors only name
+// the host.
+func postJSON(ctx context.Context, client *http.Client, webhook string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	host := webhook
+	if u, err := url.Parse(webhook); err == nil {
+		host = u.Host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("making request for webhook on %s: %v", host, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to webhook on %s: %v", host, redact(err, webhook, host))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return checkResponse(resp, "webhook on "+host)
+	}
+	return nil
+}
+
+// redact takes a secret, such as a webhook URL, out of an error from the HTTP client,
+// which includes the URL it was requesting
+func redact(err error, secret string, replacement string) string {
+	return strings.Replace(err.Error(), secret, replacement, -1)
+}
+
+// splitText breaks the text into pieces of at most limit characters, at line breaks
+// where it can
+func splitText(text string, limit int) []string {
+	var chunks []string
+	var chunk []rune
+	for _, line := range strings.SplitAfter(strings.TrimRight(text, "\n"), "\n") {
+		r := []rune(line)
+		if len(chunk)+len(r) > limit && len(chunk) > 0 {
+			chunks = append(chunks, strings.TrimRight(string(chunk), "\n"))
+			chunk = nil
+		}
+		for len(r) > limit {
+			chunks = append(chunks, string(r[:limit]))
+			r = r[limit:]
+		}
+		chunk = append(chunk, r...)
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, strings.TrimRight(string(chunk), "\n"))
+	}
+	return chunks
+}
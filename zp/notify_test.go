@@ -0,0 +1,125 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifiers(t *testing.T) {
+	var bodies []map[string]string
+	ts := withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		var body map[string]string
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("Bad body %s: %v", data, err)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	n := Notification{Kind: NotifyDigest, Title: "Digest", Text: "Well ridden\n"}
+	for _, notifier := range []Notifier{SlackNotifier{URL: ts.URL}, DiscordNotifier{URL: ts.URL}, WebhookNotifier{URL: ts.URL}} {
+		if err := notifier.Notify(context.Background(), n); err != nil {
+			t.Errorf("%T: %v", notifier, err)
+		}
+	}
+
+	if len(bodies) != 3 {
+		t.Fatalf("Expected 3 posts, got %v", bodies)
+	}
+	if bodies[0]["text"] != n.Text || bodies[1]["content"] != "Well ridden" || bodies[2]["kind"] != NotifyDigest || bodies[2]["title"] != "Digest" {
+		t.Errorf("Unexpected posts %v", bodies)
+	}
+
+	// Discord needs long messages splitting
+	bodies = nil
+	long := strings.Repeat(strings.Repeat("x", 99)+"\n", 30)
+	err := DiscordNotifier{URL: ts.URL}.Notify(context.Background(), Notification{Text: long})
+	if err != nil || len(bodies) != 2 || len(bodies[0]["content"]) > discordLimit {
+		t.Errorf("Expected 2 posts of at most %d characters, got %d: %v", discordLimit, len(bodies), err)
+	}
+}
+
+func TestWebhookErrorHidesURL(t *testing.T) {
+	ts := withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	err := SlackNotifier{URL: ts.URL + "/services/s3cret"}.Notify(context.Background(), Notification{Text: "hi"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Error gives away the webhook: %v", err)
+	}
+	if retryableNotify(err) {
+		t.Errorf("Shouldn't retry a missing webhook")
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	var out bytes.Buffer
+	calls := 0
+	flaky := NotifierFunc(func(ctx context.Context, n Notification) error {
+		calls++
+		if calls < 3 {
+			return &StatusError{URL: "webhook", StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	gone := 0
+	missing := NotifierFunc(func(ctx context.Context, n Notification) error {
+		gone++
+		return &StatusError{URL: "webhook", StatusCode: http.StatusNotFound}
+	})
+
+	f := FanOut{Notifiers: []Notifier{flaky, WriterNotifier{W: &out}}, Backoff: time.Millisecond}
+	err := f.Notify(context.Background(), Notification{Text: "hello\n"})
+	if err != nil || calls != 3 || out.String() != "hello\n" {
+		t.Errorf("Got %v after %d calls, wrote %q", err, calls, out.String())
+	}
+
+	// One notifier failing doesn't stop the others
+	out.Reset()
+	f.Notifiers = []Notifier{missing, WriterNotifier{W: &out}}
+	err = f.Notify(context.Background(), Notification{Text: "again"})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 notifiers failed") || gone != 1 || out.String() != "again\n" {
+		t.Errorf("Got %v after %d calls, wrote %q", err, gone, out.String())
+	}
+	if !errors.Is(err, ErrPartial) {
+		t.Errorf("Expected a partial failure, got %v", err)
+	}
+
+	// Nothing got through, so it isn't partial
+	f.Notifiers = []Notifier{missing}
+	err = f.Notify(context.Background(), Notification{Text: "again"})
+	if err == nil || errors.Is(err, ErrPartial) {
+		t.Errorf("Expected a complete failure, got %v", err)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	cases := []struct {
+		text     string
+		limit    int
+		expected []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"one\ntwo\nthree\n", 8, []string{"one\ntwo", "three"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"", 4, nil},
+	}
+	for _, c := range cases {
+		got := splitText(c.text, c.limit)
+		if strings.Join(got, "|") != strings.Join(c.expected, "|") || len(got) != len(c.expected) {
+			t.Errorf("splitText(%q, %d): got %q, expected %q", c.text, c.limit, got, c.expected)
+		}
+	}
+}
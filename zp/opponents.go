@@ -0,0 +1,142 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Opponents are the riders a rider meets most often in races, as ZwiftPower counts them
+// on their profile
+type Opponents struct {
+	Zwid    int        `json:"zwid"`
+	Victims []Opponent `json:"victims"` // Riders they usually beat, most races first
+	Rivals  []Opponent `json:"rivals"`  // Riders they usually lose to, most races first
+}
+
+// Opponents imports the rider's victims and rivals from their profile, without the rest
+// of it. A rider without one or the other (ZwiftPower often hasn't got them) just has
+// none.
+func (c *Client) Opponents(ctx context.Context, riderID int) (o Opponents, err error) {
+	ctx, span := startSpan(ctx, "ImportOpponents", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	o.Zwid = riderID
+	for _, part := range []struct {
+		name string
+		into *[]Opponent
+	}{{profileVictims, &o.Victims}, {profileRivals, &o.Rivals}} {
+		data, err := c.Backend.getJSON(ctx, c.HTTP, c.Backend.profilePartURL(riderID, part.name))
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return o, fmt.Errorf("getting %s for %d: %w", part.name, riderID, err)
+		}
+		var parsed struct{ Data []Opponent }
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return o, &ParseError{What: part.name, Err: err}
+		}
+		*part.into = parsed.Data
+		sortOpponents(*part.into)
+	}
+	return o, nil
+}
+
+// sortOpponents puts the riders met most often first, then the most one-sided records
+func sortOpponents(opponents []Opponent) {
+	sort.SliceStable(opponents, func(i, j int) bool {
+		a, b := opponents[i], opponents[j]
+		if a.Races != b.Races {
+			return a.Races > b.Races
+		}
+		return math.Abs(float64(a.Wins-a.Losses)) > math.Abs(float64(b.Wins-b.Losses))
+	})
+}
+
+// Among keeps the victims and rivals who are among these riders, such as the club's
+func (o Opponents) Among(riders []Rider) Opponents {
+	ids := make(map[int]bool, len(riders))
+	for _, r := range riders {
+		ids[r.Zwid] = true
+	}
+	keep := func(opponents []Opponent) []Opponent {
+		var kept []Opponent
+		for _, opp := range opponents {
+			if ids[opp.Zwid] {
+				kept = append(kept, opp)
+			}
+		}
+		return kept
+	}
+	return Opponents{Zwid: o.Zwid, Victims: keep(o.Victims), Rivals: keep(o.Rivals)}
+}
+
+// Matchups pairs up riders who often meet in races, from each of their victims and
+// rivals, as head-to-head records for seeding races between clubmates. Only opponents who
+// are among the riders are included, and each pair comes once, from the point of view of
+// whoever is ahead. Pairs who have met most often come first, with the closest records
+// first among those. ZwiftPower doesn't give margins, so those are zero.
+func Matchups(riders []Rider, opponents []Opponents) []HeadToHead {
+	names := make(map[int]string, len(riders))
+	for _, r := range riders {
+		names[r.Zwid] = r.Name
+	}
+
+	type pair struct{ a, b int }
+	records := make(map[pair]HeadToHead)
+	var order []pair
+	for _, o := range opponents {
+		if _, ok := names[o.Zwid]; !ok {
+			continue
+		}
+		for _, opp := range append(append([]Opponent(nil), o.Victims...), o.Rivals...) {
+			if _, ok := names[opp.Zwid]; !ok || opp.Zwid == o.Zwid {
+				continue
+			}
+			h := HeadToHead{
+				Zwid:      o.Zwid,
+				Name:      names[o.Zwid],
+				RivalZwid: opp.Zwid,
+				RivalName: names[opp.Zwid],
+				Races:     int(opp.Races),
+				Wins:      int(opp.Wins),
+				Losses:    int(opp.Losses),
+			}
+			if h.Losses > h.Wins {
+				h.Zwid, h.RivalZwid, h.Name, h.RivalName = h.RivalZwid, h.Zwid, h.RivalName, h.Name
+				h.Wins, h.Losses = h.Losses, h.Wins
+			}
+
+			// Both riders' profiles can have the pair: keep whichever has seen more races
+			p := pair{h.Zwid, h.RivalZwid}
+			if p.a > p.b {
+				p = pair{p.b, p.a}
+			}
+			previous, ok := records[p]
+			if !ok {
+				order = append(order, p)
+			}
+			if !ok || h.Races > previous.Races {
+				records[p] = h
+			}
+		}
+	}
+
+	matchups := make([]HeadToHead, len(order))
+	for i, p := range order {
+		matchups[i] = records[p]
+	}
+	sort.SliceStable(matchups, func(i, j int) bool {
+		a, b := matchups[i], matchups[j]
+		if a.Races != b.Races {
+			return a.Races > b.Races
+		}
+		return a.Wins-a.Losses < b.Wins-b.Losses
+	})
+	return matchups
+}
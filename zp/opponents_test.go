@@ -0,0 +1,78 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestOpponents(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/profile/1261784_victims.json":
+			fmt.Fprint(w, `{"data":[
+				{"zwid":5,"name":"Once","races":"1","wins":1,"losses":0},
+				{"zwid":98588,"name":"Liz Rice","flag":"gb","tname":"Ride Club","races":"3","wins":2,"losses":1}]}`)
+		case "/cache3/profile/98588_rivals.json":
+			fmt.Fprint(w, `not json`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	client, err := New()
+	must(t, err)
+
+	o, err := client.Opponents(context.Background(), 1261784)
+	must(t, err)
+	if o.Zwid != 1261784 || len(o.Victims) != 2 || len(o.Rivals) != 0 {
+		t.Fatalf("Unexpected opponents %+v", o)
+	}
+	if o.Victims[0].Zwid != 98588 || o.Victims[0].TeamName != "Ride Club" || o.Victims[0].Races != 3 {
+		t.Errorf("Expected most races first, got %+v", o.Victims)
+	}
+
+	club := o.Among([]Rider{{Zwid: 98588}, {Zwid: 1261784}})
+	if len(club.Victims) != 1 || club.Victims[0].Zwid != 98588 {
+		t.Errorf("Unexpected clubmates %+v", club)
+	}
+
+	_, err = client.Opponents(context.Background(), 98588)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Errorf("Expected parse error, got %v", err)
+	}
+}
+
+func TestMatchups(t *testing.T) {
+	riders := []Rider{{Zwid: 1, Name: "Ann"}, {Zwid: 2, Name: "Bob"}, {Zwid: 3, Name: "Cat"}}
+	opponents := []Opponents{
+		{Zwid: 1, Victims: []Opponent{{Zwid: 2, Races: 4, Wins: 3, Losses: 1}, {Zwid: 99, Races: 10, Wins: 9, Losses: 1}}},
+		// Bob's view of the same pair has fewer races, so Ann's is kept
+		{Zwid: 2, Rivals: []Opponent{{Zwid: 1, Races: 3, Wins: 1, Losses: 2}}, Victims: []Opponent{{Zwid: 3, Races: 4, Wins: 2, Losses: 2}}},
+		{Zwid: 3, Rivals: []Opponent{{Zwid: 1, Races: 2, Wins: 0, Losses: 2}}},
+	}
+
+	m := Matchups(riders, opponents)
+	expected := []struct {
+		zwid, rival int
+		record      string
+	}{
+		{2, 3, "2-2"}, // As many races as Ann and Bob, but closer
+		{1, 2, "3-1"},
+		{1, 3, "2-0"}, // From Cat's rivals, turned round so the winner comes first
+	}
+	if len(m) != len(expected) {
+		t.Fatalf("Expected %d matchups, got %+v", len(expected), m)
+	}
+	for i, e := range expected {
+		if m[i].Zwid != e.zwid || m[i].RivalZwid != e.rival || m[i].Record() != e.record {
+			t.Errorf("Matchup %d: expected %d v %d %s, got %+v", i, e.zwid, e.rival, e.record, m[i])
+		}
+	}
+	if m[2].Name != "Ann" || m[2].RivalName != "Cat" {
+		t.Errorf("Unexpected names %+v", m[2])
+	}
+}
@@ -0,0 +1,226 @@
+package zp
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OptOut records a rider who has asked for their data not to be kept or shown
+type OptOut struct {
+	Zwid int
+	Date time.Time
+	Note string `json:",omitempty"` // Such as how they asked
+}
+
+// OptOuts are the riders who have opted out, by Zwid. Give them to a Client to leave
+// those riders out of everything it imports.
+type OptOuts map[int]OptOut
+
+// Has is true if the rider has opted out
+func (o OptOuts) Has(riderID int) bool {
+	_, ok := o[riderID]
+	return ok
+}
+
+// FilterRiders leaves out riders who have opted out
+func (o OptOuts) FilterRiders(riders []Rider) []Rider {
+	if len(o) == 0 {
+		return riders
+	}
+	kept := make([]Rider, 0, len(riders))
+	for _, r := range riders {
+		if !o.Has(r.Zwid) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// FilterEvents leaves out the rows for riders who have opted out, such as their
+// results in an event. Everyone else keeps their positions.
+func (o OptOuts) FilterEvents(events []Event) []Event {
+	if len(o) == 0 {
+		return events
+	}
+	kept := make([]Event, 0, len(events))
+	for _, e := range events {
+		if !o.Has(e.Zwid) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// List gets the opt-outs in order of Zwid
+func (o OptOuts) List() []OptOut {
+	list := make([]OptOut, 0, len(o))
+	for _, oo := range o {
+		list = append(list, oo)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Zwid < list[j].Zwid
+	})
+	return list
+}
+
+func (s *FileStore) optOutsPath() string {
+	return filepath.Join(s.Dir, "optouts.json")
+}
+
+// OptOuts gets the riders who have opted out
+func (s *FileStore) OptOuts() (OptOuts, error) {
+	var list []OptOut
+	err := s.read(s.optOutsPath(), &list)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	o := make(OptOuts, len(list))
+	for _, oo := range list {
+		o[oo.Zwid] = oo
+	}
+	return o, nil
+}
+
+// optedOut is true if the rider has opted out. If we can't tell, it errs on the side of
+// not storing anything.
+func (s *FileStore) optedOut(riderID int) bool {
+	o, err := s.OptOuts()
+	if err != nil {
+		log.Printf("Reading opt-outs: %v", err)
+		return true
+	}
+	return o.Has(riderID)
+}
+
+// OptOut adds the rider to the opt-outs, so that the store won't keep their data, and
+// deletes everything it has about them: their rider and events, goals, any place in a
+// league division or snapshot, and their name in the index.
+func (s *FileStore) OptOut(o OptOut) error {
+	if o.Zwid == 0 {
+		return fmt.Errorf("opting out needs a rider ID")
+	}
+
+	path := s.optOutsPath()
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	optOuts, err := s.OptOuts()
+	if err == nil {
+		if o.Date.IsZero() {
+			o.Date = time.Now().UTC()
+		}
+		optOuts[o.Zwid] = o
+		err = s.write(path, optOuts.List())
+	}
+	unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.forget(o.Zwid)
+}
+
+// OptIn removes the rider from the opt-outs, so their data can be stored again from
+// the next import. Nothing that was deleted comes back.
+func (s *FileStore) OptIn(riderID int) error {
+	path := s.optOutsPath()
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	optOuts, err := s.OptOuts()
+	if err != nil {
+		return err
+	}
+	if !optOuts.Has(riderID) {
+		return fmt.Errorf("rider %d hasn't opted out", riderID)
+	}
+	delete(optOuts, riderID)
+	return s.write(path, optOuts.List())
+}
+
+// forget deletes everything the store has about the rider
+func (s *FileStore) forget(riderID int) error {
+	id := strconv.Itoa(riderID)
+	for _, kind := range []string{"riders", "events", "goals"} {
+		path := s.path(kind, id)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := s.remove(path); err != nil {
+			return err
+		}
+	}
+
+	snapshots, err := s.Snapshots()
+	if err != nil {
+		return err
+	}
+	for _, name := range snapshots {
+		riders, err := s.Snapshot(name)
+		if err != nil {
+			return err
+		}
+		if kept := (OptOuts{riderID: {}}).FilterRiders(riders); len(kept) < len(riders) {
+			if err := s.PutSnapshot(name, kept); err != nil {
+				return err
+			}
+		}
+	}
+
+	leagues, err := filepath.Glob(filepath.Join(s.Dir, "leagues", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range leagues {
+		err = s.UpdateLeague(strings.TrimSuffix(filepath.Base(f), ".json"), func(l *League) error {
+			for i, d := range l.Divisions {
+				l.Divisions[i].Riders = removeID(d.Riders, riderID)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	path := s.path("index", "names")
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	x := NewNameIndex()
+	err = s.read(path, x)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := x.Names[riderID]; !ok {
+		return nil
+	}
+	delete(x.Names, riderID)
+	return s.write(path, x)
+}
+
+func removeID(ids []int, id int) []int {
+	var kept []int
+	for _, i := range ids {
+		if i != id {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
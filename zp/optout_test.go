@@ -0,0 +1,123 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestOptOutForgetsRider(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	riders := []Rider{{Zwid: 1, Name: "Liz Rice"}, {Zwid: 2, Name: "Bob Smith"}}
+	for _, r := range riders {
+		must(t, s.PutRider(r))
+		must(t, s.PutEvents(r.Zwid, []Event{{Zid: "10", Zwid: r.Zwid, Name: r.Name}}))
+	}
+	must(t, s.SetGoal(Goal{Zwid: 1, Kind: GoalWkg, Target: 3}))
+	must(t, s.PutSnapshot("2021-01-01", riders))
+	l := NewLeague("winter", 2672)
+	l.Divisions = []Division{{Name: "A", Riders: []int{1, 2}}}
+	must(t, s.PutLeague(l))
+	must(t, s.IndexNames(riders, nil))
+
+	must(t, s.OptOut(OptOut{Zwid: 1, Note: "asked by email"}))
+
+	if _, err := s.Rider(1); !IsNotFound(err) {
+		t.Errorf("Expected rider 1 to be deleted, got %v", err)
+	}
+	if _, err := s.Events(1); !IsNotFound(err) {
+		t.Errorf("Expected rider 1's events to be deleted, got %v", err)
+	}
+	if goals, _ := s.RiderGoals(1); len(goals) != 0 {
+		t.Errorf("Expected rider 1's goals to be deleted, got %v", goals)
+	}
+	if snap, _ := s.Snapshot("2021-01-01"); len(snap) != 1 || snap[0].Zwid != 2 {
+		t.Errorf("Got snapshot %v", snap)
+	}
+	if l, _ := s.League("winter"); len(l.Divisions[0].Riders) != 1 || l.Divisions[0].Riders[0] != 2 {
+		t.Errorf("Got league %+v", l)
+	}
+	if x, _ := s.NameIndex(); len(x.Lookup("liz")) != 0 {
+		t.Errorf("Expected rider 1 to be gone from the name index")
+	}
+	if _, err := s.Rider(2); err != nil {
+		t.Errorf("Expected rider 2 to be kept, got %v", err)
+	}
+
+	// Nothing new is stored while they are opted out
+	must(t, s.PutRider(riders[0]))
+	must(t, s.PutEvents(1, []Event{{Zid: "11", Zwid: 1}}))
+	must(t, s.Update(1, func(tx *RiderTx) error {
+		return tx.PutRider(riders[0])
+	}))
+	must(t, s.IndexNames(riders, nil))
+	if _, err := s.Rider(1); !IsNotFound(err) {
+		t.Errorf("Expected rider 1 not to be stored again, got %v", err)
+	}
+	if x, _ := s.NameIndex(); len(x.Lookup("liz")) != 0 {
+		t.Errorf("Expected rider 1 not to be indexed again")
+	}
+
+	optOuts, err := s.OptOuts()
+	if err != nil || len(optOuts) != 1 || optOuts[1].Note != "asked by email" || optOuts[1].Date.IsZero() {
+		t.Errorf("Got %v, %v", optOuts, err)
+	}
+
+	must(t, s.OptIn(1))
+	must(t, s.PutRider(riders[0]))
+	if _, err := s.Rider(1); err != nil {
+		t.Errorf("Expected rider 1 to be stored after opting in, got %v", err)
+	}
+	if err := s.OptIn(1); err == nil {
+		t.Errorf("Expected an error opting in twice")
+	}
+}
+
+func TestClientOptOuts(t *testing.T) {
+	requests := 0
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/cache3/teams/2672_riders.json":
+			fmt.Fprint(w, `{"data":[{"name":"Liz Rice","zwid":1},{"name":"Bob Smith","zwid":2}]}`)
+		case "/cache3/results/10_view.json":
+			fmt.Fprint(w, `{"data":[{"zid":"10","zwid":2,"pos":1},{"zid":"10","zwid":1,"pos":2},{"zid":"10","zwid":3,"pos":3}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.OptOuts = OptOuts{1: {Zwid: 1}}
+
+	riders, err := c.Club(context.Background(), 2672)
+	if err != nil || len(riders) != 1 || riders[0].Zwid != 2 {
+		t.Errorf("Got %v, %v", riders, err)
+	}
+
+	results, err := c.EventResults(context.Background(), 10)
+	if err != nil || len(results) != 2 || results[0].Zwid != 2 || results[1].Pos != 3 {
+		t.Errorf("Got %v, %v", results, err)
+	}
+
+	requests = 0
+	events, err := c.Events(context.Background(), 1)
+	if err != nil || len(events) != 0 || requests != 0 {
+		t.Errorf("Expected no events or requests for rider 1, got %v, %v after %d requests", events, err, requests)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
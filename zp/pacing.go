@@ -0,0 +1,164 @@
+package zp
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// firstBackoff is the gap between requests after the first pushback, if the pacer's
+// minimum is shorter
+var firstBackoff = time.Second
+
+// Pacer spaces out requests to ZwiftPower. It starts at its minimum gap between
+// requests, doubles the gap each time ZwiftPower pushes back with a 429 or 403, and
+// halves it again for each cool-down period that passes without any pushback. Add its
+// Middleware to a client, and share one Pacer between clients that talk to the same
+// server. It's safe for concurrent use.
+type Pacer struct {
+	Min      time.Duration // Shortest gap between requests; zero means no pacing until we get pushback
+	Max      time.Duration // Longest gap between requests
+	CoolDown time.Duration // How long without pushback before speeding up again
+
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // Earliest time for the next request
+	changed  time.Time // When we last slowed down or sped up
+	stats    PacerStats
+}
+
+// PacerStats is telemetry about how ZwiftPower has been treating our requests
+type PacerStats struct {
+	Requests    int
+	RateLimited int           // Responses with status 429
+	Forbidden   int           // Responses with status 403
+	Slowdowns   int           // Times we increased the gap between requests
+	Speedups    int           // Times we decreased it
+	Interval    time.Duration // The current gap between requests
+	Waited      time.Duration // Total time requests have spent waiting their turn
+}
+
+// PushbackRate is the fraction of requests that got a 429 or 403
+func (s PacerStats) PushbackRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.RateLimited+s.Forbidden) / float64(s.Requests)
+}
+
+// NewPacer makes a pacer that keeps the gap between requests between min and max, and
+// speeds up after five minutes without pushback
+func NewPacer(min, max time.Duration) *Pacer {
+	return &Pacer{Min: min, Max: max, CoolDown: 5 * time.Minute, interval: min}
+}
+
+// Stats reports what the pacer has seen so far
+func (p *Pacer) Stats() PacerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.stats
+	s.Interval = p.interval
+	return s
+}
+
+// Middleware makes each request wait its turn, and adjusts the pace according to the
+// response
+func (p *Pacer) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		wait := p.reserve()
+		if wait > 0 {
+			trace.SpanFromContext(req.Context()).SetAttributes(attribute.Int64("zwiftpower.pacing_ms", wait.Milliseconds()))
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-req.Context().Done():
+				t.Stop()
+				return nil, req.Context().Err()
+			}
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err == nil {
+			p.observe(resp)
+		}
+		return resp, err
+	})
+}
+
+// reserve books the next slot for a request, and says how long to wait for it
+func (p *Pacer) reserve() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.stats.Requests++
+	p.speedUp(now)
+
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval)
+	wait := slot.Sub(now)
+	p.stats.Waited += wait
+	return wait
+}
+
+// speedUp halves the gap if there's been no pushback for a cool-down period
+func (p *Pacer) speedUp(now time.Time) {
+	if p.interval <= p.Min || p.CoolDown <= 0 || now.Sub(p.changed) < p.CoolDown {
+		return
+	}
+
+	p.interval /= 2
+	if p.interval < p.Min || p.interval < firstBackoff {
+		p.interval = p.Min
+	}
+	p.changed = now
+	p.stats.Speedups++
+	log.Printf("ZwiftPower has been quiet for %v, speeding up to one request every %v", p.CoolDown, p.interval)
+}
+
+func (p *Pacer) observe(resp *http.Response) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusForbidden:
+	default:
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		p.stats.RateLimited++
+	} else {
+		p.stats.Forbidden++
+	}
+
+	p.interval *= 2
+	if p.interval < firstBackoff {
+		p.interval = firstBackoff
+	}
+	if p.interval > p.Max {
+		p.interval = p.Max
+	}
+	p.changed = now
+	p.stats.Slowdowns++
+
+	next := now.Add(p.interval)
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		if after := now.Add(time.Duration(secs) * time.Second); after.After(next) {
+			next = after
+		}
+	}
+	if next.After(p.next) {
+		p.next = next
+	}
+	log.Printf("ZwiftPower responded %d, slowing down to one request every %v", resp.StatusCode, p.interval)
+}
@@ -0,0 +1,89 @@
+package zp
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPacer(t *testing.T) {
+	oldBackoff := firstBackoff
+	firstBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { firstBackoff = oldBackoff })
+
+	statuses := []int{429, 403, 429, 429, 200, 200}
+	var sent []time.Time
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, time.Now())
+		status := statuses[0]
+		statuses = statuses[1:]
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: http.NoBody}, nil
+	})
+
+	p := NewPacer(0, 40*time.Millisecond)
+	p.CoolDown = 30 * time.Millisecond
+	client := &http.Client{Transport: Chain(transport, p.Middleware)}
+
+	get := func() {
+		resp, err := client.Get("http://zp.test/")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	for i := 0; i < 4; i++ {
+		get()
+	}
+	s := p.Stats()
+	if s.Requests != 4 || s.RateLimited != 3 || s.Forbidden != 1 || s.Slowdowns != 4 || s.Interval != 40*time.Millisecond {
+		t.Errorf("Unexpected stats after pushback: %+v", s)
+	}
+	if s.PushbackRate() != 1 {
+		t.Errorf("Got pushback rate %v, expected 1", s.PushbackRate())
+	}
+	for i := 1; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < 10*time.Millisecond {
+			t.Errorf("Request %d only waited %v", i, gap)
+		}
+	}
+
+	// Once things have been quiet for the cool-down, we speed up again
+	time.Sleep(p.CoolDown)
+	get()
+	s = p.Stats()
+	if s.Speedups != 1 || s.Interval != 20*time.Millisecond {
+		t.Errorf("Expected to speed up, got %+v", s)
+	}
+
+	// But not again until another cool-down period has passed
+	get()
+	if s = p.Stats(); s.Speedups != 1 || s.Requests != 6 {
+		t.Errorf("Sped up too soon: %+v", s)
+	}
+}
+
+func TestPacerRetryAfter(t *testing.T) {
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": []string{"60"}}, Body: http.NoBody}, nil
+	})
+
+	p := NewPacer(0, 5*time.Millisecond)
+	client := &http.Client{Transport: Chain(transport, p.Middleware)}
+	resp, err := client.Get("http://zp.test/")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// The next request has to wait for the server's Retry-After, not just our interval,
+	// so it gives up when its context expires
+	req, _ := http.NewRequest("GET", "http://zp.test/", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.Do(req.WithContext(ctx))
+	if err == nil {
+		t.Errorf("Expected request to time out waiting for Retry-After")
+	}
+}
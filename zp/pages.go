@@ -0,0 +1,136 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourcePage is data read from ZwiftPower's web pages rather than its JSON, as recorded
+// in Provenance
+const SourcePage = "page"
+
+// eventPageURL is the event's results page. ZwiftPower still renders the results of old
+// events there after their JSON has gone.
+func eventPageURL(eventID int) string {
+	return fmt.Sprintf("%s/events.php?zid=%d", BaseURL, eventID)
+}
+
+// leaguePageURL is the page for one round of a hosted league, which is all that's left of
+// old league archives
+func leaguePageURL(leagueID int, zid string) string {
+	return fmt.Sprintf("%s/league.php?id=%d&zid=%s", BaseURL, leagueID, url.QueryEscape(zid))
+}
+
+// fetchPage gets one of ZwiftPower's pages, with the same checks as for JSON
+func fetchPage(ctx context.Context, client *http.Client, url string) (io.Reader, *Provenance, error) {
+	data, modified, err := fetchJSON(ctx, client, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	source := SourcePage
+	if BaseURL != zwiftPowerURL {
+		source = SourceMirror
+	}
+	return bytes.NewReader(data), &Provenance{Source: source, Modified: modified}, nil
+}
+
+// pageResults reads the event's results from its results page, for when its JSON has
+// gone. If the page hasn't got them either, the error is notFound, from the JSON.
+func (c *Client) pageResults(ctx context.Context, eventID int, notFound error) ([]Event, error) {
+	page, p, err := fetchPage(ctx, c.HTTP, eventPageURL(eventID))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, notFound
+		}
+		return nil, fmt.Errorf("getting results page: %w", err)
+	}
+	results, err := ParseResultsHTML(page)
+	if err != nil {
+		return nil, fmt.Errorf("%w (nor on its page: %v)", notFound, err)
+	}
+
+	p.Fetched = c.now()
+	for i := range results {
+		if results[i].Zid == "" {
+			results[i].Zid = strconv.Itoa(eventID)
+		}
+	}
+	return withProvenance(results, p), nil
+}
+
+// pageLeagueResults reads a round of a hosted league from its page, for when its JSON has
+// gone. If the page hasn't got them either, the error is notFound, from the JSON.
+func pageLeagueResults(ctx context.Context, client *http.Client, leagueID int, zid string, notFound error) ([]LeagueResult, error) {
+	page, p, err := fetchPage(ctx, client, leaguePageURL(leagueID, zid))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	results, err := ParseLeagueResultsHTML(page)
+	if err != nil {
+		return nil, fmt.Errorf("%w (nor on its page: %v)", notFound, err)
+	}
+
+	p.Fetched = time.Now()
+	for i := range results {
+		if results[i].Zid == "" {
+			results[i].Zid = zid
+		}
+		results[i].Provenance = p
+	}
+	return results, nil
+}
+
+// ParseLeagueResultsHTML reads the results of a round of a hosted league from its page, as
+// ParseResultsHTML does for events, with each rider's points from a Points or Pts column
+func ParseLeagueResultsHTML(r io.Reader) ([]LeagueResult, error) {
+	tables, title, err := htmlTables(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error = fmt.Errorf("no results table")
+	for _, t := range tables {
+		points := -1
+		for i, h := range t.headers {
+			if key := strings.ToLower(strings.TrimSpace(h)); key == "points" || key == "pts" {
+				points = i
+			}
+		}
+
+		var results []LeagueResult
+		for _, row := range t.rows {
+			// One row at a time, to keep each rider's points with them
+			events, err := resultsFromTable(t.headers, [][]resultCell{row})
+			if err != nil {
+				lastErr = err
+				results = nil
+				break
+			}
+			if len(events) == 0 {
+				continue
+			}
+			r := LeagueResult{Event: events[0]}
+			if r.EventTitle == "" {
+				r.EventTitle = title
+			}
+			if points >= 0 && points < len(row) {
+				r.Points = Number(leadingNumber(row[points].text))
+			}
+			results = append(results, r)
+		}
+		if results != nil {
+			return results, nil
+		}
+	}
+	return nil, &ParseError{What: "league results page", Err: lastErr}
+}
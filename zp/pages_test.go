@@ -0,0 +1,83 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testLeagueHTML = `<html><head><title>ZwiftPower - Winter League Round 1</title></head><body>
+<table><thead><tr><th>Pos</th><th>Name</th><th>Cat</th><th>Time</th><th>Pts</th></tr></thead><tbody>
+<tr><td>1</td><td><a href="profile.php?z=123">Ann Example</a></td><td>B</td><td>1:02:03</td><td>50 pts</td></tr>
+<tr><td>2</td><td><a href="profile.php?z=456">Bob Example</a></td><td>B</td><td>1:02:05</td><td>45</td></tr>
+</tbody></table></body></html>`
+
+func TestEventResultsPageFallback(t *testing.T) {
+	var requests []string
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String())
+		switch r.URL.String() {
+		case "/events.php?zid=1234":
+			fmt.Fprint(w, testResultsHTML)
+		case "/events.php?zid=5678":
+			fmt.Fprint(w, "<html><p>No results</p></html>")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	client, err := New()
+	must(t, err)
+
+	results, err := client.EventResults(context.Background(), 1234)
+	must(t, err)
+	if len(results) != 2 || results[0].Zwid != 789 || results[0].Zid != "1234" || results[0].EventTitle != "Saturday Crit" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if p := results[0].Provenance; p == nil || p.Source != SourceMirror || p.Fetched.IsZero() {
+		t.Errorf("Unexpected provenance %+v", p)
+	}
+	if strings.Join(requests, " ") != "/cache3/results/1234_view.json /events.php?zid=1234" {
+		t.Errorf("Unexpected requests %v", requests)
+	}
+
+	// Without results on the page either, it's still not found
+	for _, id := range []int{5678, 9999} {
+		if _, err := client.EventResults(context.Background(), id); !IsNotFound(err) {
+			t.Errorf("%d: expected not found, got %v", id, err)
+		}
+	}
+}
+
+func TestLeagueResultsPageFallback(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == "/league.php?id=77&zid=1234" {
+			fmt.Fprint(w, testLeagueHTML)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	client, err := NewClient()
+	must(t, err)
+
+	results, err := ImportLeagueResults(client, 77, "1234")
+	must(t, err)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+	ann := results[0]
+	if ann.Zwid != 123 || ann.Points != 50 || ann.Pos != 1 || ann.Category != "B" || ann.Zid != "1234" || ann.EventTitle != "Winter League Round 1" {
+		t.Errorf("Unexpected result %+v", ann)
+	}
+	if results[1].Points != 45 {
+		t.Errorf("Expected 45 points, got %+v", results[1])
+	}
+
+	if _, err := ImportLeagueResults(client, 77, "5678"); !IsNotFound(err) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+	if _, err := ParseLeagueResultsHTML(strings.NewReader("<table><tr><th>Pos</th></tr><tr><td>1</td></tr></table>")); err == nil {
+		t.Errorf("Expected an error without a name column")
+	}
+}
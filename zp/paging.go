@@ -0,0 +1,74 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+)
+
+// cacheEventLimit is the most events we've seen in a rider's cache3 profile. Riders with
+// more than this have been cut off, and need paging through api3 to get the rest.
+var cacheEventLimit = 1000
+
+// eventPageSize is how many events we ask api3 for at a time
+var eventPageSize = 500
+
+// maxEventPages stops us paging for ever if api3 ignores where we ask it to start
+var maxEventPages = 50
+
+func profilePageURL(riderID int, start int, length int) string {
+	return fmt.Sprintf("%s/api3.php?do=profile_results&z=%d&type=all&start=%d&length=%d", BaseURL, riderID, start, length)
+}
+
+// loggedIn is true if the client has session cookies for ZwiftPower, which api3 needs
+func (c *Client) loggedIn() bool {
+	if c.HTTP.Jar == nil {
+		return false
+	}
+	u, err := url.Parse(BaseURL)
+	return err == nil && len(c.HTTP.Jar.Cookies(u)) > 0
+}
+
+// pageEvents fetches the rider's events from api3 a page at a time, calling fn for those
+// that aren't already in seen. It stops at the first short page, or if a page starts
+// with the same event as the one before.
+func (c *Client) pageEvents(ctx context.Context, riderID int, seen map[string]bool, fn func(*Event) error) (pages int, err error) {
+	var previous string
+	for start := 0; pages < maxEventPages; pages++ {
+		body, err := API3.openJSON(ctx, c.HTTP, profilePageURL(riderID, start, eventPageSize))
+		if err != nil {
+			return pages, err
+		}
+		p := c.provenance(API3, bodyModified(body))
+
+		n, first := 0, ""
+		var fnErr error
+		err = decodeEvents(body, func(e *Event) error {
+			if n == 0 {
+				first = e.Zid
+			}
+			n++
+			if seen[e.Zid] {
+				return nil
+			}
+			seen[e.Zid] = true
+			e.Provenance = p
+			fnErr = fn(e)
+			return fnErr
+		})
+		body.Close()
+		if err := eventsError(fmt.Sprintf("page of events for rider %d", riderID), err, fnErr); err != nil {
+			return pages, err
+		}
+
+		if n < eventPageSize || (start > 0 && first == previous) {
+			return pages + 1, nil
+		}
+		previous = first
+		start += n
+	}
+
+	log.Printf("Stopped paging events for rider %d after %d pages", riderID, pages)
+	return pages, nil
+}
@@ -0,0 +1,144 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// eventsJSON makes a profile with events for these event IDs
+func eventsJSON(zids ...int) string {
+	var events []string
+	for _, zid := range zids {
+		events = append(events, fmt.Sprintf(`{"zid":"%d","zwid":1261784,"event_date":%d}`, zid, 1600000000+zid))
+	}
+	return `{"data":[` + strings.Join(events, ",") + `]}`
+}
+
+func TestPageEvents(t *testing.T) {
+	oldLimit, oldPage := cacheEventLimit, eventPageSize
+	cacheEventLimit, eventPageSize = 3, 2
+	t.Cleanup(func() { cacheEventLimit, eventPageSize = oldLimit, oldPage })
+
+	all := []int{1, 2, 3, 4, 5}
+	pages := 0
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/profile/1261784_all.json":
+			// The cache is cut off at the limit
+			fmt.Fprint(w, eventsJSON(all[2:]...))
+		case "/api3.php":
+			if _, err := r.Cookie("phpbb3_sid"); err != nil {
+				fmt.Fprint(w, "<html>Please log in</html>")
+				return
+			}
+			pages++
+			start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+			length, _ := strconv.Atoi(r.URL.Query().Get("length"))
+			end := start + length
+			if end > len(all) {
+				end = len(all)
+			}
+			fmt.Fprint(w, eventsJSON(all[start:end]...))
+		}
+	})
+
+	client, err := New()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+
+	events, err := client.Events(context.Background(), 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing events: %v", err)
+	}
+	if len(events) != 3 || pages != 0 {
+		t.Errorf("Got %d events from %d pages without logging in, expected just the 3 in the cache", len(events), pages)
+	}
+
+	err = SetCookies(client.HTTP, "phpbb3_sid=abc")
+	if err != nil {
+		t.Fatalf("Failed setting cookies: %v", err)
+	}
+
+	events, err = client.Events(context.Background(), 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing events: %v", err)
+	}
+	zids := make(map[string]int)
+	for _, e := range events {
+		zids[e.Zid]++
+	}
+	if len(events) != len(all) || len(zids) != len(all) {
+		t.Errorf("Got events %v, expected each of %v once", zids, all)
+	}
+	if pages != 3 {
+		t.Errorf("Fetched %d pages, expected 3", pages)
+	}
+}
+
+func TestPageEventsIgnoringStart(t *testing.T) {
+	oldLimit, oldPage := cacheEventLimit, eventPageSize
+	cacheEventLimit, eventPageSize = 2, 2
+	t.Cleanup(func() { cacheEventLimit, eventPageSize = oldLimit, oldPage })
+
+	pages := 0
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api3.php" {
+			pages++
+		}
+		// Always the same events, wherever we ask to start
+		fmt.Fprint(w, eventsJSON(1, 2))
+	})
+
+	client, err := New()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	client.Backend = API3
+	SetCookies(client.HTTP, "phpbb3_sid=abc")
+
+	events, err := client.Events(context.Background(), 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing events: %v", err)
+	}
+	if len(events) != 2 || pages > 3 {
+		t.Errorf("Got %d events from %d pages, expected 2 events and to give up paging", len(events), pages)
+	}
+}
+
+func TestPageEventsFailing(t *testing.T) {
+	oldLimit, oldPage := cacheEventLimit, eventPageSize
+	cacheEventLimit, eventPageSize = 2, 2
+	t.Cleanup(func() { cacheEventLimit, eventPageSize = oldLimit, oldPage })
+
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") == "2" {
+			fmt.Fprint(w, `{"data":[{"zid":`)
+			return
+		}
+		fmt.Fprint(w, eventsJSON(1, 2))
+	})
+
+	client, err := New()
+	if err != nil {
+		t.Fatalf("Failed to get client: %v", err)
+	}
+	SetCookies(client.HTTP, "phpbb3_sid=abc")
+
+	// Only having the first page isn't the whole history, so it mustn't look like it is
+	events, err := client.Events(context.Background(), 1261784)
+	if err == nil {
+		t.Errorf("Got %d events, expected an error for the page that failed", len(events))
+	}
+
+	errStop := errors.New("stop")
+	_, err = client.pageEvents(context.Background(), 1261784, map[string]bool{}, func(e *Event) error { return errStop })
+	if err != errStop {
+		t.Errorf("Expected the error from fn, got %v", err)
+	}
+}
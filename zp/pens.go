@@ -0,0 +1,110 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Pen is one category's start pen in an upcoming event, with the club's riders in it
+type Pen struct {
+	Category   string
+	Riders     int     // Everyone signed up in the pen
+	Club       []Event // The club's signups
+	Share      float64 // Club riders as a fraction of the pen
+	Need       int     // How many more club riders it takes to reach the target
+	Candidates []Rider // Club riders who race this category but haven't signed up
+}
+
+// ImportEventSignups imports the signups for an upcoming event
+//
+// Deprecated: use Client.EventSignups
+func ImportEventSignups(client *http.Client, eventID int) ([]Event, error) {
+	return Wrap(client).EventSignups(context.Background(), eventID)
+}
+
+// ImportEventSignupsContext imports the signups for an upcoming event, one entry per rider
+//
+// Deprecated: use Client.EventSignups
+func ImportEventSignupsContext(ctx context.Context, client *http.Client, eventID int) ([]Event, error) {
+	return Wrap(client).EventSignups(ctx, eventID)
+}
+
+// EventSignups imports the signups for an upcoming event, one entry per rider
+func (c *Client) EventSignups(ctx context.Context, eventID int) (signups []Event, err error) {
+	ctx, span := startSpan(ctx, "ImportEventSignups", attribute.Int("zwiftpower.event_id", eventID))
+	defer func() { endSpan(span, err) }()
+
+	data, modified, err := c.Backend.fetchJSON(ctx, c.HTTP, c.Backend.signupsURL(eventID))
+	if err != nil {
+		return nil, fmt.Errorf("getting event signups: %w", err)
+	}
+
+	signups, err = parseEvents(data)
+	if err != nil {
+		return nil, &ParseError{What: "event signups", Err: err}
+	}
+
+	return c.OptOuts.FilterEvents(withProvenance(signups, c.provenance(c.Backend, modified))), nil
+}
+
+// PenBalance looks at the size of each pen in an event and how many of the club's riders
+// are in it, so captains can see where they are short for team tactics. Pens with fewer
+// than target club riders say how many more they need. If the club roster is given, with
+// categories from ImportClubRider, each short pen suggests club riders from that category
+// who haven't signed up yet, strongest first.
+func PenBalance(signups []Event, clubID string, target int, roster []Rider) []Pen {
+	pens := make(map[string]*Pen)
+	signedUp := make(map[int]bool)
+	for _, e := range signups {
+		cat := strings.ToUpper(e.Category)
+		p, ok := pens[cat]
+		if !ok {
+			p = &Pen{Category: cat}
+			pens[cat] = p
+		}
+		p.Riders++
+		if e.TeamID == clubID {
+			p.Club = append(p.Club, e)
+		}
+		signedUp[e.Zwid] = true
+	}
+
+	var balance []Pen
+	for _, p := range pens {
+		p.Share = float64(len(p.Club)) / float64(p.Riders)
+		if len(p.Club) < target {
+			p.Need = target - len(p.Club)
+			p.Candidates = penCandidates(p.Category, roster, signedUp, p.Need)
+		}
+		balance = append(balance, *p)
+	}
+
+	sort.Slice(balance, func(i, j int) bool {
+		return balance[i].Category < balance[j].Category
+	})
+	return balance
+}
+
+// penCandidates picks up to n riders from the roster who race in this category and
+// haven't already signed up, with the highest 90-day FTP first
+func penCandidates(category string, roster []Rider, signedUp map[int]bool, n int) []Rider {
+	var candidates []Rider
+	for _, r := range roster {
+		if strings.EqualFold(r.Category, category) && !signedUp[r.Zwid] {
+			candidates = append(candidates, r)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Ftp90 > candidates[j].Ftp90
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
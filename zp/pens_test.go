@@ -0,0 +1,72 @@
+package zp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPenBalance(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cache3/results/1700000_signups.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"data":[
+			{"zwid":1,"name":"Ann","category":"A","tid":"2672"},
+			{"zwid":2,"name":"Bob","category":"A","tid":"2672"},
+			{"zwid":3,"name":"Cat","category":"A","tid":"2672"},
+			{"zwid":4,"name":"Dan","category":"A","tid":"1"},
+			{"zwid":5,"name":"Eve","category":"b","tid":"2672"},
+			{"zwid":6,"name":"Fay","category":"B","tid":""},
+			{"zwid":7,"name":"Gus","category":"C","tid":"1"}]}`)
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signups, err := ImportEventSignups(client, 1700000)
+	if err != nil || len(signups) != 7 {
+		t.Fatalf("Got %d signups, %v", len(signups), err)
+	}
+
+	roster := []Rider{
+		{Zwid: 1, Category: "A", Ftp90: 4.5},
+		{Zwid: 8, Name: "Hal", Category: "B", Ftp90: 3.0},
+		{Zwid: 9, Name: "Ivy", Category: "B", Ftp90: 3.4},
+		{Zwid: 10, Name: "Jo", Category: "B", Ftp90: 3.2},
+		{Zwid: 11, Name: "Kim", Category: "D", Ftp90: 2.0},
+	}
+	pens := PenBalance(signups, "2672", 3, roster)
+	if len(pens) != 3 {
+		t.Fatalf("Got %d pens, expected 3: %+v", len(pens), pens)
+	}
+
+	expected := []struct {
+		category string
+		riders   int
+		club     int
+		need     int
+		names    string
+	}{
+		{"A", 4, 3, 0, ""},
+		{"B", 2, 1, 2, "Ivy Jo"},
+		{"C", 1, 0, 3, ""},
+	}
+	for i, e := range expected {
+		p := pens[i]
+		var names []string
+		for _, r := range p.Candidates {
+			names = append(names, r.Name)
+		}
+		if p.Category != e.category || p.Riders != e.riders || len(p.Club) != e.club || p.Need != e.need || strings.Join(names, " ") != e.names {
+			t.Errorf("Pen %d: got %s with %d riders, %d club, need %d, candidates %v", i, p.Category, p.Riders, len(p.Club), p.Need, names)
+		}
+	}
+
+	if pens[0].Share != 0.75 {
+		t.Errorf("Got share %v, expected 0.75", pens[0].Share)
+	}
+}
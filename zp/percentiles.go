@@ -0,0 +1,123 @@
+package zp
+
+import (
+	"sort"
+	"time"
+)
+
+// FtpRank is where a rider's FTP puts them among the club, overall and within their
+// category, now and 90 days ago. Percentiles run from 0 (lowest) to 100 (highest).
+type FtpRank struct {
+	Zwid     int
+	Name     string
+	Category string // Category of the latest race
+
+	Ftp                float64 // Best w/kg FTP over the last 90 days
+	Percentile         float64
+	CategoryPercentile float64
+
+	// Where they were 90 days ago, if they had an FTP then
+	PreviousFtp        float64
+	PreviousPercentile float64
+	Change             float64 // Percentile points gained since then
+}
+
+const percentileWindow = 90 * 24 * time.Hour
+
+// FtpPercentiles ranks each rider's best w/kg FTP over the last 90 days against the rest
+// of the club, overall and within their category, and compares their overall ranking
+// with 90 days before. Events can be for any number of riders. Riders without an FTP in
+// the last 90 days are left out. Results are highest percentile first.
+func FtpPercentiles(events []Event, now time.Time) []FtpRank {
+	byRider := make(map[int][]Event)
+	for _, e := range events {
+		byRider[e.Zwid] = append(byRider[e.Zwid], e)
+	}
+
+	var ranks []FtpRank
+	previous := make(map[int]float64)
+	for id, ee := range byRider {
+		r := FtpRank{Zwid: id, Ftp: bestFtp(ee, now)}
+		var latest time.Time
+		for _, e := range ee {
+			if r.Name == "" && e.Name != "" {
+				r.Name = e.RiderName()
+			}
+			if e.IsRace() && e.EventDateSecs != 0 && categoryIndex(e.Category) >= 0 && e.EventDate.After(latest) {
+				latest = e.EventDate
+				r.Category = e.Category
+			}
+		}
+		if r.Ftp > 0 {
+			ranks = append(ranks, r)
+		}
+		if p := bestFtp(ee, now.Add(-percentileWindow)); p > 0 {
+			previous[id] = p
+		}
+	}
+
+	var all, before []float64
+	byCategory := make(map[string][]float64)
+	for _, r := range ranks {
+		all = append(all, r.Ftp)
+		byCategory[r.Category] = append(byCategory[r.Category], r.Ftp)
+	}
+	for _, p := range previous {
+		before = append(before, p)
+	}
+
+	for i := range ranks {
+		r := &ranks[i]
+		r.Percentile = percentile(r.Ftp, all)
+		r.CategoryPercentile = percentile(r.Ftp, byCategory[r.Category])
+		if p, ok := previous[r.Zwid]; ok {
+			r.PreviousFtp = p
+			r.PreviousPercentile = percentile(p, before)
+			r.Change = r.Percentile - r.PreviousPercentile
+		}
+	}
+
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Percentile != ranks[j].Percentile {
+			return ranks[i].Percentile > ranks[j].Percentile
+		}
+		return ranks[i].Name < ranks[j].Name
+	})
+	return ranks
+}
+
+// bestFtp is the best w/kg FTP from events in the 90 days up to this time
+func bestFtp(events []Event, at time.Time) float64 {
+	var best float64
+	for _, e := range events {
+		if e.EventDateSecs == 0 || e.EventDate.After(at) || at.Sub(e.EventDate) > percentileWindow {
+			continue
+		}
+		if wkg := toFloat(e.WkgFtp); wkg > best {
+			best = wkg
+		}
+	}
+	return best
+}
+
+// percentile is the percentage of the other values that v is above, counting ties as
+// half. A value on its own is at the 100th percentile.
+func percentile(v float64, values []float64) float64 {
+	if len(values) <= 1 {
+		return 100
+	}
+
+	var below float64
+	seenSelf := false
+	for _, x := range values {
+		switch {
+		case x < v:
+			below++
+		case x == v && !seenSelf:
+			seenSelf = true
+		case x == v:
+			below += 0.5
+		}
+	}
+	return 100 * below / float64(len(values)-1)
+}
@@ -0,0 +1,64 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{2.0, 3.0, 3.0, 4.0, 5.0}
+	cases := map[float64]float64{2.0: 0, 3.0: 37.5, 4.0: 75, 5.0: 100}
+	for v, expected := range cases {
+		if p := percentile(v, values); p != expected {
+			t.Errorf("Got %v for %v, expected %v", p, v, expected)
+		}
+	}
+	if p := percentile(3.0, []float64{3.0}); p != 100 {
+		t.Errorf("Got %v for a single value, expected 100", p)
+	}
+}
+
+func TestFtpPercentiles(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	race := func(zwid int, name string, cat string, wkg float64, daysAgo int) Event {
+		date := now.AddDate(0, 0, -daysAgo)
+		return Event{Zwid: zwid, Name: name, Category: cat, WkgFtp: []interface{}{wkg}, EventType: "TYPE_RACE",
+			EventDateSecs: EventDateType(date.Unix()), EventDate: date}
+	}
+
+	events := []Event{
+		race(1, "Ann", "A", 4.2, 10),
+		race(1, "Ann", "A", 4.4, 120),
+		race(2, "Bob", "B", 3.5, 5),
+		race(2, "Bob", "C", 2.6, 100),
+		race(3, "Cat", "B", 3.3, 20),
+		race(3, "Cat", "B", 3.6, 110),
+		race(4, "Dan", "C", 2.8, 200), // No FTP in the last 90 days
+	}
+
+	ranks := FtpPercentiles(events, now)
+	if len(ranks) != 3 {
+		t.Fatalf("Got %d ranks, expected 3: %+v", len(ranks), ranks)
+	}
+
+	expected := []struct {
+		name     string
+		category string
+		overall  float64
+		inCat    float64
+		change   float64
+	}{
+		{"Ann", "A", 100, 100, 0},
+		{"Bob", "B", 50, 100, 50},
+		{"Cat", "B", 0, 0, -50},
+	}
+	for i, e := range expected {
+		r := ranks[i]
+		if r.Name != e.name || r.Category != e.category || r.Percentile != e.overall || r.CategoryPercentile != e.inCat || r.Change != e.change {
+			t.Errorf("Rank %d: got %+v, expected %+v", i, r, e)
+		}
+	}
+	if ranks[1].PreviousFtp != 2.6 {
+		t.Errorf("Got previous FTP %v for Bob, expected 2.6", ranks[1].PreviousFtp)
+	}
+}
@@ -0,0 +1,55 @@
+package zp
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestNewPodium(t *testing.T) {
+	results := []Event{
+		{Zwid: 1, Name: "Other", TeamID: "1", Pos: 1, PositionInCat: 1, Category: "A", EventTitle: "Crit &amp; City"},
+		{Zwid: 2, Name: "Second", TeamID: "2672", TeamName: "Revo", TeamColor: "fc00e3", TeamBgColor: "000000", Pos: 5, PositionInCat: 2, Category: "b", Time: 3725},
+		{Zwid: 3, Name: "First", TeamID: "2672", Pos: 2, PositionInCat: 1, Category: "c", Time: 1800},
+		{Zwid: 4, Name: "Third", TeamID: "2672", Pos: 3, PositionInCat: 2, Category: "a"},
+		{Zwid: 5, Name: "Fourth", TeamID: "2672", Pos: 9, PositionInCat: 4, Category: "a"},
+		{Zwid: 6, Name: "DNF", TeamID: "2672", PositionInCat: 0},
+	}
+
+	p := NewPodium(123, results, "2672")
+	if p.Title != "Crit & City" {
+		t.Errorf("Got title %q", p.Title)
+	}
+	if len(p.Places) != 3 {
+		t.Fatalf("Expected three places, got %v", p.Places)
+	}
+	for i, name := range []string{"First", "Third", "Second"} {
+		if p.Places[i].Name != name {
+			t.Errorf("Place %d: got %s, expected %s", i+1, p.Places[i].Name, name)
+		}
+	}
+	if p.Places[0].Time != "30:00" || p.Places[1].Time != "" || p.Places[0].Detail() != "1st C" {
+		t.Errorf("Unexpected places %v", p.Places)
+	}
+
+	// Colours come from the first finisher's team
+	p = NewPodium(123, results[1:2], "2672")
+	if p.Color != (color.RGBA{0xfc, 0x00, 0xe3, 0xff}) || p.Background != (color.RGBA{0, 0, 0, 0xff}) || p.Team != "Revo" {
+		t.Errorf("Unexpected colours %v %v for %q", p.Color, p.Background, p.Team)
+	}
+	if p.Places[0].Time != "1:02:05" {
+		t.Errorf("Got time %q", p.Places[0].Time)
+	}
+
+	p = NewPodium(123, nil, "2672")
+	if p.Title != "Event 123" || len(p.Places) != 0 {
+		t.Errorf("Unexpected podium without results %v", p)
+	}
+}
+
+func TestHexColor(t *testing.T) {
+	for s, expected := range map[string]bool{"fc00e3": true, "#FFFFFF": true, "": false, "fff": false, "zzzzzz": false} {
+		if _, ok := hexColor(s); ok != expected {
+			t.Errorf("hexColor(%q): got %v", s, ok)
+		}
+	}
+}
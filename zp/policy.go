@@ -0,0 +1,182 @@
+package zp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Policy is a club's rules for staying on the roster, such as at least one event every
+// 60 days. Clubs can keep their policy in a JSON file, e.g.
+//
+//	{"Name": "Active members", "Exempt": [98588],
+//	 "Rules": [{"Min": 1, "Days": 60, "Grace": 14}, {"Min": 2, "Days": 180, "Races": true}]}
+type Policy struct {
+	Name   string
+	Rules  []PolicyRule
+	Exempt []int // Riders the policy doesn't apply to, e.g. club officials
+}
+
+// PolicyRule says a rider needs at least Min events, or races, in any Days days. Riders
+// who stop meeting it have Grace days to put it right before they are in breach.
+type PolicyRule struct {
+	Min   int
+	Days  int
+	Races bool
+	Grace int
+}
+
+// Compliance statuses, from best to worst
+const (
+	PolicyExempt = "exempt"
+	PolicyOK     = "ok"
+	PolicyGrace  = "grace"
+	PolicyBreach = "breach"
+)
+
+var policySeverity = map[string]int{PolicyExempt: 0, PolicyOK: 1, PolicyGrace: 2, PolicyBreach: 3}
+
+// Compliance is how a rider stands against the policy. If they aren't meeting it, Rule
+// describes the rule in question and Count is how many they have towards it.
+type Compliance struct {
+	Zwid     int
+	Name     string
+	Status   string
+	Rule     string
+	Count    int
+	Deadline time.Time // When the grace period ends, if the rider is in it
+}
+
+// ParsePolicy reads a policy from JSON, checking that the rules make sense
+func ParsePolicy(data []byte) (Policy, error) {
+	var p Policy
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return p, &ParseError{What: "policy", Err: err}
+	}
+
+	if len(p.Rules) == 0 {
+		return p, fmt.Errorf("policy %q has no rules", p.Name)
+	}
+	for i, r := range p.Rules {
+		if r.Min < 1 || r.Days < 1 || r.Grace < 0 {
+			return p, fmt.Errorf("policy %q rule %d: need Min and Days of at least 1, and Grace of at least 0", p.Name, i+1)
+		}
+	}
+	return p, nil
+}
+
+// Describe says what the rule is in words, e.g. "at least 2 races in 180 days"
+func (r PolicyRule) Describe() string {
+	what := "event"
+	if r.Races {
+		what = "race"
+	}
+	if r.Min != 1 {
+		what += "s"
+	}
+	return fmt.Sprintf("at least %d %s in %d days", r.Min, what, r.Days)
+}
+
+// check sees whether the rider's events meet the rule at the given time. A rider stops
+// meeting it when the Min'th latest qualifying event drops out of the window, and their
+// grace period runs from then. Riders with no qualifying events at all are in breach,
+// unless they joined (if we know when) less than a window ago: they have until the
+// window has passed since they joined, and their grace period runs from then.
+func (r PolicyRule) check(events []Event, joined time.Time, now time.Time) Compliance {
+	var dates []time.Time
+	for _, e := range events {
+		if e.EventDateSecs == 0 || e.EventDate.After(now) || (r.Races && !e.IsRace()) {
+			continue
+		}
+		dates = append(dates, e.EventDate)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].After(dates[j])
+	})
+
+	c := Compliance{Status: PolicyOK, Rule: r.Describe()}
+	window := now.AddDate(0, 0, -r.Days)
+	for _, d := range dates {
+		if !d.Before(window) {
+			c.Count++
+		}
+	}
+	if c.Count >= r.Min {
+		return c
+	}
+
+	var lapsed time.Time
+	if len(dates) >= r.Min {
+		lapsed = dates[r.Min-1].AddDate(0, 0, r.Days)
+	}
+	if !joined.IsZero() {
+		if settled := joined.AddDate(0, 0, r.Days); settled.After(lapsed) {
+			lapsed = settled
+		}
+	}
+	if lapsed.IsZero() {
+		c.Status = PolicyBreach
+		return c
+	}
+	if now.Before(lapsed) {
+		return c
+	}
+	c.Deadline = lapsed.AddDate(0, 0, r.Grace)
+	c.Status = PolicyGrace
+	if !now.Before(c.Deadline) {
+		c.Status = PolicyBreach
+	}
+	return c
+}
+
+// Evaluate checks each rider on the roster against the policy, using events that can
+// be for any number of riders. joined has when riders joined the club, for those we
+// know (see JoinDates), so that new members aren't in breach before they've had a
+// chance to ride. Riders are listed worst first, then by name, and each one's result
+// is for the rule they are furthest from meeting.
+func (p Policy) Evaluate(riders []Rider, events []Event, joined map[int]time.Time, now time.Time) []Compliance {
+	byRider := make(map[int][]Event)
+	for _, e := range events {
+		byRider[e.Zwid] = append(byRider[e.Zwid], e)
+	}
+	exempt := make(map[int]bool)
+	for _, id := range p.Exempt {
+		exempt[id] = true
+	}
+
+	results := make([]Compliance, len(riders))
+	for i, rider := range riders {
+		c := Compliance{Status: PolicyExempt}
+		if !exempt[rider.Zwid] {
+			c.Status = PolicyOK
+			for _, rule := range p.Rules {
+				rc := rule.check(byRider[rider.Zwid], joined[rider.Zwid], now)
+				if worse(rc, c) {
+					c = rc
+				}
+			}
+		}
+		c.Zwid = rider.Zwid
+		c.Name = rider.Name
+		results[i] = c
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Status != results[j].Status {
+			return policySeverity[results[i].Status] > policySeverity[results[j].Status]
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// worse is true if a is a worse result than b: a more serious status, or the same
+// status with less time left to put it right
+func worse(a, b Compliance) bool {
+	if a.Status != b.Status {
+		return policySeverity[a.Status] > policySeverity[b.Status]
+	}
+	return a.Status == PolicyGrace && a.Deadline.Before(b.Deadline)
+}
@@ -0,0 +1,115 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(`{"Name": "Active", "Rules": [{"Min": 1, "Days": 60, "Grace": 14}], "Exempt": [1]}`))
+	if err != nil || len(p.Rules) != 1 || p.Rules[0].Grace != 14 || p.Exempt[0] != 1 {
+		t.Errorf("Unexpected policy %+v, %v", p, err)
+	}
+	if d := p.Rules[0].Describe(); d != "at least 1 event in 60 days" {
+		t.Errorf("Unexpected description %q", d)
+	}
+
+	for _, bad := range []string{
+		`{"Name": "None"}`,
+		`{"Rules": [{"Min": 0, "Days": 60}]}`,
+		`{"Rules": [{"Min": 1}]}`,
+		`not json`,
+	} {
+		if _, err := ParsePolicy([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	// The last race in the test data was on 3 Feb 2021
+	last := time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC)
+	riders := []Rider{{Zwid: 1261784, Name: "Özge"}, {Zwid: 2, Name: "Idle"}, {Zwid: 3, Name: "Official"}}
+	p := Policy{Rules: []PolicyRule{{Min: 1, Days: 60, Grace: 14}}, Exempt: []int{3}}
+
+	cases := []struct {
+		now      time.Time
+		expected string
+	}{
+		{last.AddDate(0, 0, 30), PolicyOK},
+		{last.AddDate(0, 0, 65), PolicyGrace},
+		{last.AddDate(0, 0, 80), PolicyBreach},
+	}
+	for _, c := range cases {
+		results := p.Evaluate(riders, events, nil, c.now)
+		if len(results) != 3 {
+			t.Fatalf("Got %d results, expected 3", len(results))
+		}
+
+		// A rider with no events at all is in breach, and comes first
+		if results[0].Zwid != 2 || results[0].Status != PolicyBreach {
+			t.Errorf("Expected rider with no events in breach, got %+v", results[0])
+		}
+		if results[len(results)-1].Status != PolicyExempt {
+			t.Errorf("Expected exempt rider last, got %+v", results[len(results)-1])
+		}
+
+		for _, r := range results {
+			if r.Zwid == 1261784 && r.Status != c.expected {
+				t.Errorf("At %s got %+v, expected %s", c.now.Format("2006-01-02"), r, c.expected)
+			}
+		}
+	}
+
+	// Grace runs from when the rider stopped meeting the rule
+	results := p.Evaluate(riders[:1], events, nil, last.AddDate(0, 0, 65))
+	if expected := last.AddDate(0, 0, 74); !results[0].Deadline.Truncate(24 * time.Hour).Equal(expected) {
+		t.Errorf("Got deadline %v, expected %v", results[0].Deadline, expected)
+	}
+
+	// A stricter rule on races is the one that counts
+	p.Rules = append(p.Rules, PolicyRule{Min: 20, Days: 365, Races: true})
+	results = p.Evaluate(riders[:1], events, nil, last.AddDate(0, 0, 1))
+	if results[0].Status != PolicyBreach || results[0].Rule != "at least 20 races in 365 days" || results[0].Count != 13 {
+		t.Errorf("Unexpected result %+v", results[0])
+	}
+}
+
+func TestPolicyNewMembers(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	riders := []Rider{{Zwid: 1, Name: "New"}, {Zwid: 2, Name: "Settled"}, {Zwid: 3, Name: "Old hand"}}
+	p := Policy{Rules: []PolicyRule{{Min: 1, Days: 60, Grace: 14}}}
+	joined := map[int]time.Time{1: now.AddDate(0, 0, -10), 2: now.AddDate(0, 0, -65)}
+
+	status := make(map[int]Compliance)
+	for _, c := range p.Evaluate(riders, nil, joined, now) {
+		status[c.Zwid] = c
+	}
+
+	// Someone who joined less than a window ago hasn't had the chance to ride yet
+	if c := status[1]; c.Status != PolicyOK {
+		t.Errorf("Expected new member to be ok, got %+v", c)
+	}
+	// Once the window has passed, their grace period runs from then
+	if c := status[2]; c.Status != PolicyGrace || !c.Deadline.Equal(now.AddDate(0, 0, 9)) {
+		t.Errorf("Expected settled member in grace until %v, got %+v", now.AddDate(0, 0, 9), c)
+	}
+	// Without a join date, a rider with no events is in breach as before
+	if c := status[3]; c.Status != PolicyBreach {
+		t.Errorf("Expected rider with no join date in breach, got %+v", c)
+	}
+
+	stints := []Stint{
+		{Zwid: 1, Joined: now.AddDate(0, 0, -10)},
+		{Zwid: 2, Joined: now.AddDate(-1, 0, 0), Before: true},
+		{Zwid: 3, Joined: now.AddDate(-1, 0, 0), Left: &now},
+	}
+	if dates := JoinDates(stints); len(dates) != 1 || !dates[1].Equal(now.AddDate(0, 0, -10)) {
+		t.Errorf("Unexpected join dates %v", dates)
+	}
+}
@@ -0,0 +1,149 @@
+package zp
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// steadyShare is how much a rider's average position has to move across the windows,
+// as a share of their average, before it counts as a trend rather than noise
+const steadyShare = 0.1
+
+// TrendDirection is which way a rider's finishing positions are heading
+type TrendDirection string
+
+// Trend directions. A rider without races in at least two windows has no direction.
+const (
+	Improving TrendDirection = "improving" // Finishing further up the field
+	Steady    TrendDirection = "steady"
+	Declining TrendDirection = "declining"
+)
+
+// PositionWindow is a rider's finishing positions in their category over one window
+// of days
+type PositionWindow struct {
+	Start   time.Time // Races after this
+	End     time.Time // and up to this
+	Races   int
+	Average float64 // Mean position, or zero if there were no races
+	Best    int     // Best position, or zero if there were no races
+}
+
+// PositionTrend is how a rider's finishing positions have gone over consecutive windows,
+// to answer "is this rider racing better lately?"
+type PositionTrend struct {
+	Zwid      int
+	Name      string
+	Windows   []PositionWindow // Oldest first, the last ending now
+	Direction TrendDirection
+	Change    float64 // Positions gained per window, from the fitted line; negative if losing places
+}
+
+// Latest is the most recent window
+func (t PositionTrend) Latest() PositionWindow {
+	if len(t.Windows) == 0 {
+		return PositionWindow{}
+	}
+	return t.Windows[len(t.Windows)-1]
+}
+
+// PositionTrends works out each rider's average and best position in their category in
+// each of so many windows of days, ending now, and which way it's heading. Events can be
+// for any number of riders, and only races with a position count. The direction comes
+// from a straight line through the windows' averages, and is Steady unless that moves by
+// more than a tenth of the rider's average position from the first window to the last.
+// Riders are in order of name.
+func PositionTrends(events []Event, now time.Time, days int, windows int) []PositionTrend {
+	byRider := make(map[int][]Event)
+	names := make(map[int]string)
+	for _, e := range events {
+		if names[e.Zwid] == "" {
+			names[e.Zwid] = e.RiderName()
+		}
+		byRider[e.Zwid] = append(byRider[e.Zwid], e)
+	}
+
+	var trends []PositionTrend
+	for id, ee := range byRider {
+		t := positionTrend(ee, now, days, windows)
+		t.Zwid, t.Name = id, names[id]
+		trends = append(trends, t)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Name != trends[j].Name {
+			return trends[i].Name < trends[j].Name
+		}
+		return trends[i].Zwid < trends[j].Zwid
+	})
+	return trends
+}
+
+func positionTrend(events []Event, now time.Time, days int, windows int) PositionTrend {
+	var t PositionTrend
+	totals := make([]int, windows)
+	start := now.AddDate(0, 0, -days*windows)
+	for i := 0; i < windows; i++ {
+		t.Windows = append(t.Windows, PositionWindow{
+			Start: start.AddDate(0, 0, days*i),
+			End:   start.AddDate(0, 0, days*(i+1)),
+		})
+	}
+
+	// The same race can turn up twice, from the profile and from event results
+	seen := make(map[string]bool)
+	for _, e := range events {
+		pos := e.categoryPosition()
+		if !e.IsRace() || pos <= 0 || e.EventDateSecs == 0 || seen[e.Zid] {
+			continue
+		}
+		for i := range t.Windows {
+			w := &t.Windows[i]
+			if e.EventDate.After(w.Start) && !e.EventDate.After(w.End) {
+				seen[e.Zid] = true
+				w.Races++
+				totals[i] += pos
+				if w.Best == 0 || pos < w.Best {
+					w.Best = pos
+				}
+				break
+			}
+		}
+	}
+
+	var xs, ys []float64
+	for i := range t.Windows {
+		if t.Windows[i].Races > 0 {
+			t.Windows[i].Average = float64(totals[i]) / float64(t.Windows[i].Races)
+			xs = append(xs, float64(i))
+			ys = append(ys, t.Windows[i].Average)
+		}
+	}
+	if len(xs) < 2 {
+		return t
+	}
+
+	// Lower positions are better, so gaining places is a falling line
+	t.Change = -trend(xs, ys)
+	moved := math.Abs(t.Change) * (xs[len(xs)-1] - xs[0])
+	switch {
+	case moved <= steadyShare*mean(ys):
+		t.Direction = Steady
+	case t.Change > 0:
+		t.Direction = Improving
+	default:
+		t.Direction = Declining
+	}
+	return t
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
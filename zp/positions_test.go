@@ -0,0 +1,66 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPositionTrends(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	race := func(riderID int, zid string, daysAgo int, pos int) Event {
+		d := now.AddDate(0, 0, -daysAgo)
+		return Event{Zwid: riderID, Name: "Rider " + string(rune('A'+riderID-1)), Zid: zid, EventType: "RACE",
+			EventDateSecs: EventDateType(d.Unix()), EventDate: d, PositionInCat: pos}
+	}
+
+	events := []Event{
+		// Rider A is moving up: 10th and 12th, then 6th, then 3rd and 1st
+		race(1, "1", 80, 10), race(1, "2", 70, 12), race(1, "3", 40, 6), race(1, "4", 20, 3), race(1, "5", 5, 1),
+		race(1, "5", 5, 1), // The same race twice
+		{Zwid: 1, Zid: "6", EventType: "GROUP", Pos: 40}, // Not a race
+		race(1, "7", 100, 30),                            // Before the first window
+
+		// Rider B is about the same
+		race(2, "1", 85, 5), race(2, "3", 45, 6), race(2, "5", 10, 5),
+
+		// Rider C is sliding, with a race that only has the overall position
+		race(3, "1", 75, 2), race(3, "4", 15, 8),
+		{Zwid: 3, Zid: "8", EventType: "RACE", EventDateSecs: EventDateType(now.AddDate(0, 0, -1).Unix()), EventDate: now.AddDate(0, 0, -1), Pos: 20},
+
+		// Rider D has only raced lately
+		race(4, "4", 20, 4), race(4, "5", 5, 2),
+	}
+
+	trends := PositionTrends(events, now, 30, 3)
+	if len(trends) != 4 {
+		t.Fatalf("Expected 4 riders, got %+v", trends)
+	}
+
+	a := trends[0]
+	if a.Zwid != 1 || a.Name != "Rider A" || len(a.Windows) != 3 {
+		t.Fatalf("Unexpected trend %+v", a)
+	}
+	expected := []PositionWindow{{Races: 2, Average: 11, Best: 10}, {Races: 1, Average: 6, Best: 6}, {Races: 2, Average: 2, Best: 1}}
+	for i, w := range a.Windows {
+		if w.Races != expected[i].Races || w.Average != expected[i].Average || w.Best != expected[i].Best {
+			t.Errorf("Window %d: expected %+v, got %+v", i, expected[i], w)
+		}
+	}
+	if !a.Windows[0].Start.Equal(now.AddDate(0, 0, -90)) || !a.Latest().End.Equal(now) {
+		t.Errorf("Unexpected windows from %v to %v", a.Windows[0].Start, a.Latest().End)
+	}
+	if a.Direction != Improving || math.Abs(a.Change-4.5) > 1e-9 {
+		t.Errorf("Expected improving by 4.5 places a window, got %s %v", a.Direction, a.Change)
+	}
+
+	if trends[1].Direction != Steady {
+		t.Errorf("Expected B to be steady, got %s %v", trends[1].Direction, trends[1].Change)
+	}
+	if c := trends[2]; c.Direction != Declining || c.Latest().Races != 2 || c.Latest().Best != 8 {
+		t.Errorf("Expected C to be declining, got %+v", c)
+	}
+	if d := trends[3]; d.Direction != "" || d.Latest().Races != 2 {
+		t.Errorf("Expected no direction for D, got %+v", d)
+	}
+}
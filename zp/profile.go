@@ -0,0 +1,140 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// The files that make up a rider's profile on ZwiftPower
+const (
+	profileAll     = "all"
+	profileRaces   = "races"
+	profileVictims = "victims"
+	profileRivals  = "rivals"
+	profilePrimes  = "primes"
+)
+
+// Opponent is another rider that a rider has raced against: a victim is someone they
+// usually beat, and a rival someone they usually lose to
+type Opponent struct {
+	Zwid     int    `json:"zwid"`
+	Name     string `json:"name"`
+	Country  string `json:"flag"`
+	TeamName string `json:"tname"`
+	Races    Number `json:"races"`
+	Wins     Number `json:"wins"`
+	Losses   Number `json:"losses"`
+}
+
+// Prime is a result from a sprint or KOM segment within an event
+type Prime struct {
+	Zid        string `json:"zid"`
+	EventTitle string `json:"event_title"`
+	Segment    string `json:"name"`
+	Position   int    `json:"pos"`
+	Elapsed    Number `json:"elapsed"`
+}
+
+// RiderProfile brings together everything ZwiftPower has about a rider, which is
+// spread over several files
+type RiderProfile struct {
+	Rider
+	Events  []Event
+	Races   []Event
+	Victims []Opponent
+	Rivals  []Opponent
+	Primes  []Prime
+	Details MergedDetails // Weight, FTP and category, from every source the client can reach
+
+	// Enforcement is the rider's category for Zwift's Category Enforcement events, if the
+	// client has a Zwift token and Zwift has given them one
+	Enforcement *CategoryEnforcement `json:",omitempty"`
+	Missing     []string             // Parts of the profile that couldn't be found
+}
+
+// ImportRiderProfile imports the full profile for the rider with this ID
+//
+// Deprecated: use Client.RiderProfile
+func ImportRiderProfile(client *http.Client, riderID int) (RiderProfile, error) {
+	return Wrap(client).RiderProfile(context.Background(), riderID)
+}
+
+// ImportRiderProfileContext imports the full profile for the rider with this ID
+//
+// Deprecated: use Client.RiderProfile
+func ImportRiderProfileContext(ctx context.Context, client *http.Client, riderID int) (RiderProfile, error) {
+	return Wrap(client).RiderProfile(ctx, riderID)
+}
+
+// RiderProfile imports the full profile for the rider with this ID. Only the list of
+// events is essential: the other parts are often missing from the cache, so those that
+// can't be loaded are listed in Missing rather than causing an error. So are Zwift and
+// zwiftracing.app if the client has credentials for them but they can't be reached.
+func (c *Client) RiderProfile(ctx context.Context, riderID int) (p RiderProfile, err error) {
+	ctx, span := startSpan(ctx, "ImportRiderProfile", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	p.Events, err = c.Events(ctx, riderID)
+	if err != nil {
+		return p, err
+	}
+	p.Rider = RiderFromEventsAggregated(riderID, p.Events, SystemClock.Now(), c.FtpAggregation)
+
+	ok := p.importPart(ctx, c, riderID, profileRaces, func(data []byte) (err error) {
+		p.Races, err = parseEvents(data)
+		return err
+	})
+	if !ok {
+		for _, e := range p.Events {
+			if e.IsRace() {
+				p.Races = append(p.Races, e)
+			}
+		}
+	}
+
+	var victims, rivals struct{ Data []Opponent }
+	if p.importPart(ctx, c, riderID, profileVictims, unmarshalInto(&victims)) {
+		p.Victims = victims.Data
+	}
+	if p.importPart(ctx, c, riderID, profileRivals, unmarshalInto(&rivals)) {
+		p.Rivals = rivals.Data
+	}
+
+	var primes struct{ Data []Prime }
+	if p.importPart(ctx, c, riderID, profilePrimes, unmarshalInto(&primes)) {
+		p.Primes = primes.Data
+	}
+
+	p.mergeDetails(ctx, c, riderID)
+
+	span.SetAttributes(attribute.StringSlice("zwiftpower.missing", p.Missing))
+	return p, nil
+}
+
+func unmarshalInto(v interface{}) func([]byte) error {
+	return func(data []byte) error {
+		return json.Unmarshal(data, v)
+	}
+}
+
+// importPart loads one part of the profile, noting it as missing if that fails
+func (p *RiderProfile) importPart(ctx context.Context, c *Client, riderID int, part string, parse func([]byte) error) bool {
+	data, err := c.Backend.getJSON(ctx, c.HTTP, c.Backend.profilePartURL(riderID, part))
+	if err == nil {
+		err = parse(data)
+		if err != nil {
+			err = &ParseError{What: part, Err: err}
+		}
+	}
+
+	if err != nil {
+		log.Printf("No %s for rider %d: %v", part, riderID, err)
+		p.Missing = append(p.Missing, part)
+		return false
+	}
+	return true
+}
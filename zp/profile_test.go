@@ -0,0 +1,71 @@
+package zp
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestImportRiderProfile(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/profile.php":
+		case "/cache3/profile/1261784_all.json":
+			fmt.Fprint(w, testdata)
+		case "/cache3/profile/1261784_victims.json":
+			fmt.Fprint(w, `{"data":[{"zwid":98588,"name":"Liz Rice","flag":"gb","races":"3","wins":2,"losses":1}]}`)
+		case "/cache3/profile/1261784_primes.json":
+			fmt.Fprint(w, `{"data":[{"zid":"1234","event_title":"Crit City","name":"Sprint","pos":2,"elapsed":[12.5,0]}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := ImportRiderProfile(client, 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing profile: %v", err)
+	}
+
+	if p.Zwid != 1261784 || len(p.Events) != 14 {
+		t.Errorf("Unexpected rider %d with %d events", p.Zwid, len(p.Events))
+	}
+
+	// With no races file, races come from the events
+	if len(p.Races) != 13 {
+		t.Errorf("Got %d races, expected 13", len(p.Races))
+	}
+
+	if len(p.Victims) != 1 || p.Victims[0].Zwid != 98588 || p.Victims[0].Races != 3 {
+		t.Errorf("Unexpected victims %v", p.Victims)
+	}
+	if len(p.Primes) != 1 || p.Primes[0].Elapsed != 12.5 {
+		t.Errorf("Unexpected primes %v", p.Primes)
+	}
+
+	if len(p.Missing) != 2 || p.Missing[0] != profileRaces || p.Missing[1] != profileRivals {
+		t.Errorf("Unexpected missing parts %v", p.Missing)
+	}
+}
+
+func TestProfilePartURL(t *testing.T) {
+	cases := []struct {
+		b        Backend
+		part     string
+		expected string
+	}{
+		{Cache3, profileAll, BaseURL + "/cache3/profile/98588_all.json"},
+		{Cache3, profileVictims, BaseURL + "/cache3/profile/98588_victims.json"},
+		{API3, profileRaces, BaseURL + "/api3.php?do=profile_results&z=98588&type=race"},
+		{API3, profilePrimes, BaseURL + "/api3.php?do=profile_primes&z=98588"},
+	}
+	for _, c := range cases {
+		if u := c.b.profilePartURL(98588, c.part); u != c.expected {
+			t.Errorf("Got %s, expected %s", u, c.expected)
+		}
+	}
+}
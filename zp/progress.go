@@ -0,0 +1,103 @@
+package zp
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress is told how far a long import has got after each step: done out of total,
+// and what it has just been working on, such as a rider's name. It may be nil.
+type Progress func(done, total int, current string)
+
+// report calls the progress hook, if there is one
+func (p Progress) report(done, total int, current string) {
+	if p != nil {
+		p(done, total, current)
+	}
+}
+
+// progressWidth is how many characters wide ProgressBar's bar is
+const progressWidth = 30
+
+// ProgressBar draws a progress bar on a terminal, redrawing it in place after each
+// step and moving on to a new line when the import is done
+func ProgressBar(w io.Writer) Progress {
+	var mu sync.Mutex
+	return func(done, total int, current string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		filled := progressWidth
+		if total > 0 && done < total {
+			filled = progressWidth * done / total
+		}
+		// Padded to a fixed width, to cover up whatever was there before
+		label := string([]rune(current + strings.Repeat(" ", progressWidth))[:progressWidth])
+		fmt.Fprintf(w, "\r[%s%s] %d/%d %s", strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), done, total, label)
+		if done >= total {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// ImportStatus is how far one import tracked by an ImportTracker has got
+type ImportStatus struct {
+	Name     string
+	Done     int
+	Total    int
+	Current  string
+	Started  time.Time
+	Updated  time.Time
+	Finished bool
+}
+
+// ImportTracker keeps the progress of imports, so that a server can say what it's doing
+// while they run. It's safe for concurrent use.
+type ImportTracker struct {
+	mu      sync.Mutex
+	imports []*ImportStatus // Most recently started first
+}
+
+// NewImportTracker makes a tracker with no imports yet
+func NewImportTracker() *ImportTracker {
+	return &ImportTracker{}
+}
+
+// Progress starts tracking an import with this name, replacing any earlier import
+// with the same name, and returns the hook for it to report its progress to
+func (t *ImportTracker) Progress(name string) Progress {
+	now := time.Now()
+	s := &ImportStatus{Name: name, Started: now, Updated: now}
+	t.mu.Lock()
+	imports := []*ImportStatus{s}
+	for _, i := range t.imports {
+		if i.Name != name {
+			imports = append(imports, i)
+		}
+	}
+	t.imports = imports
+	t.mu.Unlock()
+
+	return func(done, total int, current string) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		s.Done, s.Total, s.Current = done, total, current
+		s.Updated = time.Now()
+		s.Finished = done >= total
+	}
+}
+
+// Status is the latest progress of each import, most recently started first
+func (t *ImportTracker) Status() []ImportStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var status []ImportStatus
+	for _, s := range t.imports {
+		status = append(status, *s)
+	}
+	return status
+}
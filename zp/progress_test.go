@@ -0,0 +1,51 @@
+package zp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	p := ProgressBar(&buf)
+	p(1, 4, "Liz Rice")
+	if !strings.HasPrefix(buf.String(), "\r[=======                       ] 1/4 Liz Rice") || strings.HasSuffix(buf.String(), "\n") {
+		t.Errorf("Got %q", buf.String())
+	}
+
+	buf.Reset()
+	p(4, 4, "A rider with a name much too long to fit")
+	if !strings.Contains(buf.String(), "] 4/4 A rider with a name much too l\n") {
+		t.Errorf("Got %q", buf.String())
+	}
+
+	// Nil hooks are allowed
+	var none Progress
+	none.report(1, 2, "nothing")
+}
+
+func TestImportTracker(t *testing.T) {
+	tracker := NewImportTracker()
+	first := tracker.Progress("club 1")
+	first(2, 2, "Liz Rice")
+	second := tracker.Progress("club 2")
+	second(1, 3, "Ozge Yazar")
+
+	status := tracker.Status()
+	if len(status) != 2 {
+		t.Fatalf("Got %v", status)
+	}
+	if s := status[1]; s.Name != "club 1" || !s.Finished || s.Current != "Liz Rice" {
+		t.Errorf("Got first import %+v", s)
+	}
+	if s := status[0]; s.Name != "club 2" || s.Finished || s.Done != 1 || s.Total != 3 {
+		t.Errorf("Got second import %+v", s)
+	}
+
+	// Starting again replaces the earlier run
+	tracker.Progress("club 1")
+	if s := tracker.Status()[0]; s.Name != "club 1" || s.Finished || s.Total != 0 {
+		t.Errorf("Got restarted import %+v", s)
+	}
+}
@@ -0,0 +1,79 @@
+package zp
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sources of data, as recorded in Provenance
+const (
+	SourceCache3 = string(Cache3) // ZwiftPower's cache3 files, which can lag behind
+	SourceAPI3   = string(API3)   // ZwiftPower's api3 endpoints
+	SourceMirror = "mirror"       // A mirror of ZwiftPower, or anything else at BaseURL
+	SourceFile   = "file"         // A saved export in the ResultsDir
+)
+
+// zwiftPowerURL is the real ZwiftPower, so that anything else at BaseURL counts as a
+// mirror
+const zwiftPowerURL = "https://www.zwiftpower.com"
+
+// Provenance says where some data came from and how fresh it is, so that whoever
+// looks at it can tell how far to trust it. Everything from one fetch shares the same
+// Provenance.
+type Provenance struct {
+	Source   string    `json:"source"`
+	Fetched  time.Time `json:"fetched"`            // When we got it
+	Modified time.Time `json:"modified,omitempty"` // When the source last changed it, if it said
+}
+
+// AsOf is how current the data is: when the source last changed it if we know, or
+// else when we fetched it
+func (p *Provenance) AsOf() time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	if !p.Modified.IsZero() {
+		return p.Modified
+	}
+	return p.Fetched
+}
+
+// Age is how old the data was at this time
+func (p *Provenance) Age(now time.Time) time.Duration {
+	return now.Sub(p.AsOf())
+}
+
+// provenance describes data the client is fetching now from its backend
+func (c *Client) provenance(backend Backend, modified time.Time) *Provenance {
+	source := string(backend)
+	if BaseURL != zwiftPowerURL {
+		source = SourceMirror
+	}
+	return &Provenance{Source: source, Fetched: c.now(), Modified: modified}
+}
+
+// lastModified is the time in a Last-Modified header, or zero if there isn't one
+func lastModified(header http.Header) time.Time {
+	t, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// bodyModified is the Last-Modified time of a body from openJSON
+func bodyModified(body io.ReadCloser) time.Time {
+	if jb, ok := body.(*jsonBody); ok {
+		return jb.modified
+	}
+	return time.Time{}
+}
+
+// withProvenance sets the provenance on all the events
+func withProvenance(events []Event, p *Provenance) []Event {
+	for i := range events {
+		events[i].Provenance = p
+	}
+	return events
+}
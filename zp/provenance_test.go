@@ -0,0 +1,80 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProvenance(t *testing.T) {
+	modified := time.Date(2021, 2, 1, 12, 0, 0, 0, time.UTC)
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		fmt.Fprint(w, testdata)
+	})
+
+	now := time.Date(2021, 2, 5, 0, 0, 0, 0, time.UTC)
+	client, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Clock = FixedClock(now)
+
+	events, err := client.Events(context.Background(), 1261784)
+	if err != nil {
+		t.Fatalf("Failed importing events: %v", err)
+	}
+	p := events[0].Provenance
+	if p == nil || p.Source != SourceMirror || !p.Fetched.Equal(now) || !p.Modified.Equal(modified) {
+		t.Fatalf("Got provenance %+v", p)
+	}
+	if events[len(events)-1].Provenance != p {
+		t.Errorf("Expected the events from one fetch to share their provenance")
+	}
+	if !p.AsOf().Equal(modified) || p.Age(now) != 84*time.Hour {
+		t.Errorf("Got as of %v, age %v", p.AsOf(), p.Age(now))
+	}
+
+	results, err := client.EventResults(context.Background(), 1)
+	if err != nil || results[0].Provenance == nil || !results[0].Provenance.Modified.Equal(modified) {
+		t.Errorf("Got %v for results", err)
+	}
+
+	// The rider's stats are as fresh as the freshest of their events
+	older := &Provenance{Source: SourceCache3, Fetched: modified.AddDate(0, 0, -7)}
+	events[3].Provenance = older
+	rider := RiderFromEventsAsOf(1261784, events, now)
+	if rider.Provenance != p {
+		t.Errorf("Got rider provenance %+v, expected %+v", rider.Provenance, p)
+	}
+
+	var nobody *Provenance
+	if !nobody.AsOf().IsZero() {
+		t.Errorf("Expected no time for missing provenance")
+	}
+}
+
+func TestProvenanceFromFile(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "42.json"), []byte(testdata), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ResultsDir = dir
+	results, err := client.EventResults(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("Failed reading results: %v", err)
+	}
+	if p := results[0].Provenance; p == nil || p.Source != SourceFile || p.Modified.IsZero() {
+		t.Errorf("Got provenance %+v", p)
+	}
+}
@@ -0,0 +1,126 @@
+package zp
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits are how big and how stale a directory of copied files such as a Mirror is
+// allowed to get. Zero means no limit.
+type Limits struct {
+	MaxAge  time.Duration // Files not written for this long are removed
+	MaxSize int64         // Bytes in all; the least recently written files go first
+}
+
+// PruneResult says what Prune removed, and what's left
+type PruneResult struct {
+	Removed      int
+	RemovedBytes int64
+	Kept         int
+	KeptBytes    int64
+}
+
+// Prune removes files from the directory to keep it within the limits, going by when each
+// was last written: first those older than MaxAge, then the oldest of the rest until the
+// total is no more than MaxSize. Directories left empty are removed too. Half-written files
+// (see writeFile) are left alone. In a dry run, it only logs what it would remove.
+func Prune(dir string, limits Limits, now time.Time, dryRun bool) (PruneResult, error) {
+	type file struct {
+		path     string
+		size     int64
+		modified time.Time
+	}
+	var files []file
+	var dirs []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") && strings.HasSuffix(info.Name(), ".tmp") {
+			return nil
+		}
+		files = append(files, file{path: path, size: info.Size(), modified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return PruneResult{}, fmt.Errorf("pruning %s: %w", dir, err)
+	}
+
+	// Oldest first
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modified.Before(files[j].modified)
+	})
+	var r PruneResult
+	for _, f := range files {
+		r.Kept++
+		r.KeptBytes += f.size
+	}
+
+	for _, f := range files {
+		stale := limits.MaxAge > 0 && now.Sub(f.modified) > limits.MaxAge
+		tooBig := limits.MaxSize > 0 && r.KeptBytes > limits.MaxSize
+		if !stale && !tooBig {
+			break
+		}
+		if dryRun {
+			log.Printf("Dry run: would remove %s (%d bytes, written %s)", f.path, f.size, f.modified.Format(time.RFC3339))
+		} else if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return r, fmt.Errorf("pruning %s: %w", dir, err)
+		}
+		r.Removed++
+		r.RemovedBytes += f.size
+		r.Kept--
+		r.KeptBytes -= f.size
+	}
+
+	if !dryRun {
+		// Deepest first, so that parents are empty by the time we get to them
+		sort.Slice(dirs, func(i, j int) bool {
+			return len(dirs[i]) > len(dirs[j])
+		})
+		for _, d := range dirs {
+			os.Remove(d) // Fails, as it should, unless it's empty
+		}
+	}
+	return r, nil
+}
+
+// ParseSize reads a number of bytes, which can have a unit such as 500MB or 2GiB. KB, MB
+// and GB are powers of 1000, and KiB, MiB and GiB powers of 1024.
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		bytes  int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+		{"B", 1},
+	}
+	number := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(number, u.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, u.suffix))
+			multiplier = u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("size %q should be a number of bytes, e.g. 500MB or 2GiB", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,99 @@
+package zp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	files := []struct {
+		path string
+		size int
+		age  time.Duration
+	}{
+		{"cache3/results/1_view.json", 100, 60 * 24 * time.Hour}, // Stale
+		{"cache3/results/2_view.json", 100, 20 * 24 * time.Hour},
+		{"cache3/results/3_view.json", 300, 10 * 24 * time.Hour},
+		{"cache3/profile/1_all.json", 200, time.Hour},
+		{"status/2672.json", 50, time.Minute},
+		{"cache3/profile/.2_all.json.123.tmp", 500, 90 * 24 * time.Hour}, // Being written
+		{"cache3/old/4_view.json", 10, 90 * 24 * time.Hour},              // Leaves an empty directory
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.path))
+		must(t, os.MkdirAll(filepath.Dir(path), 0755))
+		must(t, ioutil.WriteFile(path, make([]byte, f.size), 0644))
+		must(t, os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)))
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(path)))
+		return err == nil
+	}
+
+	// A dry run removes nothing, but says what it would
+	limits := Limits{MaxAge: 30 * 24 * time.Hour, MaxSize: 400}
+	r, err := Prune(dir, limits, now, true)
+	must(t, err)
+	if r != (PruneResult{Removed: 4, RemovedBytes: 510, Kept: 2, KeptBytes: 250}) {
+		t.Errorf("Unexpected dry run %+v", r)
+	}
+	if !exists("cache3/results/1_view.json") {
+		t.Errorf("Dry run removed a file")
+	}
+
+	// Stale files go first, then the oldest until it fits
+	r, err = Prune(dir, limits, now, false)
+	must(t, err)
+	if r != (PruneResult{Removed: 4, RemovedBytes: 510, Kept: 2, KeptBytes: 250}) {
+		t.Errorf("Unexpected prune %+v", r)
+	}
+	for _, f := range files {
+		kept := f.path == "cache3/profile/1_all.json" || f.path == "status/2672.json" || f.path == "cache3/profile/.2_all.json.123.tmp"
+		if exists(f.path) != kept {
+			t.Errorf("%s: expected kept %v", f.path, kept)
+		}
+	}
+	if exists("cache3/old") || exists("cache3/results") || !exists("cache3/profile") {
+		t.Errorf("Expected only empty directories to be removed")
+	}
+
+	// Without limits, nothing goes
+	r, err = Prune(dir, Limits{}, now, false)
+	must(t, err)
+	if r.Removed != 0 || r.Kept != 2 {
+		t.Errorf("Unexpected prune without limits %+v", r)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+	}{
+		{"1024", 1024},
+		{"500MB", 500000000},
+		{"2GiB", 2 << 30},
+		{"1.5 kib", 1536},
+		{"10k", 10000},
+		{"", -1},
+		{"big", -1},
+		{"-1MB", -1},
+	}
+	for _, test := range tests {
+		got, err := ParseSize(test.s)
+		if test.want < 0 {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %d", test.s, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%q: expected %d, got %d, %v", test.s, test.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"text/template"
+	"time"
+)
+
+// ClubReportDays is how far back the digest in a ClubReport goes
+var ClubReportDays = 7
+
+// DefaultClubReportTemplate is used to write club reports unless the caller supplies
+// their own. The digest part comes from DefaultDigestTemplate.
+const DefaultClubReportTemplate = `Club {{.ClubID}}: {{len .Riders}} riders
+{{range .Riders}}
+{{unescape .Name}}{{with .Category}} ({{.}}){{end}}: FTP {{printf "%.1f" .Ftp90}} w/kg, {{.Races30}} races in 30 days{{with .LatestEvent}}, latest {{.}}{{end}}
+{{- end}}
+{{with .Failed}}
+No events could be fetched for riders {{.}}
+{{end}}
+{{template "digest" .Digest}}`
+
+// ClubSummary is everything in a ClubReport
+type ClubSummary struct {
+	ClubID    int
+	Generated time.Time
+	Riders    []Rider // Everyone on the roster with their stats, in roster order
+	Digest    Digest  // How the club did over the last ClubReportDays
+	Failed    []int   // Riders whose events couldn't be fetched
+}
+
+// ClubReport is the quickest way to get started: it fetches everything about the club
+// from ZwiftPower with a new Client, and works out each rider's stats and a digest of
+// the last week, ready to print with WriteClubReport:
+//
+//	report, err := zp.ClubReport(ctx, 2672)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	zp.WriteClubReport(os.Stdout, report, "")
+func ClubReport(ctx context.Context, clubID int) (ClubSummary, error) {
+	c, err := New()
+	if err != nil {
+		return ClubSummary{}, err
+	}
+	return c.ClubReport(ctx, clubID)
+}
+
+// ClubReport fetches the club's roster and every rider's events, and works out each
+// rider's stats and a digest of the last ClubReportDays. Riders whose events can't be
+// fetched are listed in Failed rather than stopping the report.
+func (c *Client) ClubReport(ctx context.Context, clubID int) (ClubSummary, error) {
+	now := c.now()
+	s := ClubSummary{ClubID: clubID, Generated: now}
+
+	roster, err := c.Club(ctx, clubID)
+	if err != nil {
+		return s, err
+	}
+
+	var events []Event
+	for _, r := range roster {
+		ee, err := c.Events(ctx, r.Zwid)
+		if ctx.Err() != nil {
+			return s, ctx.Err()
+		}
+		if err != nil {
+			log.Printf("Club report %d: no events for %s (%d): %v", clubID, r.Name, r.Zwid, err)
+			s.Failed = append(s.Failed, r.Zwid)
+		}
+		s.Riders = append(s.Riders, withRosterData(RiderFromEventsAggregated(r.Zwid, ee, now, c.FtpAggregation), r))
+		events = append(events, ee...)
+	}
+
+	s.Digest = NewDigest(clubID, events, now.AddDate(0, 0, -ClubReportDays), now, false)
+	return s, nil
+}
+
+// WriteClubReport renders the report using the template text, which can use the digest
+// template as {{template "digest" .Digest}}. If tmpl is empty the
+// DefaultClubReportTemplate is used.
+func WriteClubReport(w io.Writer, s ClubSummary, tmpl string) error {
+	if tmpl == "" {
+		tmpl = DefaultClubReportTemplate
+	}
+
+	t := template.New("club").Funcs(reportFuncs).Funcs(template.FuncMap{"unescape": html.UnescapeString})
+	_, err := t.New("digest").Parse(DefaultDigestTemplate)
+	if err == nil {
+		_, err = t.Parse(tmpl)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing club report template: %v", err)
+	}
+
+	return t.Execute(w, s)
+}
@@ -0,0 +1,65 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClubReport(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/teams/2672_riders.json":
+			fmt.Fprint(w, `{"data":[{"name":"&Ouml;zge Yazar","zwid":1261784,"flag":"ca"},{"name":"Gone","zwid":2}]}`)
+		case "/cache3/profile/1261784_all.json":
+			fmt.Fprint(w, testdata)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The day after the last race
+	c.Clock = FixedClock(time.Unix(1612320300, 0).AddDate(0, 0, 1))
+
+	report, err := c.ClubReport(context.Background(), 2672)
+	if err != nil {
+		t.Fatalf("Failed getting club report: %v", err)
+	}
+	if len(report.Riders) != 2 || report.Riders[0].Country != "ca" || report.Riders[0].Races30 != 2 {
+		t.Errorf("Got riders %+v", report.Riders)
+	}
+	if len(report.Failed) != 1 || report.Failed[0] != 2 {
+		t.Errorf("Expected rider 2 to fail, got %v", report.Failed)
+	}
+	if len(report.Digest.Events) != 1 {
+		t.Errorf("Expected the last race in the digest, got %+v", report.Digest.Events)
+	}
+
+	var b bytes.Buffer
+	err = WriteClubReport(&b, report, "")
+	if err != nil {
+		t.Fatalf("Failed writing club report: %v", err)
+	}
+	for _, expected := range []string{
+		"Club 2672: 2 riders",
+		"Özge Yazar (C): FTP 2.9 w/kg, 2 races in 30 days",
+		"No events could be fetched for riders [2]",
+		"Club results from",
+	} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Missing %q in %q", expected, b.String())
+		}
+	}
+
+	if err := WriteClubReport(&b, report, "{{.Nope"); err == nil {
+		t.Errorf("Expected an error for a bad template")
+	}
+}
@@ -0,0 +1,87 @@
+package zp
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EventResults are the club's results from one event
+type EventResults struct {
+	Zid     string
+	Title   string
+	Date    time.Time
+	Results []Event // In finishing order, with riders who didn't finish last
+}
+
+// ClubRecentResults gets the results of every rider in the club since this time,
+// grouped by event
+func ClubRecentResults(client *http.Client, clubID int, since time.Time) ([]EventResults, error) {
+	return ClubRecentResultsContext(context.Background(), client, clubID, since)
+}
+
+// ClubRecentResultsContext gets the results of every rider in the club since this
+// time, grouped by event
+func ClubRecentResultsContext(ctx context.Context, client *http.Client, clubID int, since time.Time) (results []EventResults, err error) {
+	ctx, span := startSpan(ctx, "ClubRecentResults", attribute.Int("zwiftpower.club_id", clubID))
+	defer func() { endSpan(span, err) }()
+
+	c := Wrap(client)
+	riders, err := c.Club(ctx, clubID)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, r := range riders {
+		ee, err := c.Events(ctx, r.Zwid)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ee...)
+	}
+
+	return GroupByEvent(events, since), nil
+}
+
+// GroupByEvent collects results from this time onwards by event, oldest event first
+func GroupByEvent(events []Event, since time.Time) []EventResults {
+	index := make(map[string]int)
+	var grouped []EventResults
+	for _, e := range events {
+		if e.EventDateSecs == 0 || e.EventDate.Before(since) {
+			continue
+		}
+
+		i, ok := index[e.Zid]
+		if !ok {
+			i = len(grouped)
+			index[e.Zid] = i
+			grouped = append(grouped, EventResults{Zid: e.Zid, Title: e.EventTitle, Date: e.EventDate})
+		}
+		grouped[i].Results = append(grouped[i].Results, e)
+	}
+
+	for _, g := range grouped {
+		sort.SliceStable(g.Results, func(i, j int) bool {
+			return placedBefore(g.Results[i], g.Results[j])
+		})
+	}
+
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return grouped[i].Date.Before(grouped[j].Date)
+	})
+	return grouped
+}
+
+// placedBefore is true if a finished ahead of b. A position of 0 means the rider didn't
+// finish, which puts them behind everyone who did.
+func placedBefore(a, b Event) bool {
+	if a.Pos <= 0 || b.Pos <= 0 {
+		return a.Pos > 0 && b.Pos <= 0
+	}
+	return a.Pos < b.Pos
+}
@@ -0,0 +1,87 @@
+package zp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClubRecentResults(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/teams/2672_riders.json":
+			fmt.Fprint(w, `{"data":[{"name":"Özge Yazar","zwid":1261784},{"name":"Someone Else","zwid":2}]}`)
+		case "/cache3/profile/1261784_all.json":
+			fmt.Fprint(w, testdata)
+		case "/cache3/profile/2_all.json":
+			// Same event as Özge's latest race, but finished ahead of her
+			fmt.Fprint(w, `{"data":[{"zid":"1644250","zwid":2,"name":"Someone Else","pos":12,"event_date":1612320300,"event_title":"Zwift Racing League"}]}`)
+		}
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	since := time.Unix(1610505900, 0)
+	results, err := ClubRecentResults(client, 2672, since)
+	if err != nil {
+		t.Fatalf("Failed getting results: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Got %d events", len(results))
+	}
+	if results[0].Zid != "1497992" || len(results[0].Results) != 1 {
+		t.Errorf("Unexpected first event %+v", results[0])
+	}
+
+	latest := results[1]
+	if latest.Zid != "1644250" || !strings.Contains(latest.Title, "Zwift Racing League") || len(latest.Results) != 2 {
+		t.Fatalf("Unexpected latest event %+v", latest)
+	}
+	if latest.Results[0].Zwid != 2 || latest.Results[1].Zwid != 1261784 {
+		t.Errorf("Results not in finishing order: %v, %v", latest.Results[0].Zwid, latest.Results[1].Zwid)
+	}
+}
+
+func TestGroupByEvent(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	// The ride with no date is left out
+	grouped := GroupByEvent(events, time.Time{})
+	if len(grouped) != len(events)-1 {
+		t.Errorf("Got %d events, expected %d", len(grouped), len(events)-1)
+	}
+	for i := 1; i < len(grouped); i++ {
+		if grouped[i].Date.Before(grouped[i-1].Date) {
+			t.Errorf("Events out of order at %d", i)
+		}
+	}
+}
+
+func TestGroupByEventDNF(t *testing.T) {
+	date := time.Date(2021, 3, 1, 18, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Zid: "1", Zwid: 1, Pos: 0, EventDate: date, EventDateSecs: EventDateType(date.Unix())},
+		{Zid: "1", Zwid: 2, Pos: 3, EventDate: date, EventDateSecs: EventDateType(date.Unix())},
+		{Zid: "1", Zwid: 3, Pos: 1, EventDate: date, EventDateSecs: EventDateType(date.Unix())},
+	}
+	grouped := GroupByEvent(events, time.Time{})
+	if len(grouped) != 1 {
+		t.Fatalf("Got %d events, expected 1", len(grouped))
+	}
+	var order []int
+	for _, e := range grouped[0].Results {
+		order = append(order, e.Zwid)
+	}
+	if len(order) != 3 || order[0] != 3 || order[1] != 2 || order[2] != 1 {
+		t.Errorf("Results in order %v, expected the rider who didn't finish last", order)
+	}
+}
@@ -0,0 +1,81 @@
+package zp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// SnapshotName is the name Snapshot commands save a club's riders under for a day
+func SnapshotName(clubID int, day time.Time) string {
+	return fmt.Sprintf("%d_%s", clubID, day.Format("2006-01-02"))
+}
+
+// RosterAsOf finds who was in the club at the time, from the latest of the club's
+// snapshots (see SnapshotName) taken on or before that day. It returns the snapshot's
+// name, or ErrNotFound if there isn't one that early.
+func RosterAsOf(store Store, clubID int, asOf time.Time) (string, []Rider, error) {
+	names, err := store.Snapshots()
+	if err != nil {
+		return "", nil, err
+	}
+
+	prefix := fmt.Sprintf("%d_", clubID)
+	latest := ""
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", strings.TrimPrefix(name, prefix))
+		if err != nil || day.After(asOf) {
+			continue
+		}
+		// Names sort in date order
+		latest = name
+	}
+	if latest == "" {
+		return "", nil, fmt.Errorf("no snapshot of club %d from before %s: %w", clubID, asOf.Format("2006-01-02"), ErrNotFound)
+	}
+
+	riders, err := store.Snapshot(latest)
+	return latest, riders, err
+}
+
+// RecomputeAsOf works out the riders' stats as they were at the time, from the events in
+// the store, without asking ZwiftPower for anything. This means past decisions, such as
+// who was picked for a team, can be checked against the numbers as they stood then, and
+// reports for the end of a season come out the same however late they are run. Details
+// that only the roster has, such as names, are taken from the riders given. Riders with
+// no stored events from before then are left out, and their IDs returned. FTPs are
+// combined as the aggregation says.
+func RecomputeAsOf(store Store, roster []Rider, asOf time.Time, aggregation FtpAggregations) (riders []Rider, missing []int, err error) {
+	for _, r := range roster {
+		events, err := store.Events(r.Zwid)
+		if IsNotFound(err) {
+			missing = append(missing, r.Zwid)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading events for %d: %v", r.Zwid, err)
+		}
+
+		var before []Event
+		for _, e := range events {
+			if e.EventDateSecs != 0 && !e.EventDate.After(asOf) {
+				before = append(before, e)
+			}
+		}
+		if len(before) == 0 {
+			missing = append(missing, r.Zwid)
+			continue
+		}
+
+		riders = append(riders, withRosterData(RiderFromEventsAggregated(r.Zwid, before, asOf, aggregation), r))
+	}
+
+	if len(missing) > 0 {
+		log.Printf("No stored events from before %s for %d riders", asOf.Format("2006-01-02"), len(missing))
+	}
+	return riders, missing, nil
+}
@@ -0,0 +1,73 @@
+package zp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRosterAsOf(t *testing.T) {
+	s := NewMemoryStore()
+	for name, riders := range map[string][]Rider{
+		"2672_2020-11-01": {{Zwid: 1}},
+		"2672_2021-01-01": {{Zwid: 1}, {Zwid: 2}},
+		"99_2020-10-01":   {{Zwid: 3}},
+		"2672_notadate":   {{Zwid: 4}},
+	} {
+		must(t, s.PutSnapshot(name, riders))
+	}
+
+	name, riders, err := RosterAsOf(s, 2672, time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC))
+	if err != nil || name != "2672_2020-11-01" || len(riders) != 1 {
+		t.Errorf("Got snapshot %s %v: %v", name, riders, err)
+	}
+
+	// A snapshot taken that day counts
+	name, _, err = RosterAsOf(s, 2672, time.Date(2021, 1, 1, 23, 59, 59, 0, time.UTC))
+	if err != nil || name != "2672_2021-01-01" {
+		t.Errorf("Got snapshot %s: %v", name, err)
+	}
+
+	_, _, err = RosterAsOf(s, 2672, time.Date(2020, 10, 15, 0, 0, 0, 0, time.UTC))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected no snapshot, got %v", err)
+	}
+
+	if SnapshotName(2672, time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)) != "2672_2021-01-01" {
+		t.Errorf("Unexpected snapshot name")
+	}
+}
+
+func TestRecomputeAsOf(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewMemoryStore()
+	must(t, s.PutEvents(1261784, events))
+
+	roster := []Rider{{Zwid: 1261784, Name: "Özge", Country: "ca"}, {Zwid: 5, Name: "Nobody"}}
+	asOf := time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)
+	riders, missing, err := RecomputeAsOf(s, roster, asOf, FtpAggregations{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(riders) != 1 || len(missing) != 1 || missing[0] != 5 {
+		t.Fatalf("Got riders %v, missing %v", riders, missing)
+	}
+
+	r := riders[0]
+	expected := RiderFromEventsAsOf(1261784, events, asOf)
+	if r.Name != "Özge" || r.Country != "ca" || r.Races != expected.Races || r.Ftp90 != expected.Ftp90 || r.LatestEvent != expected.LatestEvent {
+		t.Errorf("Got %v, expected stats like %v", r, expected)
+	}
+	if !r.LatestEventDate.Before(asOf) {
+		t.Errorf("Latest event %v should be before %v", r.LatestEventDate, asOf)
+	}
+
+	// Before any of the events, there's nothing to go on
+	_, missing, err = RecomputeAsOf(s, roster, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), FtpAggregations{})
+	if err != nil || len(missing) != 2 {
+		t.Errorf("Expected both riders missing, got %v: %v", missing, err)
+	}
+}
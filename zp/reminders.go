@@ -0,0 +1,60 @@
+package zp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventReminder is a nudge for the club's riders who have signed up for an event, so
+// nobody misses the start
+type EventReminder struct {
+	Zid    string
+	Title  string
+	Start  time.Time
+	Riders []Event // The club's signups, in order of category then name
+}
+
+// NewEventReminder picks out the club's riders from an event's signups. The title and
+// start time come from the signups, so it's zero if nobody has signed up.
+func NewEventReminder(signups []Event, clubID string) EventReminder {
+	var r EventReminder
+	for _, e := range signups {
+		if r.Zid == "" {
+			r.Zid, r.Title = e.Zid, e.EventTitle
+			if e.EventDateSecs != 0 {
+				r.Start = e.EventDate
+			}
+		}
+		if e.TeamID == clubID {
+			r.Riders = append(r.Riders, e)
+		}
+	}
+
+	sort.SliceStable(r.Riders, func(i, j int) bool {
+		if r.Riders[i].Category != r.Riders[j].Category {
+			return r.Riders[i].Category < r.Riders[j].Category
+		}
+		return r.Riders[i].RiderName() < r.Riders[j].RiderName()
+	})
+	return r
+}
+
+// Due is true if club riders are signed up and the event starts within this long
+func (r EventReminder) Due(now time.Time, before time.Duration) bool {
+	return len(r.Riders) > 0 && !r.Start.IsZero() && r.Start.After(now) && r.Start.Sub(now) <= before
+}
+
+// Message is the reminder text, e.g. "WTRL TTT starts in 2h 0m (19:00 UTC). Signed up:
+// Ann Other (B), A Rider (B)"
+func (r EventReminder) Message(now time.Time) string {
+	names := make([]string, len(r.Riders))
+	for i, e := range r.Riders {
+		names[i] = fmt.Sprintf("%s (%s)", e.RiderName(), e.Category)
+	}
+
+	left := r.Start.Sub(now).Round(time.Minute)
+	return fmt.Sprintf("%s starts in %dh %dm (%s). Signed up: %s",
+		r.Title, int(left.Hours()), int(left.Minutes())%60, r.Start.Format("15:04 MST"), strings.Join(names, ", "))
+}
@@ -0,0 +1,47 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventReminder(t *testing.T) {
+	start := time.Date(2021, 3, 2, 19, 0, 0, 0, time.UTC)
+	signup := func(zwid int, name string, cat string, tid string) Event {
+		return Event{Zid: "1700000", EventTitle: "WTRL TTT", EventDateSecs: EventDateType(start.Unix()), EventDate: start,
+			Zwid: zwid, Name: name, Category: cat, TeamID: tid}
+	}
+	signups := []Event{
+		signup(1, "Bob", "B", "2672"),
+		signup(2, "Someone Else", "B", "1"),
+		signup(3, "Ann", "B", "2672"),
+		signup(4, "Cat", "A", "2672"),
+	}
+
+	r := NewEventReminder(signups, "2672")
+	r.Start = r.Start.UTC()
+	if r.Zid != "1700000" || r.Title != "WTRL TTT" || !r.Start.Equal(start) || len(r.Riders) != 3 {
+		t.Fatalf("Unexpected reminder %+v", r)
+	}
+
+	cases := map[time.Duration]bool{
+		-3 * time.Hour:   false,
+		-2 * time.Hour:   true,
+		-5 * time.Minute: true,
+		time.Minute:      false,
+	}
+	for offset, expected := range cases {
+		if due := r.Due(start.Add(offset), 2*time.Hour); due != expected {
+			t.Errorf("Due at %v from the start is %v, expected %v", offset, due, expected)
+		}
+	}
+
+	expected := "WTRL TTT starts in 1h 30m (19:00 UTC). Signed up: Cat (A), Ann (B), Bob (B)"
+	if msg := r.Message(start.Add(-90 * time.Minute)); msg != expected {
+		t.Errorf("Got message %q, expected %q", msg, expected)
+	}
+
+	if r := NewEventReminder(signups[1:2], "2672"); r.Due(start.Add(-time.Hour), 2*time.Hour) {
+		t.Errorf("Reminder due with no club riders")
+	}
+}
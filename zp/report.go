@@ -0,0 +1,238 @@
+package zp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultReportTemplate is used to write race reports unless the caller supplies their own
+const DefaultReportTemplate = `{{.Title}}
+{{- if not .Date.IsZero}} on {{.Date.Format "Monday 2 January"}}{{end}} had {{.Finishers}} finishers
+{{- with .Club}}, {{len .}} of them from the club{{end}}.
+{{- range .Categories}}{{if .Podium}} The {{.Category}} podium was {{placings .Podium}}.{{end}}
+{{- with .Club}} For the club, {{placings .}}.{{end}}
+{{- end}}
+{{- range .Notable}} {{.Name}} {{.Text}}.{{end}}
+{{- with .DNF}} {{riders .}} didn't finish.{{end}}
+`
+
+// RaceReport holds what we need to write up an event
+type RaceReport struct {
+	EventID    int
+	Title      string
+	Date       time.Time
+	Finishers  int
+	Categories []CategoryResults
+
+	// Club holds the results for club riders who finished, in finishing order, and DNF
+	// the club riders who didn't
+	Club    []Event
+	DNF     []Event
+	Notable []Notable
+}
+
+// CategoryResults holds the results for one category in an event
+type CategoryResults struct {
+	Category  string
+	Finishers int
+	Podium    []Event
+	Club      []Event
+}
+
+// Notable is a performance worth a mention
+type Notable struct {
+	Name string
+	Text string
+}
+
+// NewRaceReport collects the information for a race report from an event's results.
+// Riders with no position didn't finish, so they aren't counted as finishers or placed,
+// and any from the club are listed in DNF.
+func NewRaceReport(eventID int, clubID int, results []Event) RaceReport {
+	report := RaceReport{EventID: eventID}
+
+	sorted := make([]Event, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return placedBefore(sorted[i], sorted[j])
+	})
+
+	categories := make(map[string]*CategoryResults)
+	club := strconv.Itoa(clubID)
+	for _, r := range sorted {
+		if report.Title == "" {
+			report.Title = r.EventTitle
+		}
+		if report.Date.IsZero() && r.EventDateSecs != 0 {
+			report.Date = r.EventDate
+		}
+
+		c, ok := categories[r.Category]
+		if !ok {
+			c = &CategoryResults{Category: r.Category}
+			categories[r.Category] = c
+		}
+		if r.Pos <= 0 {
+			if r.TeamID == club {
+				report.DNF = append(report.DNF, r)
+			}
+			continue
+		}
+		report.Finishers++
+		c.Finishers++
+
+		if r.PositionInCat >= 1 && r.PositionInCat <= 3 {
+			c.Podium = append(c.Podium, r)
+		}
+
+		if r.TeamID == club {
+			c.Club = append(c.Club, r)
+			report.Club = append(report.Club, r)
+		}
+	}
+
+	for _, c := range categories {
+		sort.SliceStable(c.Podium, func(i, j int) bool {
+			return c.Podium[i].PositionInCat < c.Podium[j].PositionInCat
+		})
+		report.Categories = append(report.Categories, *c)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+
+	report.Notable = notablePerformances(report.Club)
+	return report
+}
+
+// notablePerformances picks out a few things worth mentioning about the club's results
+func notablePerformances(club []Event) []Notable {
+	var notable []Notable
+	for _, r := range club {
+		if r.PositionInCat == 1 {
+			notable = append(notable, Notable{
+				Name: r.RiderName(),
+				Text: fmt.Sprintf("won the %s race", r.Category),
+			})
+		}
+	}
+
+	var bestWkg, bestSprint *Event
+	for i := range club {
+		r := &club[i]
+		if bestWkg == nil || toFloat(r.AvgWkg) > toFloat(bestWkg.AvgWkg) {
+			bestWkg = r
+		}
+		if bestSprint == nil || r.W15 > bestSprint.W15 {
+			bestSprint = r
+		}
+	}
+
+	if bestWkg != nil && toFloat(bestWkg.AvgWkg) > 0 {
+		notable = append(notable, Notable{
+			Name: bestWkg.RiderName(),
+			Text: fmt.Sprintf("put out the club's best average of %.1f w/kg", toFloat(bestWkg.AvgWkg)),
+		})
+	}
+
+	if bestSprint != nil && bestSprint.W15 > 0 {
+		notable = append(notable, Notable{
+			Name: bestSprint.RiderName(),
+			Text: fmt.Sprintf("had the club's biggest sprint, %.0fW for 15 seconds", float64(bestSprint.W15)),
+		})
+	}
+
+	return notable
+}
+
+var reportFuncs = template.FuncMap{
+	"placings": placings,
+	"riders":   riderNames,
+	"ordinal":  ordinal,
+	"duration": func(secs Number) string {
+		return formatSeconds(float64(secs))
+	},
+	"wkg": func(v interface{}) string {
+		return strconv.FormatFloat(toFloat(v), 'f', 1, 64)
+	},
+}
+
+// WriteRaceReport renders the report using the template text. If tmpl is empty the
+// DefaultReportTemplate is used.
+func WriteRaceReport(w io.Writer, report RaceReport, tmpl string) error {
+	if tmpl == "" {
+		tmpl = DefaultReportTemplate
+	}
+
+	t, err := template.New("report").Funcs(reportFuncs).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parsing report template: %v", err)
+	}
+
+	return t.Execute(w, report)
+}
+
+// placings lists riders with their category positions, e.g. "Ann (1st), Bob (4th) and Cat (9th)",
+// falling back to their overall position if there isn't one in the category
+func placings(results []Event) string {
+	parts := make([]string, len(results))
+	for i, r := range results {
+		switch {
+		case r.PositionInCat > 0:
+			parts[i] = fmt.Sprintf("%s (%s)", r.RiderName(), ordinal(r.PositionInCat))
+		case r.Pos > 0:
+			parts[i] = fmt.Sprintf("%s (%s)", r.RiderName(), ordinal(r.Pos))
+		default:
+			parts[i] = fmt.Sprintf("%s (DNF)", r.RiderName())
+		}
+	}
+	return listOf(parts)
+}
+
+// riderNames lists the riders' names, e.g. "Ann, Bob and Cat"
+func riderNames(results []Event) string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.RiderName()
+	}
+	return listOf(names)
+}
+
+// listOf joins the parts into a list, with "and" before the last one
+func listOf(parts []string) string {
+	if len(parts) < 2 {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// ordinal turns 1 into "1st", 2 into "2nd" and so on
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// formatSeconds gives a number of seconds as h:mm:ss, or m:ss if it's under an hour
+func formatSeconds(secs float64) string {
+	s := int(secs + 0.5)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, (s/60)%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
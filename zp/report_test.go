@@ -0,0 +1,121 @@
+package zp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testResults() []Event {
+	return []Event{
+		{Name: "Bob", Pos: 2, PositionInCat: 2, Category: "B", TeamID: "2672", AvgWkg: []interface{}{"3.4", 0}, W15: 700},
+		{Name: "Ann", Pos: 1, PositionInCat: 1, Category: "B", TeamID: "1", AvgWkg: []interface{}{"3.5", 0}, W15: 800},
+		{Name: "Cat", Pos: 3, PositionInCat: 3, Category: "B", TeamID: "1"},
+		{Name: "Dan", Pos: 4, PositionInCat: 1, Category: "C", TeamID: "2672", AvgWkg: []interface{}{"2.9", 0}, W15: 750},
+		{Name: "&Ouml;zge", Pos: 5, PositionInCat: 2, Category: "C", TeamID: "2672", AvgWkg: []interface{}{"3.0", 0}},
+	}
+}
+
+func TestNewRaceReport(t *testing.T) {
+	results := testResults()
+	results[0].EventTitle = "Crit City Race"
+	results[0].EventDateSecs = EventDateType(1617469200)
+	results[0].EventDate = time.Unix(1617469200, 0)
+
+	report := NewRaceReport(1234, 2672, results)
+	if report.Title != "Crit City Race" || report.Finishers != 5 {
+		t.Errorf("Unexpected report header %s, %d finishers", report.Title, report.Finishers)
+	}
+
+	if len(report.Categories) != 2 || report.Categories[0].Category != "B" {
+		t.Fatalf("Unexpected categories %v", report.Categories)
+	}
+
+	b := report.Categories[0]
+	if len(b.Podium) != 3 || b.Podium[0].Name != "Ann" || len(b.Club) != 1 {
+		t.Errorf("Unexpected B results %v", b)
+	}
+
+	if len(report.Club) != 3 || report.Club[0].Name != "Bob" {
+		t.Errorf("Unexpected club results %v", report.Club)
+	}
+
+	var buf bytes.Buffer
+	err := WriteRaceReport(&buf, report, "")
+	if err != nil {
+		t.Fatalf("Failed writing report: %v", err)
+	}
+
+	text := buf.String()
+	for _, expected := range []string{
+		"Crit City Race on Saturday 3 April had 5 finishers, 3 of them from the club.",
+		"The B podium was Ann (1st), Bob (2nd) and Cat (3rd).",
+		"For the club, Dan (1st) and Özge (2nd).",
+		"Dan won the C race.",
+		"Bob put out the club's best average of 3.4 w/kg.",
+		"Dan had the club's biggest sprint, 750W for 15 seconds.",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Report missing %q:\n%s", expected, text)
+		}
+	}
+}
+
+func TestNewRaceReportDNF(t *testing.T) {
+	results := append(testResults(),
+		Event{Name: "Eve", Category: "B", TeamID: "2672", AvgWkg: []interface{}{"4.0", 0}},
+		Event{Name: "Fay", Category: "C", TeamID: "1"})
+	report := NewRaceReport(1234, 2672, results)
+
+	if report.Finishers != 5 || report.Categories[0].Finishers != 3 || report.Categories[1].Finishers != 2 {
+		t.Errorf("Expected riders who didn't finish not to count as finishers, got %d, %v", report.Finishers, report.Categories)
+	}
+	if len(report.Club) != 3 || report.Club[0].Name != "Bob" {
+		t.Errorf("Unexpected club results %v", report.Club)
+	}
+	if len(report.DNF) != 1 || report.DNF[0].Name != "Eve" {
+		t.Errorf("Expected Eve to be the club's DNF, got %v", report.DNF)
+	}
+
+	var buf bytes.Buffer
+	must(t, WriteRaceReport(&buf, report, ""))
+	text := buf.String()
+	if !strings.Contains(text, "had 5 finishers, 3 of them from the club.") || !strings.Contains(text, "Eve didn't finish.") ||
+		strings.Contains(text, "0th") || strings.Contains(text, "4.0 w/kg") {
+		t.Errorf("Unexpected report for riders who didn't finish:\n%s", text)
+	}
+
+	if p := placings([]Event{{Name: "Gus", Pos: 7}, {Name: "Hal"}}); p != "Gus (7th) and Hal (DNF)" {
+		t.Errorf("Unexpected placings %q", p)
+	}
+}
+
+func TestWriteRaceReportCustomTemplate(t *testing.T) {
+	report := NewRaceReport(1234, 2672, testResults())
+
+	var buf bytes.Buffer
+	err := WriteRaceReport(&buf, report, `{{range .Club}}{{.RiderName}} {{ordinal .Pos}} {{wkg .AvgWkg}};{{end}}`)
+	if err != nil {
+		t.Fatalf("Failed writing report: %v", err)
+	}
+
+	expected := "Bob 2nd 3.4;Dan 4th 2.9;Özge 5th 3.0;"
+	if buf.String() != expected {
+		t.Errorf("Got %q expected %q", buf.String(), expected)
+	}
+
+	err = WriteRaceReport(&buf, report, `{{.NoSuchField`)
+	if err == nil {
+		t.Errorf("Expected error from bad template")
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	cases := map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 102: "102nd"}
+	for n, expected := range cases {
+		if result := ordinal(n); result != expected {
+			t.Errorf("Got %s expected %s", result, expected)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package zp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NotifyResultsUpdated is the kind of notification for results that have changed since
+// they were first posted
+const NotifyResultsUpdated = "results-updated"
+
+// ResultChange is how one rider's result in an event changed between two versions of the
+// results, such as ZwiftPower's provisional results and the final ones after DQs have been
+// applied
+type ResultChange struct {
+	Zwid   int
+	Name   string
+	Before *Event // Nil if they weren't in the earlier results
+	After  *Event // Nil if they've gone from the later results, usually disqualified
+}
+
+// Removed is true if the rider has gone from the results, which usually means they were
+// disqualified
+func (c ResultChange) Removed() bool {
+	return c.Before != nil && c.After == nil
+}
+
+// Added is true if the rider wasn't in the earlier results
+func (c ResultChange) Added() bool {
+	return c.Before == nil && c.After != nil
+}
+
+// Moved is true if the rider's position in their category has changed
+func (c ResultChange) Moved() bool {
+	return c.Before != nil && c.After != nil && c.Before.categoryPosition() != c.After.categoryPosition()
+}
+
+// Retimed is true if the rider's time has been adjusted
+func (c ResultChange) Retimed() bool {
+	return c.Before != nil && c.After != nil && math.Abs(float64(c.Before.Time-c.After.Time)) >= 0.001
+}
+
+// Recategorised is true if the rider has been moved to another category
+func (c ResultChange) Recategorised() bool {
+	return c.Before != nil && c.After != nil && c.Before.Category != c.After.Category
+}
+
+// String describes the change, e.g. "A Rider: 3rd to 2nd in B"
+func (c ResultChange) String() string {
+	switch {
+	case c.Removed():
+		return fmt.Sprintf("%s: removed from the results (was %s in %s)", c.Name, ordinal(c.Before.categoryPosition()), c.Before.Category)
+	case c.Added():
+		return fmt.Sprintf("%s: added to the results, %s in %s", c.Name, ordinal(c.After.categoryPosition()), c.After.Category)
+	}
+
+	var what []string
+	if c.Recategorised() {
+		what = append(what, fmt.Sprintf("moved from %s to %s", c.Before.Category, c.After.Category))
+	}
+	if c.Moved() {
+		what = append(what, fmt.Sprintf("%s to %s in %s", ordinal(c.Before.categoryPosition()), ordinal(c.After.categoryPosition()), c.After.Category))
+	}
+	if c.Retimed() {
+		what = append(what, fmt.Sprintf("time %s to %s", formatRaceTime(float64(c.Before.Time)), formatRaceTime(float64(c.After.Time))))
+	}
+	return fmt.Sprintf("%s: %s", c.Name, strings.Join(what, ", "))
+}
+
+// ResultsDiff is what changed between two versions of an event's results
+type ResultsDiff struct {
+	Zid     string
+	Title   string
+	Changes []ResultChange // In order of category, then position
+}
+
+// DiffResults compares two versions of an event's results, one row per rider as from
+// Client.EventResults. Riders whose category, position and time are all the same aren't
+// included.
+func DiffResults(before, after []Event) ResultsDiff {
+	var d ResultsDiff
+	for _, results := range [][]Event{after, before} {
+		if d.Zid == "" && len(results) > 0 {
+			d.Zid, d.Title = results[0].Zid, results[0].EventTitle
+		}
+	}
+
+	changes := make(map[int]*ResultChange)
+	var order []int
+	change := func(e Event) *ResultChange {
+		c, ok := changes[e.Zwid]
+		if !ok {
+			c = &ResultChange{Zwid: e.Zwid, Name: e.RiderName()}
+			changes[e.Zwid] = c
+			order = append(order, e.Zwid)
+		}
+		return c
+	}
+	for i := range before {
+		change(before[i]).Before = &before[i]
+	}
+	for i := range after {
+		change(after[i]).After = &after[i]
+	}
+
+	for _, id := range order {
+		c := *changes[id]
+		if c.Removed() || c.Added() || c.Moved() || c.Retimed() || c.Recategorised() {
+			d.Changes = append(d.Changes, c)
+		}
+	}
+	sort.SliceStable(d.Changes, func(i, j int) bool {
+		a, b := d.Changes[i].latest(), d.Changes[j].latest()
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.categoryPosition() < b.categoryPosition()
+	})
+	return d
+}
+
+// latest is the rider's result in the later version, or the earlier one if they've gone
+func (c ResultChange) latest() *Event {
+	if c.After != nil {
+		return c.After
+	}
+	return c.Before
+}
+
+// ForClub keeps the changes to the club's riders
+func (d ResultsDiff) ForClub(clubID int) ResultsDiff {
+	id := strconv.Itoa(clubID)
+	kept := d
+	kept.Changes = nil
+	for _, c := range d.Changes {
+		if c.latest().TeamID == id || (c.Before != nil && c.Before.TeamID == id) {
+			kept.Changes = append(kept.Changes, c)
+		}
+	}
+	return kept
+}
+
+// Text describes the changes for a "results updated" notification, e.g.
+//
+//	Results updated for WTRL TTT:
+//	A Rider: removed from the results (was 1st in B)
+//	Ann Other: 2nd to 1st in B
+func (d ResultsDiff) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Results updated for %s:\n", d.Title)
+	for _, c := range d.Changes {
+		fmt.Fprintln(&b, c.String())
+	}
+	return b.String()
+}
+
+// Versions of an event's results in the store
+const (
+	ProvisionalResults = "provisional" // The first results we saw
+	FinalResults       = "final"       // The latest, which stop changing once DQs have landed
+)
+
+// EventResults gets a stored version of the event's results, ProvisionalResults or
+// FinalResults
+func (s *FileStore) EventResults(eventID int, version string) ([]Event, error) {
+	var results []Event
+	err := s.read(s.path("results", fmt.Sprintf("%d_%s", eventID, version)), &results)
+	return results, err
+}
+
+// UpdateResults stores the latest version of the event's results, and says what has
+// changed since the last version stored. The first version is also kept as the
+// provisional results, so that what changed overall can be found with DiffResults. The
+// first time, there's nothing to compare with, so there are no changes.
+func (s *FileStore) UpdateResults(eventID int, results []Event) (ResultsDiff, error) {
+	final := s.path("results", fmt.Sprintf("%d_%s", eventID, FinalResults))
+	unlock, err := lockFile(final)
+	if err != nil {
+		return ResultsDiff{}, err
+	}
+	defer unlock()
+
+	previous, err := s.EventResults(eventID, FinalResults)
+	if IsNotFound(err) {
+		previous, err = s.EventResults(eventID, ProvisionalResults)
+		if IsNotFound(err) {
+			err = s.write(s.path("results", fmt.Sprintf("%d_%s", eventID, ProvisionalResults)), results)
+			if err != nil {
+				return ResultsDiff{}, err
+			}
+			return DiffResults(results, results), nil
+		}
+	}
+	if err != nil {
+		return ResultsDiff{}, err
+	}
+
+	return DiffResults(previous, results), s.write(final, results)
+}
@@ -0,0 +1,91 @@
+package zp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffResults(t *testing.T) {
+	result := func(zwid int, name string, category string, pos int, secs float64, team string) Event {
+		return Event{Zid: "100", EventTitle: "Club race", Zwid: zwid, Name: name, Category: category, PositionInCat: pos, Time: Number(secs), TeamID: team}
+	}
+	provisional := []Event{
+		result(1, "Fast Rider", "B", 1, 3600, "99"),
+		result(2, "A Rider", "B", 2, 3610, "2672"),
+		result(3, "Ann Other", "B", 3, 3620, "2672"),
+		result(4, "Steady Eddie", "C", 1, 3700, "2672"),
+		result(5, "Sandbagger", "C", 2, 3705, "99"),
+	}
+	final := []Event{
+		result(2, "A Rider", "B", 1, 3610, "2672"),
+		result(3, "Ann Other", "B", 2, 3625.5, "2672"),
+		result(4, "Steady Eddie", "C", 1, 3700, "2672"),
+		result(5, "Sandbagger", "B", 3, 3705, "99"),
+		result(6, "Late Upload", "C", 2, 3800, "2672"),
+	}
+
+	d := DiffResults(provisional, final)
+	var got []string
+	for _, c := range d.Changes {
+		got = append(got, c.String())
+	}
+	expected := []string{
+		"Fast Rider: removed from the results (was 1st in B)",
+		"A Rider: 2nd to 1st in B",
+		"Ann Other: 3rd to 2nd in B, time 1:00:20.000 to 1:00:25.500",
+		"Sandbagger: moved from C to B, 2nd to 3rd in B",
+		"Late Upload: added to the results, 2nd in C",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Got changes\n%s\nexpected\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+	if d.Zid != "100" || d.Title != "Club race" {
+		t.Errorf("Got event %s %q", d.Zid, d.Title)
+	}
+
+	club := d.ForClub(2672)
+	if len(club.Changes) != 3 || club.Changes[0].Name != "A Rider" {
+		t.Errorf("Got club changes %v", club.Changes)
+	}
+	if text := club.Text(); !strings.HasPrefix(text, "Results updated for Club race:\nA Rider: 2nd to 1st in B\n") {
+		t.Errorf("Got text %q", text)
+	}
+
+	if d := DiffResults(final, final); len(d.Changes) != 0 {
+		t.Errorf("Expected no changes, got %v", d.Changes)
+	}
+}
+
+func TestUpdateResults(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := []Event{{Zid: "100", Zwid: 1, Category: "B", PositionInCat: 1}, {Zid: "100", Zwid: 2, Category: "B", PositionInCat: 2}}
+	v2 := []Event{{Zid: "100", Zwid: 2, Category: "B", PositionInCat: 1}}
+
+	d, err := s.UpdateResults(100, v1)
+	if err != nil || len(d.Changes) != 0 {
+		t.Fatalf("First results gave %v: %v", d.Changes, err)
+	}
+	d, err = s.UpdateResults(100, v1)
+	if err != nil || len(d.Changes) != 0 {
+		t.Errorf("Unchanged results gave %v: %v", d.Changes, err)
+	}
+	d, err = s.UpdateResults(100, v2)
+	if err != nil || len(d.Changes) != 2 {
+		t.Errorf("Updated results gave %v: %v", d.Changes, err)
+	}
+	d, err = s.UpdateResults(100, v2)
+	if err != nil || len(d.Changes) != 0 {
+		t.Errorf("Changes should only be reported once, got %v: %v", d.Changes, err)
+	}
+
+	provisional, err := s.EventResults(100, ProvisionalResults)
+	must(t, err)
+	final, err := s.EventResults(100, FinalResults)
+	must(t, err)
+	if len(provisional) != 2 || len(final) != 1 || len(DiffResults(provisional, final).Changes) != 2 {
+		t.Errorf("Got provisional %v and final %v", provisional, final)
+	}
+}
@@ -0,0 +1,157 @@
+package zp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// HeadToHead is one rider's record against another, from the races they have both
+// finished in the same category
+type HeadToHead struct {
+	Zwid      int
+	Name      string
+	RivalZwid int
+	RivalName string
+	Races     int
+	Wins      int
+	Losses    int
+	Margin    float64 // Average seconds ahead of the rival, negative if behind
+}
+
+// Record is the wins and losses, e.g. "5-3"
+func (h HeadToHead) Record() string {
+	return fmt.Sprintf("%d-%d", h.Wins, h.Losses)
+}
+
+// RivalryMatrix has the head-to-head record between every pair of riders
+type RivalryMatrix struct {
+	Riders  []int
+	Names   []string
+	Records [][]HeadToHead // Records[i][j] is Riders[i]'s record against Riders[j]
+}
+
+// NewRivalryMatrix works out head-to-head records between the riders from their races
+// since the given date. Events can come from profiles or event results, or both. If no
+// riders are given, it includes everyone in the events, in order of name.
+func NewRivalryMatrix(events []Event, zwids []int, since time.Time) RivalryMatrix {
+	names := make(map[int]string)
+	byEvent := make(map[string][]Event)
+	for _, e := range events {
+		if names[e.Zwid] == "" {
+			names[e.Zwid] = e.RiderName()
+		}
+		if e.EventDateSecs == 0 || !e.IsRace() || e.EventDate.Before(since) || e.Pos <= 0 {
+			continue
+		}
+		byEvent[e.Zid] = append(byEvent[e.Zid], e)
+	}
+
+	if len(zwids) == 0 {
+		for id := range names {
+			zwids = append(zwids, id)
+		}
+		sort.Slice(zwids, func(i, j int) bool {
+			if names[zwids[i]] != names[zwids[j]] {
+				return names[zwids[i]] < names[zwids[j]]
+			}
+			return zwids[i] < zwids[j]
+		})
+	}
+
+	m := RivalryMatrix{}
+	index := make(map[int]int)
+	for _, id := range zwids {
+		if _, ok := index[id]; !ok {
+			index[id] = len(m.Riders)
+			m.Riders = append(m.Riders, id)
+			m.Names = append(m.Names, names[id])
+		}
+	}
+
+	m.Records = make([][]HeadToHead, len(m.Riders))
+	timed := make([][]int, len(m.Riders))
+	for i := range m.Riders {
+		m.Records[i] = make([]HeadToHead, len(m.Riders))
+		timed[i] = make([]int, len(m.Riders))
+		for j := range m.Riders {
+			m.Records[i][j] = HeadToHead{Zwid: m.Riders[i], Name: m.Names[i], RivalZwid: m.Riders[j], RivalName: m.Names[j]}
+		}
+	}
+
+	for _, ee := range byEvent {
+		// The same result can turn up in more than one rider's history
+		finishers := make(map[int]Event)
+		for _, e := range ee {
+			if _, ok := index[e.Zwid]; ok {
+				finishers[e.Zwid] = e
+			}
+		}
+
+		for a, ea := range finishers {
+			for b, eb := range finishers {
+				if a == b || ea.Category != eb.Category {
+					continue
+				}
+				i, j := index[a], index[b]
+				h := &m.Records[i][j]
+				h.Races++
+				if finishedAhead(ea, eb) {
+					h.Wins++
+				} else {
+					h.Losses++
+				}
+				if ea.Time > 0 && eb.Time > 0 {
+					h.Margin += float64(eb.Time - ea.Time)
+					timed[i][j]++
+				}
+			}
+		}
+	}
+
+	for i := range m.Records {
+		for j := range m.Records[i] {
+			if timed[i][j] > 0 {
+				m.Records[i][j].Margin /= float64(timed[i][j])
+			}
+		}
+	}
+	return m
+}
+
+// finishedAhead is true if a beat b, going by position in category where we have it
+func finishedAhead(a, b Event) bool {
+	if a.PositionInCat > 0 && b.PositionInCat > 0 {
+		return a.PositionInCat < b.PositionInCat
+	}
+	return a.Pos < b.Pos
+}
+
+// Rivalries lists each pair of riders who have raced each other at least once, from the point of
+// view of whoever is ahead. The pairs who have met most often come first, with the
+// closest records first among those.
+func (m RivalryMatrix) Rivalries() []HeadToHead {
+	var rivalries []HeadToHead
+	for i := range m.Records {
+		for j := i + 1; j < len(m.Records[i]); j++ {
+			h := m.Records[i][j]
+			if h.Races == 0 {
+				continue
+			}
+			if h.Losses > h.Wins || (h.Losses == h.Wins && h.Margin < 0) {
+				h = m.Records[j][i]
+			}
+			rivalries = append(rivalries, h)
+		}
+	}
+
+	sort.SliceStable(rivalries, func(i, j int) bool {
+		a, b := rivalries[i], rivalries[j]
+		if a.Races != b.Races {
+			return a.Races > b.Races
+		}
+		return math.Abs(float64(a.Wins-a.Losses)) < math.Abs(float64(b.Wins-b.Losses))
+	})
+	return rivalries
+}
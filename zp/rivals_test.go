@@ -0,0 +1,63 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRivalryMatrix(t *testing.T) {
+	now := time.Date(2021, 3, 1, 18, 0, 0, 0, time.UTC)
+	result := func(zid string, zwid int, name string, cat string, pos int, secs float64, daysAgo int) Event {
+		date := now.AddDate(0, 0, -daysAgo)
+		return Event{Zid: zid, Zwid: zwid, Name: name, Category: cat, Pos: pos, Time: Number(secs),
+			EventType: "TYPE_RACE", EventDateSecs: EventDateType(date.Unix()), EventDate: date}
+	}
+
+	events := []Event{
+		result("1", 1, "Ann", "B", 1, 3600, 10),
+		result("1", 2, "Bob", "B", 3, 3630, 10),
+		result("1", 3, "Cat", "C", 2, 3700, 10),
+		result("2", 1, "Ann", "B", 5, 3000, 5),
+		result("2", 2, "Bob", "B", 2, 2990, 5),
+		result("2", 2, "Bob", "B", 2, 2990, 5), // Also in Ann's history
+		result("3", 1, "Ann", "B", 1, 3000, 100),
+		result("3", 2, "Bob", "B", 2, 3100, 100),
+	}
+	ride := result("4", 2, "Bob", "B", 1, 3000, 1)
+	ride.EventType = "TYPE_RIDE"
+	events = append(events, ride)
+
+	m := NewRivalryMatrix(events, nil, now.AddDate(0, 0, -30))
+	if len(m.Riders) != 3 || m.Names[0] != "Ann" || m.Names[2] != "Cat" {
+		t.Fatalf("Unexpected riders %v %v", m.Riders, m.Names)
+	}
+
+	ab := m.Records[0][1]
+	if ab.Races != 2 || ab.Record() != "1-1" || ab.Margin != 10 {
+		t.Errorf("Unexpected record for Ann against Bob: %+v", ab)
+	}
+	ba := m.Records[1][0]
+	if ba.Races != 2 || ba.Record() != "1-1" || ba.Margin != -10 {
+		t.Errorf("Unexpected record for Bob against Ann: %+v", ba)
+	}
+	if ac := m.Records[0][2]; ac.Races != 0 {
+		t.Errorf("Riders in different categories shouldn't count: %+v", ac)
+	}
+
+	rivalries := m.Rivalries()
+	if len(rivalries) != 1 || rivalries[0].Name != "Ann" || rivalries[0].RivalName != "Bob" {
+		t.Errorf("Unexpected rivalries %+v", rivalries)
+	}
+
+	// Selected riders, in the order given
+	m = NewRivalryMatrix(events, []int{2, 1, 2}, time.Time{})
+	if len(m.Riders) != 2 || m.Names[0] != "Bob" {
+		t.Fatalf("Unexpected riders %v %v", m.Riders, m.Names)
+	}
+	if ba := m.Records[0][1]; ba.Races != 3 || ba.Record() != "1-2" {
+		t.Errorf("Unexpected record for Bob against Ann: %+v", ba)
+	}
+	if rivalries := m.Rivalries(); len(rivalries) != 1 || rivalries[0].Name != "Ann" {
+		t.Errorf("Expected rivalry from Ann's point of view, got %+v", rivalries)
+	}
+}
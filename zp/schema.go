@@ -0,0 +1,417 @@
+package zp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxSchemaBody is the most of a response SchemaWatch holds on to while it's read. Bigger
+// responses aren't checked.
+const maxSchemaBody = 16 << 20
+
+// Schema is the shape of the JSON from one of ZwiftPower's endpoints: the JSON types seen
+// for each field of its records, by path, e.g. "ftp": "number" or "avg_power": "array".
+// ZwiftPower isn't consistent, and the same field can be a number in one record and a
+// string in the next, so a field can have several types, joined with |.
+type Schema map[string]string
+
+// SchemaOf works out the shape of some JSON. The records of ZwiftPower's usual
+// {"data": [...]} are the elements of data; anything else is a record in itself. Fields
+// of nested objects have paths such as "competitionMetrics.category". Nulls don't count.
+func SchemaOf(data []byte) (Schema, error) {
+	var top interface{}
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+
+	records := []interface{}{top}
+	if obj, ok := top.(map[string]interface{}); ok {
+		if list, ok := obj["data"].([]interface{}); ok {
+			records = list
+		}
+	} else if list, ok := top.([]interface{}); ok {
+		records = list
+	}
+
+	types := make(map[string]map[string]bool)
+	for _, r := range records {
+		addFields(types, "", r)
+	}
+	s := make(Schema, len(types))
+	for path, seen := range types {
+		s[path] = joinTypes(seen)
+	}
+	return s, nil
+}
+
+func addFields(types map[string]map[string]bool, prefix string, v interface{}) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for k, field := range obj {
+		path := prefix + k
+		t := jsonType(field)
+		if t == "" {
+			continue
+		}
+		if types[path] == nil {
+			types[path] = make(map[string]bool)
+		}
+		types[path][t] = true
+		if t == "object" {
+			addFields(types, path+".", field)
+		}
+	}
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	}
+	return ""
+}
+
+func joinTypes(seen map[string]bool) string {
+	var types []string
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return strings.Join(types, "|")
+}
+
+// Fingerprint is a short hash of the schema, which changes if any field or type does
+func (s Schema) Fingerprint() string {
+	var fields []string
+	for path, t := range s {
+		fields = append(fields, path+":"+t)
+	}
+	sort.Strings(fields)
+	sum := sha256.Sum256([]byte(strings.Join(fields, ",")))
+	return fmt.Sprintf("%x", sum[:6])
+}
+
+// SchemaDrift is how an endpoint's JSON has changed from the shape we knew
+type SchemaDrift struct {
+	Endpoint string
+	Missing  []string // Fields that have gone, perhaps renamed
+	Changed  []string // Fields whose type has changed completely, e.g. "ftp: number -> array"
+	Added    []string // New fields, which do no harm but may be the new name for a missing one
+}
+
+// Drifted is true if fields have gone or changed type, which leaves zero values where
+// there used to be data
+func (d SchemaDrift) Drifted() bool {
+	return len(d.Missing) > 0 || len(d.Changed) > 0
+}
+
+func (d SchemaDrift) String() string {
+	var parts []string
+	if len(d.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(d.Missing, ", "))
+	}
+	if len(d.Changed) > 0 {
+		parts = append(parts, "changed "+strings.Join(d.Changed, ", "))
+	}
+	if len(d.Added) > 0 {
+		parts = append(parts, "added "+strings.Join(d.Added, ", "))
+	}
+	return fmt.Sprintf("%s: %s", d.Endpoint, strings.Join(parts, "; "))
+}
+
+// CompareSchemas finds how an endpoint's JSON has changed. A field only counts as changed
+// if none of its types are the same as before, since ZwiftPower often mixes numbers and
+// strings.
+func CompareSchemas(endpoint string, known, now Schema) SchemaDrift {
+	d := SchemaDrift{Endpoint: endpoint}
+	for path, was := range known {
+		is, ok := now[path]
+		if !ok {
+			d.Missing = append(d.Missing, path)
+			continue
+		}
+		if !sharesType(was, is) {
+			d.Changed = append(d.Changed, fmt.Sprintf("%s: %s -> %s", path, was, is))
+		}
+	}
+	for path := range now {
+		if _, ok := known[path]; !ok {
+			d.Added = append(d.Added, path)
+		}
+	}
+	sort.Strings(d.Missing)
+	sort.Strings(d.Changed)
+	sort.Strings(d.Added)
+	return d
+}
+
+func sharesType(a, b string) bool {
+	for _, t := range strings.Split(a, "|") {
+		for _, u := range strings.Split(b, "|") {
+			if t == u {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// merge adds fields and types seen in the other schema
+func (s Schema) merge(other Schema) {
+	for path, t := range other {
+		seen := make(map[string]bool)
+		for _, types := range []string{s[path], t} {
+			for _, t := range strings.Split(types, "|") {
+				if t != "" {
+					seen[t] = true
+				}
+			}
+		}
+		s[path] = joinTypes(seen)
+	}
+}
+
+// SchemaWatch notices when ZwiftPower changes the names or types of fields in its JSON,
+// which would otherwise silently leave zero values, such as in FTP columns. It learns
+// each endpoint's shape from the first response it sees, and from then on warns about
+// any response with fields missing, or with a different type. New fields are learnt, but
+// fields that go missing stay in the known shape until Forget is called, so the warning
+// carries on until someone has looked into it. Add its Middleware to a client, and save
+// Known between runs (see FileStore.PutSchemas). It's safe for concurrent use.
+type SchemaWatch struct {
+	OnDrift func(SchemaDrift) // Called the first time each drift is seen; defaults to logging a warning
+	OnLearn func()            // Called when a shape is learnt or gains fields, e.g. to save Known
+
+	mu     sync.Mutex
+	known  map[string]Schema
+	warned map[string]bool
+	stats  SchemaStats
+}
+
+// SchemaStats is telemetry about the shapes of the responses we've checked
+type SchemaStats struct {
+	Checked int            // Responses checked
+	Drifted int            // Responses with fields missing or changed
+	Drifts  map[string]int // Responses with fields missing or changed, by endpoint
+	Latest  []SchemaDrift  // The latest drift for each endpoint that has drifted
+}
+
+// NewSchemaWatch makes a watch that starts from these known shapes, which can be nil
+func NewSchemaWatch(known map[string]Schema) *SchemaWatch {
+	w := &SchemaWatch{known: make(map[string]Schema), warned: make(map[string]bool)}
+	for endpoint, s := range known {
+		w.known[endpoint] = s
+	}
+	return w
+}
+
+// Known gets the shape of each endpoint as it stands, for saving
+func (w *SchemaWatch) Known() map[string]Schema {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	known := make(map[string]Schema, len(w.known))
+	for endpoint, s := range w.known {
+		copied := make(Schema, len(s))
+		for path, t := range s {
+			copied[path] = t
+		}
+		known[endpoint] = copied
+	}
+	return known
+}
+
+// Forget drops the endpoint's known shape, so that the next response becomes the new
+// one. It's for once a change has been dealt with.
+func (w *SchemaWatch) Forget(endpoint string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.known, endpoint)
+	delete(w.stats.Drifts, endpoint)
+}
+
+// Stats reports what the watch has seen so far
+func (w *SchemaWatch) Stats() SchemaStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.stats
+	s.Drifts = make(map[string]int, len(w.stats.Drifts))
+	for endpoint, n := range w.stats.Drifts {
+		s.Drifts[endpoint] = n
+	}
+	s.Latest = append([]SchemaDrift(nil), w.stats.Latest...)
+	return s
+}
+
+// Check compares a response from the endpoint with its known shape, learning the shape
+// if it's new. Responses that aren't JSON, or have no records, are skipped.
+func (w *SchemaWatch) Check(endpoint string, data []byte) SchemaDrift {
+	now, err := SchemaOf(data)
+	if err != nil || len(now) == 0 {
+		return SchemaDrift{Endpoint: endpoint}
+	}
+
+	w.mu.Lock()
+	w.stats.Checked++
+	known, ok := w.known[endpoint]
+	if !ok {
+		w.known[endpoint] = now
+		onLearn := w.OnLearn
+		w.mu.Unlock()
+		if onLearn != nil {
+			onLearn()
+		}
+		return SchemaDrift{Endpoint: endpoint}
+	}
+
+	d := CompareSchemas(endpoint, known, now)
+	learnt := len(d.Added) > 0
+	if learnt {
+		known.merge(now)
+	}
+	var report bool
+	if d.Drifted() {
+		w.stats.Drifted++
+		if w.stats.Drifts == nil {
+			w.stats.Drifts = make(map[string]int)
+		}
+		w.stats.Drifts[endpoint]++
+		w.stats.Latest = replaceDrift(w.stats.Latest, d)
+
+		// Fields added since the first report don't make it a new drift
+		key := SchemaDrift{Endpoint: endpoint, Missing: d.Missing, Changed: d.Changed}.String()
+		report = !w.warned[key]
+		w.warned[key] = true
+	}
+	onDrift, onLearn := w.OnDrift, w.OnLearn
+	w.mu.Unlock()
+
+	if learnt && onLearn != nil {
+		onLearn()
+	}
+	if report {
+		if onDrift != nil {
+			onDrift(d)
+		} else {
+			log.Printf("Warning: ZwiftPower's JSON has changed shape, so some values may be zero. %s", d)
+		}
+	}
+	return d
+}
+
+func replaceDrift(drifts []SchemaDrift, d SchemaDrift) []SchemaDrift {
+	for i := range drifts {
+		if drifts[i].Endpoint == d.Endpoint {
+			drifts[i] = d
+			return drifts
+		}
+	}
+	return append(drifts, d)
+}
+
+func (s *FileStore) schemasPath() string {
+	return filepath.Join(s.Dir, "schemas.json")
+}
+
+// Schemas gets the known shape of each of ZwiftPower's endpoints
+func (s *FileStore) Schemas() (map[string]Schema, error) {
+	schemas := make(map[string]Schema)
+	err := s.read(s.schemasPath(), &schemas)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+// PutSchemas saves the known shape of each endpoint, replacing what was there
+func (s *FileStore) PutSchemas(schemas map[string]Schema) error {
+	path := s.schemasPath()
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.write(path, schemas)
+}
+
+// urlIDs are the IDs in endpoint URLs, such as 123_all.json, which aren't part of the
+// endpoint, unlike the 3 in cache3
+var urlIDs = regexp.MustCompile(`(^|[/_.-])[0-9]+`)
+
+// SchemaEndpoint names the endpoint a URL is for, with IDs taken out, e.g.
+// "/cache3/profile/{id}_all.json", or "/api3.php?do=profile_results&type=all"
+func SchemaEndpoint(u *url.URL) string {
+	endpoint := urlIDs.ReplaceAllString(u.Path, "${1}{id}")
+	if filepath.Base(u.Path) == "api3.php" {
+		q := u.Query()
+		endpoint += "?do=" + q.Get("do")
+		if t := q.Get("type"); t != "" {
+			endpoint += "&type=" + t
+		}
+	}
+	return endpoint
+}
+
+// Middleware checks the shape of each successful response that turns out to be JSON, as
+// it's read. ZwiftPower doesn't always say that it is.
+func (w *SchemaWatch) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return resp, err
+		}
+		resp.Body = &schemaBody{ReadCloser: resp.Body, watch: w, endpoint: SchemaEndpoint(req.URL)}
+		return resp, nil
+	})
+}
+
+// schemaBody keeps a copy of a response as it's read, and checks it once it has all
+// been read
+type schemaBody struct {
+	io.ReadCloser
+	watch    *SchemaWatch
+	endpoint string
+	buf      bytes.Buffer
+	done     bool
+}
+
+func (b *schemaBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.done {
+		if b.buf.Len()+n > maxSchemaBody {
+			b.done = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.done {
+		b.done = true
+		data := bytes.TrimSpace(b.buf.Bytes())
+		if len(data) > 0 && (data[0] == '{' || data[0] == '[') {
+			b.watch.Check(b.endpoint, data)
+		}
+		b.buf = bytes.Buffer{}
+	}
+	return n, err
+}
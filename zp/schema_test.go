@@ -0,0 +1,142 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaOf(t *testing.T) {
+	s, err := SchemaOf([]byte(`{"data": [
+		{"zwid": 1, "name": "A", "ftp": 250, "avg_power": [200, 0], "team": null, "metrics": {"category": "B"}},
+		{"zwid": 2, "name": "B", "ftp": "", "avg_power": [180, 0], "team": "ZSUN", "metrics": {"category": "C", "racing": 1.5}}
+	]}`))
+	must(t, err)
+	expected := Schema{
+		"zwid":             "number",
+		"name":             "string",
+		"ftp":              "number|string",
+		"avg_power":        "array",
+		"team":             "string",
+		"metrics":          "object",
+		"metrics.category": "string",
+		"metrics.racing":   "number",
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Expected %v, got %v", expected, s)
+	}
+
+	// The order of records doesn't change the fingerprint, but a type does
+	same, _ := SchemaOf([]byte(`{"data": [{"ftp": "", "zwid": 2}, {"zwid": 1, "ftp": 250}]}`))
+	other, _ := SchemaOf([]byte(`{"data": [{"zwid": 1, "ftp": 250}]}`))
+	again, _ := SchemaOf([]byte(`[{"zwid": 1, "ftp": 250}]`))
+	if same.Fingerprint() == other.Fingerprint() || other.Fingerprint() != again.Fingerprint() {
+		t.Errorf("Unexpected fingerprints %s, %s, %s", same.Fingerprint(), other.Fingerprint(), again.Fingerprint())
+	}
+
+	if _, err := SchemaOf([]byte("<html>")); err == nil {
+		t.Errorf("Expected an error for HTML")
+	}
+}
+
+func TestCompareSchemas(t *testing.T) {
+	known := Schema{"zwid": "number", "ftp": "number|string", "name": "string", "w": "array"}
+	now := Schema{"zwid": "number", "ftp": "string", "rider_name": "string", "w": "number"}
+	d := CompareSchemas("/x", known, now)
+	if !d.Drifted() || !reflect.DeepEqual(d.Missing, []string{"name"}) ||
+		!reflect.DeepEqual(d.Changed, []string{"w: array -> number"}) || !reflect.DeepEqual(d.Added, []string{"rider_name"}) {
+		t.Errorf("Unexpected drift %+v", d)
+	}
+	if d.String() != "/x: missing name; changed w: array -> number; added rider_name" {
+		t.Errorf("Unexpected description %q", d.String())
+	}
+
+	if d := CompareSchemas("/x", known, Schema{"zwid": "number", "ftp": "number", "name": "string", "w": "array", "new": "bool"}); d.Drifted() {
+		t.Errorf("Expected new fields and narrower types not to count, got %+v", d)
+	}
+}
+
+func TestSchemaWatch(t *testing.T) {
+	body := `{"data": [{"zwid": 1, "name": "Rider A", "ftp": 250}]}`
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	})
+
+	var drifts []SchemaDrift
+	learnt := 0
+	watch := NewSchemaWatch(nil)
+	watch.OnDrift = func(d SchemaDrift) { drifts = append(drifts, d) }
+	watch.OnLearn = func() { learnt++ }
+	client, err := New(watch.Middleware)
+	must(t, err)
+
+	get := func(id int) {
+		t.Helper()
+		if _, err := client.Events(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get(1)
+	endpoint := "/cache3/profile/{id}_all.json"
+	known := watch.Known()
+	if len(known) != 1 || known[endpoint]["ftp"] != "number" || learnt != 1 {
+		t.Fatalf("Expected to learn the profile shape, got %v", known)
+	}
+
+	// ftp has been renamed, which is only reported once, but keeps counting
+	body = `{"data": [{"zwid": 1, "name": "Rider A", "ftp_watts": 250}]}`
+	get(2)
+	get(3)
+	if len(drifts) != 1 || !reflect.DeepEqual(drifts[0].Missing, []string{"ftp"}) || drifts[0].Endpoint != endpoint {
+		t.Errorf("Expected one drift for ftp, got %+v", drifts)
+	}
+	stats := watch.Stats()
+	if stats.Checked != 3 || stats.Drifted != 2 || stats.Drifts[endpoint] != 2 || len(stats.Latest) != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if watch.Known()[endpoint]["ftp_watts"] != "number" || learnt != 2 {
+		t.Errorf("Expected new fields to be learnt, got %v", watch.Known())
+	}
+
+	// Once forgotten, the new shape is the known one
+	watch.Forget(endpoint)
+	get(4)
+	if _, ok := watch.Known()[endpoint]["ftp"]; ok || len(drifts) != 1 {
+		t.Errorf("Expected the new shape to be known, got %v", watch.Known())
+	}
+}
+
+func TestSchemaEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"https://zwiftpower.com/cache3/profile/12345_all.json":                "/cache3/profile/{id}_all.json",
+		"https://zwiftpower.com/api3.php?do=profile_results&z=12345&type=all": "/api3.php?do=profile_results&type=all",
+		"https://zwiftpower.com/api3.php?do=team_riders&id=2672":              "/api3.php?do=team_riders",
+	}
+	for raw, expected := range cases {
+		u, _ := url.Parse(raw)
+		if got := SchemaEndpoint(u); got != expected {
+			t.Errorf("%s: expected %s, got %s", raw, expected, got)
+		}
+	}
+}
+
+func TestStoreSchemas(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	must(t, err)
+	schemas, err := s.Schemas()
+	must(t, err)
+	if len(schemas) != 0 {
+		t.Errorf("Expected no schemas yet, got %v", schemas)
+	}
+	expected := map[string]Schema{"/cache3/profile/{id}_all.json": {"zwid": "number"}}
+	must(t, s.PutSchemas(expected))
+	schemas, err = s.Schemas()
+	must(t, err)
+	if !reflect.DeepEqual(schemas, expected) {
+		t.Errorf("Expected %v, got %v", expected, schemas)
+	}
+}
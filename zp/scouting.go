@@ -0,0 +1,92 @@
+package zp
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TeamScout is a scouting report on one team's riders in one pen of a fixture, from their
+// power in recent events. W/kg figures are averages over the team's riders in the pen.
+type TeamScout struct {
+	Category string
+	TeamID   string
+	Team     string
+	Club     bool // Our own club
+	Riders   int
+	Scouted  int // Riders with enough recent data to say anything about
+	Wkg1     float64
+	Wkg5     float64
+	Wkg20    float64
+	FtpWkg   float64
+	Strength float64 // Average of the 1, 5 and 20 minute w/kg, weighing punch and endurance together
+	Rank     int     // Within the pen, strongest first
+}
+
+// ScoutFixture predicts how strong each team will be in each pen of a fixture. The
+// signups say who is racing in which pen; histories holds each rider's events, from
+// which we take their best power since the given date. Only teams in teamIDs are
+// included, or every team if it's empty, and clubID marks our own. Reports are in
+// category order, strongest team first.
+func ScoutFixture(signups []Event, clubID string, teamIDs []string, histories map[int][]Event, since time.Time) []TeamScout {
+	wanted := make(map[string]bool)
+	for _, id := range teamIDs {
+		wanted[id] = true
+	}
+
+	type key struct{ category, team string }
+	teams := make(map[key]*TeamScout)
+	for _, e := range signups {
+		if e.TeamID == "" || (len(wanted) > 0 && !wanted[e.TeamID] && e.TeamID != clubID) {
+			continue
+		}
+
+		k := key{strings.ToUpper(e.Category), e.TeamID}
+		t, ok := teams[k]
+		if !ok {
+			t = &TeamScout{Category: k.category, TeamID: e.TeamID, Team: html.UnescapeString(e.TeamName), Club: e.TeamID == clubID}
+			teams[k] = t
+		}
+		t.Riders++
+
+		p := NewPowerProfile(e.Zwid, histories[e.Zwid], since)
+		if p.Weight <= 0 || p.Curve[1200] <= 0 {
+			continue
+		}
+		t.Scouted++
+		t.Wkg1 += p.Curve[60] / p.Weight
+		t.Wkg5 += p.Curve[300] / p.Weight
+		t.Wkg20 += p.Curve[1200] / p.Weight
+		t.FtpWkg += p.FTP / p.Weight
+	}
+
+	scouts := make([]TeamScout, 0, len(teams))
+	for _, t := range teams {
+		if n := float64(t.Scouted); n > 0 {
+			t.Wkg1 /= n
+			t.Wkg5 /= n
+			t.Wkg20 /= n
+			t.FtpWkg /= n
+			t.Strength = (t.Wkg1 + t.Wkg5 + t.Wkg20) / 3
+		}
+		scouts = append(scouts, *t)
+	}
+
+	sort.Slice(scouts, func(i, j int) bool {
+		if scouts[i].Category != scouts[j].Category {
+			return scouts[i].Category < scouts[j].Category
+		}
+		if scouts[i].Strength != scouts[j].Strength {
+			return scouts[i].Strength > scouts[j].Strength
+		}
+		return scouts[i].TeamID < scouts[j].TeamID
+	})
+	for i := range scouts {
+		scouts[i].Rank = 1
+		if i > 0 && scouts[i].Category == scouts[i-1].Category {
+			scouts[i].Rank = scouts[i-1].Rank + 1
+		}
+	}
+	return scouts
+}
@@ -0,0 +1,57 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoutFixture(t *testing.T) {
+	now := time.Date(2021, 2, 10, 0, 0, 0, 0, time.UTC)
+	effort := func(zwid int, daysAgo int, weight, w60, w300, w1200 Number) Event {
+		return Event{Zwid: zwid, EventDate: now.AddDate(0, 0, -daysAgo), EventDateSecs: 1, Weight: weight,
+			W60: w60, W300: w300, W1200: w1200, Wftp: w1200 * 0.95}
+	}
+
+	signups := []Event{
+		{Zwid: 1, Category: "A", TeamID: "2672", TeamName: "REVO"},
+		{Zwid: 2, Category: "a", TeamID: "2672", TeamName: "REVO"},
+		{Zwid: 3, Category: "A", TeamID: "99", TeamName: "Rivals &amp; Co"},
+		{Zwid: 4, Category: "B", TeamID: "50", TeamName: "Not playing"},
+		{Zwid: 5, Category: "B", TeamID: "99", TeamName: "Rivals &amp; Co"},
+	}
+	histories := map[int][]Event{
+		1: {effort(1, 10, 60, 360, 270, 240), effort(1, 100, 60, 600, 600, 600)},
+		2: {effort(2, 5, 70, 420, 315, 280)},
+		3: {effort(3, 20, 80, 560, 400, 360)},
+		4: {effort(4, 1, 70, 500, 400, 300)},
+	}
+
+	scouts := ScoutFixture(signups, "2672", []string{"99"}, histories, now.AddDate(0, 0, -60))
+	if len(scouts) != 3 {
+		t.Fatalf("Got %d reports: %+v", len(scouts), scouts)
+	}
+
+	rivals := scouts[0]
+	if rivals.Category != "A" || rivals.Team != "Rivals & Co" || rivals.Rank != 1 || rivals.Club {
+		t.Errorf("Unexpected strongest A team %+v", rivals)
+	}
+	if rivals.Wkg1 != 7 || rivals.Wkg5 != 5 || rivals.Wkg20 != 4.5 || rivals.Strength != 5.5 {
+		t.Errorf("Unexpected w/kg for rivals %+v", rivals)
+	}
+
+	// Both club riders do 6, 4.5 and 4 w/kg. The old effort is outside the window.
+	club := scouts[1]
+	if !club.Club || club.Riders != 2 || club.Scouted != 2 || club.Rank != 2 || club.Wkg1 != 6 || club.Wkg20 != 4 {
+		t.Errorf("Unexpected club report %+v", club)
+	}
+
+	noData := scouts[2]
+	if noData.Category != "B" || noData.Riders != 1 || noData.Scouted != 0 || noData.Strength != 0 || noData.Rank != 1 {
+		t.Errorf("Unexpected report for B %+v", noData)
+	}
+
+	// Without a list of rivals, every team is included
+	if all := ScoutFixture(signups, "2672", nil, histories, now.AddDate(0, 0, -60)); len(all) != 4 {
+		t.Errorf("Got %d reports for all teams", len(all))
+	}
+}
@@ -0,0 +1,140 @@
+package zp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Series is a named race series, recognised from event titles
+type Series struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// KnownSeries lists the series we recognise, in order of precedence, so for example a
+// ZRL team time trial counts as ZRL rather than TTT. Add to this to recognise more.
+var KnownSeries = []Series{
+	{Name: "ZRL", Pattern: regexp.MustCompile(`(?i)zwift racing league|\bzrl\b`)},
+	{Name: "TTT", Pattern: regexp.MustCompile(`(?i)team time trial|\bttt\b`)},
+	{Name: "Crit City", Pattern: regexp.MustCompile(`(?i)crit city`)},
+	{Name: "EVR", Pattern: regexp.MustCompile(`(?i)\bevr\b|everyone virtual racing`)},
+	{Name: "Tour de Zwift", Pattern: regexp.MustCompile(`(?i)tour de zwift`)},
+	{Name: "Tour of Watopia", Pattern: regexp.MustCompile(`(?i)tour of watopia`)},
+	{Name: "DIRT", Pattern: regexp.MustCompile(`(?i)\bdirt\b`)},
+	{Name: "KISS", Pattern: regexp.MustCompile(`(?i)\bkiss\b`)},
+	{Name: "3R", Pattern: regexp.MustCompile(`(?i)\b3r\b`)},
+	{Name: "Herd", Pattern: regexp.MustCompile(`(?i)\bherd\b`)},
+}
+
+// NewSeries makes a series that matches event titles with this regular expression
+func NewSeries(name string, pattern string) (Series, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Series{}, fmt.Errorf("compiling pattern for series %s: %v", name, err)
+	}
+	return Series{Name: name, Pattern: re}, nil
+}
+
+// Series returns the name of the series this event belongs to, or an empty string
+// if it isn't one we recognise
+func (e Event) Series() string {
+	for _, s := range KnownSeries {
+		if s.Pattern.MatchString(e.EventTitle) {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// SeriesStats summarises results in one series
+type SeriesStats struct {
+	Series  string
+	Riders  int // Number of different riders taking part
+	Results int
+	Wins    int
+	Podiums int
+
+	// AvgPosition is the average position in category
+	AvgPosition float64
+	AvgWkg      float64
+	AvgSpeed    float64 // km/h
+}
+
+// SplitBySeries summarises the results in each series since the given date. Events can
+// come from any number of riders. Events not in a known series are ignored.
+func SplitBySeries(events []Event, since time.Time) []SeriesStats {
+	return splitBy(events, since, Event.Series)
+}
+
+// splitBy summarises results since the given date grouped by key, with the key in the
+// Series field. Events with an empty key are ignored.
+func splitBy(events []Event, since time.Time, key func(Event) string) []SeriesStats {
+	type totals struct {
+		SeriesStats
+		riders    map[int]bool
+		positions int
+		placed    int
+		wkg       float64
+		withWkg   int
+		speed     float64
+		withSpeed int
+	}
+
+	bySeries := make(map[string]*totals)
+	for _, e := range events {
+		name := key(e)
+		if name == "" || e.EventDate.Before(since) {
+			continue
+		}
+
+		t, ok := bySeries[name]
+		if !ok {
+			t = &totals{riders: make(map[int]bool)}
+			t.Series = name
+			bySeries[name] = t
+		}
+
+		t.Results++
+		t.riders[e.Zwid] = true
+		if e.PositionInCat > 0 {
+			t.positions += e.PositionInCat
+			t.placed++
+			if e.PositionInCat == 1 {
+				t.Wins++
+			}
+			if e.PositionInCat <= 3 {
+				t.Podiums++
+			}
+		}
+		if wkg := toFloat(e.AvgWkg); wkg > 0 {
+			t.wkg += wkg
+			t.withWkg++
+		}
+		if speed := e.AvgSpeed(); speed > 0 {
+			t.speed += speed
+			t.withSpeed++
+		}
+	}
+
+	var stats []SeriesStats
+	for _, t := range bySeries {
+		t.Riders = len(t.riders)
+		if t.placed > 0 {
+			t.AvgPosition = float64(t.positions) / float64(t.placed)
+		}
+		if t.withWkg > 0 {
+			t.AvgWkg = t.wkg / float64(t.withWkg)
+		}
+		if t.withSpeed > 0 {
+			t.AvgSpeed = t.speed / float64(t.withSpeed)
+		}
+		stats = append(stats, t.SeriesStats)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Series < stats[j].Series
+	})
+	return stats
+}
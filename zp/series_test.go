@@ -0,0 +1,77 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventSeries(t *testing.T) {
+	cases := map[string]string{
+		"Zwift Racing League | WTRL - AMERICAS W (WOMEN) - TTT": "ZRL",
+		"WTRL Team Time Trial - Zone 7":                         "TTT",
+		"Crit City Race":                                        "Crit City",
+		"EVR Winter Tour Stage 3":                               "EVR",
+		"REVO Social SUB2":                                      "",
+		"Kissena Park Crit":                                     "",
+	}
+
+	for title, expected := range cases {
+		e := Event{EventTitle: title}
+		if result := e.Series(); result != expected {
+			t.Errorf("Got %q for %q, expected %q", result, title, expected)
+		}
+	}
+}
+
+func TestSplitBySeries(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	stats := SplitBySeries(events, time.Time{})
+	if len(stats) != 3 {
+		t.Fatalf("Got %d series, expected 3: %v", len(stats), stats)
+	}
+
+	expected := []struct {
+		series  string
+		results int
+	}{
+		{"Crit City", 1},
+		{"TTT", 2},
+		{"ZRL", 9},
+	}
+	for i, e := range expected {
+		if stats[i].Series != e.series || stats[i].Results != e.results || stats[i].Riders != 1 {
+			t.Errorf("Got %v, expected %d results in %s", stats[i], e.results, e.series)
+		}
+	}
+
+	if stats[0].Podiums != 1 || stats[0].AvgPosition != 2 || stats[0].AvgWkg != 2.7 {
+		t.Errorf("Unexpected Crit City stats %v", stats[0])
+	}
+
+	// Only the last ZRL race is after this date
+	stats = SplitBySeries(events, time.Unix(1612000000, 0))
+	if len(stats) != 1 || stats[0].Results != 1 {
+		t.Errorf("Unexpected stats %v", stats)
+	}
+}
+
+func TestNewSeries(t *testing.T) {
+	s, err := NewSeries("REVO", `(?i)^revo`)
+	if err != nil {
+		t.Fatalf("Failed making series: %v", err)
+	}
+	KnownSeries = append(KnownSeries, s)
+	defer func() { KnownSeries = KnownSeries[:len(KnownSeries)-1] }()
+
+	if result := (Event{EventTitle: "REVO Social SUB2"}).Series(); result != "REVO" {
+		t.Errorf("Got %q expected REVO", result)
+	}
+
+	if _, err := NewSeries("Bad", "("); err == nil {
+		t.Errorf("Expected error for bad pattern")
+	}
+}
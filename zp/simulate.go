@@ -0,0 +1,140 @@
+package zp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is a stretch of a route at a steady gradient
+type Segment struct {
+	Distance float64 // km
+	Gradient float64 // Percent, negative downhill
+}
+
+// RouteProfile is a route as a series of segments, in the order they're ridden
+type RouteProfile []Segment
+
+// ParseRouteProfile reads a route profile written as distance:gradient pairs, e.g.
+// "10:0,1.2:7.5,3:-4" for 10km of flat, a 1.2km climb at 7.5% and a 3km descent at 4%.
+// A segment without a gradient is flat.
+func ParseRouteProfile(s string) (RouteProfile, error) {
+	var r RouteProfile
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.SplitN(part, ":", 2)
+		distance, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || distance <= 0 {
+			return nil, fmt.Errorf("segment %q needs a positive distance in km", part)
+		}
+		seg := Segment{Distance: distance}
+		if len(fields) == 2 {
+			seg.Gradient, err = strconv.ParseFloat(strings.TrimSuffix(fields[1], "%"), 64)
+			if err != nil || seg.Gradient < -30 || seg.Gradient > 30 {
+				return nil, fmt.Errorf("segment %q needs a gradient in percent between -30 and 30", part)
+			}
+		}
+		r = append(r, seg)
+	}
+	if len(r) == 0 {
+		return nil, fmt.Errorf("route profile has no segments")
+	}
+	return r, nil
+}
+
+// Profile is the course as a single segment at its average gradient
+func (c Course) Profile() RouteProfile {
+	if c.Distance <= 0 {
+		return nil
+	}
+	return RouteProfile{{Distance: c.Distance, Gradient: c.Elevation / (c.Distance * 10)}}
+}
+
+// Course is the route's distance and the metres climbed on it
+func (r RouteProfile) Course() Course {
+	var c Course
+	for _, seg := range r {
+		c.Distance += seg.Distance
+		if seg.Gradient > 0 {
+			c.Elevation += seg.Distance * seg.Gradient * 10
+		}
+	}
+	return c
+}
+
+// Prediction is how we expect a rider to do on a route
+type Prediction struct {
+	Zwid   int
+	Name   string
+	Time   time.Duration
+	Gap    time.Duration // Behind the fastest rider
+	Power  float64       // The steady power they can hold for Time
+	WKg    float64
+	Splits []time.Duration // Time at the end of each segment
+}
+
+// Ride predicts how this rider would get on riding the route solo, at a steady power
+// they could hold for the whole ride, with each segment at the speed that power gives on
+// its gradient. It's false if we don't have enough data.
+func (p PowerProfile) Ride(r RouteProfile) (Prediction, bool) {
+	if p.Weight <= 0 || len(r) == 0 {
+		return Prediction{}, false
+	}
+	mass := p.Weight + bikeWeight
+
+	// Sustainable power depends on how long the effort is, so iterate until the
+	// estimated time settles down
+	pred := Prediction{Zwid: p.Zwid, Name: p.Name}
+	t := time.Hour
+	for i := 0; i < 20; i++ {
+		power := p.Power(t)
+		if power <= 0 {
+			return Prediction{}, false
+		}
+		var total time.Duration
+		splits := make([]time.Duration, len(r))
+		for j, seg := range r {
+			total += time.Duration(seg.Distance * 1000 / speed(power, mass, seg.Gradient/100) * float64(time.Second))
+			splits[j] = total
+		}
+		pred.Power, pred.Splits = power, splits
+		settled := (total - t).Round(time.Second) == 0
+		t = total
+		if settled {
+			break
+		}
+	}
+
+	pred.Time = t.Round(time.Second)
+	pred.WKg = pred.Power / p.Weight
+	return pred, true
+}
+
+// Simulate predicts the finishing order of the riders on the route, each riding solo at
+// a steady effort, with the gaps between them. It's crude: there's no drafting, no
+// surges, and everyone paces perfectly. Riders without enough data are left out.
+func Simulate(r RouteProfile, riders []PowerProfile) []Prediction {
+	var predictions []Prediction
+	for _, rider := range riders {
+		pred, ok := rider.Ride(r)
+		if !ok {
+			log.Printf("Not enough data to predict a time for %s (%d)", rider.Name, rider.Zwid)
+			continue
+		}
+		predictions = append(predictions, pred)
+	}
+
+	sort.SliceStable(predictions, func(i, j int) bool {
+		return predictions[i].Time < predictions[j].Time
+	})
+	for i := range predictions {
+		predictions[i].Gap = predictions[i].Time - predictions[0].Time
+	}
+	return predictions
+}
@@ -0,0 +1,55 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRouteProfile(t *testing.T) {
+	r, err := ParseRouteProfile("10:0, 1.2:7.5%,3:-4,2")
+	must(t, err)
+	if len(r) != 4 || r[1] != (Segment{Distance: 1.2, Gradient: 7.5}) || r[2].Gradient != -4 || r[3] != (Segment{Distance: 2}) {
+		t.Errorf("Unexpected profile %+v", r)
+	}
+	if c := r.Course(); c.Distance != 16.2 || c.Elevation != 90 {
+		t.Errorf("Unexpected course %+v", c)
+	}
+
+	for _, s := range []string{"", "abc", "-1:0", "5:x", "5:45"} {
+		if _, err := ParseRouteProfile(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	// A heavy rider with more power wins on the flat, and a light one on the climb
+	diesel := PowerProfile{Zwid: 1, Name: "Diesel", Weight: 90, FTP: 320}
+	climber := PowerProfile{Zwid: 2, Name: "Climber", Weight: 58, FTP: 250}
+	riders := []PowerProfile{diesel, climber, {Zwid: 3, Name: "No data"}}
+
+	flat := Simulate(RouteProfile{{Distance: 20}}, riders)
+	if len(flat) != 2 || flat[0].Zwid != 1 || flat[0].Gap != 0 || flat[1].Gap <= 0 {
+		t.Fatalf("Expected the diesel to win on the flat, got %+v", flat)
+	}
+	if flat[1].Gap != flat[1].Time-flat[0].Time || flat[0].Splits[0].Round(time.Second) != flat[0].Time {
+		t.Errorf("Unexpected gaps or splits %+v", flat)
+	}
+
+	hilly := Simulate(RouteProfile{{Distance: 5}, {Distance: 5, Gradient: 8}}, riders)
+	if len(hilly) != 2 || hilly[0].Zwid != 2 {
+		t.Fatalf("Expected the climber to win on the hill, got %+v", hilly)
+	}
+	// The diesel is ahead at the foot of the climb
+	if d, c := hilly[1], hilly[0]; d.Splits[0] >= c.Splits[0] || len(d.Splits) != 2 {
+		t.Errorf("Expected the diesel ahead after the flat, got %v and %v", d.Splits, c.Splits)
+	}
+	if w := hilly[0].WKg; w < 3.5 || w > 5 {
+		t.Errorf("Implausible w/kg %v", w)
+	}
+
+	// A course is a single segment at its average gradient
+	if p := (Course{Distance: 20, Elevation: 200}).Profile(); len(p) != 1 || p[0].Gradient != 1 {
+		t.Errorf("Unexpected profile %+v", p)
+	}
+}
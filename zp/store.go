@@ -0,0 +1,702 @@
+package zp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store keeps riders and their events between runs. FileStore and MemoryStore are
+// provided, and anything else (a database, say) can be plugged in by implementing this.
+//
+// Events are identified by rider and event ID, so storing an event we already have
+// updates it rather than adding a duplicate (see MergeEvents). Lookups of things that
+// aren't stored return an error for which IsNotFound is true.
+type Store interface {
+	PutRider(r Rider) error
+	Rider(riderID int) (Rider, error)
+	QueryRiders(q RiderQuery) ([]Rider, error)
+
+	PutEvents(riderID int, events []Event) error
+	Events(riderID int) ([]Event, error)
+
+	// Update stores everything staged in the transaction, or nothing if fn fails
+	Update(riderID int, fn func(tx *RiderTx) error) error
+
+	// Snapshots record the club's riders as they were at a point in time
+	PutSnapshot(name string, riders []Rider) error
+	Snapshot(name string) ([]Rider, error)
+	Snapshots() ([]string, error)
+
+	// Checkpoints record progress through a long job, so it can be resumed
+	Checkpoint(name string) (map[int]bool, error)
+	SaveCheckpoint(name string, done map[int]bool) error
+	ClearCheckpoint(name string) error
+}
+
+// ErrNotFound is returned for riders, events, clubs and the like that aren't in the store.
+// Other missing files, such as a template, aren't ErrNotFound.
+var ErrNotFound = errors.New("not found")
+
+// IsNotFound is true if the error says that something isn't in the store
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// RiderQuery picks out riders from a store. The zero value matches every rider who is
+// still in the club.
+type RiderQuery struct {
+	Zwids       []int     // Only these riders
+	Category    string    // Only riders whose latest race was in this category
+	Gender      Gender    // Only riders of this gender
+	ActiveSince time.Time // Only riders with an event since this time
+	Archived    Archival  // Whether to include riders who have left the club
+	Limit       int       // At most this many riders, in order of Zwid
+}
+
+// Matches is true if the rider meets all the conditions in the query, apart from Limit
+func (q RiderQuery) Matches(r Rider) bool {
+	if len(q.Zwids) > 0 {
+		found := false
+		for _, id := range q.Zwids {
+			if id == r.Zwid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if q.Category != "" && !strings.EqualFold(q.Category, r.Category) {
+		return false
+	}
+	if q.Gender != UnknownGender && q.Gender != r.Gender {
+		return false
+	}
+	if !q.ActiveSince.IsZero() && r.LatestEventDate.Before(q.ActiveSince) {
+		return false
+	}
+	return q.Archived.matches(r)
+}
+
+// filter applies the query to riders that are already in order of Zwid
+func (q RiderQuery) filter(riders []Rider) []Rider {
+	var matched []Rider
+	for _, r := range riders {
+		if q.Limit > 0 && len(matched) >= q.Limit {
+			break
+		}
+		if q.Matches(r) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+type eventKey struct {
+	zwid int
+	zid  string
+}
+
+// MergeEvents upserts events into those already stored, using the rider and event IDs
+// as the key. Updated events keep their place, and new ones go on the end.
+func MergeEvents(stored []Event, events []Event) []Event {
+	index := make(map[eventKey]int, len(stored)+len(events))
+	var merged []Event
+	for _, e := range append(stored[:len(stored):len(stored)], events...) {
+		k := eventKey{e.Zwid, e.Zid}
+		if i, ok := index[k]; ok {
+			merged[i] = e
+			continue
+		}
+		index[k] = len(merged)
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// RiderTx holds changes to one rider's data, which are only stored if the transaction
+// commits
+type RiderTx struct {
+	riderID int
+	rider   *Rider
+	events  []Event
+}
+
+// NewRiderTx starts a transaction for the rider. It's for Store implementations: users
+// of a store call Update.
+func NewRiderTx(riderID int) *RiderTx {
+	return &RiderTx{riderID: riderID}
+}
+
+// PutRider stages the rider's data
+func (tx *RiderTx) PutRider(r Rider) error {
+	if r.Zwid != tx.riderID {
+		return fmt.Errorf("rider %d doesn't belong in transaction for %d", r.Zwid, tx.riderID)
+	}
+	tx.rider = &r
+	return nil
+}
+
+// PutEvents stages events for the rider, with the same upsert behaviour as
+// Store.PutEvents
+func (tx *RiderTx) PutEvents(events []Event) {
+	tx.events = append(tx.events, events...)
+}
+
+// Staged gets what the transaction will store: the rider (nil if unchanged) and their
+// new events
+func (tx *RiderTx) Staged() (*Rider, []Event) {
+	return tx.rider, tx.events
+}
+
+func checkName(kind string, name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid %s name %q", kind, name)
+	}
+	return nil
+}
+
+// FileStore keeps riders and their events as JSON files in a directory
+type FileStore struct {
+	Dir    string
+	DryRun bool // Log what would be written or removed, without touching the files
+}
+
+// NewFileStore opens a store in this directory, creating it if necessary
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, d := range []string{"riders", "events", "checkpoints", "leagues", "snapshots", "goals", "index", "results", "stints"} {
+		err := os.MkdirAll(filepath.Join(dir, d), 0755)
+		if err != nil {
+			return nil, fmt.Errorf("creating store directory: %v", err)
+		}
+	}
+
+	return &FileStore{Dir: dir}, nil
+}
+
+func (s *FileStore) path(kind string, name string) string {
+	return filepath.Join(s.Dir, kind, name+".json")
+}
+
+// write replaces the file atomically, so a crash part way through leaves either the
+// old contents or the new, never half of each
+func (s *FileStore) write(path string, v interface{}) error {
+	return s.writePerm(path, v, 0644)
+}
+
+// writePrivate is write for a file that only its owner should be able to read, such as
+// one with API keys in it
+func (s *FileStore) writePrivate(path string, v interface{}) error {
+	return s.writePerm(path, v, 0600)
+}
+
+func (s *FileStore) writePerm(path string, v interface{}, perm os.FileMode) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling %s: %v", path, err)
+	}
+	if s.DryRun {
+		log.Printf("Dry run: would write %s (%d bytes)", path, len(data))
+		return nil
+	}
+	return writeFilePerm(path, data, perm)
+}
+
+// remove deletes the file, unless this is a dry run
+func (s *FileStore) remove(path string) error {
+	if s.DryRun {
+		log.Printf("Dry run: would remove %s", path)
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// stage writes v to a temporary file next to path, ready to be renamed into place
+func (s *FileStore) stage(path string, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("marshalling %s: %v", path, err)
+	}
+	return stageFile(path, data, 0644)
+}
+
+// stageFile writes data to a temporary file next to path, ready to be renamed into place.
+// It gets the permissions of the file it will replace, or perm if there isn't one yet,
+// rather than the 0600 that temporary files are created with.
+func stageFile(path string, data []byte, perm os.FileMode) (string, error) {
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writing %s: %v", path, err)
+	}
+
+	return f.Name(), nil
+}
+
+// writeFile replaces the file atomically
+func writeFile(path string, data []byte) error {
+	return writeFilePerm(path, data, 0644)
+}
+
+// writeFilePerm is writeFile, with the permissions for the file if it's new
+func writeFilePerm(path string, data []byte, perm os.FileMode) error {
+	tmp, err := stageFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func (s *FileStore) read(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &notStoredError{err}
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return &ParseError{What: path, Err: err}
+	}
+	return nil
+}
+
+// PutRider saves the rider's data, replacing anything we had before. Riders who have
+// opted out aren't stored.
+func (s *FileStore) PutRider(r Rider) error {
+	if s.optedOut(r.Zwid) {
+		log.Printf("Not storing rider %d, who has opted out", r.Zwid)
+		return nil
+	}
+	return s.write(s.path("riders", strconv.Itoa(r.Zwid)), r)
+}
+
+// Rider gets the stored data for this rider
+func (s *FileStore) Rider(riderID int) (r Rider, err error) {
+	err = s.read(s.path("riders", strconv.Itoa(riderID)), &r)
+	return r, err
+}
+
+// Riders gets all the stored riders, in order of Zwid
+func (s *FileStore) Riders() ([]Rider, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "riders", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var riders []Rider
+	for _, f := range files {
+		var r Rider
+		err = s.read(f, &r)
+		if err != nil {
+			return nil, err
+		}
+		riders = append(riders, r)
+	}
+
+	sort.Slice(riders, func(i, j int) bool {
+		return riders[i].Zwid < riders[j].Zwid
+	})
+	return riders, nil
+}
+
+// QueryRiders gets the stored riders that match the query, in order of Zwid
+func (s *FileStore) QueryRiders(q RiderQuery) ([]Rider, error) {
+	if len(q.Zwids) > 0 {
+		// No need to read every file
+		var riders []Rider
+		for _, id := range q.Zwids {
+			r, err := s.Rider(id)
+			if IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			riders = append(riders, r)
+		}
+		sort.Slice(riders, func(i, j int) bool {
+			return riders[i].Zwid < riders[j].Zwid
+		})
+		return q.filter(riders), nil
+	}
+
+	riders, err := s.Riders()
+	if err != nil {
+		return nil, err
+	}
+	return q.filter(riders), nil
+}
+
+// PutEvents saves the events for this rider. Events are identified by rider and event
+// ID, so any we already had are updated rather than duplicated, and storing the same
+// events again changes nothing.
+func (s *FileStore) PutEvents(riderID int, events []Event) error {
+	if s.optedOut(riderID) {
+		log.Printf("Not storing events for rider %d, who has opted out", riderID)
+		return nil
+	}
+	path := s.path("events", strconv.Itoa(riderID))
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	merged, err := s.mergeEvents(riderID, events)
+	if err != nil {
+		return err
+	}
+	return s.write(path, merged)
+}
+
+func (s *FileStore) mergeEvents(riderID int, events []Event) ([]Event, error) {
+	stored, err := s.Events(riderID)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	return MergeEvents(stored, events), nil
+}
+
+// Events gets the stored events for this rider
+func (s *FileStore) Events(riderID int) (events []Event, err error) {
+	err = s.read(s.path("events", strconv.Itoa(riderID)), &events)
+	return events, err
+}
+
+// Update changes a rider's data as a single transaction. If fn returns an error, or the
+// rider has opted out, nothing is stored. Otherwise the files are written in full
+// before any of them replace what was there, with the rider file last. If we crash
+// between the two, the events are already stored but the rider isn't, and since
+// storing is idempotent, running the same update again finishes the job. Other
+// processes sharing the store wait while the transaction commits.
+func (s *FileStore) Update(riderID int, fn func(tx *RiderTx) error) error {
+	tx := NewRiderTx(riderID)
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+
+	if s.optedOut(riderID) {
+		log.Printf("Not storing rider %d, who has opted out", riderID)
+		return nil
+	}
+	if s.DryRun {
+		rider, events := tx.Staged()
+		log.Printf("Dry run: would store %d events for rider %d, updating the rider: %t", len(events), riderID, rider != nil)
+		return nil
+	}
+
+	unlock, err := lockFile(s.path("events", strconv.Itoa(riderID)))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	type staged struct{ tmp, path string }
+	var files []staged
+	defer func() {
+		for _, f := range files {
+			os.Remove(f.tmp)
+		}
+	}()
+
+	if tx.events != nil {
+		merged, err := s.mergeEvents(riderID, tx.events)
+		if err != nil {
+			return err
+		}
+		path := s.path("events", strconv.Itoa(riderID))
+		tmp, err := s.stage(path, merged)
+		if err != nil {
+			return err
+		}
+		files = append(files, staged{tmp, path})
+	}
+
+	if tx.rider != nil {
+		path := s.path("riders", strconv.Itoa(riderID))
+		tmp, err := s.stage(path, tx.rider)
+		if err != nil {
+			return err
+		}
+		files = append(files, staged{tmp, path})
+	}
+
+	for len(files) > 0 {
+		err = os.Rename(files[0].tmp, files[0].path)
+		if err != nil {
+			return fmt.Errorf("committing %s: %v", files[0].path, err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// PutSnapshot saves the riders as they are now under this name, such as a date
+func (s *FileStore) PutSnapshot(name string, riders []Rider) error {
+	if err := checkName("snapshot", name); err != nil {
+		return err
+	}
+	optOuts, err := s.OptOuts()
+	if err != nil {
+		return err
+	}
+	return s.write(s.path("snapshots", name), optOuts.FilterRiders(riders))
+}
+
+// Snapshot gets the riders saved under this name
+func (s *FileStore) Snapshot(name string) (riders []Rider, err error) {
+	if err := checkName("snapshot", name); err != nil {
+		return nil, err
+	}
+	err = s.read(s.path("snapshots", name), &riders)
+	return riders, err
+}
+
+// Snapshots lists the names of the saved snapshots, in order
+func (s *FileStore) Snapshots() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "snapshots", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(f), ".json")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Checkpoint gets the set of rider IDs recorded as done for the named job
+func (s *FileStore) Checkpoint(name string) (map[int]bool, error) {
+	var ids []int
+	err := s.read(s.path("checkpoints", name), &ids)
+	if IsNotFound(err) {
+		err = nil
+	}
+
+	done := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		done[id] = true
+	}
+	return done, err
+}
+
+// SaveCheckpoint records which rider IDs are done for the named job
+func (s *FileStore) SaveCheckpoint(name string, done map[int]bool) error {
+	ids := make([]int, 0, len(done))
+	for id := range done {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return s.write(s.path("checkpoints", name), ids)
+}
+
+// ClearCheckpoint removes the checkpoint for the named job
+func (s *FileStore) ClearCheckpoint(name string) error {
+	err := s.remove(s.path("checkpoints", name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// PutLeague saves the league's definition, replacing anything we had before
+func (s *FileStore) PutLeague(l *League) error {
+	if err := checkName("league", l.Name); err != nil {
+		return err
+	}
+	return s.write(s.path("leagues", l.Name), l)
+}
+
+// League gets the stored definition of the named league
+func (s *FileStore) League(name string) (*League, error) {
+	if err := checkName("league", name); err != nil {
+		return nil, err
+	}
+
+	var l League
+	err := s.read(s.path("leagues", name), &l)
+	if IsNotFound(err) {
+		return nil, fmt.Errorf("no league called %s", name)
+	}
+	return &l, err
+}
+
+// UpdateLeague loads the named league, changes it and saves it again, with other
+// processes kept waiting in between. fn should be quick, so do any importing first.
+func (s *FileStore) UpdateLeague(name string, fn func(l *League) error) error {
+	if err := checkName("league", name); err != nil {
+		return err
+	}
+	unlock, err := lockFile(s.path("leagues", name))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	l, err := s.League(name)
+	if err != nil {
+		return err
+	}
+
+	err = fn(l)
+	if err != nil {
+		return err
+	}
+	return s.write(s.path("leagues", name), l)
+}
+
+// SetGoal saves the goal, replacing any goal of the same kind the rider already had
+func (s *FileStore) SetGoal(g Goal) error {
+	path := s.path("goals", strconv.Itoa(g.Zwid))
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	goals, err := s.RiderGoals(g.Zwid)
+	if err != nil {
+		return err
+	}
+	return s.write(path, setGoal(goals, g))
+}
+
+// RemoveGoal removes the rider's goal of this kind, if they have one
+func (s *FileStore) RemoveGoal(riderID int, kind string) error {
+	path := s.path("goals", strconv.Itoa(riderID))
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	goals, err := s.RiderGoals(riderID)
+	if err != nil {
+		return err
+	}
+
+	var kept []Goal
+	for _, g := range goals {
+		if g.Kind != kind {
+			kept = append(kept, g)
+		}
+	}
+	if len(kept) == len(goals) {
+		return fmt.Errorf("rider %d has no %s goal", riderID, kind)
+	}
+	if len(kept) == 0 {
+		return s.remove(path)
+	}
+	return s.write(path, kept)
+}
+
+// RiderGoals gets the rider's goals, if they have any
+func (s *FileStore) RiderGoals(riderID int) (goals []Goal, err error) {
+	err = s.read(s.path("goals", strconv.Itoa(riderID)), &goals)
+	if IsNotFound(err) {
+		err = nil
+	}
+	return goals, err
+}
+
+// Goals gets everyone's goals, in order of Zwid
+func (s *FileStore) Goals() ([]Goal, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "goals", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var goals []Goal
+	for _, f := range files {
+		var gg []Goal
+		err = s.read(f, &gg)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, gg...)
+	}
+	sort.SliceStable(goals, func(i, j int) bool {
+		return goals[i].Zwid < goals[j].Zwid
+	})
+	return goals, nil
+}
+
+// NameIndex gets the index of riders' names, from the names added with IndexNames and
+// the riders in the store, whose names take precedence
+func (s *FileStore) NameIndex() (*NameIndex, error) {
+	x := NewNameIndex()
+	err := s.read(s.path("index", "names"), x)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+
+	riders, err := s.Riders()
+	if err != nil {
+		return nil, err
+	}
+	x.AddRiders(riders)
+	return x, nil
+}
+
+// IndexNames adds the names of these riders, and of everyone in these events, to the
+// stored index, so that riders who aren't in the store can be found by name too
+func (s *FileStore) IndexNames(riders []Rider, events []Event) error {
+	path := s.path("index", "names")
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	optOuts, err := s.OptOuts()
+	if err != nil {
+		return err
+	}
+	x := NewNameIndex()
+	err = s.read(path, x)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	x.AddRiders(optOuts.FilterRiders(riders))
+	x.AddEvents(optOuts.FilterEvents(events))
+	return s.write(path, x)
+}
@@ -0,0 +1,440 @@
+package zp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T) *FileStore {
+	dir, err := ioutil.TempDir("", "zpstore")
+	if err != nil {
+		t.Fatalf("Failed making temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("Failed making store: %v", err)
+	}
+	return s
+}
+
+func TestFileStore(t *testing.T) {
+	s := testStore(t)
+
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	err = s.PutEvents(1261784, events)
+	if err != nil {
+		t.Fatalf("Failed storing events: %v", err)
+	}
+
+	stored, err := s.Events(1261784)
+	if err != nil {
+		t.Fatalf("Failed reading events: %v", err)
+	}
+	if len(stored) != len(events) {
+		t.Fatalf("Got %d events, expected %d", len(stored), len(events))
+	}
+	for i := range events {
+		if stored[i].EventTitle != events[i].EventTitle || !stored[i].EventDate.Equal(events[i].EventDate) || stored[i].Wftp != events[i].Wftp {
+			t.Errorf("Event %d didn't round trip: got %v expected %v", i, stored[i], events[i])
+		}
+	}
+
+	// Stats should come out the same from stored events
+	expected := RiderFromEvents(1261784, events).Strings()
+	for i, s := range RiderFromEvents(1261784, stored).Strings() {
+		if s != expected[i] {
+			t.Errorf("Rider stats differ for stored events: got %s expected %s", s, expected[i])
+		}
+	}
+
+	for _, r := range []Rider{{Name: "B", Zwid: 2}, {Name: "A", Zwid: 1}} {
+		err = s.PutRider(r)
+		if err != nil {
+			t.Fatalf("Failed storing rider: %v", err)
+		}
+	}
+
+	riders, err := s.Riders()
+	if err != nil {
+		t.Fatalf("Failed reading riders: %v", err)
+	}
+	if len(riders) != 2 || riders[0].Name != "A" {
+		t.Errorf("Unexpected riders %v", riders)
+	}
+
+	if _, err := s.Events(99); !IsNotFound(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestFileStoreCheckpoint(t *testing.T) {
+	s := testStore(t)
+
+	done, err := s.Checkpoint("job")
+	if err != nil || len(done) != 0 {
+		t.Fatalf("Expected empty checkpoint, got %v, %v", done, err)
+	}
+
+	err = s.SaveCheckpoint("job", map[int]bool{1: true, 2: true})
+	if err != nil {
+		t.Fatalf("Failed saving checkpoint: %v", err)
+	}
+
+	done, err = s.Checkpoint("job")
+	if err != nil || len(done) != 2 || !done[1] || !done[2] {
+		t.Errorf("Unexpected checkpoint %v, %v", done, err)
+	}
+
+	err = s.ClearCheckpoint("job")
+	if err != nil {
+		t.Fatalf("Failed clearing checkpoint: %v", err)
+	}
+
+	done, _ = s.Checkpoint("job")
+	if len(done) != 0 {
+		t.Errorf("Expected checkpoint to be cleared, got %v", done)
+	}
+}
+
+func TestFileStoreUpsertEvents(t *testing.T) {
+	s := testStore(t)
+
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	// Storing the same events twice, or an overlapping set, doesn't duplicate them
+	for _, ee := range [][]Event{events[:10], events, events[5:]} {
+		err = s.PutEvents(1261784, ee)
+		if err != nil {
+			t.Fatalf("Failed storing events: %v", err)
+		}
+	}
+
+	stored, _ := s.Events(1261784)
+	if len(stored) != len(events) {
+		t.Fatalf("Got %d events, expected %d", len(stored), len(events))
+	}
+
+	// An updated event replaces the old one in place
+	updated := events[3]
+	updated.Pos = 1
+	err = s.PutEvents(1261784, []Event{updated})
+	if err != nil {
+		t.Fatalf("Failed storing events: %v", err)
+	}
+	stored, _ = s.Events(1261784)
+	if len(stored) != len(events) || stored[3].Pos != 1 || stored[3].Zid != events[3].Zid {
+		t.Errorf("Event not updated in place: %v", stored[3])
+	}
+
+	// No temporary files left lying around
+	files, _ := filepath.Glob(filepath.Join(s.Dir, "events", ".*"))
+	if len(files) != 0 {
+		t.Errorf("Temporary files left behind: %v", files)
+	}
+}
+
+func TestFileStoreUpdate(t *testing.T) {
+	s := testStore(t)
+
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	// A failed transaction stores nothing
+	err = s.Update(1261784, func(tx *RiderTx) error {
+		tx.PutEvents(events)
+		return fmt.Errorf("import failed")
+	})
+	if err == nil {
+		t.Errorf("Expected error from failed transaction")
+	}
+	if _, err := s.Events(1261784); !IsNotFound(err) {
+		t.Errorf("Events stored by failed transaction: %v", err)
+	}
+
+	err = s.Update(1261784, func(tx *RiderTx) error {
+		return tx.PutRider(Rider{Zwid: 98588})
+	})
+	if err == nil {
+		t.Errorf("Expected error putting the wrong rider")
+	}
+
+	for i := 0; i < 2; i++ {
+		err = s.Update(1261784, func(tx *RiderTx) error {
+			tx.PutEvents(events)
+			return tx.PutRider(RiderFromEvents(1261784, events))
+		})
+		if err != nil {
+			t.Fatalf("Failed transaction: %v", err)
+		}
+	}
+
+	stored, _ := s.Events(1261784)
+	r, err := s.Rider(1261784)
+	if len(stored) != len(events) || err != nil || r.Zwid != 1261784 {
+		t.Errorf("Unexpected result from transaction: %d events, rider %v, %v", len(stored), r, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(s.Dir, "*", ".*"))
+	if len(files) != 0 {
+		t.Errorf("Temporary files left behind: %v", files)
+	}
+}
+
+// checkStore checks the behaviour that every Store implementation should share
+func checkStore(t *testing.T, s Store) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	if _, err := s.Rider(1); !IsNotFound(err) {
+		t.Errorf("Expected not found for missing rider, got %v", err)
+	}
+	if _, err := s.Events(1); !IsNotFound(err) {
+		t.Errorf("Expected not found for missing events, got %v", err)
+	}
+	if _, err := s.Snapshot("missing"); !IsNotFound(err) {
+		t.Errorf("Expected not found for missing snapshot, got %v", err)
+	}
+
+	// Upserts
+	for _, ee := range [][]Event{events[:10], events, events[5:]} {
+		err = s.PutEvents(1261784, ee)
+		if err != nil {
+			t.Fatalf("Failed storing events: %v", err)
+		}
+	}
+	stored, err := s.Events(1261784)
+	if err != nil || len(stored) != len(events) {
+		t.Errorf("Got %d events, %v, expected %d", len(stored), err, len(events))
+	}
+
+	// Transactions
+	err = s.Update(3, func(tx *RiderTx) error {
+		tx.PutEvents([]Event{{Zwid: 3, Zid: "1"}})
+		return fmt.Errorf("failed")
+	})
+	if _, err := s.Events(3); err == nil {
+		t.Errorf("Failed transaction stored events")
+	}
+
+	now := time.Now()
+	riders := []Rider{
+		{Zwid: 3, Name: "C", Category: "B", Gender: Female, LatestEventDate: now},
+		{Zwid: 1, Name: "A", Category: "A", Gender: Male, LatestEventDate: now.AddDate(0, -6, 0)},
+		{Zwid: 2, Name: "B", Category: "B", Gender: Male, LatestEventDate: now},
+	}
+	for _, r := range riders {
+		r := r
+		err = s.Update(r.Zwid, func(tx *RiderTx) error {
+			tx.PutEvents([]Event{{Zwid: r.Zwid, Zid: "1"}})
+			return tx.PutRider(r)
+		})
+		if err != nil {
+			t.Fatalf("Failed transaction: %v", err)
+		}
+	}
+
+	// Queries
+	queries := []struct {
+		q        RiderQuery
+		expected []int
+	}{
+		{RiderQuery{}, []int{1, 2, 3}},
+		{RiderQuery{Limit: 2}, []int{1, 2}},
+		{RiderQuery{Zwids: []int{3, 1, 99}}, []int{1, 3}},
+		{RiderQuery{Category: "b"}, []int{2, 3}},
+		{RiderQuery{Gender: Male}, []int{1, 2}},
+		{RiderQuery{ActiveSince: now.AddDate(0, 0, -7)}, []int{2, 3}},
+		{RiderQuery{Category: "B", Gender: Male}, []int{2}},
+	}
+	for _, c := range queries {
+		rr, err := s.QueryRiders(c.q)
+		if err != nil {
+			t.Errorf("Query %+v failed: %v", c.q, err)
+			continue
+		}
+		var ids []int
+		for _, r := range rr {
+			ids = append(ids, r.Zwid)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(c.expected) {
+			t.Errorf("Query %+v got %v, expected %v", c.q, ids, c.expected)
+		}
+	}
+
+	// Snapshots
+	for _, name := range []string{"2021-02-01", "2021-01-01"} {
+		err = s.PutSnapshot(name, riders)
+		if err != nil {
+			t.Fatalf("Failed saving snapshot: %v", err)
+		}
+	}
+	names, err := s.Snapshots()
+	if err != nil || fmt.Sprint(names) != "[2021-01-01 2021-02-01]" {
+		t.Errorf("Got snapshots %v, %v", names, err)
+	}
+	snap, err := s.Snapshot("2021-01-01")
+	if err != nil || len(snap) != 3 || snap[0].Name != "C" {
+		t.Errorf("Got snapshot %v, %v", snap, err)
+	}
+	if err := s.PutSnapshot("../bad", riders); err == nil {
+		t.Errorf("Expected error for bad snapshot name")
+	}
+
+	// Checkpoints
+	err = s.SaveCheckpoint("job", map[int]bool{1: true, 2: true})
+	if err != nil {
+		t.Fatalf("Failed saving checkpoint: %v", err)
+	}
+	done, err := s.Checkpoint("job")
+	if err != nil || len(done) != 2 || !done[2] {
+		t.Errorf("Got checkpoint %v, %v", done, err)
+	}
+	err = s.ClearCheckpoint("job")
+	if done, _ := s.Checkpoint("job"); err != nil || len(done) != 0 {
+		t.Errorf("Checkpoint not cleared: %v, %v", done, err)
+	}
+}
+
+func TestFileStoreBehaviour(t *testing.T) {
+	checkStore(t, testStore(t))
+}
+
+func TestFileStoreGoals(t *testing.T) {
+	s := testStore(t)
+
+	goals, err := s.Goals()
+	if err != nil || len(goals) != 0 {
+		t.Fatalf("Expected no goals, got %v, %v", goals, err)
+	}
+
+	for _, g := range []Goal{
+		{Zwid: 2, Kind: GoalRaces, Target: 8},
+		{Zwid: 1, Kind: GoalWkg, Target: 3.5},
+		{Zwid: 1, Kind: GoalRaces, Target: 4},
+		{Zwid: 1, Kind: GoalWkg, Target: 4},
+	} {
+		if err := s.SetGoal(g); err != nil {
+			t.Fatalf("Failed setting goal: %v", err)
+		}
+	}
+
+	goals, err = s.Goals()
+	if err != nil || len(goals) != 3 || goals[0].Zwid != 1 || goals[0].Target != 4 {
+		t.Fatalf("Unexpected goals %v, %v", goals, err)
+	}
+
+	if err := s.RemoveGoal(1, GoalFtp); err == nil {
+		t.Errorf("Expected error removing a goal that isn't set")
+	}
+	if err := s.RemoveGoal(2, GoalRaces); err != nil {
+		t.Errorf("Failed removing goal: %v", err)
+	}
+	goals, err = s.RiderGoals(2)
+	if err != nil || len(goals) != 0 {
+		t.Errorf("Expected no goals for rider 2, got %v, %v", goals, err)
+	}
+}
+
+func TestFileStoreDryRun(t *testing.T) {
+	s := testStore(t)
+	err := s.PutRider(Rider{Zwid: 1, Name: "Stored"})
+	if err != nil {
+		t.Fatalf("Failed storing rider: %v", err)
+	}
+	err = s.SaveCheckpoint("job", map[int]bool{1: true})
+	if err != nil {
+		t.Fatalf("Failed saving checkpoint: %v", err)
+	}
+
+	s.DryRun = true
+	if err := s.PutRider(Rider{Zwid: 1, Name: "Changed"}); err != nil {
+		t.Errorf("Dry run PutRider failed: %v", err)
+	}
+	if err := s.PutEvents(2, []Event{{Zid: "1", Zwid: 2}}); err != nil {
+		t.Errorf("Dry run PutEvents failed: %v", err)
+	}
+	err = s.Update(3, func(tx *RiderTx) error {
+		tx.PutEvents([]Event{{Zid: "1", Zwid: 3}})
+		return tx.PutRider(Rider{Zwid: 3})
+	})
+	if err != nil {
+		t.Errorf("Dry run Update failed: %v", err)
+	}
+	if err := s.ClearCheckpoint("job"); err != nil {
+		t.Errorf("Dry run ClearCheckpoint failed: %v", err)
+	}
+
+	if r, _ := s.Rider(1); r.Name != "Stored" {
+		t.Errorf("Dry run changed the rider to %q", r.Name)
+	}
+	for _, id := range []int{2, 3} {
+		if _, err := s.Events(id); !IsNotFound(err) {
+			t.Errorf("Dry run stored events for %d: %v", id, err)
+		}
+	}
+	if _, err := s.Rider(3); !IsNotFound(err) {
+		t.Errorf("Dry run stored rider 3: %v", err)
+	}
+	if done, _ := s.Checkpoint("job"); !done[1] {
+		t.Errorf("Dry run cleared the checkpoint")
+	}
+}
+
+func TestFileStorePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	s := testStore(t)
+	mode := func(path string) os.FileMode {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", path, err)
+		}
+		return info.Mode().Perm()
+	}
+
+	must(t, s.PutRider(Rider{Zwid: 1, Name: "New"}))
+	path := s.path("riders", "1")
+	if m := mode(path); m != 0644 {
+		t.Errorf("New rider file has mode %v, want 0644", m)
+	}
+
+	must(t, os.Chmod(path, 0640))
+	must(t, s.PutRider(Rider{Zwid: 1, Name: "Replaced"}))
+	if m := mode(path); m != 0640 {
+		t.Errorf("Replaced rider file has mode %v, want 0640", m)
+	}
+
+	must(t, s.Update(2, func(tx *RiderTx) error {
+		tx.PutEvents([]Event{{Zid: "1", Zwid: 2}})
+		return tx.PutRider(Rider{Zwid: 2})
+	}))
+	for _, kind := range []string{"riders", "events"} {
+		if m := mode(s.path(kind, "2")); m != 0644 {
+			t.Errorf("Updated %s file has mode %v, want 0644", kind, m)
+		}
+	}
+
+	must(t, s.ConnectTraining(TrainingConnection{Zwid: 2, Service: TrainingIntervals, Athlete: "i2", Key: "a"}))
+	if m := mode(s.trainingPath()); m != 0600 {
+		t.Errorf("Training connections have mode %v, want 0600", m)
+	}
+}
@@ -0,0 +1,244 @@
+package zp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readers are reused from one request to the next, so that importing a whole club
+// doesn't allocate a fresh buffer for every rider
+var readers = sync.Pool{
+	New: func() interface{} { return bufio.NewReaderSize(nil, 32*1024) },
+}
+
+// jsonBody reads a response body through a pooled buffer
+type jsonBody struct {
+	*bufio.Reader
+	body     io.ReadCloser
+	modified time.Time
+}
+
+func (b *jsonBody) Close() error {
+	err := b.body.Close()
+	b.Reader.Reset(nil)
+	readers.Put(b.Reader)
+	return err
+}
+
+// openJSON is like getJSON, but leaves the caller to read the response body as it
+// arrives rather than holding all of it in memory
+func openJSON(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, url); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	r := readers.Get().(*bufio.Reader)
+	r.Reset(resp.Body)
+	return &jsonBody{Reader: r, body: resp.Body, modified: lastModified(resp.Header)}, nil
+}
+
+// decodeEvents reads the events in a ZwiftPower {"data": [...]} document one at a time,
+// calling fn for each. The Event passed to fn is reused for the next one, so fn must
+// copy it if it wants to keep it.
+func decodeEvents(r io.Reader, fn func(*Event) error) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	var e Event
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if key, _ := tok.(string); key != "data" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok == nil {
+			// "data": null means no events
+			continue
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return fmt.Errorf("%w: expected an array of events, got %v", errLayout, tok)
+		}
+
+		for dec.More() {
+			e = Event{}
+			if err := dec.Decode(&e); err != nil {
+				return err
+			}
+			e.EventDate = time.Unix(int64(e.EventDateSecs), 0)
+			if err := fn(&e); err != nil {
+				return err
+			}
+		}
+
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("%w: expected %v, got %v", errLayout, delim, tok)
+	}
+	return nil
+}
+
+// errLayout is valid JSON that isn't laid out the way decodeEvents expects
+var errLayout = errors.New("unexpected layout")
+
+// isJSONError is true if decodeEvents failed because of what was in the document, rather
+// than because reading it failed
+func isJSONError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, errLayout)
+}
+
+// eventsError says why decodeEvents failed on what. An error from fn comes back as it is,
+// and only a document we couldn't make sense of is a ParseError.
+func eventsError(what string, err error, fnErr error) error {
+	switch {
+	case err == nil || err == fnErr:
+		return err
+	case isJSONError(err):
+		return &ParseError{What: what, Err: err}
+	default:
+		// Most likely the connection dropped or ctx was cancelled part way through
+		return fmt.Errorf("reading %s: %w", what, err)
+	}
+}
+
+// riderStats works out a rider's stats one event at a time, so the events themselves
+// don't need to be kept
+type riderStats struct {
+	rider           Rider
+	now             time.Time
+	latestEventDate time.Time
+	latestRaceDate  time.Time
+	eventTimes      []time.Time // When the events in the last year were, to match activities with
+
+	ftp30, ftp60, ftp90 []float64 // FTP estimates in each window, for aggregation
+	aggregation         FtpAggregations
+}
+
+func newRiderStats(riderID int, now time.Time, aggregation FtpAggregations) *riderStats {
+	return &riderStats{rider: Rider{Zwid: riderID}, now: now, aggregation: aggregation}
+}
+
+func (s *riderStats) add(e *Event) {
+	if e.EventDate.After(s.now) {
+		// Not ridden yet, as far as these stats are concerned
+		return
+	}
+	if e.Provenance.AsOf().After(s.rider.Provenance.AsOf()) {
+		s.rider.Provenance = e.Provenance
+	}
+
+	rider := &s.rider
+	daysAgo := int(s.now.Sub(e.EventDate).Hours() / 24)
+	isRace := e.IsRace()
+
+	if daysAgo <= 365 {
+		s.eventTimes = append(s.eventTimes, e.EventDate)
+		rider.Rides++
+		if isRace {
+			rider.Races++
+		}
+	}
+
+	wkgFtp := toFloat(e.WkgFtp)
+	avgWkg := toFloat(e.AvgWkg)
+
+	// Last three months?
+	if daysAgo <= 90 {
+		if wkgFtp > 0 {
+			s.ftp90 = append(s.ftp90, wkgFtp)
+		}
+
+		if isRace {
+			rider.Races90++
+		}
+	}
+
+	// Last two months?
+	if daysAgo <= 60 && wkgFtp > 0 {
+		s.ftp60 = append(s.ftp60, wkgFtp)
+	}
+
+	// Last month?
+	if daysAgo <= 30 {
+		if isRace {
+			rider.Races30++
+		}
+
+		if wkgFtp > 0 {
+			s.ftp30 = append(s.ftp30, wkgFtp)
+		}
+	}
+
+	if e.EventDate.After(s.latestEventDate) {
+		s.latestEventDate = e.EventDate
+		rider.LatestEvent = e.EventTitle
+		if e.Age != "" {
+			rider.Age = e.Age
+		}
+		if e.Gender != UnknownGender {
+			rider.Gender = e.Gender
+		}
+	}
+
+	if isRace && e.EventDate.After(s.latestRaceDate) {
+		s.latestRaceDate = e.EventDate
+		rider.LatestRace = e.EventTitle
+		rider.Category = e.Category
+		rider.LatestRaceAvgWkg = avgWkg
+		rider.LatestRaceWkgFtp = wkgFtp
+	}
+}
+
+func (s *riderStats) result() Rider {
+	s.rider.Ftp30 = s.aggregation.Ftp30.Apply(s.ftp30)
+	s.rider.Ftp60 = s.aggregation.Ftp60.Apply(s.ftp60)
+	s.rider.Ftp90 = s.aggregation.Ftp90.Apply(s.ftp90)
+	s.rider.LatestEventDate = s.latestEventDate
+	s.rider.LatestRaceDate = s.latestRaceDate
+	return s.rider
+}
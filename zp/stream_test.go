@@ -0,0 +1,165 @@
+package zp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDecodeEvents(t *testing.T) {
+	expected, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	var events []Event
+	err = decodeEvents(strings.NewReader(testdata), func(e *Event) error {
+		events = append(events, *e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed decoding events: %v", err)
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Streamed events don't match parsed events")
+	}
+
+	cases := map[string]int{
+		`{"data":null}`: 0,
+		`{"other":{"data":[1,2]},"data":[{"zid":"1","age":"Vet"},{"zid":"2"}],"more":[]}`: 2,
+	}
+	for doc, count := range cases {
+		var zids []string
+		err = decodeEvents(strings.NewReader(doc), func(e *Event) error {
+			zids = append(zids, e.Zid)
+			if e.Zid == "2" && e.Age != "" {
+				t.Errorf("Age from the previous event leaked into %s", e.Zid)
+			}
+			return nil
+		})
+		if err != nil || len(zids) != count {
+			t.Errorf("Got %v, %v for %s", zids, err, doc)
+		}
+	}
+
+	for _, doc := range []string{`[]`, `{"data":{}}`, `{"data":[{"zid":"1"}`} {
+		err = decodeEvents(strings.NewReader(doc), func(e *Event) error { return nil })
+		if err == nil {
+			t.Errorf("Expected error for %s", doc)
+		}
+	}
+}
+
+func TestImportRiderStreaming(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testdata)
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+	expected := RiderFromEvents(1261784, events)
+
+	// Run it more than once, so pooled readers get reused
+	for i := 0; i < 3; i++ {
+		rider, err := ImportRider(client, 1261784)
+		if err != nil {
+			t.Fatalf("Failed importing rider: %v", err)
+		}
+		if rider.Provenance == nil || rider.Provenance.Source != SourceMirror {
+			t.Errorf("Expected the test server as a mirror, got %+v", rider.Provenance)
+		}
+		rider.Provenance = nil
+		if !reflect.DeepEqual(rider, expected) {
+			t.Errorf("Got %+v, expected %+v", rider, expected)
+		}
+	}
+}
+
+func TestAPI3StreamNeedsJSON(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("z") == "1" {
+			fmt.Fprint(w, "  <html>Please log in</html>")
+			return
+		}
+		fmt.Fprint(w, "\n  "+testdata)
+	})
+
+	DefaultBackend = API3
+	defer func() { DefaultBackend = Cache3 }()
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ImportEvents(client, 1)
+	if err == nil || !strings.Contains(err.Error(), "logged in") {
+		t.Errorf("Expected login error, got %v", err)
+	}
+
+	events, err := ImportEvents(client, 1261784)
+	if err != nil || len(events) != 14 {
+		t.Errorf("Got %d events, %v", len(events), err)
+	}
+}
+
+func TestStreamEventsErrors(t *testing.T) {
+	var doc string
+	var dropped bool
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if dropped {
+			// Promise more than we send, as if the connection dropped
+			w.Header().Set("Content-Length", strconv.Itoa(len(doc)+100))
+		}
+		fmt.Fprint(w, doc)
+	})
+
+	httpClient, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := Wrap(httpClient)
+	ignore := func(e *Event) error { return nil }
+
+	for _, doc = range []string{`{"data":[{"zid":1}]}`, `{"data":{}}`, `{"data":[nonsense]}`} {
+		err = client.streamEvents(context.Background(), 1, ignore)
+		if !errors.Is(err, ErrParse) {
+			t.Errorf("Expected a parse error for %s, got %v", doc, err)
+		}
+	}
+
+	// A connection that drops part way through is a failed read, which is worth retrying
+	doc, dropped = `{"data":[{"zid":"1"}`, true
+	err = client.streamEvents(context.Background(), 1, ignore)
+	if err == nil || errors.Is(err, ErrParse) || !retryable(err) {
+		t.Errorf("Expected a retryable error for a dropped connection, got %v", err)
+	}
+
+	doc, dropped = testdata, false
+	errStop := errors.New("stop")
+	err = client.streamEvents(context.Background(), 1, func(e *Event) error { return errStop })
+	if err != errStop {
+		t.Errorf("Expected the error from fn, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = client.streamEvents(ctx, 1, func(e *Event) error {
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrParse) {
+		t.Errorf("Expected cancellation, got %v", err)
+	}
+}
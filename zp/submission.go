@@ -0,0 +1,64 @@
+package zp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Submission is one rider's result in the layout that community league organizers
+// usually ask for when clubs submit their results
+type Submission struct {
+	Position int    `col:"Position,order=1"` // In category
+	Zwid     int    `col:"Zwift ID,order=2"`
+	Name     string `col:"Name,order=3"`
+	Category string `col:"Category,order=4"`
+	Time     string `col:"Time,order=5"` // h:mm:ss.sss
+	AvgPower int    `col:"Avg Power,order=6,key=power"`
+	AvgHR    int    `col:"Avg HR,order=7,key=hr"`
+}
+
+// SubmissionColumns describes the columns of a results submission
+var SubmissionColumns = TagColumns(Submission{})
+
+// Strings turns a submission into the columns described by SubmissionColumns
+func (s Submission) Strings() []string {
+	return TagStrings(s)
+}
+
+// NewSubmission gets the results of an event ready to submit to the league, sorted by
+// category and then position. Only the club's riders are included, unless clubID is
+// empty. Riders without a finishing time are left out.
+func NewSubmission(results []Event, clubID string) []Submission {
+	var s []Submission
+	for _, e := range results {
+		if (clubID != "" && e.TeamID != clubID) || e.Time <= 0 {
+			continue
+		}
+
+		s = append(s, Submission{
+			Position: e.PositionInCat,
+			Zwid:     e.Zwid,
+			Name:     e.RiderName(),
+			Category: strings.ToUpper(e.Category),
+			Time:     formatRaceTime(float64(e.Time)),
+			AvgPower: int(math.Round(float64(e.AvgPower))),
+			AvgHR:    int(math.Round(float64(e.AvgHR))),
+		})
+	}
+
+	sort.SliceStable(s, func(i, j int) bool {
+		if s[i].Category != s[j].Category {
+			return s[i].Category < s[j].Category
+		}
+		return s[i].Position < s[j].Position
+	})
+	return s
+}
+
+// formatRaceTime writes a time in seconds as h:mm:ss.sss
+func formatRaceTime(secs float64) string {
+	ms := int64(math.Round(secs * 1000))
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
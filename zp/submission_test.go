@@ -0,0 +1,59 @@
+package zp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSubmission(t *testing.T) {
+	results := []Event{
+		{Zwid: 1, Name: "Ann &amp; Co", Category: "b", PositionInCat: 2, TeamID: "2672", Time: 3723.4567, AvgPower: 201.6, AvgHR: 150},
+		{Zwid: 2, Name: "Bob", Category: "A", PositionInCat: 5, TeamID: "2672", Time: 3000, AvgPower: 300},
+		{Zwid: 3, Name: "Cat", Category: "B", PositionInCat: 1, TeamID: "2672", Time: 3700, AvgPower: 210, AvgHR: 160},
+		{Zwid: 4, Name: "Dan", Category: "B", PositionInCat: 3, TeamID: "999", Time: 3730},
+		{Zwid: 5, Name: "Eve", Category: "B", PositionInCat: 0, TeamID: "2672"}, // DNF
+	}
+
+	s := NewSubmission(results, "2672")
+	var zwids []int
+	for _, r := range s {
+		zwids = append(zwids, r.Zwid)
+	}
+	if !reflect.DeepEqual(zwids, []int{2, 3, 1}) {
+		t.Errorf("Got riders %v, expected 2, 3, 1", zwids)
+	}
+
+	expected := []string{"2", "1", "Ann & Co", "B", "1:02:03.457", "202", "150"}
+	if got := s[2].Strings(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+
+	if len(NewSubmission(results, "")) != 4 {
+		t.Errorf("Expected every finisher without a club")
+	}
+}
+
+func TestSubmissionColumns(t *testing.T) {
+	var keys []string
+	for _, c := range SubmissionColumns {
+		keys = append(keys, c.Key)
+	}
+	expected := []string{"position", "zwid", "name", "category", "time", "power", "hr"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Got keys %v, expected %v", keys, expected)
+	}
+}
+
+func TestFormatRaceTime(t *testing.T) {
+	cases := map[float64]string{
+		0:        "0:00:00.000",
+		59.9996:  "0:01:00.000",
+		2955.764: "0:49:15.764",
+		36000.5:  "10:00:00.500",
+	}
+	for secs, expected := range cases {
+		if got := formatRaceTime(secs); got != expected {
+			t.Errorf("Got %s for %v, expected %s", got, secs, expected)
+		}
+	}
+}
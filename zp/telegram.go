@@ -0,0 +1,263 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramURL is where the Telegram Bot API lives
+var TelegramURL = "https://api.telegram.org"
+
+// telegramLimit is the most characters Telegram accepts in one message
+const telegramLimit = 4096
+
+// TelegramNotifier posts notifications to a Telegram chat, group or channel as a bot.
+// The bot needs to be a member of the chat, and an admin to post in a channel.
+type TelegramNotifier struct {
+	Token  string // From @BotFather
+	ChatID string // The chat's numeric ID, or @name for a public channel
+	Client *http.Client
+}
+
+// Notify posts the text, in as many messages as it needs
+func (t TelegramNotifier) Notify(ctx context.Context, n Notification) error {
+	for _, chunk := range splitText(n.Text, telegramLimit) {
+		err := telegramCall(ctx, t.Client, t.Token, "sendMessage", map[string]interface{}{
+			"chat_id":                  t.ChatID,
+			"text":                     chunk,
+			"disable_web_page_preview": true,
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TelegramBot answers commands sent to a Telegram bot:
+//
+//	/rider <name or Zwift ID>   a summary of the rider's stats
+//
+// It also answers inline queries, so typing "@yourbot name" in any chat offers the
+// summaries of the riders it matches. Inline mode has to be turned on with @BotFather.
+type TelegramBot struct {
+	Token  string
+	Client *http.Client
+
+	// Lookup finds the riders a name or ID could mean, best match first
+	Lookup func(ctx context.Context, query string) ([]Rider, error)
+
+	// MaxRiders is how many matches to answer with, defaulting to 3
+	MaxRiders int
+}
+
+// telegramPoll is how long each request for updates waits for something to happen
+const telegramPoll = 30 * time.Second
+
+type telegramUpdate struct {
+	UpdateID int `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+	InlineQuery *struct {
+		ID    string `json:"id"`
+		Query string `json:"query"`
+	} `json:"inline_query"`
+}
+
+// Run polls Telegram for messages to the bot and answers them until the context is done.
+// Failures to answer are logged rather than stopping the bot.
+func (b *TelegramBot) Run(ctx context.Context) error {
+	offset := 0
+	for {
+		var updates []telegramUpdate
+		err := telegramCall(ctx, b.Client, b.Token, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         int(telegramPoll.Seconds()),
+			"allowed_updates": []string{"message", "inline_query"},
+		}, &updates)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("Getting Telegram updates: %v", err)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if err := b.handle(ctx, u); err != nil {
+				log.Printf("Answering Telegram update %d: %v", u.UpdateID, err)
+			}
+		}
+	}
+}
+
+func (b *TelegramBot) handle(ctx context.Context, u telegramUpdate) error {
+	switch {
+	case u.Message != nil:
+		command, query := parseTelegramCommand(u.Message.Text)
+		if command != "rider" {
+			return nil
+		}
+
+		text := "Usage: /rider <name or Zwift ID>"
+		if query != "" {
+			riders, err := b.lookup(ctx, query)
+			switch {
+			case err != nil:
+				text = fmt.Sprintf("Sorry, couldn't look up %q: %v", query, err)
+			case len(riders) == 0:
+				text = fmt.Sprintf("No riders called %q", query)
+			default:
+				var summaries []string
+				for _, r := range riders {
+					summaries = append(summaries, RiderSummary(r))
+				}
+				text = strings.Join(summaries, "\n\n")
+			}
+		}
+		return telegramCall(ctx, b.Client, b.Token, "sendMessage", map[string]interface{}{
+			"chat_id":                  u.Message.Chat.ID,
+			"text":                     text,
+			"disable_web_page_preview": true,
+		}, nil)
+
+	case u.InlineQuery != nil:
+		results := []map[string]interface{}{}
+		if query := strings.TrimSpace(u.InlineQuery.Query); query != "" {
+			riders, err := b.lookup(ctx, query)
+			if err != nil {
+				return err
+			}
+			for _, r := range riders {
+				card := RiderCard(r)
+				results = append(results, map[string]interface{}{
+					"type":                  "article",
+					"id":                    strconv.Itoa(r.Zwid),
+					"title":                 card.Title,
+					"description":           card.Description,
+					"input_message_content": map[string]interface{}{"message_text": RiderSummary(r), "disable_web_page_preview": true},
+				})
+			}
+		}
+		return telegramCall(ctx, b.Client, b.Token, "answerInlineQuery", map[string]interface{}{
+			"inline_query_id": u.InlineQuery.ID,
+			"results":         results,
+			"cache_time":      300,
+		}, nil)
+	}
+	return nil
+}
+
+func (b *TelegramBot) lookup(ctx context.Context, query string) ([]Rider, error) {
+	riders, err := b.Lookup(ctx, query)
+	max := b.MaxRiders
+	if max <= 0 {
+		max = 3
+	}
+	if len(riders) > max {
+		riders = riders[:max]
+	}
+	return riders, err
+}
+
+// parseTelegramCommand splits "/rider@yourbot Jane Smith" into "rider" and "Jane Smith"
+func parseTelegramCommand(text string) (command string, args string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+	parts := strings.SplitN(text[1:], " ", 2)
+	command = strings.ToLower(strings.SplitN(parts[0], "@", 2)[0])
+	if len(parts) > 1 {
+		args = strings.TrimSpace(parts[1])
+	}
+	return command, args
+}
+
+// RiderSummary describes a rider's stats in a few lines of plain text for chat messages
+func RiderSummary(r Rider) string {
+	c := RiderCard(r)
+	return fmt.Sprintf("%s\n%s\nhttps://www.zwiftpower.com/profile.php?z=%d", c.Title, c.Description, r.Zwid)
+}
+
+// telegramCall calls a Bot API method, decoding its result into result if that's not
+// nil. The token is part of the URL, so errors don't include it.
+func telegramCall(ctx context.Context, client *http.Client, token string, method string, params interface{}, result interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/%s", TelegramURL, token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("making Telegram %s request: %v", method, redact(err, token, "<token>"))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling Telegram %s: %v", method, redact(err, token, "<token>"))
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading Telegram %s response: %v", method, err)
+	}
+
+	var reply struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+		ErrorCode   int             `json:"error_code"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	err = json.Unmarshal(data, &reply)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return &ParseError{What: "Telegram " + method + " response", Err: err}
+	}
+	if !reply.OK {
+		code := reply.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		log.Printf("Telegram %s failed: %s", method, reply.Description)
+		return &StatusError{
+			URL:        "Telegram " + method,
+			StatusCode: code,
+			RetryAfter: time.Duration(reply.Parameters.RetryAfter) * time.Second,
+		}
+	}
+
+	if result != nil {
+		err = json.Unmarshal(reply.Result, result)
+		if err != nil {
+			return &ParseError{What: "Telegram " + method + " result", Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withTelegram points the Telegram API at a test server that answers each method with
+// the handler's result, recording the parameters it was called with
+func withTelegram(t *testing.T, handler func(method string, params map[string]interface{}) interface{}) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		if len(parts) != 3 || parts[1] != "bots3cret" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		var params map[string]interface{}
+		json.Unmarshal(data, &params)
+
+		result := handler(parts[2], params)
+		if err, ok := result.(error); ok {
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error_code": 429, "description": err.Error(), "parameters": map[string]int{"retry_after": 7}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+	}))
+	old := TelegramURL
+	TelegramURL = ts.URL
+	t.Cleanup(func() {
+		TelegramURL = old
+		ts.Close()
+	})
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	var sent []map[string]interface{}
+	withTelegram(t, func(method string, params map[string]interface{}) interface{} {
+		if method != "sendMessage" {
+			t.Errorf("Unexpected method %s", method)
+		}
+		sent = append(sent, params)
+		return map[string]int{"message_id": len(sent)}
+	})
+
+	n := TelegramNotifier{Token: "s3cret", ChatID: "@revo"}
+	err := n.Notify(context.Background(), Notification{Kind: NotifyDigest, Text: "Well ridden"})
+	if err != nil || len(sent) != 1 || sent[0]["chat_id"] != "@revo" || sent[0]["text"] != "Well ridden" {
+		t.Errorf("Got %v, sent %v", err, sent)
+	}
+}
+
+func TestTelegramError(t *testing.T) {
+	withTelegram(t, func(method string, params map[string]interface{}) interface{} {
+		return errors.New("Too Many Requests: retry after 7")
+	})
+
+	err := TelegramNotifier{Token: "s3cret", ChatID: "1"}.Notify(context.Background(), Notification{Text: "hi"})
+	var status *StatusError
+	if !errors.As(err, &status) || status.RetryAfter != 7*time.Second || !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected to be told to wait, got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Error gives away the token: %v", err)
+	}
+}
+
+func TestTelegramBot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	polls := 0
+	var answers []map[string]interface{}
+	withTelegram(t, func(method string, params map[string]interface{}) interface{} {
+		switch method {
+		case "getUpdates":
+			polls++
+			if polls > 1 {
+				if params["offset"] != float64(12) {
+					t.Errorf("Expected to ask for updates after the last, got %v", params["offset"])
+				}
+				cancel()
+				return []interface{}{}
+			}
+			return []interface{}{
+				map[string]interface{}{"update_id": 10, "message": map[string]interface{}{"chat": map[string]int{"id": 99}, "text": "/rider@zpbot liz"}},
+				map[string]interface{}{"update_id": 11, "inline_query": map[string]string{"id": "q1", "query": "liz"}},
+				map[string]interface{}{"update_id": 11, "message": map[string]interface{}{"chat": map[string]int{"id": 99}, "text": "just chatting"}},
+			}
+		default:
+			answers = append(answers, map[string]interface{}{"method": method, "params": params})
+			return true
+		}
+	})
+
+	bot := TelegramBot{Token: "s3cret", Lookup: func(ctx context.Context, query string) ([]Rider, error) {
+		if query != "liz" {
+			t.Errorf("Unexpected query %q", query)
+		}
+		return []Rider{{Zwid: 1, Name: "Liz", Category: "B", Races30: 3}}, nil
+	}}
+	err := bot.Run(ctx)
+	if err != context.Canceled {
+		t.Errorf("Expected the bot to stop when cancelled, got %v", err)
+	}
+
+	if len(answers) != 2 {
+		t.Fatalf("Expected 2 answers, got %v", answers)
+	}
+	message := answers[0]["params"].(map[string]interface{})
+	if answers[0]["method"] != "sendMessage" || message["chat_id"] != float64(99) || !strings.HasPrefix(message["text"].(string), "Liz\nCategory B") {
+		t.Errorf("Unexpected reply %v", answers[0])
+	}
+	inline := answers[1]["params"].(map[string]interface{})
+	results := inline["results"].([]interface{})
+	if answers[1]["method"] != "answerInlineQuery" || inline["inline_query_id"] != "q1" || len(results) != 1 {
+		t.Errorf("Unexpected inline answer %v", answers[1])
+	}
+}
+
+func TestParseTelegramCommand(t *testing.T) {
+	cases := map[string][2]string{
+		"/rider Jane Smith":       {"rider", "Jane Smith"},
+		"/Rider@zpbot  1261784 ":  {"rider", "1261784"},
+		"/rider":                  {"rider", ""},
+		"hello /rider Jane Smith": {"", ""},
+		"":                        {"", ""},
+	}
+	for text, expected := range cases {
+		command, args := parseTelegramCommand(text)
+		if command != expected[0] || args != expected[1] {
+			t.Errorf("%q: got %q %q", text, command, args)
+		}
+	}
+}
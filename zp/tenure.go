@@ -0,0 +1,224 @@
+package zp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Stint is a rider's time in the club, from when they first appeared on the
+// roster to when they were found to have left. A rider who rejoins starts a new stint.
+type Stint struct {
+	Zwid   int
+	Name   string
+	Joined time.Time
+	Left   *time.Time `json:",omitempty"`
+	Before bool       `json:",omitempty"` // Already in the club when tracking started, so they joined on or before Joined
+}
+
+// Current is true if the rider is still in the club
+func (m Stint) Current() bool {
+	return m.Left == nil
+}
+
+// Tenure is how long the stint lasted, or has lasted so far
+func (m Stint) Tenure(now time.Time) time.Duration {
+	end := now
+	if m.Left != nil {
+		end = *m.Left
+	}
+	return end.Sub(m.Joined)
+}
+
+// Months is the whole number of months the stint lasted, or has lasted so far
+func (m Stint) Months(now time.Time) int {
+	end := now
+	if m.Left != nil {
+		end = *m.Left
+	}
+	months := 0
+	for !m.Joined.AddDate(0, months+1, 0).After(end) {
+		months++
+	}
+	return months
+}
+
+// JoinDates has when each rider in the club joined it, from their current stints.
+// Riders who were already in the club when tracking started are left out, since they
+// may have joined any time before.
+func JoinDates(stints []Stint) map[int]time.Time {
+	joined := make(map[int]time.Time)
+	for _, m := range stints {
+		if m.Current() && !m.Before {
+			joined[m.Zwid] = m.Joined
+		}
+	}
+	return joined
+}
+
+// TrackStints updates the club's stints from the roster: riders on it without a current
+// stint start one now, and those with one who aren't on it any more have left. The first
+// time, with no stints yet, everyone on the roster is marked as having joined Before. It
+// returns all the stints, and those that started and ended.
+// An empty roster is an error rather than a reason to end everyone's stint.
+func TrackStints(stints []Stint, roster []Rider, now time.Time) (updated, joined, left []Stint, err error) {
+	if len(roster) == 0 {
+		return nil, nil, nil, fmt.Errorf("not tracking who is in the club: the roster is empty")
+	}
+	first := len(stints) == 0
+	onRoster := make(map[int]Rider, len(roster))
+	for _, r := range roster {
+		onRoster[r.Zwid] = r
+	}
+
+	current := make(map[int]bool)
+	updated = append([]Stint(nil), stints...)
+	for i, m := range updated {
+		if !m.Current() {
+			continue
+		}
+		if r, ok := onRoster[m.Zwid]; ok {
+			current[m.Zwid] = true
+			if r.Name != "" {
+				updated[i].Name = r.Name
+			}
+			continue
+		}
+		when := now
+		updated[i].Left = &when
+		left = append(left, updated[i])
+	}
+
+	for _, r := range roster {
+		if current[r.Zwid] {
+			continue
+		}
+		current[r.Zwid] = true
+		m := Stint{Zwid: r.Zwid, Name: r.Name, Joined: now, Before: first}
+		updated = append(updated, m)
+		if !first {
+			joined = append(joined, m)
+		}
+	}
+
+	sort.SliceStable(updated, func(i, j int) bool {
+		if updated[i].Zwid != updated[j].Zwid {
+			return updated[i].Zwid < updated[j].Zwid
+		}
+		return updated[i].Joined.Before(updated[j].Joined)
+	})
+	return updated, joined, left, nil
+}
+
+// Anniversaries finds the riders still in the club who have been in it for another
+// whole year at some time from since until until, for shout-outs alongside the other
+// milestones. Riders who joined Before tracking started are left out, since we don't
+// know when their anniversaries are.
+func Anniversaries(stints []Stint, since, until time.Time) []Milestone {
+	var milestones []Milestone
+	for _, m := range stints {
+		if !m.Current() || m.Before {
+			continue
+		}
+		for years := 1; ; years++ {
+			date := m.Joined.AddDate(years, 0, 0)
+			if date.After(until) {
+				break
+			}
+			if date.Before(since) {
+				continue
+			}
+			text := "has been in the club for a year"
+			if years > 1 {
+				text = fmt.Sprintf("has been in the club for %d years", years)
+			}
+			milestones = append(milestones, Milestone{Date: date, Zwid: m.Zwid, Name: m.Name, Text: text})
+		}
+	}
+	sort.SliceStable(milestones, func(i, j int) bool {
+		return milestones[i].Date.Before(milestones[j].Date)
+	})
+	return milestones
+}
+
+// RetentionStat is how many riders joined and left over a period, and how well the club
+// keeps new riders for that long
+type RetentionStat struct {
+	Days   int
+	Joined int // Riders who joined in the last Days
+	Left   int // Riders who left in the last Days
+	Cohort int // Riders who joined at least Days ago, apart from those who joined Before
+	Stayed int // Of the Cohort, those who stayed for at least Days
+}
+
+// Rate is the fraction of the Cohort who stayed, or 0 if there isn't one yet
+func (s RetentionStat) Rate() float64 {
+	if s.Cohort == 0 {
+		return 0
+	}
+	return float64(s.Stayed) / float64(s.Cohort)
+}
+
+// Retention works out the club's comings and goings over each of the periods, given in
+// days, up to now
+func Retention(stints []Stint, now time.Time, periods []int) []RetentionStat {
+	stats := make([]RetentionStat, len(periods))
+	for i, days := range periods {
+		s := RetentionStat{Days: days}
+		start := now.AddDate(0, 0, -days)
+		for _, m := range stints {
+			if !m.Before && m.Joined.After(start) && !m.Joined.After(now) {
+				s.Joined++
+			}
+			if m.Left != nil && m.Left.After(start) && !m.Left.After(now) {
+				s.Left++
+			}
+			if m.Before || m.Joined.After(start) {
+				continue
+			}
+			s.Cohort++
+			if m.Left == nil || !m.Left.Before(m.Joined.AddDate(0, 0, days)) {
+				s.Stayed++
+			}
+		}
+		stats[i] = s
+	}
+	return stats
+}
+
+func (s *FileStore) stintsPath(clubID int) string {
+	return filepath.Join(s.Dir, "stints", fmt.Sprintf("%d.json", clubID))
+}
+
+// Stints gets every stint of every rider in the club, in order of Zwid and then
+// when they joined. Each club sharing the store has stints of its own.
+func (s *FileStore) Stints(clubID int) ([]Stint, error) {
+	var list []Stint
+	err := s.read(s.stintsPath(clubID), &list)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RecordRoster tracks the club's stints with its roster as of now (see
+// TrackStints), returning the stints that started and ended
+func (s *FileStore) RecordRoster(clubID int, roster []Rider, now time.Time) (joined, left []Stint, err error) {
+	path := s.stintsPath(clubID)
+	unlock, err := lockFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	list, err := s.Stints(clubID)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, joined, left, err = TrackStints(list, roster, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return joined, left, s.write(path, list)
+}
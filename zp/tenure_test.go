@@ -0,0 +1,131 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackStints(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	// Everyone on the first roster was already there
+	stints, joined, left, err := TrackStints(nil, []Rider{{Zwid: 1, Name: "A"}, {Zwid: 2, Name: "B"}}, day(2020, 1, 1))
+	must(t, err)
+	if len(stints) != 2 || !stints[0].Before || len(joined) != 0 || len(left) != 0 {
+		t.Fatalf("Unexpected first stints %+v, joined %v, left %v", stints, joined, left)
+	}
+
+	stints, joined, left, err = TrackStints(stints, []Rider{{Zwid: 1, Name: "A2"}, {Zwid: 3, Name: "C"}}, day(2020, 3, 1))
+	must(t, err)
+	if len(joined) != 1 || joined[0].Zwid != 3 || joined[0].Before || len(left) != 1 || left[0].Zwid != 2 {
+		t.Fatalf("Expected 3 to join and 2 to leave, got %v and %v", joined, left)
+	}
+	if stints[0].Name != "A2" {
+		t.Errorf("Expected the name to follow the roster, got %q", stints[0].Name)
+	}
+
+	// Rejoining starts a new stint
+	stints, joined, _, err = TrackStints(stints, []Rider{{Zwid: 1}, {Zwid: 2}, {Zwid: 3}}, day(2020, 6, 1))
+	must(t, err)
+	if len(joined) != 1 || joined[0].Zwid != 2 || len(stints) != 4 || stints[1].Current() || !stints[2].Current() {
+		t.Fatalf("Expected 2 to rejoin, got %v in %+v", joined, stints)
+	}
+	if m := stints[1].Months(day(2021, 1, 1)); m != 2 {
+		t.Errorf("Expected the first stint to last 2 months, got %d", m)
+	}
+	if m := stints[3].Months(day(2020, 5, 31)); m != 2 {
+		t.Errorf("Expected 2 whole months, got %d", m)
+	}
+	if d := stints[3].Tenure(day(2020, 3, 11)); d != 10*24*time.Hour {
+		t.Errorf("Expected 10 days, got %s", d)
+	}
+
+	if _, _, _, err := TrackStints(stints, nil, day(2020, 7, 1)); err == nil {
+		t.Errorf("Expected an error for an empty roster")
+	}
+}
+
+func TestAnniversaries(t *testing.T) {
+	joined := time.Date(2019, 3, 10, 0, 0, 0, 0, time.UTC)
+	left := joined.AddDate(1, 6, 0)
+	stints := []Stint{
+		{Zwid: 1, Name: "One", Joined: joined},
+		{Zwid: 2, Name: "Two", Joined: joined.AddDate(1, 0, 0)},
+		{Zwid: 3, Name: "Gone", Joined: joined, Left: &left},
+		{Zwid: 4, Name: "Founder", Joined: joined, Before: true},
+	}
+
+	got := Anniversaries(stints, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 11, 0, 0, 0, 0, time.UTC))
+	if len(got) != 2 || got[0].Text != "has been in the club for 2 years" || got[1].Text != "has been in the club for a year" {
+		t.Errorf("Unexpected anniversaries %+v", got)
+	}
+	if got := Anniversaries(stints, time.Date(2021, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)); len(got) != 0 {
+		t.Errorf("Expected no anniversaries, got %+v", got)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	ago := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	leftAt := func(days int) *time.Time { d := ago(days); return &d }
+	stints := []Stint{
+		{Zwid: 1, Joined: ago(400), Before: true},
+		{Zwid: 2, Joined: ago(200)},                    // Stayed
+		{Zwid: 3, Joined: ago(200), Left: leftAt(150)}, // Left after 50 days
+		{Zwid: 4, Joined: ago(20)},
+		{Zwid: 5, Joined: ago(400), Before: true, Left: leftAt(10)},
+	}
+
+	stats := Retention(stints, now, []int{30, 90})
+	if s := stats[0]; s.Joined != 1 || s.Left != 1 || s.Cohort != 2 || s.Stayed != 2 {
+		t.Errorf("Unexpected 30 day stats %+v", s)
+	}
+	if s := stats[1]; s.Days != 90 || s.Cohort != 2 || s.Stayed != 1 || s.Rate() != 0.5 {
+		t.Errorf("Unexpected 90 day stats %+v", s)
+	}
+	if (RetentionStat{}).Rate() != 0 {
+		t.Errorf("Expected no rate without a cohort")
+	}
+}
+
+func TestStoreRecordRoster(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	must(t, err)
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _, err = s.RecordRoster(10, []Rider{{Zwid: 1}}, now)
+	must(t, err)
+	joined, _, err := s.RecordRoster(10, []Rider{{Zwid: 1}, {Zwid: 2, Name: "New"}}, now.AddDate(0, 0, 1))
+	must(t, err)
+	stints, err := s.Stints(10)
+	must(t, err)
+	if len(joined) != 1 || len(stints) != 2 || !stints[0].Before || stints[1].Name != "New" {
+		t.Errorf("Unexpected stints %+v after joining %+v", stints, joined)
+	}
+}
+
+func TestStoreRecordRosterClubs(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	must(t, err)
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _, err = s.RecordRoster(10, []Rider{{Zwid: 1}, {Zwid: 2}}, now)
+	must(t, err)
+	// Another club sharing the store doesn't mean the first club's riders have left
+	joined, left, err := s.RecordRoster(20, []Rider{{Zwid: 3}}, now.AddDate(0, 0, 1))
+	must(t, err)
+	if len(joined) != 0 || len(left) != 0 {
+		t.Errorf("Recording another club joined %+v and left %+v", joined, left)
+	}
+
+	first, err := s.Stints(10)
+	must(t, err)
+	if len(first) != 2 || !first[0].Current() || !first[1].Current() {
+		t.Errorf("Unexpected stints for the first club %+v", first)
+	}
+	second, err := s.Stints(20)
+	must(t, err)
+	if len(second) != 1 || second[0].Zwid != 3 || !second[0].Before {
+		t.Errorf("Unexpected stints for the second club %+v", second)
+	}
+}
@@ -0,0 +1,62 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	oldProvider, oldPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(oldProvider)
+		otel.SetTextMapPropagator(oldPropagator)
+	})
+
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("traceparent") == "" {
+			t.Errorf("No trace context in request for %s", r.URL)
+		}
+		fmt.Fprint(w, testdata)
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ImportRiderContext(context.Background(), client, 1261784)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := sr.Ended()
+	names := make(map[string]int)
+	var riderSpan sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		names[s.Name()]++
+		if s.Name() == "ImportRider" {
+			riderSpan = s
+		}
+	}
+
+	// The Cache3 backend makes two requests: one for the profile page and one for the JSON
+	if names["ImportRider"] != 1 || names["ImportEvents"] != 1 || names["HTTP GET"] != 2 {
+		t.Fatalf("Unexpected spans %v", names)
+	}
+
+	for _, s := range spans {
+		if s.SpanContext().TraceID() != riderSpan.SpanContext().TraceID() {
+			t.Errorf("Span %s is not in the same trace as ImportRider", s.Name())
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package zp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// IntervalsURL is where the intervals.icu API lives
+var IntervalsURL = "https://intervals.icu"
+
+// TrainingPeaksURL is where the TrainingPeaks partner API lives
+var TrainingPeaksURL = "https://api.trainingpeaks.com"
+
+// Training logs that riders can connect, for their races to be exported to
+const (
+	TrainingIntervals     = "intervals"
+	TrainingTrainingPeaks = "trainingpeaks"
+)
+
+// TrainingActivity is a race result as it goes into a rider's training log
+type TrainingActivity struct {
+	ExternalID  string // The same every time the result is exported, so it's only logged once
+	Name        string
+	Description string
+	Start       time.Time
+	Duration    time.Duration
+	Distance    float64 // Metres
+	AvgPower    float64
+	NP          float64
+	AvgHR       float64
+	MaxHR       float64
+	Load        Load // TSS and IF, if the result has the power and FTP for them
+}
+
+// NewTrainingActivity describes the rider's race for their training log: the event and
+// where they finished as its name, with a link to the results
+func NewTrainingActivity(e Event) TrainingActivity {
+	name := e.EventTitle
+	if name == "" {
+		name = "Zwift race " + e.Zid
+	}
+	description := fmt.Sprintf("https://www.zwiftpower.com/events.php?zid=%s", e.Zid)
+	if p := e.categoryPosition(); p > 0 {
+		place := ordinal(p)
+		if e.Category != "" {
+			place += " in " + e.Category
+		}
+		name += " (" + place + ")"
+		description = fmt.Sprintf("Finished %s on ZwiftPower\n%s", place, description)
+	}
+
+	a := TrainingActivity{
+		ExternalID:  fmt.Sprintf("zwiftpower-%s-%d", e.Zid, e.Zwid),
+		Name:        name,
+		Description: description,
+		Start:       e.EventDate,
+		Duration:    e.EventDuration(),
+		Distance:    float64(e.Distance) * 1000,
+		AvgPower:    float64(e.AvgPower),
+		NP:          float64(e.NP),
+		AvgHR:       float64(e.AvgHR),
+		MaxHR:       float64(e.MaxHR),
+	}
+	if l, ok := e.Load(); ok {
+		a.Load = l
+	}
+	return a
+}
+
+// TrainingLog is somewhere a rider keeps their training, that races can be added to
+type TrainingLog interface {
+	Log(ctx context.Context, a TrainingActivity) error
+}
+
+// IntervalsICU adds activities to a rider's intervals.icu calendar as manual activities
+type IntervalsICU struct {
+	AthleteID string // Such as i12345; 0 means the owner of the API key
+	APIKey    string // From the rider's Settings page, under Developer Settings
+	Client    *http.Client
+}
+
+// Log adds the activity, with its training load, to the rider's intervals.icu calendar.
+// intervals.icu wants local times, and the start is given in UTC as ZwiftPower has it.
+func (i IntervalsICU) Log(ctx context.Context, a TrainingActivity) error {
+	athlete := i.AthleteID
+	if athlete == "" {
+		athlete = "0"
+	}
+	activity := map[string]interface{}{
+		"external_id":      a.ExternalID,
+		"type":             "VirtualRide",
+		"name":             a.Name,
+		"description":      a.Description,
+		"start_date_local": a.Start.UTC().Format("2006-01-02T15:04:05"),
+		"moving_time":      int(a.Duration.Seconds()),
+		"elapsed_time":     int(a.Duration.Seconds()),
+	}
+	optional := map[string]float64{
+		"distance":               a.Distance,
+		"icu_average_watts":      a.AvgPower,
+		"icu_weighted_avg_watts": a.NP,
+		"average_heartrate":      a.AvgHR,
+		"max_heartrate":          a.MaxHR,
+		"icu_training_load":      a.Load.TSS,
+		"icu_intensity":          a.Load.IF * 100,
+	}
+	for k, v := range optional {
+		if v > 0 {
+			activity[k] = v
+		}
+	}
+
+	url := fmt.Sprintf("%s/api/v1/athlete/%s/activities/manual", IntervalsURL, athlete)
+	return postTraining(ctx, i.Client, url, activity, func(req *http.Request) {
+		req.SetBasicAuth("API_KEY", i.APIKey)
+	})
+}
+
+// TrainingPeaks adds completed workouts to a rider's TrainingPeaks calendar. Its API is
+// only open to partners, so the access token has to come from an OAuth app that
+// TrainingPeaks has approved, with the workouts:write scope.
+type TrainingPeaks struct {
+	Token  string
+	Client *http.Client
+}
+
+// Log adds the activity to the rider's TrainingPeaks calendar as a completed bike workout
+func (t TrainingPeaks) Log(ctx context.Context, a TrainingActivity) error {
+	workout := map[string]interface{}{
+		"WorkoutDay":  a.Start.UTC().Format("2006-01-02"),
+		"StartTime":   a.Start.UTC().Format("2006-01-02T15:04:05"),
+		"Title":       a.Name,
+		"Description": a.Description,
+		"WorkoutType": "Bike",
+		"Completed":   true,
+		"TotalTime":   a.Duration.Hours(),
+	}
+	optional := map[string]float64{
+		"Distance":         a.Distance,
+		"PowerAverage":     a.AvgPower,
+		"NormalizedPower":  a.NP,
+		"HeartRateAverage": a.AvgHR,
+		"HeartRateMaximum": a.MaxHR,
+		"TssActual":        a.Load.TSS,
+		"IntensityFactor":  a.Load.IF,
+	}
+	for k, v := range optional {
+		if v > 0 {
+			workout[k] = v
+		}
+	}
+
+	return postTraining(ctx, t.Client, TrainingPeaksURL+"/v2/workouts", workout, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+t.Token)
+	})
+}
+
+// postTraining posts JSON to a training log's API, with auth added to the request
+func postTraining(ctx context.Context, client *http.Client, url string, v interface{}, auth func(*http.Request)) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	auth(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("posting to %s: %s %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// ExportRaces adds the rider's races since the given time to their training log, oldest
+// first, leaving out any whose ExternalID is in exported. It returns the ExternalIDs of
+// the races it added, which may be some of them even if there's an error.
+func ExportRaces(ctx context.Context, log TrainingLog, events []Event, since time.Time, exported map[string]bool) ([]string, error) {
+	var races []Event
+	seen := make(map[string]bool)
+	for _, e := range events {
+		if !e.IsRace() || e.EventDate.Before(since) || e.EventDuration() <= 0 || seen[e.Zid] {
+			continue
+		}
+		seen[e.Zid] = true
+		races = append(races, e)
+	}
+	sort.SliceStable(races, func(i, j int) bool {
+		return races[i].EventDate.Before(races[j].EventDate)
+	})
+
+	var done []string
+	for _, e := range races {
+		a := NewTrainingActivity(e)
+		if exported[a.ExternalID] {
+			continue
+		}
+		if err := log.Log(ctx, a); err != nil {
+			return done, fmt.Errorf("exporting %s: %w", a.Name, err)
+		}
+		done = append(done, a.ExternalID)
+	}
+	return done, nil
+}
+
+// TrainingConnection is a rider's account with a training log, that their races are
+// exported to
+type TrainingConnection struct {
+	Zwid     int
+	Service  string    // TrainingIntervals or TrainingTrainingPeaks
+	Athlete  string    `json:",omitempty"` // The intervals.icu athlete ID
+	Key      string    // The API key or access token
+	Date     time.Time // When they connected
+	Exported []string  `json:",omitempty"` // The ExternalIDs of races already exported
+}
+
+// Log gets the training log the connection is for
+func (c TrainingConnection) Log(client *http.Client) (TrainingLog, error) {
+	switch c.Service {
+	case TrainingIntervals:
+		return IntervalsICU{AthleteID: c.Athlete, APIKey: c.Key, Client: client}, nil
+	case TrainingTrainingPeaks:
+		return TrainingPeaks{Token: c.Key, Client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown training log %q, expected %s or %s", c.Service, TrainingIntervals, TrainingTrainingPeaks)
+}
+
+// ExportedSet is the races already exported, for ExportRaces
+func (c TrainingConnection) ExportedSet() map[string]bool {
+	exported := make(map[string]bool, len(c.Exported))
+	for _, id := range c.Exported {
+		exported[id] = true
+	}
+	return exported
+}
+
+func (s *FileStore) trainingPath() string {
+	return filepath.Join(s.Dir, "training.json")
+}
+
+// TrainingConnections gets the riders' connected training logs, in order of Zwid
+func (s *FileStore) TrainingConnections() ([]TrainingConnection, error) {
+	var list []TrainingConnection
+	err := s.read(s.trainingPath(), &list)
+	if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ConnectTraining adds the rider's training log, or replaces their key if it's already
+// connected, keeping the record of what has been exported
+func (s *FileStore) ConnectTraining(c TrainingConnection) error {
+	if c.Zwid == 0 {
+		return fmt.Errorf("connecting a training log needs a rider ID")
+	}
+	if _, err := c.Log(nil); err != nil {
+		return err
+	}
+	return s.updateTraining(func(list []TrainingConnection) ([]TrainingConnection, error) {
+		if c.Date.IsZero() {
+			c.Date = time.Now().UTC()
+		}
+		for i := range list {
+			if list[i].Zwid == c.Zwid && list[i].Service == c.Service {
+				c.Exported = list[i].Exported
+				list[i] = c
+				return list, nil
+			}
+		}
+		list = append(list, c)
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].Zwid < list[j].Zwid
+		})
+		return list, nil
+	})
+}
+
+// DisconnectTraining removes the rider's training log, or all of their logs if service
+// is empty
+func (s *FileStore) DisconnectTraining(riderID int, service string) error {
+	return s.updateTraining(func(list []TrainingConnection) ([]TrainingConnection, error) {
+		var kept []TrainingConnection
+		for _, c := range list {
+			if c.Zwid != riderID || (service != "" && c.Service != service) {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) == len(list) {
+			return nil, fmt.Errorf("rider %d has no training log connected", riderID)
+		}
+		return kept, nil
+	})
+}
+
+// MarkExported records that these races have been exported to the rider's training log
+func (s *FileStore) MarkExported(riderID int, service string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.updateTraining(func(list []TrainingConnection) ([]TrainingConnection, error) {
+		for i := range list {
+			if list[i].Zwid == riderID && list[i].Service == service {
+				list[i].Exported = append(list[i].Exported, ids...)
+				return list, nil
+			}
+		}
+		return nil, fmt.Errorf("rider %d has no %s training log connected", riderID, service)
+	})
+}
+
+// updateTraining changes the training connections while holding the lock on them
+func (s *FileStore) updateTraining(change func([]TrainingConnection) ([]TrainingConnection, error)) error {
+	path := s.trainingPath()
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	list, err := s.TrainingConnections()
+	if err != nil {
+		return err
+	}
+	list, err = change(list)
+	if err != nil {
+		return err
+	}
+	// The connections have riders' API keys in them
+	return s.writePrivate(path, list)
+}
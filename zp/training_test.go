@@ -0,0 +1,144 @@
+package zp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewTrainingActivity(t *testing.T) {
+	date := time.Date(2021, 6, 1, 18, 0, 0, 0, time.UTC)
+	e := Event{Zwid: 123, Zid: "456", EventTitle: "Saturday Crit", EventType: "RACE", Category: "B", Pos: 12, PositionInCat: 3,
+		EventDate: date, Time: 3600, Distance: 40.5, AvgPower: 220, NP: 250, Ftp: 250, AvgHR: 150, MaxHR: 180}
+	a := NewTrainingActivity(e)
+	if a.ExternalID != "zwiftpower-456-123" || a.Name != "Saturday Crit (3rd in B)" || !a.Start.Equal(date) ||
+		a.Duration != time.Hour || a.Distance != 40500 || a.NP != 250 || a.Load.TSS != 100 || a.Load.IF != 1 {
+		t.Errorf("Unexpected activity %+v", a)
+	}
+	if a.Description != "Finished 3rd in B on ZwiftPower\nhttps://www.zwiftpower.com/events.php?zid=456" {
+		t.Errorf("Unexpected description %q", a.Description)
+	}
+
+	// Without a position or the power for a load
+	a = NewTrainingActivity(Event{Zwid: 123, Zid: "789", Time: 1800})
+	if a.Name != "Zwift race 789" || a.Load.TSS != 0 {
+		t.Errorf("Unexpected activity %+v", a)
+	}
+}
+
+func TestExportRacesToIntervals(t *testing.T) {
+	var posted []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, key, ok := r.BasicAuth(); !ok || user != "API_KEY" || key != "k3y" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/athlete/i42/activities/manual" {
+			t.Errorf("Unexpected %s %s", r.Method, r.URL)
+		}
+		var activity map[string]interface{}
+		must(t, json.NewDecoder(r.Body).Decode(&activity))
+		posted = append(posted, activity)
+		if activity["external_id"] == "zwiftpower-3-1" {
+			http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+	oldURL := IntervalsURL
+	IntervalsURL = ts.URL
+	defer func() { IntervalsURL = oldURL }()
+
+	now := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	race := func(zid string, daysAgo int) Event {
+		return Event{Zwid: 1, Zid: zid, EventType: "RACE", EventDate: now.AddDate(0, 0, -daysAgo), Time: 3600, NP: 250, Ftp: 250}
+	}
+	events := []Event{
+		race("1", 2), race("2", 5), race("2", 5), // The same race twice
+		race("old", 30),
+		{Zwid: 1, Zid: "ride", EventType: "GROUP", EventDate: now, Time: 3600},
+		race("exported", 1),
+	}
+	log := IntervalsICU{AthleteID: "i42", APIKey: "k3y"}
+	exported := map[string]bool{"zwiftpower-exported-1": true}
+
+	done, err := ExportRaces(context.Background(), log, events, now.AddDate(0, 0, -7), exported)
+	must(t, err)
+	if !reflect.DeepEqual(done, []string{"zwiftpower-2-1", "zwiftpower-1-1"}) {
+		t.Errorf("Expected the two new races, oldest first, got %v", done)
+	}
+	if len(posted) != 2 || posted[0]["start_date_local"] != "2021-06-05T00:00:00" || posted[0]["moving_time"] != float64(3600) ||
+		posted[0]["type"] != "VirtualRide" || posted[0]["icu_training_load"] != 100.0 {
+		t.Errorf("Unexpected activities %v", posted)
+	}
+
+	// What was done before an error is still reported
+	events = append(events, race("3", 0))
+	done, err = ExportRaces(context.Background(), log, events, now.AddDate(0, 0, -7), map[string]bool{"zwiftpower-2-1": true})
+	if err == nil || !reflect.DeepEqual(done, []string{"zwiftpower-1-1", "zwiftpower-exported-1"}) {
+		t.Errorf("Expected two exported then an error, got %v, %v", done, err)
+	}
+
+	log.APIKey = "wrong"
+	if _, err := ExportRaces(context.Background(), log, events, now.AddDate(0, 0, -7), nil); err == nil {
+		t.Errorf("Expected an error with the wrong key")
+	}
+}
+
+func TestTrainingPeaks(t *testing.T) {
+	var workout map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" || r.URL.Path != "/v2/workouts" {
+			t.Errorf("Unexpected request %s with %q", r.URL, r.Header.Get("Authorization"))
+		}
+		must(t, json.NewDecoder(r.Body).Decode(&workout))
+	}))
+	defer ts.Close()
+	oldURL := TrainingPeaksURL
+	TrainingPeaksURL = ts.URL
+	defer func() { TrainingPeaksURL = oldURL }()
+
+	a := TrainingActivity{Name: "Race", Start: time.Date(2021, 6, 1, 18, 0, 0, 0, time.UTC), Duration: 90 * time.Minute, Load: Load{TSS: 80}}
+	must(t, TrainingPeaks{Token: "t0ken"}.Log(context.Background(), a))
+	if workout["WorkoutDay"] != "2021-06-01" || workout["TotalTime"] != 1.5 || workout["TssActual"] != 80.0 || workout["Completed"] != true {
+		t.Errorf("Unexpected workout %v", workout)
+	}
+	if _, ok := workout["PowerAverage"]; ok {
+		t.Errorf("Expected no power without any, got %v", workout)
+	}
+}
+
+func TestStoreTrainingConnections(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	must(t, err)
+
+	if err := s.ConnectTraining(TrainingConnection{Zwid: 1, Service: "strava"}); err == nil {
+		t.Errorf("Expected an error for an unknown service")
+	}
+	must(t, s.ConnectTraining(TrainingConnection{Zwid: 2, Service: TrainingIntervals, Athlete: "i2", Key: "a"}))
+	must(t, s.ConnectTraining(TrainingConnection{Zwid: 1, Service: TrainingTrainingPeaks, Key: "b"}))
+	must(t, s.MarkExported(2, TrainingIntervals, []string{"x", "y"}))
+
+	// Connecting again changes the key but remembers what has been exported
+	must(t, s.ConnectTraining(TrainingConnection{Zwid: 2, Service: TrainingIntervals, Athlete: "i2", Key: "c"}))
+	list, err := s.TrainingConnections()
+	must(t, err)
+	if len(list) != 2 || list[0].Zwid != 1 || list[1].Key != "c" || !list[1].ExportedSet()["y"] || list[1].Date.IsZero() {
+		t.Errorf("Unexpected connections %+v", list)
+	}
+
+	must(t, s.DisconnectTraining(2, ""))
+	if err := s.DisconnectTraining(2, ""); err == nil {
+		t.Errorf("Expected an error disconnecting twice")
+	}
+	if err := s.MarkExported(2, TrainingIntervals, []string{"z"}); err == nil {
+		t.Errorf("Expected an error marking a disconnected rider")
+	}
+	list, _ = s.TrainingConnections()
+	if len(list) != 1 || list[0].Zwid != 1 {
+		t.Errorf("Unexpected connections %+v", list)
+	}
+}
@@ -0,0 +1,132 @@
+package zp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// UpgradeDays is how far back the numbers that could move a rider up a category go
+const UpgradeDays = 90
+
+// UpgradeMargin is how close to the next category up counts as close enough to warn about
+type UpgradeMargin struct {
+	Wkg   float64
+	Watts float64
+}
+
+// DefaultUpgradeMargin warns riders within 0.2 w/kg and 10W of moving up
+var DefaultUpgradeMargin = UpgradeMargin{Wkg: 0.2, Watts: 10}
+
+// UpgradeWarning is a rider whose recent races put them close to, or over, the limits of
+// the category above theirs
+type UpgradeWarning struct {
+	Zwid      int
+	Name      string
+	Category  string
+	Next      string  // The category they're close to moving up to
+	Wkg       float64 // Best FTP estimate in w/kg from their races in the last UpgradeDays
+	Watts     float64 // Best FTP estimate in watts
+	WkgToGo   float64 // Below zero if they're already over
+	WattsToGo float64
+	Events    []Event // The races that were close enough to warn about, newest first
+}
+
+// Over is true if the rider is already over both limits for the next category
+func (w UpgradeWarning) Over() bool {
+	return w.WkgToGo <= 0 && w.WattsToGo <= 0
+}
+
+// ParseCategoryBoundaries reads category limits from JSON, to use instead of
+// CategoryBoundaries when the rules change, e.g.
+//
+//	[{"Category": "A", "MinWkg": 4.0, "MinWatts": 250}, {"Category": "B", "MinWkg": 3.2, "MinWatts": 200},
+//	 {"Category": "C", "MinWkg": 2.5, "MinWatts": 150}, {"Category": "D"}]
+//
+// Categories go from the top down.
+func ParseCategoryBoundaries(data []byte) ([]CategoryBoundary, error) {
+	var limits []CategoryBoundary
+	err := json.Unmarshal(data, &limits)
+	if err != nil {
+		return nil, &ParseError{What: "category limits", Err: err}
+	}
+	if len(limits) < 2 {
+		return nil, fmt.Errorf("need at least two categories, got %d", len(limits))
+	}
+	for i, l := range limits {
+		if l.Category == "" {
+			return nil, fmt.Errorf("category %d has no name", i+1)
+		}
+		if i > 0 && l.MinWkg > limits[i-1].MinWkg {
+			return nil, fmt.Errorf("category %s needs less w/kg than %s, so should come before it", limits[i-1].Category, l.Category)
+		}
+	}
+	return limits, nil
+}
+
+// CheckUpgrade looks at the rider's races over the last UpgradeDays for FTP estimates
+// within the margin of the limits of the category above the one they last raced in. Both
+// the w/kg and the watts have to be close, since moving up needs both. The limits are
+// CategoryBoundaries if nil. It's false if there's nothing to warn about.
+func CheckUpgrade(riderID int, events []Event, limits []CategoryBoundary, margin UpgradeMargin, now time.Time) (UpgradeWarning, bool) {
+	if limits == nil {
+		limits = CategoryBoundaries
+	}
+
+	w := UpgradeWarning{Zwid: riderID}
+	var latest time.Time
+	for _, e := range events {
+		if w.Name == "" && e.Name != "" {
+			w.Name = e.RiderName()
+		}
+		if e.EventDateSecs != 0 && e.IsRace() && boundaryIndex(limits, e.Category) >= 0 &&
+			!e.EventDate.After(now) && e.EventDate.After(latest) {
+			w.Category = e.Category
+			latest = e.EventDate
+		}
+	}
+
+	i := boundaryIndex(limits, w.Category)
+	if i <= 0 {
+		return w, false
+	}
+	next := limits[i-1]
+	w.Next = next.Category
+
+	since := now.AddDate(0, 0, -UpgradeDays)
+	for _, e := range events {
+		if e.EventDateSecs == 0 || !e.IsRace() || e.EventDate.Before(since) || e.EventDate.After(now) {
+			continue
+		}
+		wkg, watts := e.FtpWkg(), float64(e.Wftp)
+		if wkg > w.Wkg {
+			w.Wkg = wkg
+		}
+		if watts > w.Watts {
+			w.Watts = watts
+		}
+		if wkg > 0 && wkg >= next.MinWkg-margin.Wkg && watts >= next.MinWatts-margin.Watts {
+			w.Events = append(w.Events, e)
+		}
+	}
+	if len(w.Events) == 0 {
+		return w, false
+	}
+
+	sort.SliceStable(w.Events, func(i, j int) bool {
+		return w.Events[i].EventDate.After(w.Events[j].EventDate)
+	})
+	w.WkgToGo = next.MinWkg - w.Wkg
+	w.WattsToGo = next.MinWatts - w.Watts
+	return w, true
+}
+
+func boundaryIndex(limits []CategoryBoundary, category string) int {
+	for i, b := range limits {
+		if b.Category == category {
+			return i
+		}
+	}
+	return -1
+}
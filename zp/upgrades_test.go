@@ -0,0 +1,80 @@
+package zp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCheckUpgrade(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	race := func(id string, category string, daysAgo int, wkgFtp float64, watts float64) Event {
+		date := now.AddDate(0, 0, -daysAgo)
+		return Event{
+			Zwid:          1,
+			Zid:           id,
+			Name:          "Rider",
+			Category:      category,
+			EventType:     "TYPE_RACE",
+			EventDateSecs: EventDateType(date.Unix()),
+			EventDate:     date,
+			WkgFtp:        []interface{}{wkgFtp, 0.0},
+			Wftp:          Number(watts),
+		}
+	}
+
+	events := []Event{
+		race("1", "C", 120, 3.4, 240), // Too long ago
+		race("2", "C", 60, 3.1, 230),
+		race("3", "C", 30, 2.8, 200), // Not close enough
+		race("4", "C", 10, 3.05, 190),
+		race("5", "C", 5, 3.1, 185), // Not enough watts
+	}
+
+	w, ok := CheckUpgrade(1, events, nil, DefaultUpgradeMargin, now)
+	if !ok {
+		t.Fatalf("Expected a warning")
+	}
+	if w.Category != "C" || w.Next != "B" || w.Name != "Rider" {
+		t.Errorf("Unexpected warning %+v", w)
+	}
+	if len(w.Events) != 2 || w.Events[0].Zid != "4" || w.Events[1].Zid != "2" {
+		t.Errorf("Got triggering events %v", w.Events)
+	}
+	if math.Abs(w.WkgToGo-0.1) > 0.001 || w.WattsToGo != -30 || w.Over() {
+		t.Errorf("Got %.2f w/kg and %.0fW to go", w.WkgToGo, w.WattsToGo)
+	}
+
+	if _, ok := CheckUpgrade(1, events, nil, UpgradeMargin{Wkg: 0.05, Watts: 10}, now); ok {
+		t.Errorf("Expected no warning with a smaller margin")
+	}
+
+	if _, ok := CheckUpgrade(1, []Event{race("6", "A", 5, 4.5, 300)}, nil, DefaultUpgradeMargin, now); ok {
+		t.Errorf("Expected no warning for the top category")
+	}
+
+	limits := []CategoryBoundary{{Category: "B", MinWkg: 3.0}, {Category: "C"}}
+	w, ok = CheckUpgrade(1, events, limits, UpgradeMargin{}, now)
+	if !ok || len(w.Events) != 3 || !w.Over() {
+		t.Errorf("Unexpected warning with other limits %+v", w)
+	}
+}
+
+func TestParseCategoryBoundaries(t *testing.T) {
+	limits, err := ParseCategoryBoundaries([]byte(`[{"Category": "A", "MinWkg": 4.2, "MinWatts": 260}, {"Category": "B"}]`))
+	must(t, err)
+	if len(limits) != 2 || limits[0].MinWatts != 260 {
+		t.Errorf("Got %+v", limits)
+	}
+
+	for _, data := range []string{
+		`{}`,
+		`[{"Category": "A", "MinWkg": 4}]`,
+		`[{"Category": "A", "MinWkg": 4}, {"MinWkg": 3}]`,
+		`[{"Category": "B", "MinWkg": 3.2}, {"Category": "A", "MinWkg": 4}]`,
+	} {
+		if _, err := ParseCategoryBoundaries([]byte(data)); err == nil {
+			t.Errorf("Expected an error for %s", data)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package zp
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Vetting summarises what a club admin wants to know about someone asking to join: how
+// active they are, what they race, anything odd in their results, and which teams they
+// have ridden for
+type Vetting struct {
+	Rider      Rider // Activity and FTP, as of when the vetting was done
+	Category   CategoryHistory
+	Categories []CategoryRaces // Races in each category over the last year
+	Flags      []Anomaly       // About the rider, then their results newest first
+	Teams      []TeamSpell     // Other teams, most recent first
+}
+
+// CategoryRaces counts the races in one category
+type CategoryRaces struct {
+	Category string
+	Races    int
+}
+
+// TeamSpell is a time a rider spent riding for a team, as far as their events show it
+type TeamSpell struct {
+	TeamID string
+	Team   string
+	First  time.Time
+	Last   time.Time
+	Events int
+}
+
+// Checks made when vetting a rider, on top of the checks on their results (see
+// ReviewResults)
+const (
+	CheckAboveCategory = "above-category" // Still racing in a category their FTP is above
+	CheckNewRider      = "new-rider"      // First event on ZwiftPower was very recent
+	CheckNoRaces       = "no-races"       // No races in the last year
+)
+
+// newRiderDays is how recent a rider's first event has to be for them to count as new
+const newRiderDays = 30
+
+// VetRider summarises the rider's events for a join request to the club with this ID.
+// Results are checked for implausible power, weight and no-draft times over the last
+// year; the heart rate and outlier checks need the rest of the field, so they aren't
+// made here. The rider's time in this club doesn't count towards their other teams.
+// FTPs are combined as the aggregation says.
+func VetRider(riderID int, events []Event, clubID string, now time.Time, aggregation FtpAggregations) Vetting {
+	v := Vetting{
+		Rider:    RiderFromEventsAggregated(riderID, events, now, aggregation),
+		Category: CategoryHistoryAsOf(riderID, events, now),
+	}
+	v.Rider.Name = v.Category.Name // Only club rosters give riders their names
+
+	// Newest first, so that the flags come out in that order
+	events = append([]Event(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventDate.After(events[j].EventDate)
+	})
+
+	yearAgo := now.AddDate(-1, 0, 0)
+	categories := make(map[string]int)
+	teams := make(map[string]*TeamSpell)
+	var first time.Time
+	for _, e := range events {
+		if e.EventDateSecs == 0 || e.EventDate.After(now) {
+			continue
+		}
+		if first.IsZero() || e.EventDate.Before(first) {
+			first = e.EventDate
+		}
+
+		if e.TeamID != "" && e.TeamID != "0" && e.TeamID != clubID {
+			t, ok := teams[e.TeamID]
+			if !ok {
+				t = &TeamSpell{TeamID: e.TeamID, Team: html.UnescapeString(e.TeamName), First: e.EventDate, Last: e.EventDate}
+				teams[e.TeamID] = t
+			}
+			t.Events++
+			if e.EventDate.Before(t.First) {
+				t.First = e.EventDate
+			}
+			if e.EventDate.After(t.Last) {
+				t.Last = e.EventDate
+			}
+		}
+
+		if e.EventDate.Before(yearAgo) {
+			continue
+		}
+		if e.IsRace() && e.Category != "" {
+			categories[strings.ToUpper(e.Category)]++
+		}
+		for _, check := range []func(Event) []Anomaly{checkPower, checkWeight, checkNoDraft} {
+			v.Flags = append(v.Flags, check(e)...)
+		}
+	}
+
+	for c, n := range categories {
+		v.Categories = append(v.Categories, CategoryRaces{Category: c, Races: n})
+	}
+	sort.Slice(v.Categories, func(i, j int) bool {
+		return v.Categories[i].Category < v.Categories[j].Category
+	})
+
+	for _, t := range teams {
+		v.Teams = append(v.Teams, *t)
+	}
+	sort.Slice(v.Teams, func(i, j int) bool {
+		return v.Teams[i].Last.After(v.Teams[j].Last)
+	})
+
+	// Flags about the rider as a whole go before those about their results
+	rider := Event{Zwid: riderID, Name: v.Rider.Name, Category: v.Category.Current}
+	var flags []Anomaly
+	if v.Category.NearUpgrade(0) {
+		flags = append(flags, newAnomaly(rider, CheckAboveCategory, "60-day FTP of %.1f w/kg is over the bottom of the category above %s",
+			v.Category.Ftp60, v.Category.Current))
+	}
+	if !first.IsZero() && now.Sub(first) < newRiderDays*24*time.Hour {
+		flags = append(flags, newAnomaly(rider, CheckNewRider, "first event on ZwiftPower was on %s", first.Format("2006-01-02")))
+	}
+	if len(categories) == 0 {
+		flags = append(flags, newAnomaly(rider, CheckNoRaces, "no races since %s", yearAgo.Format("2006-01-02")))
+	}
+	v.Flags = append(flags, v.Flags...)
+	return v
+}
@@ -0,0 +1,57 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVetRider(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	event := func(daysAgo int, category string, team string) Event {
+		date := now.AddDate(0, 0, -daysAgo)
+		return Event{
+			Zwid: 1, Name: "Applicant", Category: category, TeamID: team, TeamName: "Team " + team,
+			EventType: "TYPE_RACE", EventDate: date, EventDateSecs: EventDateType(date.Unix()),
+		}
+	}
+
+	heavy := event(3, "C", "2672")
+	heavy.Weight = 35
+	events := []Event{
+		event(400, "B", "10"),
+		event(200, "B", "10"),
+		event(100, "c", "20"),
+		heavy,
+	}
+
+	v := VetRider(1, events, "2672", now, FtpAggregations{})
+	if v.Rider.Zwid != 1 || v.Rider.Name != "Applicant" {
+		t.Errorf("Unexpected rider %v", v.Rider)
+	}
+	if len(v.Categories) != 2 || v.Categories[0] != (CategoryRaces{"B", 1}) || v.Categories[1] != (CategoryRaces{"C", 2}) {
+		t.Errorf("Unexpected categories %v", v.Categories)
+	}
+
+	// The applicant's time in our club doesn't count
+	if len(v.Teams) != 2 || v.Teams[0].TeamID != "20" || v.Teams[1].TeamID != "10" {
+		t.Fatalf("Unexpected teams %v", v.Teams)
+	}
+	if v.Teams[1].Events != 2 || !v.Teams[1].First.Equal(now.AddDate(0, 0, -400)) || v.Teams[1].Team != "Team 10" {
+		t.Errorf("Unexpected team history %v", v.Teams[1])
+	}
+
+	if len(v.Flags) != 1 || v.Flags[0].Check != CheckWeight {
+		t.Errorf("Expected a weight flag, got %v", v.Flags)
+	}
+
+	// Someone new to ZwiftPower who hasn't raced
+	ride := event(10, "", "")
+	ride.EventType = "TYPE_RIDE"
+	v = VetRider(1, []Event{ride}, "2672", now, FtpAggregations{})
+	if len(v.Flags) != 2 || v.Flags[0].Check != CheckNewRider || v.Flags[1].Check != CheckNoRaces {
+		t.Errorf("Unexpected flags %v", v.Flags)
+	}
+	if len(v.Teams) != 0 {
+		t.Errorf("Expected no teams, got %v", v.Teams)
+	}
+}
@@ -0,0 +1,111 @@
+package zp
+
+import (
+	"regexp"
+	"time"
+)
+
+// World is a Zwift world, recognised from the names of its routes in event titles
+type World struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// KnownWorlds lists the worlds we recognise, in order of precedence, so for example
+// Crit City comes before Watopia because some Crit City events mention Watopia. Add
+// to this to recognise more routes.
+var KnownWorlds = []World{
+	{Name: "Crit City", Pattern: regexp.MustCompile(`(?i)crit city|downtown dolphin|bell lap`)},
+	{Name: "Makuri Islands", Pattern: regexp.MustCompile(`(?i)makuri|neokyo|yumezi|urukazi|castle (crit|to|traverse)|temple trek|country to coastal|valley of the kami`)},
+	{Name: "Richmond", Pattern: regexp.MustCompile(`(?i)richmond|libby hill|23rd street|uci (course|worlds)`)},
+	{Name: "London", Pattern: regexp.MustCompile(`(?i)london|surrey hills|box hill|leith hill|keith hill|greater london`)},
+	{Name: "New York", Pattern: regexp.MustCompile(`(?i)new york|park perimeter|knickerbocker|mighty metropolitan|central park`)},
+	{Name: "Innsbruck", Pattern: regexp.MustCompile(`(?i)innsbruck|lutscher`)},
+	{Name: "Yorkshire", Pattern: regexp.MustCompile(`(?i)yorkshire|harrogate|royal pump room`)},
+	{Name: "Bologna", Pattern: regexp.MustCompile(`(?i)bologna`)},
+	{Name: "Paris", Pattern: regexp.MustCompile(`(?i)\b(paris|champs[- ][eé]lys|lutece)`)},
+	{Name: "France", Pattern: regexp.MustCompile(`(?i)france|ven-top|petit kom|casse-pattes|tire-bouchon|\brgv\b`)},
+	{Name: "Scotland", Pattern: regexp.MustCompile(`(?i)scotland|glasgow|sgurr|loch loop`)},
+	{Name: "Watopia", Pattern: regexp.MustCompile(`(?i)watopia|volcano|alpe du zwift|epic kom|jungle|tempus fugit|tick tock|titans grove|sand and sequoias|triple flat|hilly route|big loop|mountain route|road to sky|ocean lava|dust in the wind|out and back again`)},
+}
+
+// RouteWorlds maps ZwiftPower route IDs to world names, for events whose titles don't
+// give the route away. It starts empty: add routes as you come across them.
+var RouteWorlds = map[string]string{}
+
+// World returns the name of the Zwift world the event took place in, or an empty
+// string if we can't tell
+func (e Event) World() string {
+	if w, ok := RouteWorlds[e.RouteID]; ok {
+		return w
+	}
+
+	for _, w := range KnownWorlds {
+		if w.Pattern.MatchString(e.EventTitle) {
+			return w.Name
+		}
+	}
+	return ""
+}
+
+// Time slots, by when the event started
+const (
+	Morning   = "Morning"   // 05:00 to 11:59
+	Afternoon = "Afternoon" // 12:00 to 16:59
+	Evening   = "Evening"   // 17:00 to 21:59
+	Night     = "Night"     // 22:00 to 04:59
+)
+
+// StartTime gives the time of day the event started, as hh:mm in the local time zone
+func (e Event) StartTime() string {
+	if e.EventDateSecs == 0 {
+		return ""
+	}
+	return e.EventDate.Format("15:04")
+}
+
+// TimeSlot says whether the event started in the morning, afternoon, evening or
+// night, in the local time zone
+func (e Event) TimeSlot() string {
+	if e.EventDateSecs == 0 {
+		return ""
+	}
+
+	switch h := e.EventDate.Hour(); {
+	case h >= 5 && h < 12:
+		return Morning
+	case h >= 12 && h < 17:
+		return Afternoon
+	case h >= 17 && h < 22:
+		return Evening
+	default:
+		return Night
+	}
+}
+
+// raceWorld is the world for races, and empty for everything else
+func raceWorld(e Event) string {
+	if !e.IsRace() {
+		return ""
+	}
+	return e.World()
+}
+
+// SplitByWorld summarises races since the given date in each world we can recognise,
+// with the world's name in the Series field
+func SplitByWorld(events []Event, since time.Time) []SeriesStats {
+	return splitBy(events, since, raceWorld)
+}
+
+func raceTimeSlot(e Event) string {
+	if !e.IsRace() {
+		return ""
+	}
+	return e.TimeSlot()
+}
+
+// SplitByTimeSlot summarises races since the given date by the time of day they
+// started, with the time slot in the Series field
+func SplitByTimeSlot(events []Event, since time.Time) []SeriesStats {
+	return splitBy(events, since, raceTimeSlot)
+}
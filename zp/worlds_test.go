@@ -0,0 +1,83 @@
+package zp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventWorld(t *testing.T) {
+	cases := map[string]string{
+		"Crit City Race":                      "Crit City",
+		"Tour of Watopia Stage 4":             "Watopia",
+		"Club Race - Volcano Circuit":         "Watopia",
+		"Neokyo All-Nighter":                  "Makuri Islands",
+		"KISS Race - Greater London Flat":     "London",
+		"3R Ven-Top Hill Climb":               "France",
+		"Tour de France - Champs-Élysées":     "Paris",
+		"Lutece Express Race":                 "Paris",
+		"Club Champs - Road Race":             "",
+		"Power Comparison Ride":               "",
+		"Zwift Racing League | WTRL - Womens": "",
+	}
+	for title, expected := range cases {
+		if w := (Event{EventTitle: title}).World(); w != expected {
+			t.Errorf("Got %q for %q, expected %q", w, title, expected)
+		}
+	}
+
+	RouteWorlds["2196019512"] = "Watopia"
+	defer delete(RouteWorlds, "2196019512")
+	e := Event{EventTitle: "Zwift Racing League | WTRL - Womens", RouteID: "2196019512"}
+	if w := e.World(); w != "Watopia" {
+		t.Errorf("Got %q for known route", w)
+	}
+}
+
+func TestTimeSlot(t *testing.T) {
+	cases := map[int]string{5: Morning, 11: Morning, 12: Afternoon, 17: Evening, 21: Evening, 22: Night, 3: Night}
+	for hour, expected := range cases {
+		date := time.Date(2021, 2, 1, hour, 30, 0, 0, time.UTC)
+		e := Event{EventDateSecs: EventDateType(date.Unix()), EventDate: date}
+		if slot := e.TimeSlot(); slot != expected {
+			t.Errorf("Got %s for %d:30, expected %s", slot, hour, expected)
+		}
+		if e.StartTime() == "" {
+			t.Errorf("No start time for %v", date)
+		}
+	}
+
+	if (Event{}).TimeSlot() != "" || (Event{}).StartTime() != "" {
+		t.Errorf("Expected no time slot for an event with no date")
+	}
+}
+
+func TestSplitByWorld(t *testing.T) {
+	events, err := parseEvents([]byte(testdata))
+	if err != nil {
+		t.Fatalf("Failed parsing events: %v", err)
+	}
+
+	// The ride on a known route doesn't count, because it isn't a race
+	RouteWorlds["1776635757"] = "Watopia"
+	defer delete(RouteWorlds, "1776635757")
+
+	stats := SplitByWorld(events, time.Time{})
+	if len(stats) != 2 {
+		t.Fatalf("Got %d worlds: %v", len(stats), stats)
+	}
+	if stats[0].Series != "Crit City" || stats[0].Results != 1 {
+		t.Errorf("Unexpected Crit City stats %+v", stats[0])
+	}
+	if stats[1].Series != "Watopia" || stats[1].Results != 1 || stats[1].Riders != 1 {
+		t.Errorf("Unexpected Watopia stats %+v", stats[1])
+	}
+
+	slots := SplitByTimeSlot(events, time.Time{})
+	total := 0
+	for _, s := range slots {
+		total += s.Results
+	}
+	if total != 13 {
+		t.Errorf("Got %d races split by time slot, expected 13", total)
+	}
+}
@@ -1,8 +1,11 @@
 package zp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -10,6 +13,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type club struct {
@@ -18,21 +23,30 @@ type club struct {
 
 // Rider shows data about a rider
 type Rider struct {
-	Name             string
-	Zwid             int
-	LatestEventDate  time.Time
-	Rides            int
-	Races            int
-	Races90          int
-	Races30          int
-	Ftp90            float64
+	Name             string    `col:"Name,order=1"`
+	Zwid             int       `col:"Zwid,order=2;Profile,key=profile,order=7,format=profile"`
+	Country          string    `json:"flag"`
+	Age              string    `json:"age"`
+	Gender           Gender    `json:"male"`
+	Category         string    // Category of the latest race
+	LatestEventDate  time.Time `col:"Latest event date,key=latestdate,order=3,format=date;Latest event when,key=monthsago,order=4,format=monthsago"`
+	Rides            int       `col:"Rides,order=6"`
+	Races            int       `col:"Races,order=12"`
+	Races90          int       `col:"Races 90 days,order=11"`
+	Races30          int       `col:"Races 30 days,order=10"`
+	Ftp90            float64   `col:"FTP 90 days,order=9,format=%.1f"`
 	Ftp60            float64
-	Ftp30            float64
-	LatestRace       string
-	LatestRaceDate   time.Time
-	LatestEvent      string
+	Ftp30            float64   `col:"FTP 30 days,order=8,format=%.1f"`
+	LatestRace       string    `col:"Latest race,order=13"`
+	LatestRaceDate   time.Time `col:"Latest race date,order=14,format=date"`
+	LatestEvent      string    `col:"Latest event,order=5"`
 	LatestRaceAvgWkg float64
 	LatestRaceWkgFtp float64
+	Avatar           string      `json:",omitempty"` // Profile picture URL, if we have one
+	CECategory       string      `json:",omitempty"` // Zwift's Category Enforcement category, if we have it (see CEPaceGroups)
+	FreeRides        int         `json:",omitempty"` // Rides in the last year that aren't ZwiftPower events, included in Rides
+	Provenance       *Provenance `json:",omitempty"` // Where the rider's events came from, and how fresh they were
+	Archived         *time.Time  `json:",omitempty"` // When the rider was found to have left the club, if they have (see ArchiveDeparted)
 }
 
 type riderData struct {
@@ -41,12 +55,65 @@ type riderData struct {
 
 // Event is a ZwiftPower event
 type Event struct {
+	Zid           string        `json:"zid"`
+	Zwid          int           `json:"zwid"`
+	Name          string        `json:"name"`
+	Pos           int           `json:"pos"`
+	PositionInCat int           `json:"position_in_cat"`
+	Category      string        `json:"category"`
+	TeamID        string        `json:"tid"`
+	TeamName      string        `json:"tname"`
+	TeamColor     string        `json:"tc"`  // Team colour as hex RGB, e.g. "fc00e3"
+	TeamBgColor   string        `json:"tbc"` // Team background colour
+	Gender        Gender        `json:"male"`
+	Time          Number        `json:"time"`
 	EventType     string        `json:"f_t"`
 	EventDateSecs EventDateType `json:"event_date"`
 	EventDate     time.Time
 	EventTitle    string      `json:"event_title"`
+	RouteID       string      `json:"rt"`
+	Age           string      `json:"age"`
 	AvgWkg        interface{} `json:"avg_wkg"`
 	WkgFtp        interface{} `json:"wkg_ftp"`
+	Duration      Number      `json:"dur"`
+	Distance      Number      `json:"distance"` // km
+	AvgHR         Number      `json:"avg_hr"`
+	MaxHR         Number      `json:"max_hr"`
+	Ftp           Number      `json:"ftp"`
+	NP            Number      `json:"np"`
+	AvgPower      Number      `json:"avg_power"`
+	Weight        Number      `json:"weight"`
+	Height        Number      `json:"height"`
+	Wftp          Number      `json:"wftp"`
+	W5            Number      `json:"w5"`
+	W15           Number      `json:"w15"`
+	W30           Number      `json:"w30"`
+	W60           Number      `json:"w60"`
+	W120          Number      `json:"w120"`
+	W300          Number      `json:"w300"`
+	W1200         Number      `json:"w1200"`
+	Placings      []Placing   `json:"placings,omitempty"` // Every placing, if the rider had several result rows (see CanonicalResults)
+	Provenance    *Provenance `json:"provenance,omitempty"`
+}
+
+// RiderName is the rider's name, with any HTML entities decoded
+func (e Event) RiderName() string {
+	return html.UnescapeString(e.Name)
+}
+
+// IsRace is true for races, as opposed to group rides, workouts and so on
+func (e Event) IsRace() bool {
+	return strings.Contains(e.EventType, "RACE")
+}
+
+// Wkg is the average w/kg for the event, or zero if we don't have it
+func (e Event) Wkg() float64 {
+	return toFloat(e.AvgWkg)
+}
+
+// FtpWkg is the FTP in w/kg that ZwiftPower estimated from the event, or zero
+func (e Event) FtpWkg() float64 {
+	return toFloat(e.WkgFtp)
 }
 
 // EventDateType so we can use a custom unmarshaller
@@ -63,7 +130,38 @@ func (e *EventDateType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func NewClient() (*http.Client, error) {
+// Number is a numeric field that ZwiftPower sometimes sends as a number, sometimes
+// as a string, and sometimes as a [value, flag] pair
+type Number float64
+
+// UnmarshalJSON custom to cope with all the different ways a Number can turn up
+func (n *Number) UnmarshalJSON(data []byte) error {
+	var v interface{}
+
+	// As with EventDateType, anything we can't make sense of is treated as 0
+	json.Unmarshal(data, &v)
+	*n = Number(toFloat(v))
+	return nil
+}
+
+func toFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	case []interface{}:
+		if len(val) > 0 {
+			return toFloat(val[0])
+		}
+	}
+	return 0
+}
+
+// NewClient gets a client for talking to ZwiftPower, with any middleware wrapped
+// around its transport. Requests are always traced (see Tracing).
+func NewClient(middleware ...Middleware) (*http.Client, error) {
 	log.Printf("NewClient")
 	jar, err := cookiejar.New(nil)
 	if err != nil {
@@ -71,157 +169,408 @@ func NewClient() (*http.Client, error) {
 	}
 
 	client := &http.Client{
-		Jar: jar,
+		Jar:       jar,
+		Transport: Chain(nil, append([]Middleware{Tracing}, middleware...)...),
 	}
 
 	return client, nil
 }
 
 // ImportZP imports data about the club with this ID
+//
+// Deprecated: use Client.Club
 func ImportZP(client *http.Client, clubID int) ([]Rider, error) {
-	data, err := getJSON(client, fmt.Sprintf("https://www.zwiftpower.com/cache3/teams/%d_riders.json", clubID))
+	return Wrap(client).Club(context.Background(), clubID)
+}
+
+// ImportZPContext imports data about the club with this ID
+//
+// Deprecated: use Client.Club
+func ImportZPContext(ctx context.Context, client *http.Client, clubID int) ([]Rider, error) {
+	return Wrap(client).Club(ctx, clubID)
+}
+
+// Club imports the roster of the club with this ID
+func (c *Client) Club(ctx context.Context, clubID int) (riders []Rider, err error) {
+	ctx, span := startSpan(ctx, "ImportZP", attribute.Int("zwiftpower.club_id", clubID))
+	defer func() { endSpan(span, err) }()
+
+	data, modified, err := c.Backend.fetchJSON(ctx, c.HTTP, c.Backend.clubURL(clubID))
 	if err != nil {
-		return nil, fmt.Errorf("getting club data: %v", err)
+		return nil, fmt.Errorf("getting club data: %w", err)
 	}
 
-	var c club
-	err = json.Unmarshal(data, &c)
+	var cd club
+	err = json.Unmarshal(data, &cd)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshalling club data: %v", err)
+		return nil, &ParseError{What: "club data", Err: err}
+	}
+	p := c.provenance(c.Backend, modified)
+	for i := range cd.Data {
+		cd.Data[i].Provenance = p
 	}
+	cd.Data = c.OptOuts.FilterRiders(cd.Data)
 
-	return c.Data, nil
+	span.SetAttributes(attribute.Int("zwiftpower.riders", len(cd.Data)))
+	return cd.Data, nil
 }
 
-// ImportRider imports data about the rider with this ID
-func ImportRider(client *http.Client, riderID int) (rider Rider, err error) {
-	// I think hitting the profile URL loads the data into the cache
-	log.Printf("ImportRider(%d)", riderID)
-	_, _ = client.Get(fmt.Sprintf("https://www.zwiftpower.com/profile.php?z=%d", riderID))
-	data, err := getJSON(client, fmt.Sprintf("https://www.zwiftpower.com/cache3/profile/%d_all.json", riderID))
+// ImportEvents imports the list of events for the rider with this ID
+//
+// Deprecated: use Client.Events
+func ImportEvents(client *http.Client, riderID int) ([]Event, error) {
+	return Wrap(client).Events(context.Background(), riderID)
+}
+
+// ImportEventsContext imports the list of events for the rider with this ID
+//
+// Deprecated: use Client.Events
+func ImportEventsContext(ctx context.Context, client *http.Client, riderID int) ([]Event, error) {
+	return Wrap(client).Events(ctx, riderID)
+}
+
+// Events imports the list of events for the rider with this ID
+func (c *Client) Events(ctx context.Context, riderID int) (events []Event, err error) {
+	err = c.streamEvents(ctx, riderID, func(e *Event) error {
+		events = append(events, *e)
+		return nil
+	})
 	if err != nil {
-		return rider, err
+		return nil, err
 	}
+	return events, nil
+}
 
-	var r riderData
-	err = json.Unmarshal(data, &r)
-	if err != nil {
-		log.Printf("Error unmarshalling data: %v", err)
-		log.Printf(string(data))
-		return rider, err
+// primeProfile asks ZwiftPower to bring the rider's profile in the cache up to date
+func (c *Client) primeProfile(ctx context.Context, riderID int) {
+	if c.Backend != Cache3 {
+		return
 	}
 
-	rider.Zwid = riderID
-	if len(r.Data) < 1 {
-		log.Printf("No event data for rider %d", riderID)
-		return rider, nil
+	// I think hitting the profile URL loads the data into the cache
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/profile.php?z=%d", BaseURL, riderID), nil)
+	if err == nil {
+		resp, err := c.HTTP.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// streamEvents calls fn for each of the rider's events as they are decoded, without
+// holding them all in memory. The Event is reused, so fn must copy it to keep it.
+//
+// A long history can be cut off at cacheEventLimit events. If so, and the client is
+// logged in, the rest come from paging through api3.
+func (c *Client) streamEvents(ctx context.Context, riderID int, fn func(*Event) error) (err error) {
+	ctx, span := startSpan(ctx, "ImportEvents", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	if c.OptOuts.Has(riderID) {
+		log.Printf("Not importing events for rider %d, who has opted out", riderID)
+		return nil
 	}
 
-	var latestEventDate time.Time
-	var latestRaceDate time.Time
-	for _, e := range r.Data {
-		e.EventDate = time.Unix(int64(e.EventDateSecs), 0)
-		daysAgo := int(time.Now().Sub(e.EventDate).Hours() / 24)
-		// log.Printf("date %v, from %v is %d days ago\n", e.EventDate, e.EventDateSecs, daysAgo)
-		isRace := strings.Contains(e.EventType, "RACE")
+	c.primeProfile(ctx, riderID)
+	body, err := c.Backend.openJSON(ctx, c.HTTP, c.Backend.profileURL(riderID))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	p := c.provenance(c.Backend, bodyModified(body))
+
+	count := 0
+	seen := make(map[string]bool)
+	var fnErr error
+	err = decodeEvents(body, func(e *Event) error {
+		count++
+		seen[e.Zid] = true
+		e.Provenance = p
+		fnErr = fn(e)
+		return fnErr
+	})
+	if err := eventsError(fmt.Sprintf("events for rider %d", riderID), err, fnErr); err != nil {
+		log.Printf("Error getting events for rider %d: %v", riderID, err)
+		return err
+	}
 
-		if daysAgo <= 365 {
-			rider.Rides++
-			if isRace {
-				rider.Races++
+	if count >= cacheEventLimit {
+		if !c.loggedIn() {
+			log.Printf("History for rider %d may be cut off at %d events; log in (see SetCookies) to get the rest", riderID, count)
+		} else {
+			pages, err := c.pageEvents(ctx, riderID, seen, func(e *Event) error {
+				count++
+				return fn(e)
+			})
+			span.SetAttributes(attribute.Int("zwiftpower.pages", pages))
+			if err != nil {
+				// Without the rest of the pages the history is cut short, so the
+				// caller needs to know rather than take it as the whole story
+				log.Printf("Failed paging events for rider %d after %d pages: %v", riderID, pages, err)
+				return err
 			}
 		}
+	}
 
-		var wkgFtp float64
-		var avgWkg float64
+	span.SetAttributes(attribute.Int("zwiftpower.events", count))
+	return nil
+}
 
-		eventWkgFtp := e.WkgFtp.([]interface{})
-		wkgFtp, ok := eventWkgFtp[0].(float64)
-		if !ok {
-			wkgFtp, err = strconv.ParseFloat(eventWkgFtp[0].(string), 64)
-			if err != nil {
-				log.Fatal(err)
-			}
+// ImportEventResults imports the results for the event with this ID, one entry per rider
+//
+// Deprecated: use Client.EventResults
+func ImportEventResults(client *http.Client, eventID int) ([]Event, error) {
+	return Wrap(client).EventResults(context.Background(), eventID)
+}
+
+// ImportEventResultsContext imports the results for the event with this ID, one entry per rider
+//
+// Deprecated: use Client.EventResults
+func ImportEventResultsContext(ctx context.Context, client *http.Client, eventID int) ([]Event, error) {
+	return Wrap(client).EventResults(ctx, eventID)
+}
+
+// EventResults imports the results for the event with this ID, one entry per rider
+func (c *Client) EventResults(ctx context.Context, eventID int) (results []Event, err error) {
+	ctx, span := startSpan(ctx, "ImportEventResults", attribute.Int("zwiftpower.event_id", eventID))
+	defer func() { endSpan(span, err) }()
+
+	if c.ResultsDir != "" {
+		results, ok, err := c.localResults(eventID)
+		if ok {
+			return c.OptOuts.FilterEvents(results), err
 		}
+	}
 
-		avgWkg, err = strconv.ParseFloat(e.AvgWkg.([]interface{})[0].(string), 64)
+	data, modified, err := c.Backend.fetchJSON(ctx, c.HTTP, c.Backend.eventURL(eventID))
+	if IsNotFound(err) {
+		// Older events often only have their results page
+		results, err = c.pageResults(ctx, eventID, fmt.Errorf("getting event results: %w", err))
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
+		return c.OptOuts.FilterEvents(results), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting event results: %w", err)
+	}
 
-		// Last three months?
-		if daysAgo <= 90 {
-			if wkgFtp > rider.Ftp90 {
-				rider.Ftp90 = wkgFtp
-			}
+	results, err = parseEvents(data)
+	if err != nil {
+		return nil, &ParseError{What: "event results", Err: err}
+	}
 
-			if isRace {
-				rider.Races90++
-			}
-		}
+	results = withProvenance(results, c.provenance(c.Backend, modified))
+	return c.OptOuts.FilterEvents(CanonicalResults(results)), nil
+}
 
-		// Last two months?
-		if daysAgo <= 60 {
-			if wkgFtp > rider.Ftp60 {
-				rider.Ftp60 = wkgFtp
-			}
-		}
+func parseEvents(data []byte) ([]Event, error) {
+	var r riderData
+	err := json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, err
+	}
 
-		// Last month?
-		if daysAgo <= 30 {
-			if isRace {
-				rider.Races30++
-			}
+	for i := range r.Data {
+		r.Data[i].EventDate = time.Unix(int64(r.Data[i].EventDateSecs), 0)
+	}
 
-			if wkgFtp > rider.Ftp30 {
-				rider.Ftp30 = wkgFtp
-			}
-		}
+	return r.Data, nil
+}
 
-		if e.EventDate.After(latestEventDate) {
-			latestEventDate = e.EventDate
-			rider.LatestEvent = e.EventTitle
-		}
+// ParseEvents reads the events from a rider's profile or an event's results, as JSON
+// that the caller has already fetched from ZwiftPower or a mirror. It needs nothing
+// from the network, so it works in a browser when zp is built for WebAssembly.
+func ParseEvents(data []byte) ([]Event, error) {
+	events, err := parseEvents(data)
+	if err != nil {
+		return nil, &ParseError{What: "events", Err: err}
+	}
+	return events, nil
+}
+
+// ParseClub reads the riders from a club's JSON, as ParseEvents does for events
+func ParseClub(data []byte) ([]Rider, error) {
+	var cd club
+	err := json.Unmarshal(data, &cd)
+	if err != nil {
+		return nil, &ParseError{What: "club data", Err: err}
+	}
+	return cd.Data, nil
+}
+
+// ImportRider imports data about the rider with this ID
+//
+// Deprecated: use Client.Rider
+func ImportRider(client *http.Client, riderID int) (Rider, error) {
+	return Wrap(client).Rider(context.Background(), riderID)
+}
+
+// ImportRiderContext imports data about the rider with this ID
+//
+// Deprecated: use Client.Rider
+func ImportRiderContext(ctx context.Context, client *http.Client, riderID int) (Rider, error) {
+	return Wrap(client).Rider(ctx, riderID)
+}
 
-		if isRace && e.EventDate.After(latestRaceDate) {
-			latestRaceDate = e.EventDate
-			rider.LatestRace = e.EventTitle
-			rider.LatestRaceAvgWkg = avgWkg
-			rider.LatestRaceWkgFtp = wkgFtp
+// Rider imports data about the rider with this ID, working out their stats from their
+// events
+func (c *Client) Rider(ctx context.Context, riderID int) (rider Rider, err error) {
+	log.Printf("ImportRider(%d)", riderID)
+	ctx, span := startSpan(ctx, "ImportRider", attribute.Int("zwiftpower.rider_id", riderID))
+	defer func() { endSpan(span, err) }()
+
+	// Work out the stats as the events arrive, rather than holding on to them all
+	stats := newRiderStats(riderID, c.now(), c.FtpAggregation)
+	count := 0
+	err = c.streamEvents(ctx, riderID, func(e *Event) error {
+		count++
+		stats.add(e)
+		return nil
+	})
+	if err != nil {
+		return rider, err
+	}
+
+	if c.ZwiftToken != "" && !c.OptOuts.Has(riderID) {
+		activities, err := ImportActivities(ctx, c.HTTP, c.ZwiftToken, riderID, stats.now.AddDate(-1, 0, 0))
+		if errors.Is(err, ErrZwiftAuth) {
+			return rider, err
+		}
+		if err != nil {
+			// The events are still worth having
+			log.Printf("Failed getting activities for rider %d: %v", riderID, err)
 		}
+		stats.addActivities(activities)
 	}
 
-	rider.LatestEventDate = latestEventDate
-	rider.LatestRaceDate = latestRaceDate
-	return rider, nil
+	if count == 0 {
+		log.Printf("No event data for rider %d", riderID)
+	}
+	return stats.result(), nil
+}
+
+// ImportClubRider imports data about a rider from the club roster, keeping the details
+// that only the roster has
+//
+// Deprecated: use Client.ClubRider
+func ImportClubRider(client *http.Client, clubRider Rider) (Rider, error) {
+	return Wrap(client).ClubRider(context.Background(), clubRider)
 }
 
-func getJSON(client *http.Client, url string) ([]byte, error) {
-	resp, err := client.Get(url)
+// ImportClubRiderContext imports data about a rider from the club roster, keeping the
+// details that only the roster has
+//
+// Deprecated: use Client.ClubRider
+func ImportClubRiderContext(ctx context.Context, client *http.Client, clubRider Rider) (Rider, error) {
+	return Wrap(client).ClubRider(ctx, clubRider)
+}
+
+// ClubRider imports data about a rider from the club roster, keeping the details that
+// only the roster has
+func (c *Client) ClubRider(ctx context.Context, clubRider Rider) (Rider, error) {
+	rider, err := c.Rider(ctx, clubRider.Zwid)
 	if err != nil {
-		return []byte{}, err
+		return rider, err
+	}
+
+	return withRosterData(rider, clubRider), nil
+}
+
+func withRosterData(rider Rider, clubRider Rider) Rider {
+	rider.Name = clubRider.Name
+	rider.Country = clubRider.Country
+	if clubRider.Age != "" {
+		rider.Age = clubRider.Age
+	}
+	if clubRider.Gender != UnknownGender {
+		rider.Gender = clubRider.Gender
+	}
+	if clubRider.Avatar != "" {
+		rider.Avatar = clubRider.Avatar
+	}
+	if clubRider.CECategory != "" {
+		rider.CECategory = clubRider.CECategory
+	}
+	return rider
+}
+
+// RiderFromEvents works out the rider's stats from their events
+func RiderFromEvents(riderID int, events []Event) Rider {
+	return RiderFromEventsAsOf(riderID, events, SystemClock.Now())
+}
+
+// RiderFromEventsAsOf works out the rider's stats as they were at this time, ignoring
+// any events after it
+func RiderFromEventsAsOf(riderID int, events []Event, asOf time.Time) Rider {
+	return RiderFromEventsAggregated(riderID, events, asOf, FtpAggregations{})
+}
+
+// RiderFromEventsAggregated works out the rider's stats as of this time, combining
+// the FTP estimates in each window as the aggregation says
+func RiderFromEventsAggregated(riderID int, events []Event, asOf time.Time, aggregation FtpAggregations) Rider {
+	if len(events) < 1 {
+		log.Printf("No event data for rider %d", riderID)
+		return Rider{Zwid: riderID}
+	}
+
+	stats := newRiderStats(riderID, asOf, aggregation)
+	for i := range events {
+		stats.add(&events[i])
+	}
+	return stats.result()
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	data, _, err := fetchJSON(ctx, client, url)
+	return data, err
+}
+
+// fetchJSON is getJSON that also returns the response's Last-Modified time
+func fetchJSON(ctx context.Context, client *http.Client, url string) ([]byte, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return []byte{}, time.Time{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return []byte{}, time.Time{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return []byte{}, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, url)
+	if err := checkResponse(resp, url); err != nil {
+		return []byte{}, time.Time{}, err
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
-	return body, err
+	if err == nil && isMaintenancePage(body) {
+		return []byte{}, time.Time{}, fmt.Errorf("%w: got a maintenance page from %s", ErrMaintenance, url)
+	}
+	return body, lastModified(resp.Header), err
 }
 
 // MonthsAgo describes how many months since the rider's latest event
 func (r Rider) MonthsAgo() string {
-	if r.LatestEventDate.IsZero() {
+	return r.MonthsAgoAsOf(SystemClock.Now())
+}
+
+// MonthsAgoAsOf describes how many months before now the rider's latest event was
+func (r Rider) MonthsAgoAsOf(now time.Time) string {
+	return monthsAgo(r.LatestEventDate, now)
+}
+
+func monthsAgo(date time.Time, now time.Time) string {
+	if date.IsZero() {
 		return "No latest event"
 	}
 
-	if time.Now().Sub(r.LatestEventDate) > (time.Hour * 24 * 365) {
+	if now.Sub(date) > (time.Hour * 24 * 365) {
 		return "Over a year ago"
 	}
 
-	monthDiff := time.Now().Month() - r.LatestEventDate.Month()
+	monthDiff := now.Month() - date.Month()
 	if monthDiff < 0 {
 		monthDiff += 12
 	}
@@ -236,22 +585,23 @@ func (r Rider) MonthsAgo() string {
 	}
 }
 
+// Column describes a column of tabular output
+type Column struct {
+	Key    string // Short name, e.g. for choosing columns on the command line
+	Header string
+}
+
+// RiderColumns describes the columns returned by Rider.Strings, which come from the
+// col tags on Rider's fields
+var RiderColumns = TagColumns(Rider{})
+
 // Strings turns a rider struct into []string
 func (r Rider) Strings() []string {
-	output := make([]string, 14)
-	output[0] = r.Name
-	output[1] = strconv.Itoa(r.Zwid)
-	output[2] = r.LatestEventDate.Format("2006-01-02")
-	output[3] = r.MonthsAgo()
-	output[4] = r.LatestEvent
-	output[5] = strconv.Itoa(r.Rides)
-	output[6] = fmt.Sprintf("https://www.zwiftpower.com/profile.php?z=%d", r.Zwid)
-	output[7] = strconv.FormatFloat(r.Ftp30, 'f', 1, 64)
-	output[8] = strconv.FormatFloat(r.Ftp90, 'f', 1, 64)
-	output[9] = strconv.Itoa(r.Races30)
-	output[10] = strconv.Itoa(r.Races90)
-	output[11] = strconv.Itoa(r.Races)
-	output[12] = r.LatestRace
-	output[13] = r.LatestRaceDate.Format("2006-01-02")
-	return output
+	return TagStrings(r)
+}
+
+// StringsAsOf is Strings for a report as of now, such as one worked out with a Client
+// whose Clock is set to the past
+func (r Rider) StringsAsOf(now time.Time) []string {
+	return TagStringsAsOf(r, now)
 }
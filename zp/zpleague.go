@@ -0,0 +1,182 @@
+package zp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HostedLeague is a league run on ZwiftPower itself (league.php), as opposed to a
+// League we run within the club
+type HostedLeague struct {
+	ID        int
+	Standings []LeagueStanding
+	Rounds    []LeagueRound
+	Results   map[string][]LeagueResult // Keyed by the round's event ID
+}
+
+// LeagueStanding is a rider's place in a hosted league's overall standings
+type LeagueStanding struct {
+	Position int    `json:"position"`
+	Zwid     int    `json:"zwid"`
+	Name     string `json:"name"`
+	Country  string `json:"flag"`
+	TeamName string `json:"tname"`
+	Category string `json:"category"`
+	Points   Number `json:"points"`
+	Rounds   Number `json:"rounds"`
+}
+
+// LeagueRound is one of the events that make up a hosted league
+type LeagueRound struct {
+	Round         int           `json:"round"`
+	Zid           string        `json:"zid"`
+	Title         string        `json:"event_title"`
+	EventDateSecs EventDateType `json:"event_date"`
+	Date          time.Time
+}
+
+// LeagueResult is a rider's result in one round, with the league points it earned
+type LeagueResult struct {
+	Event
+	Points Number `json:"points"`
+}
+
+// RiderName is the rider's name, with any HTML entities decoded
+func (s LeagueStanding) RiderName() string {
+	return Event{Name: s.Name}.RiderName()
+}
+
+// ImportLeagueStandings imports the overall standings for the hosted league with this ID
+func ImportLeagueStandings(client *http.Client, leagueID int) ([]LeagueStanding, error) {
+	return ImportLeagueStandingsContext(context.Background(), client, leagueID)
+}
+
+// ImportLeagueStandingsContext imports the overall standings for the hosted league with
+// this ID, in order of position
+func ImportLeagueStandingsContext(ctx context.Context, client *http.Client, leagueID int) (standings []LeagueStanding, err error) {
+	ctx, span := startSpan(ctx, "ImportLeagueStandings", attribute.Int("zwiftpower.league_id", leagueID))
+	defer func() { endSpan(span, err) }()
+
+	var data struct{ Data []LeagueStanding }
+	err = importLeaguePart(ctx, client, DefaultBackend.leagueURL(leagueID, leagueStandings), &data)
+	if err != nil {
+		return nil, fmt.Errorf("getting league standings: %w", err)
+	}
+
+	standings = data.Data
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Position < standings[j].Position
+	})
+	return standings, nil
+}
+
+// ImportLeagueRounds imports the list of rounds for the hosted league with this ID
+func ImportLeagueRounds(client *http.Client, leagueID int) ([]LeagueRound, error) {
+	return ImportLeagueRoundsContext(context.Background(), client, leagueID)
+}
+
+// ImportLeagueRoundsContext imports the list of rounds for the hosted league with this
+// ID, in date order
+func ImportLeagueRoundsContext(ctx context.Context, client *http.Client, leagueID int) (rounds []LeagueRound, err error) {
+	ctx, span := startSpan(ctx, "ImportLeagueRounds", attribute.Int("zwiftpower.league_id", leagueID))
+	defer func() { endSpan(span, err) }()
+
+	var data struct{ Data []LeagueRound }
+	err = importLeaguePart(ctx, client, DefaultBackend.leagueURL(leagueID, leagueRounds), &data)
+	if err != nil {
+		return nil, fmt.Errorf("getting league rounds: %w", err)
+	}
+
+	rounds = data.Data
+	for i := range rounds {
+		rounds[i].Date = time.Unix(int64(rounds[i].EventDateSecs), 0)
+	}
+	sort.SliceStable(rounds, func(i, j int) bool {
+		return rounds[i].EventDateSecs < rounds[j].EventDateSecs
+	})
+	return rounds, nil
+}
+
+// ImportLeagueResults imports the results of one round of the hosted league
+func ImportLeagueResults(client *http.Client, leagueID int, zid string) ([]LeagueResult, error) {
+	return ImportLeagueResultsContext(context.Background(), client, leagueID, zid)
+}
+
+// ImportLeagueResultsContext imports the results of one round of the hosted league, in
+// finishing order
+func ImportLeagueResultsContext(ctx context.Context, client *http.Client, leagueID int, zid string) (results []LeagueResult, err error) {
+	ctx, span := startSpan(ctx, "ImportLeagueResults",
+		attribute.Int("zwiftpower.league_id", leagueID), attribute.String("zwiftpower.event_id", zid))
+	defer func() { endSpan(span, err) }()
+
+	var data struct{ Data []LeagueResult }
+	err = importLeaguePart(ctx, client, DefaultBackend.leagueResultsURL(leagueID, zid), &data)
+	switch {
+	case IsNotFound(err):
+		// Old league archives are only on the league's pages
+		results, err = pageLeagueResults(ctx, client, leagueID, zid, err)
+		if err != nil {
+			return nil, fmt.Errorf("getting league results for %s: %w", zid, err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("getting league results for %s: %w", zid, err)
+	default:
+		results = data.Data
+		for i := range results {
+			results[i].EventDate = time.Unix(int64(results[i].EventDateSecs), 0)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Pos < results[j].Pos
+	})
+	return results, nil
+}
+
+// ImportHostedLeague imports the standings, rounds and results of every round for the
+// hosted league with this ID
+func ImportHostedLeague(client *http.Client, leagueID int) (HostedLeague, error) {
+	return ImportHostedLeagueContext(context.Background(), client, leagueID)
+}
+
+// ImportHostedLeagueContext imports the standings, rounds and results of every round for
+// the hosted league with this ID
+func ImportHostedLeagueContext(ctx context.Context, client *http.Client, leagueID int) (l HostedLeague, err error) {
+	ctx, span := startSpan(ctx, "ImportHostedLeague", attribute.Int("zwiftpower.league_id", leagueID))
+	defer func() { endSpan(span, err) }()
+
+	l = HostedLeague{ID: leagueID, Results: make(map[string][]LeagueResult)}
+	l.Standings, err = ImportLeagueStandingsContext(ctx, client, leagueID)
+	if err != nil {
+		return l, err
+	}
+
+	l.Rounds, err = ImportLeagueRoundsContext(ctx, client, leagueID)
+	if err != nil {
+		return l, err
+	}
+
+	for _, r := range l.Rounds {
+		l.Results[r.Zid], err = ImportLeagueResultsContext(ctx, client, leagueID, r.Zid)
+		if err != nil {
+			return l, err
+		}
+	}
+	return l, nil
+}
+
+func importLeaguePart(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	data, err := DefaultBackend.getJSON(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	err = unmarshalInto(v)(data)
+	if err != nil {
+		return &ParseError{What: url, Err: err}
+	}
+	return nil
+}
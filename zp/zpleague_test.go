@@ -0,0 +1,68 @@
+package zp
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestImportHostedLeague(t *testing.T) {
+	withTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cache3/lg/77_standings.json":
+			fmt.Fprint(w, `{"data":[
+				{"position":2,"zwid":2,"name":"Someone Else","points":"40","rounds":2},
+				{"position":1,"zwid":1261784,"name":"&Ouml;zge Yazar","tname":"REVO","category":"C","points":50,"rounds":2}]}`)
+		case "/cache3/lg/77_events.json":
+			fmt.Fprint(w, `{"data":[
+				{"round":2,"zid":"1644250","event_title":"Round 2","event_date":1612320300},
+				{"round":1,"zid":"1497992","event_title":"Round 1","event_date":1610505900}]}`)
+		case "/cache3/lg/77_1497992_results.json", "/cache3/lg/77_1644250_results.json":
+			fmt.Fprint(w, `{"data":[
+				{"zid":"1497992","zwid":2,"name":"Someone Else","pos":2,"points":20},
+				{"zid":"1497992","zwid":1261784,"name":"Özge Yazar","pos":1,"points":[25,0]}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := ImportHostedLeague(client, 77)
+	if err != nil {
+		t.Fatalf("Failed importing league: %v", err)
+	}
+
+	if len(l.Standings) != 2 || l.Standings[0].Zwid != 1261784 || l.Standings[0].Points != 50 || l.Standings[1].Points != 40 {
+		t.Errorf("Unexpected standings %+v", l.Standings)
+	}
+	if l.Standings[0].RiderName() != "Özge Yazar" {
+		t.Errorf("Name not decoded: %q", l.Standings[0].RiderName())
+	}
+
+	if len(l.Rounds) != 2 || l.Rounds[0].Round != 1 || l.Rounds[0].Date.Unix() != 1610505900 {
+		t.Errorf("Unexpected rounds %+v", l.Rounds)
+	}
+
+	results := l.Results["1497992"]
+	if len(l.Results) != 2 || len(results) != 2 || results[0].Zwid != 1261784 || results[0].Points != 25 {
+		t.Errorf("Unexpected results %+v", l.Results)
+	}
+
+	_, err = ImportLeagueStandings(client, 78)
+	if err == nil {
+		t.Errorf("Expected error for missing league")
+	}
+}
+
+func TestLeagueURLs(t *testing.T) {
+	if u := API3.leagueURL(77, leagueStandings); u != BaseURL+"/api3.php?do=league_standings&id=77" {
+		t.Errorf("Unexpected URL %s", u)
+	}
+	if u := API3.leagueResultsURL(77, "123"); u != BaseURL+"/api3.php?do=league_event_results&id=77&zid=123" {
+		t.Errorf("Unexpected URL %s", u)
+	}
+}